@@ -21,6 +21,8 @@ package pxapi
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -60,6 +62,10 @@ type Client struct {
 	bearerAuth string
 
 	cloudAddr string
+	// directAddr is the address of a standalone Vizier. When set, the client talks to the Vizier
+	// directly instead of going through Pixie Cloud.
+	directAddr string
+	caCert     []byte
 
 	useEncryption bool
 
@@ -86,12 +92,23 @@ func NewClient(ctx context.Context, opts ...ClientOption) (*Client, error) {
 }
 
 func (c *Client) init(ctx context.Context) error {
-	isInternal := strings.ContainsAny(c.cloudAddr, "cluster.local")
+	addr := c.cloudAddr
+	if c.directAddr != "" {
+		addr = c.directAddr
+	}
+	isInternal := strings.ContainsAny(addr, "cluster.local")
 
 	tlsConfig := &tls.Config{InsecureSkipVerify: isInternal}
+	if len(c.caCert) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(c.caCert) {
+			return errors.New("failed to parse CA cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
 	creds := credentials.NewTLS(tlsConfig)
 
-	conn, err := grpc.Dial(c.cloudAddr, grpc.WithTransportCredentials(creds))
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(creds))
 	if err != nil {
 		return err
 	}
@@ -48,6 +48,21 @@ type VizierInfo struct {
 	Status VizierStatus
 	// Version of the installed vizier.
 	Version string
+	// Labels attached to the Vizier's cluster.
+	Labels map[string]string
+	// Names of the cluster groups the Vizier's cluster belongs to.
+	Groups []string
+}
+
+func clusterInfoToVizierInfo(v *cloudpb.ClusterInfo) *VizierInfo {
+	return &VizierInfo{
+		Name:    v.ClusterName,
+		ID:      utils.ProtoToUUIDStr(v.ID),
+		Version: v.VizierVersion,
+		Status:  clusterStatusToVizierStatus(v.Status),
+		Labels:  v.Labels,
+		Groups:  v.Groups,
+	}
 }
 
 func clusterStatusToVizierStatus(status cloudpb.ClusterStatus) VizierStatus {
@@ -67,7 +82,21 @@ func clusterStatusToVizierStatus(status cloudpb.ClusterStatus) VizierStatus {
 
 // ListViziers gets a list of Viziers registered with Pixie.
 func (c *Client) ListViziers(ctx context.Context) ([]*VizierInfo, error) {
-	req := &cloudpb.GetClusterInfoRequest{}
+	return c.listViziers(ctx, &cloudpb.GetClusterInfoRequest{})
+}
+
+// ListViziersBySelector gets the Viziers whose cluster labels match the given label selector,
+// for example "env=prod,team!=infra".
+func (c *Client) ListViziersBySelector(ctx context.Context, selector string) ([]*VizierInfo, error) {
+	return c.listViziers(ctx, &cloudpb.GetClusterInfoRequest{LabelSelector: selector})
+}
+
+// ListViziersByGroup gets the Viziers in the named cluster group.
+func (c *Client) ListViziersByGroup(ctx context.Context, group string) ([]*VizierInfo, error) {
+	return c.listViziers(ctx, &cloudpb.GetClusterInfoRequest{Group: group})
+}
+
+func (c *Client) listViziers(ctx context.Context, req *cloudpb.GetClusterInfoRequest) ([]*VizierInfo, error) {
 	res, err := c.cmClient.GetClusterInfo(c.cloudCtxWithMD(ctx), req)
 	if err != nil {
 		return nil, err
@@ -75,12 +104,7 @@ func (c *Client) ListViziers(ctx context.Context) ([]*VizierInfo, error) {
 
 	viziers := make([]*VizierInfo, 0)
 	for _, v := range res.Clusters {
-		viziers = append(viziers, &VizierInfo{
-			Name:    v.ClusterName,
-			ID:      utils.ProtoToUUIDStr(v.ID),
-			Version: v.VizierVersion,
-			Status:  clusterStatusToVizierStatus(v.Status),
-		})
+		viziers = append(viziers, clusterInfoToVizierInfo(v))
 	}
 
 	return viziers, nil
@@ -100,14 +124,7 @@ func (c *Client) GetVizierInfo(ctx context.Context, clusterID string) (*VizierIn
 		return nil, errdefs.ErrClusterNotFound
 	}
 
-	v := res.Clusters[0]
-
-	return &VizierInfo{
-		Name:    v.ClusterName,
-		ID:      utils.ProtoToUUIDStr(v.ID),
-		Version: v.VizierVersion,
-		Status:  clusterStatusToVizierStatus(v.Status),
-	}, nil
+	return clusterInfoToVizierInfo(res.Clusters[0]), nil
 }
 
 // CreateDeployKey creates a new deploy key, with an optional description.
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"px.dev/pixie/src/api/go/pxapi"
+	"px.dev/pixie/src/api/go/pxapi/types"
+)
+
+var (
+	pxl = `
+import px
+df = px.DataFrame('process_stats', start_time='-30s')
+df = df.groupby('upid').agg(count=('cpu_ktime_ns', px.count))
+df = df.head(10)
+px.display(df, 'processes')
+`
+)
+
+type tablePrinter struct{}
+
+func (t *tablePrinter) HandleInit(ctx context.Context, metadata types.TableMetadata) error {
+	return nil
+}
+
+func (t *tablePrinter) HandleRecord(ctx context.Context, r *types.Record) error {
+	for _, d := range r.Data {
+		fmt.Printf("%s ", d.String())
+	}
+	fmt.Printf("\n")
+	return nil
+}
+
+func (t *tablePrinter) HandleDone(ctx context.Context) error {
+	return nil
+}
+
+type tableMux struct {
+}
+
+func (s *tableMux) AcceptTable(ctx context.Context, metadata types.TableMetadata) (pxapi.TableRecordHandler, error) {
+	return &tablePrinter{}, nil
+}
+
+// This example runs a script on a standalone Vizier, which is reached directly rather than through Pixie Cloud.
+func main() {
+	apiKey, ok := os.LookupEnv("PX_API_KEY")
+	if !ok {
+		panic("please set PX_API_KEY")
+	}
+	directAddr, ok := os.LookupEnv("PX_DIRECT_ADDR")
+	if !ok {
+		panic("please set PX_DIRECT_ADDR")
+	}
+	clusterID, ok := os.LookupEnv("PX_CLUSTER_ID")
+	if !ok {
+		panic("please set PX_CLUSTER_ID")
+	}
+
+	opts := []pxapi.ClientOption{pxapi.WithDirectAddr(directAddr), pxapi.WithAPIKey(apiKey)}
+	// The standalone Vizier serves the locally provisioned certs, so the client needs their CA.
+	if caCertPath, ok := os.LookupEnv("PX_CA_CERT"); ok {
+		caCert, err := os.ReadFile(caCertPath)
+		if err != nil {
+			panic(err)
+		}
+		opts = append(opts, pxapi.WithCACert(caCert))
+	}
+
+	ctx := context.Background()
+	client, err := pxapi.NewClient(ctx, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	vz, err := client.NewVizierClient(ctx, clusterID)
+	if err != nil {
+		panic(err)
+	}
+
+	resultSet, err := vz.ExecuteScript(ctx, pxl, &tableMux{})
+	if err != nil {
+		panic(err)
+	}
+
+	defer resultSet.Close()
+	if err := resultSet.Stream(); err != nil {
+		panic(err)
+	}
+}
@@ -28,6 +28,22 @@ func WithCloudAddr(cloudAddr string) ClientOption {
 	}
 }
 
+// WithDirectAddr is the option to connect directly to a standalone Vizier at the given address,
+// instead of going through Pixie Cloud. Cloud APIs are unavailable on a direct connection.
+func WithDirectAddr(directAddr string) ClientOption {
+	return func(c *Client) {
+		c.directAddr = directAddr
+	}
+}
+
+// WithCACert is the option to specify the PEM encoded CA cert used to verify the server, such as the
+// locally provisioned CA of a standalone Vizier.
+func WithCACert(caCert []byte) ClientOption {
+	return func(c *Client) {
+		c.caCert = caCert
+	}
+}
+
 // WithBearerAuth is the option to specify bearer auth to use.
 func WithBearerAuth(auth string) ClientOption {
 	return func(c *Client) {
@@ -64,6 +64,7 @@ enum class QLObjectType {
   kExporter,
   kOTelEndpoint,
   kOTelDataContainer,
+  kAgentSelector,
 };
 
 std::string QLObjectTypeString(QLObjectType type);
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+#include <memory>
+
+#include "src/carnot/planner/objects/funcobject.h"
+#include "src/carnot/planner/plannerpb/func_args.pb.h"
+
+namespace px {
+namespace carnot {
+namespace planner {
+namespace compiler {
+
+/**
+ * @brief AgentSelector is the QLObject that wraps the selection of the agents which a tracepoint
+ * deployment runs on.
+ */
+class AgentSelector : public QLObject {
+ public:
+  static constexpr TypeDescriptor AgentSelectorType = {
+      /* name */ "AgentSelector",
+      /* type */ QLObjectType::kAgentSelector,
+  };
+
+  static StatusOr<std::shared_ptr<AgentSelector>> Create(const pypa::AstPtr& ast,
+                                                         ASTVisitor* visitor,
+                                                         const plannerpb::AgentSelector& selector) {
+    return std::shared_ptr<AgentSelector>(new AgentSelector(ast, visitor, selector));
+  }
+
+  static bool IsAgentSelector(const QLObjectPtr& ptr) {
+    return ptr->type() == AgentSelectorType.type();
+  }
+
+  const plannerpb::AgentSelector& selector() const { return selector_; }
+
+ private:
+  AgentSelector(const pypa::AstPtr& ast, ASTVisitor* visitor,
+                const plannerpb::AgentSelector& selector)
+      : QLObject(AgentSelectorType, ast, visitor), selector_(selector) {}
+
+  plannerpb::AgentSelector selector_;
+};
+
+}  // namespace compiler
+}  // namespace planner
+}  // namespace carnot
+}  // namespace px
@@ -38,7 +38,8 @@ StatusOr<QLObjectPtr> SetHandler(MutationsIR* mutations, const pypa::AstPtr& ast
   PL_ASSIGN_OR_RETURN(auto agent_pod_name_ir, GetArgAs<StringIR>(ast, args, "agent_pod_name"));
   PL_ASSIGN_OR_RETURN(auto key_ir, GetArgAs<StringIR>(ast, args, "key"));
   PL_ASSIGN_OR_RETURN(auto val_ir, GetArgAs<StringIR>(ast, args, "value"));
-  mutations->AddConfig(agent_pod_name_ir->str(), key_ir->str(), val_ir->str());
+  mutations->AddConfig(agent_pod_name_ir->str(), key_ir->str(), val_ir->str(),
+                       SourcePositionOf(agent_pod_name_ir));
   return std::static_pointer_cast<QLObject>(std::make_shared<NoneObject>(ast, visitor));
 }
 
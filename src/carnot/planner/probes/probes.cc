@@ -231,37 +231,65 @@ Status TracepointDeployment::ToProto(
   return Status::OK();
 }
 void MutationsIR::AddConfig(const std::string& pem_pod_name, const std::string& key,
-                            const std::string& value) {
+                            const std::string& value, const plannerpb::SourcePosition& position) {
   plannerpb::ConfigUpdate update;
   update.set_key(key);
   update.set_value(value);
   update.set_agent_pod_name(pem_pod_name);
   config_updates_.push_back(update);
+  config_positions_.push_back(position);
 }
 
+namespace {
+Status TracepointMutationToProto(const TracepointDeployment& program,
+                                 plannerpb::CompileMutation* mutation_pb) {
+  PL_RETURN_IF_ERROR(program.ToProto(mutation_pb->mutable_trace()));
+  *(mutation_pb->mutable_position()) = program.name_position();
+  for (const auto& position : program.table_positions()) {
+    *(mutation_pb->add_table_positions()) = position;
+  }
+  if (program.agent_selector().has_value()) {
+    *(mutation_pb->mutable_agent_selector()) = program.agent_selector().value();
+  }
+  return Status::OK();
+}
+}  // namespace
+
 Status MutationsIR::ToProto(plannerpb::CompileMutationsResponse* pb) {
   for (const auto& [spec, program] : deployments_) {
-    auto program_pb = pb->add_mutations()->mutable_trace();
-    PL_RETURN_IF_ERROR(program->ToProto(program_pb));
-    *(program_pb->mutable_deployment_spec()) = spec;
+    auto mutation_pb = pb->add_mutations();
+    PL_RETURN_IF_ERROR(TracepointMutationToProto(*program, mutation_pb));
+    *(mutation_pb->mutable_trace()->mutable_deployment_spec()) = spec;
   }
 
   for (const auto& program : bpftrace_programs_) {
-    auto program_pb = pb->add_mutations()->mutable_trace();
-    PL_RETURN_IF_ERROR(program->ToProto(program_pb));
+    PL_RETURN_IF_ERROR(TracepointMutationToProto(*program, pb->add_mutations()));
   }
 
-  for (const auto& tracepoint_to_delete : TracepointsToDelete()) {
-    pb->add_mutations()->mutable_delete_tracepoint()->set_name(tracepoint_to_delete);
+  for (const auto& [i, tracepoint_to_delete] : Enumerate(TracepointsToDelete())) {
+    auto mutation_pb = pb->add_mutations();
+    mutation_pb->mutable_delete_tracepoint()->set_name(tracepoint_to_delete);
+    *(mutation_pb->mutable_position()) = delete_positions_[i];
   }
 
-  for (const auto& update : config_updates_) {
-    *(pb->add_mutations()->mutable_config_update()) = update;
+  for (const auto& [i, update] : Enumerate(config_updates_)) {
+    auto mutation_pb = pb->add_mutations();
+    *(mutation_pb->mutable_config_update()) = update;
+    *(mutation_pb->mutable_position()) = config_positions_[i];
   }
 
   return Status::OK();
 }
 
+plannerpb::SourcePosition SourcePositionOf(const IRNode* node) {
+  plannerpb::SourcePosition position;
+  if (node->line_col_set()) {
+    position.set_line(node->line());
+    position.set_column(node->col());
+  }
+  return position;
+}
+
 void MutationsIR::EndProbe() { current_tracepoint_ = nullptr; }
 
 }  // namespace compiler
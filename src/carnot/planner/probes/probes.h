@@ -18,6 +18,7 @@
 
 #pragma once
 #include <memory>
+#include <optional>
 #include <string>
 #include <utility>
 #include <vector>
@@ -195,6 +196,36 @@ class TracepointDeployment {
    */
   Status AddBPFTrace(const std::string& bpftrace_program, const std::string& output_name);
 
+  /**
+   * @brief Records where the tracepoint's name is set in the script.
+   *
+   * @param position
+   */
+  void SetNamePosition(const plannerpb::SourcePosition& position) { name_position_ = position; }
+
+  /**
+   * @brief Records where the output table name of the last added program is set in the script.
+   *
+   * @param position
+   */
+  void AddTablePosition(const plannerpb::SourcePosition& position) {
+    table_positions_.push_back(position);
+  }
+
+  /**
+   * @brief Restricts the deployment to the agents selected by the selector.
+   *
+   * @param selector
+   */
+  void SetAgentSelector(const plannerpb::AgentSelector& selector) { agent_selector_ = selector; }
+
+  const std::optional<plannerpb::AgentSelector>& agent_selector() const { return agent_selector_; }
+
+  const plannerpb::SourcePosition& name_position() const { return name_position_; }
+  const std::vector<plannerpb::SourcePosition>& table_positions() const {
+    return table_positions_;
+  }
+
  private:
   std::string name_;
   int64_t ttl_ns_;
@@ -206,6 +237,9 @@ class TracepointDeployment {
   std::vector<carnot::planner::dynamic_tracing::ir::logical::Output> outputs_;
   absl::flat_hash_map<std::string, carnot::planner::dynamic_tracing::ir::logical::Output*>
       output_map_;
+  plannerpb::SourcePosition name_position_;
+  std::vector<plannerpb::SourcePosition> table_positions_;
+  std::optional<plannerpb::AgentSelector> agent_selector_;
 };
 
 class MutationsIR {
@@ -293,9 +327,12 @@ class MutationsIR {
    * @brief Deletes the tracepoint passed in.
    *
    * @param tracepoint_to_delete
+   * @param position where the tracepoint's name is set in the script.
    */
-  void DeleteTracepoint(const std::string& tracepoint_to_delete) {
+  void DeleteTracepoint(const std::string& tracepoint_to_delete,
+                        const plannerpb::SourcePosition& position = plannerpb::SourcePosition()) {
     tracepoints_to_delete_.push_back(tracepoint_to_delete);
+    delete_positions_.push_back(position);
   }
 
   const std::vector<std::string>& TracepointsToDelete() { return tracepoints_to_delete_; }
@@ -308,8 +345,10 @@ class MutationsIR {
    * @param asid
    * @param key
    * @param value
+   * @param position where the pod name is set in the script.
    */
-  void AddConfig(const std::string& pem_pod_name, const std::string& key, const std::string& value);
+  void AddConfig(const std::string& pem_pod_name, const std::string& key, const std::string& value,
+                 const plannerpb::SourcePosition& position = plannerpb::SourcePosition());
 
  private:
   // All the new tracepoints added as part of this mutation. DeploymentSpecs are protobufs because
@@ -325,11 +364,24 @@ class MutationsIR {
   std::shared_ptr<TracepointIR> current_tracepoint_;
 
   std::vector<std::string> tracepoints_to_delete_;
+  // The positions of the names in tracepoints_to_delete_.
+  std::vector<plannerpb::SourcePosition> delete_positions_;
 
   // The updates to internal config that need to be done.
   std::vector<plannerpb::ConfigUpdate> config_updates_;
+  // The positions of the pod names in config_updates_.
+  std::vector<plannerpb::SourcePosition> config_positions_;
 };
 
+/**
+ * @brief Returns the position of the node in the script, which is attached to mutations so that
+ * errors found while applying them can point to the script.
+ *
+ * @param node
+ * @return plannerpb::SourcePosition
+ */
+plannerpb::SourcePosition SourcePositionOf(const IRNode* node);
+
 }  // namespace compiler
 }  // namespace planner
 }  // namespace carnot
@@ -523,6 +523,14 @@ TEST_F(ProbeCompilerTest, delete_tracepoint) {
                                           "\npxtrace.DeleteTracepoint('cool_http_func')"));
   EXPECT_THAT(probe_ir->TracepointsToDelete(),
               UnorderedElementsAre("http_return", "cool_http_func"));
+
+  // Each deletion points to the line of its tracepoint name.
+  plannerpb::CompileMutationsResponse pb;
+  EXPECT_OK(probe_ir->ToProto(&pb));
+  ASSERT_EQ(pb.mutations_size(), 2);
+  EXPECT_EQ(pb.mutations()[0].position().line(), 2);
+  EXPECT_EQ(pb.mutations()[1].position().line(), 3);
+  EXPECT_GT(pb.mutations()[1].position().column(), 0);
 }
 
 constexpr char kBPFTraceProgram[] = R"bpftrace(
@@ -578,6 +586,62 @@ TEST_F(ProbeCompilerTest, parse_bpftrace) {
 
   EXPECT_THAT(pb.mutations()[0].trace(),
               testing::proto::EqualsProto(absl::Substitute(kBPFTraceProgramPb, literal_bpf_trace)));
+  // The output table name is on the line after the tracepoint name.
+  ASSERT_EQ(pb.mutations()[0].table_positions_size(), 1);
+  EXPECT_GT(pb.mutations()[0].position().line(), 0);
+  EXPECT_EQ(pb.mutations()[0].table_positions(0).line(), pb.mutations()[0].position().line() + 1);
+}
+
+constexpr char kAgentSelectorPxl[] = R"pxl(
+import pxtrace
+import px
+
+pxtrace.UpsertTracepoint('syscall_write_bpftrace',
+                         'output_table',
+                         """$0""",
+                         pxtrace.kprobe(),
+                         '5m',
+                         selector=$1)
+)pxl";
+
+constexpr char kAgentSelectorPb[] = R"proto(
+node_names: "node-1"
+node_names: "node-2"
+pod_selector: "app=web"
+)proto";
+
+TEST_F(ProbeCompilerTest, agent_selector) {
+  ASSERT_OK_AND_ASSIGN(
+      auto probe_ir,
+      CompileProbeScript(absl::Substitute(
+          kAgentSelectorPxl, kBPFTraceProgram,
+          "pxtrace.AgentSelector(node_names=['node-1', 'node-2'], pod_selector='app=web')")));
+  plannerpb::CompileMutationsResponse pb;
+  EXPECT_OK(probe_ir->ToProto(&pb));
+  ASSERT_EQ(pb.mutations_size(), 1);
+  ASSERT_TRUE(pb.mutations()[0].has_agent_selector());
+  EXPECT_THAT(pb.mutations()[0].agent_selector(), testing::proto::EqualsProto(kAgentSelectorPb));
+
+  // A tracepoint without a selector is deployed to all agents.
+  ASSERT_OK_AND_ASSIGN(probe_ir,
+                       CompileProbeScript(absl::Substitute(kBPFTracePxl, kBPFTraceProgram)));
+  pb.Clear();
+  EXPECT_OK(probe_ir->ToProto(&pb));
+  ASSERT_EQ(pb.mutations_size(), 1);
+  EXPECT_FALSE(pb.mutations()[0].has_agent_selector());
+}
+
+TEST_F(ProbeCompilerTest, agent_selector_wrong_type) {
+  auto probe_ir_or_s =
+      CompileProbeScript(absl::Substitute(kAgentSelectorPxl, kBPFTraceProgram, "'node-1'"));
+  ASSERT_NOT_OK(probe_ir_or_s);
+  EXPECT_THAT(probe_ir_or_s.status(),
+              HasCompilerError("Unexpected type 'expr' for arg 'selector'"));
+
+  probe_ir_or_s = CompileProbeScript(absl::Substitute(kAgentSelectorPxl, kBPFTraceProgram,
+                                                      "pxtrace.AgentSelector(node_names=[1])"));
+  ASSERT_NOT_OK(probe_ir_or_s);
+  EXPECT_THAT(probe_ir_or_s.status(), HasCompilerError("Expected 'String' in arg 'node_names"));
 }
 
 constexpr char kConfigChangePxl[] = R"pxl(
@@ -601,7 +665,10 @@ TEST_F(ProbeCompilerTest, config_update) {
   EXPECT_OK(probe_ir->ToProto(&pb));
   ASSERT_EQ(pb.mutations_size(), 1);
 
-  EXPECT_THAT(pb.mutations()[0], testing::proto::EqualsProto(kConfigMutationPb));
+  EXPECT_THAT(pb.mutations()[0],
+              testing::proto::Partially(testing::proto::EqualsProto(kConfigMutationPb)));
+  EXPECT_EQ(pb.mutations()[0].position().line(), 5);
+  EXPECT_GT(pb.mutations()[0].position().column(), 0);
 }
 
 }  // namespace compiler
@@ -23,6 +23,7 @@
 #include "src/carnot/planner/objects/dict_object.h"
 #include "src/carnot/planner/objects/expr_object.h"
 #include "src/carnot/planner/objects/none_object.h"
+#include "src/carnot/planner/probes/agent_selector.h"
 #include "src/carnot/planner/probes/kprobe_target.h"
 #include "src/carnot/planner/probes/process_target.h"
 
@@ -73,6 +74,8 @@ class ReturnHandler {
 };
 StatusOr<QLObjectPtr> ProcessTargetHandler(const pypa::AstPtr& ast, const ParsedArgs& args,
                                            ASTVisitor* visitor);
+StatusOr<QLObjectPtr> AgentSelectorHandler(const pypa::AstPtr& ast, const ParsedArgs& args,
+                                           ASTVisitor* visitor);
 
 StatusOr<QLObjectPtr> LatencyHandler::Eval(MutationsIR* mutations_ir, const pypa::AstPtr& ast,
                                            const ParsedArgs&, ASTVisitor* visitor) {
@@ -137,7 +140,9 @@ Status TraceModule::Init() {
 
   PL_ASSIGN_OR_RETURN(
       std::shared_ptr<FuncObject> upsert_fn,
-      FuncObject::Create(kUpsertTraceID, {"name", "table_name", "probe_fn", "target", "ttl"}, {},
+      FuncObject::Create(kUpsertTraceID,
+                         {"name", "table_name", "probe_fn", "target", "ttl", "selector"},
+                         {{"selector", "None"}},
                          // TODO(philkuz/zasgar) uncomment definition when pod based upsert works.
                          // FuncObject::Create(kUpsertTracingVariable, {"name", "probe_fn",
                          // "pod_name", "binary", "ttl"}, {},
@@ -192,6 +197,22 @@ Status TraceModule::Init() {
   PL_RETURN_IF_ERROR(process_target_constructor->SetDocString(kProcessTargetDocstring));
   AddMethod(kProcessTargetID, process_target_constructor);
 
+  PL_ASSIGN_OR_RETURN(
+      std::shared_ptr<FuncObject> agent_selector_constructor,
+      FuncObject::Create(kAgentSelectorID,
+                         {"node_names", "node_selector", "namespaces", "pod_selector"},
+                         {{"node_names", "[]"},
+                          {"node_selector", "''"},
+                          {"namespaces", "[]"},
+                          {"pod_selector", "''"}},
+                         /* has_variable_len_args */ false, /* has_variable_len_kwargs */ false,
+                         std::bind(AgentSelectorHandler, std::placeholders::_1,
+                                   std::placeholders::_2, std::placeholders::_3),
+                         ast_visitor()));
+
+  PL_RETURN_IF_ERROR(agent_selector_constructor->SetDocString(kAgentSelectorDocstring));
+  AddMethod(kAgentSelectorID, agent_selector_constructor);
+
   return Status::OK();
 }
 
@@ -379,6 +400,15 @@ StatusOr<QLObjectPtr> UpsertHandler::Eval(MutationsIR* mutations_ir, const pypa:
     return CreateAstError(ast, "Unexpected type '$0' for arg '$1'",
                           QLObjectTypeString(target->type()), "target");
   }
+  auto selector = args.GetArg("selector");
+  if (AgentSelector::IsAgentSelector(selector)) {
+    trace_program->SetAgentSelector(static_cast<AgentSelector*>(selector.get())->selector());
+  } else if (!NoneObject::IsNoneObject(selector)) {
+    return CreateAstError(ast, "Unexpected type '$0' for arg '$1'",
+                          QLObjectTypeString(selector->type()), "selector");
+  }
+  trace_program->SetNamePosition(SourcePositionOf(tp_deployment_name_ir));
+  trace_program->AddTablePosition(SourcePositionOf(output_name_ir));
 
   if (FuncObject::IsFuncObject(args.GetArg("probe_fn"))) {
     PL_ASSIGN_OR_RETURN(auto probe_fn, GetCallMethod(ast, args.GetArg("probe_fn")));
@@ -417,7 +447,7 @@ StatusOr<QLObjectPtr> DeleteTracepointHandler::Eval(MutationsIR* mutations_ir,
                                                     ASTVisitor* visitor) {
   PL_ASSIGN_OR_RETURN(auto tp_deployment_name_ir, GetArgAs<StringIR>(ast, args, "name"));
   const std::string& tp_deployment_name = tp_deployment_name_ir->str();
-  mutations_ir->DeleteTracepoint(tp_deployment_name);
+  mutations_ir->DeleteTracepoint(tp_deployment_name, SourcePositionOf(tp_deployment_name_ir));
   return std::static_pointer_cast<QLObject>(std::make_shared<NoneObject>(ast, visitor));
 }
 
@@ -430,6 +460,40 @@ StatusOr<QLObjectPtr> ProcessTargetHandler(const pypa::AstPtr& ast, const Parsed
                                process_path_ir->str());
 }
 
+// Parses a list of strings, or a single string, passed as the named argument.
+StatusOr<std::vector<std::string>> ParseStrings(const QLObjectPtr& obj,
+                                                std::string_view arg_name) {
+  std::vector<std::string> strs;
+  bool with_index = CollectionObject::IsCollection(obj);
+  for (const auto& [idx, item] : Enumerate(ObjectAsCollection(obj))) {
+    std::string name = std::string(arg_name);
+    if (with_index) {
+      name = absl::Substitute("$0 (index $1)", arg_name, idx);
+    }
+    PL_ASSIGN_OR_RETURN(StringIR * str_ir, GetArgAs<StringIR>(item, name));
+    strs.push_back(str_ir->str());
+  }
+  return strs;
+}
+
+StatusOr<QLObjectPtr> AgentSelectorHandler(const pypa::AstPtr& ast, const ParsedArgs& args,
+                                           ASTVisitor* visitor) {
+  plannerpb::AgentSelector selector;
+  PL_ASSIGN_OR_RETURN(auto node_names, ParseStrings(args.GetArg("node_names"), "node_names"));
+  PL_ASSIGN_OR_RETURN(auto namespaces, ParseStrings(args.GetArg("namespaces"), "namespaces"));
+  PL_ASSIGN_OR_RETURN(auto node_selector_ir, GetArgAs<StringIR>(ast, args, "node_selector"));
+  PL_ASSIGN_OR_RETURN(auto pod_selector_ir, GetArgAs<StringIR>(ast, args, "pod_selector"));
+  for (const auto& node_name : node_names) {
+    selector.add_node_names(node_name);
+  }
+  for (const auto& ns : namespaces) {
+    selector.add_namespaces(ns);
+  }
+  selector.set_node_selector(node_selector_ir->str());
+  selector.set_pod_selector(pod_selector_ir->str());
+  return AgentSelector::Create(ast, visitor, selector);
+}
+
 }  // namespace compiler
 }  // namespace planner
 }  // namespace carnot
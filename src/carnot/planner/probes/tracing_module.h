@@ -171,6 +171,8 @@ class TraceModule : public QLObject {
       to trace as specified by unique Vizier PID.
     ttl (px.Duration): The length of time that a tracepoint will stay alive, after
       which it will be removed.
+    selector (pxtrace.AgentSelector, optional): The agents to deploy the tracepoint to.
+      Deploys to all agents if not specified.
   )doc";
 
   inline static constexpr char kDeleteTracepointID[] = "DeleteTracepoint";
@@ -251,6 +253,28 @@ class TraceModule : public QLObject {
     to UpsertTracepoint.
   )doc";
 
+  inline static constexpr char kAgentSelectorID[] = "AgentSelector";
+  inline static constexpr char kAgentSelectorDocstring[] = R"doc(
+  Selects the agents which a tracepoint is deployed to.
+
+  Agents are selected by the node they run on. An agent is selected if its node matches
+  all of the specified arguments. The selection is kept up to date as agents, nodes and pods
+  come and go.
+
+  :topic: tracepoint_fields
+
+  Args:
+    node_names (List[str], optional): The names of the nodes to deploy to.
+    node_selector (str, optional): A Kubernetes label selector for the nodes to deploy to.
+    namespaces (List[str], optional): Deploy to the nodes which run a pod in one of these
+      namespaces.
+    pod_selector (str, optional): Deploy to the nodes which run a pod matching this
+      Kubernetes label selector.
+
+  Returns:
+    AgentSelector: The selection, which can be passed as the selector to UpsertTracepoint.
+  )doc";
+
  protected:
   explicit TraceModule(MutationsIR* mutations_ir, ASTVisitor* ast_visitor)
       : QLObject(TraceModuleType, ast_visitor), mutations_ir_(mutations_ir) {}
@@ -38,6 +38,8 @@ import (
 	"px.dev/pixie/src/cloud/api/controllers"
 	"px.dev/pixie/src/cloud/api/ptproxy"
 	"px.dev/pixie/src/cloud/autocomplete"
+	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/cloud/indexer/schema"
 	"px.dev/pixie/src/cloud/shared/esutils"
 	"px.dev/pixie/src/cloud/shared/idprovider"
 	"px.dev/pixie/src/cloud/shared/vzshard"
@@ -47,6 +49,7 @@ import (
 	"px.dev/pixie/src/shared/services/handler"
 	"px.dev/pixie/src/shared/services/healthz"
 	"px.dev/pixie/src/shared/services/msgbus"
+	"px.dev/pixie/src/shared/services/pg"
 	"px.dev/pixie/src/shared/services/server"
 )
 
@@ -61,12 +64,20 @@ func init() {
 	pflag.String("elastic_tls_key", "/elastic-certs/tls.key", "TLS Key for elastic cluster")
 	pflag.String("elastic_username", "elastic", "Username for access to elastic cluster")
 	pflag.String("elastic_password", "", "Password for access to elastic")
+	pflag.String("search_backend", md.SearchBackendElastic, "The backend used to search metadata, either elastic or postgres")
 	pflag.String("allowed_origins", "", "The allowed origins for CORS")
+	pflag.Int("trusted_proxy_hops", 1, "The number of proxies in front of the API service that append to X-Forwarded-For")
 
 	pflag.String("auth_connector_name", "", "If any, the name of the auth connector to be used with Pixie")
 	pflag.String("auth_connector_callback_url", "", "If any, the callback URL for the auth connector")
 }
 
+// scriptBundleSuggester is a suggester which also suggests the scripts in the script bundle.
+type scriptBundleSuggester interface {
+	autocomplete.Suggester
+	UpdateScriptBundle(br *script.BundleManager)
+}
+
 func main() {
 	services.SetupService("api-service", 51200)
 	services.SetupSSLClientFlags()
@@ -114,11 +125,21 @@ func main() {
 		log.WithError(err).Fatal("Failed to init API key client")
 	}
 
+	dac, err := controllers.NewDeviceAuthClient()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to init device auth client")
+	}
+
 	oa, err := idprovider.NewHydraKratosClient()
 	if err != nil {
 		log.WithError(err).Fatal("Failed to init Hydra + Kratos idprovider client")
 	}
 
+	aud, err := apienv.NewAuditServiceClient()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to init audit client")
+	}
+
 	ps, drps, err := apienv.NewPluginServiceClients()
 	if err != nil {
 		log.WithError(err).Fatal("Failed to connect to plugin service")
@@ -132,17 +153,6 @@ func main() {
 	// Connect to NATS.
 	nc := msgbus.MustConnectNATS()
 
-	esConfig := &esutils.Config{
-		URL:        []string{viper.GetString("elastic_service")},
-		User:       viper.GetString("elastic_username"),
-		Passwd:     viper.GetString("elastic_password"),
-		CaCertFile: viper.GetString("elastic_ca_cert"),
-	}
-	es, err := esutils.NewEsClient(esConfig)
-	if err != nil {
-		log.WithError(err).Fatal("Could not connect to elastic")
-	}
-
 	mux := http.NewServeMux()
 	mux.Handle("/api/auth/signup", handler.New(env, controllers.AuthSignupHandler))
 	mux.Handle("/api/auth/login", handler.New(env, controllers.AuthLoginHandler))
@@ -181,6 +191,8 @@ func main() {
 			"/pl.cloudapi.ArtifactTracker/GetDownloadLink":  true,
 			"/px.cloudapi.ConfigService/GetConfigForVizier": true,
 			"/px.cloudapi.AuthService/Login":                true,
+			"/px.cloudapi.AuthService/CreateDeviceCode":     true,
+			"/px.cloudapi.AuthService/PollDeviceCode":       true,
 		},
 	}
 
@@ -199,16 +211,16 @@ func main() {
 	}
 	cloudpb.RegisterArtifactTrackerServer(s.GRPCServer(), artifactTrackerServer)
 
-	cis := &controllers.VizierClusterInfo{VzMgr: vc, ArtifactTrackerClient: at}
+	cis := &controllers.VizierClusterInfo{VzMgr: vc, ArtifactTrackerClient: at, AuditClient: aud}
 	cloudpb.RegisterVizierClusterInfoServer(s.GRPCServer(), cis)
 
-	vdks := &controllers.VizierDeploymentKeyServer{VzDeploymentKey: vk}
+	vdks := &controllers.VizierDeploymentKeyServer{VzDeploymentKey: vk, AuditClient: aud}
 	cloudpb.RegisterVizierDeploymentKeyManagerServer(s.GRPCServer(), vdks)
 
-	aks := &controllers.APIKeyServer{APIKeyClient: ak}
+	aks := &controllers.APIKeyServer{APIKeyClient: ak, AuditClient: aud}
 	cloudpb.RegisterAPIKeyManagerServer(s.GRPCServer(), aks)
 
-	authServer := &controllers.AuthServer{AuthClient: ac}
+	authServer := &controllers.AuthServer{AuthClient: ac, DeviceAuthClient: dac}
 	cloudpb.RegisterAuthServiceServer(s.GRPCServer(), authServer)
 
 	vpt := ptproxy.NewVizierPassThroughProxy(nc, vc)
@@ -222,9 +234,32 @@ func main() {
 	sms := &controllers.ScriptMgrServer{ScriptMgr: sm}
 	cloudpb.RegisterScriptMgrServer(s.GRPCServer(), sms)
 
-	esSuggester, err := autocomplete.NewElasticSuggester(es, "scripts", pc)
-	if err != nil {
-		log.WithError(err).Fatal("Failed to start elastic suggester")
+	var suggester scriptBundleSuggester
+	switch backend := viper.GetString("search_backend"); backend {
+	case md.SearchBackendElastic:
+		esConfig := &esutils.Config{
+			URL:        []string{viper.GetString("elastic_service")},
+			User:       viper.GetString("elastic_username"),
+			Passwd:     viper.GetString("elastic_password"),
+			CaCertFile: viper.GetString("elastic_ca_cert"),
+		}
+		es, err := esutils.NewEsClient(esConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Could not connect to elastic")
+		}
+		suggester, err = autocomplete.NewElasticSuggester(es, "scripts", pc)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to start elastic suggester")
+		}
+	case md.SearchBackendPostgres:
+		db := pg.MustConnectDefaultPostgresDB()
+		err := schema.PerformMigrations(db)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to apply migrations")
+		}
+		suggester = autocomplete.NewPostgresSuggester(db)
+	default:
+		log.Fatalf("Unknown search backend: %s", backend)
 	}
 
 	var br *script.BundleManager
@@ -237,7 +272,7 @@ func main() {
 			log.WithError(bundleErr).Error("Failed to init bundle manager")
 			br = nil
 		}
-		esSuggester.UpdateScriptBundle(br)
+		suggester.UpdateScriptBundle(br)
 	}
 
 	quitCh := make(chan bool)
@@ -256,21 +291,24 @@ func main() {
 	}()
 	defer close(quitCh)
 
-	as := &controllers.AutocompleteServer{Suggester: esSuggester}
+	as := &controllers.AutocompleteServer{Suggester: suggester}
 	cloudpb.RegisterAutocompleteServiceServer(s.GRPCServer(), as)
 
-	os := &controllers.OrganizationServiceServer{ProfileServiceClient: pc, AuthServiceClient: ac, OrgServiceClient: oc}
+	os := &controllers.OrganizationServiceServer{ProfileServiceClient: pc, AuthServiceClient: ac, OrgServiceClient: oc, AuditClient: aud}
 	cloudpb.RegisterOrganizationServiceServer(s.GRPCServer(), os)
 
-	us := &controllers.UserServiceServer{ProfileServiceClient: pc, OrgServiceClient: oc}
+	us := &controllers.UserServiceServer{ProfileServiceClient: pc, OrgServiceClient: oc, AuditClient: aud, VzMgr: vc}
 	cloudpb.RegisterUserServiceServer(s.GRPCServer(), us)
 
 	cs := &controllers.ConfigServiceServer{ConfigServiceClient: cm}
 	cloudpb.RegisterConfigServiceServer(s.GRPCServer(), cs)
 
-	pss := &controllers.PluginServiceServer{PluginServiceClient: ps, DataRetentionPluginServiceClient: drps}
+	pss := &controllers.PluginServiceServer{PluginServiceClient: ps, DataRetentionPluginServiceClient: drps, AuditClient: aud}
 	cloudpb.RegisterPluginServiceServer(s.GRPCServer(), pss)
 
+	auds := &controllers.AuditServer{AuditClient: aud}
+	cloudpb.RegisterAuditServiceServer(s.GRPCServer(), auds)
+
 	gqlEnv := controllers.GraphQLEnv{
 		ArtifactTrackerServer: artifactTrackerServer,
 		VizierClusterInfo:     cis,
@@ -281,6 +319,8 @@ func main() {
 		OrgServer:             os,
 		UserServer:            us,
 		PluginServer:          pss,
+		AuditServer:           auds,
+		AuthServer:            authServer,
 	}
 
 	mux.Handle("/api/graphql", controllers.WithAugmentedAuthMiddleware(env, controllers.NewGraphQLHandler(gqlEnv)))
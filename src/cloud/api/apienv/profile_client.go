@@ -60,3 +60,18 @@ func NewOrgServiceClient() (profilepb.OrgServiceClient, error) {
 
 	return profilepb.NewOrgServiceClient(authChannel), nil
 }
+
+// NewAuditServiceClient creates a new audit RPC client stub.
+func NewAuditServiceClient() (profilepb.AuditServiceClient, error) {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	authChannel, err := grpc.Dial(viper.GetString("profile_service"), dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return profilepb.NewAuditServiceClient(authChannel), nil
+}
@@ -20,17 +20,23 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/gogo/protobuf/types"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/auth/authpb"
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/shared/rbac"
+	srvutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
 )
 
 // APIKeyServer is the server that implements the APIKeyManager gRPC service.
 type APIKeyServer struct {
 	APIKeyClient authpb.APIKeyServiceClient
+	AuditClient  profilepb.AuditServiceClient
 }
 
 func apiKeyToCloudAPI(key *authpb.APIKey) *cloudpb.APIKey {
@@ -56,6 +62,10 @@ func apiKeyMetadataToCloudAPI(key *authpb.APIKeyMetadata) *cloudpb.APIKeyMetadat
 
 // Create creates a new API key.
 func (v *APIKeyServer) Create(ctx context.Context, req *cloudpb.CreateAPIKeyRequest) (*cloudpb.APIKey, error) {
+	if err := rbac.RequireOrgRole(ctx, srvutils.MemberRole); err != nil {
+		return nil, err
+	}
+
 	ctx, err := contextWithAuthToken(ctx)
 	if err != nil {
 		return nil, err
@@ -65,10 +75,16 @@ func (v *APIKeyServer) Create(ctx context.Context, req *cloudpb.CreateAPIKeyRequ
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     "api_key.create",
+		TargetType: "api_key",
+		TargetID:   utils.UUIDFromProtoOrNil(resp.ID).String(),
+		After:      fmt.Sprintf("desc: %s", resp.Desc),
+	})
 	return apiKeyToCloudAPI(resp), nil
 }
 
-// List lists all of the API keys in vzmgr.
+// List lists all of the API keys in vzmgr. Only the metadata of the keys is returned, never the key values.
 func (v *APIKeyServer) List(ctx context.Context, req *cloudpb.ListAPIKeyRequest) (*cloudpb.ListAPIKeyResponse, error) {
 	ctx, err := contextWithAuthToken(ctx)
 	if err != nil {
@@ -88,16 +104,29 @@ func (v *APIKeyServer) List(ctx context.Context, req *cloudpb.ListAPIKeyRequest)
 	}, nil
 }
 
-// Get fetches a specific API key.
+// getOwnedKey fetches the API key, and checks that the caller either created it or administers the org. Tokens
+// for an API key carry the roles of its creator, so any other user with access to the key could act as them.
+func (v *APIKeyServer) getOwnedKey(ctx context.Context, id *uuidpb.UUID) (*authpb.GetAPIKeyResponse, error) {
+	resp, err := v.APIKeyClient.Get(ctx, &authpb.GetAPIKeyRequest{
+		ID: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := rbac.RequireUserOrOrgRole(ctx, utils.UUIDFromProtoOrNil(resp.Key.UserID), srvutils.AdminRole); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Get fetches a specific API key. Only the creator of the key and org admins may fetch it.
 func (v *APIKeyServer) Get(ctx context.Context, req *cloudpb.GetAPIKeyRequest) (*cloudpb.GetAPIKeyResponse, error) {
 	ctx, err := contextWithAuthToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := v.APIKeyClient.Get(ctx, &authpb.GetAPIKeyRequest{
-		ID: req.ID,
-	})
+	resp, err := v.getOwnedKey(ctx, req.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -106,13 +135,25 @@ func (v *APIKeyServer) Get(ctx context.Context, req *cloudpb.GetAPIKeyRequest) (
 	}, nil
 }
 
-// Delete deletes a specific API key.
+// Delete deletes a specific API key. Only the creator of the key and org admins may delete it.
 func (v *APIKeyServer) Delete(ctx context.Context, uuid *uuidpb.UUID) (*types.Empty, error) {
 	ctx, err := contextWithAuthToken(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return v.APIKeyClient.Delete(ctx, uuid)
+	if _, err := v.getOwnedKey(ctx, uuid); err != nil {
+		return nil, err
+	}
+	resp, err := v.APIKeyClient.Delete(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     "api_key.delete",
+		TargetType: "api_key",
+		TargetID:   utils.UUIDFromProtoOrNil(uuid).String(),
+	})
+	return resp, nil
 }
 
 // LookupAPIKey gets the complete API key information using just the Key.
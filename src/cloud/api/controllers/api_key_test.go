@@ -26,11 +26,15 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/api/controllers"
 	"px.dev/pixie/src/cloud/api/controllers/testutils"
 	"px.dev/pixie/src/cloud/auth/authpb"
+	svcutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 )
 
@@ -212,6 +216,9 @@ func TestAPIKeyServer_Delete(t *testing.T) {
 			defer cleanup()
 
 			id := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+			mockClients.MockAPIKey.EXPECT().
+				Get(gomock.Any(), &authpb.GetAPIKeyRequest{ID: id}).
+				Return(&authpb.GetAPIKeyResponse{Key: &authpb.APIKey{ID: id}}, nil)
 			vzresp := &types.Empty{}
 			mockClients.MockAPIKey.EXPECT().
 				Delete(gomock.Any(), id).Return(vzresp, nil)
@@ -226,3 +233,67 @@ func TestAPIKeyServer_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIKeyServer_KeyOwnership(t *testing.T) {
+	otherUser := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c9")
+	self := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9")
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		keyUserID   *uuidpb.UUID
+		expectedErr codes.Code
+	}{
+		{
+			name:        "viewer fetching another user's key",
+			ctx:         CreateTestContextWithRole(svcutils.ViewerRole),
+			keyUserID:   otherUser,
+			expectedErr: codes.PermissionDenied,
+		},
+		{
+			name:        "member fetching another user's key",
+			ctx:         CreateTestContextWithRole(svcutils.MemberRole),
+			keyUserID:   otherUser,
+			expectedErr: codes.PermissionDenied,
+		},
+		{
+			name:        "viewer fetching their own key",
+			ctx:         CreateTestContextWithRole(svcutils.ViewerRole),
+			keyUserID:   self,
+			expectedErr: codes.OK,
+		},
+		{
+			name:        "admin fetching another user's key",
+			ctx:         CreateTestContextWithRole(svcutils.AdminRole),
+			keyUserID:   otherUser,
+			expectedErr: codes.OK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+			defer cleanup()
+
+			id := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+			key := &authpb.GetAPIKeyResponse{
+				Key: &authpb.APIKey{ID: id, UserID: test.keyUserID, Key: "foobar"},
+			}
+			mockClients.MockAPIKey.EXPECT().
+				Get(gomock.Any(), &authpb.GetAPIKeyRequest{ID: id}).Return(key, nil).Times(2)
+			if test.expectedErr == codes.OK {
+				mockClients.MockAPIKey.EXPECT().Delete(gomock.Any(), id).Return(&types.Empty{}, nil)
+			}
+
+			vzAPIKeyServer := &controllers.APIKeyServer{
+				APIKeyClient: mockClients.MockAPIKey,
+			}
+			resp, err := vzAPIKeyServer.Get(test.ctx, &cloudpb.GetAPIKeyRequest{ID: id})
+			assert.Equal(t, test.expectedErr, status.Code(err))
+			if test.expectedErr == codes.OK {
+				assert.Equal(t, "foobar", resp.Key.Key)
+			}
+			_, err = vzAPIKeyServer.Delete(test.ctx, id)
+			assert.Equal(t, test.expectedErr, status.Code(err))
+		})
+	}
+}
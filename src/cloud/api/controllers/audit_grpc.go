@@ -0,0 +1,192 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/shared/rbac"
+	"px.dev/pixie/src/shared/services/authcontext"
+	srvutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+const (
+	auditActorUser   = "user"
+	auditActorAPIKey = "api_key"
+)
+
+// AuditServer is the server that implements the AuditService gRPC service.
+type AuditServer struct {
+	AuditClient profilepb.AuditServiceClient
+}
+
+func auditEventToCloudProto(e *profilepb.AuditEvent) *cloudpb.AuditEvent {
+	return &cloudpb.AuditEvent{
+		ID:         e.ID,
+		ActorID:    e.ActorID,
+		ActorType:  e.ActorType,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Before:     e.Before,
+		After:      e.After,
+		SourceIP:   e.SourceIP,
+		Timestamp:  e.Timestamp,
+	}
+}
+
+// ListAuditEvents lists the audit events for the caller's org. Only admins may view the audit trail.
+func (a *AuditServer) ListAuditEvents(ctx context.Context, req *cloudpb.ListAuditEventsRequest) (*cloudpb.ListAuditEventsResponse, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := rbac.RequireOrgRole(ctx, srvutils.AdminRole); err != nil {
+		return nil, err
+	}
+	orgID, err := uuid.FromString(sCtx.Claims.GetUserClaims().OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.AuditClient.ListAuditEvents(ctx, &profilepb.ListAuditEventsRequest{
+		OrgID:    utils.ProtoFromUUID(orgID),
+		ActorID:  req.ActorID,
+		Action:   req.Action,
+		TargetID: req.TargetID,
+		Before:   req.Before,
+		Limit:    req.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*cloudpb.AuditEvent, len(resp.Events))
+	for i, e := range resp.Events {
+		events[i] = auditEventToCloudProto(e)
+	}
+	return &cloudpb.ListAuditEventsResponse{Events: events}, nil
+}
+
+// recordAuditEvent appends the event to the audit trail of the caller's org, filling in the actor and source IP
+// from the context. The action has already been performed by the time this is called, so failures are logged
+// rather than returned.
+func recordAuditEvent(ctx context.Context, client profilepb.AuditServiceClient, event *profilepb.AuditEvent) {
+	if client == nil {
+		return
+	}
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil || srvutils.GetClaimsType(sCtx.Claims) != srvutils.UserClaimType {
+		return
+	}
+	claims := sCtx.Claims.GetUserClaims()
+	orgID := uuid.FromStringOrNil(claims.OrgID)
+	if orgID == uuid.Nil {
+		return
+	}
+
+	event.OrgID = utils.ProtoFromUUID(orgID)
+	event.ActorType = auditActorUser
+	if claims.IsAPIUser {
+		event.ActorType = auditActorAPIKey
+	}
+	if userID := uuid.FromStringOrNil(claims.UserID); userID != uuid.Nil {
+		event.ActorID = utils.ProtoFromUUID(userID)
+	}
+	event.SourceIP = sourceIPFromContext(ctx)
+
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return
+	}
+	if _, err := client.RecordAuditEvent(ctx, event); err != nil {
+		log.WithError(err).WithField("action", event.Action).Error("Failed to record audit event")
+	}
+}
+
+type sourceIPKey struct{}
+
+// withSourceIP stores the IP of the client that made an HTTP request, so that actions taken
+// through GraphQL are attributed to the right address.
+func withSourceIP(ctx context.Context, r *http.Request) context.Context {
+	ip := trustedForwardedIP(r.Header.Get("X-Forwarded-For"))
+	if ip == "" {
+		ip = hostFromAddr(r.RemoteAddr)
+	}
+	return context.WithValue(ctx, sourceIPKey{}, ip)
+}
+
+func sourceIPFromContext(ctx context.Context) string {
+	if ip, ok := ctx.Value(sourceIPKey{}).(string); ok {
+		return ip
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if xff := md.Get("x-forwarded-for"); len(xff) > 0 {
+			if ip := trustedForwardedIP(xff[len(xff)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return hostFromAddr(p.Addr.String())
+	}
+	return ""
+}
+
+// trustedForwardedIP returns the client address recorded by our own proxies in an
+// X-Forwarded-For header value. Each proxy appends the address it received the request
+// from, so everything left of the last trusted_proxy_hops entries is client-controlled
+// and can't be used for attribution.
+func trustedForwardedIP(xff string) string {
+	hops := viper.GetInt("trusted_proxy_hops")
+	if hops <= 0 || xff == "" {
+		return ""
+	}
+	ips := strings.Split(xff, ",")
+	idx := len(ips) - hops
+	if idx < 0 {
+		idx = 0
+	}
+	return strings.TrimSpace(ips[idx])
+}
+
+func hostFromAddr(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/cloud/api/controllers"
+	"px.dev/pixie/src/cloud/api/controllers/testutils"
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	svcutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+func TestAuditServer_ListAuditEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContextWithRole(svcutils.AdminRole)
+
+	eventID := uuid.Must(uuid.NewV4())
+	ts := types.TimestampNow()
+	mockClients.MockAudit.EXPECT().ListAuditEvents(gomock.Any(), &profilepb.ListAuditEventsRequest{
+		OrgID:  utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		Action: "deploy_key.delete",
+		Limit:  10,
+	}).Return(&profilepb.ListAuditEventsResponse{
+		Events: []*profilepb.AuditEvent{
+			{
+				ID:         utils.ProtoFromUUID(eventID),
+				OrgID:      utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+				ActorType:  "user",
+				Action:     "deploy_key.delete",
+				TargetType: "deploy_key",
+				TargetID:   "abc",
+				SourceIP:   "10.0.0.1",
+				Timestamp:  ts,
+			},
+		},
+	}, nil)
+
+	as := &controllers.AuditServer{AuditClient: mockClients.MockAudit}
+	resp, err := as.ListAuditEvents(ctx, &cloudpb.ListAuditEventsRequest{
+		Action: "deploy_key.delete",
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.ListAuditEventsResponse{
+		Events: []*cloudpb.AuditEvent{
+			{
+				ID:         utils.ProtoFromUUID(eventID),
+				ActorType:  "user",
+				Action:     "deploy_key.delete",
+				TargetType: "deploy_key",
+				TargetID:   "abc",
+				SourceIP:   "10.0.0.1",
+				Timestamp:  ts,
+			},
+		},
+	}, resp)
+}
+
+func TestAuditServer_ListAuditEvents_RequiresAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContextWithRole(svcutils.MemberRole)
+
+	as := &controllers.AuditServer{AuditClient: mockClients.MockAudit}
+	_, err := as.ListAuditEvents(ctx, &cloudpb.ListAuditEventsRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestAuditServer_RecordsSourceIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		md         metadata.MD
+		hops       int
+		expectedIP string
+	}{
+		{
+			name:       "forwarded",
+			md:         metadata.Pairs("x-forwarded-for", "1.2.3.4"),
+			hops:       1,
+			expectedIP: "1.2.3.4",
+		},
+		{
+			name:       "spoofed forwarded",
+			md:         metadata.Pairs("x-forwarded-for", "6.6.6.6, 1.2.3.4"),
+			hops:       1,
+			expectedIP: "1.2.3.4",
+		},
+		{
+			name:       "multiple proxies",
+			md:         metadata.Pairs("x-forwarded-for", "6.6.6.6, 1.2.3.4, 10.0.0.2"),
+			hops:       2,
+			expectedIP: "1.2.3.4",
+		},
+		{
+			name:       "fewer entries than hops",
+			md:         metadata.Pairs("x-forwarded-for", "1.2.3.4"),
+			hops:       2,
+			expectedIP: "1.2.3.4",
+		},
+		{
+			name:       "no trusted proxies",
+			md:         metadata.Pairs("x-forwarded-for", "1.2.3.4"),
+			hops:       0,
+			expectedIP: "10.0.0.3",
+		},
+		{
+			name:       "peer",
+			hops:       1,
+			expectedIP: "10.0.0.3",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			viper.Set("trusted_proxy_hops", tc.hops)
+			defer viper.Set("trusted_proxy_hops", nil)
+
+			_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+			defer cleanup()
+			ctx := CreateTestContext()
+			ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.3"), Port: 5000}})
+			if tc.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tc.md)
+			}
+
+			mockClients.MockVzDeployKey.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(&types.Empty{}, nil)
+			mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), &profilepb.AuditEvent{
+				OrgID:      utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+				ActorID:    utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9"),
+				ActorType:  "user",
+				Action:     "deploy_key.delete",
+				TargetType: "deploy_key",
+				TargetID:   "7ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				SourceIP:   tc.expectedIP,
+			}).Return(&types.Empty{}, nil)
+
+			vzDeployKeyServer := &controllers.VizierDeploymentKeyServer{
+				VzDeploymentKey: mockClients.MockVzDeployKey,
+				AuditClient:     mockClients.MockAudit,
+			}
+			_, err := vzDeployKeyServer.Delete(ctx, utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8"))
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/graph-gophers/graphql-go"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/utils"
+)
+
+// AuditEventResolver resolves a single audit event.
+type AuditEventResolver struct {
+	event *cloudpb.AuditEvent
+}
+
+// ID returns the ID of the event.
+func (a *AuditEventResolver) ID() graphql.ID {
+	return graphql.ID(utils.UUIDFromProtoOrNil(a.event.ID).String())
+}
+
+// ActorID returns the ID of the user that performed the action, if known.
+func (a *AuditEventResolver) ActorID() *graphql.ID {
+	actorID := utils.UUIDFromProtoOrNil(a.event.ActorID)
+	if actorID == uuid.Nil {
+		return nil
+	}
+	id := graphql.ID(actorID.String())
+	return &id
+}
+
+// ActorType returns the type of credentials used to perform the action.
+func (a *AuditEventResolver) ActorType() string {
+	return a.event.ActorType
+}
+
+// Action returns the action that was performed.
+func (a *AuditEventResolver) Action() string {
+	return a.event.Action
+}
+
+// TargetType returns the kind of resource that was acted on.
+func (a *AuditEventResolver) TargetType() string {
+	return a.event.TargetType
+}
+
+// TargetID returns the ID of the resource that was acted on.
+func (a *AuditEventResolver) TargetID() string {
+	return a.event.TargetID
+}
+
+// Before returns a summary of the target before the action.
+func (a *AuditEventResolver) Before() string {
+	return a.event.Before
+}
+
+// After returns a summary of the target after the action.
+func (a *AuditEventResolver) After() string {
+	return a.event.After
+}
+
+// SourceIP returns the IP of the client that performed the action.
+func (a *AuditEventResolver) SourceIP() string {
+	return a.event.SourceIP
+}
+
+// TimestampMs returns the time at which the action was performed.
+func (a *AuditEventResolver) TimestampMs() float64 {
+	if a.event.Timestamp == nil {
+		return 0
+	}
+	return float64(a.event.Timestamp.Seconds*NanosPerSecond+int64(a.event.Timestamp.Nanos)) / 1e6
+}
+
+type auditEventsArgs struct {
+	ActorID  *graphql.ID
+	Action   *string
+	TargetID *string
+	BeforeMs *float64
+	Limit    *int32
+}
+
+// AuditEvents lists the audit events for the user's org.
+func (q *QueryResolver) AuditEvents(ctx context.Context, args *auditEventsArgs) ([]*AuditEventResolver, error) {
+	req := &cloudpb.ListAuditEventsRequest{}
+	if args.ActorID != nil {
+		req.ActorID = utils.ProtoFromUUIDStrOrNil(string(*args.ActorID))
+	}
+	if args.Action != nil {
+		req.Action = *args.Action
+	}
+	if args.TargetID != nil {
+		req.TargetID = *args.TargetID
+	}
+	if args.BeforeMs != nil {
+		ns := int64(*args.BeforeMs * 1e6)
+		req.Before = &types.Timestamp{Seconds: ns / NanosPerSecond, Nanos: int32(ns % NanosPerSecond)}
+	}
+	if args.Limit != nil {
+		req.Limit = int64(*args.Limit)
+	}
+
+	resp, err := q.Env.AuditServer.ListAuditEvents(ctx, req)
+	if err != nil {
+		return nil, rpcErrorHelper(err)
+	}
+
+	events := make([]*AuditEventResolver, len(resp.Events))
+	for i, e := range resp.Events {
+		events[i] = &AuditEventResolver{event: e}
+	}
+	return events, nil
+}
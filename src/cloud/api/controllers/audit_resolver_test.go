@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/mock/gomock"
+	"github.com/graph-gophers/graphql-go/gqltesting"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/cloud/api/controllers/testutils"
+	"px.dev/pixie/src/utils"
+)
+
+func TestAuditEvents(t *testing.T) {
+	gqlEnv, mockClients, cleanup := testutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockAudit.EXPECT().ListAuditEvents(gomock.Any(), &cloudpb.ListAuditEventsRequest{
+		Action: "user.remove",
+		Before: &types.Timestamp{Seconds: 1600000000, Nanos: 500000000},
+		Limit:  20,
+	}).Return(&cloudpb.ListAuditEventsResponse{
+		Events: []*cloudpb.AuditEvent{
+			{
+				ID:         utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+				ActorID:    utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9"),
+				ActorType:  "user",
+				Action:     "user.remove",
+				TargetType: "user",
+				TargetID:   "6ba7b810-9dad-11d1-80b4-00c04fd43000",
+				Before:     "email: test@test.com, role: OR_MEMBER",
+				SourceIP:   "1.2.3.4",
+				Timestamp:  &types.Timestamp{Seconds: 1599999999},
+			},
+			{
+				ID:         utils.ProtoFromUUIDStrOrNil("8ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+				ActorType:  "api_key",
+				Action:     "user.remove",
+				TargetType: "user",
+				TargetID:   "6ba7b810-9dad-11d1-80b4-00c04fd43001",
+				Timestamp:  &types.Timestamp{Seconds: 1599999998},
+			},
+		},
+	}, nil)
+
+	gqlSchema := LoadSchema(gqlEnv)
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:  gqlSchema,
+			Context: ctx,
+			Query: `
+				query {
+					auditEvents(action: "user.remove", beforeMs: 1.6000000005e12, limit: 20) {
+						id
+						actorID
+						actorType
+						action
+						targetType
+						targetID
+						before
+						after
+						sourceIP
+						timestampMs
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"auditEvents": [
+						{
+							"id": "7ba7b810-9dad-11d1-80b4-00c04fd430c8",
+							"actorID": "6ba7b810-9dad-11d1-80b4-00c04fd430c9",
+							"actorType": "user",
+							"action": "user.remove",
+							"targetType": "user",
+							"targetID": "6ba7b810-9dad-11d1-80b4-00c04fd43000",
+							"before": "email: test@test.com, role: OR_MEMBER",
+							"after": "",
+							"sourceIP": "1.2.3.4",
+							"timestampMs": 1599999999000
+						},
+						{
+							"id": "8ba7b810-9dad-11d1-80b4-00c04fd430c8",
+							"actorID": null,
+							"actorType": "api_key",
+							"action": "user.remove",
+							"targetType": "user",
+							"targetID": "6ba7b810-9dad-11d1-80b4-00c04fd43001",
+							"before": "",
+							"after": "",
+							"sourceIP": "",
+							"timestampMs": 1599999998000
+						}
+					]
+				}
+			`,
+		},
+	})
+}
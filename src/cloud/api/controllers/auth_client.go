@@ -60,3 +60,18 @@ func NewAPIKeyClient() (authpb.APIKeyServiceClient, error) {
 
 	return authpb.NewAPIKeyServiceClient(authChannel), nil
 }
+
+// NewDeviceAuthClient creates a new device auth client.
+func NewDeviceAuthClient() (authpb.DeviceAuthServiceClient, error) {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	authChannel, err := grpc.Dial(viper.GetString("auth_service"), dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return authpb.NewDeviceAuthServiceClient(authChannel), nil
+}
@@ -22,6 +22,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 
 	"github.com/spf13/viper"
 	"google.golang.org/grpc/metadata"
@@ -32,17 +33,25 @@ import (
 
 // AuthServer logs users
 type AuthServer struct {
-	AuthClient authpb.AuthServiceClient
+	AuthClient       authpb.AuthServiceClient
+	DeviceAuthClient authpb.DeviceAuthServiceClient
+}
+
+func serviceAuthContext(ctx context.Context) (context.Context, error) {
+	serviceAuthToken, err := getServiceCredentials(viper.GetString("jwt_signing_key"))
+	if err != nil {
+		return nil, err
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization",
+		fmt.Sprintf("bearer %s", serviceAuthToken)), nil
 }
 
 // Login logs the user in by taking an access token from the auth provider, or using an API key.
 func (a *AuthServer) Login(ctx context.Context, req *cloudpb.LoginRequest) (*cloudpb.LoginReply, error) {
-	serviceAuthToken, err := getServiceCredentials(viper.GetString("jwt_signing_key"))
+	aCtx, err := serviceAuthContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	aCtx := metadata.AppendToOutgoingContext(ctx, "authorization",
-		fmt.Sprintf("bearer %s", serviceAuthToken))
 
 	var token string
 	var expiresAt int64
@@ -80,3 +89,76 @@ func (a *AuthServer) Login(ctx context.Context, req *cloudpb.LoginRequest) (*clo
 		ExpiresAt: expiresAt,
 	}, nil
 }
+
+// CreateDeviceCode starts a device authorization flow. The user approves the request by entering the
+// returned user code in the UI.
+func (a *AuthServer) CreateDeviceCode(ctx context.Context, req *cloudpb.CreateDeviceCodeRequest) (*cloudpb.CreateDeviceCodeResponse, error) {
+	aCtx, err := serviceAuthContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.DeviceAuthClient.CreateDeviceCode(aCtx, &authpb.CreateDeviceCodeRequest{
+		ClientName: req.ClientName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	verificationURI := url.URL{
+		Scheme: "https",
+		Host:   fmt.Sprintf("work.%s", viper.GetString("domain_name")),
+		Path:   "/device",
+	}
+	verificationURIComplete := verificationURI
+	verificationURIComplete.RawQuery = url.Values{"user_code": []string{resp.UserCode}}.Encode()
+
+	return &cloudpb.CreateDeviceCodeResponse{
+		DeviceCode:              resp.DeviceCode,
+		UserCode:                resp.UserCode,
+		VerificationURI:         verificationURI.String(),
+		VerificationURIComplete: verificationURIComplete.String(),
+		ExpiresAt:               resp.ExpiresAt,
+		Interval:                resp.Interval,
+	}, nil
+}
+
+// ApproveDeviceCode approves or denies a device authorization request as the logged in user.
+func (a *AuthServer) ApproveDeviceCode(ctx context.Context, req *cloudpb.ApproveDeviceCodeRequest) (*cloudpb.ApproveDeviceCodeResponse, error) {
+	ctx, err := contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.DeviceAuthClient.ApproveDeviceCode(ctx, &authpb.ApproveDeviceCodeRequest{
+		UserCode: req.UserCode,
+		Approved: req.Approved,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cloudpb.ApproveDeviceCodeResponse{
+		ClientName: resp.ClientName,
+	}, nil
+}
+
+// PollDeviceCode returns the state of a device authorization request, including the refresh token
+// once the request is approved.
+func (a *AuthServer) PollDeviceCode(ctx context.Context, req *cloudpb.PollDeviceCodeRequest) (*cloudpb.PollDeviceCodeResponse, error) {
+	aCtx, err := serviceAuthContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.DeviceAuthClient.PollDeviceCode(aCtx, &authpb.PollDeviceCodeRequest{
+		DeviceCode: req.DeviceCode,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &cloudpb.PollDeviceCodeResponse{
+		Status:    cloudpb.DeviceCodeStatus(resp.Status),
+		Token:     resp.Token,
+		ExpiresAt: resp.ExpiresAt,
+	}, nil
+}
@@ -49,7 +49,7 @@ func TestAuthServer_LoginAccessToken(t *testing.T) {
 			ExpiresAt: 10,
 		}, nil)
 
-	authServer := &controllers.AuthServer{mockClients.MockAuth}
+	authServer := &controllers.AuthServer{AuthClient: mockClients.MockAuth}
 
 	resp, err := authServer.Login(ctx, &cloudpb.LoginRequest{
 		AccessToken: "test-token",
@@ -81,7 +81,7 @@ func TestAuthServer_LoginAPIKey(t *testing.T) {
 			ExpiresAt: 10,
 		}, nil)
 
-	authServer := &controllers.AuthServer{mockClients.MockAuth}
+	authServer := &controllers.AuthServer{AuthClient: mockClients.MockAuth}
 
 	resp, err := authServer.Login(ctx, &cloudpb.LoginRequest{})
 
@@ -99,9 +99,105 @@ func TestAuthServer_UnAuthorized(t *testing.T) {
 	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
 	defer cleanup()
 
-	authServer := &controllers.AuthServer{mockClients.MockAuth}
+	authServer := &controllers.AuthServer{AuthClient: mockClients.MockAuth}
 
 	_, err := authServer.Login(context.Background(), &cloudpb.LoginRequest{})
 
 	require.Error(t, err)
 }
+
+func TestAuthServer_CreateDeviceCode(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+
+	mockClients.MockDeviceAuth.EXPECT().CreateDeviceCode(gomock.Any(), &authpb.CreateDeviceCodeRequest{
+		ClientName: "px",
+	}).
+		Return(&authpb.CreateDeviceCodeResponse{
+			DeviceCode: "device-code",
+			UserCode:   "BCDF-GHJK",
+			ExpiresAt:  10,
+			Interval:   5,
+		}, nil)
+
+	authServer := &controllers.AuthServer{DeviceAuthClient: mockClients.MockDeviceAuth}
+
+	resp, err := authServer.CreateDeviceCode(context.Background(), &cloudpb.CreateDeviceCodeRequest{
+		ClientName: "px",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.CreateDeviceCodeResponse{
+		DeviceCode:              "device-code",
+		UserCode:                "BCDF-GHJK",
+		VerificationURI:         "https://work.withpixie.ai/device",
+		VerificationURIComplete: "https://work.withpixie.ai/device?user_code=BCDF-GHJK",
+		ExpiresAt:               10,
+		Interval:                5,
+	}, resp)
+}
+
+func TestAuthServer_ApproveDeviceCode(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockDeviceAuth.EXPECT().ApproveDeviceCode(gomock.Any(), &authpb.ApproveDeviceCodeRequest{
+		UserCode: "BCDF-GHJK",
+		Approved: true,
+	}).
+		Return(&authpb.ApproveDeviceCodeResponse{
+			ClientName: "px",
+		}, nil)
+
+	authServer := &controllers.AuthServer{DeviceAuthClient: mockClients.MockDeviceAuth}
+
+	resp, err := authServer.ApproveDeviceCode(ctx, &cloudpb.ApproveDeviceCodeRequest{
+		UserCode: "BCDF-GHJK",
+		Approved: true,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "px", resp.ClientName)
+}
+
+func TestAuthServer_ApproveDeviceCode_Unauthenticated(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+
+	authServer := &controllers.AuthServer{DeviceAuthClient: mockClients.MockDeviceAuth}
+
+	_, err := authServer.ApproveDeviceCode(context.Background(), &cloudpb.ApproveDeviceCodeRequest{
+		UserCode: "BCDF-GHJK",
+		Approved: true,
+	})
+
+	require.Error(t, err)
+}
+
+func TestAuthServer_PollDeviceCode(t *testing.T) {
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+
+	mockClients.MockDeviceAuth.EXPECT().PollDeviceCode(gomock.Any(), &authpb.PollDeviceCodeRequest{
+		DeviceCode: "device-code",
+	}).
+		Return(&authpb.PollDeviceCodeResponse{
+			Status:    authpb.DCS_APPROVED,
+			Token:     "auth-token",
+			ExpiresAt: 10,
+		}, nil)
+
+	authServer := &controllers.AuthServer{DeviceAuthClient: mockClients.MockDeviceAuth}
+
+	resp, err := authServer.PollDeviceCode(context.Background(), &cloudpb.PollDeviceCodeRequest{
+		DeviceCode: "device-code",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.PollDeviceCodeResponse{
+		Status:    cloudpb.DCS_APPROVED,
+		Token:     "auth-token",
+		ExpiresAt: 10,
+	}, resp)
+}
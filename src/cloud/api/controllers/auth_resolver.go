@@ -0,0 +1,43 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+)
+
+type approveDeviceCodeArgs struct {
+	UserCode string
+	Approved bool
+}
+
+// ApproveDeviceCode approves or denies the login request of a device as the current user, and returns
+// the name of the device's client.
+func (q *QueryResolver) ApproveDeviceCode(ctx context.Context, args *approveDeviceCodeArgs) (string, error) {
+	resp, err := q.Env.AuthServer.ApproveDeviceCode(ctx, &cloudpb.ApproveDeviceCodeRequest{
+		UserCode: args.UserCode,
+		Approved: args.Approved,
+	})
+	if err != nil {
+		return "", rpcErrorHelper(err)
+	}
+	return resp.ClientName, nil
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/graph-gophers/graphql-go/gqltesting"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/cloud/api/controllers/testutils"
+)
+
+func TestApproveDeviceCode(t *testing.T) {
+	gqlEnv, mockClients, cleanup := testutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockAuth.EXPECT().ApproveDeviceCode(gomock.Any(), &cloudpb.ApproveDeviceCodeRequest{
+		UserCode: "BCDF-GHJK",
+		Approved: true,
+	}).Return(&cloudpb.ApproveDeviceCodeResponse{
+		ClientName: "Pixie CLI on my-host",
+	}, nil)
+
+	gqlSchema := LoadSchema(gqlEnv)
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:  gqlSchema,
+			Context: ctx,
+			Query: `
+				mutation {
+					ApproveDeviceCode(userCode: "BCDF-GHJK", approved: true)
+				}
+			`,
+			ExpectedResult: `
+				{
+					"ApproveDeviceCode": "Pixie CLI on my-host"
+				}
+			`,
+		},
+	})
+}
@@ -32,21 +32,35 @@ var actionToProtoMap = map[string]cloudpb.AutocompleteActionType{
 }
 
 var protoToKindMap = map[cloudpb.AutocompleteEntityKind]string{
-	cloudpb.AEK_UNKNOWN:   "AEK_UNKNOWN",
-	cloudpb.AEK_POD:       "AEK_POD",
-	cloudpb.AEK_SVC:       "AEK_SVC",
-	cloudpb.AEK_SCRIPT:    "AEK_SCRIPT",
-	cloudpb.AEK_NAMESPACE: "AEK_NAMESPACE",
-	cloudpb.AEK_NODE:      "AEK_NODE",
+	cloudpb.AEK_UNKNOWN:     "AEK_UNKNOWN",
+	cloudpb.AEK_POD:         "AEK_POD",
+	cloudpb.AEK_SVC:         "AEK_SVC",
+	cloudpb.AEK_SCRIPT:      "AEK_SCRIPT",
+	cloudpb.AEK_NAMESPACE:   "AEK_NAMESPACE",
+	cloudpb.AEK_NODE:        "AEK_NODE",
+	cloudpb.AEK_CONTAINER:   "AEK_CONTAINER",
+	cloudpb.AEK_DEPLOYMENT:  "AEK_DEPLOYMENT",
+	cloudpb.AEK_REPLICASET:  "AEK_REPLICASET",
+	cloudpb.AEK_STATEFULSET: "AEK_STATEFULSET",
+	cloudpb.AEK_DAEMONSET:   "AEK_DAEMONSET",
+	cloudpb.AEK_TABLE:       "AEK_TABLE",
+	cloudpb.AEK_CRON_SCRIPT: "AEK_CRON_SCRIPT",
 }
 
 var kindToProtoMap = map[string]cloudpb.AutocompleteEntityKind{
-	"AEK_UNKNOWN":   cloudpb.AEK_UNKNOWN,
-	"AEK_POD":       cloudpb.AEK_POD,
-	"AEK_SVC":       cloudpb.AEK_SVC,
-	"AEK_SCRIPT":    cloudpb.AEK_SCRIPT,
-	"AEK_NAMESPACE": cloudpb.AEK_NAMESPACE,
-	"AEK_NODE":      cloudpb.AEK_NODE,
+	"AEK_UNKNOWN":     cloudpb.AEK_UNKNOWN,
+	"AEK_POD":         cloudpb.AEK_POD,
+	"AEK_SVC":         cloudpb.AEK_SVC,
+	"AEK_SCRIPT":      cloudpb.AEK_SCRIPT,
+	"AEK_NAMESPACE":   cloudpb.AEK_NAMESPACE,
+	"AEK_NODE":        cloudpb.AEK_NODE,
+	"AEK_CONTAINER":   cloudpb.AEK_CONTAINER,
+	"AEK_DEPLOYMENT":  cloudpb.AEK_DEPLOYMENT,
+	"AEK_REPLICASET":  cloudpb.AEK_REPLICASET,
+	"AEK_STATEFULSET": cloudpb.AEK_STATEFULSET,
+	"AEK_DAEMONSET":   cloudpb.AEK_DAEMONSET,
+	"AEK_TABLE":       cloudpb.AEK_TABLE,
+	"AEK_CRON_SCRIPT": cloudpb.AEK_CRON_SCRIPT,
 }
 
 var protoToStateMap = map[cloudpb.AutocompleteEntityState]string{
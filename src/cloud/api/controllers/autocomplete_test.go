@@ -65,14 +65,14 @@ func TestAutocompleteService_Autocomplete(t *testing.T) {
 						OrgID:        orgID,
 						ClusterUID:   "test",
 						Input:        "px/svc_info",
-						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT},
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
 					},
 					{
 						OrgID:        orgID,
 						ClusterUID:   "test",
 						Input:        "pl/test",
-						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT},
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
 					},
 				},
@@ -161,6 +161,25 @@ type ClusterInfoResolver struct {
 	NumInstrumentedNodes          int32
 	PreviousStatus                *string
 	PreviousStatusTimeMs          *float64
+	Labels                        []ClusterLabelResolver
+	Groups                        []string
+}
+
+// ClusterLabelResolver resolves a label attached to a cluster.
+type ClusterLabelResolver struct {
+	Key   string
+	Value string
+}
+
+func labelsToResolvers(labels map[string]string) []ClusterLabelResolver {
+	res := make([]ClusterLabelResolver, 0, len(labels))
+	for k, v := range labels {
+		res = append(res, ClusterLabelResolver{Key: k, Value: v})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
+	})
+	return res
 }
 
 // ID returns cluster ID.
@@ -207,6 +226,11 @@ func clusterInfoToResolver(cluster *cloudpb.ClusterInfo) (*ClusterInfoResolver,
 		UnhealthyDataPlanePodStatuses: mapPodStatusArray(cluster.UnhealthyDataPlanePodStatuses),
 		NumNodes:                      cluster.NumNodes,
 		NumInstrumentedNodes:          cluster.NumInstrumentedNodes,
+		Labels:                        labelsToResolvers(cluster.Labels),
+		Groups:                        cluster.Groups,
+	}
+	if resolver.Groups == nil {
+		resolver.Groups = []string{}
 	}
 
 	if cluster.PreviousStatusTime != nil {
@@ -219,10 +243,22 @@ func clusterInfoToResolver(cluster *cloudpb.ClusterInfo) (*ClusterInfoResolver,
 	return resolver, nil
 }
 
-// Clusters lists all of the clusters.
-func (q *QueryResolver) Clusters(ctx context.Context) ([]*ClusterInfoResolver, error) {
+type clustersArgs struct {
+	LabelSelector *string
+	Group         *string
+}
+
+// Clusters lists all of the clusters, optionally filtered by a label selector or cluster group.
+func (q *QueryResolver) Clusters(ctx context.Context, args *clustersArgs) ([]*ClusterInfoResolver, error) {
 	grpcAPI := q.Env.VizierClusterInfo
-	resp, err := grpcAPI.GetClusterInfo(ctx, &cloudpb.GetClusterInfoRequest{})
+	req := &cloudpb.GetClusterInfoRequest{}
+	if args != nil && args.LabelSelector != nil {
+		req.LabelSelector = *args.LabelSelector
+	}
+	if args != nil && args.Group != nil {
+		req.Group = *args.Group
+	}
+	resp, err := grpcAPI.GetClusterInfo(ctx, req)
 	if err != nil {
 		return nil, rpcErrorHelper(err)
 	}
@@ -322,6 +358,114 @@ func (q *QueryResolver) UpdateVizierConfig(ctx context.Context, args *updateVizi
 	return clusterInfoToResolver(res.Clusters[0])
 }
 
+type clusterLabelInput struct {
+	Key   string
+	Value string
+}
+
+type updateClusterLabelsArgs struct {
+	ClusterID graphql.ID
+	Labels    []clusterLabelInput
+}
+
+// UpdateClusterLabels replaces the labels attached to the input cluster.
+func (q *QueryResolver) UpdateClusterLabels(ctx context.Context, args *updateClusterLabelsArgs) (*ClusterInfoResolver, error) {
+	grpcAPI := q.Env.VizierClusterInfo
+
+	clusterID := utils.ProtoFromUUIDStrOrNil(string(args.ClusterID))
+	labels := make(map[string]string, len(args.Labels))
+	for _, l := range args.Labels {
+		labels[l.Key] = l.Value
+	}
+
+	_, err := grpcAPI.UpdateClusterLabels(ctx, &cloudpb.UpdateClusterLabelsRequest{
+		ID:     clusterID,
+		Labels: labels,
+	})
+	if err != nil {
+		return nil, rpcErrorHelper(err)
+	}
+
+	return q.Cluster(ctx, &clusterArgs{ID: args.ClusterID})
+}
+
+// ClusterGroupResolver resolves a named group of clusters.
+type ClusterGroupResolver struct {
+	id       uuid.UUID
+	Name     string
+	Selector string
+}
+
+// ID returns the ID of the cluster group.
+func (g *ClusterGroupResolver) ID() graphql.ID {
+	return graphql.ID(g.id.String())
+}
+
+func clusterGroupToResolver(g *cloudpb.ClusterGroup) *ClusterGroupResolver {
+	return &ClusterGroupResolver{
+		id:       utils.UUIDFromProtoOrNil(g.ID),
+		Name:     g.Name,
+		Selector: g.Selector,
+	}
+}
+
+// ClusterGroups lists all of the cluster groups in the org.
+func (q *QueryResolver) ClusterGroups(ctx context.Context) ([]*ClusterGroupResolver, error) {
+	resp, err := q.Env.VizierClusterInfo.GetClusterGroups(ctx, &cloudpb.GetClusterGroupsRequest{})
+	if err != nil {
+		return nil, rpcErrorHelper(err)
+	}
+	res := make([]*ClusterGroupResolver, len(resp.Groups))
+	for i, g := range resp.Groups {
+		res[i] = clusterGroupToResolver(g)
+	}
+	return res, nil
+}
+
+type clusterGroupArgs struct {
+	Name     string
+	Selector string
+}
+
+// CreateClusterGroup creates a new cluster group.
+func (q *QueryResolver) CreateClusterGroup(ctx context.Context, args *clusterGroupArgs) (*ClusterGroupResolver, error) {
+	g, err := q.Env.VizierClusterInfo.CreateClusterGroup(ctx, &cloudpb.CreateClusterGroupRequest{
+		Name:     args.Name,
+		Selector: args.Selector,
+	})
+	if err != nil {
+		return nil, rpcErrorHelper(err)
+	}
+	return clusterGroupToResolver(g), nil
+}
+
+// UpdateClusterGroup updates the selector of a cluster group.
+func (q *QueryResolver) UpdateClusterGroup(ctx context.Context, args *clusterGroupArgs) (*ClusterGroupResolver, error) {
+	g, err := q.Env.VizierClusterInfo.UpdateClusterGroup(ctx, &cloudpb.UpdateClusterGroupRequest{
+		Name:     args.Name,
+		Selector: args.Selector,
+	})
+	if err != nil {
+		return nil, rpcErrorHelper(err)
+	}
+	return clusterGroupToResolver(g), nil
+}
+
+type deleteClusterGroupArgs struct {
+	Name string
+}
+
+// DeleteClusterGroup deletes a cluster group.
+func (q *QueryResolver) DeleteClusterGroup(ctx context.Context, args *deleteClusterGroupArgs) (bool, error) {
+	_, err := q.Env.VizierClusterInfo.DeleteClusterGroup(ctx, &cloudpb.DeleteClusterGroupRequest{
+		Name: args.Name,
+	})
+	if err != nil {
+		return false, rpcErrorHelper(err)
+	}
+	return true, nil
+}
+
 // ClusterConnectionInfoResolver is the resolver responsible for cluster connection info.
 type ClusterConnectionInfoResolver struct {
 	IPAddress string
@@ -39,19 +39,25 @@ import (
 
 func CreateTestContext() context.Context {
 	sCtx := authcontext.New()
-	sCtx.Claims = svcutils.GenerateJWTForUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "test@test.com", time.Now(), "pixie")
+	sCtx.Claims = svcutils.GenerateJWTForUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "test@test.com", time.Now(), "pixie", &svcutils.UserRoles{OrgRole: svcutils.OwnerRole})
 	return authcontext.NewContext(context.Background(), sCtx)
 }
 
 func CreateTestContextNoOrg() context.Context {
 	sCtx := authcontext.New()
-	sCtx.Claims = svcutils.GenerateJWTForUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "00000000-0000-0000-0000-000000000000", "email-password@fancy.com", time.Now(), "pixie")
+	sCtx.Claims = svcutils.GenerateJWTForUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "00000000-0000-0000-0000-000000000000", "email-password@fancy.com", time.Now(), "pixie", &svcutils.UserRoles{OrgRole: svcutils.OwnerRole})
+	return authcontext.NewContext(context.Background(), sCtx)
+}
+
+func CreateTestContextWithRole(role svcutils.OrgRole) context.Context {
+	sCtx := authcontext.New()
+	sCtx.Claims = svcutils.GenerateJWTForUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "test@test.com", time.Now(), "pixie", &svcutils.UserRoles{OrgRole: role})
 	return authcontext.NewContext(context.Background(), sCtx)
 }
 
 func CreateAPIUserTestContext() context.Context {
 	sCtx := authcontext.New()
-	sCtx.Claims = svcutils.GenerateJWTForAPIUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", time.Now(), "pixie")
+	sCtx.Claims = svcutils.GenerateJWTForAPIUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", time.Now(), "pixie", &svcutils.UserRoles{OrgRole: svcutils.OwnerRole})
 	return authcontext.NewContext(context.Background(), sCtx)
 }
 
@@ -414,3 +420,246 @@ func TestUpdateClusterVizierConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestClustersWithLabelSelector(t *testing.T) {
+	gqlEnv, mockClients, cleanup := testutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVizierClusterInfo.EXPECT().
+		GetClusterInfo(gomock.Any(), &cloudpb.GetClusterInfoRequest{
+			LabelSelector: "env=prod",
+		}).
+		Return(&cloudpb.GetClusterInfoResponse{
+			Clusters: []*cloudpb.ClusterInfo{{
+				ID:     utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+				Status: cloudpb.CS_HEALTHY,
+				Config: &cloudpb.VizierConfig{},
+				Labels: map[string]string{"region": "eu", "env": "prod"},
+				Groups: []string{"prod"},
+			}},
+		}, nil)
+
+	gqlSchema := LoadSchema(gqlEnv)
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:  gqlSchema,
+			Context: ctx,
+			Query: `
+				query {
+					clusters(labelSelector: "env=prod") {
+						id
+						labels {
+							key
+							value
+						}
+						groups
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"clusters": [{
+						"id": "7ba7b810-9dad-11d1-80b4-00c04fd430c8",
+						"labels": [
+							{"key": "env", "value": "prod"},
+							{"key": "region", "value": "eu"}
+						],
+						"groups": ["prod"]
+					}]
+				}
+			`,
+		},
+	})
+}
+
+func TestClustersWithGroup(t *testing.T) {
+	gqlEnv, mockClients, cleanup := testutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVizierClusterInfo.EXPECT().
+		GetClusterInfo(gomock.Any(), &cloudpb.GetClusterInfoRequest{
+			Group: "prod",
+		}).
+		Return(&cloudpb.GetClusterInfoResponse{
+			Clusters: []*cloudpb.ClusterInfo{{
+				ID:     utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+				Status: cloudpb.CS_HEALTHY,
+				Config: &cloudpb.VizierConfig{},
+				Labels: map[string]string{"region": "eu", "env": "prod"},
+				Groups: []string{"prod"},
+			}},
+		}, nil)
+
+	gqlSchema := LoadSchema(gqlEnv)
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:  gqlSchema,
+			Context: ctx,
+			Query: `
+				query {
+					clusters(group: "prod") {
+						id
+						labels {
+							key
+							value
+						}
+						groups
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"clusters": [{
+						"id": "7ba7b810-9dad-11d1-80b4-00c04fd430c8",
+						"labels": [
+							{"key": "env", "value": "prod"},
+							{"key": "region", "value": "eu"}
+						],
+						"groups": ["prod"]
+					}]
+				}
+			`,
+		},
+	})
+}
+
+func TestUpdateClusterLabels(t *testing.T) {
+	gqlEnv, mockClients, cleanup := testutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	clusterID := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	mockClients.MockVizierClusterInfo.EXPECT().
+		UpdateClusterLabels(gomock.Any(), &cloudpb.UpdateClusterLabelsRequest{
+			ID:     clusterID,
+			Labels: map[string]string{"env": "prod", "canary": ""},
+		}).
+		Return(&cloudpb.UpdateClusterLabelsResponse{}, nil)
+
+	mockClients.MockVizierClusterInfo.EXPECT().
+		GetClusterInfo(gomock.Any(), &cloudpb.GetClusterInfoRequest{
+			ID: clusterID,
+		}).
+		Return(&cloudpb.GetClusterInfoResponse{
+			Clusters: []*cloudpb.ClusterInfo{{
+				ID:     clusterID,
+				Status: cloudpb.CS_HEALTHY,
+				Config: &cloudpb.VizierConfig{},
+				Labels: map[string]string{"env": "prod", "canary": ""},
+			}},
+		}, nil)
+
+	gqlSchema := LoadSchema(gqlEnv)
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:  gqlSchema,
+			Context: ctx,
+			Query: `
+				mutation {
+					UpdateClusterLabels(clusterID: "7ba7b810-9dad-11d1-80b4-00c04fd430c8",
+						labels: [{key: "env", value: "prod"}, {key: "canary", value: ""}]) {
+						id
+						labels {
+							key
+							value
+						}
+						groups
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"UpdateClusterLabels": {
+						"id": "7ba7b810-9dad-11d1-80b4-00c04fd430c8",
+						"labels": [
+							{"key": "canary", "value": ""},
+							{"key": "env", "value": "prod"}
+						],
+						"groups": []
+					}
+				}
+			`,
+		},
+	})
+}
+
+func TestClusterGroups(t *testing.T) {
+	gqlEnv, mockClients, cleanup := testutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	groupID := utils.ProtoFromUUIDStrOrNil("8ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	mockClients.MockVizierClusterInfo.EXPECT().
+		GetClusterGroups(gomock.Any(), &cloudpb.GetClusterGroupsRequest{}).
+		Return(&cloudpb.GetClusterGroupsResponse{
+			Groups: []*cloudpb.ClusterGroup{{ID: groupID, Name: "prod", Selector: "env=prod"}},
+		}, nil)
+	mockClients.MockVizierClusterInfo.EXPECT().
+		CreateClusterGroup(gomock.Any(), &cloudpb.CreateClusterGroupRequest{Name: "eu", Selector: "region=eu"}).
+		Return(&cloudpb.ClusterGroup{ID: groupID, Name: "eu", Selector: "region=eu"}, nil)
+	mockClients.MockVizierClusterInfo.EXPECT().
+		DeleteClusterGroup(gomock.Any(), &cloudpb.DeleteClusterGroupRequest{Name: "eu"}).
+		Return(&cloudpb.DeleteClusterGroupResponse{}, nil)
+
+	gqlSchema := LoadSchema(gqlEnv)
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:  gqlSchema,
+			Context: ctx,
+			Query: `
+				query {
+					clusterGroups {
+						id
+						name
+						selector
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"clusterGroups": [{
+						"id": "8ba7b810-9dad-11d1-80b4-00c04fd430c8",
+						"name": "prod",
+						"selector": "env=prod"
+					}]
+				}
+			`,
+		},
+		{
+			Schema:  gqlSchema,
+			Context: ctx,
+			Query: `
+				mutation {
+					CreateClusterGroup(name: "eu", selector: "region=eu") {
+						name
+						selector
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"CreateClusterGroup": {
+						"name": "eu",
+						"selector": "region=eu"
+					}
+				}
+			`,
+		},
+		{
+			Schema:  gqlSchema,
+			Context: ctx,
+			Query: `
+				mutation {
+					DeleteClusterGroup(name: "eu")
+				}
+			`,
+			ExpectedResult: `
+				{
+					"DeleteClusterGroup": true
+				}
+			`,
+		},
+	})
+}
@@ -20,6 +20,7 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/gogo/protobuf/types"
 	"google.golang.org/grpc/codes"
@@ -28,14 +29,18 @@ import (
 	apiUtils "px.dev/pixie/src/api/go/pxapi/utils"
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/shared/rbac"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/services/authcontext"
+	srvutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 )
 
 // VizierDeploymentKeyServer is the server that implements the VizierDeploymentKeyManager gRPC service.
 type VizierDeploymentKeyServer struct {
 	VzDeploymentKey vzmgrpb.VZDeploymentKeyServiceClient
+	AuditClient     profilepb.AuditServiceClient
 }
 
 func deployKeyToCloudAPI(key *vzmgrpb.DeploymentKey) *cloudpb.DeploymentKey {
@@ -77,6 +82,9 @@ func (v *VizierDeploymentKeyServer) Create(ctx context.Context, req *cloudpb.Cre
 	if userID == nil {
 		return nil, status.Error(codes.Internal, "error parsing user ID as UUID")
 	}
+	if err := rbac.RequireOrgRole(ctx, srvutils.MemberRole); err != nil {
+		return nil, err
+	}
 	resp, err := v.VzDeploymentKey.Create(ctx, &vzmgrpb.CreateDeploymentKeyRequest{
 		Desc:   req.Desc,
 		OrgID:  orgID,
@@ -85,6 +93,12 @@ func (v *VizierDeploymentKeyServer) Create(ctx context.Context, req *cloudpb.Cre
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     "deploy_key.create",
+		TargetType: "deploy_key",
+		TargetID:   utils.UUIDFromProtoOrNil(resp.ID).String(),
+		After:      fmt.Sprintf("desc: %s", resp.Desc),
+	})
 	return deployKeyToCloudAPI(resp), nil
 }
 
@@ -160,13 +174,22 @@ func (v *VizierDeploymentKeyServer) Delete(ctx context.Context, uuid *uuidpb.UUI
 		return nil, status.Error(codes.Internal, "error parsing org ID as UUID")
 	}
 
-	if err != nil {
+	if err := rbac.RequireOrgRole(ctx, srvutils.AdminRole); err != nil {
 		return nil, err
 	}
-	return v.VzDeploymentKey.Delete(ctx, &vzmgrpb.DeleteDeploymentKeyRequest{
+	resp, err := v.VzDeploymentKey.Delete(ctx, &vzmgrpb.DeleteDeploymentKeyRequest{
 		OrgID: orgID,
 		ID:    uuid,
 	})
+	if err != nil {
+		return nil, err
+	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     "deploy_key.delete",
+		TargetType: "deploy_key",
+		TargetID:   utils.UUIDFromProtoOrNil(uuid).String(),
+	})
+	return resp, nil
 }
 
 // LookupDeploymentKey gets the complete API key information using just the Key.
@@ -42,6 +42,8 @@ type GraphQLEnv struct {
 	OrgServer             cloudpb.OrganizationServiceServer
 	UserServer            cloudpb.UserServiceServer
 	PluginServer          cloudpb.PluginServiceServer
+	AuditServer           cloudpb.AuditServiceServer
+	AuthServer            cloudpb.AuthServiceServer
 }
 
 // QueryResolver resolves queries for GQL.
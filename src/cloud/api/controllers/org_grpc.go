@@ -20,6 +20,7 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/types"
@@ -31,8 +32,10 @@ import (
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/auth/authpb"
 	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/shared/rbac"
 	"px.dev/pixie/src/shared/services/authcontext"
 	"px.dev/pixie/src/shared/services/events"
+	claimsutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 )
 
@@ -41,6 +44,7 @@ type OrganizationServiceServer struct {
 	ProfileServiceClient profilepb.ProfileServiceClient
 	AuthServiceClient    authpb.AuthServiceClient
 	OrgServiceClient     profilepb.OrgServiceClient
+	AuditClient          profilepb.AuditServiceClient
 }
 
 // InviteUser creates and returns an invite link for the org for the specified user info.
@@ -58,6 +62,9 @@ func (o *OrganizationServiceServer) InviteUser(ctx context.Context, externalReq
 	if orgIDPb == nil {
 		return nil, status.Errorf(codes.InvalidArgument, "Could not identify user's org")
 	}
+	if err := rbac.RequireOrgRole(ctx, claimsutils.AdminRole); err != nil {
+		return nil, err
+	}
 
 	internalReq := &authpb.InviteUserRequest{
 		OrgID:     orgIDPb,
@@ -70,6 +77,11 @@ func (o *OrganizationServiceServer) InviteUser(ctx context.Context, externalReq
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, o.AuditClient, &profilepb.AuditEvent{
+		Action:     "user.invite",
+		TargetType: "user",
+		TargetID:   externalReq.Email,
+	})
 
 	return &cloudpb.InviteUserResponse{
 		Email:      externalReq.Email,
@@ -143,9 +155,11 @@ func (o *OrganizationServiceServer) CreateOrg(ctx context.Context, req *cloudpb.
 			Set("org_name", req.OrgName).
 			Set("org_id", utils.ProtoToUUIDStr(orgID)),
 	})
+	// The user creating the org becomes its owner.
 	_, err = o.ProfileServiceClient.UpdateUser(ctx, &profilepb.UpdateUserRequest{
 		ID:    utils.ProtoFromUUIDStrOrNil(sCtx.Claims.GetUserClaims().UserID),
 		OrgID: orgID,
+		Role:  profilepb.OR_OWNER,
 	})
 	if err != nil {
 		return nil, err
@@ -167,6 +181,9 @@ func (o *OrganizationServiceServer) UpdateOrg(ctx context.Context, req *cloudpb.
 	if uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID) != utils.UUIDFromProtoOrNil(req.ID) {
 		return nil, status.Errorf(codes.PermissionDenied, "User may only update their own org")
 	}
+	if err := rbac.RequireOrgRole(ctx, claimsutils.AdminRole); err != nil {
+		return nil, err
+	}
 	resp, err := o.OrgServiceClient.UpdateOrg(ctx, &profilepb.UpdateOrgRequest{
 		ID:              req.ID,
 		EnableApprovals: req.EnableApprovals,
@@ -174,6 +191,14 @@ func (o *OrganizationServiceServer) UpdateOrg(ctx context.Context, req *cloudpb.
 	if err != nil {
 		return nil, err
 	}
+	if req.EnableApprovals != nil {
+		recordAuditEvent(ctx, o.AuditClient, &profilepb.AuditEvent{
+			Action:     "org.update",
+			TargetType: "org",
+			TargetID:   utils.UUIDFromProtoOrNil(req.ID).String(),
+			After:      fmt.Sprintf("enable_approvals: %t", resp.EnableApprovals),
+		})
+	}
 
 	return &cloudpb.OrgInfo{
 		ID:              resp.ID,
@@ -210,15 +235,7 @@ func (o *OrganizationServiceServer) GetUsersInOrg(ctx context.Context, req *clou
 
 	userList := make([]*cloudpb.UserInfo, len(resp.Users))
 	for idx, user := range resp.Users {
-		userList[idx] = &cloudpb.UserInfo{
-			ID:             user.ID,
-			OrgID:          user.OrgID,
-			FirstName:      user.FirstName,
-			LastName:       user.LastName,
-			Email:          user.Email,
-			ProfilePicture: user.ProfilePicture,
-			IsApproved:     user.IsApproved,
-		}
+		userList[idx] = userInfoToCloudProto(user)
 	}
 
 	return &cloudpb.GetUsersInOrgResponse{
@@ -245,6 +262,16 @@ func (o *OrganizationServiceServer) RemoveUserFromOrg(ctx context.Context, req *
 	if uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID) != utils.UUIDFromProtoOrNil(userInfo.OrgID) {
 		return nil, status.Errorf(codes.PermissionDenied, "User may only remove users from their own org")
 	}
+	// Users may leave the org themselves, otherwise only admins may remove users and only owners may remove owners.
+	if uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().UserID) != utils.UUIDFromProtoOrNil(userInfo.ID) {
+		required := claimsutils.AdminRole
+		if userInfo.Role == profilepb.OR_OWNER {
+			required = claimsutils.OwnerRole
+		}
+		if err := rbac.RequireOrgRole(ctx, required); err != nil {
+			return nil, err
+		}
+	}
 
 	_, err = o.ProfileServiceClient.UpdateUser(ctx, &profilepb.UpdateUserRequest{
 		ID:    req.UserID,
@@ -253,6 +280,12 @@ func (o *OrganizationServiceServer) RemoveUserFromOrg(ctx context.Context, req *
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, o.AuditClient, &profilepb.AuditEvent{
+		Action:     "user.remove",
+		TargetType: "user",
+		TargetID:   utils.UUIDFromProtoOrNil(req.UserID).String(),
+		Before:     fmt.Sprintf("email: %s, role: %s", userInfo.Email, orgRoleToCloudProto(userInfo.Role)),
+	})
 
 	return &cloudpb.RemoveUserFromOrgResponse{Success: true}, nil
 }
@@ -271,6 +304,9 @@ func (o *OrganizationServiceServer) AddOrgIDEConfig(ctx context.Context, req *cl
 	if uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID) != utils.UUIDFromProtoOrNil(req.OrgID) {
 		return nil, status.Errorf(codes.PermissionDenied, "Could not add IDE config for org")
 	}
+	if err := rbac.RequireOrgRole(ctx, claimsutils.AdminRole); err != nil {
+		return nil, err
+	}
 
 	resp, err := o.OrgServiceClient.AddOrgIDEConfig(ctx, &profilepb.AddOrgIDEConfigRequest{
 		OrgID: req.OrgID,
@@ -282,6 +318,12 @@ func (o *OrganizationServiceServer) AddOrgIDEConfig(ctx context.Context, req *cl
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, o.AuditClient, &profilepb.AuditEvent{
+		Action:     "ide_config.add",
+		TargetType: "ide_config",
+		TargetID:   req.Config.IDEName,
+		After:      fmt.Sprintf("path: %s", req.Config.Path),
+	})
 
 	return &cloudpb.AddOrgIDEConfigResponse{
 		Config: &cloudpb.IDEConfig{
@@ -305,6 +347,9 @@ func (o *OrganizationServiceServer) DeleteOrgIDEConfig(ctx context.Context, req
 	if uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID) != utils.UUIDFromProtoOrNil(req.OrgID) {
 		return nil, status.Errorf(codes.PermissionDenied, "Could not delete IDE config for org")
 	}
+	if err := rbac.RequireOrgRole(ctx, claimsutils.AdminRole); err != nil {
+		return nil, err
+	}
 
 	_, err = o.OrgServiceClient.DeleteOrgIDEConfig(ctx, &profilepb.DeleteOrgIDEConfigRequest{
 		OrgID:   req.OrgID,
@@ -313,6 +358,11 @@ func (o *OrganizationServiceServer) DeleteOrgIDEConfig(ctx context.Context, req
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, o.AuditClient, &profilepb.AuditEvent{
+		Action:     "ide_config.delete",
+		TargetType: "ide_config",
+		TargetID:   req.IDEName,
+	})
 
 	return &cloudpb.DeleteOrgIDEConfigResponse{}, nil
 }
@@ -367,6 +417,9 @@ func (o *OrganizationServiceServer) CreateInviteToken(ctx context.Context, req *
 	if uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID) != utils.UUIDFromProtoOrNil(req.OrgID) {
 		return nil, status.Errorf(codes.PermissionDenied, "cannot create invite for org")
 	}
+	if err := rbac.RequireOrgRole(ctx, claimsutils.AdminRole); err != nil {
+		return nil, err
+	}
 
 	resp, err := o.OrgServiceClient.CreateInviteToken(ctx, &profilepb.CreateInviteTokenRequest{
 		OrgID: req.OrgID,
@@ -374,6 +427,11 @@ func (o *OrganizationServiceServer) CreateInviteToken(ctx context.Context, req *
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, o.AuditClient, &profilepb.AuditEvent{
+		Action:     "invite_token.create",
+		TargetType: "org",
+		TargetID:   utils.UUIDFromProtoOrNil(req.OrgID).String(),
+	})
 
 	return &cloudpb.InviteToken{SignedClaims: resp.SignedClaims}, nil
 }
@@ -392,8 +450,20 @@ func (o *OrganizationServiceServer) RevokeAllInviteTokens(ctx context.Context, r
 	if uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID) != utils.UUIDFromProtoOrNil(req) {
 		return nil, status.Errorf(codes.PermissionDenied, "cannot revoke invites for org")
 	}
+	if err := rbac.RequireOrgRole(ctx, claimsutils.AdminRole); err != nil {
+		return nil, err
+	}
 
-	return o.OrgServiceClient.RevokeAllInviteTokens(ctx, req)
+	resp, err := o.OrgServiceClient.RevokeAllInviteTokens(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	recordAuditEvent(ctx, o.AuditClient, &profilepb.AuditEvent{
+		Action:     "invite_token.revoke_all",
+		TargetType: "org",
+		TargetID:   utils.UUIDFromProtoOrNil(req).String(),
+	})
+	return resp, nil
 }
 
 // VerifyInviteToken verifies that the given invite JWT is still valid by performing expiration and
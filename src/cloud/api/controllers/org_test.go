@@ -74,7 +74,8 @@ func TestOrganizationServiceServer_InviteUser(t *testing.T) {
 					InviteLink: "withpixie.ai/invite&id=abcd",
 				}, nil)
 
-			os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+			os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, mockClients.MockAudit}
+			mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).Return(&types.Empty{}, nil)
 
 			resp, err := os.InviteUser(ctx, &cloudpb.InviteUserRequest{
 				Email:     "bobloblaw@lawblog.law",
@@ -97,7 +98,7 @@ func TestOrganizationServiceServer_CreateOrg_UserAlreadyInOrg(t *testing.T) {
 	defer cleanup()
 	ctx := CreateTestContext()
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, mockClients.MockAudit}
 
 	_, err := os.CreateOrg(ctx, &cloudpb.CreateOrgRequest{
 		OrgName: "new_org_name",
@@ -124,12 +125,13 @@ func TestOrganizationServiceServer_CreateOrg_UserWithNoOrg(t *testing.T) {
 	mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), &profilepb.UpdateUserRequest{
 		ID:    utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9"),
 		OrgID: orgID,
+		Role:  profilepb.OR_OWNER,
 	}).Return(&profilepb.UserInfo{
 		ID:    utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9"),
 		OrgID: orgID,
 	}, nil)
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, mockClients.MockAudit}
 
 	resp, err := os.CreateOrg(ctx, &cloudpb.CreateOrgRequest{
 		OrgName: "new_org_name",
@@ -147,7 +149,7 @@ func TestOrganizationServiceServer_CreateOrg_BadOrgName(t *testing.T) {
 	defer cleanup()
 	ctx := CreateTestContextNoOrg()
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, mockClients.MockAudit}
 
 	_, err := os.CreateOrg(ctx, &cloudpb.CreateOrgRequest{
 		OrgName: "a.b",
@@ -165,7 +167,7 @@ func TestOrganizationServiceServer_RemoveUserFromOrg(t *testing.T) {
 	defer cleanup()
 	ctx := CreateTestContext()
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, mockClients.MockAudit}
 
 	userID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd43000")
 	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
@@ -173,6 +175,8 @@ func TestOrganizationServiceServer_RemoveUserFromOrg(t *testing.T) {
 	mockClients.MockProfile.EXPECT().GetUser(gomock.Any(), userID).Return(&profilepb.UserInfo{
 		ID:    userID,
 		OrgID: orgID,
+		Email: "test@test.com",
+		Role:  profilepb.OR_MEMBER,
 	}, nil)
 
 	mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), &profilepb.UpdateUserRequest{
@@ -183,6 +187,16 @@ func TestOrganizationServiceServer_RemoveUserFromOrg(t *testing.T) {
 		OrgID: nil,
 	}, nil)
 
+	mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), &profilepb.AuditEvent{
+		OrgID:      orgID,
+		ActorID:    utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9"),
+		ActorType:  "user",
+		Action:     "user.remove",
+		TargetType: "user",
+		TargetID:   "6ba7b810-9dad-11d1-80b4-00c04fd43000",
+		Before:     "email: test@test.com, role: OR_MEMBER",
+	}).Return(&types.Empty{}, nil)
+
 	resp, err := os.RemoveUserFromOrg(ctx, &cloudpb.RemoveUserFromOrgRequest{
 		UserID: userID,
 	})
@@ -199,7 +213,7 @@ func TestOrganizationServiceServer_RemoveUserFromOrg_UserNotInOrg(t *testing.T)
 	defer cleanup()
 	ctx := CreateTestContext()
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, mockClients.MockAudit}
 
 	userID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd43010")
 	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430d0")
@@ -239,7 +253,8 @@ func TestOrganizationServiceServer_AddOrgIDEConfig(t *testing.T) {
 		},
 	}, nil)
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, mockClients.MockAudit}
+	mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).Return(&types.Empty{}, nil)
 
 	resp, err := os.AddOrgIDEConfig(ctx, &cloudpb.AddOrgIDEConfigRequest{
 		OrgID: utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
@@ -267,7 +282,8 @@ func TestOrganizationServiceServer_DeleteOrgIDEConfig(t *testing.T) {
 		IDEName: "test",
 	}).Return(&profilepb.DeleteOrgIDEConfigResponse{}, nil)
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, mockClients.MockAudit}
+	mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).Return(&types.Empty{}, nil)
 
 	resp, err := os.DeleteOrgIDEConfig(ctx, &cloudpb.DeleteOrgIDEConfigRequest{
 		OrgID:   utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
@@ -418,7 +434,9 @@ func TestOrganizationServiceServer_CorrectOrgPermissions(t *testing.T) {
 			defer cleanup()
 			ctx := CreateTestContext()
 
-			os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, &fakeOrg{}}
+			os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, &fakeOrg{}, mockClients.MockAudit}
+			// Only the mutations are audited.
+			mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).Return(&types.Empty{}, nil).AnyTimes()
 			// Incorrect org call.
 			err := test.funcCall(ctx, os, utils.ProtoFromUUIDStrOrNil("11111111-9dad-11d1-80b4-00c04fd430c8"))
 			require.Error(t, err)
@@ -453,7 +471,7 @@ func TestOrganizationServiceServer_GetOrgIDEConfigs(t *testing.T) {
 		},
 	}, nil)
 
-	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg}
+	os := &controllers.OrganizationServiceServer{mockClients.MockProfile, mockClients.MockAuth, mockClients.MockOrg, mockClients.MockAudit}
 
 	resp, err := os.GetOrgIDEConfigs(ctx, &cloudpb.GetOrgIDEConfigsRequest{
 		OrgID: utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
@@ -20,10 +20,16 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/shared/rbac"
 	"px.dev/pixie/src/shared/services/authcontext"
+	srvutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 )
 
@@ -31,6 +37,7 @@ import (
 type PluginServiceServer struct {
 	PluginServiceClient              pluginpb.PluginServiceClient
 	DataRetentionPluginServiceClient pluginpb.DataRetentionPluginServiceClient
+	AuditClient                      profilepb.AuditServiceClient
 }
 
 func kindCloudProtoToPluginProto(kind cloudpb.PluginKind) pluginpb.PluginKind {
@@ -149,6 +156,10 @@ func (p *PluginServiceServer) GetRetentionPluginInfo(ctx context.Context, req *c
 
 // UpdateRetentionPluginConfig updates the retention plugin config for a plugin.
 func (p *PluginServiceServer) UpdateRetentionPluginConfig(ctx context.Context, req *cloudpb.UpdateRetentionPluginConfigRequest) (*cloudpb.UpdateRetentionPluginConfigResponse, error) {
+	if err := rbac.RequireOrgRole(ctx, srvutils.AdminRole); err != nil {
+		return nil, err
+	}
+
 	sCtx, err := authcontext.FromContext(ctx)
 	if err != nil {
 		return nil, err
@@ -172,6 +183,12 @@ func (p *PluginServiceServer) UpdateRetentionPluginConfig(ctx context.Context, r
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, p.AuditClient, &profilepb.AuditEvent{
+		Action:     "retention_plugin.update_config",
+		TargetType: "plugin",
+		TargetID:   req.PluginId,
+		After:      retentionPluginConfigSummary(req),
+	})
 
 	return &cloudpb.UpdateRetentionPluginConfigResponse{}, nil
 }
@@ -200,14 +217,16 @@ func (p *PluginServiceServer) GetRetentionScripts(ctx context.Context, req *clou
 	scripts := make([]*cloudpb.RetentionScript, len(resp.Scripts))
 	for i, s := range resp.Scripts {
 		scripts[i] = &cloudpb.RetentionScript{
-			ScriptID:    s.ScriptID,
-			ScriptName:  s.ScriptName,
-			Description: s.Description,
-			FrequencyS:  s.FrequencyS,
-			ClusterIDs:  s.ClusterIDs,
-			PluginId:    s.PluginId,
-			Enabled:     s.Enabled,
-			IsPreset:    s.IsPreset,
+			ScriptID:        s.ScriptID,
+			ScriptName:      s.ScriptName,
+			Description:     s.Description,
+			FrequencyS:      s.FrequencyS,
+			ClusterIDs:      s.ClusterIDs,
+			ClusterSelector: s.ClusterSelector,
+			ClusterGroup:    s.ClusterGroup,
+			PluginId:        s.PluginId,
+			Enabled:         s.Enabled,
+			IsPreset:        s.IsPreset,
 		}
 	}
 	return &cloudpb.GetRetentionScriptsResponse{
@@ -240,14 +259,16 @@ func (p *PluginServiceServer) GetRetentionScript(ctx context.Context, req *cloud
 	scriptDetails := resp.Script.Script
 	return &cloudpb.GetRetentionScriptResponse{
 		Script: &cloudpb.RetentionScript{
-			ScriptID:    scriptDetails.ScriptID,
-			ScriptName:  scriptDetails.ScriptName,
-			Description: scriptDetails.Description,
-			FrequencyS:  scriptDetails.FrequencyS,
-			ClusterIDs:  scriptDetails.ClusterIDs,
-			PluginId:    scriptDetails.PluginId,
-			Enabled:     scriptDetails.Enabled,
-			IsPreset:    scriptDetails.IsPreset,
+			ScriptID:        scriptDetails.ScriptID,
+			ScriptName:      scriptDetails.ScriptName,
+			Description:     scriptDetails.Description,
+			FrequencyS:      scriptDetails.FrequencyS,
+			ClusterIDs:      scriptDetails.ClusterIDs,
+			ClusterSelector: scriptDetails.ClusterSelector,
+			ClusterGroup:    scriptDetails.ClusterGroup,
+			PluginId:        scriptDetails.PluginId,
+			Enabled:         scriptDetails.Enabled,
+			IsPreset:        scriptDetails.IsPreset,
 		},
 		Contents:  resp.Script.Contents,
 		ExportURL: resp.Script.ExportURL,
@@ -256,6 +277,10 @@ func (p *PluginServiceServer) GetRetentionScript(ctx context.Context, req *cloud
 
 // UpdateRetentionScript updates a specific retention script.
 func (p *PluginServiceServer) UpdateRetentionScript(ctx context.Context, req *cloudpb.UpdateRetentionScriptRequest) (*cloudpb.UpdateRetentionScriptResponse, error) {
+	if err := rbac.RequireOrgRole(ctx, srvutils.MemberRole); err != nil {
+		return nil, err
+	}
+
 	var err error
 	ctx, err = contextWithAuthToken(ctx)
 	if err != nil {
@@ -263,24 +288,36 @@ func (p *PluginServiceServer) UpdateRetentionScript(ctx context.Context, req *cl
 	}
 
 	_, err = p.DataRetentionPluginServiceClient.UpdateRetentionScript(ctx, &pluginpb.UpdateRetentionScriptRequest{
-		ScriptID:    req.ID,
-		ScriptName:  req.ScriptName,
-		Description: req.Description,
-		Enabled:     req.Enabled,
-		FrequencyS:  req.FrequencyS,
-		Contents:    req.Contents,
-		ExportUrl:   req.ExportUrl,
-		ClusterIDs:  req.ClusterIDs,
+		ScriptID:        req.ID,
+		ScriptName:      req.ScriptName,
+		Description:     req.Description,
+		Enabled:         req.Enabled,
+		FrequencyS:      req.FrequencyS,
+		Contents:        req.Contents,
+		ExportUrl:       req.ExportUrl,
+		ClusterIDs:      req.ClusterIDs,
+		ClusterSelector: req.ClusterSelector,
+		ClusterGroup:    req.ClusterGroup,
 	})
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, p.AuditClient, &profilepb.AuditEvent{
+		Action:     "retention_script.update",
+		TargetType: "retention_script",
+		TargetID:   utils.UUIDFromProtoOrNil(req.ID).String(),
+		After:      retentionScriptUpdateSummary(req),
+	})
 
 	return &cloudpb.UpdateRetentionScriptResponse{}, nil
 }
 
 // CreateRetentionScript creates a retention script.
 func (p *PluginServiceServer) CreateRetentionScript(ctx context.Context, req *cloudpb.CreateRetentionScriptRequest) (*cloudpb.CreateRetentionScriptResponse, error) {
+	if err := rbac.RequireOrgRole(ctx, srvutils.MemberRole); err != nil {
+		return nil, err
+	}
+
 	sCtx, err := authcontext.FromContext(ctx)
 	if err != nil {
 		return nil, err
@@ -296,13 +333,15 @@ func (p *PluginServiceServer) CreateRetentionScript(ctx context.Context, req *cl
 	resp, err := p.DataRetentionPluginServiceClient.CreateRetentionScript(ctx, &pluginpb.CreateRetentionScriptRequest{
 		Script: &pluginpb.DetailedRetentionScript{
 			Script: &pluginpb.RetentionScript{
-				ScriptName:  req.ScriptName,
-				Description: req.Description,
-				FrequencyS:  req.FrequencyS,
-				ClusterIDs:  req.ClusterIDs,
-				PluginId:    req.PluginId,
-				Enabled:     true,
-				IsPreset:    false,
+				ScriptName:      req.ScriptName,
+				Description:     req.Description,
+				FrequencyS:      req.FrequencyS,
+				ClusterIDs:      req.ClusterIDs,
+				ClusterSelector: req.ClusterSelector,
+				ClusterGroup:    req.ClusterGroup,
+				PluginId:        req.PluginId,
+				Enabled:         true,
+				IsPreset:        false,
 			},
 			Contents:  req.Contents,
 			ExportURL: req.ExportUrl,
@@ -312,12 +351,22 @@ func (p *PluginServiceServer) CreateRetentionScript(ctx context.Context, req *cl
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, p.AuditClient, &profilepb.AuditEvent{
+		Action:     "retention_script.create",
+		TargetType: "retention_script",
+		TargetID:   utils.UUIDFromProtoOrNil(resp.ID).String(),
+		After:      fmt.Sprintf("name: %s, plugin: %s, frequency_s: %d", req.ScriptName, req.PluginId, req.FrequencyS),
+	})
 
 	return &cloudpb.CreateRetentionScriptResponse{ID: resp.ID}, nil
 }
 
 // DeleteRetentionScript deletes a specific retention script.
 func (p *PluginServiceServer) DeleteRetentionScript(ctx context.Context, req *cloudpb.DeleteRetentionScriptRequest) (*cloudpb.DeleteRetentionScriptResponse, error) {
+	if err := rbac.RequireOrgRole(ctx, srvutils.MemberRole); err != nil {
+		return nil, err
+	}
+
 	sCtx, err := authcontext.FromContext(ctx)
 	if err != nil {
 		return nil, err
@@ -337,6 +386,65 @@ func (p *PluginServiceServer) DeleteRetentionScript(ctx context.Context, req *cl
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, p.AuditClient, &profilepb.AuditEvent{
+		Action:     "retention_script.delete",
+		TargetType: "retention_script",
+		TargetID:   utils.UUIDFromProtoOrNil(req.ID).String(),
+	})
 
 	return &cloudpb.DeleteRetentionScriptResponse{}, nil
 }
+
+// retentionPluginConfigSummary describes a retention plugin config update for the audit trail.
+// Config values often hold credentials for the export destination, so only the keys are included.
+func retentionPluginConfigSummary(req *cloudpb.UpdateRetentionPluginConfigRequest) string {
+	var parts []string
+	if req.Enabled != nil {
+		parts = append(parts, fmt.Sprintf("enabled: %t", req.Enabled.Value))
+	}
+	if req.Version != nil {
+		parts = append(parts, fmt.Sprintf("version: %s", req.Version.Value))
+	}
+	if req.CustomExportUrl != nil {
+		parts = append(parts, "custom_export_url updated")
+	}
+	if len(req.Configs) > 0 {
+		keys := make([]string, 0, len(req.Configs))
+		for k := range req.Configs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts = append(parts, fmt.Sprintf("configs updated: %s", strings.Join(keys, ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// retentionScriptUpdateSummary describes a retention script update for the audit trail.
+func retentionScriptUpdateSummary(req *cloudpb.UpdateRetentionScriptRequest) string {
+	var parts []string
+	if req.ScriptName != nil {
+		parts = append(parts, fmt.Sprintf("name: %s", req.ScriptName.Value))
+	}
+	if req.Enabled != nil {
+		parts = append(parts, fmt.Sprintf("enabled: %t", req.Enabled.Value))
+	}
+	if req.FrequencyS != nil {
+		parts = append(parts, fmt.Sprintf("frequency_s: %d", req.FrequencyS.Value))
+	}
+	if req.Contents != nil {
+		parts = append(parts, "contents updated")
+	}
+	if req.ExportUrl != nil {
+		parts = append(parts, "export_url updated")
+	}
+	if req.ClusterIDs != nil {
+		parts = append(parts, fmt.Sprintf("clusters: %d", len(req.ClusterIDs)))
+	}
+	if req.ClusterSelector != nil {
+		parts = append(parts, fmt.Sprintf("cluster_selector: %s", req.ClusterSelector.Value))
+	}
+	if req.ClusterGroup != nil {
+		parts = append(parts, fmt.Sprintf("cluster_group: %s", req.ClusterGroup.Value))
+	}
+	return strings.Join(parts, ", ")
+}
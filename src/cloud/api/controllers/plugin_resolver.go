@@ -262,6 +262,8 @@ type RetentionScriptResolver struct {
 	FrequencyS      int32
 	Enabled         bool
 	clusterIDs      []uuid.UUID
+	ClusterSelector string
+	ClusterGroup    string
 	Contents        string
 	PluginID        string
 	CustomExportURL *string
@@ -277,6 +279,8 @@ type editableRetentionScript struct {
 	PluginID        *string
 	CustomExportURL *string
 	Clusters        *[]string
+	ClusterSelector *string
+	ClusterGroup    *string
 }
 
 type updateRetentionScriptArgs struct {
@@ -326,14 +330,16 @@ func (q *QueryResolver) RetentionScripts(ctx context.Context) ([]*RetentionScrip
 		}
 
 		scripts[i] = &RetentionScriptResolver{
-			scriptID:    utils.UUIDFromProtoOrNil(s.ScriptID),
-			Name:        s.ScriptName,
-			Description: s.Description,
-			FrequencyS:  int32(s.FrequencyS),
-			clusterIDs:  clusterIDs,
-			PluginID:    s.PluginId,
-			Enabled:     s.Enabled,
-			IsPreset:    s.IsPreset,
+			scriptID:        utils.UUIDFromProtoOrNil(s.ScriptID),
+			Name:            s.ScriptName,
+			Description:     s.Description,
+			FrequencyS:      int32(s.FrequencyS),
+			clusterIDs:      clusterIDs,
+			ClusterSelector: s.ClusterSelector,
+			ClusterGroup:    s.ClusterGroup,
+			PluginID:        s.PluginId,
+			Enabled:         s.Enabled,
+			IsPreset:        s.IsPreset,
 		}
 	}
 
@@ -364,6 +370,8 @@ func (q *QueryResolver) RetentionScript(ctx context.Context, args retentionScrip
 		PluginID:        s.PluginId,
 		CustomExportURL: &resp.ExportURL,
 		clusterIDs:      clusterIDs,
+		ClusterSelector: s.ClusterSelector,
+		ClusterGroup:    s.ClusterGroup,
 		IsPreset:        s.IsPreset,
 	}
 
@@ -418,6 +426,14 @@ func (q *QueryResolver) UpdateRetentionScript(ctx context.Context, args updateRe
 		req.ExportUrl = &types.StringValue{Value: *script.CustomExportURL}
 	}
 
+	if script.ClusterSelector != nil {
+		req.ClusterSelector = &types.StringValue{Value: *script.ClusterSelector}
+	}
+
+	if script.ClusterGroup != nil {
+		req.ClusterGroup = &types.StringValue{Value: *script.ClusterGroup}
+	}
+
 	_, err := q.Env.PluginServer.UpdateRetentionScript(ctx, req)
 	if err != nil {
 		return false, err
@@ -484,6 +500,14 @@ func (q *QueryResolver) CreateRetentionScript(ctx context.Context, args createRe
 		req.ExportUrl = *script.CustomExportURL
 	}
 
+	if script.ClusterSelector != nil {
+		req.ClusterSelector = *script.ClusterSelector
+	}
+
+	if script.ClusterGroup != nil {
+		req.ClusterGroup = *script.ClusterGroup
+	}
+
 	resp, err := q.Env.PluginServer.CreateRetentionScript(ctx, req)
 	if err != nil {
 		return graphql.ID(""), err
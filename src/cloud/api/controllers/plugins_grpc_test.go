@@ -26,12 +26,14 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/api/controllers"
 	"px.dev/pixie/src/cloud/api/controllers/testutils"
 	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/cloud/profile/profilepb"
 	"px.dev/pixie/src/utils"
 )
 
@@ -188,7 +190,7 @@ func TestGetPlugins(t *testing.T) {
 					Plugins: test.orgRetentionPlugins,
 				}, nil)
 
-			pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+			pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAudit}
 
 			resp, err := pServer.GetPlugins(ctx, &cloudpb.GetPluginsRequest{
 				Kind: cloudpb.PK_RETENTION,
@@ -223,7 +225,7 @@ func TestGetOrgRetentionPluginConfig(t *testing.T) {
 			CustomExportUrl: "https://localhost:8080",
 		}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAudit}
 
 	resp, err := pServer.GetOrgRetentionPluginConfig(ctx, &cloudpb.GetOrgRetentionPluginConfigRequest{
 		PluginId: "test-plugin",
@@ -259,7 +261,7 @@ func TestGetRetentionPluginInfo(t *testing.T) {
 			AllowCustomExportURL: true,
 		}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAudit}
 
 	resp, err := pServer.GetRetentionPluginInfo(ctx, &cloudpb.GetRetentionPluginInfoRequest{
 		PluginId: "test-plugin",
@@ -299,7 +301,14 @@ func TestUpdateRetentionPluginConfig(t *testing.T) {
 	mockClients.MockDataRetentionPlugin.EXPECT().UpdateOrgRetentionPluginConfig(gomock.Any(), mockReq).
 		Return(&pluginpb.UpdateOrgRetentionPluginConfigResponse{}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAudit}
+	// Config values may hold credentials, so only the changed keys are recorded.
+	mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, e *profilepb.AuditEvent, opts ...grpc.CallOption) (*types.Empty, error) {
+			assert.Equal(t, "retention_plugin.update_config", e.Action)
+			assert.Equal(t, "enabled: true, version: 2.0.0, custom_export_url updated, configs updated: API_KEY", e.After)
+			return &types.Empty{}, nil
+		})
 
 	resp, err := pServer.UpdateRetentionPluginConfig(ctx, &cloudpb.UpdateRetentionPluginConfigRequest{
 		PluginId: "test-plugin",
@@ -360,7 +369,7 @@ func TestGetRetentionScripts(t *testing.T) {
 			},
 		}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAudit}
 
 	resp, err := pServer.GetRetentionScripts(ctx, &cloudpb.GetRetentionScriptsRequest{})
 
@@ -435,7 +444,7 @@ func TestGetRetentionScript(t *testing.T) {
 			},
 		}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAudit}
 
 	resp, err := pServer.GetRetentionScript(ctx, &cloudpb.GetRetentionScriptRequest{
 		ID: scriptID,
@@ -490,7 +499,8 @@ func TestUpdateRetentionScript(t *testing.T) {
 	mockClients.MockDataRetentionPlugin.EXPECT().UpdateRetentionScript(gomock.Any(), mockReq).
 		Return(&pluginpb.UpdateRetentionScriptResponse{}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAudit}
+	mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).Return(&types.Empty{}, nil)
 
 	resp, err := pServer.UpdateRetentionScript(ctx, &cloudpb.UpdateRetentionScriptRequest{
 		ID:          scriptID,
@@ -546,7 +556,8 @@ func TestCreateRetentionScript(t *testing.T) {
 	mockClients.MockDataRetentionPlugin.EXPECT().CreateRetentionScript(gomock.Any(), mockReq).
 		Return(&pluginpb.CreateRetentionScriptResponse{ID: scriptID}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAudit}
+	mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).Return(&types.Empty{}, nil)
 
 	resp, err := pServer.CreateRetentionScript(ctx, &cloudpb.CreateRetentionScriptRequest{
 		ScriptName:  "Test Script",
@@ -587,7 +598,8 @@ func TestDeleteRetentionScript(t *testing.T) {
 	mockClients.MockDataRetentionPlugin.EXPECT().DeleteRetentionScript(gomock.Any(), mockReq).
 		Return(&pluginpb.DeleteRetentionScriptResponse{}, nil)
 
-	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin, mockClients.MockAudit}
+	mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).Return(&types.Empty{}, nil)
 
 	resp, err := pServer.DeleteRetentionScript(ctx, &cloudpb.DeleteRetentionScriptRequest{
 		ID: scriptID,
@@ -85,7 +85,7 @@ func WithAugmentedAuthMiddleware(env apienv.APIEnv, next http.Handler) http.Hand
 			}
 			return
 		}
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(withSourceIP(ctx, r)))
 	}
 	return http.HandlerFunc(f)
 }
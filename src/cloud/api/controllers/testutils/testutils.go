@@ -46,6 +46,8 @@ type MockCloudClients struct {
 	MockUser              *mock_cloudpb.MockUserServiceServer
 	MockAPIKey            *mock_cloudpb.MockAPIKeyManagerServer
 	MockPlugin            *mock_cloudpb.MockPluginServiceServer
+	MockAudit             *mock_cloudpb.MockAuditServiceServer
+	MockAuth              *mock_cloudpb.MockAuthServiceServer
 }
 
 // CreateTestGraphQLEnv creates a test graphql environment and mock clients.
@@ -60,6 +62,8 @@ func CreateTestGraphQLEnv(t *testing.T) (controllers.GraphQLEnv, *MockCloudClien
 	os := mock_cloudpb.NewMockOrganizationServiceServer(ctrl)
 	us := mock_cloudpb.NewMockUserServiceServer(ctrl)
 	ps := mock_cloudpb.NewMockPluginServiceServer(ctrl)
+	aus := mock_cloudpb.NewMockAuditServiceServer(ctrl)
+	auths := mock_cloudpb.NewMockAuthServiceServer(ctrl)
 	gqlEnv := controllers.GraphQLEnv{
 		APIKeyMgr:             aps,
 		ArtifactTrackerServer: ats,
@@ -70,6 +74,8 @@ func CreateTestGraphQLEnv(t *testing.T) (controllers.GraphQLEnv, *MockCloudClien
 		OrgServer:             os,
 		UserServer:            us,
 		PluginServer:          ps,
+		AuditServer:           aus,
+		AuthServer:            auths,
 	}
 	return gqlEnv, &MockCloudClients{
 		MockAPIKey:            aps,
@@ -81,6 +87,8 @@ func CreateTestGraphQLEnv(t *testing.T) (controllers.GraphQLEnv, *MockCloudClien
 		MockOrg:               os,
 		MockUser:              us,
 		MockPlugin:            ps,
+		MockAudit:             aus,
+		MockAuth:              auths,
 	}, ctrl.Finish
 }
 
@@ -91,11 +99,13 @@ type MockAPIClients struct {
 	MockOrg                 *mock_profilepb.MockOrgServiceClient
 	MockVzDeployKey         *mock_vzmgrpb.MockVZDeploymentKeyServiceClient
 	MockAPIKey              *mock_auth.MockAPIKeyServiceClient
+	MockDeviceAuth          *mock_auth.MockDeviceAuthServiceClient
 	MockVzMgr               *mock_vzmgrpb.MockVZMgrServiceClient
 	MockArtifact            *mock_artifacttrackerpb.MockArtifactTrackerClient
 	MockConfigMgr           *mock_configmanagerpb.MockConfigManagerServiceClient
 	MockPlugin              *mock_pluginpb.MockPluginServiceClient
 	MockDataRetentionPlugin *mock_pluginpb.MockDataRetentionPluginServiceClient
+	MockAudit               *mock_profilepb.MockAuditServiceClient
 }
 
 // CreateTestAPIEnv creates a test environment and mock clients.
@@ -111,10 +121,12 @@ func CreateTestAPIEnv(t *testing.T) (apienv.APIEnv, *MockAPIClients, func()) {
 	mockVzMgrClient := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
 	mockVzDeployKey := mock_vzmgrpb.NewMockVZDeploymentKeyServiceClient(ctrl)
 	mockAPIKey := mock_auth.NewMockAPIKeyServiceClient(ctrl)
+	mockDeviceAuth := mock_auth.NewMockDeviceAuthServiceClient(ctrl)
 	mockArtifactTrackerClient := mock_artifacttrackerpb.NewMockArtifactTrackerClient(ctrl)
 	mockConfigMgrClient := mock_configmanagerpb.NewMockConfigManagerServiceClient(ctrl)
 	mockPluginClient := mock_pluginpb.NewMockPluginServiceClient(ctrl)
 	mockRetentionClient := mock_pluginpb.NewMockDataRetentionPluginServiceClient(ctrl)
+	mockAuditClient := mock_profilepb.NewMockAuditServiceClient(ctrl)
 	apiEnv, err := apienv.New(mockAuthClient, mockProfileClient, mockOrgClient, mockVzDeployKey, mockAPIKey, mockVzMgrClient, mockArtifactTrackerClient, nil, mockConfigMgrClient, mockPluginClient, mockRetentionClient)
 	if err != nil {
 		t.Fatal("failed to init api env")
@@ -126,10 +138,12 @@ func CreateTestAPIEnv(t *testing.T) (apienv.APIEnv, *MockAPIClients, func()) {
 		MockOrg:                 mockOrgClient,
 		MockVzMgr:               mockVzMgrClient,
 		MockAPIKey:              mockAPIKey,
+		MockDeviceAuth:          mockDeviceAuth,
 		MockVzDeployKey:         mockVzDeployKey,
 		MockArtifact:            mockArtifactTrackerClient,
 		MockConfigMgr:           mockConfigMgrClient,
 		MockPlugin:              mockPluginClient,
 		MockDataRetentionPlugin: mockRetentionClient,
+		MockAudit:               mockAuditClient,
 	}, ctrl.Finish
 }
@@ -21,12 +21,17 @@ package controllers
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/shared/rbac"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/services/authcontext"
 	claimsutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
@@ -36,6 +41,58 @@ import (
 type UserServiceServer struct {
 	ProfileServiceClient profilepb.ProfileServiceClient
 	OrgServiceClient     profilepb.OrgServiceClient
+	AuditClient          profilepb.AuditServiceClient
+	VzMgr                vzmgrpb.VZMgrServiceClient
+}
+
+func orgRoleToCloudProto(r profilepb.OrgRole) cloudpb.OrgRole {
+	switch r {
+	case profilepb.OR_VIEWER:
+		return cloudpb.OR_VIEWER
+	case profilepb.OR_MEMBER:
+		return cloudpb.OR_MEMBER
+	case profilepb.OR_ADMIN:
+		return cloudpb.OR_ADMIN
+	case profilepb.OR_OWNER:
+		return cloudpb.OR_OWNER
+	default:
+		return cloudpb.OR_UNKNOWN
+	}
+}
+
+func orgRoleFromCloudProto(r cloudpb.OrgRole) profilepb.OrgRole {
+	switch r {
+	case cloudpb.OR_VIEWER:
+		return profilepb.OR_VIEWER
+	case cloudpb.OR_MEMBER:
+		return profilepb.OR_MEMBER
+	case cloudpb.OR_ADMIN:
+		return profilepb.OR_ADMIN
+	case cloudpb.OR_OWNER:
+		return profilepb.OR_OWNER
+	default:
+		return profilepb.OR_UNKNOWN
+	}
+}
+
+func userInfoToCloudProto(u *profilepb.UserInfo) *cloudpb.UserInfo {
+	userInfo := &cloudpb.UserInfo{
+		ID:             u.ID,
+		OrgID:          u.OrgID,
+		FirstName:      u.FirstName,
+		LastName:       u.LastName,
+		Email:          u.Email,
+		ProfilePicture: u.ProfilePicture,
+		IsApproved:     u.IsApproved,
+		Role:           orgRoleToCloudProto(u.Role),
+	}
+	for _, cr := range u.ClusterRoles {
+		userInfo.ClusterRoles = append(userInfo.ClusterRoles, &cloudpb.ClusterRole{
+			ClusterID: cr.ClusterID,
+			Role:      orgRoleToCloudProto(cr.Role),
+		})
+	}
+	return userInfo
 }
 
 // GetUser will retrieve user based on UUID.
@@ -49,15 +106,7 @@ func (u *UserServiceServer) GetUser(ctx context.Context, req *uuidpb.UUID) (*clo
 	if err != nil {
 		return nil, err
 	}
-	return &cloudpb.UserInfo{
-		ID:             resp.ID,
-		OrgID:          resp.OrgID,
-		FirstName:      resp.FirstName,
-		LastName:       resp.LastName,
-		Email:          resp.Email,
-		ProfilePicture: resp.ProfilePicture,
-		IsApproved:     resp.IsApproved,
-	}, nil
+	return userInfoToCloudProto(resp), nil
 }
 
 // GetUserSettings will retrieve settings given the user ID.
@@ -119,8 +168,7 @@ func (u *UserServiceServer) UpdateUser(ctx context.Context, req *cloudpb.UpdateU
 	claimsUserID := uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().UserID)
 
 	// Check permissions.
-	// If user is in the org, they are permitted to update any org user's info, since all
-	// users are considered admins.
+	// Users may update their own info, but only admins may update other users in the org.
 	userResp, err := u.ProfileServiceClient.GetUser(ctx, req.ID)
 	if err != nil {
 		return nil, err
@@ -128,10 +176,21 @@ func (u *UserServiceServer) UpdateUser(ctx context.Context, req *cloudpb.UpdateU
 	if claimsOrgID != utils.UUIDFromProtoOrNil(userResp.OrgID) {
 		return nil, errors.New("Unauthorized")
 	}
+	isSelf := claimsUserID == utils.UUIDFromProtoOrNil(userResp.ID)
+	if !isSelf {
+		if err := rbac.RequireOrgRole(ctx, claimsutils.AdminRole); err != nil {
+			return nil, err
+		}
+	}
 	// A user cannot update their own "isApproved" status.
-	if req.IsApproved != nil && claimsUserID == utils.UUIDFromProtoOrNil(userResp.ID) {
+	if req.IsApproved != nil && isSelf {
 		return nil, errors.New("Unauthorized")
 	}
+	if req.Role != cloudpb.OR_UNKNOWN {
+		if err := checkCanGrantRole(ctx, isSelf, userResp.Role, req.Role); err != nil {
+			return nil, err
+		}
+	}
 
 	ctx, err = contextWithAuthToken(ctx)
 	if err != nil {
@@ -142,22 +201,109 @@ func (u *UserServiceServer) UpdateUser(ctx context.Context, req *cloudpb.UpdateU
 		ID:             req.ID,
 		DisplayPicture: req.DisplayPicture,
 		IsApproved:     req.IsApproved,
+		Role:           orgRoleFromCloudProto(req.Role),
 	}
 
 	resp, err := u.ProfileServiceClient.UpdateUser(ctx, in)
 	if err != nil {
 		return nil, err
 	}
+	// Profile changes are not audited, only changes to what the user is allowed to do.
+	if req.IsApproved != nil || req.Role != cloudpb.OR_UNKNOWN {
+		recordAuditEvent(ctx, u.AuditClient, &profilepb.AuditEvent{
+			Action:     "user.update_permissions",
+			TargetType: "user",
+			TargetID:   utils.UUIDFromProtoOrNil(req.ID).String(),
+			Before:     userPermissionsSummary(userResp),
+			After:      userPermissionsSummary(resp),
+		})
+	}
 
-	return &cloudpb.UserInfo{
-		ID:             resp.ID,
-		OrgID:          resp.OrgID,
-		FirstName:      resp.FirstName,
-		LastName:       resp.LastName,
-		Email:          resp.Email,
-		ProfilePicture: resp.ProfilePicture,
-		IsApproved:     resp.IsApproved,
-	}, nil
+	return userInfoToCloudProto(resp), nil
+}
+
+func userPermissionsSummary(u *profilepb.UserInfo) string {
+	return fmt.Sprintf("is_approved: %t, role: %s", u.IsApproved, orgRoleToCloudProto(u.Role))
+}
+
+// checkCanGrantRole checks whether the user in the context may change another user's role from
+// currentRole to newRole. Admins manage members and viewers, only owners may manage owners.
+func checkCanGrantRole(ctx context.Context, isSelf bool, currentRole profilepb.OrgRole, newRole cloudpb.OrgRole) error {
+	if isSelf {
+		return status.Error(codes.PermissionDenied, "Users may not change their own role")
+	}
+	if orgRoleFromCloudProto(newRole) == profilepb.OR_UNKNOWN {
+		return status.Error(codes.InvalidArgument, "Invalid role")
+	}
+	required := claimsutils.AdminRole
+	if newRole == cloudpb.OR_OWNER || currentRole == profilepb.OR_OWNER {
+		required = claimsutils.OwnerRole
+	}
+	return rbac.RequireOrgRole(ctx, required)
+}
+
+// SetUserClusterRole grants a user in the org a role on a single cluster.
+func (u *UserServiceServer) SetUserClusterRole(ctx context.Context, req *cloudpb.SetUserClusterRoleRequest) (*cloudpb.SetUserClusterRoleResponse, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if claimsutils.GetClaimsType(sCtx.Claims) != claimsutils.UserClaimType {
+		return nil, errors.New("Unauthorized")
+	}
+
+	claimsOrgID := uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID)
+	claimsUserID := uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().UserID)
+
+	userResp, err := u.ProfileServiceClient.GetUser(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if claimsOrgID != utils.UUIDFromProtoOrNil(userResp.OrgID) {
+		return nil, errors.New("Unauthorized")
+	}
+	isSelf := claimsUserID == utils.UUIDFromProtoOrNil(userResp.ID)
+	if isSelf {
+		return nil, status.Error(codes.PermissionDenied, "Users may not change their own role")
+	}
+	required := claimsutils.AdminRole
+	if req.Role == cloudpb.OR_OWNER {
+		required = claimsutils.OwnerRole
+	}
+	if err := rbac.RequireOrgRole(ctx, required); err != nil {
+		return nil, err
+	}
+
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Grants may only be made on the org's own clusters.
+	orgResp, err := u.VzMgr.GetOrgFromVizier(ctx, req.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	if claimsOrgID != utils.UUIDFromProtoOrNil(orgResp.OrgID) {
+		return nil, status.Error(codes.NotFound, "cluster not found")
+	}
+
+	_, err = u.ProfileServiceClient.SetUserClusterRole(ctx, &profilepb.SetUserClusterRoleRequest{
+		UserID:    req.UserID,
+		ClusterID: req.ClusterID,
+		Role:      orgRoleFromCloudProto(req.Role),
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordAuditEvent(ctx, u.AuditClient, &profilepb.AuditEvent{
+		Action:     "user.set_cluster_role",
+		TargetType: "user",
+		TargetID:   utils.UUIDFromProtoOrNil(req.UserID).String(),
+		After:      fmt.Sprintf("cluster: %s, role: %s", utils.UUIDFromProtoOrNil(req.ClusterID), req.Role),
+	})
+	return &cloudpb.SetUserClusterRoleResponse{}, nil
 }
 
 // GetUserAttributes will retrieve attributes given the user ID.
@@ -92,6 +92,29 @@ func (u *UserInfoResolver) IsApproved() bool {
 	return u.UserInfo.IsApproved
 }
 
+// Role returns the user's role in their org.
+func (u *UserInfoResolver) Role() string {
+	return u.UserInfo.Role.String()
+}
+
+// UserClusterRoleResolver resolves a role granted to a user on a cluster.
+type UserClusterRoleResolver struct {
+	ClusterID graphql.ID
+	Role      string
+}
+
+// ClusterRoles returns the per-cluster roles granted to the user.
+func (u *UserInfoResolver) ClusterRoles() []*UserClusterRoleResolver {
+	roles := make([]*UserClusterRoleResolver, len(u.UserInfo.ClusterRoles))
+	for i, cr := range u.UserInfo.ClusterRoles {
+		roles[i] = &UserClusterRoleResolver{
+			ClusterID: graphql.ID(utils.ProtoToUUIDStr(cr.ClusterID)),
+			Role:      cr.Role.String(),
+		}
+	}
+	return roles
+}
+
 // UserSettingsResolver resolves user settings.
 type UserSettingsResolver struct {
 	AnalyticsOptout bool
@@ -183,6 +206,48 @@ func (q *QueryResolver) UpdateUserPermissions(ctx context.Context, args *updateU
 	return &UserInfoResolver{ctx, &q.Env, userInfo}, nil
 }
 
+type updateUserRoleArgs struct {
+	UserID graphql.ID
+	Role   string
+}
+
+// UpdateUserRole changes the role of a user in the org.
+func (q *QueryResolver) UpdateUserRole(ctx context.Context, args *updateUserRoleArgs) (*UserInfoResolver, error) {
+	userID := utils.ProtoFromUUIDStrOrNil(string(args.UserID))
+	_, err := q.Env.UserServer.UpdateUser(ctx, &cloudpb.UpdateUserRequest{
+		ID:   userID,
+		Role: cloudpb.OrgRole(cloudpb.OrgRole_value[args.Role]),
+	})
+	if err != nil {
+		return nil, rpcErrorHelper(err)
+	}
+
+	userInfo, err := q.Env.UserServer.GetUser(ctx, userID)
+	if err != nil {
+		return nil, rpcErrorHelper(err)
+	}
+	return &UserInfoResolver{ctx, &q.Env, userInfo}, nil
+}
+
+type setUserClusterRoleArgs struct {
+	UserID    graphql.ID
+	ClusterID graphql.ID
+	Role      string
+}
+
+// SetUserClusterRole grants a user a role on a single cluster.
+func (q *QueryResolver) SetUserClusterRole(ctx context.Context, args *setUserClusterRoleArgs) (bool, error) {
+	_, err := q.Env.UserServer.SetUserClusterRole(ctx, &cloudpb.SetUserClusterRoleRequest{
+		UserID:    utils.ProtoFromUUIDStrOrNil(string(args.UserID)),
+		ClusterID: utils.ProtoFromUUIDStrOrNil(string(args.ClusterID)),
+		Role:      cloudpb.OrgRole(cloudpb.OrgRole_value[args.Role]),
+	})
+	if err != nil {
+		return false, rpcErrorHelper(err)
+	}
+	return true, nil
+}
+
 // UserAttributesResolver is a resolver for user attributes.
 type UserAttributesResolver struct {
 	TourSeen bool
@@ -27,11 +27,16 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/api/controllers"
 	"px.dev/pixie/src/cloud/api/controllers/testutils"
 	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	svcutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 )
 
@@ -205,9 +210,13 @@ func TestServer_UpdateUser(t *testing.T) {
 			if !tc.shouldReject {
 				mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), mockUpdateReq).
 					Return(updatedUserInfo, nil)
+				// Only permission changes are audited.
+				if req.IsApproved != nil {
+					mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).Return(&types.Empty{}, nil)
+				}
 			}
 
-			userServer := &controllers.UserServiceServer{mockClients.MockProfile, mockClients.MockOrg}
+			userServer := &controllers.UserServiceServer{mockClients.MockProfile, mockClients.MockOrg, mockClients.MockAudit, mockClients.MockVzMgr}
 			resp, err := userServer.UpdateUser(tc.ctx, req)
 
 			if !tc.shouldReject {
@@ -221,3 +230,162 @@ func TestServer_UpdateUser(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_UpdateUser_Role(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+
+	otherUserID := "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	selfUserID := "6ba7b810-9dad-11d1-80b4-00c04fd430c9"
+	orgID := "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+	tests := []struct {
+		name         string
+		userID       string
+		currentRole  profilepb.OrgRole
+		newRole      cloudpb.OrgRole
+		ctx          context.Context
+		shouldReject bool
+	}{
+		{
+			name:        "admin can make another user a viewer",
+			userID:      otherUserID,
+			currentRole: profilepb.OR_MEMBER,
+			newRole:     cloudpb.OR_VIEWER,
+			ctx:         CreateTestContextWithRole(svcutils.AdminRole),
+		},
+		{
+			name:         "admin cannot grant owner",
+			userID:       otherUserID,
+			currentRole:  profilepb.OR_MEMBER,
+			newRole:      cloudpb.OR_OWNER,
+			ctx:          CreateTestContextWithRole(svcutils.AdminRole),
+			shouldReject: true,
+		},
+		{
+			name:         "admin cannot demote an owner",
+			userID:       otherUserID,
+			currentRole:  profilepb.OR_OWNER,
+			newRole:      cloudpb.OR_MEMBER,
+			ctx:          CreateTestContextWithRole(svcutils.AdminRole),
+			shouldReject: true,
+		},
+		{
+			name:        "owner can grant owner",
+			userID:      otherUserID,
+			currentRole: profilepb.OR_ADMIN,
+			newRole:     cloudpb.OR_OWNER,
+			ctx:         CreateTestContextWithRole(svcutils.OwnerRole),
+		},
+		{
+			name:         "member cannot change roles",
+			userID:       otherUserID,
+			currentRole:  profilepb.OR_VIEWER,
+			newRole:      cloudpb.OR_MEMBER,
+			ctx:          CreateTestContextWithRole(svcutils.MemberRole),
+			shouldReject: true,
+		},
+		{
+			name:         "owner cannot change their own role",
+			userID:       selfUserID,
+			currentRole:  profilepb.OR_OWNER,
+			newRole:      cloudpb.OR_ADMIN,
+			ctx:          CreateTestContextWithRole(svcutils.OwnerRole),
+			shouldReject: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			userID := utils.ProtoFromUUIDStrOrNil(tc.userID)
+			mockClients.MockProfile.EXPECT().GetUser(gomock.Any(), userID).
+				Return(&profilepb.UserInfo{
+					ID:    userID,
+					OrgID: utils.ProtoFromUUIDStrOrNil(orgID),
+					Role:  tc.currentRole,
+				}, nil)
+
+			if !tc.shouldReject {
+				mockClients.MockProfile.EXPECT().UpdateUser(gomock.Any(), &profilepb.UpdateUserRequest{
+					ID:   userID,
+					Role: profilepb.OrgRole(tc.newRole),
+				}).Return(&profilepb.UserInfo{
+					ID:    userID,
+					OrgID: utils.ProtoFromUUIDStrOrNil(orgID),
+					Role:  profilepb.OrgRole(tc.newRole),
+				}, nil)
+				mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).Return(&types.Empty{}, nil)
+			}
+
+			userServer := &controllers.UserServiceServer{mockClients.MockProfile, mockClients.MockOrg, mockClients.MockAudit, mockClients.MockVzMgr}
+			resp, err := userServer.UpdateUser(tc.ctx, &cloudpb.UpdateUserRequest{
+				ID:   userID,
+				Role: tc.newRole,
+			})
+
+			if tc.shouldReject {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.newRole, resp.Role)
+		})
+	}
+}
+
+func TestServer_SetUserClusterRole(t *testing.T) {
+	otherUserID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	clusterID := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	tests := []struct {
+		name         string
+		clusterOrgID *uuidpb.UUID
+		shouldReject bool
+	}{
+		{
+			name:         "cluster in the org",
+			clusterOrgID: orgID,
+		},
+		{
+			name:         "cluster in another org",
+			clusterOrgID: utils.ProtoFromUUIDStrOrNil("8ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+			shouldReject: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+			defer cleanup()
+
+			mockClients.MockProfile.EXPECT().GetUser(gomock.Any(), otherUserID).
+				Return(&profilepb.UserInfo{ID: otherUserID, OrgID: orgID, Role: profilepb.OR_VIEWER}, nil)
+			mockClients.MockVzMgr.EXPECT().GetOrgFromVizier(gomock.Any(), clusterID).
+				Return(&vzmgrpb.GetOrgFromVizierResponse{OrgID: tc.clusterOrgID}, nil)
+			if !tc.shouldReject {
+				mockClients.MockProfile.EXPECT().SetUserClusterRole(gomock.Any(), &profilepb.SetUserClusterRoleRequest{
+					UserID:    otherUserID,
+					ClusterID: clusterID,
+					Role:      profilepb.OR_ADMIN,
+				}).Return(&types.Empty{}, nil)
+				mockClients.MockAudit.EXPECT().RecordAuditEvent(gomock.Any(), gomock.Any()).Return(&types.Empty{}, nil)
+			}
+
+			userServer := &controllers.UserServiceServer{mockClients.MockProfile, mockClients.MockOrg, mockClients.MockAudit, mockClients.MockVzMgr}
+			_, err := userServer.SetUserClusterRole(CreateTestContextWithRole(svcutils.AdminRole), &cloudpb.SetUserClusterRoleRequest{
+				UserID:    otherUserID,
+				ClusterID: clusterID,
+				Role:      cloudpb.OR_ADMIN,
+			})
+			if tc.shouldReject {
+				assert.Equal(t, codes.NotFound, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -21,8 +21,11 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -30,11 +33,15 @@ import (
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/shared/labelselector"
+	"px.dev/pixie/src/cloud/shared/rbac"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/artifacts/versionspb"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/k8s/metadatapb"
 	"px.dev/pixie/src/shared/services/authcontext"
+	srvutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 )
 
@@ -42,6 +49,7 @@ import (
 type VizierClusterInfo struct {
 	VzMgr                 vzmgrpb.VZMgrServiceClient
 	ArtifactTrackerClient artifacttrackerpb.ArtifactTrackerClient
+	AuditClient           profilepb.AuditServiceClient
 }
 
 func contextWithAuthToken(ctx context.Context) (context.Context, error) {
@@ -75,10 +83,37 @@ func (v *VizierClusterInfo) GetClusterInfo(ctx context.Context, request *cloudpb
 		return nil, err
 	}
 
+	groups, err := v.VzMgr.GetClusterGroups(ctx, utils.ProtoFromUUID(orgID))
+	if err != nil {
+		return nil, err
+	}
+
+	selector := request.LabelSelector
+	if request.ID == nil && request.Group != "" {
+		if selector != "" {
+			return nil, status.Error(codes.InvalidArgument, "only one of label_selector and group may be specified")
+		}
+		group := findClusterGroup(groups.Groups, request.Group)
+		if group == nil {
+			return nil, status.Errorf(codes.NotFound, "cluster group '%s' not found", request.Group)
+		}
+		selector = group.Selector
+	}
+
 	vzIDs := make([]*uuidpb.UUID, 0)
-	if request.ID != nil {
+	switch {
+	case request.ID != nil:
 		vzIDs = append(vzIDs, request.ID)
-	} else {
+	case selector != "":
+		viziers, err := v.VzMgr.GetViziersBySelector(ctx, &vzmgrpb.GetViziersBySelectorRequest{
+			OrgID:    utils.ProtoFromUUID(orgID),
+			Selector: selector,
+		})
+		if err != nil {
+			return nil, err
+		}
+		vzIDs = viziers.VizierIDs
+	default:
 		viziers, err := v.VzMgr.GetViziersByOrg(ctx, utils.ProtoFromUUID(orgID))
 		if err != nil {
 			return nil, err
@@ -86,7 +121,7 @@ func (v *VizierClusterInfo) GetClusterInfo(ctx context.Context, request *cloudpb
 		vzIDs = viziers.VizierIDs
 	}
 
-	return v.getClusterInfoForViziers(ctx, vzIDs)
+	return v.getClusterInfoForViziers(ctx, vzIDs, groups.Groups)
 }
 
 func convertContainerState(cs metadatapb.ContainerState) cloudpb.ContainerState {
@@ -161,7 +196,22 @@ func convertPodStatuses(vzMgrStatuses map[string]*cvmsgspb.PodStatus) map[string
 	return podStatuses
 }
 
-func (v *VizierClusterInfo) getClusterInfoForViziers(ctx context.Context, ids []*uuidpb.UUID) (*cloudpb.GetClusterInfoResponse, error) {
+// matchingGroups returns the names of the groups whose selector matches the given labels.
+func matchingGroups(groups []*vzmgrpb.ClusterGroup, labels map[string]string) []string {
+	var names []string
+	for _, g := range groups {
+		sel, err := labelselector.Parse(g.Selector)
+		if err != nil {
+			continue
+		}
+		if sel.Matches(labels) {
+			names = append(names, g.Name)
+		}
+	}
+	return names
+}
+
+func (v *VizierClusterInfo) getClusterInfoForViziers(ctx context.Context, ids []*uuidpb.UUID, groups []*vzmgrpb.ClusterGroup) (*cloudpb.GetClusterInfoResponse, error) {
 	resp := &cloudpb.GetClusterInfoResponse{}
 
 	cNames := make(map[string]int)
@@ -208,6 +258,8 @@ func (v *VizierClusterInfo) getClusterInfoForViziers(ctx context.Context, ids []
 			NumInstrumentedNodes:          vzInfo.NumInstrumentedNodes,
 			PreviousStatus:                prevS,
 			PreviousStatusTime:            vzInfo.PreviousStatusTime,
+			Labels:                        vzInfo.Labels,
+			Groups:                        matchingGroups(groups, vzInfo.Labels),
 		})
 	}
 
@@ -242,6 +294,10 @@ func (v *VizierClusterInfo) GetClusterConnectionInfo(ctx context.Context, reques
 
 // UpdateClusterVizierConfig supports updates of VizierConfig for a cluster
 func (v *VizierClusterInfo) UpdateClusterVizierConfig(ctx context.Context, req *cloudpb.UpdateClusterVizierConfigRequest) (*cloudpb.UpdateClusterVizierConfigResponse, error) {
+	if err := rbac.RequireClusterRole(ctx, utils.UUIDFromProtoOrNil(req.ID), srvutils.AdminRole); err != nil {
+		return nil, err
+	}
+
 	ctx, err := contextWithAuthToken(ctx)
 	if err != nil {
 		return nil, err
@@ -256,6 +312,12 @@ func (v *VizierClusterInfo) UpdateClusterVizierConfig(ctx context.Context, req *
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     "cluster.update_config",
+		TargetType: "cluster",
+		TargetID:   utils.UUIDFromProtoOrNil(req.ID).String(),
+		After:      req.ConfigUpdate.String(),
+	})
 
 	return &cloudpb.UpdateClusterVizierConfigResponse{}, nil
 }
@@ -266,6 +328,10 @@ func (v *VizierClusterInfo) UpdateOrInstallCluster(ctx context.Context, req *clo
 		return nil, status.Errorf(codes.InvalidArgument, "version cannot be empty")
 	}
 
+	if err := rbac.RequireClusterRole(ctx, utils.UUIDFromProtoOrNil(req.ClusterID), srvutils.AdminRole); err != nil {
+		return nil, err
+	}
+
 	ctx, err := contextWithAuthToken(ctx)
 	if err != nil {
 		return nil, err
@@ -291,12 +357,390 @@ func (v *VizierClusterInfo) UpdateOrInstallCluster(ctx context.Context, req *clo
 	if err != nil {
 		return nil, err
 	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     "cluster.update_vizier",
+		TargetType: "cluster",
+		TargetID:   utils.UUIDFromProtoOrNil(req.ClusterID).String(),
+		After:      fmt.Sprintf("version: %s, redeploy_etcd: %t", req.Version, req.RedeployEtcd),
+	})
 
 	return &cloudpb.UpdateOrInstallClusterResponse{
 		UpdateStarted: resp.UpdateStarted,
 	}, nil
 }
 
+// UpdateClusterLabels replaces the labels attached to the given cluster.
+func (v *VizierClusterInfo) UpdateClusterLabels(ctx context.Context, req *cloudpb.UpdateClusterLabelsRequest) (*cloudpb.UpdateClusterLabelsResponse, error) {
+	if err := rbac.RequireClusterRole(ctx, utils.UUIDFromProtoOrNil(req.ID), srvutils.AdminRole); err != nil {
+		return nil, err
+	}
+
+	ctx, err := contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = v.VzMgr.UpdateVizierLabels(ctx, &vzmgrpb.UpdateVizierLabelsRequest{
+		VizierID: req.ID,
+		Labels:   req.Labels,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     "cluster.update_labels",
+		TargetType: "cluster",
+		TargetID:   utils.UUIDFromProtoOrNil(req.ID).String(),
+		After:      formatLabels(req.Labels),
+	})
+
+	return &cloudpb.UpdateClusterLabelsResponse{}, nil
+}
+
+func formatLabels(labels map[string]string) string {
+	parts := make([]string, 0, len(labels))
+	for k, v := range labels {
+		parts = append(parts, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func clusterGroupToProto(g *vzmgrpb.ClusterGroup) *cloudpb.ClusterGroup {
+	return &cloudpb.ClusterGroup{
+		ID:       g.ID,
+		Name:     g.Name,
+		Selector: g.Selector,
+	}
+}
+
+// findClusterGroup returns the group with the given name, or nil if there is none.
+func findClusterGroup(groups []*vzmgrpb.ClusterGroup, name string) *vzmgrpb.ClusterGroup {
+	for _, g := range groups {
+		if g.Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+// orgIDFromContext returns the org of the user in the context.
+func orgIDFromContext(ctx context.Context) (*uuidpb.UUID, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID, err := uuid.FromString(sCtx.Claims.GetUserClaims().OrgID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid org id")
+	}
+	return utils.ProtoFromUUID(orgID), nil
+}
+
+// GetClusterGroups returns all cluster groups in the org.
+func (v *VizierClusterInfo) GetClusterGroups(ctx context.Context, req *cloudpb.GetClusterGroupsRequest) (*cloudpb.GetClusterGroupsResponse, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := v.VzMgr.GetClusterGroups(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &cloudpb.GetClusterGroupsResponse{}
+	for _, g := range groups.Groups {
+		resp.Groups = append(resp.Groups, clusterGroupToProto(g))
+	}
+	return resp, nil
+}
+
+// CreateClusterGroup creates a new cluster group in the org.
+func (v *VizierClusterInfo) CreateClusterGroup(ctx context.Context, req *cloudpb.CreateClusterGroupRequest) (*cloudpb.ClusterGroup, error) {
+	if err := rbac.RequireOrgRole(ctx, srvutils.AdminRole); err != nil {
+		return nil, err
+	}
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := v.VzMgr.CreateClusterGroup(ctx, &vzmgrpb.ClusterGroup{
+		OrgID:    orgID,
+		Name:     req.Name,
+		Selector: req.Selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     "cluster_group.create",
+		TargetType: "cluster_group",
+		TargetID:   g.Name,
+		After:      g.Selector,
+	})
+	return clusterGroupToProto(g), nil
+}
+
+// UpdateClusterGroup updates the selector of a cluster group.
+func (v *VizierClusterInfo) UpdateClusterGroup(ctx context.Context, req *cloudpb.UpdateClusterGroupRequest) (*cloudpb.ClusterGroup, error) {
+	if err := rbac.RequireOrgRole(ctx, srvutils.AdminRole); err != nil {
+		return nil, err
+	}
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := v.VzMgr.UpdateClusterGroup(ctx, &vzmgrpb.ClusterGroup{
+		OrgID:    orgID,
+		Name:     req.Name,
+		Selector: req.Selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     "cluster_group.update",
+		TargetType: "cluster_group",
+		TargetID:   g.Name,
+		After:      g.Selector,
+	})
+	return clusterGroupToProto(g), nil
+}
+
+// DeleteClusterGroup deletes a cluster group from the org.
+func (v *VizierClusterInfo) DeleteClusterGroup(ctx context.Context, req *cloudpb.DeleteClusterGroupRequest) (*cloudpb.DeleteClusterGroupResponse, error) {
+	if err := rbac.RequireOrgRole(ctx, srvutils.AdminRole); err != nil {
+		return nil, err
+	}
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = v.VzMgr.DeleteClusterGroup(ctx, &vzmgrpb.DeleteClusterGroupRequest{
+		OrgID: orgID,
+		Name:  req.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     "cluster_group.delete",
+		TargetType: "cluster_group",
+		TargetID:   req.Name,
+	})
+	return &cloudpb.DeleteClusterGroupResponse{}, nil
+}
+
+func rolloutPolicyToProto(p *vzmgrpb.RolloutPolicy) *cloudpb.RolloutPolicy {
+	waves := make([]*cloudpb.RolloutWave, len(p.Waves))
+	for i, w := range p.Waves {
+		waves[i] = &cloudpb.RolloutWave{
+			Selector:   w.Selector,
+			ClusterIDs: w.ClusterIDs,
+		}
+	}
+	return &cloudpb.RolloutPolicy{
+		Waves:                            waves,
+		SoakTimeS:                        p.SoakTimeS,
+		HealthDeadlineS:                  p.HealthDeadlineS,
+		MaintenanceWindowStartMinute:     p.MaintenanceWindowStartMinute,
+		MaintenanceWindowDurationMinutes: p.MaintenanceWindowDurationMinutes,
+	}
+}
+
+func rolloutToProto(r *vzmgrpb.Rollout) *cloudpb.Rollout {
+	clusters := make([]*cloudpb.RolloutCluster, len(r.Clusters))
+	for i, c := range r.Clusters {
+		clusters[i] = &cloudpb.RolloutCluster{
+			ID:      c.VizierID,
+			Wave:    c.Wave,
+			Updated: c.Updated,
+		}
+	}
+	return &cloudpb.Rollout{
+		ID:          r.ID,
+		Version:     r.Version,
+		State:       cloudpb.RolloutState(cloudpb.RolloutState_value[r.State.String()]),
+		CurrentWave: r.CurrentWave,
+		NumWaves:    r.NumWaves,
+		Message:     r.Message,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+		Clusters:    clusters,
+	}
+}
+
+// GetRolloutPolicy gets the auto-update rollout policy of the org.
+func (v *VizierClusterInfo) GetRolloutPolicy(ctx context.Context, req *cloudpb.GetRolloutPolicyRequest) (*cloudpb.RolloutPolicy, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := v.VzMgr.GetRolloutPolicy(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return rolloutPolicyToProto(policy), nil
+}
+
+// UpdateRolloutPolicy creates or replaces the auto-update rollout policy of the org.
+func (v *VizierClusterInfo) UpdateRolloutPolicy(ctx context.Context, req *cloudpb.RolloutPolicy) (*cloudpb.RolloutPolicy, error) {
+	if err := rbac.RequireOrgRole(ctx, srvutils.AdminRole); err != nil {
+		return nil, err
+	}
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	waves := make([]*vzmgrpb.RolloutWave, len(req.Waves))
+	for i, w := range req.Waves {
+		waves[i] = &vzmgrpb.RolloutWave{
+			Selector:   w.Selector,
+			ClusterIDs: w.ClusterIDs,
+		}
+	}
+	policy, err := v.VzMgr.UpdateRolloutPolicy(ctx, &vzmgrpb.RolloutPolicy{
+		OrgID:                            orgID,
+		Waves:                            waves,
+		SoakTimeS:                        req.SoakTimeS,
+		HealthDeadlineS:                  req.HealthDeadlineS,
+		MaintenanceWindowStartMinute:     req.MaintenanceWindowStartMinute,
+		MaintenanceWindowDurationMinutes: req.MaintenanceWindowDurationMinutes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     "rollout_policy.update",
+		TargetType: "rollout_policy",
+		TargetID:   utils.ProtoToUUIDStr(orgID),
+		After: fmt.Sprintf("waves=%d soak_time_s=%d health_deadline_s=%d maintenance_window=%d+%dm",
+			len(policy.Waves), policy.SoakTimeS, policy.HealthDeadlineS,
+			policy.MaintenanceWindowStartMinute, policy.MaintenanceWindowDurationMinutes),
+	})
+	return rolloutPolicyToProto(policy), nil
+}
+
+// DeleteRolloutPolicy deletes the auto-update rollout policy of the org.
+func (v *VizierClusterInfo) DeleteRolloutPolicy(ctx context.Context, req *cloudpb.DeleteRolloutPolicyRequest) (*cloudpb.DeleteRolloutPolicyResponse, error) {
+	if err := rbac.RequireOrgRole(ctx, srvutils.AdminRole); err != nil {
+		return nil, err
+	}
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = v.VzMgr.DeleteRolloutPolicy(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     "rollout_policy.delete",
+		TargetType: "rollout_policy",
+		TargetID:   utils.ProtoToUUIDStr(orgID),
+	})
+	return &cloudpb.DeleteRolloutPolicyResponse{}, nil
+}
+
+// GetRollouts gets the most recent auto-update rollouts of the org.
+func (v *VizierClusterInfo) GetRollouts(ctx context.Context, req *cloudpb.GetRolloutsRequest) (*cloudpb.GetRolloutsResponse, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rollouts, err := v.VzMgr.GetRollouts(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &cloudpb.GetRolloutsResponse{
+		Rollouts: make([]*cloudpb.Rollout, len(rollouts.Rollouts)),
+	}
+	for i, r := range rollouts.Rollouts {
+		resp.Rollouts[i] = rolloutToProto(r)
+	}
+	return resp, nil
+}
+
+type rolloutActionFn func(context.Context, *vzmgrpb.RolloutRequest, ...grpc.CallOption) (*vzmgrpb.Rollout, error)
+
+func (v *VizierClusterInfo) updateRollout(ctx context.Context, req *cloudpb.RolloutRequest, action string, fn rolloutActionFn) (*cloudpb.Rollout, error) {
+	if err := rbac.RequireOrgRole(ctx, srvutils.AdminRole); err != nil {
+		return nil, err
+	}
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rollout, err := fn(ctx, &vzmgrpb.RolloutRequest{
+		OrgID:     orgID,
+		RolloutID: req.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordAuditEvent(ctx, v.AuditClient, &profilepb.AuditEvent{
+		Action:     action,
+		TargetType: "rollout",
+		TargetID:   utils.ProtoToUUIDStr(req.ID),
+		After:      rollout.Version,
+	})
+	return rolloutToProto(rollout), nil
+}
+
+// PauseRollout pauses a running auto-update rollout.
+func (v *VizierClusterInfo) PauseRollout(ctx context.Context, req *cloudpb.RolloutRequest) (*cloudpb.Rollout, error) {
+	return v.updateRollout(ctx, req, "rollout.pause", v.VzMgr.PauseRollout)
+}
+
+// ResumeRollout resumes a paused or halted auto-update rollout.
+func (v *VizierClusterInfo) ResumeRollout(ctx context.Context, req *cloudpb.RolloutRequest) (*cloudpb.Rollout, error) {
+	return v.updateRollout(ctx, req, "rollout.resume", v.VzMgr.ResumeRollout)
+}
+
 func vzStatusToClusterStatus(s cvmsgspb.VizierStatus) cloudpb.ClusterStatus {
 	switch s {
 	case cvmsgspb.VZ_ST_HEALTHY:
@@ -26,6 +26,8 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/uuidpb"
@@ -36,6 +38,7 @@ import (
 	"px.dev/pixie/src/shared/artifacts/versionspb"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/k8s/metadatapb"
+	svcutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 )
 
@@ -114,6 +117,8 @@ func TestVizierClusterInfo_GetClusterInfo(t *testing.T) {
 				VizierIDs: []*uuidpb.UUID{clusterID},
 			}, nil)
 
+			mockClients.MockVzMgr.EXPECT().GetClusterGroups(gomock.Any(), orgID).Return(&vzmgrpb.GetClusterGroupsResponse{}, nil)
+
 			mockClients.MockVzMgr.EXPECT().GetVizierInfos(gomock.Any(), &vzmgrpb.GetVizierInfosRequest{
 				VizierIDs: []*uuidpb.UUID{clusterID},
 			}).Return(&vzmgrpb.GetVizierInfosResponse{
@@ -255,6 +260,8 @@ func TestVizierClusterInfo_GetClusterInfoDuplicates(t *testing.T) {
 				VizierIDs: []*uuidpb.UUID{clusterID, clusterID2},
 			}, nil)
 
+			mockClients.MockVzMgr.EXPECT().GetClusterGroups(gomock.Any(), orgID).Return(&vzmgrpb.GetClusterGroupsResponse{}, nil)
+
 			mockClients.MockVzMgr.EXPECT().GetVizierInfos(gomock.Any(), &vzmgrpb.GetVizierInfosRequest{
 				VizierIDs: []*uuidpb.UUID{clusterID, clusterID2},
 			}).Return(&vzmgrpb.GetVizierInfosResponse{
@@ -332,6 +339,8 @@ func TestVizierClusterInfo_GetClusterInfoWithID(t *testing.T) {
 			defer cleanup()
 			ctx := test.ctx
 
+			mockClients.MockVzMgr.EXPECT().GetClusterGroups(gomock.Any(), gomock.Any()).Return(&vzmgrpb.GetClusterGroupsResponse{}, nil)
+
 			mockClients.MockVzMgr.EXPECT().GetVizierInfos(gomock.Any(), &vzmgrpb.GetVizierInfosRequest{
 				VizierIDs: []*uuidpb.UUID{clusterID},
 			}).Return(&vzmgrpb.GetVizierInfosResponse{
@@ -481,3 +490,299 @@ func TestVizierClusterInfo_UpdateOrInstallCluster(t *testing.T) {
 		})
 	}
 }
+
+func TestVizierClusterInfo_GetClusterInfoWithSelector(t *testing.T) {
+	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	clusterID := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVzMgr.EXPECT().GetViziersBySelector(gomock.Any(), &vzmgrpb.GetViziersBySelectorRequest{
+		OrgID:    orgID,
+		Selector: "env=prod",
+	}).Return(&vzmgrpb.GetViziersByOrgResponse{
+		VizierIDs: []*uuidpb.UUID{clusterID},
+	}, nil)
+
+	mockClients.MockVzMgr.EXPECT().GetClusterGroups(gomock.Any(), orgID).Return(&vzmgrpb.GetClusterGroupsResponse{
+		Groups: []*vzmgrpb.ClusterGroup{
+			{Name: "prod", Selector: "env=prod"},
+			{Name: "prod-eu", Selector: "env=prod,region=eu"},
+			{Name: "prod-us", Selector: "env=prod,region=us"},
+		},
+	}, nil)
+
+	mockClients.MockVzMgr.EXPECT().GetVizierInfos(gomock.Any(), &vzmgrpb.GetVizierInfosRequest{
+		VizierIDs: []*uuidpb.UUID{clusterID},
+	}).Return(&vzmgrpb.GetVizierInfosResponse{
+		VizierInfos: []*cvmsgspb.VizierInfo{{
+			VizierID:    clusterID,
+			Status:      cvmsgspb.VZ_ST_HEALTHY,
+			Config:      &cvmsgspb.VizierConfig{},
+			ClusterName: "some cluster",
+			Labels:      map[string]string{"env": "prod", "region": "eu"},
+		}},
+	}, nil)
+
+	vzClusterInfoServer := &controllers.VizierClusterInfo{
+		VzMgr: mockClients.MockVzMgr,
+	}
+
+	resp, err := vzClusterInfoServer.GetClusterInfo(ctx, &cloudpb.GetClusterInfoRequest{
+		LabelSelector: "env=prod",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(resp.Clusters))
+	assert.Equal(t, clusterID, resp.Clusters[0].ID)
+	assert.Equal(t, map[string]string{"env": "prod", "region": "eu"}, resp.Clusters[0].Labels)
+	assert.Equal(t, []string{"prod", "prod-eu"}, resp.Clusters[0].Groups)
+}
+
+func TestVizierClusterInfo_GetClusterInfoWithGroup(t *testing.T) {
+	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	clusterID := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVzMgr.EXPECT().GetClusterGroups(gomock.Any(), orgID).Return(&vzmgrpb.GetClusterGroupsResponse{
+		Groups: []*vzmgrpb.ClusterGroup{
+			{Name: "prod", Selector: "env=prod"},
+			{Name: "prod-eu", Selector: "env=prod,region=eu"},
+		},
+	}, nil)
+
+	mockClients.MockVzMgr.EXPECT().GetViziersBySelector(gomock.Any(), &vzmgrpb.GetViziersBySelectorRequest{
+		OrgID:    orgID,
+		Selector: "env=prod,region=eu",
+	}).Return(&vzmgrpb.GetViziersByOrgResponse{
+		VizierIDs: []*uuidpb.UUID{clusterID},
+	}, nil)
+
+	mockClients.MockVzMgr.EXPECT().GetVizierInfos(gomock.Any(), &vzmgrpb.GetVizierInfosRequest{
+		VizierIDs: []*uuidpb.UUID{clusterID},
+	}).Return(&vzmgrpb.GetVizierInfosResponse{
+		VizierInfos: []*cvmsgspb.VizierInfo{{
+			VizierID:    clusterID,
+			Status:      cvmsgspb.VZ_ST_HEALTHY,
+			Config:      &cvmsgspb.VizierConfig{},
+			ClusterName: "some cluster",
+			Labels:      map[string]string{"env": "prod", "region": "eu"},
+		}},
+	}, nil)
+
+	vzClusterInfoServer := &controllers.VizierClusterInfo{
+		VzMgr: mockClients.MockVzMgr,
+	}
+
+	resp, err := vzClusterInfoServer.GetClusterInfo(ctx, &cloudpb.GetClusterInfoRequest{
+		Group: "prod-eu",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(resp.Clusters))
+	assert.Equal(t, clusterID, resp.Clusters[0].ID)
+	assert.Equal(t, []string{"prod", "prod-eu"}, resp.Clusters[0].Groups)
+}
+
+func TestVizierClusterInfo_GetClusterInfoWithGroup_Errors(t *testing.T) {
+	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	tests := []struct {
+		name string
+		req  *cloudpb.GetClusterInfoRequest
+		code codes.Code
+	}{
+		{
+			name: "unknown group",
+			req:  &cloudpb.GetClusterInfoRequest{Group: "staging"},
+			code: codes.NotFound,
+		},
+		{
+			name: "group and selector",
+			req:  &cloudpb.GetClusterInfoRequest{Group: "prod", LabelSelector: "env=prod"},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+			defer cleanup()
+			ctx := CreateTestContext()
+
+			mockClients.MockVzMgr.EXPECT().GetClusterGroups(gomock.Any(), orgID).Return(&vzmgrpb.GetClusterGroupsResponse{
+				Groups: []*vzmgrpb.ClusterGroup{
+					{Name: "prod", Selector: "env=prod"},
+				},
+			}, nil)
+
+			vzClusterInfoServer := &controllers.VizierClusterInfo{
+				VzMgr: mockClients.MockVzMgr,
+			}
+
+			_, err := vzClusterInfoServer.GetClusterInfo(ctx, test.req)
+			require.Error(t, err)
+			assert.Equal(t, test.code, status.Code(err))
+		})
+	}
+}
+
+func TestVizierClusterInfo_UpdateClusterLabels(t *testing.T) {
+	clusterID := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+
+	mockClients.MockVzMgr.EXPECT().UpdateVizierLabels(gomock.Any(), &vzmgrpb.UpdateVizierLabelsRequest{
+		VizierID: clusterID,
+		Labels:   map[string]string{"env": "prod"},
+	}).Return(&types.Empty{}, nil)
+
+	vzClusterInfoServer := &controllers.VizierClusterInfo{
+		VzMgr: mockClients.MockVzMgr,
+	}
+
+	resp, err := vzClusterInfoServer.UpdateClusterLabels(CreateTestContext(), &cloudpb.UpdateClusterLabelsRequest{
+		ID:     clusterID,
+		Labels: map[string]string{"env": "prod"},
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, resp)
+
+	_, err = vzClusterInfoServer.UpdateClusterLabels(CreateTestContextWithRole(svcutils.MemberRole), &cloudpb.UpdateClusterLabelsRequest{
+		ID:     clusterID,
+		Labels: map[string]string{"env": "staging"},
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestVizierClusterInfo_ClusterGroups(t *testing.T) {
+	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	groupID := utils.ProtoFromUUIDStrOrNil("8ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	vzClusterInfoServer := &controllers.VizierClusterInfo{
+		VzMgr: mockClients.MockVzMgr,
+	}
+
+	mockClients.MockVzMgr.EXPECT().CreateClusterGroup(gomock.Any(), &vzmgrpb.ClusterGroup{
+		OrgID:    orgID,
+		Name:     "prod",
+		Selector: "env=prod",
+	}).Return(&vzmgrpb.ClusterGroup{ID: groupID, OrgID: orgID, Name: "prod", Selector: "env=prod"}, nil)
+	g, err := vzClusterInfoServer.CreateClusterGroup(ctx, &cloudpb.CreateClusterGroupRequest{Name: "prod", Selector: "env=prod"})
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.ClusterGroup{ID: groupID, Name: "prod", Selector: "env=prod"}, g)
+
+	mockClients.MockVzMgr.EXPECT().UpdateClusterGroup(gomock.Any(), &vzmgrpb.ClusterGroup{
+		OrgID:    orgID,
+		Name:     "prod",
+		Selector: "env=production",
+	}).Return(&vzmgrpb.ClusterGroup{ID: groupID, OrgID: orgID, Name: "prod", Selector: "env=production"}, nil)
+	g, err = vzClusterInfoServer.UpdateClusterGroup(ctx, &cloudpb.UpdateClusterGroupRequest{Name: "prod", Selector: "env=production"})
+	require.NoError(t, err)
+	assert.Equal(t, "env=production", g.Selector)
+
+	mockClients.MockVzMgr.EXPECT().GetClusterGroups(gomock.Any(), orgID).Return(&vzmgrpb.GetClusterGroupsResponse{
+		Groups: []*vzmgrpb.ClusterGroup{{ID: groupID, OrgID: orgID, Name: "prod", Selector: "env=production"}},
+	}, nil)
+	groups, err := vzClusterInfoServer.GetClusterGroups(ctx, &cloudpb.GetClusterGroupsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []*cloudpb.ClusterGroup{{ID: groupID, Name: "prod", Selector: "env=production"}}, groups.Groups)
+
+	mockClients.MockVzMgr.EXPECT().DeleteClusterGroup(gomock.Any(), &vzmgrpb.DeleteClusterGroupRequest{
+		OrgID: orgID,
+		Name:  "prod",
+	}).Return(&types.Empty{}, nil)
+	_, err = vzClusterInfoServer.DeleteClusterGroup(ctx, &cloudpb.DeleteClusterGroupRequest{Name: "prod"})
+	require.NoError(t, err)
+
+	_, err = vzClusterInfoServer.CreateClusterGroup(CreateTestContextWithRole(svcutils.MemberRole), &cloudpb.CreateClusterGroupRequest{Name: "dev"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestVizierClusterInfo_Rollouts(t *testing.T) {
+	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	rolloutID := utils.ProtoFromUUIDStrOrNil("8ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	clusterID := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	vzClusterInfoServer := &controllers.VizierClusterInfo{
+		VzMgr: mockClients.MockVzMgr,
+	}
+
+	policy := &vzmgrpb.RolloutPolicy{
+		OrgID:           orgID,
+		Waves:           []*vzmgrpb.RolloutWave{{Selector: "env=staging"}},
+		SoakTimeS:       3600,
+		HealthDeadlineS: 600,
+	}
+	mockClients.MockVzMgr.EXPECT().UpdateRolloutPolicy(gomock.Any(), policy).Return(policy, nil)
+	p, err := vzClusterInfoServer.UpdateRolloutPolicy(ctx, &cloudpb.RolloutPolicy{
+		Waves:           []*cloudpb.RolloutWave{{Selector: "env=staging"}},
+		SoakTimeS:       3600,
+		HealthDeadlineS: 600,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.RolloutPolicy{
+		Waves:           []*cloudpb.RolloutWave{{Selector: "env=staging"}},
+		SoakTimeS:       3600,
+		HealthDeadlineS: 600,
+	}, p)
+
+	mockClients.MockVzMgr.EXPECT().GetRollouts(gomock.Any(), orgID).Return(&vzmgrpb.GetRolloutsResponse{
+		Rollouts: []*vzmgrpb.Rollout{{
+			ID:          rolloutID,
+			OrgID:       orgID,
+			Version:     "0.1.2",
+			State:       vzmgrpb.ROLLOUT_HALTED,
+			CurrentWave: 0,
+			NumWaves:    2,
+			Message:     "1 of 1 clusters in wave 0 not healthy",
+			Clusters:    []*vzmgrpb.RolloutCluster{{VizierID: clusterID, Wave: 0, Updated: false}},
+		}},
+	}, nil)
+	rollouts, err := vzClusterInfoServer.GetRollouts(ctx, &cloudpb.GetRolloutsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(rollouts.Rollouts))
+	assert.Equal(t, cloudpb.ROLLOUT_HALTED, rollouts.Rollouts[0].State)
+	assert.Equal(t, []*cloudpb.RolloutCluster{{ID: clusterID, Wave: 0, Updated: false}}, rollouts.Rollouts[0].Clusters)
+
+	mockClients.MockVzMgr.EXPECT().ResumeRollout(gomock.Any(), &vzmgrpb.RolloutRequest{
+		OrgID:     orgID,
+		RolloutID: rolloutID,
+	}).Return(&vzmgrpb.Rollout{ID: rolloutID, OrgID: orgID, Version: "0.1.2", State: vzmgrpb.ROLLOUT_RUNNING, NumWaves: 2}, nil)
+	r, err := vzClusterInfoServer.ResumeRollout(ctx, &cloudpb.RolloutRequest{ID: rolloutID})
+	require.NoError(t, err)
+	assert.Equal(t, cloudpb.ROLLOUT_RUNNING, r.State)
+
+	_, err = vzClusterInfoServer.PauseRollout(CreateTestContextWithRole(svcutils.MemberRole), &cloudpb.RolloutRequest{ID: rolloutID})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -24,9 +24,7 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 
-	"cloud.google.com/go/storage"
 	bindata "github.com/golang-migrate/migrate/source/go_bindata"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
@@ -39,6 +37,7 @@ import (
 	atpb "px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
 	"px.dev/pixie/src/cloud/artifact_tracker/controllers"
 	"px.dev/pixie/src/cloud/artifact_tracker/schema"
+	"px.dev/pixie/src/cloud/shared/blobstore"
 	"px.dev/pixie/src/cloud/shared/pgmigrate"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/shared/services/healthz"
@@ -94,17 +93,13 @@ func main() {
 
 	saCfg := loadServiceAccountConfig()
 
-	ctx := context.Background()
-	var client *storage.Client
-	var err error
-
+	gcsOpt := option.WithoutAuthentication()
 	if saCfg != nil {
-		client, err = storage.NewClient(ctx, option.WithCredentialsFile(viper.GetString("sa_key_path")))
-	} else {
-		client, err = storage.NewClient(ctx, option.WithoutAuthentication())
+		gcsOpt = option.WithCredentialsFile(viper.GetString("sa_key_path"))
 	}
+	store, err := blobstore.NewFromFlags(context.Background(), saCfg, gcsOpt)
 	if err != nil {
-		log.WithError(err).Fatal("Failed to initialize GCS client.")
+		log.WithError(err).Fatal("Failed to initialize blob store.")
 	}
 
 	env := artifacttrackerenv.New()
@@ -112,7 +107,12 @@ func main() {
 	db := mustLoadDB()
 	bucket := viper.GetString("artifact_bucket")
 	releaseBucket := viper.GetString("release_artifact_bucket")
-	svr := controllers.NewServer(db, stiface.AdaptClient(client), bucket, releaseBucket, saCfg)
+	svr := controllers.NewServer(db, store, bucket, releaseBucket)
+
+	// The filesystem store has no way of signing URLs for another server, so it serves downloads itself.
+	if h, ok := store.(http.Handler); ok {
+		mux.Handle("/blobs/", http.StripPrefix("/blobs", h))
+	}
 
 	serverOpts := &server.GRPCServerOptions{
 		DisableAuth: map[string]bool{
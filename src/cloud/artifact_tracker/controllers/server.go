@@ -26,24 +26,19 @@ import (
 	"strings"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/gogo/protobuf/types"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"github.com/spf13/viper"
-	"golang.org/x/oauth2/jwt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	apb "px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
+	"px.dev/pixie/src/cloud/shared/blobstore"
 	vpb "px.dev/pixie/src/shared/artifacts/versionspb"
 	"px.dev/pixie/src/shared/artifacts/versionspb/utils"
 )
 
-// URLSigner is the function used to sign urls.
-var URLSigner = storage.SignedURL
-
 const (
 	vizierArtifactName   = "vizier"
 	cliArtifactName      = "cli"
@@ -53,15 +48,14 @@ const (
 // Server is the controller for the artifact tracker service.
 type Server struct {
 	db             *sqlx.DB
-	sc             stiface.Client
+	store          blobstore.Store
 	artifactBucket string
 	releaseBucket  string
-	gcsSA          *jwt.Config
 }
 
 // NewServer creates a new artifact tracker server.
-func NewServer(db *sqlx.DB, client stiface.Client, bucket string, releaseBucket string, gcsSA *jwt.Config) *Server {
-	return &Server{db: db, sc: client, artifactBucket: bucket, releaseBucket: releaseBucket, gcsSA: gcsSA}
+func NewServer(db *sqlx.DB, store blobstore.Store, bucket string, releaseBucket string) *Server {
+	return &Server{db: db, store: store, artifactBucket: bucket, releaseBucket: releaseBucket}
 }
 
 func (s *Server) getArtifactListSpecifiedVizier() (*vpb.ArtifactSet, error) {
@@ -268,46 +262,25 @@ func (s *Server) GetDownloadLink(ctx context.Context, in *apb.GetDownloadLinkReq
 	expires := time.Now().Add(time.Minute * 60)
 
 	// Artifact found, generate the download link.
-	// location: gs://<artifact_bucket>/cli/2019.10.03-1/cli_linux_amd64
+	// location: <artifact_bucket>/cli/2019.10.03-1/cli_linux_amd64
 
-	// If the version is not an official release, it is contained in a private bucket which requires creds to
-	// generate a signed URL.
+	// If the version is not an official release, it is contained in a private bucket.
 	release := !strings.Contains(versionStr, "-")
 	bucket := s.artifactBucket
 	if release {
 		bucket = s.releaseBucket
 	}
-	if !release && s.gcsSA == nil {
-		return nil, status.Error(codes.Internal, "Could not get download URL for non-release build without creds")
-	}
 
-	var url string
-	var err error
 	objectPath := path.Join(name, versionStr, fmt.Sprintf("%s_%s", name, downloadSuffix(at)))
-	if !release {
-		url, err = URLSigner(bucket, objectPath, &storage.SignedURLOptions{
-			GoogleAccessID: s.gcsSA.Email,
-			PrivateKey:     s.gcsSA.PrivateKey,
-			Method:         "GET",
-			Expires:        expires,
-			Scheme:         0,
-		})
-
-		if err != nil {
-			return nil, status.Error(codes.Internal, "failed to sign download URL")
-		}
-	} else {
-		attr, err := s.sc.Bucket(bucket).Object(objectPath).Attrs(ctx)
-		if err != nil {
-			return nil, status.Error(codes.Internal, "failed to get URL")
-		}
-		url = attr.MediaLink
+	url, err := s.store.DownloadURL(ctx, bucket, objectPath, expires)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get download URL")
 	}
 
 	tpb, _ := types.TimestampProto(expires)
 
 	sha256ObjectPath := objectPath + ".sha256"
-	r, err := s.sc.Bucket(bucket).Object(sha256ObjectPath).NewReader(ctx)
+	r, err := s.store.NewReader(ctx, bucket, sha256ObjectPath)
 
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to fetch sha256 file")
@@ -28,7 +28,6 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/gogo/protobuf/types"
 	bindata "github.com/golang-migrate/migrate/source/go_bindata"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,6 +38,7 @@ import (
 	apb "px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
 	"px.dev/pixie/src/cloud/artifact_tracker/controllers"
 	"px.dev/pixie/src/cloud/artifact_tracker/schema"
+	"px.dev/pixie/src/cloud/shared/blobstore"
 	vpb "px.dev/pixie/src/shared/artifacts/versionspb"
 	"px.dev/pixie/src/shared/services/pgtest"
 	"px.dev/pixie/src/utils/testingutils"
@@ -71,8 +71,8 @@ func testMain(m *testing.M) error {
 	return nil
 }
 
-func mustSetupFakeBucket(t *testing.T) stiface.Client {
-	return testingutils.NewMockGCSClient(map[string]*testingutils.MockGCSBucket{
+func mustSetupFakeBucket(t *testing.T, sa *jwt.Config) blobstore.Store {
+	client := testingutils.NewMockGCSClient(map[string]*testingutils.MockGCSBucket{
 		"test-bucket": testingutils.NewMockGCSBucket(
 			map[string]*testingutils.MockGCSObject{
 				"cli/1.2.1-pre.3/cli_linux_amd64.sha256": testingutils.NewMockGCSObject([]byte("the-sha256"), nil),
@@ -80,6 +80,7 @@ func mustSetupFakeBucket(t *testing.T) stiface.Client {
 			nil,
 		),
 	})
+	return blobstore.NewGCSStore(client, sa)
 }
 
 func mustLoadTestData(db *sqlx.DB) {
@@ -125,7 +126,7 @@ func mustLoadTestData(db *sqlx.DB) {
 func TestServer_GetArtifactList(t *testing.T) {
 	mustLoadTestData(db)
 
-	server := controllers.NewServer(db, nil, "bucket", "release-bucket", nil)
+	server := controllers.NewServer(db, nil, "bucket", "release-bucket")
 
 	testCases := []struct {
 		name         string
@@ -249,13 +250,13 @@ func TestServer_GetArtifactList(t *testing.T) {
 
 func TestServer_GetDownloadLink(t *testing.T) {
 	mustLoadTestData(db)
-	storageClient := mustSetupFakeBucket(t)
-
-	server := controllers.NewServer(db, storageClient, "test-bucket", "test-release", &jwt.Config{
+	store := mustSetupFakeBucket(t, &jwt.Config{
 		Email:      "test@test.com",
 		PrivateKey: []byte("the-key"),
 	})
 
+	server := controllers.NewServer(db, store, "test-bucket", "test-release")
+
 	testCases := []struct {
 		name         string
 		req          apb.GetDownloadLinkRequest
@@ -310,7 +311,7 @@ func TestServer_GetDownloadLink(t *testing.T) {
 		},
 	}
 
-	controllers.URLSigner = func(bucket, name string, opts *storage.SignedURLOptions) (s string, err error) {
+	blobstore.GCSURLSigner = func(bucket, name string, opts *storage.SignedURLOptions) (s string, err error) {
 		return "the-url", nil
 	}
 	// Only testing error cases for now because the storage API is hard to mock.
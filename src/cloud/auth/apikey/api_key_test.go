@@ -84,13 +84,13 @@ func testMain(m *testing.M) error {
 
 func createTestContext() context.Context {
 	sCtx := authcontext.New()
-	sCtx.Claims = jwtutils.GenerateJWTForUser(testAuthUserID.String(), testAuthOrgID.String(), "test@test.com", time.Now(), "pixie")
+	sCtx.Claims = jwtutils.GenerateJWTForUser(testAuthUserID.String(), testAuthOrgID.String(), "test@test.com", time.Now(), "pixie", nil)
 	return authcontext.NewContext(context.Background(), sCtx)
 }
 
 func createTestAPIUserContext() context.Context {
 	sCtx := authcontext.New()
-	sCtx.Claims = jwtutils.GenerateJWTForAPIUser(testAuthUserID.String(), testAuthOrgID.String(), time.Now(), "pixie")
+	sCtx.Claims = jwtutils.GenerateJWTForAPIUser(testAuthUserID.String(), testAuthOrgID.String(), time.Now(), "pixie", nil)
 	return authcontext.NewContext(context.Background(), sCtx)
 }
 
@@ -32,6 +32,7 @@ import (
 	"px.dev/pixie/src/cloud/auth/authenv"
 	"px.dev/pixie/src/cloud/auth/authpb"
 	"px.dev/pixie/src/cloud/auth/controllers"
+	"px.dev/pixie/src/cloud/auth/devicecode"
 	"px.dev/pixie/src/cloud/auth/schema"
 	"px.dev/pixie/src/cloud/shared/pgmigrate"
 	"px.dev/pixie/src/shared/services"
@@ -112,6 +113,7 @@ func main() {
 	s := server.NewPLServer(env, mux)
 	authpb.RegisterAuthServiceServer(s.GRPCServer(), svr)
 	authpb.RegisterAPIKeyServiceServer(s.GRPCServer(), apiKeyMgr)
+	authpb.RegisterDeviceAuthServiceServer(s.GRPCServer(), devicecode.New(env, db))
 
 	s.Start()
 	s.StopOnInterrupt()
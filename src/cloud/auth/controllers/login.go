@@ -35,6 +35,7 @@ import (
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/auth/authpb"
 	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/shared/rbac"
 	"px.dev/pixie/src/shared/services/authcontext"
 	srvutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
@@ -318,7 +319,7 @@ func (s *Server) completeUserLogin(ctx context.Context, userInfo *UserInfo, orgI
 		}
 	}
 	// Update user's profile photo.
-	_, err := pc.UpdateUser(ctx, &profilepb.UpdateUserRequest{
+	user, err := pc.UpdateUser(ctx, &profilepb.UpdateUserRequest{
 		ID:             utils.ProtoFromUUIDStrOrNil(userInfo.PLUserID),
 		DisplayPicture: &types.StringValue{Value: userInfo.Picture},
 	})
@@ -327,7 +328,7 @@ func (s *Server) completeUserLogin(ctx context.Context, userInfo *UserInfo, orgI
 	}
 
 	expiresAt := time.Now().Add(RefreshTokenValidDuration)
-	claims := srvutils.GenerateJWTForUser(userInfo.PLUserID, orgID, userInfo.Email, expiresAt, viper.GetString("domain_name"))
+	claims := srvutils.GenerateJWTForUser(userInfo.PLUserID, orgID, userInfo.Email, expiresAt, viper.GetString("domain_name"), rbac.UserRolesFromProto(user))
 	tkn, err := srvutils.SignJWTClaims(claims, s.env.JWTSigningKey())
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to generate token")
@@ -580,8 +581,18 @@ func (s *Server) GetAugmentedTokenForAPIKey(ctx context.Context, in *authpb.GetA
 		return nil, status.Errorf(codes.Internal, "Failed to generate auth token")
 	}
 
+	// API keys act with the roles of the user that created them.
+	var roles *srvutils.UserRoles
+	if userID != uuid.Nil {
+		user, err := s.env.ProfileClient().GetUser(ctxWithSvcCreds, utils.ProtoFromUUID(userID))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to generate auth token")
+		}
+		roles = rbac.UserRolesFromProto(user)
+	}
+
 	// Create JWT for user/org.
-	claims := srvutils.GenerateJWTForAPIUser(userID.String(), orgID.String(), time.Now().Add(AugmentedTokenValidDuration), viper.GetString("domain_name"))
+	claims := srvutils.GenerateJWTForAPIUser(userID.String(), orgID.String(), time.Now().Add(AugmentedTokenValidDuration), viper.GetString("domain_name"), roles)
 	token, err := srvutils.SignJWTClaims(claims, s.env.JWTSigningKey())
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to generate auth token")
@@ -609,6 +620,9 @@ func (s *Server) GetAugmentedToken(
 		return nil, status.Error(codes.Unauthenticated, "Invalid auth/user")
 	}
 
+	// The roles of the user are refreshed from the profile service, so that role changes
+	// take effect without having to log in again.
+	var roles *srvutils.UserRoles
 	// We perform extra checks for user tokens.
 	if srvutils.GetClaimsType(aCtx.Claims) == srvutils.UserClaimType {
 		// Check to make sure that the org and user exist in the system.
@@ -637,6 +651,7 @@ func (s *Server) GetAugmentedToken(
 			if uuid.FromStringOrNil(orgIDstr) != utils.UUIDFromProtoOrNil(userInfo.OrgID) {
 				return nil, status.Error(codes.Unauthenticated, "Mismatched org")
 			}
+			roles = rbac.UserRolesFromProto(userInfo)
 		}
 	}
 
@@ -644,6 +659,9 @@ func (s *Server) GetAugmentedToken(
 	claims := *aCtx.Claims
 	claims.IssuedAt = time.Now().Unix()
 	claims.ExpiresAt = time.Now().Add(AugmentedTokenValidDuration).Unix()
+	if roles != nil {
+		srvutils.SetRoleScopes(&claims, roles)
+	}
 
 	augmentedToken, err := srvutils.SignJWTClaims(&claims, s.env.JWTSigningKey())
 	if err != nil {
@@ -782,7 +800,7 @@ func (s *Server) GetAuthConnectorToken(ctx context.Context, req *authpb.GetAuthC
 	// Create access token.
 	now := time.Now()
 	expiresAt := now.Add(AuthConnectorTokenValidDuration)
-	claims := srvutils.GenerateJWTForUser(utils.UUIDFromProtoOrNil(userInfo.ID).String(), utils.UUIDFromProtoOrNil(userInfo.OrgID).String(), userInfo.Email, expiresAt, viper.GetString("domain_name"))
+	claims := srvutils.GenerateJWTForUser(utils.UUIDFromProtoOrNil(userInfo.ID).String(), utils.UUIDFromProtoOrNil(userInfo.OrgID).String(), userInfo.Email, expiresAt, viper.GetString("domain_name"), rbac.UserRolesFromProto(userInfo))
 	token, err := srvutils.ProtoToToken(claims)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create authConnector token")
@@ -839,6 +857,7 @@ func (s *Server) RefetchToken(ctx context.Context, in *authpb.RefetchTokenReques
 		user.Email,
 		expiresAt,
 		viper.GetString("domain_name"),
+		rbac.UserRolesFromProto(user),
 	)
 	tkn, err := srvutils.SignJWTClaims(claims, s.env.JWTSigningKey())
 	if err != nil {
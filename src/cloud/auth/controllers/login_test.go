@@ -967,6 +967,7 @@ func TestServer_GetAugmentedToken(t *testing.T) {
 	mockUserInfo := &profilepb.UserInfo{
 		ID:    utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID),
 		OrgID: utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID),
+		Role:  profilepb.OR_VIEWER,
 	}
 	mockOrgInfo := &profilepb.OrgInfo{
 		ID: utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID),
@@ -1004,6 +1005,12 @@ func TestServer_GetAugmentedToken(t *testing.T) {
 	assert.True(t, resp.ExpiresAt > 0)
 
 	verifyToken(t, resp.Token, testingutils.TestUserID, testingutils.TestOrgID, resp.ExpiresAt, "jwtkey")
+
+	// The role in the token should be replaced with the user's current role.
+	parsed, err := srvutils.ParseToken(resp.Token, "jwtkey", "withpixie.ai")
+	require.NoError(t, err)
+	assert.Contains(t, srvutils.GetScopes(parsed), "role:viewer")
+	assert.NotContains(t, srvutils.GetScopes(parsed), "role:owner")
 }
 
 func TestServer_GetAugmentedToken_Service(t *testing.T) {
@@ -1063,7 +1070,7 @@ func TestServer_GetAugmentedToken_EmptyOrg(t *testing.T) {
 	s, err := controllers.NewServer(env, a, nil)
 	require.NoError(t, err)
 
-	claims := srvutils.GenerateJWTForUser(testingutils.TestUserID, "", "testing@testing.org", time.Now().Add(time.Hour), "withpixie.ai")
+	claims := srvutils.GenerateJWTForUser(testingutils.TestUserID, "", "testing@testing.org", time.Now().Add(time.Hour), "withpixie.ai", nil)
 	token := testingutils.SignPBClaims(t, claims, "jwtkey")
 	req := &authpb.GetAugmentedAuthTokenRequest{
 		Token: token,
@@ -1284,7 +1291,7 @@ func TestServer_GetAugmentedTokenAPIUser(t *testing.T) {
 	s, err := controllers.NewServer(env, a, nil)
 	require.NoError(t, err)
 
-	claims := srvutils.GenerateJWTForAPIUser(testingutils.TestUserID, testingutils.TestOrgID, time.Now().Add(30*time.Minute), "withpixie.ai")
+	claims := srvutils.GenerateJWTForAPIUser(testingutils.TestUserID, testingutils.TestOrgID, time.Now().Add(30*time.Minute), "withpixie.ai", nil)
 	token := testingutils.SignPBClaims(t, claims, "jwtkey")
 	req := &authpb.GetAugmentedAuthTokenRequest{
 		Token: token,
@@ -1324,6 +1331,13 @@ func TestServer_GetAugmentedTokenFromAPIKey(t *testing.T) {
 	mockOrg.EXPECT().
 		GetOrg(gomock.Any(), utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID)).
 		Return(mockOrgInfo, nil)
+	mockProfile.EXPECT().
+		GetUser(gomock.Any(), utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID)).
+		Return(&profilepb.UserInfo{
+			ID:    utils.ProtoFromUUIDStrOrNil(testingutils.TestUserID),
+			OrgID: utils.ProtoFromUUIDStrOrNil(testingutils.TestOrgID),
+			Role:  profilepb.OR_ADMIN,
+		}, nil)
 
 	viper.Set("jwt_signing_key", "jwtkey")
 	viper.Set("domain_name", "withpixie.ai")
@@ -1353,6 +1367,7 @@ func TestServer_GetAugmentedTokenFromAPIKey(t *testing.T) {
 	assert.Equal(t, testingutils.TestOrgID, srvutils.GetOrgID(parsed))
 	assert.Equal(t, resp.ExpiresAt, parsed.Expiration().Unix())
 	assert.True(t, srvutils.GetIsAPIUser(parsed))
+	assert.Contains(t, srvutils.GetScopes(parsed), "role:admin")
 }
 
 func TestServer_Signup_LookupHostedDomain(t *testing.T) {
@@ -2319,7 +2334,7 @@ func TestServer_GetAuthConnectorToken(t *testing.T) {
 	userPb := utils.ProtoFromUUIDStrOrNil(userID)
 
 	sCtx := authcontext.New()
-	sCtx.Claims = srvutils.GenerateJWTForUser(userID, orgID, "test@test.com", time.Now(), "pixie")
+	sCtx.Claims = srvutils.GenerateJWTForUser(userID, orgID, "test@test.com", time.Now(), "pixie", nil)
 	ctx := authcontext.NewContext(context.Background(), sCtx)
 
 	mockProfile := mock_profile.NewMockProfileServiceClient(ctrl)
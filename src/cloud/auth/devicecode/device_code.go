@@ -0,0 +1,263 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package devicecode
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/auth/authenv"
+	"px.dev/pixie/src/cloud/auth/authpb"
+	"px.dev/pixie/src/cloud/shared/rbac"
+	"px.dev/pixie/src/shared/services/authcontext"
+	srvutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+const (
+	// CodeValidDuration is how long a device code can be used after it is created.
+	CodeValidDuration = 10 * time.Minute
+	// PollInterval is the minimum interval between polls of a device code.
+	PollInterval = 5 * time.Second
+	// RefreshTokenValidDuration is how long the refresh token handed out for an approved device code is valid.
+	RefreshTokenValidDuration = 90 * 24 * time.Hour
+
+	// userCodeCharset only contains consonants, to avoid ambiguous characters and accidental words.
+	userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength  = 8
+	deviceCodeBytes = 32
+
+	statePending  = "pending"
+	stateApproved = "approved"
+	stateDenied   = "denied"
+)
+
+// Service implements the device authorization flow.
+type Service struct {
+	env authenv.AuthEnv
+	db  *sqlx.DB
+}
+
+// New creates a new Service.
+func New(env authenv.AuthEnv, db *sqlx.DB) *Service {
+	return &Service{
+		env: env,
+		db:  db,
+	}
+}
+
+func generateUserCode() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(userCodeCharset)))
+	for i := 0; i < userCodeLength; i++ {
+		if i == userCodeLength/2 {
+			sb.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(userCodeCharset[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+func generateDeviceCode() (string, error) {
+	b := make([]byte, deviceCodeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// normalizeUserCode allows users to enter the user code in lower case and without the separator.
+func normalizeUserCode(code string) string {
+	code = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
+
+// CreateDeviceCode starts a new device authorization flow.
+func (s *Service) CreateDeviceCode(ctx context.Context, req *authpb.CreateDeviceCodeRequest) (*authpb.CreateDeviceCodeResponse, error) {
+	// Clean up any expired codes, so that their user codes can be reused.
+	_, err := s.db.ExecContext(ctx, `DELETE FROM device_codes WHERE expires_at < NOW()`)
+	if err != nil {
+		log.WithError(err).Error("Failed to delete expired device codes")
+	}
+
+	deviceCode, err := generateDeviceCode()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to generate device code")
+	}
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to generate user code")
+	}
+
+	expiresAt := time.Now().Add(CodeValidDuration)
+	query := `INSERT INTO device_codes(hashed_device_code, user_code, client_name, expires_at)
+                VALUES(sha256($1), $2, $3, $4)`
+	_, err = s.db.ExecContext(ctx, query, deviceCode, userCode, req.ClientName, expiresAt.UTC())
+	if err != nil {
+		log.WithError(err).Error("Failed to insert device code")
+		return nil, status.Error(codes.Internal, "failed to create device code")
+	}
+
+	return &authpb.CreateDeviceCodeResponse{
+		DeviceCode: deviceCode,
+		UserCode:   userCode,
+		ExpiresAt:  expiresAt.Unix(),
+		Interval:   int64(PollInterval / time.Second),
+	}, nil
+}
+
+// ApproveDeviceCode approves or denies the device code on behalf of the logged in user.
+func (s *Service) ApproveDeviceCode(ctx context.Context, req *authpb.ApproveDeviceCodeRequest) (*authpb.ApproveDeviceCodeResponse, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if srvutils.GetClaimsType(sCtx.Claims) != srvutils.UserClaimType {
+		return nil, status.Error(codes.Unauthenticated, "device codes can only be approved by users")
+	}
+	userClaims := sCtx.Claims.GetUserClaims()
+
+	state := stateDenied
+	if req.Approved {
+		state = stateApproved
+	}
+
+	var clientName sql.NullString
+	query := `UPDATE device_codes SET state=$1, user_id=$2, org_id=$3
+                WHERE user_code=$4 AND state=$5 AND expires_at > NOW()
+                RETURNING client_name`
+	err = s.db.QueryRowxContext(ctx, query, state, userClaims.UserID, userClaims.OrgID,
+		normalizeUserCode(req.UserCode), statePending).Scan(&clientName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "invalid or expired code")
+		}
+		log.WithError(err).Error("Failed to update device code")
+		return nil, status.Error(codes.Internal, "failed to update device code")
+	}
+
+	return &authpb.ApproveDeviceCodeResponse{
+		ClientName: clientName.String,
+	}, nil
+}
+
+// PollDeviceCode returns the state of the device code, and a refresh token once it has been approved.
+// The device code can only be exchanged for a token once.
+func (s *Service) PollDeviceCode(ctx context.Context, req *authpb.PollDeviceCodeRequest) (*authpb.PollDeviceCodeResponse, error) {
+	var id uuid.UUID
+	var state string
+	var userID uuid.NullUUID
+	var expiresAt time.Time
+	var lastPolledAt sql.NullTime
+	query := `SELECT id, state, user_id, expires_at, last_polled_at
+                FROM device_codes
+                WHERE hashed_device_code=sha256($1)`
+	err := s.db.QueryRowxContext(ctx, query, req.DeviceCode).Scan(&id, &state, &userID, &expiresAt, &lastPolledAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &authpb.PollDeviceCodeResponse{Status: authpb.DCS_EXPIRED}, nil
+		}
+		log.WithError(err).Error("Failed to query device code")
+		return nil, status.Error(codes.Internal, "failed to query device code")
+	}
+
+	now := time.Now()
+	if now.After(expiresAt) {
+		s.deleteDeviceCode(ctx, id)
+		return &authpb.PollDeviceCodeResponse{Status: authpb.DCS_EXPIRED}, nil
+	}
+
+	switch state {
+	case stateDenied:
+		s.deleteDeviceCode(ctx, id)
+		return &authpb.PollDeviceCodeResponse{Status: authpb.DCS_DENIED}, nil
+	case stateApproved:
+		// The code is deleted before the token is handed out, so that it can't be exchanged twice.
+		if !s.deleteDeviceCode(ctx, id) {
+			return &authpb.PollDeviceCodeResponse{Status: authpb.DCS_EXPIRED}, nil
+		}
+		return s.createRefreshToken(ctx, userID.UUID)
+	}
+
+	_, err = s.db.ExecContext(ctx, `UPDATE device_codes SET last_polled_at=$1 WHERE id=$2`, now.UTC(), id)
+	if err != nil {
+		log.WithError(err).Error("Failed to update device code")
+	}
+	if lastPolledAt.Valid && now.Sub(lastPolledAt.Time) < PollInterval {
+		return &authpb.PollDeviceCodeResponse{Status: authpb.DCS_SLOW_DOWN}, nil
+	}
+	return &authpb.PollDeviceCodeResponse{Status: authpb.DCS_PENDING}, nil
+}
+
+// deleteDeviceCode deletes the device code, and returns whether it still existed.
+func (s *Service) deleteDeviceCode(ctx context.Context, id uuid.UUID) bool {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM device_codes WHERE id=$1`, id)
+	if err != nil {
+		log.WithError(err).Error("Failed to delete device code")
+		return false
+	}
+	c, err := res.RowsAffected()
+	return err == nil && c > 0
+}
+
+func (s *Service) createRefreshToken(ctx context.Context, userID uuid.UUID) (*authpb.PollDeviceCodeResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	user, err := s.env.ProfileClient().GetUser(ctx, utils.ProtoFromUUID(userID))
+	if err != nil || user == nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid auth/user")
+	}
+	orgIDStr := ""
+	if !utils.IsNilUUIDProto(user.OrgID) {
+		orgIDStr = utils.UUIDFromProtoOrNil(user.OrgID).String()
+	}
+
+	expiresAt := time.Now().Add(RefreshTokenValidDuration)
+	claims := srvutils.GenerateJWTForUser(userID.String(), orgIDStr, user.Email, expiresAt, viper.GetString("domain_name"), rbac.UserRolesFromProto(user))
+	tkn, err := srvutils.SignJWTClaims(claims, s.env.JWTSigningKey())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to generate token")
+	}
+	return &authpb.PollDeviceCodeResponse{
+		Status:    authpb.DCS_APPROVED,
+		Token:     tkn,
+		ExpiresAt: expiresAt.Unix(),
+	}, nil
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package devicecode
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	bindata "github.com/golang-migrate/migrate/source/go_bindata"
+	"github.com/golang/mock/gomock"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/auth/authenv"
+	"px.dev/pixie/src/cloud/auth/authpb"
+	"px.dev/pixie/src/cloud/auth/schema"
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	mock_profilepb "px.dev/pixie/src/cloud/profile/profilepb/mock"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/pgtest"
+	jwtutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+var (
+	testOrgID  = uuid.FromStringOrNil("223e4567-e89b-12d3-a456-426655440000")
+	testUserID = uuid.FromStringOrNil("423e4567-e89b-12d3-a456-426655440000")
+)
+
+func TestMain(m *testing.M) {
+	err := testMain(m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Got error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+var db *sqlx.DB
+
+func testMain(m *testing.M) error {
+	s := bindata.Resource(schema.AssetNames(), schema.Asset)
+	testDB, teardown, err := pgtest.SetupTestDB(s)
+	if err != nil {
+		return fmt.Errorf("failed to start test database: %w", err)
+	}
+
+	defer teardown()
+	db = testDB
+
+	if c := m.Run(); c != 0 {
+		return fmt.Errorf("some tests failed with code: %d", c)
+	}
+	return nil
+}
+
+func createTestContext() context.Context {
+	sCtx := authcontext.New()
+	sCtx.Claims = jwtutils.GenerateJWTForUser(testUserID.String(), testOrgID.String(), "test@test.com", time.Now(), "pixie", nil)
+	return authcontext.NewContext(context.Background(), sCtx)
+}
+
+func setupService(t *testing.T) (*Service, *mock_profilepb.MockProfileServiceClient, func()) {
+	viper.Set("jwt_signing_key", "jwtkey")
+	viper.Set("domain_name", "withpixie.ai")
+	db.MustExec(`DELETE from device_codes`)
+
+	ctrl := gomock.NewController(t)
+	mockProfile := mock_profilepb.NewMockProfileServiceClient(ctrl)
+	mockOrg := mock_profilepb.NewMockOrgServiceClient(ctrl)
+	env, err := authenv.New(mockProfile, mockOrg)
+	require.NoError(t, err)
+
+	return New(env, db), mockProfile, ctrl.Finish
+}
+
+// resetLastPolled allows the test to poll again without waiting for the poll interval.
+func resetLastPolled() {
+	db.MustExec(`UPDATE device_codes SET last_polled_at=NULL`)
+}
+
+func TestService_ApproveFlow(t *testing.T) {
+	svc, mockProfile, cleanup := setupService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	createResp, err := svc.CreateDeviceCode(ctx, &authpb.CreateDeviceCodeRequest{ClientName: "px on bastion"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, createResp.DeviceCode)
+	assert.Regexp(t, "^[A-Z]{4}-[A-Z]{4}$", createResp.UserCode)
+	assert.Equal(t, int64(5), createResp.Interval)
+
+	pollReq := &authpb.PollDeviceCodeRequest{DeviceCode: createResp.DeviceCode}
+	pollResp, err := svc.PollDeviceCode(ctx, pollReq)
+	require.NoError(t, err)
+	assert.Equal(t, authpb.DCS_PENDING, pollResp.Status)
+
+	// Polling again immediately should ask the client to slow down.
+	pollResp, err = svc.PollDeviceCode(ctx, pollReq)
+	require.NoError(t, err)
+	assert.Equal(t, authpb.DCS_SLOW_DOWN, pollResp.Status)
+
+	// The user code is accepted in lower case and without the separator.
+	approveResp, err := svc.ApproveDeviceCode(createTestContext(), &authpb.ApproveDeviceCodeRequest{
+		UserCode: fmt.Sprintf("%s%s", createResp.UserCode[:4], createResp.UserCode[5:]),
+		Approved: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "px on bastion", approveResp.ClientName)
+
+	mockProfile.EXPECT().GetUser(gomock.Any(), utils.ProtoFromUUID(testUserID)).
+		Return(&profilepb.UserInfo{
+			ID:    utils.ProtoFromUUID(testUserID),
+			OrgID: utils.ProtoFromUUID(testOrgID),
+			Email: "test@test.com",
+		}, nil)
+
+	resetLastPolled()
+	pollResp, err = svc.PollDeviceCode(ctx, pollReq)
+	require.NoError(t, err)
+	assert.Equal(t, authpb.DCS_APPROVED, pollResp.Status)
+	assert.Greater(t, pollResp.ExpiresAt, time.Now().Unix())
+
+	aCtx := authcontext.New()
+	require.NoError(t, aCtx.UseJWTAuth("jwtkey", pollResp.Token, "withpixie.ai"))
+	assert.Equal(t, testUserID.String(), aCtx.Claims.GetUserClaims().UserID)
+	assert.Equal(t, testOrgID.String(), aCtx.Claims.GetUserClaims().OrgID)
+
+	// The device code can only be exchanged once.
+	pollResp, err = svc.PollDeviceCode(ctx, pollReq)
+	require.NoError(t, err)
+	assert.Equal(t, authpb.DCS_EXPIRED, pollResp.Status)
+	assert.Empty(t, pollResp.Token)
+}
+
+func TestService_DenyFlow(t *testing.T) {
+	svc, _, cleanup := setupService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	createResp, err := svc.CreateDeviceCode(ctx, &authpb.CreateDeviceCodeRequest{})
+	require.NoError(t, err)
+
+	_, err = svc.ApproveDeviceCode(createTestContext(), &authpb.ApproveDeviceCodeRequest{
+		UserCode: createResp.UserCode,
+		Approved: false,
+	})
+	require.NoError(t, err)
+
+	// A denied code can't be approved afterwards.
+	_, err = svc.ApproveDeviceCode(createTestContext(), &authpb.ApproveDeviceCodeRequest{
+		UserCode: createResp.UserCode,
+		Approved: true,
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	pollResp, err := svc.PollDeviceCode(ctx, &authpb.PollDeviceCodeRequest{DeviceCode: createResp.DeviceCode})
+	require.NoError(t, err)
+	assert.Equal(t, authpb.DCS_DENIED, pollResp.Status)
+}
+
+func TestService_Expired(t *testing.T) {
+	svc, _, cleanup := setupService(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	createResp, err := svc.CreateDeviceCode(ctx, &authpb.CreateDeviceCodeRequest{})
+	require.NoError(t, err)
+	db.MustExec(`UPDATE device_codes SET expires_at=NOW() - INTERVAL '1 minute'`)
+
+	_, err = svc.ApproveDeviceCode(createTestContext(), &authpb.ApproveDeviceCodeRequest{
+		UserCode: createResp.UserCode,
+		Approved: true,
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	pollResp, err := svc.PollDeviceCode(ctx, &authpb.PollDeviceCodeRequest{DeviceCode: createResp.DeviceCode})
+	require.NoError(t, err)
+	assert.Equal(t, authpb.DCS_EXPIRED, pollResp.Status)
+}
+
+func TestService_ApproveRequiresUser(t *testing.T) {
+	svc, _, cleanup := setupService(t)
+	defer cleanup()
+
+	_, err := svc.ApproveDeviceCode(context.Background(), &authpb.ApproveDeviceCodeRequest{UserCode: "BCDF-GHJK", Approved: true})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,27 @@
+-- This table contains the pending requests of the device authorization flow.
+CREATE TABLE device_codes (
+  -- The ID of the request.
+  id UUID UNIQUE DEFAULT uuid_generate_v4(),
+  -- The SHA-256 hash of the device code. The device code itself is only known to the client.
+  hashed_device_code bytea NOT NULL,
+  -- The short code that the user enters to approve the request.
+  user_code varchar(16) NOT NULL,
+  -- Human readable name of the client that made the request.
+  client_name varchar(256),
+  -- The state of the request: 'pending', 'approved' or 'denied'.
+  state varchar(16) NOT NULL DEFAULT 'pending',
+  -- The user that approved or denied the request.
+  user_id UUID,
+  -- The org of the user that approved or denied the request.
+  org_id UUID,
+  -- Timestamp when the request was created.
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  -- Timestamp when the request expires.
+  expires_at TIMESTAMP NOT NULL,
+  -- Timestamp when the client last polled for the state of the request.
+  last_polled_at TIMESTAMP,
+
+  UNIQUE(hashed_device_code),
+  UNIQUE(user_code),
+  PRIMARY KEY(id)
+);
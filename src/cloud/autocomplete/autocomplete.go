@@ -68,17 +68,31 @@ type Command struct {
 }
 
 var kindLabelToProtoMap = map[string]cloudpb.AutocompleteEntityKind{
-	"svc":    cloudpb.AEK_SVC,
-	"pod":    cloudpb.AEK_POD,
-	"script": cloudpb.AEK_SCRIPT,
-	"ns":     cloudpb.AEK_NAMESPACE,
+	"svc":         cloudpb.AEK_SVC,
+	"pod":         cloudpb.AEK_POD,
+	"script":      cloudpb.AEK_SCRIPT,
+	"ns":          cloudpb.AEK_NAMESPACE,
+	"container":   cloudpb.AEK_CONTAINER,
+	"deploy":      cloudpb.AEK_DEPLOYMENT,
+	"rs":          cloudpb.AEK_REPLICASET,
+	"sts":         cloudpb.AEK_STATEFULSET,
+	"ds":          cloudpb.AEK_DAEMONSET,
+	"table":       cloudpb.AEK_TABLE,
+	"cron_script": cloudpb.AEK_CRON_SCRIPT,
 }
 
 var protoToKindLabelMap = map[cloudpb.AutocompleteEntityKind]string{
-	cloudpb.AEK_SVC:       "svc",
-	cloudpb.AEK_POD:       "pod",
-	cloudpb.AEK_SCRIPT:    "script",
-	cloudpb.AEK_NAMESPACE: "ns",
+	cloudpb.AEK_SVC:         "svc",
+	cloudpb.AEK_POD:         "pod",
+	cloudpb.AEK_SCRIPT:      "script",
+	cloudpb.AEK_NAMESPACE:   "ns",
+	cloudpb.AEK_CONTAINER:   "container",
+	cloudpb.AEK_DEPLOYMENT:  "deploy",
+	cloudpb.AEK_REPLICASET:  "rs",
+	cloudpb.AEK_STATEFULSET: "sts",
+	cloudpb.AEK_DAEMONSET:   "ds",
+	cloudpb.AEK_TABLE:       "table",
+	cloudpb.AEK_CRON_SCRIPT: "cron_script",
 }
 
 // Autocomplete returns a formatted string and suggestions for the given input.
@@ -138,7 +152,10 @@ func parseRunScript(parsedCmd *ebnf.ParsedCmd, cmd *Command, s Suggester, orgID
 	argTypes := make([]cloudpb.AutocompleteEntityKind, 0)
 	scriptTabIndex := -1
 	for i, a := range parsedCmd.Args {
-		if a.Type != nil && *a.Type == "script" {
+		if a.Type == nil {
+			continue
+		}
+		if kind := kindLabelToProtoMap[*a.Type]; kind == cloudpb.AEK_SCRIPT || kind == cloudpb.AEK_CRON_SCRIPT {
 			// Determine if this is a valid script, and if so, what arguments it takes.
 			input := ""
 			if a.Name != nil {
@@ -150,7 +167,7 @@ func parseRunScript(parsedCmd *ebnf.ParsedCmd, cmd *Command, s Suggester, orgID
 				searchTerm = strings.Replace(searchTerm, CursorMarker, "", 1)
 			}
 
-			res, err := s.GetSuggestions([]*SuggestionRequest{{orgID, clusterUID, searchTerm, []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT}, []cloudpb.AutocompleteEntityKind{}}})
+			res, err := s.GetSuggestions([]*SuggestionRequest{{orgID, clusterUID, searchTerm, []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT}, []cloudpb.AutocompleteEntityKind{}}})
 			if err != nil {
 				return -1, nil, nil, err
 			}
@@ -162,11 +179,19 @@ func parseRunScript(parsedCmd *ebnf.ParsedCmd, cmd *Command, s Suggester, orgID
 				argNames = suggestions[0].ArgNames
 				argTypes = suggestions[0].ArgKinds
 				cmd.HasValidScript = true
+				// The matched script may be a cron script rather than a bundle script.
+				kind = cloudpb.AEK_SCRIPT
+				for _, sugg := range suggestions {
+					if sugg.Name == searchTerm && sugg.Kind == cloudpb.AEK_CRON_SCRIPT {
+						kind = cloudpb.AEK_CRON_SCRIPT
+						break
+					}
+				}
 			}
 
 			cmd.TabStops = append(cmd.TabStops, &TabStop{
 				Value:          input,
-				Kind:           cloudpb.AEK_SCRIPT,
+				Kind:           kind,
 				Valid:          exactMatch,
 				ContainsCursor: containsCursor,
 			})
@@ -367,7 +392,7 @@ func parseRunCommand(parsedCmd *ebnf.ParsedCmd, cmd *Command, s Suggester, orgID
 	} else {
 		args, specifiedEntities = parseRunArgs(parsedCmd, cmd, s, scriptTabIndex)
 		if scriptTabIndex == -1 {
-			allowedKinds = append(allowedKinds, cloudpb.AEK_SCRIPT)
+			allowedKinds = append(allowedKinds, cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT)
 		}
 	}
 
@@ -428,7 +453,7 @@ func (cmd *Command) ToFormatString(action cloudpb.AutocompleteActionType, s Sugg
 			// First get a list of the arg types that the autocompleted script should take.
 			knownTypes := make(map[cloudpb.AutocompleteEntityKind]bool)
 			for _, t := range cmd.TabStops {
-				if t.Kind != cloudpb.AEK_UNKNOWN && t.Kind != cloudpb.AEK_SCRIPT {
+				if t.Kind != cloudpb.AEK_UNKNOWN && t.Kind != cloudpb.AEK_SCRIPT && t.Kind != cloudpb.AEK_CRON_SCRIPT {
 					knownTypes[t.Kind] = true
 				}
 			}
@@ -437,7 +462,7 @@ func (cmd *Command) ToFormatString(action cloudpb.AutocompleteActionType, s Sugg
 				scriptTypes = append(scriptTypes, k)
 			}
 			res, err := s.GetSuggestions([]*SuggestionRequest{{orgID, clusterUID, "",
-				[]cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT},
+				[]cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 				scriptTypes}})
 			if err == nil {
 				cmd.TabStops[curTabStop].Suggestions = res[0].Suggestions
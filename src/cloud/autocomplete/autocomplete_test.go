@@ -50,7 +50,7 @@ func TestParseIntoCommand(t *testing.T) {
 						OrgID:        orgID,
 						ClusterUID:   "test",
 						Input:        "px/svc_info",
-						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
 					},
 				},
@@ -107,6 +107,47 @@ func TestParseIntoCommand(t *testing.T) {
 				Executable: true,
 			},
 		},
+		{
+			name:  "valid cron script",
+			input: "script:http_data",
+			requests: [][]*autocomplete.SuggestionRequest{
+				{
+					{
+						OrgID:        orgID,
+						ClusterUID:   "test",
+						Input:        "http_data",
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
+						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
+					},
+				},
+				{},
+			},
+			responses: [][]*autocomplete.SuggestionResult{
+				{
+					{
+						Suggestions: []*autocomplete.Suggestion{
+							{
+								Name:  "http_data",
+								Score: 1,
+								Kind:  cloudpb.AEK_CRON_SCRIPT,
+							},
+						},
+						ExactMatch: true,
+					},
+				},
+				{},
+			},
+			expectedCmd: &autocomplete.Command{
+				TabStops: []*autocomplete.TabStop{
+					{
+						Value: "http_data",
+						Kind:  cloudpb.AEK_CRON_SCRIPT,
+						Valid: true,
+					},
+				},
+				Executable: true,
+			},
+		},
 		{
 			name:  "valid with run",
 			input: "run script:px/svc_info svc_name:pl/test",
@@ -116,7 +157,7 @@ func TestParseIntoCommand(t *testing.T) {
 						OrgID:        orgID,
 						ClusterUID:   "test",
 						Input:        "px/svc_info",
-						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
 					},
 				},
@@ -187,7 +228,7 @@ func TestParseIntoCommand(t *testing.T) {
 						OrgID:        orgID,
 						ClusterUID:   "test",
 						Input:        "px/svc_info",
-						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
 					},
 				},
@@ -253,7 +294,7 @@ func TestParseIntoCommand(t *testing.T) {
 						OrgID:        orgID,
 						ClusterUID:   "test",
 						Input:        "px/svc_info",
-						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
 					},
 				},
@@ -319,7 +360,7 @@ func TestParseIntoCommand(t *testing.T) {
 						OrgID:        orgID,
 						ClusterUID:   "test",
 						Input:        "px/svc_info",
-						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT},
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD},
 					},
 					{
@@ -380,7 +421,7 @@ func TestParseIntoCommand(t *testing.T) {
 						OrgID:        orgID,
 						ClusterUID:   "test",
 						Input:        "px/svc_info",
-						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
 					},
 				},
@@ -446,7 +487,7 @@ func TestParseIntoCommand(t *testing.T) {
 						OrgID:        orgID,
 						ClusterUID:   "test",
 						Input:        "px/svc_info",
-						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
 					},
 				},
@@ -535,7 +576,7 @@ func TestParseIntoCommand(t *testing.T) {
 						OrgID:        orgID,
 						ClusterUID:   "test",
 						Input:        "px/svc_info",
-						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
 					},
 				},
@@ -623,7 +664,7 @@ func TestParseIntoCommand(t *testing.T) {
 						OrgID:        orgID,
 						ClusterUID:   "test",
 						Input:        "px/svc_info",
-						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
 					},
 				},
@@ -711,7 +752,7 @@ func TestParseIntoCommand(t *testing.T) {
 						OrgID:        orgID,
 						ClusterUID:   "test",
 						Input:        "px/svc_info",
-						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+						AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 						AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
 					},
 				},
@@ -1281,7 +1322,7 @@ func TestToFormatString(t *testing.T) {
 					GetSuggestions([]*autocomplete.SuggestionRequest{
 						{
 							orgID, "test", "",
-							[]cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT},
+							[]cloudpb.AutocompleteEntityKind{cloudpb.AEK_POD, cloudpb.AEK_SVC, cloudpb.AEK_NAMESPACE, cloudpb.AEK_SCRIPT, cloudpb.AEK_CRON_SCRIPT},
 							test.suggestionScriptTypes,
 						},
 					}).Return([]*autocomplete.SuggestionResult{
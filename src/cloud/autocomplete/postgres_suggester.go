@@ -0,0 +1,184 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package autocomplete
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/pixie_cli/pkg/script"
+)
+
+// PostgresSuggester provides suggestions based on the metadata entities indexed in postgres.
+type PostgresSuggester struct {
+	db *sqlx.DB
+	// This is temporary, and will be removed once we start indexing scripts.
+	br *script.BundleManager
+}
+
+// NewPostgresSuggester creates a suggester based on the md_entities table in postgres.
+func NewPostgresSuggester(db *sqlx.DB) *PostgresSuggester {
+	return &PostgresSuggester{db: db}
+}
+
+// UpdateScriptBundle updates the script bundle used to populate the suggester's script suggestions.
+func (p *PostgresSuggester) UpdateScriptBundle(br *script.BundleManager) {
+	p.br = br
+}
+
+// maxNGramLen is the length of the longest ngrams that the names are indexed as in elastic.
+const maxNGramLen = 10
+
+// searchTokens splits the input into lowercase tokens in the same way as the search analyzer of the elastic index.
+// Tokens longer than the longest indexed ngram are dropped, since they can't match any name in elastic either.
+func searchTokens(input string) []string {
+	tokens := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-' || r == '/' || r == '_'
+	})
+	matchable := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if utf8.RuneCountInString(t) <= maxNGramLen {
+			matchable = append(matchable, t)
+		}
+	}
+	return matchable
+}
+
+func likePattern(token string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(token) + "%"
+}
+
+// highlightName wraps the sections of the name which contain any of the tokens in <em></em>, in the same format
+// as the highlights returned by elastic.
+func highlightName(name string, tokens []string) string {
+	lower := strings.ToLower(name)
+	if len(lower) != len(name) {
+		// The highlight indexes are byte offsets into the name, so they can't be computed from the lowercase name.
+		return ""
+	}
+
+	matched := make([]bool, len(name))
+	found := false
+	for _, t := range tokens {
+		for i := 0; i < len(lower); {
+			j := strings.Index(lower[i:], t)
+			if j < 0 {
+				break
+			}
+			for k := i + j; k < i+j+len(t); k++ {
+				matched[k] = true
+			}
+			found = true
+			i += j + 1
+		}
+	}
+	if !found {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if matched[i] && (i == 0 || !matched[i-1]) {
+			sb.WriteString("<em>")
+		}
+		sb.WriteByte(name[i])
+		if matched[i] && (i == len(name)-1 || !matched[i+1]) {
+			sb.WriteString("</em>")
+		}
+	}
+	return sb.String()
+}
+
+// The matches are deduplicated by name, preferring the entity with the most recent update version. Each match is
+// scored by the number of input tokens in its name, plus the trigram similarity of its name to the whole input,
+// so that exact matches rank first. As in elastic, entities which aren't running or pending are ranked lower.
+const entityMatchQuery = `
+SELECT name, ns, kind, state, update_version, score FROM (
+  SELECT DISTINCT ON (name) name, ns, kind, state, update_version,
+    ((SELECT count(*) FROM unnest($4::varchar[]) AS t WHERE strpos(lower(name), t) > 0)
+      + similarity(lower(name), $5::text))
+    * (CASE WHEN state = ANY($6::int[]) THEN 1.0 ELSE 0.7 END)::float8 AS score
+  FROM md_entities
+  WHERE org_id = $1 AND kind = ANY($2)
+    AND ($3 = '' OR cluster_uid = $3 OR kind = $7)
+    AND (cardinality($8::varchar[]) = 0 OR lower(name) LIKE ANY($8))
+  ORDER BY name, update_version DESC
+) AS m
+ORDER BY score DESC, name
+LIMIT $9`
+
+func (p *PostgresSuggester) getMatches(r *SuggestionRequest) ([]*entityMatch, error) {
+	tokens := searchTokens(r.Input)
+	if r.Input != "" && len(tokens) == 0 {
+		// The input only contains separators or tokens that are too long, which can't match any names.
+		return []*entityMatch{}, nil
+	}
+
+	kinds := make([]string, len(r.AllowedKinds))
+	for i, k := range r.AllowedKinds {
+		kinds[i] = string(protoToElasticLabelMap[k])
+	}
+	patterns := make([]string, len(tokens))
+	for i, t := range tokens {
+		patterns[i] = likePattern(t)
+	}
+	activeStates := pq.Int64Array{int64(md.ESMDEntityStateRunning), int64(md.ESMDEntityStatePending)}
+
+	rows, err := p.db.Queryx(entityMatchQuery, r.OrgID, pq.StringArray(kinds), r.ClusterUID, pq.StringArray(tokens),
+		strings.ToLower(r.Input), activeStates, string(md.EsMDTypeCronScript), pq.StringArray(patterns), searchLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := make([]*entityMatch, 0)
+	for rows.Next() {
+		e := &md.EsMDEntity{}
+		m := &entityMatch{entity: e}
+		err = rows.Scan(&e.Name, &e.NS, &e.Kind, &e.State, &e.UpdateVersion, &m.score)
+		if err != nil {
+			return nil, err
+		}
+		m.nameHighlight = highlightName(e.Name, tokens)
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// GetSuggestions get suggestions for the given input using postgres.
+func (p *PostgresSuggester) GetSuggestions(reqs []*SuggestionRequest) ([]*SuggestionResult, error) {
+	br := p.br
+
+	matches := make([][]*entityMatch, len(reqs))
+	for i, r := range reqs {
+		m, err := p.getMatches(r)
+		if err != nil {
+			return nil, err
+		}
+		matches[i] = m
+	}
+
+	return buildSuggestionResults(br, reqs, matches), nil
+}
@@ -49,22 +49,38 @@ const (
 	// single result).
 	resultLimit = 5
 	searchLimit = resultLimit + 1
+	// The boost for entities whose name is exactly the input.
+	exactMatchBoost = 10
 )
 
 var protoToElasticLabelMap = map[cloudpb.AutocompleteEntityKind]md.EsMDType{
-	cloudpb.AEK_SVC:       md.EsMDTypeService,
-	cloudpb.AEK_POD:       md.EsMDTypePod,
-	cloudpb.AEK_SCRIPT:    md.EsMDTypeScript,
-	cloudpb.AEK_NAMESPACE: md.EsMDTypeNamespace,
-	cloudpb.AEK_NODE:      md.EsMDTypeNode,
+	cloudpb.AEK_SVC:         md.EsMDTypeService,
+	cloudpb.AEK_POD:         md.EsMDTypePod,
+	cloudpb.AEK_SCRIPT:      md.EsMDTypeScript,
+	cloudpb.AEK_NAMESPACE:   md.EsMDTypeNamespace,
+	cloudpb.AEK_NODE:        md.EsMDTypeNode,
+	cloudpb.AEK_CONTAINER:   md.EsMDTypeContainer,
+	cloudpb.AEK_DEPLOYMENT:  md.EsMDTypeDeployment,
+	cloudpb.AEK_REPLICASET:  md.EsMDTypeReplicaSet,
+	cloudpb.AEK_STATEFULSET: md.EsMDTypeStatefulSet,
+	cloudpb.AEK_DAEMONSET:   md.EsMDTypeDaemonSet,
+	cloudpb.AEK_TABLE:       md.EsMDTypeTable,
+	cloudpb.AEK_CRON_SCRIPT: md.EsMDTypeCronScript,
 }
 
 var elasticLabelToProtoMap = map[md.EsMDType]cloudpb.AutocompleteEntityKind{
-	md.EsMDTypeService:   cloudpb.AEK_SVC,
-	md.EsMDTypePod:       cloudpb.AEK_POD,
-	md.EsMDTypeScript:    cloudpb.AEK_SCRIPT,
-	md.EsMDTypeNamespace: cloudpb.AEK_NAMESPACE,
-	md.EsMDTypeNode:      cloudpb.AEK_NODE,
+	md.EsMDTypeService:     cloudpb.AEK_SVC,
+	md.EsMDTypePod:         cloudpb.AEK_POD,
+	md.EsMDTypeScript:      cloudpb.AEK_SCRIPT,
+	md.EsMDTypeNamespace:   cloudpb.AEK_NAMESPACE,
+	md.EsMDTypeNode:        cloudpb.AEK_NODE,
+	md.EsMDTypeContainer:   cloudpb.AEK_CONTAINER,
+	md.EsMDTypeDeployment:  cloudpb.AEK_DEPLOYMENT,
+	md.EsMDTypeReplicaSet:  cloudpb.AEK_REPLICASET,
+	md.EsMDTypeStatefulSet: cloudpb.AEK_STATEFULSET,
+	md.EsMDTypeDaemonSet:   cloudpb.AEK_DAEMONSET,
+	md.EsMDTypeTable:       cloudpb.AEK_TABLE,
+	md.EsMDTypeCronScript:  cloudpb.AEK_CRON_SCRIPT,
 }
 
 var elasticStateToProtoMap = map[md.ESMDEntityState]cloudpb.AutocompleteEntityState{
@@ -125,6 +141,14 @@ func parseHighlightIndexes(highlightStr string, offset int) []int64 {
 	return matchedIndexes
 }
 
+// entityMatch is an indexed entity which matched a suggestion request.
+type entityMatch struct {
+	entity *md.EsMDEntity
+	score  float64
+	// The name of the entity with the matching sections wrapped in <em></em>, or empty if nothing is highlighted.
+	nameHighlight string
+}
+
 // UpdateScriptBundle updates the script bundle used to populate the suggester's script suggestions.
 func (e *ElasticSuggester) UpdateScriptBundle(br *script.BundleManager) {
 	e.br = br
@@ -150,9 +174,14 @@ func (e *ElasticSuggester) GetSuggestions(reqs []*SuggestionRequest) ([]*Suggest
 	coll := elastic.NewCollapseBuilder("name.keyword").InnerHit(elastic.NewInnerHit().Size(1).Name("collapse").Sort("updateVersion", false))
 
 	for _, r := range reqs {
+		// Score with the term statistics of the whole index rather than of each shard, and break ties by name,
+		// so that equally good matches are always returned in the same order.
 		ms.Add(elastic.NewSearchRequest().
+			SearchTypeDfsQueryThenFetch().
 			Highlight(highlight).
-			Query(e.getQueryForRequest(r.OrgID, r.ClusterUID, r.Input, r.AllowedKinds, r.AllowedArgs)).FetchSourceIncludeExclude([]string{"kind", "name", "ns", "state", "updateVersion"}, []string{}).Collapse(coll).Size(searchLimit))
+			Query(e.getQueryForRequest(r.OrgID, r.ClusterUID, r.Input, r.AllowedKinds, r.AllowedArgs)).FetchSourceIncludeExclude([]string{"kind", "name", "ns", "state", "updateVersion"}, []string{}).Collapse(coll).
+			SortBy(elastic.NewScoreSort(), elastic.NewFieldSort("name.keyword").Asc()).TrackScores(true).
+			Size(searchLimit))
 	}
 
 	resp, err := ms.Do(context.Background())
@@ -161,6 +190,39 @@ func (e *ElasticSuggester) GetSuggestions(reqs []*SuggestionRequest) ([]*Suggest
 		return nil, err
 	}
 
+	matches := make([][]*entityMatch, len(resp.Responses))
+	for i, r := range resp.Responses {
+		matches[i] = make([]*entityMatch, 0, len(r.Hits.Hits))
+		for _, h := range r.Hits.Hits {
+			src := h.Source
+			// Use the top-ranked result from the collapse. For some reason, this doesn't automatically
+			// become the main hit in Elastic and we need to pull it out of the innerHits.
+			// The innerHits from the collapse should always be defined, so this just is extra defensive.
+			if h.InnerHits["collapse"].Hits != nil && len(h.InnerHits["collapse"].Hits.Hits) > 0 {
+				src = h.InnerHits["collapse"].Hits.Hits[0].Source
+			}
+			res := &md.EsMDEntity{}
+			err = json.Unmarshal(src, res)
+			if err != nil {
+				return nil, err
+			}
+
+			m := &entityMatch{entity: res, score: float64(*h.Score)}
+			if len(h.Highlight["name"]) > 0 {
+				m.nameHighlight = h.Highlight["name"][0]
+			}
+			matches[i] = append(matches[i], m)
+		}
+	}
+
+	return buildSuggestionResults(br, reqs, matches), nil
+}
+
+// buildSuggestionResults converts the ranked entity matches for each request into suggestions, and adds any
+// matching scripts from the bundle.
+func buildSuggestionResults(br *script.BundleManager, reqs []*SuggestionRequest, matches [][]*entityMatch) []*SuggestionResult {
+	resps := make([]*SuggestionResult, len(reqs))
+
 	// Parse scripts to prepare for matching. This is temporary until we have script indexing.
 	scripts := []string{}
 	scriptArgMap := make(map[string][]cloudpb.AutocompleteEntityKind)
@@ -175,6 +237,8 @@ func (e *ElasticSuggester) GetSuggestions(reqs []*SuggestionRequest) ([]*Suggest
 					aKind = cloudpb.AEK_POD
 				} else if a.Type == vispb.PX_SERVICE {
 					aKind = cloudpb.AEK_SVC
+				} else if a.Type == vispb.PX_CONTAINER {
+					aKind = cloudpb.AEK_CONTAINER
 				}
 
 				if aKind != cloudpb.AEK_UNKNOWN {
@@ -185,7 +249,7 @@ func (e *ElasticSuggester) GetSuggestions(reqs []*SuggestionRequest) ([]*Suggest
 		}
 	}
 
-	for i, r := range resp.Responses {
+	for i := range reqs {
 		// This is temporary until we index scripts in Elastic.
 		scriptResults := make([]*Suggestion, 0)
 		if br != nil {
@@ -248,24 +312,13 @@ func (e *ElasticSuggester) GetSuggestions(reqs []*SuggestionRequest) ([]*Suggest
 		// Convert elastic entity into a suggestion object.
 		hasAdditionalMatches := false
 		results := make([]*Suggestion, 0)
-		for _, h := range r.Hits.Hits {
-			src := h.Source
-			// Use the top-ranked result from the collapse. For some reason, this doesn't automatically
-			// become the main hit in Elastic and we need to pull it out of the innerHits.
-			// The innerHits from the collapse should always be defined, so this just is extra defensive.
-			if h.InnerHits["collapse"].Hits != nil && len(h.InnerHits["collapse"].Hits.Hits) > 0 {
-				src = h.InnerHits["collapse"].Hits.Hits[0].Source
-			}
-			res := &md.EsMDEntity{}
-			err = json.Unmarshal(src, res)
-			if err != nil {
-				return nil, err
-			}
+		for _, m := range matches[i] {
+			res := m.entity
 
 			matchedIndexes := make([]int64, 0)
 			// Parse highlight string into indexes.
-			if len(h.Highlight["name"]) > 0 {
-				matchedIndexes = append(matchedIndexes, parseHighlightIndexes(h.Highlight["name"][0], 0)...)
+			if m.nameHighlight != "" {
+				matchedIndexes = append(matchedIndexes, parseHighlightIndexes(m.nameHighlight, 0)...)
 			}
 
 			// TODO(michellenguyen): Remove namespace handling when we create a new index and ensure there are no more
@@ -284,7 +337,7 @@ func (e *ElasticSuggester) GetSuggestions(reqs []*SuggestionRequest) ([]*Suggest
 			}
 			results = append(results, &Suggestion{
 				Name:           resName,
-				Score:          float64(*h.Score),
+				Score:          m.score,
 				Kind:           elasticLabelToProtoMap[md.EsMDType(res.Kind)],
 				MatchedIndexes: matchedIndexes,
 				State:          elasticStateToProtoMap[res.State],
@@ -307,7 +360,7 @@ func (e *ElasticSuggester) GetSuggestions(reqs []*SuggestionRequest) ([]*Suggest
 		}
 	}
 
-	return resps, nil
+	return resps
 }
 
 func (e *ElasticSuggester) getQueryForRequest(orgID uuid.UUID, clusterUID string, input string, allowedKinds []cloudpb.AutocompleteEntityKind, allowedArgs []cloudpb.AutocompleteEntityKind) *elastic.BoolQuery {
@@ -333,13 +386,18 @@ func (e *ElasticSuggester) getMDEntityQuery(orgID uuid.UUID, clusterUID string,
 	// If the user hasn't provided any input string, don't run bother running a match query.
 	if len(input) >= 1 {
 		entityQuery.Must(elastic.NewMatchQuery("name", input))
+		// Rank an exact match first, even if shorter names contain more of the input.
+		entityQuery.Should(elastic.NewTermQuery("name.keyword", input).Boost(exactMatchBoost))
 	}
 
 	// Only search for entities in org.
 	entityQuery.Must(elastic.NewTermQuery("orgID", orgID.String()))
 
 	if clusterUID != "" {
-		entityQuery.Must(elastic.NewTermQuery("clusterUID", clusterUID))
+		// Cron scripts belong to the org rather than a cluster, so they are indexed without a cluster UID.
+		clusterQuery := elastic.NewBoolQuery()
+		clusterQuery.Should(elastic.NewTermQuery("clusterUID", clusterUID)).Should(elastic.NewTermQuery("kind", md.EsMDTypeCronScript))
+		entityQuery.Must(clusterQuery)
 	}
 
 	// Only search for allowed kinds.
@@ -25,6 +25,8 @@ import (
 	"testing"
 
 	"github.com/gofrs/uuid"
+	bindata "github.com/golang-migrate/migrate/source/go_bindata"
+	"github.com/jmoiron/sqlx"
 	"github.com/olivere/elastic/v7"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,6 +34,8 @@ import (
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/cloud/autocomplete"
 	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/cloud/indexer/schema"
+	"px.dev/pixie/src/shared/services/pgtest"
 	"px.dev/pixie/src/utils/testingutils"
 )
 
@@ -151,18 +155,51 @@ var mdEntities = []md.EsMDEntity{
 		TimeStoppedNS:      0,
 		RelatedEntityNames: []string{},
 	},
+	{
+		OrgID:              org1.String(),
+		ClusterUID:         "test",
+		UID:                "deploy1",
+		Name:               "pl/testDeployment",
+		Kind:               "deployment",
+		TimeStartedNS:      1,
+		TimeStoppedNS:      0,
+		RelatedEntityNames: []string{"pl/testDeployment-6d4f8c9b5-abcde"},
+		State:              md.ESMDEntityStateRunning,
+	},
+	{
+		OrgID:              org1.String(),
+		UID:                "script1",
+		Name:               "testScript",
+		Kind:               "cron_script",
+		TimeStartedNS:      0,
+		TimeStoppedNS:      0,
+		RelatedEntityNames: []string{},
+		State:              md.ESMDEntityStateRunning,
+	},
 }
 
 var elasticClient *elastic.Client
+var db *sqlx.DB
 
 func TestMain(m *testing.M) {
-	es, cleanup, err := testingutils.SetupElastic()
+	es, esCleanup, err := testingutils.SetupElastic()
 	if err != nil {
-		cleanup()
+		esCleanup()
 		log.Fatal(err)
 	}
 	elasticClient = es
 
+	testDB, dbCleanup, err := pgtest.SetupTestDB(bindata.Resource(schema.AssetNames(), schema.Asset))
+	if err != nil {
+		esCleanup()
+		log.Fatal(err)
+	}
+	db = testDB
+	cleanup := func() {
+		esCleanup()
+		dbCleanup()
+	}
+
 	// Set up elastic indexes.
 	_, err = es.CreateIndex(md.IndexName).Body(md.IndexMapping).Do(context.Background())
 	if err != nil {
@@ -170,12 +207,19 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 
+	pgBackend := md.NewPostgresBackend(db)
 	for _, e := range mdEntities {
 		err = insertIntoIndex(md.IndexName, e.UID, e)
 		if err != nil {
 			cleanup()
 			log.Fatal(err)
 		}
+		entity := e
+		err = pgBackend.UpsertEntity(e.UID, &entity)
+		if err != nil {
+			cleanup()
+			log.Fatal(err)
+		}
 	}
 
 	code := m.Run()
@@ -197,6 +241,16 @@ func insertIntoIndex(index string, id string, e md.EsMDEntity) error {
 	return nil
 }
 
+// testSuggesters returns a suggester for each of the search backends, which should all give the same suggestions.
+func testSuggesters(t *testing.T) map[string]autocomplete.Suggester {
+	es, err := autocomplete.NewElasticSuggester(elasticClient, "scripts", nil)
+	require.NoError(t, err)
+	return map[string]autocomplete.Suggester{
+		"elastic":  es,
+		"postgres": autocomplete.NewPostgresSuggester(db),
+	}
+}
+
 func TestGetSuggestions(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -222,12 +276,14 @@ func TestGetSuggestions(t *testing.T) {
 					HasAdditionalMatches: false,
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name: "pl/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "pl/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{3, 4, 5, 6},
 						},
 						{
-							Name: "anotherNS/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "anotherNS/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{10, 11, 12, 13},
 						},
 					},
 				},
@@ -249,10 +305,18 @@ func TestGetSuggestions(t *testing.T) {
 				{
 					ExactMatch:           true,
 					HasAdditionalMatches: false,
+					// The exact match ranks first. Only "pl" is highlighted, since "testservice" is longer than the
+					// longest indexed ngram and can't match.
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name: "pl/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "pl/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{0, 1},
+						},
+						{
+							Name:           "pl/abcd",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{0, 1},
 						},
 					},
 				},
@@ -274,26 +338,32 @@ func TestGetSuggestions(t *testing.T) {
 				{
 					ExactMatch:           false,
 					HasAdditionalMatches: true,
+					// All of the nodes match equally well, so they are ordered by name and dup/dup6 is the additional match.
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name: "dup/dup1",
-							Kind: cloudpb.AEK_NODE,
+							Name:           "dup/dup1",
+							Kind:           cloudpb.AEK_NODE,
+							MatchedIndexes: []int64{0, 1, 2, 4, 5, 6},
 						},
 						{
-							Name: "dup/dup2",
-							Kind: cloudpb.AEK_NODE,
+							Name:           "dup/dup2",
+							Kind:           cloudpb.AEK_NODE,
+							MatchedIndexes: []int64{0, 1, 2, 4, 5, 6},
 						},
 						{
-							Name: "dup/dup3",
-							Kind: cloudpb.AEK_NODE,
+							Name:           "dup/dup3",
+							Kind:           cloudpb.AEK_NODE,
+							MatchedIndexes: []int64{0, 1, 2, 4, 5, 6},
 						},
 						{
-							Name: "dup/dup4",
-							Kind: cloudpb.AEK_NODE,
+							Name:           "dup/dup4",
+							Kind:           cloudpb.AEK_NODE,
+							MatchedIndexes: []int64{0, 1, 2, 4, 5, 6},
 						},
 						{
-							Name: "dup/dup6",
-							Kind: cloudpb.AEK_NODE,
+							Name:           "dup/dup5",
+							Kind:           cloudpb.AEK_NODE,
+							MatchedIndexes: []int64{0, 1, 2, 4, 5, 6},
 						},
 					},
 				},
@@ -317,8 +387,14 @@ func TestGetSuggestions(t *testing.T) {
 					HasAdditionalMatches: false,
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name: "pl/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "pl/abcd",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{0, 1},
+						},
+						{
+							Name:           "pl/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{0, 1},
 						},
 					},
 				},
@@ -340,11 +416,13 @@ func TestGetSuggestions(t *testing.T) {
 				{
 					ExactMatch:           false,
 					HasAdditionalMatches: false,
+					// Each of the input tokens is highlighted wherever it occurs in the name.
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name:  "anotherNS/test-Pod",
-							Kind:  cloudpb.AEK_POD,
-							State: cloudpb.AES_RUNNING,
+							Name:           "anotherNS/test-Pod",
+							Kind:           cloudpb.AEK_POD,
+							MatchedIndexes: []int64{3, 10, 13, 15, 16},
+							State:          cloudpb.AES_RUNNING,
 						},
 					},
 				},
@@ -368,9 +446,10 @@ func TestGetSuggestions(t *testing.T) {
 					HasAdditionalMatches: false,
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name:  "anotherNS/test-Pod",
-							Kind:  cloudpb.AEK_POD,
-							State: cloudpb.AES_RUNNING,
+							Name:           "anotherNS/test-Pod",
+							Kind:           cloudpb.AEK_POD,
+							MatchedIndexes: []int64{15, 16},
+							State:          cloudpb.AES_RUNNING,
 						},
 					},
 				},
@@ -392,19 +471,23 @@ func TestGetSuggestions(t *testing.T) {
 				{
 					ExactMatch:           false,
 					HasAdditionalMatches: false,
+					// Running entities are ranked above the others.
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name: "pl/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "anotherNS/test-Pod",
+							Kind:           cloudpb.AEK_POD,
+							MatchedIndexes: []int64{10, 11, 12, 13},
+							State:          cloudpb.AES_RUNNING,
 						},
 						{
-							Name: "anotherNS/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "pl/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{3, 4, 5, 6},
 						},
 						{
-							Name:  "anotherNS/test-Pod",
-							Kind:  cloudpb.AEK_POD,
-							State: cloudpb.AES_RUNNING,
+							Name:           "anotherNS/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{10, 11, 12, 13},
 						},
 					},
 				},
@@ -429,8 +512,43 @@ func TestGetSuggestions(t *testing.T) {
 					HasAdditionalMatches: false,
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name: "anotherNS/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "anotherNS/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{10, 11, 12, 13},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "cluster UID with cron scripts",
+			reqs: []*autocomplete.SuggestionRequest{
+				{
+					Input:      "test",
+					ClusterUID: "test",
+					OrgID:      org1,
+					AllowedKinds: []cloudpb.AutocompleteEntityKind{
+						cloudpb.AEK_DEPLOYMENT, cloudpb.AEK_CRON_SCRIPT,
+					},
+					AllowedArgs: []cloudpb.AutocompleteEntityKind{},
+				},
+			},
+			expectedResults: []*autocomplete.SuggestionResult{
+				{
+					ExactMatch:           false,
+					HasAdditionalMatches: false,
+					Suggestions: []*autocomplete.Suggestion{
+						{
+							Name:           "testScript",
+							Kind:           cloudpb.AEK_CRON_SCRIPT,
+							MatchedIndexes: []int64{0, 1, 2, 3},
+							State:          cloudpb.AES_RUNNING,
+						},
+						{
+							Name:           "pl/testDeployment",
+							Kind:           cloudpb.AEK_DEPLOYMENT,
+							MatchedIndexes: []int64{3, 4, 5, 6},
+							State:          cloudpb.AES_RUNNING,
 						},
 					},
 				},
@@ -462,8 +580,14 @@ func TestGetSuggestions(t *testing.T) {
 					HasAdditionalMatches: false,
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name: "pl/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "pl/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{0, 1},
+						},
+						{
+							Name:           "pl/abcd",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{0, 1},
 						},
 					},
 				},
@@ -472,12 +596,14 @@ func TestGetSuggestions(t *testing.T) {
 					HasAdditionalMatches: false,
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name: "pl/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "pl/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{3, 4, 5, 6},
 						},
 						{
-							Name: "anotherNS/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "anotherNS/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{10, 11, 12, 13},
 						},
 					},
 				},
@@ -501,16 +627,19 @@ func TestGetSuggestions(t *testing.T) {
 					HasAdditionalMatches: false,
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name: "pl/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "anotherNS/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{},
 						},
 						{
-							Name: "anotherNS/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "pl/abcd",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{},
 						},
 						{
-							Name: "pl/abcd",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "pl/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{},
 						},
 					},
 				},
@@ -534,12 +663,14 @@ func TestGetSuggestions(t *testing.T) {
 					HasAdditionalMatches: false,
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name: "pl/testService",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "pl/abcd",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{0, 1},
 						},
 						{
-							Name: "pl/abcd",
-							Kind: cloudpb.AEK_SVC,
+							Name:           "pl/testService",
+							Kind:           cloudpb.AEK_SVC,
+							MatchedIndexes: []int64{0, 1},
 						},
 					},
 				},
@@ -568,8 +699,9 @@ func TestGetSuggestions(t *testing.T) {
 					HasAdditionalMatches: false,
 					Suggestions: []*autocomplete.Suggestion{
 						{
-							Name: "testNamespace",
-							Kind: cloudpb.AEK_NAMESPACE,
+							Name:           "testNamespace",
+							Kind:           cloudpb.AEK_NAMESPACE,
+							MatchedIndexes: []int64{0, 1, 2, 3},
 						},
 					},
 				},
@@ -578,23 +710,19 @@ func TestGetSuggestions(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			es, _ := autocomplete.NewElasticSuggester(elasticClient, "scripts", nil)
-			results, err := es.GetSuggestions(test.reqs)
-			require.NoError(t, err)
-			assert.NotNil(t, results)
-			assert.Equal(t, len(test.expectedResults), len(results))
-			for i, r := range results {
-				// Ensure that the expected results are atleast contained in the actual results.
-				assert.GreaterOrEqual(t, len(r.Suggestions), len(test.expectedResults[i].Suggestions))
-				// Remove the score so we can do a comparison.
-				for j := range r.Suggestions {
-					r.Suggestions[j].Score = 0
-					r.Suggestions[j].MatchedIndexes = nil
-				}
-				// Check that the expected results are contained in the results.
-				assert.Subset(t, r.Suggestions, test.expectedResults[i].Suggestions)
-				assert.Equal(t, test.expectedResults[i].ExactMatch, r.ExactMatch)
-				assert.Equal(t, test.expectedResults[i].HasAdditionalMatches, r.HasAdditionalMatches)
+			for backend, suggester := range testSuggesters(t) {
+				t.Run(backend, func(t *testing.T) {
+					results, err := suggester.GetSuggestions(test.reqs)
+					require.NoError(t, err)
+					// The scores depend on the backend, but the suggestions, their order and their highlights
+					// should be the same.
+					for _, r := range results {
+						for _, s := range r.Suggestions {
+							s.Score = 0
+						}
+					}
+					assert.Equal(t, test.expectedResults, results)
+				})
 			}
 		})
 	}
@@ -143,42 +143,8 @@ func (s *Server) GetConfigForVizier(ctx context.Context,
 	}
 
 	// Fill in template values.
-	cloudAddr := in.VzSpec.CloudAddr
-	updateCloudAddr := in.VzSpec.CloudAddr
-	if in.VzSpec.DevCloudNamespace != "" {
-		cloudAddr = fmt.Sprintf("vzconn-service.%s.svc.cluster.local:51600", in.VzSpec.DevCloudNamespace)
-		updateCloudAddr = fmt.Sprintf("api-service.%s.svc.cluster.local:51200", in.VzSpec.DevCloudNamespace)
-	}
-
-	// We should eventually clean up the templating code, since our Helm charts and extracted YAMLs will now just
-	// be simple CRDs.
-	tmplValues := &vizieryamls.VizierTmplValues{
-		DeployKey:             in.VzSpec.DeployKey,
-		CustomDeployKeySecret: in.VzSpec.CustomDeployKeySecret,
-		UseEtcdOperator:       in.VzSpec.UseEtcdOperator,
-		PEMMemoryLimit:        in.VzSpec.PemMemoryLimit,
-		Namespace:             in.Namespace,
-		CloudAddr:             cloudAddr,
-		CloudUpdateAddr:       updateCloudAddr,
-		ClusterName:           in.VzSpec.ClusterName,
-		DisableAutoUpdate:     in.VzSpec.DisableAutoUpdate,
-		SentryDSN:             getSentryDSN(in.VzSpec.Version),
-		ClockConverter:        in.VzSpec.ClockConverter,
-		DataAccess:            in.VzSpec.DataAccess,
-	}
-
-	if in.VzSpec.DataCollectorParams != nil && in.VzSpec.DataCollectorParams.DatastreamBufferSize != 0 {
-		tmplValues.DatastreamBufferSize = in.VzSpec.DataCollectorParams.DatastreamBufferSize
-	}
-	if in.VzSpec.DataCollectorParams != nil && in.VzSpec.DataCollectorParams.DatastreamBufferSpikeSize != 0 {
-		tmplValues.DatastreamBufferSpikeSize = in.VzSpec.DataCollectorParams.DatastreamBufferSpikeSize
-	}
-	if in.VzSpec.DataCollectorParams != nil && in.VzSpec.DataCollectorParams.CustomPEMFlags != nil {
-		tmplValues.CustomPEMFlags = in.VzSpec.DataCollectorParams.CustomPEMFlags
-	}
-	if in.VzSpec.LeadershipElectionParams != nil {
-		tmplValues.ElectionPeriodMs = in.VzSpec.LeadershipElectionParams.ElectionPeriodMs
-	}
+	tmplValues := vizieryamls.VizierTmplValuesFromSpec(in.Namespace, in.VzSpec)
+	tmplValues.SentryDSN = getSentryDSN(in.VzSpec.Version)
 
 	// If the table store data limit is not specified, then we should add in the default
 	// table store size. Default will be 60% of the total requested PEM memory.
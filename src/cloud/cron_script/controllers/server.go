@@ -20,12 +20,10 @@ package controllers
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"sync"
 	"time"
 
-	"github.com/cenkalti/backoff/v3"
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/proto"
 	"github.com/gogo/protobuf/types"
@@ -39,6 +37,7 @@ import (
 
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/cron_script/cronscriptpb"
+	"px.dev/pixie/src/cloud/shared/labelselector"
 	"px.dev/pixie/src/cloud/shared/vzshard"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/cvmsgs"
@@ -49,13 +48,6 @@ import (
 	"px.dev/pixie/src/utils"
 )
 
-const (
-	msgBackoffInitialInterval = 5 * time.Second
-	msgBackoffMultiplier      = 2
-	msgBackoffMaxElapsedTime  = 3 * time.Minute
-	natsWaitTimeout           = 10 * time.Second
-)
-
 // HandleNATSMessageFunc is the signature for a NATS message handler.
 type HandleNATSMessageFunc func(*cvmsgspb.V2CMessage)
 
@@ -93,13 +85,50 @@ func (s *Server) Stop() {
 
 // CronScript contains metadata about a regularly scheduled script.
 type CronScript struct {
-	ID         uuid.UUID  `db:"id"`
-	OrgID      uuid.UUID  `db:"org_id"`
-	Script     string     `db:"script"`
-	ClusterIDs ClusterIDs `db:"cluster_ids"`
-	ConfigStr  string     `db:"configs"`
-	Enabled    bool       `db:"enabled"`
-	FrequencyS int64      `db:"frequency_s"`
+	ID              uuid.UUID  `db:"id"`
+	OrgID           uuid.UUID  `db:"org_id"`
+	Script          string     `db:"script"`
+	ClusterIDs      ClusterIDs `db:"cluster_ids"`
+	ClusterSelector string     `db:"cluster_selector"`
+	ClusterGroup    string     `db:"cluster_group"`
+	ConfigStr       string     `db:"configs"`
+	Enabled         bool       `db:"enabled"`
+	FrequencyS      int64      `db:"frequency_s"`
+}
+
+// targetsVizier returns whether the script should run on the given Vizier. A script with no cluster
+// IDs, cluster selector or cluster group runs on every Vizier in the org, otherwise it runs on the
+// listed clusters and on any cluster whose labels match the selector or the group's selector.
+// groupSelectors maps the org's cluster group names to their selectors.
+func (c *CronScript) targetsVizier(vizierID uuid.UUID, labels map[string]string, groupSelectors map[string]string) bool {
+	if len(c.ClusterIDs) == 0 && c.ClusterSelector == "" && c.ClusterGroup == "" {
+		return true
+	}
+	for _, id := range c.ClusterIDs {
+		if id == vizierID {
+			return true
+		}
+	}
+	if c.matchesSelector(c.ClusterSelector, labels) {
+		return true
+	}
+	if c.ClusterGroup == "" {
+		return false
+	}
+	// A script whose group has been deleted no longer runs on the group's clusters.
+	return c.matchesSelector(groupSelectors[c.ClusterGroup], labels)
+}
+
+func (c *CronScript) matchesSelector(selector string, labels map[string]string) bool {
+	if selector == "" {
+		return false
+	}
+	sel, err := labelselector.Parse(selector)
+	if err != nil {
+		log.WithError(err).WithField("scriptID", c.ID).Error("Invalid cluster selector for cron script")
+		return false
+	}
+	return sel.Matches(labels)
 }
 
 func (s *Server) handleRequests() {
@@ -204,44 +233,118 @@ func (s *Server) fetchScriptsForVizier(vizierID *uuidpb.UUID) (map[string]*cvmsg
 	}
 
 	// Fetch all scripts registered to this Vizier.
-	query := `SELECT id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, frequency_s FROM cron_scripts WHERE org_id=$2`
-	rows, err := s.db.Queryx(query, s.dbKey, utils.UUIDFromProtoOrNil(resp.OrgID))
+	orgID := utils.UUIDFromProtoOrNil(resp.OrgID)
+	query := `SELECT id, script, cluster_ids, cluster_selector, cluster_group, PGP_SYM_DECRYPT(configs, $1::text) as configs, frequency_s FROM cron_scripts WHERE org_id=$2`
+	rows, err := s.db.Queryx(query, s.dbKey, orgID)
 	if err != nil {
 		log.WithError(err).Error("Could not fetch scripts for org")
 		return nil, err
 	}
 	defer rows.Close()
 
-	scriptsMap := make(map[string]*cvmsgspb.CronScript)
+	var orgScripts []*CronScript
+	needsLabels := false
+	needsGroups := false
 	for rows.Next() {
-		var s CronScript
-		err = rows.StructScan(&s)
+		var cs CronScript
+		err = rows.StructScan(&cs)
 		if err != nil {
 			continue
 		}
-		// If no cluster IDs are specified, script is registered to all orgs.
-		// Otherwise, we should check if this cluster is in the list of clusters.
-		if len(s.ClusterIDs) != 0 {
-			found := false
-			for _, c := range s.ClusterIDs {
-				if c == vizierUUID {
-					found = true
-				}
-			}
-			if !found {
-				continue
+		if cs.ClusterSelector != "" {
+			needsLabels = true
+		}
+		if cs.ClusterGroup != "" {
+			needsLabels = true
+			needsGroups = true
+		}
+		orgScripts = append(orgScripts, &cs)
+	}
+
+	// The Vizier's labels are only needed if a script targets clusters by selector or group. They
+	// and the org's groups are read on every fetch so that scripts follow clusters as they are
+	// created or relabelled, and groups as their selectors change.
+	var labels map[string]string
+	var groupSelectors map[string]string
+	if needsLabels {
+		orgCtx, err := contextForOrg(orgID)
+		if err != nil {
+			return nil, err
+		}
+		vzInfo, err := s.vzmgrClient.GetVizierInfo(orgCtx, vizierID)
+		if err != nil {
+			log.WithError(err).Error("Could not fetch labels for Vizier")
+			return nil, err
+		}
+		labels = vzInfo.Labels
+
+		if needsGroups {
+			groupSelectors, err = s.clusterGroupSelectors(orgCtx, orgID)
+			if err != nil {
+				log.WithError(err).Error("Could not fetch cluster groups for org")
+				return nil, err
 			}
 		}
-		scriptsMap[s.ID.String()] = &cvmsgspb.CronScript{
-			ID:         utils.ProtoFromUUID(s.ID),
-			Script:     s.Script,
-			Configs:    s.ConfigStr,
-			FrequencyS: s.FrequencyS,
+	}
+
+	scriptsMap := make(map[string]*cvmsgspb.CronScript)
+	for _, cs := range orgScripts {
+		if !cs.targetsVizier(vizierUUID, labels, groupSelectors) {
+			continue
+		}
+		scriptsMap[cs.ID.String()] = &cvmsgspb.CronScript{
+			ID:         utils.ProtoFromUUID(cs.ID),
+			Script:     cs.Script,
+			Configs:    cs.ConfigStr,
+			FrequencyS: cs.FrequencyS,
 		}
 	}
 	return scriptsMap, nil
 }
 
+// contextForOrg returns a context authorized to make vzmgr requests on behalf of the given org.
+func contextForOrg(orgID uuid.UUID) (context.Context, error) {
+	svcJWT := jwtutils.GenerateJWTForAPIUser("", orgID.String(), time.Now().Add(time.Minute*10), viper.GetString("domain_name"), nil)
+	svcClaims, err := jwtutils.SignJWTClaims(svcJWT, viper.GetString("jwt_signing_key"))
+	if err != nil {
+		return nil, err
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization",
+		fmt.Sprintf("bearer %s", svcClaims)), nil
+}
+
+// clusterGroupSelectors returns the selectors of the org's cluster groups, keyed by group name.
+func (s *Server) clusterGroupSelectors(ctx context.Context, orgID uuid.UUID) (map[string]string, error) {
+	resp, err := s.vzmgrClient.GetClusterGroups(ctx, utils.ProtoFromUUID(orgID))
+	if err != nil {
+		return nil, err
+	}
+	selectors := make(map[string]string, len(resp.Groups))
+	for _, g := range resp.Groups {
+		selectors[g.Name] = g.Selector
+	}
+	return selectors, nil
+}
+
+// validateClusterGroup checks that the named cluster group exists in the org.
+func (s *Server) validateClusterGroup(orgID uuid.UUID, group string) error {
+	if group == "" {
+		return nil
+	}
+	ctx, err := contextForOrg(orgID)
+	if err != nil {
+		return status.Error(codes.Internal, "Failed to fetch cluster groups")
+	}
+	selectors, err := s.clusterGroupSelectors(ctx, orgID)
+	if err != nil {
+		return status.Error(codes.Internal, "Failed to fetch cluster groups")
+	}
+	if _, ok := selectors[group]; !ok {
+		return status.Errorf(codes.NotFound, "cluster group '%s' not found", group)
+	}
+	return nil
+}
+
 // HandleScriptsRequest handles incoming requests for cron scripts registered to the given vizier.
 func (s *Server) HandleScriptsRequest(msg *cvmsgspb.V2CMessage) {
 	anyMsg := msg.Msg
@@ -292,7 +395,7 @@ func (s *Server) GetScript(ctx context.Context, req *cronscriptpb.GetScriptReque
 	claimsOrgID := uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID)
 	scriptID := utils.UUIDFromProtoOrNil(req.ID)
 
-	query := `SELECT id, org_id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, enabled, frequency_s FROM cron_scripts WHERE org_id=$2 AND id=$3`
+	query := `SELECT id, org_id, script, cluster_ids, cluster_selector, cluster_group, PGP_SYM_DECRYPT(configs, $1::text) as configs, enabled, frequency_s FROM cron_scripts WHERE org_id=$2 AND id=$3`
 	rows, err := s.db.Queryx(query, s.dbKey, claimsOrgID, scriptID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to fetch cron script")
@@ -315,13 +418,15 @@ func (s *Server) GetScript(ctx context.Context, req *cronscriptpb.GetScriptReque
 
 	return &cronscriptpb.GetScriptResponse{
 		Script: &cronscriptpb.CronScript{
-			ID:         req.ID,
-			OrgID:      utils.ProtoFromUUID(claimsOrgID),
-			Script:     script.Script,
-			ClusterIDs: clusterIDs,
-			Configs:    script.ConfigStr,
-			Enabled:    script.Enabled,
-			FrequencyS: script.FrequencyS,
+			ID:              req.ID,
+			OrgID:           utils.ProtoFromUUID(claimsOrgID),
+			Script:          script.Script,
+			ClusterIDs:      clusterIDs,
+			ClusterSelector: script.ClusterSelector,
+			ClusterGroup:    script.ClusterGroup,
+			Configs:         script.ConfigStr,
+			Enabled:         script.Enabled,
+			FrequencyS:      script.FrequencyS,
 		},
 	}, nil
 }
@@ -338,7 +443,7 @@ func (s *Server) GetScripts(ctx context.Context, req *cronscriptpb.GetScriptsReq
 		ids[i] = utils.UUIDFromProtoOrNil(id)
 	}
 
-	strQuery := `SELECT id, org_id, script, cluster_ids, PGP_SYM_DECRYPT(configs, '%s'::text) as configs, enabled, frequency_s FROM cron_scripts WHERE org_id='%s' AND id IN (?)`
+	strQuery := `SELECT id, org_id, script, cluster_ids, cluster_selector, cluster_group, PGP_SYM_DECRYPT(configs, '%s'::text) as configs, enabled, frequency_s FROM cron_scripts WHERE org_id='%s' AND id IN (?)`
 	strQuery = fmt.Sprintf(strQuery, s.dbKey, sCtx.Claims.GetUserClaims().OrgID)
 
 	query, args, err := sqlx.In(strQuery, ids)
@@ -367,13 +472,15 @@ func (s *Server) GetScripts(ctx context.Context, req *cronscriptpb.GetScriptsReq
 		}
 
 		cpb := &cronscriptpb.CronScript{
-			ID:         utils.ProtoFromUUID(p.ID),
-			OrgID:      utils.ProtoFromUUID(p.OrgID),
-			Script:     p.Script,
-			ClusterIDs: clusterIDs,
-			Configs:    p.ConfigStr,
-			Enabled:    p.Enabled,
-			FrequencyS: p.FrequencyS,
+			ID:              utils.ProtoFromUUID(p.ID),
+			OrgID:           utils.ProtoFromUUID(p.OrgID),
+			Script:          p.Script,
+			ClusterIDs:      clusterIDs,
+			ClusterSelector: p.ClusterSelector,
+			ClusterGroup:    p.ClusterGroup,
+			Configs:         p.ConfigStr,
+			Enabled:         p.Enabled,
+			FrequencyS:      p.FrequencyS,
 		}
 		scripts = append(scripts, cpb)
 	}
@@ -395,8 +502,18 @@ func (s *Server) CreateScript(ctx context.Context, req *cronscriptpb.CreateScrip
 		clusterIDs[i] = utils.UUIDFromProtoOrNil(c)
 	}
 
-	query := `INSERT INTO cron_scripts(org_id, script, cluster_ids, configs, enabled, frequency_s) VALUES ($1, $2, $3, PGP_SYM_ENCRYPT($4, $5), $6, $7) RETURNING id`
-	rows, err := s.db.Queryx(query, claimsOrgID, req.Script, ClusterIDs(clusterIDs), req.Configs, s.dbKey, true, req.FrequencyS)
+	clusterSelector, err := canonicalSelector(req.ClusterSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.validateClusterGroup(claimsOrgID, req.ClusterGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO cron_scripts(org_id, script, cluster_ids, cluster_selector, cluster_group, configs, enabled, frequency_s) VALUES ($1, $2, $3, $4, $5, PGP_SYM_ENCRYPT($6, $7), $8, $9) RETURNING id`
+	rows, err := s.db.Queryx(query, claimsOrgID, req.Script, ClusterIDs(clusterIDs), clusterSelector, req.ClusterGroup, req.Configs, s.dbKey, true, req.FrequencyS)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to create cron script")
 	}
@@ -424,7 +541,7 @@ func (s *Server) CreateScript(ctx context.Context, req *cronscriptpb.CreateScrip
 				},
 			},
 		},
-	}, claimsOrgID, req.ClusterIDs)
+	}, claimsOrgID, req.ClusterIDs, clusterSelector, req.ClusterGroup)
 
 	return &cronscriptpb.CreateScriptResponse{ID: idPb}, nil
 }
@@ -438,7 +555,7 @@ func (s *Server) UpdateScript(ctx context.Context, req *cronscriptpb.UpdateScrip
 	claimsOrgID := uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID)
 	scriptID := utils.UUIDFromProtoOrNil(req.ScriptId)
 
-	query := `SELECT id, org_id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, enabled, frequency_s FROM cron_scripts WHERE org_id=$2 AND id=$3`
+	query := `SELECT id, org_id, script, cluster_ids, cluster_selector, cluster_group, PGP_SYM_DECRYPT(configs, $1::text) as configs, enabled, frequency_s FROM cron_scripts WHERE org_id=$2 AND id=$3`
 	rows, err := s.db.Queryx(query, s.dbKey, claimsOrgID, scriptID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to fetch cron script")
@@ -482,8 +599,25 @@ func (s *Server) UpdateScript(ctx context.Context, req *cronscriptpb.UpdateScrip
 		}
 	}
 
-	query = `UPDATE cron_scripts SET script = $1, configs = PGP_SYM_ENCRYPT($2, $3), enabled = $4, frequency_s = $5, cluster_ids=$6 WHERE id = $7`
-	_, err = s.db.Exec(query, contents, configs, s.dbKey, enabled, freq, ClusterIDs(clusterIDs), scriptID)
+	clusterSelector := script.ClusterSelector
+	if req.ClusterSelector != nil {
+		clusterSelector, err = canonicalSelector(req.ClusterSelector.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	clusterGroup := script.ClusterGroup
+	if req.ClusterGroup != nil && req.ClusterGroup.Value != script.ClusterGroup {
+		clusterGroup = req.ClusterGroup.Value
+		err = s.validateClusterGroup(claimsOrgID, clusterGroup)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query = `UPDATE cron_scripts SET script = $1, configs = PGP_SYM_ENCRYPT($2, $3), enabled = $4, frequency_s = $5, cluster_ids=$6, cluster_selector=$7, cluster_group=$8 WHERE id = $9`
+	_, err = s.db.Exec(query, contents, configs, s.dbKey, enabled, freq, ClusterIDs(clusterIDs), clusterSelector, clusterGroup, scriptID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to update cron script")
 	}
@@ -505,7 +639,7 @@ func (s *Server) UpdateScript(ctx context.Context, req *cronscriptpb.UpdateScrip
 				ScriptID: req.ScriptId,
 			},
 		},
-	}, claimsOrgID, prevClusterIDs)
+	}, claimsOrgID, prevClusterIDs, script.ClusterSelector, script.ClusterGroup)
 
 	go s.sendCronScriptUpdateToViziers(&cvmsgspb.CronScriptUpdate{
 		Msg: &cvmsgspb.CronScriptUpdate_UpsertReq{
@@ -518,7 +652,7 @@ func (s *Server) UpdateScript(ctx context.Context, req *cronscriptpb.UpdateScrip
 				},
 			},
 		},
-	}, claimsOrgID, newClusterIDs)
+	}, claimsOrgID, newClusterIDs, clusterSelector, clusterGroup)
 
 	return &cronscriptpb.UpdateScriptResponse{}, nil
 }
@@ -532,7 +666,7 @@ func (s *Server) DeleteScript(ctx context.Context, req *cronscriptpb.DeleteScrip
 	claimsOrgID := uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID)
 	scriptID := utils.UUIDFromProtoOrNil(req.ID)
 
-	query := `SELECT cluster_ids FROM cron_scripts WHERE org_id=$1 AND id=$2`
+	query := `SELECT cluster_ids, cluster_selector, cluster_group FROM cron_scripts WHERE org_id=$1 AND id=$2`
 	rows, err := s.db.Queryx(query, claimsOrgID, scriptID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to fetch cron script")
@@ -542,7 +676,9 @@ func (s *Server) DeleteScript(ctx context.Context, req *cronscriptpb.DeleteScrip
 		return nil, status.Error(codes.NotFound, "cron script not found")
 	}
 	var clusterIDs ClusterIDs
-	err = rows.Scan(&clusterIDs)
+	var clusterSelector string
+	var clusterGroup string
+	err = rows.Scan(&clusterIDs, &clusterSelector, &clusterGroup)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "Failed to read cron script")
 	}
@@ -563,12 +699,21 @@ func (s *Server) DeleteScript(ctx context.Context, req *cronscriptpb.DeleteScrip
 				ScriptID: req.ID,
 			},
 		},
-	}, claimsOrgID, clusterIDProtos)
+	}, claimsOrgID, clusterIDProtos, clusterSelector, clusterGroup)
 
 	return &cronscriptpb.DeleteScriptResponse{}, nil
 }
 
-func (s *Server) sendCronScriptUpdateToViziers(msg *cvmsgspb.CronScriptUpdate, orgID uuid.UUID, clusterIDs []*uuidpb.UUID) {
+// canonicalSelector validates a cluster selector and returns it in canonical form.
+func canonicalSelector(selector string) (string, error) {
+	sel, err := labelselector.Parse(selector)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid cluster selector: %s", err.Error())
+	}
+	return sel.String(), nil
+}
+
+func (s *Server) sendCronScriptUpdateToViziers(msg *cvmsgspb.CronScriptUpdate, orgID uuid.UUID, clusterIDs []*uuidpb.UUID, clusterSelector string, clusterGroup string) {
 	msg.RequestID = uuid.Must(uuid.NewV4()).String()
 	msg.Timestamp = time.Now().UnixNano()
 
@@ -577,21 +722,46 @@ func (s *Server) sendCronScriptUpdateToViziers(msg *cvmsgspb.CronScriptUpdate, o
 		log.WithError(err).Error("Failed to marshal update script msg")
 		return
 	}
-	c2vMsg := &cvmsgspb.C2VMessage{
-		Msg: c2vAnyMsg,
-	}
 
 	// Get healthy viziers for org.
-	svcJWT := jwtutils.GenerateJWTForAPIUser("", orgID.String(), time.Now().Add(time.Minute*10), viper.GetString("domain_name"))
-	svcClaims, err := jwtutils.SignJWTClaims(svcJWT, viper.GetString("jwt_signing_key"))
+	ctx, err := contextForOrg(orgID)
 	if err != nil {
 		log.WithError(err).Error("Failed to sign claims")
 		return
 	}
-	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization",
-		fmt.Sprintf("bearer %s", svcClaims))
 
-	if len(clusterIDs) == 0 { // If no clusterIDs specified, this message should be sent to all Viziers in the org.
+	if clusterSelector != "" || clusterGroup != "" {
+		// The message goes to the explicitly listed clusters and to every cluster matching the
+		// selector or the group's selector.
+		selectors := make([]string, 0)
+		if clusterSelector != "" {
+			selectors = append(selectors, clusterSelector)
+		}
+		if clusterGroup != "" {
+			groupSelectors, err := s.clusterGroupSelectors(ctx, orgID)
+			if err != nil {
+				log.WithError(err).Error("Could not get cluster groups for org")
+				return
+			}
+			if sel := groupSelectors[clusterGroup]; sel != "" {
+				selectors = append(selectors, sel)
+			}
+		}
+		for _, sel := range selectors {
+			matched, err := s.vzmgrClient.GetViziersBySelector(ctx, &vzmgrpb.GetViziersBySelectorRequest{
+				OrgID:    utils.ProtoFromUUID(orgID),
+				Selector: sel,
+			})
+			if err != nil {
+				log.WithError(err).Error("Could not get viziers for selector")
+				return
+			}
+			clusterIDs = mergeClusterIDs(clusterIDs, matched.VizierIDs)
+		}
+		if len(clusterIDs) == 0 {
+			return
+		}
+	} else if len(clusterIDs) == 0 { // If no clusterIDs specified, this message should be sent to all Viziers in the org.
 		viziers, err := s.vzmgrClient.GetViziersByOrg(ctx, utils.ProtoFromUUID(orgID))
 		if err != nil {
 			log.WithError(err).Error("Could not get viziers for org")
@@ -609,62 +779,14 @@ func (s *Server) sendCronScriptUpdateToViziers(msg *cvmsgspb.CronScriptUpdate, o
 	}
 
 	for _, v := range vzInfoResp.VizierInfos {
-		vzUUID := utils.UUIDFromProtoOrNil(v.VizierID)
-		if v.Status != cvmsgspb.VZ_ST_DISCONNECTED && v.Status != cvmsgspb.VZ_ST_UNKNOWN {
-			s.retryMessageUntilResponse(c2vMsg, vzshard.C2VTopic(cvmsgs.CronScriptUpdatesChannel, vzUUID), vzshard.V2CTopic(fmt.Sprintf("%s:%s", cvmsgs.CronScriptUpdatesResponseChannel, msg.RequestID), vzUUID))
-		}
-	}
-}
-
-func (s *Server) retryMessageUntilResponse(msg *cvmsgspb.C2VMessage, publishTopic string, respTopic string) {
-	sendMsg := func() error {
-		return s.natsReplyAndResponse(msg, publishTopic, respTopic)
-	}
-
-	backOffOpts := backoff.NewExponentialBackOff()
-	backOffOpts.InitialInterval = msgBackoffInitialInterval
-	backOffOpts.Multiplier = msgBackoffMultiplier
-	backOffOpts.MaxElapsedTime = msgBackoffMaxElapsedTime
-	err := backoff.Retry(sendMsg, backOffOpts)
-	if err != nil {
-		log.WithError(err).Info("Failed to get response from Vizier. Assuming disconnected")
-	}
-}
-
-func (s *Server) natsReplyAndResponse(req *cvmsgspb.C2VMessage, publishTopic string, responseTopic string) error {
-	// Subscribe to topic that the response will be sent on.
-	subCh := make(chan *nats.Msg, 4096)
-	sub, err := s.nc.ChanSubscribe(responseTopic, subCh)
-	if err != nil {
-		return err
-	}
-	defer sub.Unsubscribe()
-
-	// Publish request.
-	b, err := req.Marshal()
-	if err != nil {
-		return err
-	}
-
-	err = s.nc.Publish(publishTopic, b)
-	if err != nil {
-		return err
-	}
-
-	// Wait for response.
-	t := time.NewTimer(natsWaitTimeout)
-	for {
-		select {
-		case msg := <-subCh:
-			v2cMsg := &cvmsgspb.V2CMessage{}
-			err := proto.Unmarshal(msg.Data, v2cMsg)
-			if err != nil {
-				log.WithError(err).Error("Failed to unmarshal v2c message")
-				return err
-			}
-			return nil
-		case <-t.C:
-			return errors.New("Failed to get response")
+		// The update goes through the vizier's outbox, so it is delivered once the vizier is connected.
+		_, err := s.vzmgrClient.EnqueueC2VMessage(ctx, &vzmgrpb.EnqueueC2VMessageRequest{
+			VizierID: v.VizierID,
+			Topic:    cvmsgs.CronScriptUpdatesChannel,
+			Msg:      c2vAnyMsg,
+		})
+		if err != nil {
+			log.WithError(err).WithField("vizierID", utils.UUIDFromProtoOrNil(v.VizierID)).Error("Failed to enqueue cron script update")
 		}
 	}
 }
@@ -36,6 +36,9 @@ import (
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/cron_script/controllers"
@@ -84,7 +87,7 @@ func testMain(m *testing.M) error {
 
 func createTestContext() context.Context {
 	sCtx := authcontext.New()
-	sCtx.Claims = srvutils.GenerateJWTForUser("abcdef", "223e4567-e89b-12d3-a456-426655440000", "test@test.com", time.Now(), "pixie")
+	sCtx.Claims = srvutils.GenerateJWTForUser("abcdef", "223e4567-e89b-12d3-a456-426655440000", "test@test.com", time.Now(), "pixie", nil)
 	return authcontext.NewContext(context.Background(), sCtx)
 }
 
@@ -209,8 +212,7 @@ func TestServer_CreateScript(t *testing.T) {
 		},
 	}, nil)
 
-	var wg sync.WaitGroup
-	wg.Add(2)
+	enqueued := expectCronScriptUpdates(t, mockVZMgr, 2)
 
 	expectedCronScript := &cvmsgspb.CronScript{
 		Script:     "px.display()",
@@ -218,73 +220,24 @@ func TestServer_CreateScript(t *testing.T) {
 		FrequencyS: 11,
 	}
 
-	mdSub1, err := nc.Subscribe(vzshard.C2VTopic(cvmsgs.CronScriptUpdatesChannel, uuid.FromStringOrNil(vz1ID)), func(msg *nats.Msg) {
-		c2vMsg := &cvmsgspb.C2VMessage{}
-		err := proto.Unmarshal(msg.Data, c2vMsg)
-		require.NoError(t, err)
-		req := &cvmsgspb.CronScriptUpdate{}
-		err = types.UnmarshalAny(c2vMsg.Msg, req)
-		require.NoError(t, err)
-		assert.Equal(t, expectedCronScript.Script, req.GetUpsertReq().Script.Script)
-		assert.Equal(t, expectedCronScript.Configs, req.GetUpsertReq().Script.Configs)
-		assert.Equal(t, expectedCronScript.FrequencyS, req.GetUpsertReq().Script.FrequencyS)
-		wg.Done()
-
-		// Send response.
-		resp := &cvmsgspb.RegisterOrUpdateCronScriptResponse{}
-		v2cAnyMsg, err := types.MarshalAny(resp)
-		require.NoError(t, err)
-		v2cMsg := &cvmsgspb.V2CMessage{
-			Msg: v2cAnyMsg,
-		}
-		b, err := v2cMsg.Marshal()
-		require.NoError(t, err)
-
-		err = nc.Publish(vzshard.V2CTopic(fmt.Sprintf("%s:%s", cvmsgs.CronScriptUpdatesResponseChannel, req.RequestID), uuid.FromStringOrNil(vz1ID)), b)
-		require.NoError(t, err)
-	})
-	defer func() {
-		err = mdSub1.Unsubscribe()
-		require.NoError(t, err)
-	}()
-
-	mdSub2, err := nc.Subscribe(vzshard.C2VTopic(cvmsgs.CronScriptUpdatesChannel, uuid.FromStringOrNil(vz2ID)), func(msg *nats.Msg) {
-		c2vMsg := &cvmsgspb.C2VMessage{}
-		err := proto.Unmarshal(msg.Data, c2vMsg)
-		require.NoError(t, err)
-		req := &cvmsgspb.CronScriptUpdate{}
-		err = types.UnmarshalAny(c2vMsg.Msg, req)
-		require.NoError(t, err)
-		wg.Done()
-
-		// Send response.
-		resp := &cvmsgspb.RegisterOrUpdateCronScriptResponse{}
-		v2cAnyMsg, err := types.MarshalAny(resp)
-		require.NoError(t, err)
-		v2cMsg := &cvmsgspb.V2CMessage{
-			Msg: v2cAnyMsg,
-		}
-		b, err := v2cMsg.Marshal()
-		require.NoError(t, err)
-
-		err = nc.Publish(vzshard.V2CTopic(fmt.Sprintf("%s:%s", cvmsgs.CronScriptUpdatesResponseChannel, req.RequestID), uuid.FromStringOrNil(vz2ID)), b)
-		require.NoError(t, err)
-	})
-	defer func() {
-		err = mdSub2.Unsubscribe()
-		require.NoError(t, err)
-	}()
-
 	resp, err := s.CreateScript(createTestContext(), &cronscriptpb.CreateScriptRequest{
 		Script:     "px.display()",
 		Configs:    "testYAML",
 		FrequencyS: 11,
 		ClusterIDs: clusterIDs,
 	})
-	wg.Wait()
 	require.NoError(t, err)
 	require.NotNil(t, resp)
 
+	updates := enqueued.wait()
+	require.Len(t, updates, 2)
+	for _, vzID := range []string{vz1ID, vz2ID} {
+		require.NotNil(t, updates[vzID].GetUpsertReq())
+		assert.Equal(t, expectedCronScript.Script, updates[vzID].GetUpsertReq().Script.Script)
+		assert.Equal(t, expectedCronScript.Configs, updates[vzID].GetUpsertReq().Script.Configs)
+		assert.Equal(t, expectedCronScript.FrequencyS, updates[vzID].GetUpsertReq().Script.FrequencyS)
+	}
+
 	id := resp.ID
 
 	query := `SELECT id, org_id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, enabled, frequency_s FROM cron_scripts WHERE org_id=$2 AND id=$3`
@@ -312,45 +265,40 @@ func TestServer_CreateScript(t *testing.T) {
 	}, script)
 }
 
-func sendUpdateAndWaitForResponse(t *testing.T, nc *nats.Conn, vzID string, wg *sync.WaitGroup, isUpdate bool) func() {
-	mdSub, err := nc.Subscribe(vzshard.C2VTopic(cvmsgs.CronScriptUpdatesChannel, uuid.FromStringOrNil(vzID)), func(msg *nats.Msg) {
-		c2vMsg := &cvmsgspb.C2VMessage{}
-		err := proto.Unmarshal(msg.Data, c2vMsg)
-		require.NoError(t, err)
-		req := &cvmsgspb.CronScriptUpdate{}
-		err = types.UnmarshalAny(c2vMsg.Msg, req)
-		require.NoError(t, err)
-		wg.Done()
-		// Send response.
-		var v2cMsg *cvmsgspb.V2CMessage
-		if isUpdate {
-			resp := &cvmsgspb.RegisterOrUpdateCronScriptResponse{}
-			v2cAnyMsg, err := types.MarshalAny(resp)
-			require.NoError(t, err)
-			v2cMsg = &cvmsgspb.V2CMessage{
-				Msg: v2cAnyMsg,
-			}
-			assert.NotNil(t, req.GetUpsertReq())
-		} else {
-			resp2 := &cvmsgspb.DeleteCronScriptResponse{}
-			v2cAnyMsg, err := types.MarshalAny(resp2)
+// enqueuedCronScriptUpdates records the cron script updates enqueued in each Vizier's outbox.
+type enqueuedCronScriptUpdates struct {
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	updates map[string]*cvmsgspb.CronScriptUpdate
+}
+
+// expectCronScriptUpdates expects count cron script updates to be enqueued through vzmgr.
+func expectCronScriptUpdates(t *testing.T, mockVZMgr *mock_vzmgrpb.MockVZMgrServiceClient, count int) *enqueuedCronScriptUpdates {
+	e := &enqueuedCronScriptUpdates{updates: make(map[string]*cvmsgspb.CronScriptUpdate)}
+	e.wg.Add(count)
+	mockVZMgr.EXPECT().EnqueueC2VMessage(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, in *vzmgrpb.EnqueueC2VMessageRequest, opts ...grpc.CallOption) (*vzmgrpb.EnqueueC2VMessageResponse, error) {
+			defer e.wg.Done()
+			assert.Equal(t, cvmsgs.CronScriptUpdatesChannel, in.Topic)
+			req := &cvmsgspb.CronScriptUpdate{}
+			err := types.UnmarshalAny(in.Msg, req)
 			require.NoError(t, err)
-			v2cMsg = &cvmsgspb.V2CMessage{
-				Msg: v2cAnyMsg,
-			}
-			assert.NotNil(t, req.GetDeleteReq())
-		}
-		b, err := v2cMsg.Marshal()
-		require.NoError(t, err)
 
-		err = nc.Publish(vzshard.V2CTopic(fmt.Sprintf("%s:%s", cvmsgs.CronScriptUpdatesResponseChannel, req.RequestID), uuid.FromStringOrNil(vzID)), b)
-		require.NoError(t, err)
-	})
-	require.NoError(t, err)
-	return func() {
-		err = mdSub.Unsubscribe()
-		require.NoError(t, err)
-	}
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.updates[utils.UUIDFromProtoOrNil(in.VizierID).String()] = req
+			return &vzmgrpb.EnqueueC2VMessageResponse{SeqID: int64(len(e.updates))}, nil
+		}).
+		Times(count)
+	return e
+}
+
+// wait blocks until all expected updates are enqueued and returns them keyed by Vizier ID.
+func (e *enqueuedCronScriptUpdates) wait() map[string]*cvmsgspb.CronScriptUpdate {
+	e.wg.Wait()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.updates
 }
 
 func TestServer_UpdateScript(t *testing.T) {
@@ -396,15 +344,7 @@ func TestServer_UpdateScript(t *testing.T) {
 		},
 	}, nil)
 
-	var wg sync.WaitGroup
-	wg.Add(3)
-
-	cleanup1 := sendUpdateAndWaitForResponse(t, nc, "323e4567-e89b-12d3-a456-426655440003", &wg, true)
-	defer cleanup1()
-	cleanup2 := sendUpdateAndWaitForResponse(t, nc, "323e4567-e89b-12d3-a456-426655440002", &wg, true)
-	defer cleanup2()
-	cleanup3 := sendUpdateAndWaitForResponse(t, nc, "323e4567-e89b-12d3-a456-426655440000", &wg, false)
-	defer cleanup3()
+	enqueued := expectCronScriptUpdates(t, mockVZMgr, 3)
 
 	resp, err := s.UpdateScript(createTestContext(), &cronscriptpb.UpdateScriptRequest{
 		Script:     &types.StringValue{Value: "px.updatedScript()"},
@@ -412,10 +352,14 @@ func TestServer_UpdateScript(t *testing.T) {
 		ClusterIDs: &cronscriptpb.ClusterIDs{Value: clusterIDs},
 		ScriptId:   utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440002"),
 	})
-	wg.Wait()
 	require.NoError(t, err)
 	require.NotNil(t, resp)
 
+	updates := enqueued.wait()
+	assert.NotNil(t, updates["323e4567-e89b-12d3-a456-426655440003"].GetUpsertReq())
+	assert.NotNil(t, updates["323e4567-e89b-12d3-a456-426655440002"].GetUpsertReq())
+	assert.NotNil(t, updates["323e4567-e89b-12d3-a456-426655440000"].GetDeleteReq())
+
 	query := `SELECT id, org_id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, enabled, frequency_s FROM cron_scripts WHERE org_id=$2 AND id=$3`
 	rows, err := db.Queryx(query, "test", "223e4567-e89b-12d3-a456-426655440000", "123e4567-e89b-12d3-a456-426655440002")
 	require.Nil(t, err)
@@ -467,23 +411,7 @@ func TestServer_DeleteScript(t *testing.T) {
 
 	s := controllers.New(db, "test", nc, mockVZMgr)
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	mdSub, err := nc.Subscribe(vzshard.C2VTopic(cvmsgs.CronScriptUpdatesChannel, uuid.FromStringOrNil("323e4567-e89b-12d3-a456-426655440000")), func(msg *nats.Msg) {
-		c2vMsg := &cvmsgspb.C2VMessage{}
-		err := proto.Unmarshal(msg.Data, c2vMsg)
-		require.NoError(t, err)
-		req := &cvmsgspb.CronScriptUpdate{}
-		err = types.UnmarshalAny(c2vMsg.Msg, req)
-		require.NoError(t, err)
-		assert.Equal(t, req.GetDeleteReq().ScriptID, scriptIDpb)
-		wg.Done()
-	})
-	defer func() {
-		err = mdSub.Unsubscribe()
-		require.NoError(t, err)
-	}()
+	enqueued := expectCronScriptUpdates(t, mockVZMgr, 1)
 
 	resp, err := s.DeleteScript(createTestContext(), &cronscriptpb.DeleteScriptRequest{
 		ID: scriptIDpb,
@@ -492,7 +420,8 @@ func TestServer_DeleteScript(t *testing.T) {
 	require.NotNil(t, resp)
 
 	assert.Equal(t, &cronscriptpb.DeleteScriptResponse{}, resp)
-	wg.Wait()
+	updates := enqueued.wait()
+	assert.Equal(t, scriptIDpb, updates["323e4567-e89b-12d3-a456-426655440000"].GetDeleteReq().ScriptID)
 
 	query := `SELECT id, org_id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, enabled, frequency_s FROM cron_scripts WHERE org_id=$2 AND id=$3`
 	rows, err := db.Queryx(query, "test", "223e4567-e89b-12d3-a456-426655440000", "123e4567-e89b-12d3-a456-426655440002")
@@ -618,3 +547,175 @@ func TestServer_HandleGetScriptsRequest(t *testing.T) {
 	s.HandleScriptsRequest(v2cMsg)
 	wg.Wait()
 }
+
+func TestServer_HandleGetScriptsRequestWithSelector(t *testing.T) {
+	mustLoadTestData(db)
+
+	insertScript := `INSERT INTO cron_scripts(id, org_id, script, cluster_ids, cluster_selector, configs, enabled, frequency_s) VALUES ($1, $2, $3, $4, $5, PGP_SYM_ENCRYPT($6, $7), $8, $9)`
+	db.MustExec(insertScript, "123e4567-e89b-12d3-a456-426655440003", "223e4567-e89b-12d3-a456-426655440001", "px.prod()", controllers.ClusterIDs([]uuid.UUID{}), "env=prod", "testConfigYaml3: ijkl", "test", true, 30)
+	db.MustExec(insertScript, "123e4567-e89b-12d3-a456-426655440004", "223e4567-e89b-12d3-a456-426655440001", "px.staging()", controllers.ClusterIDs([]uuid.UUID{}), "env=staging", "testConfigYaml4: mnop", "test", true, 30)
+
+	ctrl := gomock.NewController(t)
+	mockVZMgr := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
+
+	vzID := "523e4567-e89b-12d3-a456-426655440001"
+	orgID := "223e4567-e89b-12d3-a456-426655440001"
+
+	mockVZMgr.EXPECT().GetOrgFromVizier(gomock.Any(), utils.ProtoFromUUIDStrOrNil(vzID)).Return(&vzmgrpb.GetOrgFromVizierResponse{
+		OrgID: utils.ProtoFromUUIDStrOrNil(orgID)}, nil)
+	mockVZMgr.EXPECT().GetVizierInfo(gomock.Any(), utils.ProtoFromUUIDStrOrNil(vzID)).Return(&cvmsgspb.VizierInfo{
+		VizierID: utils.ProtoFromUUIDStrOrNil(vzID),
+		Labels:   map[string]string{"env": "prod"},
+	}, nil)
+
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+
+	s := controllers.New(db, "test", nc, mockVZMgr)
+
+	req := &cvmsgspb.GetCronScriptsRequest{
+		Topic: "test",
+	}
+	anyMsg, err := types.MarshalAny(req)
+	require.NoError(t, err)
+	v2cMsg := &cvmsgspb.V2CMessage{
+		Msg:      anyMsg,
+		VizierID: vzID,
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	csMap := map[string]*cvmsgspb.CronScript{
+		"123e4567-e89b-12d3-a456-426655440003": &cvmsgspb.CronScript{
+			ID:         utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440003"),
+			Script:     "px.prod()",
+			FrequencyS: 30,
+			Configs:    "testConfigYaml3: ijkl",
+		},
+	}
+	mdSub, err := nc.Subscribe(vzshard.C2VTopic(fmt.Sprintf("%s:%s", cvmsgs.GetCronScriptsResponseChannel, "test"), uuid.FromStringOrNil(vzID)), func(msg *nats.Msg) {
+		c2vMsg := &cvmsgspb.C2VMessage{}
+		err := proto.Unmarshal(msg.Data, c2vMsg)
+		require.NoError(t, err)
+		req := &cvmsgspb.GetCronScriptsResponse{}
+		err = types.UnmarshalAny(c2vMsg.Msg, req)
+		require.NoError(t, err)
+		assert.Equal(t, csMap, req.Scripts)
+		wg.Done()
+	})
+	defer func() {
+		err = mdSub.Unsubscribe()
+		require.NoError(t, err)
+	}()
+
+	s.HandleScriptsRequest(v2cMsg)
+	wg.Wait()
+}
+
+func TestServer_HandleGetScriptsRequestWithGroup(t *testing.T) {
+	mustLoadTestData(db)
+
+	insertScript := `INSERT INTO cron_scripts(id, org_id, script, cluster_ids, cluster_group, configs, enabled, frequency_s) VALUES ($1, $2, $3, $4, $5, PGP_SYM_ENCRYPT($6, $7), $8, $9)`
+	db.MustExec(insertScript, "123e4567-e89b-12d3-a456-426655440003", "223e4567-e89b-12d3-a456-426655440001", "px.prod()", controllers.ClusterIDs([]uuid.UUID{}), "prod", "testConfigYaml3: ijkl", "test", true, 30)
+	db.MustExec(insertScript, "123e4567-e89b-12d3-a456-426655440004", "223e4567-e89b-12d3-a456-426655440001", "px.staging()", controllers.ClusterIDs([]uuid.UUID{}), "staging", "testConfigYaml4: mnop", "test", true, 30)
+	// Scripts whose group has been deleted run nowhere rather than on every cluster.
+	db.MustExec(insertScript, "123e4567-e89b-12d3-a456-426655440005", "223e4567-e89b-12d3-a456-426655440001", "px.deleted()", controllers.ClusterIDs([]uuid.UUID{}), "deleted", "testConfigYaml5: qrst", "test", true, 30)
+
+	ctrl := gomock.NewController(t)
+	mockVZMgr := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
+
+	vzID := "523e4567-e89b-12d3-a456-426655440001"
+	orgID := "223e4567-e89b-12d3-a456-426655440001"
+
+	mockVZMgr.EXPECT().GetOrgFromVizier(gomock.Any(), utils.ProtoFromUUIDStrOrNil(vzID)).Return(&vzmgrpb.GetOrgFromVizierResponse{
+		OrgID: utils.ProtoFromUUIDStrOrNil(orgID)}, nil)
+	mockVZMgr.EXPECT().GetVizierInfo(gomock.Any(), utils.ProtoFromUUIDStrOrNil(vzID)).Return(&cvmsgspb.VizierInfo{
+		VizierID: utils.ProtoFromUUIDStrOrNil(vzID),
+		Labels:   map[string]string{"env": "prod"},
+	}, nil)
+	mockVZMgr.EXPECT().GetClusterGroups(gomock.Any(), utils.ProtoFromUUIDStrOrNil(orgID)).Return(&vzmgrpb.GetClusterGroupsResponse{
+		Groups: []*vzmgrpb.ClusterGroup{
+			{Name: "prod", Selector: "env=prod"},
+			{Name: "staging", Selector: "env=staging"},
+		},
+	}, nil)
+
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+
+	s := controllers.New(db, "test", nc, mockVZMgr)
+
+	req := &cvmsgspb.GetCronScriptsRequest{
+		Topic: "test",
+	}
+	anyMsg, err := types.MarshalAny(req)
+	require.NoError(t, err)
+	v2cMsg := &cvmsgspb.V2CMessage{
+		Msg:      anyMsg,
+		VizierID: vzID,
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	csMap := map[string]*cvmsgspb.CronScript{
+		"123e4567-e89b-12d3-a456-426655440003": &cvmsgspb.CronScript{
+			ID:         utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440003"),
+			Script:     "px.prod()",
+			FrequencyS: 30,
+			Configs:    "testConfigYaml3: ijkl",
+		},
+	}
+	mdSub, err := nc.Subscribe(vzshard.C2VTopic(fmt.Sprintf("%s:%s", cvmsgs.GetCronScriptsResponseChannel, "test"), uuid.FromStringOrNil(vzID)), func(msg *nats.Msg) {
+		c2vMsg := &cvmsgspb.C2VMessage{}
+		err := proto.Unmarshal(msg.Data, c2vMsg)
+		require.NoError(t, err)
+		req := &cvmsgspb.GetCronScriptsResponse{}
+		err = types.UnmarshalAny(c2vMsg.Msg, req)
+		require.NoError(t, err)
+		assert.Equal(t, csMap, req.Scripts)
+		wg.Done()
+	})
+	defer func() {
+		err = mdSub.Unsubscribe()
+		require.NoError(t, err)
+	}()
+
+	s.HandleScriptsRequest(v2cMsg)
+	wg.Wait()
+}
+
+func TestServer_CreateScriptInvalidSelector(t *testing.T) {
+	mustLoadTestData(db)
+
+	s := controllers.New(db, "test", nil, nil)
+	_, err := s.CreateScript(createTestContext(), &cronscriptpb.CreateScriptRequest{
+		Script:          "px.display()",
+		ClusterSelector: "-env=prod",
+		FrequencyS:      11,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_CreateScriptUnknownGroup(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctrl := gomock.NewController(t)
+	mockVZMgr := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
+	mockVZMgr.EXPECT().GetClusterGroups(gomock.Any(), utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000")).Return(&vzmgrpb.GetClusterGroupsResponse{
+		Groups: []*vzmgrpb.ClusterGroup{
+			{Name: "prod", Selector: "env=prod"},
+		},
+	}, nil)
+
+	s := controllers.New(db, "test", nil, mockVZMgr)
+	_, err := s.CreateScript(createTestContext(), &cronscriptpb.CreateScriptRequest{
+		Script:       "px.display()",
+		ClusterGroup: "staging",
+		FrequencyS:   11,
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
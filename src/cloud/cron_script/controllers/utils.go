@@ -23,6 +23,9 @@ import (
 	"encoding/json"
 
 	"github.com/gofrs/uuid"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/utils"
 )
 
 // ClusterIDs represents an array of cluster IDs.
@@ -46,3 +49,18 @@ func (p *ClusterIDs) Scan(src interface{}) error {
 	}
 	return json.Unmarshal(data, p)
 }
+
+// mergeClusterIDs returns the union of the given cluster ID lists, preserving order.
+func mergeClusterIDs(a []*uuidpb.UUID, b []*uuidpb.UUID) []*uuidpb.UUID {
+	seen := make(map[uuid.UUID]bool)
+	merged := make([]*uuidpb.UUID, 0, len(a)+len(b))
+	for _, id := range append(append([]*uuidpb.UUID{}, a...), b...) {
+		u := utils.UUIDFromProtoOrNil(id)
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		merged = append(merged, id)
+	}
+	return merged
+}
@@ -0,0 +1 @@
+ALTER TABLE cron_scripts ADD COLUMN cluster_selector varchar(1000) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE cron_scripts DROP COLUMN cluster_group;
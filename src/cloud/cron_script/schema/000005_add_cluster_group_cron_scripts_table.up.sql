@@ -0,0 +1 @@
+ALTER TABLE cron_scripts ADD COLUMN cluster_group varchar(255) NOT NULL DEFAULT '';
@@ -0,0 +1,232 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+)
+
+// acmeChallengeTTL is the TTL in seconds of the TXT records created for DNS-01 challenges.
+const acmeChallengeTTL = 60
+
+// CertIssuer issues SSL certs on demand.
+type CertIssuer interface {
+	// IssueCert returns a PEM encoded cert chain and key valid for the given domain.
+	IssueCert(ctx context.Context, domain string) (*IssuedCert, error)
+}
+
+// IssuedCert is a cert returned by a CertIssuer.
+type IssuedCert struct {
+	Cert     string
+	Key      string
+	NotAfter time.Time
+}
+
+// ACMEIssuer is a CertIssuer that gets certs from an ACME CA, such as Let's Encrypt,
+// solving DNS-01 challenges through a DNSService.
+type ACMEIssuer struct {
+	client     *acme.Client
+	dnsService DNSService
+	email      string
+	// PropagationWait is how long to wait after creating challenge records before asking the CA to validate them.
+	PropagationWait time.Duration
+}
+
+// NewACMEIssuer creates a new ACME issuer for the CA at the given directory URL. If httpClient is nil,
+// http.DefaultClient is used.
+func NewACMEIssuer(directoryURL string, accountKey crypto.Signer, email string, dnsService DNSService, httpClient *http.Client) *ACMEIssuer {
+	return &ACMEIssuer{
+		client: &acme.Client{
+			Key:          accountKey,
+			DirectoryURL: directoryURL,
+			HTTPClient:   httpClient,
+		},
+		dnsService:      dnsService,
+		email:           email,
+		PropagationWait: 30 * time.Second,
+	}
+}
+
+func (a *ACMEIssuer) register(ctx context.Context) error {
+	acct := &acme.Account{}
+	if a.email != "" {
+		acct.Contact = []string{"mailto:" + a.email}
+	}
+	_, err := a.client.Register(ctx, acct, acme.AcceptTOS)
+	if err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return err
+	}
+	return nil
+}
+
+// IssueCert orders a cert for the given domain, which may be a wildcard.
+func (a *ACMEIssuer) IssueCert(ctx context.Context, domain string) (*IssuedCert, error) {
+	if err := a.register(ctx); err != nil {
+		return nil, fmt.Errorf("failed to register ACME account: %w", err)
+	}
+
+	order, err := a.client.AuthorizeOrder(ctx, acme.DomainIDs(domain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ACME order: %w", err)
+	}
+	for _, authzURL := range order.AuthzURLs {
+		if err := a.authorize(ctx, authzURL); err != nil {
+			return nil, err
+		}
+	}
+	order, err = a.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, fmt.Errorf("ACME order failed: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domain},
+		DNSNames: []string{domain},
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+	chain, _, err := a.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize ACME order: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var certPEM bytes.Buffer
+	for _, der := range chain {
+		if err := pem.Encode(&certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+			return nil, err
+		}
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return &IssuedCert{
+		Cert:     certPEM.String(),
+		Key:      string(keyPEM),
+		NotAfter: leaf.NotAfter,
+	}, nil
+}
+
+// authorize solves the DNS-01 challenge for the authorization, if it isn't already valid.
+func (a *ACMEIssuer) authorize(ctx context.Context, authzURL string) error {
+	authz, err := a.client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("failed to get ACME authorization: %w", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var chal *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == "dns-01" {
+			chal = c
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("no dns-01 challenge offered for %s", authz.Identifier.Value)
+	}
+
+	value, err := a.client.DNS01ChallengeRecord(chal.Token)
+	if err != nil {
+		return err
+	}
+	recordName := fmt.Sprintf("_acme-challenge.%s.", strings.TrimPrefix(authz.Identifier.Value, "*."))
+	if err := a.dnsService.CreateTXTRecord(recordName, value, acmeChallengeTTL); err != nil {
+		return fmt.Errorf("failed to create challenge record: %w", err)
+	}
+	defer func() {
+		if err := a.dnsService.DeleteTXTRecord(recordName, value, acmeChallengeTTL); err != nil {
+			log.WithError(err).WithField("record", recordName).Error("Failed to delete challenge record")
+		}
+	}()
+
+	select {
+	case <-time.After(a.PropagationWait):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if _, err := a.client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("failed to accept ACME challenge: %w", err)
+	}
+	if _, err := a.client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("ACME authorization failed: %w", err)
+	}
+	return nil
+}
+
+// LoadOrCreateACMEAccountKey returns the account key for the given ACME directory, generating and
+// storing a new one if none exists yet.
+func LoadOrCreateACMEAccountKey(db *sqlx.DB, directoryURL string) (crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	// Only store the new key if there isn't one already, then read back whichever key won.
+	_, err = db.Exec(`INSERT INTO acme_accounts (directory_url, key) VALUES ($1, $2)
+		ON CONFLICT (directory_url) DO NOTHING`,
+		directoryURL, string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	if err != nil {
+		return nil, err
+	}
+
+	var keyPEM string
+	if err := db.Get(&keyPEM, `SELECT key FROM acme_accounts WHERE directory_url=$1`, directoryURL); err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, errors.New("invalid ACME account key")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
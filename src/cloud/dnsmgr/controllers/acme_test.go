@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/dnsmgr/controllers"
+	mock_controllers "px.dev/pixie/src/cloud/dnsmgr/controllers/mock"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+func TestACMEIssuer_IssueCert(t *testing.T) {
+	directoryURL, httpClient, cleanup, err := testingutils.SetupPebble()
+	require.NoError(t, err)
+	defer cleanup()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDNS := mock_controllers.NewMockDNSService(ctrl)
+
+	var challengeValue string
+	mockDNS.EXPECT().
+		CreateTXTRecord("_acme-challenge.abcd.clusters.example.com.", gomock.Any(), int64(60)).
+		DoAndReturn(func(name, value string, ttl int64) error {
+			challengeValue = value
+			return nil
+		})
+	mockDNS.EXPECT().
+		DeleteTXTRecord("_acme-challenge.abcd.clusters.example.com.", gomock.Any(), int64(60)).
+		DoAndReturn(func(name, value string, ttl int64) error {
+			assert.Equal(t, challengeValue, value)
+			return nil
+		})
+
+	accountKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	issuer := controllers.NewACMEIssuer(directoryURL, accountKey, "test@example.com", mockDNS, httpClient)
+	issuer.PropagationWait = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	cert, err := issuer.IssueCert(ctx, "*.abcd.clusters.example.com")
+	require.NoError(t, err)
+	assert.NotEmpty(t, challengeValue)
+	assert.True(t, cert.NotAfter.After(time.Now()))
+	assert.True(t, strings.HasPrefix(cert.Cert, "-----BEGIN CERTIFICATE-----"))
+
+	pair, err := tls.X509KeyPair([]byte(cert.Cert), []byte(cert.Key))
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*.abcd.clusters.example.com"}, leaf.DNSNames)
+}
@@ -20,14 +20,21 @@ package controllers
 
 import (
 	"context"
+	"strconv"
 
+	log "github.com/sirupsen/logrus"
 	"google.golang.org/api/dns/v1"
 	"google.golang.org/api/option"
 )
 
 // DNSService is a service that can get and update DNS records.
 type DNSService interface {
+	// CreateResourceRecord creates an A record with the given name and address.
 	CreateResourceRecord(name string, data string, ttl int64) error
+	// CreateTXTRecord creates a TXT record with the given name and value. It is used to solve ACME DNS-01 challenges.
+	CreateTXTRecord(name string, data string, ttl int64) error
+	// DeleteTXTRecord deletes a TXT record previously created by CreateTXTRecord.
+	DeleteTXTRecord(name string, data string, ttl int64) error
 }
 
 // DNSRecord represents a DNS record.
@@ -74,3 +81,52 @@ func (s *CloudDNSService) CreateResourceRecord(name string, data string, ttl int
 	_, err := cSvc.Create(s.DNSProject, s.DNSZone, change).Do()
 	return err
 }
+
+// CreateTXTRecord creates the TXT record with the given name and value.
+func (s *CloudDNSService) CreateTXTRecord(name string, data string, ttl int64) error {
+	change := &dns.Change{
+		Additions: []*dns.ResourceRecordSet{txtRecordSet(name, data, ttl)},
+	}
+	_, err := dns.NewChangesService(s.dnsService).Create(s.DNSProject, s.DNSZone, change).Do()
+	return err
+}
+
+// DeleteTXTRecord deletes the TXT record with the given name and value.
+func (s *CloudDNSService) DeleteTXTRecord(name string, data string, ttl int64) error {
+	change := &dns.Change{
+		Deletions: []*dns.ResourceRecordSet{txtRecordSet(name, data, ttl)},
+	}
+	_, err := dns.NewChangesService(s.dnsService).Create(s.DNSProject, s.DNSZone, change).Do()
+	return err
+}
+
+func txtRecordSet(name string, data string, ttl int64) *dns.ResourceRecordSet {
+	return &dns.ResourceRecordSet{
+		Name:    name,
+		Rrdatas: []string{strconv.Quote(data)},
+		Type:    "TXT",
+		Ttl:     ttl,
+	}
+}
+
+// NoopDNSService is a DNSService for deployments where DNS records are managed outside of Pixie,
+// for example with a static wildcard record. It only logs the records it is asked to create.
+type NoopDNSService struct{}
+
+// CreateResourceRecord logs the A record that would have been created.
+func (s *NoopDNSService) CreateResourceRecord(name string, data string, ttl int64) error {
+	log.WithField("name", name).WithField("address", data).Info("Skipping creation of DNS A record")
+	return nil
+}
+
+// CreateTXTRecord logs the TXT record that would have been created.
+func (s *NoopDNSService) CreateTXTRecord(name string, data string, ttl int64) error {
+	log.WithField("name", name).Info("Skipping creation of DNS TXT record")
+	return nil
+}
+
+// DeleteTXTRecord logs the TXT record that would have been deleted.
+func (s *NoopDNSService) DeleteTXTRecord(name string, data string, ttl int64) error {
+	log.WithField("name", name).Info("Skipping deletion of DNS TXT record")
+	return nil
+}
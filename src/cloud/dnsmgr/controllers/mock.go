@@ -19,3 +19,4 @@
 package controllers
 
 //go:generate mockgen -source=dns.go  -destination=mock/dns_mock.gen.go DNSService
+//go:generate mockgen -source=acme.go -destination=mock/acme_mock.gen.go CertIssuer
@@ -0,0 +1,492 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	dnsOpcodeUpdate = 5
+	dnsTypeA        = 1
+	dnsTypeSOA      = 6
+	dnsTypeTXT      = 16
+	dnsTypeTSIG     = 250
+	dnsClassIN      = 1
+	dnsClassNone    = 254
+	dnsClassAny     = 255
+	tsigFudge       = 300
+)
+
+var tsigAlgorithms = map[string]func() hash.Hash{
+	"hmac-sha1.":   sha1.New,
+	"hmac-sha256.": sha256.New,
+	"hmac-sha512.": sha512.New,
+}
+
+var tsigErrorNames = map[int]string{
+	16: "BADSIG",
+	17: "BADKEY",
+	18: "BADTIME",
+	22: "BADTRUNC",
+}
+
+var dnsRCodeNames = map[int]string{
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+// RFC2136DNSService updates records on an authoritative DNS server using RFC 2136 dynamic updates,
+// optionally signed with a TSIG key (RFC 8945).
+type RFC2136DNSService struct {
+	server      string
+	zone        string
+	tsigKeyName string
+	tsigAlg     string
+	tsigSecret  []byte
+	timeout     time.Duration
+	now         func() time.Time
+}
+
+// NewRFC2136DNSService creates a new RFC 2136 DNS service for the given zone. The server is a host:port
+// address. If tsigKeyName is empty, updates are sent unsigned. The TSIG secret is base64 encoded.
+func NewRFC2136DNSService(server, zone, tsigKeyName, tsigAlgorithm, tsigSecret string) (*RFC2136DNSService, error) {
+	if server == "" || zone == "" {
+		return nil, errors.New("server and zone are required for RFC 2136 updates")
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	s := &RFC2136DNSService{
+		server:  server,
+		zone:    fqdn(zone),
+		timeout: 10 * time.Second,
+		now:     time.Now,
+	}
+	if tsigKeyName != "" {
+		s.tsigKeyName = fqdn(strings.ToLower(tsigKeyName))
+		s.tsigAlg = fqdn(strings.ToLower(tsigAlgorithm))
+		if _, ok := tsigAlgorithms[s.tsigAlg]; !ok {
+			return nil, fmt.Errorf("unsupported TSIG algorithm %q", tsigAlgorithm)
+		}
+		secret, err := base64.StdEncoding.DecodeString(tsigSecret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TSIG secret: %w", err)
+		}
+		s.tsigSecret = secret
+	}
+	return s, nil
+}
+
+// dnsRR is a resource record in the update section of a DNS update message.
+type dnsRR struct {
+	rrType  uint16
+	rrClass uint16
+	ttl     uint32
+	rdata   []byte
+}
+
+// CreateResourceRecord replaces the A records with the given name by a single record with the given address.
+func (s *RFC2136DNSService) CreateResourceRecord(name string, data string, ttl int64) error {
+	ip := net.ParseIP(data).To4()
+	if ip == nil {
+		return fmt.Errorf("invalid IPv4 address %q", data)
+	}
+	// Deleting the RRset first, in the same update, keeps stale addresses from accumulating
+	// when a cluster's DNS address is requested again.
+	return s.update(name,
+		dnsRR{rrType: dnsTypeA, rrClass: dnsClassAny},
+		dnsRR{rrType: dnsTypeA, rrClass: dnsClassIN, ttl: uint32(ttl), rdata: ip})
+}
+
+// CreateTXTRecord adds a TXT record with the given name and value.
+func (s *RFC2136DNSService) CreateTXTRecord(name string, data string, ttl int64) error {
+	return s.update(name, dnsRR{rrType: dnsTypeTXT, rrClass: dnsClassIN, ttl: uint32(ttl), rdata: txtRData(data)})
+}
+
+// DeleteTXTRecord deletes the TXT record with the given name and value.
+func (s *RFC2136DNSService) DeleteTXTRecord(name string, data string, ttl int64) error {
+	// Deleting an individual RR is done by sending it with class NONE and a zero TTL.
+	return s.update(name, dnsRR{rrType: dnsTypeTXT, rrClass: dnsClassNone, rdata: txtRData(data)})
+}
+
+func (s *RFC2136DNSService) update(name string, rrs ...dnsRR) error {
+	id := uint16(rand.Intn(1 << 16))
+	msg := buildUpdateMessage(id, s.zone, fqdn(name), rrs)
+	var requestMAC []byte
+	if s.tsigKeyName != "" {
+		msg, requestMAC = s.sign(msg, id)
+	}
+	resp, err := s.exchange(msg)
+	if err != nil {
+		return err
+	}
+	if len(resp) < 12 || binary.BigEndian.Uint16(resp) != id {
+		return errors.New("invalid response to DNS update")
+	}
+	if s.tsigKeyName != "" {
+		if err := s.verify(resp, requestMAC); err != nil {
+			return fmt.Errorf("DNS update for %s: could not verify response: %w", name, err)
+		}
+	}
+	if rcode := int(binary.BigEndian.Uint16(resp[2:]) & 0xF); rcode != 0 {
+		rcodeName, ok := dnsRCodeNames[rcode]
+		if !ok {
+			rcodeName = fmt.Sprintf("RCODE %d", rcode)
+		}
+		return fmt.Errorf("DNS update for %s rejected: %s", name, rcodeName)
+	}
+	return nil
+}
+
+// exchange sends the message over TCP, which avoids any truncation issues, and returns the response.
+func (s *RFC2136DNSService) exchange(msg []byte) ([]byte, error) {
+	conn, err := net.DialTimeout("tcp", s.server, s.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return nil, err
+	}
+
+	framed := make([]byte, 2, 2+len(msg))
+	binary.BigEndian.PutUint16(framed, uint16(len(msg)))
+	if _, err := conn.Write(append(framed, msg...)); err != nil {
+		return nil, err
+	}
+	var lenBuf [2]byte
+	if _, err := io.ReadFull(conn, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(lenBuf[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// sign appends a TSIG record to the message and returns the signed message and its MAC.
+func (s *RFC2136DNSService) sign(msg []byte, id uint16) ([]byte, []byte) {
+	timeSigned := uint64(s.now().Unix())
+	keyName := encodeDNSName(s.tsigKeyName)
+	algName := encodeDNSName(s.tsigAlg)
+
+	// The MAC covers the unsigned message followed by the TSIG variables.
+	mac := hmac.New(tsigAlgorithms[s.tsigAlg], s.tsigSecret)
+	mac.Write(msg)
+	mac.Write(s.tsigVariables(timeSigned, tsigFudge, 0, nil))
+	sum := mac.Sum(nil)
+
+	var rdata bytes.Buffer
+	rdata.Write(algName)
+	writeUint48(&rdata, timeSigned)
+	writeUint16(&rdata, tsigFudge)
+	writeUint16(&rdata, uint16(len(sum)))
+	rdata.Write(sum)
+	writeUint16(&rdata, id)
+	writeUint16(&rdata, 0) // Error.
+	writeUint16(&rdata, 0) // Other len.
+
+	var out bytes.Buffer
+	out.Write(msg)
+	writeRR(&out, keyName, dnsTypeTSIG, dnsClassAny, 0, rdata.Bytes())
+	signed := out.Bytes()
+	// Bump ARCOUNT to account for the TSIG record.
+	binary.BigEndian.PutUint16(signed[10:], binary.BigEndian.Uint16(signed[10:])+1)
+	return signed, sum
+}
+
+// tsigVariables returns the TSIG fields which are covered by the MAC, in wire format.
+func (s *RFC2136DNSService) tsigVariables(timeSigned uint64, fudge, tsigError uint16, other []byte) []byte {
+	var vars bytes.Buffer
+	vars.Write(encodeDNSName(s.tsigKeyName))
+	writeUint16(&vars, dnsClassAny)
+	writeUint32(&vars, 0)
+	vars.Write(encodeDNSName(s.tsigAlg))
+	writeUint48(&vars, timeSigned)
+	writeUint16(&vars, fudge)
+	writeUint16(&vars, tsigError)
+	writeUint16(&vars, uint16(len(other)))
+	vars.Write(other)
+	return vars.Bytes()
+}
+
+// verify checks the TSIG record that a server must append to its response to a signed request.
+func (s *RFC2136DNSService) verify(resp []byte, requestMAC []byte) error {
+	r := &dnsReader{b: resp}
+	r.bytes(4)
+	qdCount := int(r.u16())
+	rrCount := int(r.u16()) + int(r.u16())
+	arCount := int(r.u16())
+	if r.err == nil && arCount == 0 {
+		return errors.New("response is not signed")
+	}
+	for i := 0; i < qdCount; i++ {
+		r.skipName()
+		r.bytes(4)
+	}
+	for i := 0; i < rrCount+arCount-1; i++ {
+		r.skipRR()
+	}
+
+	// The TSIG record must be the last record in the message.
+	tsigStart := r.off
+	keyName := r.name()
+	rrType := r.u16()
+	r.bytes(6) // Class and TTL.
+	rdLen := int(r.u16())
+	rdata := &dnsReader{b: r.bytes(rdLen)}
+	if r.err != nil {
+		return r.err
+	}
+	if rrType != dnsTypeTSIG {
+		return errors.New("response is not signed")
+	}
+	if r.off != len(resp) {
+		return errors.New("TSIG record is not the last record in the response")
+	}
+	if keyName != s.tsigKeyName {
+		return fmt.Errorf("response is signed with unexpected key %q", keyName)
+	}
+
+	alg := rdata.name()
+	timeSigned := rdata.u48()
+	fudge := rdata.u16()
+	mac := rdata.bytes(int(rdata.u16()))
+	origID := rdata.u16()
+	tsigError := rdata.u16()
+	other := rdata.bytes(int(rdata.u16()))
+	if rdata.err != nil {
+		return rdata.err
+	}
+	if tsigError != 0 {
+		errName, ok := tsigErrorNames[int(tsigError)]
+		if !ok {
+			errName = fmt.Sprintf("TSIG error %d", tsigError)
+		}
+		return fmt.Errorf("server rejected the request signature: %s", errName)
+	}
+	if alg != s.tsigAlg {
+		return fmt.Errorf("response is signed with unexpected algorithm %q", alg)
+	}
+
+	// The response MAC covers the request MAC, the response without its TSIG record and
+	// with the original ID, and the TSIG variables.
+	unsigned := append([]byte{}, resp[:tsigStart]...)
+	binary.BigEndian.PutUint16(unsigned, origID)
+	binary.BigEndian.PutUint16(unsigned[10:], uint16(arCount-1))
+	expected := hmac.New(tsigAlgorithms[s.tsigAlg], s.tsigSecret)
+	var macLen [2]byte
+	binary.BigEndian.PutUint16(macLen[:], uint16(len(requestMAC)))
+	expected.Write(macLen[:])
+	expected.Write(requestMAC)
+	expected.Write(unsigned)
+	expected.Write(s.tsigVariables(timeSigned, fudge, tsigError, other))
+	if !hmac.Equal(expected.Sum(nil), mac) {
+		return errors.New("invalid response signature")
+	}
+
+	now := s.now().Unix()
+	if diff := now - int64(timeSigned); diff > int64(fudge) || -diff > int64(fudge) {
+		return errors.New("response signature has expired")
+	}
+	return nil
+}
+
+func buildUpdateMessage(id uint16, zone, name string, rrs []dnsRR) []byte {
+	var b bytes.Buffer
+	writeUint16(&b, id)
+	writeUint16(&b, dnsOpcodeUpdate<<11)
+	writeUint16(&b, 1)                // ZOCOUNT
+	writeUint16(&b, 0)                // PRCOUNT
+	writeUint16(&b, uint16(len(rrs))) // UPCOUNT
+	writeUint16(&b, 0)                // ADCOUNT
+	// Zone section.
+	b.Write(encodeDNSName(zone))
+	writeUint16(&b, dnsTypeSOA)
+	writeUint16(&b, dnsClassIN)
+	// Update section.
+	for _, rr := range rrs {
+		writeRR(&b, encodeDNSName(name), rr.rrType, rr.rrClass, rr.ttl, rr.rdata)
+	}
+	return b.Bytes()
+}
+
+func writeRR(b *bytes.Buffer, name []byte, rrType, rrClass uint16, ttl uint32, rdata []byte) {
+	b.Write(name)
+	writeUint16(b, rrType)
+	writeUint16(b, rrClass)
+	writeUint32(b, ttl)
+	writeUint16(b, uint16(len(rdata)))
+	b.Write(rdata)
+}
+
+// txtRData encodes a TXT value as a sequence of character-strings of at most 255 bytes.
+func txtRData(data string) []byte {
+	var b bytes.Buffer
+	for {
+		chunk := data
+		if len(chunk) > 255 {
+			chunk = chunk[:255]
+		}
+		b.WriteByte(byte(len(chunk)))
+		b.WriteString(chunk)
+		data = data[len(chunk):]
+		if data == "" {
+			return b.Bytes()
+		}
+	}
+}
+
+// encodeDNSName encodes a fully qualified name in uncompressed, lowercase wire format.
+func encodeDNSName(name string) []byte {
+	var b bytes.Buffer
+	for _, label := range strings.Split(strings.TrimSuffix(strings.ToLower(name), "."), ".") {
+		if label == "" {
+			continue
+		}
+		b.WriteByte(byte(len(label)))
+		b.WriteString(label)
+	}
+	b.WriteByte(0)
+	return b.Bytes()
+}
+
+// dnsReader reads fields from a DNS message. The first out of bounds read sets err, after which
+// all reads return zero values.
+type dnsReader struct {
+	b   []byte
+	off int
+	err error
+}
+
+func (r *dnsReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if r.off+n > len(r.b) {
+		r.err = errors.New("truncated DNS message")
+		return nil
+	}
+	b := r.b[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *dnsReader) u16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *dnsReader) u48() uint64 {
+	if b := r.bytes(6); b != nil {
+		return uint64(binary.BigEndian.Uint16(b))<<32 | uint64(binary.BigEndian.Uint32(b[2:]))
+	}
+	return 0
+}
+
+// skipName skips over a possibly compressed name.
+func (r *dnsReader) skipName() {
+	for r.err == nil {
+		l := r.bytes(1)
+		switch {
+		case l == nil:
+		case l[0] == 0:
+			return
+		case l[0]&0xC0 == 0xC0:
+			r.bytes(1)
+			return
+		default:
+			r.bytes(int(l[0]))
+		}
+	}
+}
+
+// name reads an uncompressed name, as used in TSIG records, and returns it in lowercase.
+func (r *dnsReader) name() string {
+	var labels []string
+	for r.err == nil {
+		l := r.bytes(1)
+		switch {
+		case l == nil:
+		case l[0] == 0:
+			return fqdn(strings.ToLower(strings.Join(labels, ".")))
+		case l[0]&0xC0 != 0:
+			r.err = errors.New("unexpected compressed name")
+		default:
+			labels = append(labels, string(r.bytes(int(l[0]))))
+		}
+	}
+	return ""
+}
+
+func (r *dnsReader) skipRR() {
+	r.skipName()
+	r.bytes(8) // Type, class and TTL.
+	r.bytes(int(r.u16()))
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+func writeUint16(b *bytes.Buffer, v uint16) {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], v)
+	b.Write(buf[:])
+}
+
+func writeUint32(b *bytes.Buffer, v uint32) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	b.Write(buf[:])
+}
+
+func writeUint48(b *bytes.Buffer, v uint64) {
+	writeUint16(b, uint16(v>>32))
+	writeUint32(b, uint32(v))
+}
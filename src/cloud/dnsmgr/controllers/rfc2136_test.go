@@ -0,0 +1,367 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/dnsmgr/controllers"
+)
+
+type updateRR struct {
+	name  string
+	typ   uint16
+	class uint16
+	ttl   uint32
+	rdata []byte
+}
+
+type updateMsg struct {
+	opcode  int
+	zone    string
+	updates []updateRR
+	tsig    *updateRR
+	// signedPart is the message as it was before the TSIG record was added.
+	signedPart []byte
+}
+
+type msgReader struct {
+	b   []byte
+	off int
+}
+
+func (r *msgReader) u16() uint16 {
+	v := binary.BigEndian.Uint16(r.b[r.off:])
+	r.off += 2
+	return v
+}
+
+func (r *msgReader) name() string {
+	var labels []string
+	for {
+		l := int(r.b[r.off])
+		r.off++
+		if l == 0 {
+			return strings.Join(labels, ".") + "."
+		}
+		labels = append(labels, string(r.b[r.off:r.off+l]))
+		r.off += l
+	}
+}
+
+func (r *msgReader) rr() updateRR {
+	rr := updateRR{name: r.name(), typ: r.u16(), class: r.u16()}
+	rr.ttl = binary.BigEndian.Uint32(r.b[r.off:])
+	r.off += 4
+	l := int(r.u16())
+	rr.rdata = r.b[r.off : r.off+l]
+	r.off += l
+	return rr
+}
+
+func parseUpdate(t *testing.T, b []byte) *updateMsg {
+	r := &msgReader{b: b}
+	r.u16()
+	msg := &updateMsg{opcode: int(r.u16()>>11) & 0xF}
+	require.Equal(t, uint16(1), r.u16())
+	require.Equal(t, uint16(0), r.u16())
+	upCount := int(r.u16())
+	arCount := r.u16()
+	msg.zone = r.name()
+	r.u16()
+	r.u16()
+	for i := 0; i < upCount; i++ {
+		msg.updates = append(msg.updates, r.rr())
+	}
+	if arCount == 1 {
+		tsigStart := r.off
+		tsig := r.rr()
+		msg.tsig = &tsig
+		msg.signedPart = append([]byte{}, b[:tsigStart]...)
+		binary.BigEndian.PutUint16(msg.signedPart[10:], 0)
+	}
+	return msg
+}
+
+// tsigMAC returns the MAC field of a TSIG record.
+func tsigMAC(tsig *updateRR) []byte {
+	r := &msgReader{b: tsig.rdata}
+	r.name()
+	r.off += 8
+	macLen := int(r.u16())
+	return r.b[r.off : r.off+macLen]
+}
+
+// response returns a response to the given request with the given rcode.
+func response(req []byte, rcode uint16) []byte {
+	resp := make([]byte, 12)
+	copy(resp, req[:2])
+	binary.BigEndian.PutUint16(resp[2:], 1<<15|5<<11|rcode)
+	return resp
+}
+
+// signResponse appends a TSIG record for the given key to a response of a signed request.
+func signResponse(t *testing.T, req, resp []byte, keyName string, secret []byte, tsigError uint16) []byte {
+	reqMsg := parseUpdate(t, req)
+	require.NotNil(t, reqMsg.tsig)
+
+	var timeAndFudge bytes.Buffer
+	binary.Write(&timeAndFudge, binary.BigEndian, uint16(0))
+	binary.Write(&timeAndFudge, binary.BigEndian, uint32(time.Now().Unix()))
+	binary.Write(&timeAndFudge, binary.BigEndian, uint16(300))
+
+	encodedKey := append([]byte{byte(len(keyName))}, append([]byte(keyName), 0)...)
+	alg := []byte("\x0bhmac-sha256\x00")
+	var mac []byte
+	if tsigError == 0 {
+		reqMAC := tsigMAC(reqMsg.tsig)
+		h := hmac.New(sha256.New, secret)
+		binary.Write(h, binary.BigEndian, uint16(len(reqMAC)))
+		h.Write(reqMAC)
+		h.Write(resp)
+		h.Write(encodedKey)
+		h.Write([]byte{0, 255, 0, 0, 0, 0})
+		h.Write(alg)
+		h.Write(timeAndFudge.Bytes())
+		binary.Write(h, binary.BigEndian, tsigError)
+		h.Write([]byte{0, 0})
+		mac = h.Sum(nil)
+	}
+
+	var rdata bytes.Buffer
+	rdata.Write(alg)
+	rdata.Write(timeAndFudge.Bytes())
+	binary.Write(&rdata, binary.BigEndian, uint16(len(mac)))
+	rdata.Write(mac)
+	rdata.Write(resp[:2])
+	binary.Write(&rdata, binary.BigEndian, tsigError)
+	rdata.Write([]byte{0, 0})
+
+	signed := bytes.NewBuffer(append([]byte{}, resp...))
+	signed.Write(encodedKey)
+	signed.Write([]byte{0, 250, 0, 255, 0, 0, 0, 0})
+	binary.Write(signed, binary.BigEndian, uint16(rdata.Len()))
+	signed.Write(rdata.Bytes())
+	out := signed.Bytes()
+	binary.BigEndian.PutUint16(out[10:], 1)
+	return out
+}
+
+// fakeDNSServer accepts a single DNS update over TCP and replies with the response returned by respond.
+func fakeDNSServer(t *testing.T, respond func(req []byte) []byte) (string, <-chan []byte) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	msgCh := make(chan []byte, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var lenBuf [2]byte
+		if _, err := io.ReadFull(conn, lenBuf[:]); err != nil {
+			return
+		}
+		msg := make([]byte, binary.BigEndian.Uint16(lenBuf[:]))
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+		msgCh <- msg
+
+		resp := respond(msg)
+		binary.BigEndian.PutUint16(lenBuf[:], uint16(len(resp)))
+		conn.Write(append(lenBuf[:], resp...))
+	}()
+	return l.Addr().String(), msgCh
+}
+
+func TestRFC2136DNSService_CreateResourceRecord(t *testing.T) {
+	secret := []byte("super-secret-key")
+	addr, msgCh := fakeDNSServer(t, func(req []byte) []byte {
+		return signResponse(t, req, response(req, 0), "pixie-key", secret, 0)
+	})
+	s, err := controllers.NewRFC2136DNSService(addr, "clusters.example.com", "pixie-key", "hmac-sha256",
+		base64.StdEncoding.EncodeToString(secret))
+	require.NoError(t, err)
+
+	err = s.CreateResourceRecord("123.abcd.clusters.example.com.", "10.0.0.1", 30)
+	require.NoError(t, err)
+
+	msg := parseUpdate(t, <-msgCh)
+	assert.Equal(t, 5, msg.opcode)
+	assert.Equal(t, "clusters.example.com.", msg.zone)
+	// The existing A records are deleted in the same update.
+	assert.Equal(t, []updateRR{
+		{
+			name:  "123.abcd.clusters.example.com.",
+			typ:   1,
+			class: 255,
+			ttl:   0,
+			rdata: []byte{},
+		},
+		{
+			name:  "123.abcd.clusters.example.com.",
+			typ:   1,
+			class: 1,
+			ttl:   30,
+			rdata: []byte{10, 0, 0, 1},
+		},
+	}, msg.updates)
+
+	// Verify the TSIG MAC.
+	require.NotNil(t, msg.tsig)
+	assert.Equal(t, "pixie-key.", msg.tsig.name)
+	assert.Equal(t, uint16(250), msg.tsig.typ)
+	r := &msgReader{b: msg.tsig.rdata}
+	alg := r.name()
+	assert.Equal(t, "hmac-sha256.", alg)
+	timeAndFudge := r.b[r.off : r.off+8]
+	r.off += 8
+	macLen := int(r.u16())
+	mac := r.b[r.off : r.off+macLen]
+
+	var vars bytes.Buffer
+	vars.Write([]byte("\x09pixie-key\x00"))
+	vars.Write([]byte{0, 255, 0, 0, 0, 0})
+	vars.Write([]byte("\x0bhmac-sha256\x00"))
+	vars.Write(timeAndFudge)
+	vars.Write([]byte{0, 0, 0, 0})
+	h := hmac.New(sha256.New, secret)
+	h.Write(msg.signedPart)
+	h.Write(vars.Bytes())
+	assert.True(t, hmac.Equal(h.Sum(nil), mac))
+}
+
+func TestRFC2136DNSService_UnverifiedResponse(t *testing.T) {
+	secret := []byte("super-secret-key")
+	tests := []struct {
+		name        string
+		respond     func(req []byte) []byte
+		expectedErr string
+	}{
+		{
+			name: "unsigned",
+			respond: func(req []byte) []byte {
+				return response(req, 0)
+			},
+			expectedErr: "not signed",
+		},
+		{
+			name: "wrong key",
+			respond: func(req []byte) []byte {
+				return signResponse(t, req, response(req, 0), "pixie-key", []byte("other-key"), 0)
+			},
+			expectedErr: "invalid response signature",
+		},
+		{
+			name: "wrong key name",
+			respond: func(req []byte) []byte {
+				return signResponse(t, req, response(req, 0), "other", secret, 0)
+			},
+			expectedErr: "unexpected key",
+		},
+		{
+			name: "bad key",
+			respond: func(req []byte) []byte {
+				return signResponse(t, req, response(req, 9), "pixie-key", secret, 17)
+			},
+			expectedErr: "BADKEY",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, _ := fakeDNSServer(t, tc.respond)
+			s, err := controllers.NewRFC2136DNSService(addr, "example.com", "pixie-key", "hmac-sha256",
+				base64.StdEncoding.EncodeToString(secret))
+			require.NoError(t, err)
+
+			err = s.CreateResourceRecord("abcd.example.com", "10.0.0.1", 30)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expectedErr)
+		})
+	}
+}
+
+func unsignedResponse(req []byte) []byte {
+	return response(req, 0)
+}
+
+func TestRFC2136DNSService_TXTRecords(t *testing.T) {
+	addr, msgCh := fakeDNSServer(t, unsignedResponse)
+	s, err := controllers.NewRFC2136DNSService(addr, "example.com", "", "", "")
+	require.NoError(t, err)
+
+	require.NoError(t, s.CreateTXTRecord("_acme-challenge.abcd.example.com.", "token", 60))
+	msg := parseUpdate(t, <-msgCh)
+	assert.Nil(t, msg.tsig)
+	assert.Equal(t, []updateRR{{
+		name:  "_acme-challenge.abcd.example.com.",
+		typ:   16,
+		class: 1,
+		ttl:   60,
+		rdata: []byte("\x05token"),
+	}}, msg.updates)
+
+	addr, msgCh = fakeDNSServer(t, unsignedResponse)
+	s, err = controllers.NewRFC2136DNSService(addr, "example.com", "", "", "")
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteTXTRecord("_acme-challenge.abcd.example.com.", "token", 60))
+	msg = parseUpdate(t, <-msgCh)
+	// Individual records are deleted with class NONE.
+	require.Len(t, msg.updates, 1)
+	assert.Equal(t, uint16(254), msg.updates[0].class)
+	assert.Equal(t, uint32(0), msg.updates[0].ttl)
+	assert.Equal(t, []byte("\x05token"), msg.updates[0].rdata)
+}
+
+func TestRFC2136DNSService_Refused(t *testing.T) {
+	addr, _ := fakeDNSServer(t, func(req []byte) []byte {
+		return response(req, 5)
+	})
+	s, err := controllers.NewRFC2136DNSService(addr, "example.com", "", "", "")
+	require.NoError(t, err)
+
+	err = s.CreateResourceRecord("abcd.example.com", "10.0.0.1", 30)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REFUSED")
+}
+
+func TestNewRFC2136DNSService_InvalidConfig(t *testing.T) {
+	_, err := controllers.NewRFC2136DNSService("", "example.com", "", "", "")
+	assert.Error(t, err)
+	_, err = controllers.NewRFC2136DNSService("ns1:53", "example.com", "key", "hmac-md5", "c2VjcmV0")
+	assert.Error(t, err)
+	_, err = controllers.NewRFC2136DNSService("ns1:53", "example.com", "key", "hmac-sha256", "not base64!")
+	assert.Error(t, err)
+}
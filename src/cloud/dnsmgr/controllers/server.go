@@ -22,11 +22,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/cloud/dnsmgr/dnsmgrenv"
 	"px.dev/pixie/src/cloud/dnsmgr/dnsmgrpb"
@@ -36,11 +41,20 @@ import (
 // ResourceRecordTTL is the TTL of the resource record in seconds.
 const ResourceRecordTTL = 30
 
+// certIssueTimeout bounds how long a background cert issuance may take, including waiting for
+// the ACME challenge records to propagate.
+const certIssueTimeout = 10 * time.Minute
+
 // Server is an implementation of GRPC server for dnsmgr service.
 type Server struct {
 	env        dnsmgrenv.DNSMgrEnv
 	dnsService DNSService
+	certIssuer CertIssuer
 	db         *sqlx.DB
+
+	// issuingMu protects issuing, the set of clusters that have a cert issuance in progress.
+	issuingMu sync.Mutex
+	issuing   map[uuid.UUID]bool
 }
 
 // SSLCert represents an ssl cert in our system.
@@ -49,6 +63,7 @@ type SSLCert struct {
 	ClusterID *uuid.UUID `db:"cluster_id"`
 	Cert      string     `db:"cert"`
 	Key       string     `db:"key"`
+	ExpiresAt *time.Time `db:"expires_at"`
 }
 
 // NewServer creates a new GRPC dnsmgr server.
@@ -56,6 +71,100 @@ func NewServer(env dnsmgrenv.DNSMgrEnv, dnsService DNSService, db *sqlx.DB) *Ser
 	return &Server{env: env, dnsService: dnsService, db: db}
 }
 
+// NewServerWithCertIssuer creates a new GRPC dnsmgr server which issues new SSL certs
+// with the given issuer once the pool of preloaded certs runs out.
+func NewServerWithCertIssuer(env dnsmgrenv.DNSMgrEnv, dnsService DNSService, certIssuer CertIssuer, db *sqlx.DB) *Server {
+	return &Server{env: env, dnsService: dnsService, certIssuer: certIssuer, db: db, issuing: make(map[uuid.UUID]bool)}
+}
+
+// certDomain returns the wildcard domain covered by the cert for the given cname.
+func certDomain(cname string) string {
+	return fmt.Sprintf("*.%s.clusters.%s", cname, viper.GetString("domain_name"))
+}
+
+// startSSLCertIssuance issues a cert for the cluster in the background, since solving the ACME
+// challenges takes far longer than a GetSSLCerts call should. Clusters re-request their certs
+// periodically, and pick up the new cert once it is stored.
+func (s *Server) startSSLCertIssuance(clusterID uuid.UUID) {
+	s.issuingMu.Lock()
+	defer s.issuingMu.Unlock()
+	if s.issuing[clusterID] {
+		return
+	}
+	s.issuing[clusterID] = true
+
+	go func() {
+		defer func() {
+			s.issuingMu.Lock()
+			delete(s.issuing, clusterID)
+			s.issuingMu.Unlock()
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), certIssueTimeout)
+		defer cancel()
+		if _, err := s.issueSSLCert(ctx, clusterID); err != nil {
+			log.WithError(err).WithField("clusterID", clusterID).Error("Failed to issue SSL cert")
+			return
+		}
+		log.WithField("clusterID", clusterID).Info("Issued SSL cert")
+	}()
+}
+
+func (s *Server) issueSSLCert(ctx context.Context, clusterID uuid.UUID) (*SSLCert, error) {
+	// Cnames follow the same format as the preloaded certs: the first 8 characters of a UUID.
+	cname := uuid.Must(uuid.NewV4()).String()[:8]
+	issued, err := s.certIssuer.IssueCert(ctx, certDomain(cname))
+	if err != nil {
+		return nil, err
+	}
+
+	cert := &SSLCert{
+		CName:     cname,
+		ClusterID: &clusterID,
+		Cert:      issued.Cert,
+		Key:       issued.Key,
+		ExpiresAt: &issued.NotAfter,
+	}
+	query := `INSERT INTO ssl_certs (cname, cluster_id, cert, key, expires_at)
+		VALUES (:cname, :cluster_id, :cert, :key, :expires_at)`
+	if _, err := s.db.NamedExec(query, cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// RenewExpiringCerts reissues all certs issued by the cert issuer that expire within renewBefore.
+func (s *Server) RenewExpiringCerts(ctx context.Context, renewBefore time.Duration) error {
+	if s.certIssuer == nil {
+		return nil
+	}
+
+	var cnames []string
+	query := `SELECT cname FROM ssl_certs WHERE expires_at IS NOT NULL AND expires_at < $1`
+	if err := s.db.Select(&cnames, query, time.Now().Add(renewBefore)); err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, cname := range cnames {
+		issued, err := s.certIssuer.IssueCert(ctx, certDomain(cname))
+		if err != nil {
+			log.WithError(err).WithField("cname", cname).Error("Failed to renew SSL cert")
+			errs = append(errs, fmt.Sprintf("%s: %s", cname, err.Error()))
+			continue
+		}
+		_, err = s.db.Exec(`UPDATE ssl_certs SET cert=$1, key=$2, expires_at=$3 WHERE cname=$4`,
+			issued.Cert, issued.Key, issued.NotAfter, cname)
+		if err != nil {
+			return err
+		}
+		log.WithField("cname", cname).WithField("expiresAt", issued.NotAfter).Info("Renewed SSL cert")
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to renew %d certs: %s", len(errs), strings.Join(errs, ", "))
+	}
+	return nil
+}
+
 func (s *Server) createSSLCert(clusterID uuid.UUID) (*SSLCert, error) {
 	query := `UPDATE ssl_certs SET cluster_id=$1
 		WHERE cname =(SELECT cname FROM ssl_certs WHERE cluster_id IS NULL ORDER BY cname LIMIT 1) RETURNING *`
@@ -75,6 +184,10 @@ func (s *Server) createSSLCert(clusterID uuid.UUID) (*SSLCert, error) {
 		}
 		return &val, nil
 	}
+	if s.certIssuer != nil {
+		s.startSSLCertIssuance(clusterID)
+		return nil, status.Error(codes.Unavailable, "SSL cert is being issued, retry later")
+	}
 	return nil, errors.New("Could not read ssl_cert")
 }
 
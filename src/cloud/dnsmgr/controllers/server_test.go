@@ -24,6 +24,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	bindata "github.com/golang-migrate/migrate/source/go_bindata"
 	"github.com/golang/mock/gomock"
@@ -31,6 +32,8 @@ import (
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/cloud/dnsmgr/controllers"
 	mock_controllers "px.dev/pixie/src/cloud/dnsmgr/controllers/mock"
@@ -233,3 +236,90 @@ func TestServer_CreateNewSSLCertDefault(t *testing.T) {
 	assert.Equal(t, "cert-default", resp.Cert)
 	assert.Equal(t, "key-default", resp.Key)
 }
+
+func TestServer_IssueSSLCertWhenPoolEmpty(t *testing.T) {
+	viper.Set("domain_name", "withpixie.ai")
+	viper.Set("use_default_dns_cert", false)
+	mustLoadTestData(db)
+	db.MustExec(`UPDATE ssl_certs SET cluster_id='123e4567-e89b-12d3-a456-426655440009' WHERE cluster_id IS NULL`)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDNS := mock_controllers.NewMockDNSService(ctrl)
+	mockIssuer := mock_controllers.NewMockCertIssuer(ctrl)
+
+	notAfter := time.Now().Add(90 * 24 * time.Hour).UTC().Truncate(time.Second)
+	var issuedDomain string
+	mockIssuer.EXPECT().
+		IssueCert(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, domain string) (*controllers.IssuedCert, error) {
+			issuedDomain = domain
+			return &controllers.IssuedCert{Cert: "cert-issued", Key: "key-issued", NotAfter: notAfter}, nil
+		})
+
+	s := controllers.NewServerWithCertIssuer(nil, mockDNS, mockIssuer, db)
+
+	clusterID := "123e4567-e89b-12d3-a456-426655440001"
+	// The cert is issued in the background, so the first request asks the cluster to retry.
+	_, err := s.GetSSLCerts(context.Background(), &dnsmgrpb.GetSSLCertsRequest{
+		ClusterID: utils.ProtoFromUUIDStrOrNil(clusterID),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	var resp *dnsmgrpb.GetSSLCertsResponse
+	require.Eventually(t, func() bool {
+		resp, err = s.GetSSLCerts(context.Background(), &dnsmgrpb.GetSSLCertsRequest{
+			ClusterID: utils.ProtoFromUUIDStrOrNil(clusterID),
+		})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "cert-issued", resp.Cert)
+	assert.Equal(t, "key-issued", resp.Key)
+
+	var cert controllers.SSLCert
+	require.NoError(t, db.Get(&cert, `SELECT * FROM ssl_certs WHERE cluster_id=$1`, clusterID))
+	assert.Equal(t, "*."+cert.CName+".clusters.withpixie.ai", issuedDomain)
+	require.NotNil(t, cert.ExpiresAt)
+	assert.Equal(t, notAfter, cert.ExpiresAt.UTC())
+
+	// The cluster keeps its cert on subsequent requests.
+	resp, err = s.GetSSLCerts(context.Background(), &dnsmgrpb.GetSSLCertsRequest{
+		ClusterID: utils.ProtoFromUUIDStrOrNil(clusterID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "cert-issued", resp.Cert)
+}
+
+func TestServer_RenewExpiringCerts(t *testing.T) {
+	viper.Set("domain_name", "withpixie.ai")
+	mustLoadTestData(db)
+
+	insertQuery := `INSERT INTO ssl_certs(cname, cluster_id, cert, key, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	db.MustExec(insertQuery, "soon", "123e4567-e89b-12d3-a456-426655440002", "cert-soon", "key-soon",
+		time.Now().Add(24*time.Hour))
+	db.MustExec(insertQuery, "later", "123e4567-e89b-12d3-a456-426655440003", "cert-later", "key-later",
+		time.Now().Add(80*24*time.Hour))
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDNS := mock_controllers.NewMockDNSService(ctrl)
+	mockIssuer := mock_controllers.NewMockCertIssuer(ctrl)
+
+	notAfter := time.Now().Add(90 * 24 * time.Hour).UTC().Truncate(time.Second)
+	mockIssuer.EXPECT().
+		IssueCert(gomock.Any(), "*.soon.clusters.withpixie.ai").
+		Return(&controllers.IssuedCert{Cert: "cert-renewed", Key: "key-renewed", NotAfter: notAfter}, nil)
+
+	s := controllers.NewServerWithCertIssuer(nil, mockDNS, mockIssuer, db)
+	require.NoError(t, s.RenewExpiringCerts(context.Background(), 30*24*time.Hour))
+
+	var cert controllers.SSLCert
+	require.NoError(t, db.Get(&cert, `SELECT * FROM ssl_certs WHERE cname='soon'`))
+	assert.Equal(t, "cert-renewed", cert.Cert)
+	assert.Equal(t, "key-renewed", cert.Key)
+	assert.Equal(t, notAfter, cert.ExpiresAt.UTC())
+
+	require.NoError(t, db.Get(&cert, `SELECT * FROM ssl_certs WHERE cname='later'`))
+	assert.Equal(t, "cert-later", cert.Cert)
+}
@@ -19,10 +19,16 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"time"
 
 	bindata "github.com/golang-migrate/migrate/source/go_bindata"
+	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -43,6 +49,86 @@ func init() {
 	pflag.String("dns_project", "pl-dev-infra", "The project to use for cloud DNS")
 	pflag.String("domain_name", "withpixie.ai", "The domain name")
 	pflag.Bool("use_default_dns_cert", false, "Whether to use the default DNS ssl cert")
+	pflag.String("dns_provider", "clouddns", "The DNS provider to create records with: one of clouddns|rfc2136|noop")
+	pflag.String("rfc2136_server", "", "The host:port of the DNS server to send RFC 2136 updates to")
+	pflag.String("rfc2136_zone", "", "The zone to update with RFC 2136. Defaults to the domain name")
+	pflag.String("rfc2136_tsig_key_name", "", "The name of the TSIG key used to sign RFC 2136 updates")
+	pflag.String("rfc2136_tsig_algorithm", "hmac-sha256", "The TSIG algorithm: one of hmac-sha1|hmac-sha256|hmac-sha512")
+	pflag.String("rfc2136_tsig_secret", "", "The base64 encoded TSIG secret")
+	pflag.String("acme_directory_url", "", "If set, new SSL certs are issued from this ACME directory once the preloaded certs run out")
+	pflag.String("acme_email", "", "The contact email for the ACME account")
+	pflag.String("acme_ca_cert_path", "", "Path to a CA cert to trust for the ACME directory, eg: for a Pebble test server")
+	pflag.Duration("acme_propagation_wait", 30*time.Second, "How long to wait for DNS-01 challenge records to propagate")
+	pflag.Duration("acme_renew_before", 30*24*time.Hour, "Renew issued certs this long before they expire")
+}
+
+func newDNSService() (controllers.DNSService, error) {
+	switch viper.GetString("dns_provider") {
+	case "rfc2136":
+		zone := viper.GetString("rfc2136_zone")
+		if zone == "" {
+			zone = viper.GetString("domain_name")
+		}
+		return controllers.NewRFC2136DNSService(
+			viper.GetString("rfc2136_server"),
+			zone,
+			viper.GetString("rfc2136_tsig_key_name"),
+			viper.GetString("rfc2136_tsig_algorithm"),
+			viper.GetString("rfc2136_tsig_secret"),
+		)
+	case "noop":
+		return &controllers.NoopDNSService{}, nil
+	default:
+		return controllers.NewCloudDNSService(
+			viper.GetString("dns_zone"),
+			viper.GetString("dns_project"),
+			"/secrets/clouddns/dns_service_account.json",
+		)
+	}
+}
+
+func newACMEIssuer(db *sqlx.DB, dnsService controllers.DNSService) (*controllers.ACMEIssuer, error) {
+	directoryURL := viper.GetString("acme_directory_url")
+	key, err := controllers.LoadOrCreateACMEAccountKey(db, directoryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var httpClient *http.Client
+	if caPath := viper.GetString("acme_ca_cert_path"); caPath != "" {
+		caPEM, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(caPEM)
+		httpClient = &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		}
+	}
+
+	issuer := controllers.NewACMEIssuer(directoryURL, key, viper.GetString("acme_email"), dnsService, httpClient)
+	issuer.PropagationWait = viper.GetDuration("acme_propagation_wait")
+	return issuer, nil
+}
+
+func renewCerts(svr *controllers.Server, quitCh <-chan struct{}) {
+	renew := func() {
+		if err := svr.RenewExpiringCerts(context.Background(), viper.GetDuration("acme_renew_before")); err != nil {
+			log.WithError(err).Error("Failed to renew SSL certs")
+		}
+	}
+	renew()
+	t := time.NewTicker(12 * time.Hour)
+	defer t.Stop()
+	for {
+		select {
+		case <-quitCh:
+			return
+		case <-t.C:
+			renew()
+		}
+	}
 }
 
 func main() {
@@ -64,18 +150,27 @@ func main() {
 	}
 
 	env := dnsmgrenv.New()
-	dnsService, err := controllers.NewCloudDNSService(
-		viper.GetString("dns_zone"),
-		viper.GetString("dns_project"),
-		"/secrets/clouddns/dns_service_account.json",
-	)
-
+	dnsService, err := newDNSService()
 	if err != nil {
-		log.WithError(err).Info("Failed to connect to Cloud DNS service. Unable to generate DNS records for Direct mode.")
+		log.WithError(err).Info("Failed to connect to DNS service. Unable to generate DNS records for Direct mode.")
 		dnsService = nil
 	}
 
 	svr := controllers.NewServer(env, dnsService, db)
+	if viper.GetString("acme_directory_url") != "" {
+		if dnsService == nil {
+			log.Fatal("A DNS service is required to solve ACME challenges")
+		}
+		issuer, err := newACMEIssuer(db, dnsService)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to set up ACME issuer")
+		}
+		svr = controllers.NewServerWithCertIssuer(env, dnsService, issuer, db)
+
+		quitCh := make(chan struct{})
+		defer close(quitCh)
+		go renewCerts(svr, quitCh)
+	}
 
 	s := server.NewPLServer(env, mux)
 	dnsmgrpb.RegisterDNSMgrServiceServer(s.GRPCServer(), svr)
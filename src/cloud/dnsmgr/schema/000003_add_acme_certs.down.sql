@@ -0,0 +1,4 @@
+DROP TABLE acme_accounts;
+
+ALTER TABLE ssl_certs
+DROP COLUMN expires_at;
@@ -0,0 +1,11 @@
+-- expires_at is set for certs issued by the ACME issuer, which renews them before they expire.
+-- Certs loaded into the pool ahead of time are managed externally and leave it NULL.
+ALTER TABLE ssl_certs
+ADD COLUMN expires_at TIMESTAMP;
+
+CREATE TABLE acme_accounts (
+  -- directory_url is the URL of the ACME directory the account is registered with.
+  directory_url varchar(1000) PRIMARY KEY,
+  -- key is the PEM encoded private key of the account.
+  key varchar(8192) NOT NULL
+);
@@ -19,23 +19,32 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/nats-io/nats.go"
-	"github.com/olivere/elastic/v7"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/metadata"
 
 	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
 	"px.dev/pixie/src/cloud/shared/vzutils"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/services/msgbus"
+	jwtutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
 )
 
 // The topic on which updates are written to.
 const indexerMetadataTopic = "MetadataIndex"
 
+// How often the scripts of each org are reindexed.
+const scriptSyncInterval = 5 * time.Minute
+
 type concurrentIndexersMap struct {
 	unsafeMap map[string]*md.VizierIndexer
 	mapMu     sync.RWMutex
@@ -69,36 +78,51 @@ func (c *concurrentIndexersMap) values() []*md.VizierIndexer {
 type Indexer struct {
 	clusters *concurrentIndexersMap // Map from cluster UID->indexer.
 
-	st msgbus.Streamer
-	es *elastic.Client
+	st         msgbus.Streamer
+	newBackend md.BackendFactory
+	// The backend used to index scripts, which aren't tied to a Vizier.
+	scriptsBackend md.Backend
+
+	pluginClient pluginpb.DataRetentionPluginServiceClient
+	orgs         map[uuid.UUID]bool // The orgs with clusters being indexed.
+	orgsMu       sync.Mutex
 
 	watcher *vzutils.Watcher
+	quitCh  chan struct{}
 }
 
 // NewIndexer creates a new Vizier indexer. This is a wrapper around the Vizier Watcher, which starts the indexer
 // for any active viziers.
-func NewIndexer(nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServiceClient, st msgbus.Streamer, es *elastic.Client, fromShardID string, toShardID string) (*Indexer, error) {
+func NewIndexer(nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServiceClient, pluginClient pluginpb.DataRetentionPluginServiceClient, st msgbus.Streamer, newBackend md.BackendFactory, fromShardID string, toShardID string) (*Indexer, error) {
 	watcher, err := vzutils.NewWatcher(nc, vzmgrClient, fromShardID, toShardID)
 	if err != nil {
 		return nil, err
 	}
 
 	i := &Indexer{
-		clusters: &concurrentIndexersMap{unsafeMap: make(map[string]*md.VizierIndexer)},
-		watcher:  watcher,
-		st:       st,
-		es:       es,
+		clusters:       &concurrentIndexersMap{unsafeMap: make(map[string]*md.VizierIndexer)},
+		watcher:        watcher,
+		st:             st,
+		newBackend:     newBackend,
+		scriptsBackend: newBackend(uuid.Nil),
+		pluginClient:   pluginClient,
+		orgs:           make(map[uuid.UUID]bool),
+		quitCh:         make(chan struct{}),
 	}
 
 	err = watcher.RegisterVizierHandler(i.handleVizier)
 	if err != nil {
 		return nil, err
 	}
+
+	go i.syncScripts()
 	return i, nil
 }
 
 // Stop stops the indexer.
 func (i *Indexer) Stop() {
+	close(i.quitCh)
+
 	// Stop the watcher.
 	i.watcher.Stop()
 
@@ -115,7 +139,7 @@ func (i *Indexer) handleVizier(id uuid.UUID, orgID uuid.UUID, uid string) error
 	}
 
 	// Start indexer.
-	vzIndexer := md.NewVizierIndexer(id, orgID, uid, i.st, i.es)
+	vzIndexer := md.NewVizierIndexerWithBackend(id, orgID, uid, i.st, i.newBackend(id))
 	err := vzIndexer.Start(fmt.Sprintf("%s.%s", indexerMetadataTopic, uid))
 	if err != nil {
 		log.WithField("UID", uid).WithError(err).Error("Could not set up Vizier watcher for metadata updates")
@@ -123,5 +147,69 @@ func (i *Indexer) handleVizier(id uuid.UUID, orgID uuid.UUID, uid string) error
 	}
 
 	i.clusters.write(uid, vzIndexer)
+
+	i.orgsMu.Lock()
+	defer i.orgsMu.Unlock()
+	if !i.orgs[orgID] {
+		// Index the org's scripts right away, rather than waiting for the next sync.
+		i.orgs[orgID] = true
+		go func() {
+			err := IndexOrgScripts(i.pluginClient, i.scriptsBackend, orgID)
+			if err != nil {
+				log.WithField("org", orgID.String()).WithError(err).Error("Failed to index scripts")
+			}
+		}()
+	}
 	return nil
 }
+
+// contextForOrg returns a context authorized to make plugin requests on behalf of the given org.
+func contextForOrg(orgID uuid.UUID) (context.Context, error) {
+	svcJWT := jwtutils.GenerateJWTForAPIUser("", orgID.String(), time.Now().Add(time.Minute*10), viper.GetString("domain_name"), nil)
+	svcClaims, err := jwtutils.SignJWTClaims(svcJWT, viper.GetString("jwt_signing_key"))
+	if err != nil {
+		return nil, err
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization",
+		fmt.Sprintf("bearer %s", svcClaims)), nil
+}
+
+// IndexOrgScripts indexes the names of the scripts configured by the given org.
+func IndexOrgScripts(pluginClient pluginpb.DataRetentionPluginServiceClient, b md.Backend, orgID uuid.UUID) error {
+	ctx, err := contextForOrg(orgID)
+	if err != nil {
+		return err
+	}
+	resp, err := pluginClient.GetRetentionScripts(ctx, &pluginpb.GetRetentionScriptsRequest{
+		OrgID: utils.ProtoFromUUID(orgID),
+	})
+	if err != nil {
+		return err
+	}
+	return md.IndexScripts(b, orgID, resp.Scripts)
+}
+
+func (i *Indexer) syncScripts() {
+	ticker := time.NewTicker(scriptSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.quitCh:
+			return
+		case <-ticker.C:
+			i.orgsMu.Lock()
+			orgIDs := make([]uuid.UUID, 0, len(i.orgs))
+			for orgID := range i.orgs {
+				orgIDs = append(orgIDs, orgID)
+			}
+			i.orgsMu.Unlock()
+
+			for _, orgID := range orgIDs {
+				err := IndexOrgScripts(i.pluginClient, i.scriptsBackend, orgID)
+				if err != nil {
+					log.WithField("org", orgID.String()).WithError(err).Error("Failed to index scripts")
+				}
+			}
+		}
+	}
+}
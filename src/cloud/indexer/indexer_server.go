@@ -32,6 +32,8 @@ import (
 
 	"px.dev/pixie/src/cloud/indexer/controllers"
 	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/cloud/indexer/schema"
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
 	"px.dev/pixie/src/cloud/shared/esutils"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/services"
@@ -39,6 +41,7 @@ import (
 	"px.dev/pixie/src/shared/services/healthz"
 	"px.dev/pixie/src/shared/services/metrics"
 	"px.dev/pixie/src/shared/services/msgbus"
+	"px.dev/pixie/src/shared/services/pg"
 	"px.dev/pixie/src/shared/services/server"
 )
 
@@ -48,7 +51,9 @@ func init() {
 	pflag.String("es_user", "elastic", "The user for elastic")
 	pflag.String("es_passwd", "elastic", "The password for elastic")
 	pflag.String("vzmgr_service", "kubernetes:///vzmgr-service.plc:51800", "The profile service url (load balancer/list is ok)")
+	pflag.String("plugin_service", "plugin-service.plc.svc.cluster.local:50600", "The plugin service url (load balancer/list is ok)")
 	pflag.String("domain_name", "dev.withpixie.dev", "The domain name of Pixie Cloud")
+	pflag.String("search_backend", md.SearchBackendElastic, "The backend used to store metadata for search, either elastic or postgres")
 }
 
 func newVZMgrClient() (vzmgrpb.VZMgrServiceClient, error) {
@@ -65,6 +70,20 @@ func newVZMgrClient() (vzmgrpb.VZMgrServiceClient, error) {
 	return vzmgrpb.NewVZMgrServiceClient(vzmgrChannel), nil
 }
 
+func newPluginClient() (pluginpb.DataRetentionPluginServiceClient, error) {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	pluginChannel, err := grpc.Dial(viper.GetString("plugin_service"), dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return pluginpb.NewDataRetentionPluginServiceClient(pluginChannel), nil
+}
+
 func mustConnectElastic() *elastic.Client {
 	esURL := viper.GetString("es_url")
 
@@ -81,6 +100,28 @@ func mustConnectElastic() *elastic.Client {
 	return es
 }
 
+func mustCreateBackendFactory() md.BackendFactory {
+	switch backend := viper.GetString("search_backend"); backend {
+	case md.SearchBackendElastic:
+		es := mustConnectElastic()
+		err := md.InitializeMapping(es)
+		if err != nil {
+			log.WithError(err).Fatal("Could not initialize elastic mapping")
+		}
+		return md.ElasticBackendFactory(es)
+	case md.SearchBackendPostgres:
+		db := pg.MustConnectDefaultPostgresDB()
+		err := schema.PerformMigrations(db)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to apply migrations")
+		}
+		return md.PostgresBackendFactory(db)
+	default:
+		log.Fatalf("Unknown search backend: %s", backend)
+		return nil
+	}
+}
+
 func main() {
 	services.SetupService("indexer-service", 51800)
 	services.PostFlagSetupAndParse()
@@ -111,18 +152,19 @@ func main() {
 			Error("Got nats error")
 	})
 
-	es := mustConnectElastic()
-	err = md.InitializeMapping(es)
-	if err != nil {
-		log.WithError(err).Fatal("Could not initialize elastic mapping")
-	}
+	newBackend := mustCreateBackendFactory()
 
 	vzmgrClient, err := newVZMgrClient()
 	if err != nil {
 		log.WithError(err).Fatal("Could not connect to vzmgr")
 	}
 
-	indexer, err := controllers.NewIndexer(nc, vzmgrClient, strmr, es, "00", "ff")
+	pluginClient, err := newPluginClient()
+	if err != nil {
+		log.WithError(err).Fatal("Could not connect to plugin service")
+	}
+
+	indexer, err := controllers.NewIndexer(nc, vzmgrClient, pluginClient, strmr, newBackend, "00", "ff")
 	if err != nil {
 		log.WithError(err).Fatal("Could not start indexer")
 	}
@@ -0,0 +1,250 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v3"
+	"github.com/gofrs/uuid"
+	"github.com/olivere/elastic/v7"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	maxActionsPerBatch          = 256
+	maxActionBatchFlushInterval = time.Second * 30
+	maxElasticBackoffInterval   = time.Second * 60
+)
+
+var (
+	elasticRetriesCollector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "elastic_index_retries",
+		Help: "The number of retries for this particular index",
+	}, []string{"vizier_id"})
+)
+
+func init() {
+	prometheus.MustRegister(elasticRetriesCollector)
+}
+
+const elasticUpdateScript = `
+if (params.updateVersion <= ctx._source.updateVersion)  {
+  ctx.op = 'noop';
+}
+ctx._source.relatedEntityNames.addAll(params.entities);
+ctx._source.relatedEntityNames = ctx._source.relatedEntityNames.stream().distinct().sorted().collect(Collectors.toList());
+ctx._source.timeStoppedNS = params.timeStoppedNS;
+ctx._source.updateVersion = params.updateVersion;
+ctx._source.state = params.state;
+`
+
+// ownerUpdateScript is the painless equivalent of applyOwnerUpdate.
+const ownerUpdateScript = `
+if (params.active) {
+  if (!ctx._source.relatedEntityNames.contains(params.pod)) {
+    ctx._source.relatedEntityNames.add(params.pod);
+  }
+} else {
+  ctx._source.relatedEntityNames.removeIf(n -> n == params.pod);
+}
+ctx._source.relatedEntityNames = ctx._source.relatedEntityNames.stream().distinct().sorted().collect(Collectors.toList());
+if (ctx._source.relatedEntityNames.isEmpty()) {
+  ctx._source.state = params.terminatedState;
+  if (ctx._source.timeStoppedNS == 0) {
+    ctx._source.timeStoppedNS = params.timeStoppedNS;
+  }
+} else {
+  ctx._source.state = params.runningState;
+  ctx._source.timeStoppedNS = 0;
+}
+if (params.updateVersion > ctx._source.updateVersion) {
+  ctx._source.updateVersion = params.updateVersion;
+}
+`
+
+// terminateTablesScript marks the tables which are no longer part of a Vizier's tracepoints as terminated.
+const terminateTablesScript = `
+ctx._source.state = params.state;
+ctx._source.timeStoppedNS = params.timeStoppedNS;
+ctx._source.updateVersion = params.updateVersion;
+`
+
+// ElasticBackend is a Backend which stores the entities in elastic. Entity updates are batched using the bulk API.
+type ElasticBackend struct {
+	es *elastic.Client
+	// The label used for the retry metrics of this backend.
+	label string
+
+	bulk   *elastic.BulkService
+	bulkMu sync.Mutex
+
+	// Specification for when to flush updates to Elastic using the bulk API.
+	maxActionsPerBatch          int
+	maxActionBatchFlushInterval time.Duration
+	lastFlushTime               time.Time
+}
+
+// NewElasticBackendWithBulkSettings creates a new elastic backend with bulk settings.
+func NewElasticBackendWithBulkSettings(es *elastic.Client, label string, actionsPerBatch int, batchFlushInterval time.Duration) *ElasticBackend {
+	return &ElasticBackend{
+		es:    es,
+		label: label,
+		// This will get automatically reset for reuse after every call to `bulk.Do`.
+		bulk:                        es.Bulk().Index(IndexName),
+		maxActionsPerBatch:          actionsPerBatch,
+		maxActionBatchFlushInterval: batchFlushInterval,
+		lastFlushTime:               time.Now(),
+	}
+}
+
+// NewElasticBackend creates a new elastic backend.
+func NewElasticBackend(es *elastic.Client, label string) *ElasticBackend {
+	return NewElasticBackendWithBulkSettings(es, label, maxActionsPerBatch, maxActionBatchFlushInterval)
+}
+
+// ElasticBackendFactory returns a factory which creates a separate elastic backend for each Vizier, so that
+// the updates of each Vizier are batched independently.
+func ElasticBackendFactory(es *elastic.Client) BackendFactory {
+	return func(vizierID uuid.UUID) Backend {
+		return NewElasticBackend(es, vizierID.String())
+	}
+}
+
+// UpsertEntity creates or updates the entity with the given ID.
+func (b *ElasticBackend) UpsertEntity(id string, e *EsMDEntity) error {
+	b.bulkMu.Lock()
+	defer b.bulkMu.Unlock()
+
+	b.bulk.Add(elastic.NewBulkUpdateRequest().
+		Id(id).
+		Script(
+			elastic.NewScript(elasticUpdateScript).
+				Param("entities", e.RelatedEntityNames).
+				Param("timeStoppedNS", e.TimeStoppedNS).
+				Param("updateVersion", e.UpdateVersion).
+				Param("state", e.State).
+				Lang("painless")).
+		Upsert(e))
+	return b.maybeFlush()
+}
+
+// UpsertOwner creates or updates a workload, with the state of one of the pods it owns.
+func (b *ElasticBackend) UpsertOwner(id string, owner *EsMDEntity, pod string, podActive bool, podStoppedNS int64) error {
+	b.bulkMu.Lock()
+	defer b.bulkMu.Unlock()
+
+	b.bulk.Add(elastic.NewBulkUpdateRequest().
+		Id(id).
+		Script(
+			elastic.NewScript(ownerUpdateScript).
+				Param("pod", pod).
+				Param("active", podActive).
+				Param("timeStoppedNS", podStoppedNS).
+				Param("updateVersion", owner.UpdateVersion).
+				Param("runningState", ESMDEntityStateRunning).
+				Param("terminatedState", ESMDEntityStateTerminated).
+				Lang("painless")).
+		Upsert(owner))
+	return b.maybeFlush()
+}
+
+// TerminateTables terminates the tables of the given Vizier, other than those with the given IDs.
+func (b *ElasticBackend) TerminateTables(vizierID string, keepIDs []string, timestampNS int64) error {
+	query := elastic.NewBoolQuery().
+		Must(elastic.NewTermQuery("kind", string(EsMDTypeTable))).
+		Must(elastic.NewTermQuery("vizierID", vizierID)).
+		MustNot(elastic.NewTermQuery("state", ESMDEntityStateTerminated)).
+		MustNot(elastic.NewIdsQuery().Ids(keepIDs...))
+	_, err := b.es.UpdateByQuery(IndexName).
+		Query(query).
+		Script(
+			elastic.NewScript(terminateTablesScript).
+				Param("state", ESMDEntityStateTerminated).
+				Param("timeStoppedNS", timestampNS).
+				Param("updateVersion", timestampNS).
+				Lang("painless")).
+		Refresh("true").
+		Do(context.Background())
+	return err
+}
+
+// ReplaceScripts replaces the scripts of the given org.
+func (b *ElasticBackend) ReplaceScripts(orgID string, ids []string, scripts []*EsMDEntity) error {
+	if len(scripts) > 0 {
+		bulk := b.es.Bulk().Index(IndexName)
+		for i, s := range scripts {
+			bulk.Add(elastic.NewBulkIndexRequest().Id(ids[i]).Doc(s))
+		}
+		resp, err := bulk.Refresh("wait_for").Do(context.Background())
+		if err != nil {
+			return err
+		}
+		if failed := resp.Failed(); len(failed) > 0 {
+			return fmt.Errorf("Failed to index %d scripts: %s", len(failed), failed[0].Error.Reason)
+		}
+	}
+
+	query := elastic.NewBoolQuery().
+		Must(elastic.NewTermQuery("kind", string(EsMDTypeCronScript))).
+		Must(elastic.NewTermQuery("orgID", orgID)).
+		MustNot(elastic.NewIdsQuery().Ids(ids...))
+	_, err := b.es.DeleteByQuery(IndexName).Query(query).Refresh("true").Do(context.Background())
+	return err
+}
+
+// Flush writes any pending updates to elastic.
+func (b *ElasticBackend) Flush() error {
+	b.bulkMu.Lock()
+	defer b.bulkMu.Unlock()
+	return b.flush()
+}
+
+func (b *ElasticBackend) maybeFlush() error {
+	if b.bulk.NumberOfActions() >= b.maxActionsPerBatch || time.Since(b.lastFlushTime) > b.maxActionBatchFlushInterval {
+		return b.flush()
+	}
+	return nil
+}
+
+func (b *ElasticBackend) flush() error {
+	if b.bulk.NumberOfActions() == 0 {
+		b.lastFlushTime = time.Now()
+		return nil
+	}
+
+	bo := backoff.NewExponentialBackOff()
+	// We never want this to return for now and are hoping
+	// that elastic should start to respond after enough time.
+	bo.MaxElapsedTime = 0
+	bo.MaxInterval = maxElasticBackoffInterval
+
+	retryCount := 0.0
+	retryErr := backoff.Retry(func() error {
+		_, err := b.bulk.Refresh("wait_for").Do(context.Background())
+		elasticRetriesCollector.WithLabelValues(b.label).Set(retryCount)
+		retryCount++
+		return err
+	}, bo)
+	b.lastFlushTime = time.Now()
+	return retryErr
+}
@@ -54,6 +54,20 @@ const (
 	EsMDTypeScript EsMDType = "script"
 	// EsMDTypeNode is for node entities.
 	EsMDTypeNode EsMDType = "node"
+	// EsMDTypeContainer is for container entities.
+	EsMDTypeContainer EsMDType = "container"
+	// EsMDTypeDeployment is for deployment entities.
+	EsMDTypeDeployment EsMDType = "deployment"
+	// EsMDTypeReplicaSet is for replicaset entities.
+	EsMDTypeReplicaSet EsMDType = "replicaset"
+	// EsMDTypeStatefulSet is for statefulset entities.
+	EsMDTypeStatefulSet EsMDType = "statefulset"
+	// EsMDTypeDaemonSet is for daemonset entities.
+	EsMDTypeDaemonSet EsMDType = "daemonset"
+	// EsMDTypeTable is for the output tables of tracepoints.
+	EsMDTypeTable EsMDType = "table"
+	// EsMDTypeCronScript is for cron scripts, such as data retention plugin scripts.
+	EsMDTypeCronScript EsMDType = "cron_script"
 )
 
 // EsMDEntity is the struct that is stored in elastic.
@@ -19,76 +19,86 @@
 package md
 
 import (
-	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/cenkalti/backoff/v3"
 	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
 	"github.com/olivere/elastic/v7"
-	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/cloud/shared/vzshard"
+	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/k8s/metadatapb"
 	"px.dev/pixie/src/shared/services/msgbus"
+	"px.dev/pixie/src/utils"
 )
 
-const (
-	maxActionsPerBatch          = 256
-	maxActionBatchFlushInterval = time.Second * 30
-	maxElasticBackoffInterval   = time.Second * 60
-)
+// The topic on which the Vizier sends the output tables of its tracepoints.
+const tracepointTablesTopic = "DurableTracepointTables"
 
-var (
-	elasticRetriesCollector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "elastic_index_retries",
-		Help: "The number of retries for this particular index",
-	}, []string{"vizier_id"})
+// The backends which can be used to store and search the metadata entities.
+const (
+	SearchBackendElastic  = "elastic"
+	SearchBackendPostgres = "postgres"
 )
 
-func init() {
-	prometheus.MustRegister(elasticRetriesCollector)
+// Backend stores the metadata entities which are searched by autocomplete.
+type Backend interface {
+	// UpsertEntity creates or updates the entity with the given ID. The related entities of the update are added
+	// to those already stored, and updates which are older than the stored entity are dropped.
+	UpsertEntity(id string, e *EsMDEntity) error
+	// UpsertOwner creates or updates a workload with the state of one of the pods it owns.
+	UpsertOwner(id string, owner *EsMDEntity, pod string, podActive bool, podStoppedNS int64) error
+	// TerminateTables terminates the tables of the given Vizier, other than those with the given IDs.
+	TerminateTables(vizierID string, keepIDs []string, timestampNS int64) error
+	// ReplaceScripts replaces the scripts of the given org with the given scripts.
+	ReplaceScripts(orgID string, ids []string, scripts []*EsMDEntity) error
+	// Flush writes any buffered updates.
+	Flush() error
 }
 
+// BackendFactory returns the backend used by the indexer of the given Vizier.
+type BackendFactory func(vizierID uuid.UUID) Backend
+
 // VizierIndexer run the indexer for a single vizier index.
 type VizierIndexer struct {
 	st       msgbus.Streamer
-	es       *elastic.Client
-	bulk     *elastic.BulkService
+	backend  Backend
 	vizierID uuid.UUID
 	orgID    uuid.UUID
 	k8sUID   string
 
-	sub    msgbus.PersistentSub
-	quitCh chan bool
-	errCh  chan error
+	sub       msgbus.PersistentSub
+	tablesSub msgbus.PersistentSub
+	quitCh    chan bool
+	errCh     chan error
+}
 
-	// Specification for when to flush updates to Elastic using the bulk API.
-	maxActionsPerBatch          int
-	maxActionBatchFlushInterval time.Duration
-	lastFlushTime               time.Time
+// NewVizierIndexerWithBackend creates a new Vizier indexer which writes to the given backend.
+func NewVizierIndexerWithBackend(vizierID uuid.UUID, orgID uuid.UUID, k8sUID string, st msgbus.Streamer, backend Backend) *VizierIndexer {
+	return &VizierIndexer{
+		st:       st,
+		backend:  backend,
+		vizierID: vizierID,
+		orgID:    orgID,
+		k8sUID:   k8sUID,
+		quitCh:   make(chan bool),
+		errCh:    make(chan error),
+	}
 }
 
-// NewVizierIndexerWithBulkSettings creates a new Vizier indexer with bulk settings.
+// NewVizierIndexerWithBulkSettings creates a new elastic Vizier indexer with bulk settings.
 func NewVizierIndexerWithBulkSettings(vizierID uuid.UUID, orgID uuid.UUID, k8sUID string, st msgbus.Streamer,
 	es *elastic.Client, actionsPerBatch int, batchFlushInterval time.Duration) *VizierIndexer {
-	return &VizierIndexer{
-		st: st,
-		es: es,
-		// This will get automatically reset for reuse after every call to `bulk.Do`.
-		bulk:                        es.Bulk().Index(IndexName),
-		vizierID:                    vizierID,
-		orgID:                       orgID,
-		k8sUID:                      k8sUID,
-		quitCh:                      make(chan bool),
-		errCh:                       make(chan error),
-		maxActionsPerBatch:          actionsPerBatch,
-		maxActionBatchFlushInterval: batchFlushInterval,
-		lastFlushTime:               time.Now(),
-	}
-}
-
-// NewVizierIndexer creates a new Vizier indexer.
+	backend := NewElasticBackendWithBulkSettings(es, vizierID.String(), actionsPerBatch, batchFlushInterval)
+	return NewVizierIndexerWithBackend(vizierID, orgID, k8sUID, st, backend)
+}
+
+// NewVizierIndexer creates a new elastic Vizier indexer.
 func NewVizierIndexer(vizierID uuid.UUID, orgID uuid.UUID, k8sUID string, st msgbus.Streamer, es *elastic.Client) *VizierIndexer {
 	return NewVizierIndexerWithBulkSettings(vizierID, orgID, k8sUID, st, es, maxActionsPerBatch, maxActionBatchFlushInterval)
 }
@@ -106,6 +116,14 @@ func (v *VizierIndexer) Start(topic string) error {
 	}
 	v.sub = sub
 
+	tablesTopic := vzshard.V2CTopic(tracepointTablesTopic, v.vizierID)
+	tablesSub, err := v.st.PersistentSubscribe(tablesTopic, "indexer"+IndexName, v.tablesStreamHandler)
+	if err != nil {
+		v.sub.Close()
+		return fmt.Errorf("Failed to subscribe to topic %s: %s", tablesTopic, err.Error())
+	}
+	v.tablesSub = tablesSub
+
 	go func() {
 		for {
 			select {
@@ -126,6 +144,10 @@ func (v *VizierIndexer) Stop() {
 	if err != nil {
 		log.WithError(err).Error("Failed to un-subscribe from channel")
 	}
+	err = v.tablesSub.Close()
+	if err != nil {
+		log.WithError(err).Error("Failed to un-subscribe from channel")
+	}
 }
 
 func namespacedName(namespace string, name string) string {
@@ -246,6 +268,202 @@ func nodeConditionToState(node *metadatapb.NodeUpdate) ESMDEntityState {
 	return ESMDEntityStatePending
 }
 
+func containerStateToState(containerUpdate *metadatapb.ContainerUpdate) ESMDEntityState {
+	switch containerUpdate.ContainerState {
+	case metadatapb.CONTAINER_STATE_WAITING:
+		return ESMDEntityStatePending
+	case metadatapb.CONTAINER_STATE_RUNNING:
+		return ESMDEntityStateRunning
+	case metadatapb.CONTAINER_STATE_TERMINATED:
+		// Containers which exit successfully are terminated with the reason "Completed".
+		if containerUpdate.Reason == "" || containerUpdate.Reason == "Completed" {
+			return ESMDEntityStateTerminated
+		}
+		return ESMDEntityStateFailed
+	default:
+		if containerUpdate.StopTimestampNS > 0 {
+			return ESMDEntityStateTerminated
+		}
+		return ESMDEntityStateUnknown
+	}
+}
+
+func (v *VizierIndexer) containerUpdateToEMD(u *metadatapb.ResourceUpdate, containerUpdate *metadatapb.ContainerUpdate) *EsMDEntity {
+	// Containers which haven't been started yet don't have an ID, so we wait for a later update.
+	if containerUpdate.CID == "" {
+		return nil
+	}
+	relatedEntities := []string{}
+	if containerUpdate.PodName != "" {
+		relatedEntities = append(relatedEntities, namespacedName(containerUpdate.Namespace, containerUpdate.PodName))
+	}
+	return &EsMDEntity{
+		OrgID:              v.orgID.String(),
+		VizierID:           v.vizierID.String(),
+		ClusterUID:         v.k8sUID,
+		UID:                containerUpdate.CID,
+		Name:               containerUpdate.Name,
+		Kind:               string(EsMDTypeContainer),
+		TimeStartedNS:      containerUpdate.StartTimestampNS,
+		TimeStoppedNS:      containerUpdate.StopTimestampNS,
+		RelatedEntityNames: relatedEntities,
+		UpdateVersion:      u.UpdateVersion,
+		State:              containerStateToState(containerUpdate),
+	}
+}
+
+// ownerKindToEsMDType maps the kinds of the owner references of a pod to the entity types we index.
+var ownerKindToEsMDType = map[string]EsMDType{
+	"ReplicaSet":  EsMDTypeReplicaSet,
+	"StatefulSet": EsMDTypeStatefulSet,
+	"DaemonSet":   EsMDTypeDaemonSet,
+}
+
+// The characters which k8s uses to generate the pod-template-hash suffix of a replicaset's name.
+const podTemplateHashChars = "bcdfghjklmnpqrstvwxz2456789"
+
+// deploymentNameFromReplicaSet infers the name of the deployment which owns a replicaset. Replicasets created by
+// a deployment are named "<deployment>-<pod-template-hash>".
+func deploymentNameFromReplicaSet(rsName string) (string, bool) {
+	idx := strings.LastIndex(rsName, "-")
+	if idx <= 0 || idx == len(rsName)-1 {
+		return "", false
+	}
+	for _, c := range rsName[idx+1:] {
+		if !strings.ContainsRune(podTemplateHashChars, c) {
+			return "", false
+		}
+	}
+	return rsName[:idx], true
+}
+
+func podIsActive(podUpdate *metadatapb.PodUpdate) bool {
+	if podUpdate.StopTimestampNS > 0 {
+		return false
+	}
+	switch podUpdate.Phase {
+	case metadatapb.SUCCEEDED, metadatapb.FAILED, metadatapb.TERMINATED:
+		return false
+	default:
+		return true
+	}
+}
+
+func (v *VizierIndexer) ownerToEMD(u *metadatapb.ResourceUpdate, kind EsMDType, uid string, name string, podUpdate *metadatapb.PodUpdate) *EsMDEntity {
+	e := &EsMDEntity{
+		OrgID:              v.orgID.String(),
+		VizierID:           v.vizierID.String(),
+		ClusterUID:         v.k8sUID,
+		UID:                uid,
+		Name:               name,
+		Kind:               string(kind),
+		TimeStartedNS:      podUpdate.StartTimestampNS,
+		RelatedEntityNames: []string{},
+		UpdateVersion:      u.UpdateVersion,
+		State:              ESMDEntityStateTerminated,
+	}
+	if podIsActive(podUpdate) {
+		e.RelatedEntityNames = append(e.RelatedEntityNames, namespacedName(podUpdate.Namespace, podUpdate.Name))
+		e.State = ESMDEntityStateRunning
+	} else {
+		e.TimeStoppedNS = podUpdate.StopTimestampNS
+	}
+	return e
+}
+
+// podOwnersToEMD returns the workloads which own the given pod. Pods created by a deployment are owned by one of
+// its replicasets, so the deployment is inferred from the name of the replicaset.
+func (v *VizierIndexer) podOwnersToEMD(u *metadatapb.ResourceUpdate, podUpdate *metadatapb.PodUpdate) []*EsMDEntity {
+	owners := make([]*EsMDEntity, 0)
+	for _, ref := range podUpdate.OwnerReferences {
+		kind, ok := ownerKindToEsMDType[ref.Kind]
+		if !ok || ref.Name == "" {
+			continue
+		}
+		name := namespacedName(podUpdate.Namespace, ref.Name)
+		uid := ref.UID
+		if uid == "" {
+			uid = fmt.Sprintf("%s/%s", kind, name)
+		}
+		owners = append(owners, v.ownerToEMD(u, kind, uid, name, podUpdate))
+
+		if kind != EsMDTypeReplicaSet {
+			continue
+		}
+		if deployment, ok := deploymentNameFromReplicaSet(ref.Name); ok {
+			name := namespacedName(podUpdate.Namespace, deployment)
+			// Deployments aren't referenced by the pod, so we don't know their UID.
+			uid := fmt.Sprintf("%s/%s", EsMDTypeDeployment, name)
+			owners = append(owners, v.ownerToEMD(u, EsMDTypeDeployment, uid, name, podUpdate))
+		}
+	}
+	return owners
+}
+
+// applyOwnerUpdate tracks the active pods of a workload. A workload is running for as long as it has active pods.
+// Updates for different pods aren't ordered with respect to each other, so the update version isn't used to drop
+// updates here.
+func applyOwnerUpdate(owner *EsMDEntity, pod string, podActive bool, podStoppedNS int64, updateVersion int64) {
+	pods := make([]string, 0, len(owner.RelatedEntityNames)+1)
+	for _, p := range owner.RelatedEntityNames {
+		if p != pod {
+			pods = append(pods, p)
+		}
+	}
+	if podActive {
+		pods = append(pods, pod)
+	}
+	sort.Strings(pods)
+	owner.RelatedEntityNames = pods
+
+	if len(pods) == 0 {
+		owner.State = ESMDEntityStateTerminated
+		if owner.TimeStoppedNS == 0 {
+			owner.TimeStoppedNS = podStoppedNS
+		}
+	} else {
+		owner.State = ESMDEntityStateRunning
+		owner.TimeStoppedNS = 0
+	}
+	if updateVersion > owner.UpdateVersion {
+		owner.UpdateVersion = updateVersion
+	}
+}
+
+func lifeCycleStateToState(state vizierpb.LifeCycleState) ESMDEntityState {
+	switch state {
+	case vizierpb.PENDING_STATE:
+		return ESMDEntityStatePending
+	case vizierpb.RUNNING_STATE:
+		return ESMDEntityStateRunning
+	case vizierpb.FAILED_STATE:
+		return ESMDEntityStateFailed
+	case vizierpb.TERMINATED_STATE:
+		return ESMDEntityStateTerminated
+	default:
+		return ESMDEntityStateUnknown
+	}
+}
+
+func (v *VizierIndexer) tracepointTableToEMD(update *cvmsgspb.TracepointTablesUpdate, table *cvmsgspb.TracepointTable) *EsMDEntity {
+	relatedEntities := []string{}
+	if table.TracepointName != "" {
+		relatedEntities = append(relatedEntities, table.TracepointName)
+	}
+	return &EsMDEntity{
+		OrgID:              v.orgID.String(),
+		VizierID:           v.vizierID.String(),
+		ClusterUID:         v.k8sUID,
+		UID:                fmt.Sprintf("%s/%s", utils.UUIDFromProtoOrNil(table.TracepointID), table.Name),
+		Name:               table.Name,
+		Kind:               string(EsMDTypeTable),
+		TimeStartedNS:      update.TimestampNS,
+		RelatedEntityNames: relatedEntities,
+		UpdateVersion:      update.TimestampNS,
+		State:              lifeCycleStateToState(table.State),
+	}
+}
+
 func (v *VizierIndexer) resourceUpdateToEMD(update *metadatapb.ResourceUpdate) *EsMDEntity {
 	switch update.Update.(type) {
 	case *metadatapb.ResourceUpdate_NamespaceUpdate:
@@ -256,24 +474,14 @@ func (v *VizierIndexer) resourceUpdateToEMD(update *metadatapb.ResourceUpdate) *
 		return v.serviceUpdateToEMD(update, update.GetServiceUpdate())
 	case *metadatapb.ResourceUpdate_NodeUpdate:
 		return v.nodeUpdateToEMD(update, update.GetNodeUpdate())
+	case *metadatapb.ResourceUpdate_ContainerUpdate:
+		return v.containerUpdateToEMD(update, update.GetContainerUpdate())
 	default:
 		// We don't care about any other update types.
-		// Notably containerUpdates and nodeUpdates.
 		return nil
 	}
 }
 
-const elasticUpdateScript = `
-if (params.updateVersion <= ctx._source.updateVersion)  {
-  ctx.op = 'noop';
-}
-ctx._source.relatedEntityNames.addAll(params.entities);
-ctx._source.relatedEntityNames = ctx._source.relatedEntityNames.stream().distinct().sorted().collect(Collectors.toList());
-ctx._source.timeStoppedNS = params.timeStoppedNS;
-ctx._source.updateVersion = params.updateVersion;
-ctx._source.state = params.state;
-`
-
 func (v *VizierIndexer) streamHandler(msg msgbus.Msg) {
 	ru := metadatapb.ResourceUpdate{}
 	err := ru.Unmarshal(msg.Data())
@@ -305,43 +513,78 @@ func (v *VizierIndexer) streamHandler(msg msgbus.Msg) {
 	}
 }
 
-// HandleResourceUpdate indexes the resource update in elastic.
+func (v *VizierIndexer) tablesStreamHandler(msg msgbus.Msg) {
+	v2cMsg := &cvmsgspb.V2CMessage{}
+	update := &cvmsgspb.TracepointTablesUpdate{}
+	err := v2cMsg.Unmarshal(msg.Data())
+	if err == nil {
+		err = types.UnmarshalAny(v2cMsg.Msg, update)
+	}
+	if err != nil {
+		log.WithError(err).Error("Could not unmarshal tracepoint tables from stan")
+		v.errCh <- err
+	} else if err = v.HandleTracepointTablesUpdate(update); err != nil {
+		log.WithError(err).Error("Error handling tracepoint tables update")
+		v.errCh <- err
+	}
+
+	err = msg.Ack()
+	if err != nil {
+		log.WithError(err).Error("Failed to ack stan msg")
+	}
+}
+
+func (v *VizierIndexer) docID(uid string) string {
+	return fmt.Sprintf("%s-%s-%s", v.vizierID, v.k8sUID, uid)
+}
+
+// HandleResourceUpdate indexes the resource update.
 func (v *VizierIndexer) HandleResourceUpdate(update *metadatapb.ResourceUpdate) error {
 	esEntity := v.resourceUpdateToEMD(update)
 	if esEntity == nil { // We are not handling this resource yet.
 		return nil
 	}
 
-	id := fmt.Sprintf("%s-%s-%s", v.vizierID, v.k8sUID, esEntity.UID)
-	req := elastic.NewBulkUpdateRequest().
-		Id(id).
-		Script(
-			elastic.NewScript(elasticUpdateScript).
-				Param("entities", esEntity.RelatedEntityNames).
-				Param("timeStoppedNS", esEntity.TimeStoppedNS).
-				Param("updateVersion", esEntity.UpdateVersion).
-				Param("state", esEntity.State).
-				Lang("painless")).
-		Upsert(esEntity)
-	v.bulk.Add(req)
-
-	if v.bulk.NumberOfActions() >= v.maxActionsPerBatch || time.Since(v.lastFlushTime) > v.maxActionBatchFlushInterval {
-		bo := backoff.NewExponentialBackOff()
-		// We never want this to return for now and are hoping
-		// that elastic should start to respond after enough time.
-		bo.MaxElapsedTime = 0
-		bo.MaxInterval = maxElasticBackoffInterval
-
-		retryCount := 0.0
-		retryErr := backoff.Retry(func() error {
-			_, err := v.bulk.Refresh("wait_for").Do(context.Background())
-			elasticRetriesCollector.WithLabelValues(v.vizierID.String()).Set(retryCount)
-			retryCount++
-			return err
-		}, bo)
-		v.lastFlushTime = time.Now()
-		return retryErr
+	err := v.backend.UpsertEntity(v.docID(esEntity.UID), esEntity)
+	if err != nil {
+		return err
 	}
 
+	podUpdate := update.GetPodUpdate()
+	if podUpdate == nil {
+		return nil
+	}
+	pod := namespacedName(podUpdate.Namespace, podUpdate.Name)
+	for _, owner := range v.podOwnersToEMD(update, podUpdate) {
+		err = v.backend.UpsertOwner(v.docID(owner.UID), owner, pod, podIsActive(podUpdate), podUpdate.StopTimestampNS)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// HandleTracepointTablesUpdate indexes the output tables of a Vizier's tracepoints. Each update contains all of
+// the tables in the Vizier, so any previously indexed table which is missing from the update is terminated.
+func (v *VizierIndexer) HandleTracepointTablesUpdate(update *cvmsgspb.TracepointTablesUpdate) error {
+	ids := make([]string, len(update.Tables))
+	for i, t := range update.Tables {
+		esEntity := v.tracepointTableToEMD(update, t)
+		ids[i] = v.docID(esEntity.UID)
+		err := v.backend.UpsertEntity(ids[i], esEntity)
+		if err != nil {
+			return err
+		}
+	}
+	// Tables change rarely, so flush them immediately rather than waiting for the next batch.
+	err := v.backend.Flush()
+	if err != nil {
+		return err
+	}
+	return v.backend.TerminateTables(v.vizierID.String(), ids, update.TimestampNS)
+}
+
+// Flush writes any pending updates to the backend.
+func (v *VizierIndexer) Flush() error {
+	return v.backend.Flush()
+}
@@ -21,31 +21,51 @@ package md_test
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/gofrs/uuid"
+	bindata "github.com/golang-migrate/migrate/source/go_bindata"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/olivere/elastic/v7"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"px.dev/pixie/src/api/proto/vizierpb"
 	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/cloud/indexer/schema"
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/k8s/metadatapb"
+	"px.dev/pixie/src/shared/services/pgtest"
+	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/utils/testingutils"
 )
 
 var elasticClient *elastic.Client
+var db *sqlx.DB
 var vzID uuid.UUID
 var orgID uuid.UUID
 
 func TestMain(m *testing.M) {
-	es, cleanup, err := testingutils.SetupElastic()
+	es, esCleanup, err := testingutils.SetupElastic()
 	if err != nil {
-		cleanup()
+		esCleanup()
 		log.Fatal(err)
 	}
+	testDB, dbCleanup, err := pgtest.SetupTestDB(bindata.Resource(schema.AssetNames(), schema.Asset))
+	if err != nil {
+		esCleanup()
+		log.Fatal(err)
+	}
+	cleanup := func() {
+		esCleanup()
+		dbCleanup()
+	}
 
 	vzID = uuid.Must(uuid.NewV4())
 	orgID = uuid.Must(uuid.NewV4())
@@ -57,12 +77,82 @@ func TestMain(m *testing.M) {
 	}
 
 	elasticClient = es
+	db = testDB
 	code := m.Run()
 	// Can't be deferred b/c of os.Exit.
 	cleanup()
 	os.Exit(code)
 }
 
+// testBackend is a backend which the indexer tests are run against.
+type testBackend struct {
+	name    string
+	backend func() md.Backend
+	// entities returns the stored entities of the given kind and org, sorted by name.
+	entities func(t *testing.T, kind string, orgID uuid.UUID) []*md.EsMDEntity
+}
+
+func elasticEntities(t *testing.T, kind string, orgID uuid.UUID) []*md.EsMDEntity {
+	// Refresh the data since we are using "wait_for" on the indexer.
+	elasticClient.Refresh()
+	resp, err := elasticClient.Search().
+		Index(md.IndexName).
+		Query(elastic.NewBoolQuery().
+			Must(elastic.NewTermQuery("kind", kind)).
+			Must(elastic.NewTermQuery("orgID", orgID.String()))).
+		Sort("name.keyword", true).
+		Do(context.Background())
+	require.NoError(t, err)
+
+	entities := make([]*md.EsMDEntity, len(resp.Hits.Hits))
+	for i, hit := range resp.Hits.Hits {
+		entities[i] = &md.EsMDEntity{}
+		err = json.Unmarshal(hit.Source, entities[i])
+		require.NoError(t, err)
+	}
+	return entities
+}
+
+func postgresEntities(t *testing.T, kind string, orgID uuid.UUID) []*md.EsMDEntity {
+	query := `SELECT org_id, vizier_id, cluster_uid, uid, name, ns, kind, time_started_ns, time_stopped_ns,
+		related_entity_names, update_version, state FROM md_entities WHERE kind = $1 AND org_id = $2 ORDER BY name`
+	rows, err := db.Query(query, kind, orgID)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	entities := []*md.EsMDEntity{}
+	for rows.Next() {
+		e := &md.EsMDEntity{}
+		var related pq.StringArray
+		err = rows.Scan(&e.OrgID, &e.VizierID, &e.ClusterUID, &e.UID, &e.Name, &e.NS, &e.Kind, &e.TimeStartedNS,
+			&e.TimeStoppedNS, &related, &e.UpdateVersion, &e.State)
+		require.NoError(t, err)
+		e.RelatedEntityNames = related
+		entities = append(entities, e)
+	}
+	require.NoError(t, rows.Err())
+	return entities
+}
+
+func testBackends() []testBackend {
+	return []testBackend{
+		{
+			name: "elastic",
+			backend: func() md.Backend {
+				return md.NewElasticBackendWithBulkSettings(elasticClient, "test", 1, time.Second*1)
+			},
+			entities: elasticEntities,
+		},
+		{
+			name: "postgres",
+			backend: func() md.Backend {
+				return md.NewPostgresBackend(db)
+			},
+			entities: postgresEntities,
+		},
+	}
+}
+
 func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -367,30 +457,273 @@ func TestVizierIndexer_ResourceUpdate(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "container update",
+			updates: []*metadatapb.ResourceUpdate{
+				{
+					Update: &metadatapb.ResourceUpdate_ContainerUpdate{
+						ContainerUpdate: &metadatapb.ContainerUpdate{
+							CID:              "500",
+							Name:             "test-container",
+							Namespace:        "pl",
+							PodName:          "test-pod",
+							StartTimestampNS: 1000,
+							StopTimestampNS:  0,
+							ContainerState:   metadatapb.CONTAINER_STATE_RUNNING,
+						},
+					},
+					UpdateVersion:     1,
+					PrevUpdateVersion: 0,
+				},
+				{
+					Update: &metadatapb.ResourceUpdate_ContainerUpdate{
+						ContainerUpdate: &metadatapb.ContainerUpdate{
+							CID:              "500",
+							Name:             "test-container",
+							Namespace:        "pl",
+							PodName:          "test-pod",
+							StartTimestampNS: 1000,
+							StopTimestampNS:  1200,
+							ContainerState:   metadatapb.CONTAINER_STATE_TERMINATED,
+							Reason:           "OOMKilled",
+						},
+					},
+					UpdateVersion:     2,
+					PrevUpdateVersion: 1,
+				},
+			},
+			updateKind: "container",
+			expectedResults: []*md.EsMDEntity{
+				{
+					OrgID:              orgID.String(),
+					VizierID:           vzID.String(),
+					ClusterUID:         "test",
+					UID:                "500",
+					NS:                 "",
+					Name:               "test-container",
+					Kind:               "container",
+					TimeStartedNS:      int64(1000),
+					TimeStoppedNS:      int64(1200),
+					RelatedEntityNames: []string{"pl/test-pod"},
+					UpdateVersion:      2,
+					State:              md.ESMDEntityStateFailed,
+				},
+			},
+		},
+		{
+			name: "replicaset update",
+			updates: []*metadatapb.ResourceUpdate{
+				{
+					Update: &metadatapb.ResourceUpdate_PodUpdate{
+						PodUpdate: &metadatapb.PodUpdate{
+							UID:              "301",
+							Name:             "test-deploy-6d4f8c9b5-abcde",
+							Namespace:        "pl",
+							StartTimestampNS: 1000,
+							Phase:            metadatapb.RUNNING,
+							OwnerReferences: []*metadatapb.OwnerReference{
+								{Kind: "ReplicaSet", Name: "test-deploy-6d4f8c9b5", UID: "600"},
+							},
+						},
+					},
+					UpdateVersion: 3,
+				},
+				{
+					Update: &metadatapb.ResourceUpdate_PodUpdate{
+						PodUpdate: &metadatapb.PodUpdate{
+							UID:              "302",
+							Name:             "test-deploy-6d4f8c9b5-fghij",
+							Namespace:        "pl",
+							StartTimestampNS: 1100,
+							Phase:            metadatapb.RUNNING,
+							OwnerReferences: []*metadatapb.OwnerReference{
+								{Kind: "ReplicaSet", Name: "test-deploy-6d4f8c9b5", UID: "600"},
+							},
+						},
+					},
+					UpdateVersion: 4,
+				},
+				{
+					Update: &metadatapb.ResourceUpdate_PodUpdate{
+						PodUpdate: &metadatapb.PodUpdate{
+							UID:              "301",
+							Name:             "test-deploy-6d4f8c9b5-abcde",
+							Namespace:        "pl",
+							StartTimestampNS: 1000,
+							StopTimestampNS:  1200,
+							Phase:            metadatapb.TERMINATED,
+							OwnerReferences: []*metadatapb.OwnerReference{
+								{Kind: "ReplicaSet", Name: "test-deploy-6d4f8c9b5", UID: "600"},
+							},
+						},
+					},
+					UpdateVersion: 5,
+				},
+			},
+			updateKind: "replicaset",
+			expectedResults: []*md.EsMDEntity{
+				{
+					OrgID:              orgID.String(),
+					VizierID:           vzID.String(),
+					ClusterUID:         "test",
+					UID:                "600",
+					NS:                 "",
+					Name:               "pl/test-deploy-6d4f8c9b5",
+					Kind:               "replicaset",
+					TimeStartedNS:      int64(1000),
+					TimeStoppedNS:      int64(0),
+					RelatedEntityNames: []string{"pl/test-deploy-6d4f8c9b5-fghij"},
+					UpdateVersion:      5,
+					State:              md.ESMDEntityStateRunning,
+				},
+			},
+		},
+		{
+			name: "deployment update",
+			updates: []*metadatapb.ResourceUpdate{
+				{
+					Update: &metadatapb.ResourceUpdate_PodUpdate{
+						PodUpdate: &metadatapb.PodUpdate{
+							UID:              "302",
+							Name:             "test-deploy-6d4f8c9b5-fghij",
+							Namespace:        "pl",
+							StartTimestampNS: 1100,
+							StopTimestampNS:  1300,
+							Phase:            metadatapb.TERMINATED,
+							OwnerReferences: []*metadatapb.OwnerReference{
+								{Kind: "ReplicaSet", Name: "test-deploy-6d4f8c9b5", UID: "600"},
+							},
+						},
+					},
+					UpdateVersion: 6,
+				},
+			},
+			updateKind: "deployment",
+			expectedResults: []*md.EsMDEntity{
+				{
+					OrgID:              orgID.String(),
+					VizierID:           vzID.String(),
+					ClusterUID:         "test",
+					UID:                "deployment/pl/test-deploy",
+					NS:                 "",
+					Name:               "pl/test-deploy",
+					Kind:               "deployment",
+					TimeStartedNS:      int64(1000),
+					TimeStoppedNS:      int64(1300),
+					RelatedEntityNames: []string{},
+					UpdateVersion:      6,
+					State:              md.ESMDEntityStateTerminated,
+				},
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			indexer := md.NewVizierIndexerWithBulkSettings(vzID, orgID, "test", nil, elasticClient, 1, time.Second*1)
+			for _, b := range testBackends() {
+				t.Run(b.name, func(t *testing.T) {
+					indexer := md.NewVizierIndexerWithBackend(vzID, orgID, "test", nil, b.backend())
 
-			for _, u := range test.updates {
-				err := indexer.HandleResourceUpdate(u)
-				require.NoError(t, err)
-			}
+					for _, u := range test.updates {
+						err := indexer.HandleResourceUpdate(u)
+						require.NoError(t, err)
+					}
+					require.NoError(t, indexer.Flush())
 
-			// Refresh the data since we are using "wait_for" on the indexer.
-			elasticClient.Refresh()
-			resp, err := elasticClient.Search().
-				Index(md.IndexName).
-				Query(elastic.NewTermQuery("kind", test.updateKind)).
-				Do(context.Background())
-			require.NoError(t, err)
-			require.Equal(t, int64(len(test.expectedResults)), resp.TotalHits())
-			for i, r := range test.expectedResults {
-				res := &md.EsMDEntity{}
-				err = json.Unmarshal(resp.Hits.Hits[i].Source, res)
-				require.NoError(t, err)
-				assert.Equal(t, r, res)
+					assert.Equal(t, test.expectedResults, b.entities(t, test.updateKind, orgID))
+				})
 			}
 		})
 	}
 }
+
+func TestVizierIndexer_TracepointTablesUpdate(t *testing.T) {
+	for _, b := range testBackends() {
+		t.Run(b.name, func(t *testing.T) {
+			testTracepointTablesUpdate(t, b)
+		})
+	}
+}
+
+func testTracepointTablesUpdate(t *testing.T, b testBackend) {
+	tpID := uuid.Must(uuid.NewV4())
+	indexer := md.NewVizierIndexerWithBackend(vzID, orgID, "test", nil, b.backend())
+
+	err := indexer.HandleTracepointTablesUpdate(&cvmsgspb.TracepointTablesUpdate{
+		Tables: []*cvmsgspb.TracepointTable{
+			{Name: "http_table", TracepointID: utils.ProtoFromUUID(tpID), TracepointName: "http_probe", State: vizierpb.RUNNING_STATE},
+			{Name: "sql_table", TracepointID: utils.ProtoFromUUID(tpID), TracepointName: "http_probe", State: vizierpb.PENDING_STATE},
+		},
+		TimestampNS: 1000,
+	})
+	require.NoError(t, err)
+	// The second table has been removed from the tracepoint.
+	err = indexer.HandleTracepointTablesUpdate(&cvmsgspb.TracepointTablesUpdate{
+		Tables: []*cvmsgspb.TracepointTable{
+			{Name: "http_table", TracepointID: utils.ProtoFromUUID(tpID), TracepointName: "http_probe", State: vizierpb.RUNNING_STATE},
+		},
+		TimestampNS: 2000,
+	})
+	require.NoError(t, err)
+
+	expectedResults := []*md.EsMDEntity{
+		{
+			OrgID:              orgID.String(),
+			VizierID:           vzID.String(),
+			ClusterUID:         "test",
+			UID:                fmt.Sprintf("%s/http_table", tpID),
+			Name:               "http_table",
+			Kind:               "table",
+			TimeStartedNS:      int64(1000),
+			RelatedEntityNames: []string{"http_probe"},
+			UpdateVersion:      2000,
+			State:              md.ESMDEntityStateRunning,
+		},
+		{
+			OrgID:              orgID.String(),
+			VizierID:           vzID.String(),
+			ClusterUID:         "test",
+			UID:                fmt.Sprintf("%s/sql_table", tpID),
+			Name:               "sql_table",
+			Kind:               "table",
+			TimeStartedNS:      int64(1000),
+			TimeStoppedNS:      int64(2000),
+			RelatedEntityNames: []string{"http_probe"},
+			UpdateVersion:      2000,
+			State:              md.ESMDEntityStateTerminated,
+		},
+	}
+	assert.Equal(t, expectedResults, b.entities(t, "table", orgID))
+}
+
+func TestIndexScripts(t *testing.T) {
+	for _, b := range testBackends() {
+		t.Run(b.name, func(t *testing.T) {
+			testIndexScripts(t, b)
+		})
+	}
+}
+
+func testIndexScripts(t *testing.T, b testBackend) {
+	scriptOrgID := uuid.Must(uuid.NewV4())
+	script1 := uuid.Must(uuid.NewV4())
+	script2 := uuid.Must(uuid.NewV4())
+
+	err := md.IndexScripts(b.backend(), scriptOrgID, []*pluginpb.RetentionScript{
+		{ScriptID: utils.ProtoFromUUID(script1), ScriptName: "http_data", Enabled: true},
+		{ScriptID: utils.ProtoFromUUID(script2), ScriptName: "dns_data", Enabled: false},
+	})
+	require.NoError(t, err)
+	// The second script has been deleted.
+	err = md.IndexScripts(b.backend(), scriptOrgID, []*pluginpb.RetentionScript{
+		{ScriptID: utils.ProtoFromUUID(script1), ScriptName: "http_data", Enabled: true},
+	})
+	require.NoError(t, err)
+
+	entities := b.entities(t, "cron_script", scriptOrgID)
+	require.Equal(t, 1, len(entities))
+	res := entities[0]
+	assert.Equal(t, script1.String(), res.UID)
+	assert.Equal(t, "http_data", res.Name)
+	assert.Equal(t, "", res.ClusterUID)
+	assert.Equal(t, md.ESMDEntityStateRunning, res.State)
+}
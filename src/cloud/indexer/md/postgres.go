@@ -0,0 +1,165 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// PostgresBackend is a Backend which stores the entities in the md_entities table, so that metadata search
+// doesn't require elastic. Names are searched using the pg_trgm extension.
+type PostgresBackend struct {
+	db *sqlx.DB
+}
+
+// NewPostgresBackend creates a new postgres backend.
+func NewPostgresBackend(db *sqlx.DB) *PostgresBackend {
+	return &PostgresBackend{db: db}
+}
+
+// PostgresBackendFactory returns a factory which shares a single postgres backend between all Viziers. Updates
+// are written immediately, so there is no per-Vizier state.
+func PostgresBackendFactory(db *sqlx.DB) BackendFactory {
+	b := NewPostgresBackend(db)
+	return func(vizierID uuid.UUID) Backend {
+		return b
+	}
+}
+
+const insertEntityQuery = `
+INSERT INTO md_entities (id, org_id, vizier_id, cluster_uid, uid, name, ns, kind, time_started_ns, time_stopped_ns,
+  related_entity_names, update_version, state)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+// The related entities are merged and sorted byte-wise, which matches the ordering used by elastic.
+const upsertEntityQuery = insertEntityQuery + `
+ON CONFLICT (id) DO UPDATE SET
+  related_entity_names = ARRAY(
+    SELECT n FROM (
+      SELECT DISTINCT unnest(md_entities.related_entity_names || EXCLUDED.related_entity_names) AS n
+    ) AS d ORDER BY n COLLATE "C"),
+  time_stopped_ns = EXCLUDED.time_stopped_ns,
+  update_version = EXCLUDED.update_version,
+  state = EXCLUDED.state
+WHERE md_entities.update_version < EXCLUDED.update_version`
+
+func entityArgs(id string, e *EsMDEntity) []interface{} {
+	return []interface{}{id, e.OrgID, e.VizierID, e.ClusterUID, e.UID, e.Name, e.NS, e.Kind,
+		e.TimeStartedNS, e.TimeStoppedNS, stringArray(e.RelatedEntityNames), e.UpdateVersion, e.State}
+}
+
+// stringArray converts the slice to a postgres array. A nil slice would be stored as NULL rather than as an
+// empty array.
+func stringArray(s []string) pq.StringArray {
+	if s == nil {
+		return pq.StringArray{}
+	}
+	return pq.StringArray(s)
+}
+
+// UpsertEntity creates or updates the entity with the given ID.
+func (b *PostgresBackend) UpsertEntity(id string, e *EsMDEntity) error {
+	_, err := b.db.Exec(upsertEntityQuery, entityArgs(id, e)...)
+	return err
+}
+
+// UpsertOwner creates or updates a workload with the state of one of the pods it owns.
+func (b *PostgresBackend) UpsertOwner(id string, owner *EsMDEntity, pod string, podActive bool, podStoppedNS int64) error {
+	tx, err := b.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The owner is only inserted as is if this is the first update for it. Otherwise the update has to be merged
+	// with the stored pods of the owner.
+	res, err := tx.Exec(insertEntityQuery+` ON CONFLICT (id) DO NOTHING`, entityArgs(id, owner)...)
+	if err != nil {
+		return err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted > 0 {
+		return tx.Commit()
+	}
+
+	stored := &EsMDEntity{}
+	var pods pq.StringArray
+	query := `SELECT related_entity_names, time_stopped_ns, update_version, state FROM md_entities WHERE id = $1 FOR UPDATE`
+	err = tx.QueryRowx(query, id).Scan(&pods, &stored.TimeStoppedNS, &stored.UpdateVersion, &stored.State)
+	if err != nil {
+		return err
+	}
+	stored.RelatedEntityNames = pods
+	applyOwnerUpdate(stored, pod, podActive, podStoppedNS, owner.UpdateVersion)
+
+	query = `UPDATE md_entities SET related_entity_names = $2, time_stopped_ns = $3, update_version = $4, state = $5 WHERE id = $1`
+	_, err = tx.Exec(query, id, stringArray(stored.RelatedEntityNames), stored.TimeStoppedNS, stored.UpdateVersion, stored.State)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TerminateTables terminates the tables of the given Vizier, other than those with the given IDs.
+func (b *PostgresBackend) TerminateTables(vizierID string, keepIDs []string, timestampNS int64) error {
+	query := `
+UPDATE md_entities SET state = $1, time_stopped_ns = $2, update_version = $2
+WHERE kind = $3 AND vizier_id = $4 AND state != $1 AND NOT (id = ANY($5))`
+	_, err := b.db.Exec(query, ESMDEntityStateTerminated, timestampNS, string(EsMDTypeTable), vizierID, stringArray(keepIDs))
+	return err
+}
+
+// ReplaceScripts replaces the scripts of the given org with the given scripts.
+func (b *PostgresBackend) ReplaceScripts(orgID string, ids []string, scripts []*EsMDEntity) error {
+	tx, err := b.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := insertEntityQuery + `
+ON CONFLICT (id) DO UPDATE SET
+  name = EXCLUDED.name,
+  update_version = EXCLUDED.update_version,
+  state = EXCLUDED.state`
+	for i, s := range scripts {
+		_, err = tx.Exec(query, entityArgs(ids[i], s)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove any scripts which have been deleted since we last indexed the org.
+	query = `DELETE FROM md_entities WHERE kind = $1 AND org_id = $2 AND NOT (id = ANY($3))`
+	_, err = tx.Exec(query, string(EsMDTypeCronScript), orgID, stringArray(ids))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Flush is a no-op, since the postgres backend writes updates immediately.
+func (b *PostgresBackend) Flush() error {
+	return nil
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package md
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/utils"
+)
+
+func scriptToEMD(orgID uuid.UUID, script *pluginpb.RetentionScript, updateVersion int64) *EsMDEntity {
+	state := ESMDEntityStateTerminated
+	if script.Enabled {
+		state = ESMDEntityStateRunning
+	}
+	return &EsMDEntity{
+		OrgID:              orgID.String(),
+		UID:                utils.UUIDFromProtoOrNil(script.ScriptID).String(),
+		Name:               script.ScriptName,
+		Kind:               string(EsMDTypeCronScript),
+		RelatedEntityNames: []string{},
+		UpdateVersion:      updateVersion,
+		State:              state,
+	}
+}
+
+func scriptDocID(orgID uuid.UUID, scriptID string) string {
+	return fmt.Sprintf("%s-%s", orgID, scriptID)
+}
+
+// IndexScripts replaces the indexed scripts for the given org with the given scripts. Scripts aren't tied to a
+// cluster, so they are indexed without a cluster UID. Enabled scripts are considered running, and disabled
+// scripts terminated.
+func IndexScripts(b Backend, orgID uuid.UUID, scripts []*pluginpb.RetentionScript) error {
+	updateVersion := time.Now().UnixNano()
+	ids := make([]string, len(scripts))
+	entities := make([]*EsMDEntity, len(scripts))
+	for i, s := range scripts {
+		entities[i] = scriptToEMD(orgID, s, updateVersion)
+		ids[i] = scriptDocID(orgID, entities[i].UID)
+	}
+	return b.ReplaceScripts(orgID.String(), ids, entities)
+}
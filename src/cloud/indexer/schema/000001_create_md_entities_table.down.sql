@@ -0,0 +1 @@
+DROP TABLE IF EXISTS md_entities;
@@ -0,0 +1,27 @@
+CREATE EXTENSION IF NOT EXISTS "pg_trgm";
+
+CREATE TABLE md_entities (
+  -- The document ID of the entity, which is unique across all Viziers.
+  id varchar NOT NULL,
+  -- org_id is the org which the entity belongs to.
+  org_id UUID NOT NULL,
+  -- vizier_id and cluster_uid are empty for entities which aren't tied to a cluster, such as scripts.
+  vizier_id varchar NOT NULL DEFAULT '',
+  cluster_uid varchar NOT NULL DEFAULT '',
+  -- uid is the ID of the entity within its cluster.
+  uid varchar NOT NULL,
+  name varchar NOT NULL,
+  ns varchar NOT NULL DEFAULT '',
+  kind varchar NOT NULL,
+  time_started_ns bigint NOT NULL DEFAULT 0,
+  time_stopped_ns bigint NOT NULL DEFAULT 0,
+  related_entity_names varchar[] NOT NULL DEFAULT '{}',
+  update_version bigint NOT NULL DEFAULT 0,
+  state int NOT NULL DEFAULT 0,
+
+  PRIMARY KEY (id)
+);
+
+CREATE INDEX idx_md_entities_org_kind ON md_entities (org_id, kind);
+-- The trigram index is used to search for entities by any part of their name.
+CREATE INDEX idx_md_entities_name_trgm ON md_entities USING GIN (lower(name) gin_trgm_ops);
@@ -0,0 +1,36 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package schema
+
+import (
+	bindata "github.com/golang-migrate/migrate/source/go_bindata"
+	"github.com/jmoiron/sqlx"
+
+	"px.dev/pixie/src/cloud/shared/pgmigrate"
+)
+
+//go:generate go-bindata -modtime=1 -ignore=\.go -ignore=\.sh -ignore=\.bazel -pkg=schema -o=bindata.gen.go ./...
+
+// PerformMigrations creates or updates the md_entities table used by the postgres search backend. It is run by
+// each of the services which use the table, so that none of them depend on another having started first.
+// Concurrent runs are serialized by the migration lock.
+func PerformMigrations(db *sqlx.DB) error {
+	return pgmigrate.PerformMigrationsUsingBindata(db, "indexer_service_migrations",
+		bindata.Resource(AssetNames(), Asset))
+}
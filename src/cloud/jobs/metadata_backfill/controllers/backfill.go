@@ -0,0 +1,217 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+
+	indexer "px.dev/pixie/src/cloud/indexer/controllers"
+	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/cloud/shared/vzshard"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/shared/k8s/metadatapb"
+	"px.dev/pixie/src/utils"
+)
+
+// The topic on which to make metadata requests.
+const metadataRequestTopic = "MetadataRequest"
+
+// The topic on which to listen to metadata responses.
+const metadataResponseTopic = "MetadataResponse"
+
+// UpdateHandlerFn is called for each of the metadata updates fetched from a Vizier.
+type UpdateHandlerFn func(update *metadatapb.ResourceUpdate) error
+
+// Backfiller reindexes the metadata of existing clusters, and the scripts of their orgs.
+type Backfiller struct {
+	nc           *nats.Conn
+	newBackend   md.BackendFactory
+	vzmgrClient  vzmgrpb.VZMgrServiceClient
+	pluginClient pluginpb.DataRetentionPluginServiceClient
+
+	// How long to wait for a Vizier to respond before giving up on it.
+	timeout time.Duration
+}
+
+// NewBackfiller creates a new Backfiller.
+func NewBackfiller(nc *nats.Conn, newBackend md.BackendFactory, vzmgrClient vzmgrpb.VZMgrServiceClient, pluginClient pluginpb.DataRetentionPluginServiceClient, timeout time.Duration) *Backfiller {
+	return &Backfiller{
+		nc:           nc,
+		newBackend:   newBackend,
+		vzmgrClient:  vzmgrClient,
+		pluginClient: pluginClient,
+		timeout:      timeout,
+	}
+}
+
+// Run reindexes all of the Viziers in the given shard range. Viziers which can't be reindexed are logged and
+// skipped, so that one unreachable cluster doesn't block the others.
+func (b *Backfiller) Run(ctx context.Context, fromShardID string, toShardID string) error {
+	resp, err := b.vzmgrClient.GetViziersByShard(ctx, &vzmgrpb.GetViziersByShardRequest{
+		FromShardID: fromShardID,
+		ToShardID:   toShardID,
+	})
+	if err != nil {
+		return err
+	}
+
+	orgs := make(map[uuid.UUID]bool)
+	failed := 0
+	for _, vz := range resp.Viziers {
+		vzID := utils.UUIDFromProtoOrNil(vz.VizierID)
+		orgID := utils.UUIDFromProtoOrNil(vz.OrgID)
+		orgs[orgID] = true
+
+		count, err := b.BackfillVizier(vzID, orgID, vz.K8sUID)
+		if err != nil {
+			log.WithError(err).WithField("vizier", vzID.String()).Error("Failed to backfill Vizier")
+			failed++
+			continue
+		}
+		log.WithField("vizier", vzID.String()).WithField("updates", count).Info("Backfilled Vizier")
+	}
+
+	for orgID := range orgs {
+		err := indexer.IndexOrgScripts(b.pluginClient, b.newBackend(uuid.Nil), orgID)
+		if err != nil {
+			log.WithError(err).WithField("org", orgID.String()).Error("Failed to backfill scripts")
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Failed to backfill %d viziers/orgs", failed)
+	}
+	return nil
+}
+
+// BackfillVizier reindexes all of the metadata currently held by the given Vizier. It returns the number of
+// updates which were indexed.
+func (b *Backfiller) BackfillVizier(vzID uuid.UUID, orgID uuid.UUID, k8sUID string) (int, error) {
+	vzIndexer := md.NewVizierIndexerWithBackend(vzID, orgID, k8sUID, nil, b.newBackend(vzID))
+	count, err := FetchAllUpdates(b.nc, vzID, b.timeout, vzIndexer.HandleResourceUpdate)
+	if err != nil {
+		return count, err
+	}
+	return count, vzIndexer.Flush()
+}
+
+func readMetadataResponse(data []byte) (*metadatapb.MissingK8SMetadataResponse, error) {
+	v2cMsg := &cvmsgspb.V2CMessage{}
+	err := proto.Unmarshal(data, v2cMsg)
+	if err != nil {
+		return nil, err
+	}
+	updates := &metadatapb.MissingK8SMetadataResponse{}
+	err = types.UnmarshalAny(v2cMsg.Msg, updates)
+	if err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// FetchAllUpdates requests all of the metadata updates held by a Vizier, and calls handleFn on each of them in
+// order. A Vizier which is unreachable, or has no updates, doesn't respond at all, so timing out is an error.
+func FetchAllUpdates(nc *nats.Conn, vzID uuid.UUID, timeout time.Duration, handleFn UpdateHandlerFn) (int, error) {
+	topicID, err := uuid.NewV4()
+	if err != nil {
+		return 0, err
+	}
+	topic := topicID.String()
+
+	// A ToUpdateVersion of 0 requests everything up to the latest update.
+	reqAnyMsg, err := types.MarshalAny(&metadatapb.MissingK8SMetadataRequest{
+		FromUpdateVersion: 0,
+		ToUpdateVersion:   0,
+		CustomTopic:       topic,
+	})
+	if err != nil {
+		return 0, err
+	}
+	reqBytes, err := (&cvmsgspb.C2VMessage{
+		VizierID: vzID.String(),
+		Msg:      reqAnyMsg,
+	}).Marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	// Subscribe to topic that the response will be sent on.
+	subCh := make(chan *nats.Msg, 4096)
+	sub, err := nc.ChanSubscribe(vzshard.V2CTopic(fmt.Sprintf("%s:%s", metadataResponseTopic, topic), vzID), subCh)
+	if err != nil {
+		return 0, err
+	}
+	defer sub.Unsubscribe()
+
+	err = nc.Publish(vzshard.C2VTopic(metadataRequestTopic, vzID), reqBytes)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	for {
+		select {
+		case msg := <-subCh:
+			resp, err := readMetadataResponse(msg.Data)
+			if err != nil {
+				return count, err
+			}
+			if len(resp.Updates) == 0 {
+				return count, nil
+			}
+
+			for _, update := range resp.Updates {
+				err = handleFn(update)
+				if err != nil {
+					return count, err
+				}
+				count++
+			}
+
+			if resp.Updates[len(resp.Updates)-1].UpdateVersion == resp.LastUpdateAvailable {
+				return count, nil
+			}
+
+			// Timer resets should only be invoked on stopped/expired timers with drained channels.
+			if !t.Stop() {
+				<-t.C
+			}
+			t.Reset(timeout)
+		case <-t.C:
+			if count == 0 {
+				return count, errors.New("Timed out waiting for metadata from Vizier")
+			}
+			return count, fmt.Errorf("Timed out after receiving %d metadata updates from Vizier", count)
+		}
+	}
+}
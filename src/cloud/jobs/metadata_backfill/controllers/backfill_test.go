@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/jobs/metadata_backfill/controllers"
+	"px.dev/pixie/src/cloud/shared/vzshard"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/shared/k8s/metadatapb"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+func podUpdate(version int64, name string) *metadatapb.ResourceUpdate {
+	return &metadatapb.ResourceUpdate{
+		UpdateVersion: version,
+		Update: &metadatapb.ResourceUpdate_PodUpdate{
+			PodUpdate: &metadatapb.PodUpdate{
+				UID:       name,
+				Name:      name,
+				Namespace: "pl",
+			},
+		},
+	}
+}
+
+// fakeVizier responds to metadata requests with the given batches of updates.
+func fakeVizier(t *testing.T, nc *nats.Conn, vzID uuid.UUID, batches [][]*metadatapb.ResourceUpdate) {
+	_, err := nc.Subscribe(vzshard.C2VTopic("MetadataRequest", vzID), func(msg *nats.Msg) {
+		c2vMsg := &cvmsgspb.C2VMessage{}
+		require.NoError(t, c2vMsg.Unmarshal(msg.Data))
+		req := &metadatapb.MissingK8SMetadataRequest{}
+		require.NoError(t, types.UnmarshalAny(c2vMsg.Msg, req))
+		assert.Equal(t, int64(0), req.FromUpdateVersion)
+		assert.Equal(t, int64(0), req.ToUpdateVersion)
+
+		for _, batch := range batches {
+			resp := &metadatapb.MissingK8SMetadataResponse{
+				Updates:              batch,
+				FirstUpdateAvailable: batches[0][0].UpdateVersion,
+				LastUpdateAvailable:  batches[len(batches)-1][len(batches[len(batches)-1])-1].UpdateVersion,
+			}
+			anyMsg, err := types.MarshalAny(resp)
+			require.NoError(t, err)
+			b, err := (&cvmsgspb.V2CMessage{Msg: anyMsg}).Marshal()
+			require.NoError(t, err)
+			require.NoError(t, nc.Publish(vzshard.V2CTopic("MetadataResponse:"+req.CustomTopic, vzID), b))
+		}
+	})
+	require.NoError(t, err)
+}
+
+func TestFetchAllUpdates(t *testing.T) {
+	nc, cleanup := testingutils.MustStartTestNATS(t)
+	defer cleanup()
+
+	vzID := uuid.Must(uuid.NewV4())
+	fakeVizier(t, nc, vzID, [][]*metadatapb.ResourceUpdate{
+		{podUpdate(1, "a"), podUpdate(2, "b")},
+		{podUpdate(3, "c")},
+	})
+
+	versions := make([]int64, 0)
+	count, err := controllers.FetchAllUpdates(nc, vzID, 5*time.Second, func(u *metadatapb.ResourceUpdate) error {
+		versions = append(versions, u.UpdateVersion)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, []int64{1, 2, 3}, versions)
+}
+
+func TestFetchAllUpdates_Timeout(t *testing.T) {
+	nc, cleanup := testingutils.MustStartTestNATS(t)
+	defer cleanup()
+
+	// No Vizier is listening for the request.
+	count, err := controllers.FetchAllUpdates(nc, uuid.Must(uuid.NewV4()), 100*time.Millisecond, func(u *metadatapb.ResourceUpdate) error {
+		return nil
+	})
+	require.Error(t, err)
+	assert.Equal(t, 0, count)
+}
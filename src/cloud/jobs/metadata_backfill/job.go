@@ -0,0 +1,133 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/olivere/elastic/v7"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/cloud/indexer/schema"
+	"px.dev/pixie/src/cloud/jobs/metadata_backfill/controllers"
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/cloud/shared/esutils"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/services"
+	"px.dev/pixie/src/shared/services/msgbus"
+	"px.dev/pixie/src/shared/services/pg"
+	"px.dev/pixie/src/shared/services/utils"
+)
+
+func init() {
+	pflag.String("es_url", "https://pl-elastic-es-http:9200", "The URL for the elastic cluster.")
+	pflag.String("es_ca_cert", "/es-certs/tls.crt", "The CA cert for elastic.")
+	pflag.String("es_user", "elastic", "The user for elastic.")
+	pflag.String("es_passwd", "elastic", "The password for elastic.")
+	pflag.String("vzmgr_service", "kubernetes:///vzmgr-service.plc:51800", "The vzmgr service url (load balancer/list is ok)")
+	pflag.String("plugin_service", "plugin-service.plc.svc.cluster.local:50600", "The plugin service url (load balancer/list is ok)")
+	pflag.String("domain_name", "dev.withpixie.dev", "The domain name of Pixie Cloud")
+	pflag.String("from_shard", "00", "The first shard of Viziers to backfill.")
+	pflag.String("to_shard", "ff", "The last shard of Viziers to backfill.")
+	pflag.Duration("vizier_timeout", 2*time.Minute, "How long to wait for each Vizier to respond.")
+	pflag.String("search_backend", md.SearchBackendElastic, "The backend used to store metadata for search, either elastic or postgres.")
+}
+
+func mustDial(addr string) *grpc.ClientConn {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to get dial options")
+	}
+	conn, err := grpc.Dial(addr, dialOpts...)
+	if err != nil {
+		log.WithError(err).Fatalf("Failed to dial %s", addr)
+	}
+	return conn
+}
+
+func mustConnectElastic() *elastic.Client {
+	elasticURL := viper.GetString("es_url")
+	log.Infof("Connecting to elastic cluster at '%s'", elasticURL)
+	es, err := esutils.NewEsClient(&esutils.Config{
+		URL:        []string{elasticURL},
+		User:       viper.GetString("es_user"),
+		Passwd:     viper.GetString("es_passwd"),
+		CaCertFile: viper.GetString("es_ca_cert"),
+	})
+	if err != nil {
+		log.WithError(err).Fatalf("Failed to connect to %s", elasticURL)
+	}
+	err = md.InitializeMapping(es)
+	if err != nil {
+		log.WithError(err).Fatal("Could not initialize elastic mapping")
+	}
+	return es
+}
+
+func main() {
+	services.SetupSSLClientFlags()
+	services.PostFlagSetupAndParse()
+	services.CheckServiceFlags()
+	services.CheckSSLClientFlags()
+
+	var newBackend md.BackendFactory
+	switch backend := viper.GetString("search_backend"); backend {
+	case md.SearchBackendElastic:
+		newBackend = md.ElasticBackendFactory(mustConnectElastic())
+	case md.SearchBackendPostgres:
+		db := pg.MustConnectDefaultPostgresDB()
+		err := schema.PerformMigrations(db)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to apply migrations")
+		}
+		newBackend = md.PostgresBackendFactory(db)
+	default:
+		log.Fatalf("Unknown search backend: %s", backend)
+	}
+
+	nc := msgbus.MustConnectNATS()
+	defer nc.Close()
+
+	vzmgrClient := vzmgrpb.NewVZMgrServiceClient(mustDial(viper.GetString("vzmgr_service")))
+	pluginClient := pluginpb.NewDataRetentionPluginServiceClient(mustDial(viper.GetString("plugin_service")))
+
+	claims := utils.GenerateJWTForService("Metadata Backfill", viper.GetString("domain_name"))
+	serviceAuthToken, err := utils.SignJWTClaims(claims, viper.GetString("jwt_signing_key"))
+	if err != nil {
+		log.WithError(err).Fatal("Unable to sign JWT claims")
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization",
+		fmt.Sprintf("bearer %s", serviceAuthToken))
+
+	// Tracepoint tables aren't backfilled, since each Vizier periodically resends its tables.
+	b := controllers.NewBackfiller(nc, newBackend, vzmgrClient, pluginClient, viper.GetDuration("vizier_timeout"))
+	err = b.Run(ctx, viper.GetString("from_shard"), viper.GetString("to_shard"))
+	if err != nil {
+		log.WithError(err).Fatal("Backfill did not complete")
+	}
+
+	log.Info("Metadata successfully backfilled")
+}
@@ -291,7 +291,7 @@ func (s *Server) createPresetScripts(ctx context.Context, txn *sqlx.Tx, orgID uu
 			Description: j.Description,
 			IsPreset:    true,
 			ExportURL:   "",
-		}, j.Script, make([]*uuidpb.UUID, 0), j.DefaultFrequencyS)
+		}, j.Script, make([]*uuidpb.UUID, 0), "", "", j.DefaultFrequencyS)
 		if err != nil {
 			return status.Errorf(codes.Internal, "Failed to create preset scripts")
 		}
@@ -606,6 +606,8 @@ func (s *Server) GetRetentionScripts(ctx context.Context, req *pluginpb.GetReten
 			v.FrequencyS = c.FrequencyS
 			v.Enabled = c.Enabled
 			v.ClusterIDs = c.ClusterIDs
+			v.ClusterSelector = c.ClusterSelector
+			v.ClusterGroup = c.ClusterGroup
 		}
 	}
 
@@ -652,14 +654,16 @@ func (s *Server) GetRetentionScript(ctx context.Context, req *pluginpb.GetRetent
 	return &pluginpb.GetRetentionScriptResponse{
 		Script: &pluginpb.DetailedRetentionScript{
 			Script: &pluginpb.RetentionScript{
-				ScriptID:    req.ScriptID,
-				ScriptName:  script.ScriptName,
-				Description: script.Description,
-				FrequencyS:  cronScript.FrequencyS,
-				ClusterIDs:  cronScript.ClusterIDs,
-				PluginId:    script.PluginID,
-				Enabled:     cronScript.Enabled,
-				IsPreset:    script.IsPreset,
+				ScriptID:        req.ScriptID,
+				ScriptName:      script.ScriptName,
+				Description:     script.Description,
+				FrequencyS:      cronScript.FrequencyS,
+				ClusterIDs:      cronScript.ClusterIDs,
+				ClusterSelector: cronScript.ClusterSelector,
+				ClusterGroup:    cronScript.ClusterGroup,
+				PluginId:        script.PluginID,
+				Enabled:         cronScript.Enabled,
+				IsPreset:        script.IsPreset,
 			},
 			Contents:  cronScript.Script,
 			ExportURL: script.ExportURL,
@@ -667,7 +671,7 @@ func (s *Server) GetRetentionScript(ctx context.Context, req *pluginpb.GetRetent
 	}, nil
 }
 
-func (s *Server) createRetentionScript(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string, rs *RetentionScript, contents string, clusterIDs []*uuidpb.UUID, frequencyS int64) (*uuidpb.UUID, error) {
+func (s *Server) createRetentionScript(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string, rs *RetentionScript, contents string, clusterIDs []*uuidpb.UUID, clusterSelector string, clusterGroup string, frequencyS int64) (*uuidpb.UUID, error) {
 	pluginExportURL, configMap, err := s.getPluginConfigs(txn, orgID, pluginID)
 	if err != nil {
 		return nil, err
@@ -684,13 +688,15 @@ func (s *Server) createRetentionScript(ctx context.Context, txn *sqlx.Tx, orgID
 	}
 
 	cronScriptResp, err := s.cronScriptClient.CreateScript(ctx, &cronscriptpb.CreateScriptRequest{
-		Script:     contents,
-		ClusterIDs: clusterIDs,
-		Configs:    configYAML,
-		FrequencyS: frequencyS,
+		Script:          contents,
+		ClusterIDs:      clusterIDs,
+		ClusterSelector: clusterSelector,
+		ClusterGroup:    clusterGroup,
+		Configs:         configYAML,
+		FrequencyS:      frequencyS,
 	})
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Failed to create cron script")
+		return nil, cronScriptError(err, "Failed to create cron script")
 	}
 
 	scriptID := cronScriptResp.ID
@@ -722,7 +728,7 @@ func (s *Server) CreateRetentionScript(ctx context.Context, req *pluginpb.Create
 		Description: req.Script.Script.Description,
 		IsPreset:    req.Script.Script.IsPreset,
 		ExportURL:   req.Script.ExportURL,
-	}, req.Script.Contents, req.Script.Script.ClusterIDs, req.Script.Script.FrequencyS)
+	}, req.Script.Contents, req.Script.Script.ClusterIDs, req.Script.Script.ClusterSelector, req.Script.Script.ClusterGroup, req.Script.Script.FrequencyS)
 	if err != nil {
 		return nil, err
 	}
@@ -870,20 +876,33 @@ func (s *Server) UpdateRetentionScript(ctx context.Context, req *pluginpb.Update
 
 	// Update cron script.
 	_, err = s.cronScriptClient.UpdateScript(ctx, &cronscriptpb.UpdateScriptRequest{
-		Script:     req.Contents,
-		ClusterIDs: &cronscriptpb.ClusterIDs{Value: req.ClusterIDs},
-		Enabled:    req.Enabled,
-		FrequencyS: req.FrequencyS,
-		ScriptId:   req.ScriptID,
-		Configs:    &types.StringValue{Value: configYAML},
+		Script:          req.Contents,
+		ClusterIDs:      &cronscriptpb.ClusterIDs{Value: req.ClusterIDs},
+		ClusterSelector: req.ClusterSelector,
+		ClusterGroup:    req.ClusterGroup,
+		Enabled:         req.Enabled,
+		FrequencyS:      req.FrequencyS,
+		ScriptId:        req.ScriptID,
+		Configs:         &types.StringValue{Value: configYAML},
 	})
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Failed to update cron script")
+		return nil, cronScriptError(err, "Failed to update cron script")
 	}
 
 	return &pluginpb.UpdateRetentionScriptResponse{}, nil
 }
 
+// cronScriptError passes through cron script errors caused by the request, such as an invalid
+// cluster selector or an unknown cluster group, and hides any others behind msg.
+func cronScriptError(err error, msg string) error {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound:
+		return err
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
+
 // DeleteRetentionScript creates a script that is used for long-term data retention.
 func (s *Server) DeleteRetentionScript(ctx context.Context, req *pluginpb.DeleteRetentionScriptRequest) (*pluginpb.DeleteRetentionScriptResponse, error) {
 	txn, err := s.db.Beginx()
@@ -79,7 +79,7 @@ func testMain(m *testing.M) error {
 
 func createTestContext() context.Context {
 	sCtx := authcontext.New()
-	sCtx.Claims = srvutils.GenerateJWTForUser("abcdef", "223e4567-e89b-12d3-a456-426655440000", "test@test.com", time.Now(), "pixie")
+	sCtx.Claims = srvutils.GenerateJWTForUser("abcdef", "223e4567-e89b-12d3-a456-426655440000", "test@test.com", time.Now(), "pixie", nil)
 	return authcontext.NewContext(context.Background(), sCtx)
 }
 
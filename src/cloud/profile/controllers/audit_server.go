@@ -0,0 +1,169 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/profile/datastore"
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/utils"
+)
+
+const (
+	defaultAuditEventLimit = 100
+	maxAuditEventLimit     = 1000
+)
+
+// AuditDatastore is the interface used as the backing store for the org audit trail.
+type AuditDatastore interface {
+	// CreateAuditEvent appends an event to the audit trail.
+	CreateAuditEvent(*datastore.AuditEvent) (uuid.UUID, error)
+	// ListAuditEvents lists the audit events for an org, newest first.
+	ListAuditEvents(uuid.UUID, *datastore.AuditEventFilter) ([]*datastore.AuditEvent, error)
+	// DeleteAuditEventsBefore deletes all audit events older than the given time.
+	DeleteAuditEventsBefore(time.Time) (int64, error)
+}
+
+// AuditServer is an implementation of the GRPC audit service.
+type AuditServer struct {
+	ads AuditDatastore
+}
+
+// NewAuditServer creates a new GRPC audit server.
+func NewAuditServer(ads AuditDatastore) *AuditServer {
+	return &AuditServer{ads: ads}
+}
+
+func auditEventToProto(e *datastore.AuditEvent) (*profilepb.AuditEvent, error) {
+	ts, err := types.TimestampProto(e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	pb := &profilepb.AuditEvent{
+		ID:         utils.ProtoFromUUID(e.ID),
+		OrgID:      utils.ProtoFromUUID(e.OrgID),
+		ActorType:  e.ActorType,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Before:     e.Before,
+		After:      e.After,
+		SourceIP:   e.SourceIP,
+		Timestamp:  ts,
+	}
+	if e.ActorID != nil {
+		pb.ActorID = utils.ProtoFromUUID(*e.ActorID)
+	}
+	return pb, nil
+}
+
+// RecordAuditEvent appends an event to the org's audit trail.
+func (s *AuditServer) RecordAuditEvent(ctx context.Context, req *profilepb.AuditEvent) (*types.Empty, error) {
+	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
+	if orgID == uuid.Nil {
+		return nil, status.Error(codes.InvalidArgument, "org must be specified")
+	}
+	if req.Action == "" {
+		return nil, status.Error(codes.InvalidArgument, "action must be specified")
+	}
+
+	event := &datastore.AuditEvent{
+		OrgID:      orgID,
+		ActorType:  req.ActorType,
+		Action:     req.Action,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Before:     req.Before,
+		After:      req.After,
+		SourceIP:   req.SourceIP,
+	}
+	if actorID := utils.UUIDFromProtoOrNil(req.ActorID); actorID != uuid.Nil {
+		event.ActorID = &actorID
+	}
+
+	if _, err := s.ads.CreateAuditEvent(event); err != nil {
+		return nil, status.Error(codes.Internal, "failed to record audit event")
+	}
+	return &types.Empty{}, nil
+}
+
+// ListAuditEvents lists the audit events for an org, newest first.
+func (s *AuditServer) ListAuditEvents(ctx context.Context, req *profilepb.ListAuditEventsRequest) (*profilepb.ListAuditEventsResponse, error) {
+	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
+	if orgID == uuid.Nil {
+		return nil, status.Error(codes.InvalidArgument, "org must be specified")
+	}
+
+	filter := &datastore.AuditEventFilter{
+		ActorID:  utils.UUIDFromProtoOrNil(req.ActorID),
+		Action:   req.Action,
+		TargetID: req.TargetID,
+		Limit:    defaultAuditEventLimit,
+	}
+	if req.Limit > 0 {
+		filter.Limit = int(req.Limit)
+	}
+	if filter.Limit > maxAuditEventLimit {
+		filter.Limit = maxAuditEventLimit
+	}
+	if req.Before != nil {
+		before, err := types.TimestampFromProto(req.Before)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid before timestamp")
+		}
+		filter.Before = before
+	}
+
+	events, err := s.ads.ListAuditEvents(orgID, filter)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list audit events")
+	}
+
+	resp := &profilepb.ListAuditEventsResponse{
+		Events: make([]*profilepb.AuditEvent, len(events)),
+	}
+	for i, e := range events {
+		pb, err := auditEventToProto(e)
+		if err != nil {
+			return nil, err
+		}
+		resp.Events[i] = pb
+	}
+	return resp, nil
+}
+
+// DeleteExpiredEvents enforces the audit retention policy by deleting all events older than the retention period.
+func (s *AuditServer) DeleteExpiredEvents(retention time.Duration) error {
+	n, err := s.ads.DeleteAuditEventsBefore(time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.WithField("count", n).Info("Deleted expired audit events")
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/profile/controllers"
+	mock_controllers "px.dev/pixie/src/cloud/profile/controllers/mock"
+	"px.dev/pixie/src/cloud/profile/datastore"
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/utils"
+)
+
+func TestAuditServer_RecordAuditEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ads := mock_controllers.NewMockAuditDatastore(ctrl)
+	s := controllers.NewAuditServer(ads)
+
+	orgID := uuid.Must(uuid.NewV4())
+	actorID := uuid.Must(uuid.NewV4())
+
+	ads.EXPECT().CreateAuditEvent(&datastore.AuditEvent{
+		OrgID:      orgID,
+		ActorID:    &actorID,
+		ActorType:  "user",
+		Action:     "deploy_key.delete",
+		TargetType: "deploy_key",
+		TargetID:   "key1",
+		Before:     "desc: test",
+		SourceIP:   "10.0.0.1",
+	}).Return(uuid.Must(uuid.NewV4()), nil)
+
+	_, err := s.RecordAuditEvent(context.Background(), &profilepb.AuditEvent{
+		OrgID:      utils.ProtoFromUUID(orgID),
+		ActorID:    utils.ProtoFromUUID(actorID),
+		ActorType:  "user",
+		Action:     "deploy_key.delete",
+		TargetType: "deploy_key",
+		TargetID:   "key1",
+		Before:     "desc: test",
+		SourceIP:   "10.0.0.1",
+	})
+	require.NoError(t, err)
+}
+
+func TestAuditServer_RecordAuditEvent_MissingOrg(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ads := mock_controllers.NewMockAuditDatastore(ctrl)
+	s := controllers.NewAuditServer(ads)
+
+	_, err := s.RecordAuditEvent(context.Background(), &profilepb.AuditEvent{
+		Action: "deploy_key.delete",
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAuditServer_ListAuditEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ads := mock_controllers.NewMockAuditDatastore(ctrl)
+	s := controllers.NewAuditServer(ads)
+
+	orgID := uuid.Must(uuid.NewV4())
+	eventID := uuid.Must(uuid.NewV4())
+	before := time.Unix(1700000000, 0).UTC()
+	createdAt := before.Add(-time.Minute)
+
+	ads.EXPECT().ListAuditEvents(orgID, &datastore.AuditEventFilter{
+		Action: "user.remove",
+		Before: before,
+		Limit:  1000,
+	}).Return([]*datastore.AuditEvent{
+		{
+			ID:         eventID,
+			OrgID:      orgID,
+			ActorType:  "api_key",
+			Action:     "user.remove",
+			TargetType: "user",
+			TargetID:   "abc",
+			CreatedAt:  createdAt,
+		},
+	}, nil)
+
+	beforePb, err := types.TimestampProto(before)
+	require.NoError(t, err)
+	resp, err := s.ListAuditEvents(context.Background(), &profilepb.ListAuditEventsRequest{
+		OrgID:  utils.ProtoFromUUID(orgID),
+		Action: "user.remove",
+		Before: beforePb,
+		Limit:  5000,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(resp.Events))
+	assert.Equal(t, utils.ProtoFromUUID(eventID), resp.Events[0].ID)
+	assert.Nil(t, resp.Events[0].ActorID)
+	assert.Equal(t, "api_key", resp.Events[0].ActorType)
+	ts, err := types.TimestampFromProto(resp.Events[0].Timestamp)
+	require.NoError(t, err)
+	assert.Equal(t, createdAt, ts)
+}
+
+func TestAuditServer_DeleteExpiredEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ads := mock_controllers.NewMockAuditDatastore(ctrl)
+	s := controllers.NewAuditServer(ads)
+
+	ads.EXPECT().DeleteAuditEventsBefore(gomock.Any()).DoAndReturn(func(t2 time.Time) (int64, error) {
+		assert.WithinDuration(t, time.Now().Add(-24*time.Hour), t2, time.Minute)
+		return 3, nil
+	})
+	require.NoError(t, s.DeleteExpiredEvents(24*time.Hour))
+}
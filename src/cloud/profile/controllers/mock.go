@@ -19,3 +19,4 @@
 package controllers
 
 //go:generate mockgen -source=server.go -destination=mock/datastore_mock.gen.go Datastore
+//go:generate mockgen -source=audit_server.go -destination=mock/audit_datastore_mock.gen.go AuditDatastore
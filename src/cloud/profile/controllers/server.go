@@ -40,6 +40,7 @@ import (
 	"px.dev/pixie/src/cloud/profile/profileenv"
 	"px.dev/pixie/src/cloud/profile/profilepb"
 	"px.dev/pixie/src/cloud/project_manager/projectmanagerpb"
+	"px.dev/pixie/src/cloud/shared/rbac"
 	"px.dev/pixie/src/shared/services/authcontext"
 	claimsutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
@@ -66,6 +67,10 @@ type UserDatastore interface {
 	CreateUserAndOrg(*datastore.OrgInfo, *datastore.UserInfo) (orgID uuid.UUID, userID uuid.UUID, err error)
 	// UpdateUser updates the user info.
 	UpdateUser(*datastore.UserInfo) error
+	// GetUserClusterRoles gets the per-cluster roles granted to the user.
+	GetUserClusterRoles(uuid.UUID) ([]*datastore.ClusterRole, error)
+	// SetUserClusterRole grants the user a role on a cluster, or removes the grant if the role is empty.
+	SetUserClusterRole(userID uuid.UUID, clusterID uuid.UUID, role claimsutils.OrgRole) error
 }
 
 // OrgDatastore is the interface used as the backing store for org information.
@@ -154,6 +159,7 @@ func userInfoToProto(u *datastore.UserInfo) *profilepb.UserInfo {
 		IsApproved:       u.IsApproved,
 		IdentityProvider: u.IdentityProvider,
 		AuthProviderID:   u.AuthProviderID,
+		Role:             rbac.RoleToProto(u.Role),
 	}
 }
 
@@ -237,7 +243,18 @@ func (s *Server) GetUser(ctx context.Context, req *uuidpb.UUID) (*profilepb.User
 	if userInfo == nil {
 		return nil, status.Error(codes.NotFound, "no such user")
 	}
-	return userInfoToProto(userInfo), nil
+	clusterRoles, err := s.uds.GetUserClusterRoles(uid)
+	if err != nil {
+		return nil, err
+	}
+	resp := userInfoToProto(userInfo)
+	for _, cr := range clusterRoles {
+		resp.ClusterRoles = append(resp.ClusterRoles, &profilepb.ClusterRole{
+			ClusterID: utils.ProtoFromUUID(cr.ClusterID),
+			Role:      rbac.RoleToProto(cr.Role),
+		})
+	}
+	return resp, nil
 }
 
 // GetUserByEmail is the GRPC method to get a user by email.
@@ -406,6 +423,10 @@ func (s *Server) UpdateUser(ctx context.Context, req *profilepb.UpdateUserReques
 
 	if req.OrgID != nil {
 		newOrgID := utils.UUIDFromProtoOrNil(req.OrgID)
+		if userInfo.OrgID == nil || *userInfo.OrgID != newOrgID {
+			// Roles don't carry over between orgs.
+			userInfo.Role = claimsutils.MemberRole
+		}
 		if newOrgID == uuid.Nil {
 			userInfo.OrgID = nil
 		} else {
@@ -413,6 +434,10 @@ func (s *Server) UpdateUser(ctx context.Context, req *profilepb.UpdateUserReques
 		}
 	}
 
+	if req.Role != profilepb.OR_UNKNOWN {
+		userInfo.Role = rbac.RoleFromProto(req.Role)
+	}
+
 	if req.DisplayPicture != nil {
 		userInfo.ProfilePicture = &req.DisplayPicture.Value
 	}
@@ -429,6 +454,22 @@ func (s *Server) UpdateUser(ctx context.Context, req *profilepb.UpdateUserReques
 	return userInfoToProto(userInfo), nil
 }
 
+// SetUserClusterRole grants the user a role on a single cluster, or removes the grant if no role is specified.
+func (s *Server) SetUserClusterRole(ctx context.Context, req *profilepb.SetUserClusterRoleRequest) (*types.Empty, error) {
+	userID := utils.UUIDFromProtoOrNil(req.UserID)
+	clusterID := utils.UUIDFromProtoOrNil(req.ClusterID)
+	if userID == uuid.Nil || clusterID == uuid.Nil {
+		return nil, status.Error(codes.InvalidArgument, "user and cluster must be specified")
+	}
+	if _, err := s.uds.GetUser(userID); err != nil {
+		return nil, toExternalError(err)
+	}
+	if err := s.uds.SetUserClusterRole(userID, clusterID, rbac.RoleFromProto(req.Role)); err != nil {
+		return nil, status.Error(codes.Internal, "failed to set cluster role")
+	}
+	return &types.Empty{}, nil
+}
+
 // GetUserSettings gets the user settings for the given user.
 func (s *Server) GetUserSettings(ctx context.Context, req *profilepb.GetUserSettingsRequest) (*profilepb.GetUserSettingsResponse, error) {
 	userID := utils.UUIDFromProtoOrNil(req.ID)
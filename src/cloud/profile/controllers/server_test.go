@@ -42,6 +42,7 @@ import (
 	"px.dev/pixie/src/cloud/profile/profilepb"
 	"px.dev/pixie/src/cloud/project_manager/projectmanagerpb"
 	mock_projectmanager "px.dev/pixie/src/cloud/project_manager/projectmanagerpb/mock"
+	"px.dev/pixie/src/cloud/shared/rbac"
 	"px.dev/pixie/src/shared/services/authcontext"
 	svcutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
@@ -338,12 +339,20 @@ func TestServer_GetUser(t *testing.T) {
 		LastName:       "bar",
 		Email:          "foo@bar.com",
 		AuthProviderID: "github|asdfghjkl;",
+		Role:           svcutils.AdminRole,
 	}
+	clusterUUID := uuid.Must(uuid.NewV4())
 
 	uds.EXPECT().
 		GetUser(userUUID).
 		Return(mockReply, nil)
 
+	uds.EXPECT().
+		GetUserClusterRoles(userUUID).
+		Return([]*datastore.ClusterRole{
+			{UserID: userUUID, ClusterID: clusterUUID, Role: svcutils.OwnerRole},
+		}, nil)
+
 	resp, err := s.GetUser(context.Background(), utils.ProtoFromUUID(userUUID))
 
 	require.NoError(t, err)
@@ -353,6 +362,10 @@ func TestServer_GetUser(t *testing.T) {
 	assert.Equal(t, resp.LastName, "bar")
 	assert.Equal(t, resp.Email, "foo@bar.com")
 	assert.Equal(t, resp.AuthProviderID, "github|asdfghjkl;")
+	assert.Equal(t, profilepb.OR_ADMIN, resp.Role)
+	assert.Equal(t, []*profilepb.ClusterRole{
+		{ClusterID: utils.ProtoFromUUID(clusterUUID), Role: profilepb.OR_OWNER},
+	}, resp.ClusterRoles)
 }
 
 func TestServer_GetUser_MissingUser(t *testing.T) {
@@ -1011,6 +1024,7 @@ func TestServer_UpdateUser(t *testing.T) {
 		updatedProfilePic string
 		updatedIsApproved bool
 		updatedOrg        string
+		updatedRole       profilepb.OrgRole
 	}{
 		{
 			name:              "user can update their own profile picture",
@@ -1051,6 +1065,20 @@ func TestServer_UpdateUser(t *testing.T) {
 			userOrg:    "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
 			updatedOrg: "6ba7b810-9dad-11d1-80b4-00c04fd430c9",
 		},
+		{
+			name:              "user role can be updated",
+			userID:            "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			userOrg:           "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			updatedProfilePic: "something",
+			updatedRole:       profilepb.OR_VIEWER,
+		},
+		{
+			name:        "user joining an org can be given a role",
+			userID:      "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			userOrg:     "00000000-0000-0000-0000-000000000000",
+			updatedOrg:  "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			updatedRole: profilepb.OR_OWNER,
+		},
 	}
 
 	for _, tc := range updateUserTest {
@@ -1069,6 +1097,7 @@ func TestServer_UpdateUser(t *testing.T) {
 				ProfilePicture: &profilePicture,
 				IsApproved:     false,
 				OrgID:          &orgID,
+				Role:           svcutils.AdminRole,
 			}
 
 			req := &profilepb.UpdateUserRequest{
@@ -1082,6 +1111,7 @@ func TestServer_UpdateUser(t *testing.T) {
 				ProfilePicture: &profilePicture,
 				IsApproved:     false,
 				OrgID:          &orgID,
+				Role:           svcutils.AdminRole,
 			}
 
 			if tc.updatedProfilePic != profilePicture {
@@ -1101,6 +1131,14 @@ func TestServer_UpdateUser(t *testing.T) {
 				if newOrgID == uuid.Nil {
 					mockUpdateReq.OrgID = nil
 				}
+				if newOrgID != orgID {
+					mockUpdateReq.Role = svcutils.MemberRole
+				}
+			}
+
+			if tc.updatedRole != profilepb.OR_UNKNOWN {
+				req.Role = tc.updatedRole
+				mockUpdateReq.Role = rbac.RoleFromProto(tc.updatedRole)
 			}
 
 			uds.EXPECT().
@@ -1123,6 +1161,41 @@ func TestServer_UpdateUser(t *testing.T) {
 	}
 }
 
+func TestServer_SetUserClusterRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uds := mock_controllers.NewMockUserDatastore(ctrl)
+	ods := mock_controllers.NewMockOrgDatastore(ctrl)
+	usds := mock_controllers.NewMockUserSettingsDatastore(ctrl)
+	osds := mock_controllers.NewMockOrgSettingsDatastore(ctrl)
+
+	userID := uuid.Must(uuid.NewV4())
+	clusterID := uuid.Must(uuid.NewV4())
+	s := controllers.NewServer(nil, uds, usds, ods, osds)
+
+	uds.EXPECT().
+		GetUser(userID).
+		Return(&datastore.UserInfo{ID: userID}, nil)
+
+	uds.EXPECT().
+		SetUserClusterRole(userID, clusterID, svcutils.AdminRole).
+		Return(nil)
+
+	_, err := s.SetUserClusterRole(CreateTestContext(), &profilepb.SetUserClusterRoleRequest{
+		UserID:    utils.ProtoFromUUID(userID),
+		ClusterID: utils.ProtoFromUUID(clusterID),
+		Role:      profilepb.OR_ADMIN,
+	})
+	require.NoError(t, err)
+
+	_, err = s.SetUserClusterRole(CreateTestContext(), &profilepb.SetUserClusterRoleRequest{
+		UserID: utils.ProtoFromUUID(userID),
+		Role:   profilepb.OR_ADMIN,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
 func TestServer_UpdateOrg_EnableApprovals(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -1568,6 +1641,7 @@ func CreateTestContext() context.Context {
 		"test@test.com",
 		time.Now(),
 		"pixie",
+		nil,
 	)
 	return authcontext.NewContext(context.Background(), sCtx)
 }
@@ -23,10 +23,13 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx"
 	"github.com/jmoiron/sqlx"
+
+	srvutils "px.dev/pixie/src/shared/services/utils"
 )
 
 const (
@@ -51,6 +54,15 @@ type UserInfo struct {
 	IsApproved       bool       `db:"is_approved"`
 	IdentityProvider string     `db:"identity_provider"`
 	AuthProviderID   string     `db:"auth_provider_id"`
+	// Role is the role of the user in their org. Defaults to member when empty on creation.
+	Role srvutils.OrgRole `db:"role"`
+}
+
+// ClusterRole is a role granted to a user on a single cluster.
+type ClusterRole struct {
+	UserID    uuid.UUID        `db:"user_id"`
+	ClusterID uuid.UUID        `db:"cluster_id"`
+	Role      srvutils.OrgRole `db:"role"`
 }
 
 // OrgInfo tracks information about an organization.
@@ -126,7 +138,7 @@ func (d *Datastore) CreateUser(userInfo *UserInfo) (uuid.UUID, error) {
 
 // GetUser gets user information by user ID.
 func (d *Datastore) GetUser(id uuid.UUID) (*UserInfo, error) {
-	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id FROM users WHERE id=$1`
+	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id, role FROM users WHERE id=$1`
 	rows, err := d.db.Queryx(query, id)
 	if err != nil {
 		return nil, err
@@ -170,6 +182,8 @@ func (d *Datastore) CreateUserAndOrg(orgInfo *OrgInfo, userInfo *UserInfo) (uuid
 		return uuid.Nil, uuid.Nil, err
 	}
 	userInfo.OrgID = &orgID
+	// The user creating the org is its owner.
+	userInfo.Role = srvutils.OwnerRole
 	userID, err := d.createUserUsingTxn(txn, userInfo)
 	if err != nil {
 		return uuid.Nil, uuid.Nil, err
@@ -315,7 +329,7 @@ func (d *Datastore) CreateInviteSigningKey(id uuid.UUID) (string, error) {
 
 // GetUserByEmail gets user info by email.
 func (d *Datastore) GetUserByEmail(email string) (*UserInfo, error) {
-	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id FROM users WHERE email=$1`
+	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id, role FROM users WHERE email=$1`
 	rows, err := d.db.Queryx(query, email)
 	if err != nil {
 		return nil, err
@@ -332,7 +346,7 @@ func (d *Datastore) GetUserByEmail(email string) (*UserInfo, error) {
 
 // GetUserByAuthProviderID gets userinfo by auth provider id.
 func (d *Datastore) GetUserByAuthProviderID(id string) (*UserInfo, error) {
-	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id FROM users WHERE auth_provider_id=$1`
+	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id, role FROM users WHERE auth_provider_id=$1`
 	rows, err := d.db.Queryx(query, id)
 	if err != nil {
 		return nil, err
@@ -369,7 +383,10 @@ func (d *Datastore) DeleteOrgAndUsers(orgID uuid.UUID) error {
 }
 
 func (d *Datastore) createUserUsingTxn(txn *sqlx.Tx, userInfo *UserInfo) (uuid.UUID, error) {
-	query := `INSERT INTO users (org_id, first_name, last_name, email, is_approved, identity_provider, auth_provider_id) VALUES (:org_id, :first_name, :last_name, :email, :is_approved, :identity_provider, :auth_provider_id) RETURNING id`
+	if userInfo.Role == srvutils.NoRole {
+		userInfo.Role = srvutils.MemberRole
+	}
+	query := `INSERT INTO users (org_id, first_name, last_name, email, is_approved, identity_provider, auth_provider_id, role) VALUES (:org_id, :first_name, :last_name, :email, :is_approved, :identity_provider, :auth_provider_id, :role) RETURNING id`
 	rows, err := txn.NamedQuery(query, userInfo)
 	if err != nil {
 		return uuid.Nil, err
@@ -420,7 +437,7 @@ func (d *Datastore) createOrgUsingTxn(txn *sqlx.Tx, orgInfo *OrgInfo) (uuid.UUID
 
 // GetUsersInOrg gets all users in the given org.
 func (d *Datastore) GetUsersInOrg(orgID uuid.UUID) ([]*UserInfo, error) {
-	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id FROM users WHERE org_id=$1 order by created_at desc`
+	query := `SELECT id, org_id, first_name, last_name, email, profile_picture, is_approved, identity_provider, auth_provider_id, role FROM users WHERE org_id=$1 order by created_at desc`
 	rows, err := d.db.Queryx(query, orgID)
 	if err != nil {
 		return nil, err
@@ -461,11 +478,46 @@ func (d *Datastore) NumUsersInOrg(orgID uuid.UUID) (int, error) {
 
 // UpdateUser updates the user in the database.
 func (d *Datastore) UpdateUser(userInfo *UserInfo) error {
-	query := `UPDATE users SET profile_picture = :profile_picture, is_approved = :is_approved, org_id = :org_id WHERE id = :id`
+	query := `UPDATE users SET profile_picture = :profile_picture, is_approved = :is_approved, org_id = :org_id, role = :role WHERE id = :id`
 	_, err := d.db.NamedExec(query, userInfo)
 	return err
 }
 
+// GetUserClusterRoles gets the per-cluster roles granted to the given user.
+func (d *Datastore) GetUserClusterRoles(userID uuid.UUID) ([]*ClusterRole, error) {
+	query := `SELECT user_id, cluster_id, role FROM user_cluster_roles WHERE user_id=$1`
+	rows, err := d.db.Queryx(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make([]*ClusterRole, 0)
+	for rows.Next() {
+		var role ClusterRole
+		err := rows.StructScan(&role)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, &role)
+	}
+	return roles, nil
+}
+
+// SetUserClusterRole grants the user a role on the given cluster, replacing any existing grant.
+// The grant is removed if the role is empty.
+func (d *Datastore) SetUserClusterRole(userID uuid.UUID, clusterID uuid.UUID, role srvutils.OrgRole) error {
+	if role == srvutils.NoRole {
+		query := `DELETE FROM user_cluster_roles WHERE user_id=$1 AND cluster_id=$2`
+		_, err := d.db.Exec(query, userID, clusterID)
+		return err
+	}
+	query := `INSERT INTO user_cluster_roles (user_id, cluster_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, cluster_id) DO UPDATE SET role = EXCLUDED.role`
+	_, err := d.db.Exec(query, userID, clusterID, role)
+	return err
+}
+
 // UpdateOrg updates the org in the database.
 func (d *Datastore) UpdateOrg(orgInfo *OrgInfo) error {
 	query := `UPDATE orgs SET enable_approvals = :enable_approvals, domain_name = :domain_name WHERE id = :id`
@@ -688,3 +740,97 @@ func (d *Datastore) GetIDEConfig(orgID uuid.UUID, name string) (*IDEConfig, erro
 	}
 	return nil, errors.New("failed to get IDE config for IDE with given name")
 }
+
+// AuditEvent is a single control-plane action taken in an org.
+type AuditEvent struct {
+	ID         uuid.UUID  `db:"id"`
+	OrgID      uuid.UUID  `db:"org_id"`
+	ActorID    *uuid.UUID `db:"actor_id"`
+	ActorType  string     `db:"actor_type"`
+	Action     string     `db:"action"`
+	TargetType string     `db:"target_type"`
+	TargetID   string     `db:"target_id"`
+	Before     string     `db:"before_summary"`
+	After      string     `db:"after_summary"`
+	SourceIP   string     `db:"source_ip"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+// AuditEventFilter restricts the audit events returned by ListAuditEvents. Empty fields match all events.
+type AuditEventFilter struct {
+	ActorID  uuid.UUID
+	Action   string
+	TargetID string
+	// Before only matches events created strictly before this time.
+	Before time.Time
+	Limit  int
+}
+
+// CreateAuditEvent appends an event to the audit trail. The ID and creation time are assigned by the datastore.
+func (d *Datastore) CreateAuditEvent(event *AuditEvent) (uuid.UUID, error) {
+	query := `INSERT INTO audit_events (org_id, actor_id, actor_type, action, target_type, target_id, before_summary, after_summary, source_ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+	var id uuid.UUID
+	err := d.db.QueryRowx(query, event.OrgID, event.ActorID, event.ActorType, event.Action, event.TargetType,
+		event.TargetID, event.Before, event.After, event.SourceIP).Scan(&id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// ListAuditEvents returns the audit events for the org matching the filter, newest first.
+func (d *Datastore) ListAuditEvents(orgID uuid.UUID, filter *AuditEventFilter) ([]*AuditEvent, error) {
+	conds := []string{"org_id=$1"}
+	args := []interface{}{orgID}
+	addCond := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.ActorID != uuid.Nil {
+		addCond("actor_id=$%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		addCond("action=$%d", filter.Action)
+	}
+	if filter.TargetID != "" {
+		addCond("target_id=$%d", filter.TargetID)
+	}
+	if !filter.Before.IsZero() {
+		addCond("created_at<$%d", filter.Before)
+	}
+
+	query := `SELECT id, org_id, actor_id, actor_type, action, target_type, target_id, before_summary, after_summary, source_ip, created_at
+		FROM audit_events WHERE ` + strings.Join(conds, " AND ") + ` ORDER BY created_at DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := d.db.Queryx(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]*AuditEvent, 0)
+	for rows.Next() {
+		var event AuditEvent
+		err := rows.StructScan(&event)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+// DeleteAuditEventsBefore deletes all audit events created before the given time, and returns the number deleted.
+func (d *Datastore) DeleteAuditEventsBefore(t time.Time) (int64, error) {
+	query := `DELETE FROM audit_events WHERE created_at<$1`
+	res, err := d.db.Exec(query, t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	bindata "github.com/golang-migrate/migrate/source/go_bindata"
@@ -32,6 +33,7 @@ import (
 	"px.dev/pixie/src/cloud/profile/datastore"
 	"px.dev/pixie/src/cloud/profile/schema"
 	"px.dev/pixie/src/shared/services/pgtest"
+	srvutils "px.dev/pixie/src/shared/services/utils"
 )
 
 func TestMain(m *testing.M) {
@@ -64,8 +66,10 @@ func testMain(m *testing.M) error {
 func mustLoadTestData(db *sqlx.DB) {
 	// Cleanup.
 	db.MustExec(`DELETE FROM org_ide_configs`)
+	db.MustExec(`DELETE FROM audit_events`)
 	db.MustExec(`DELETE FROM user_attributes`)
 	db.MustExec(`DELETE FROM user_settings`)
+	db.MustExec(`DELETE FROM user_cluster_roles`)
 	db.MustExec(`DELETE FROM users`)
 	db.MustExec(`DELETE FROM orgs`)
 
@@ -112,6 +116,7 @@ func TestDatastore(t *testing.T) {
 		assert.Equal(t, userInfo.LastName, userInfoFetched.LastName)
 		assert.Equal(t, userInfo.Email, userInfoFetched.Email)
 		assert.Equal(t, userInfo.AuthProviderID, userInfoFetched.AuthProviderID)
+		assert.Equal(t, srvutils.MemberRole, userInfoFetched.Role)
 
 		// Check value in DB.
 		query := `SELECT * from user_attributes WHERE user_id=$1`
@@ -259,6 +264,7 @@ func TestDatastore(t *testing.T) {
 		userInfoFetched, err := d.GetUser(userID)
 		require.NoError(t, err)
 		assert.Equal(t, userInfo.AuthProviderID, userInfoFetched.AuthProviderID)
+		assert.Equal(t, srvutils.OwnerRole, userInfoFetched.Role)
 
 		// Check value in DB.
 		query := `SELECT * from user_attributes WHERE user_id=$1`
@@ -486,7 +492,7 @@ func TestDatastore(t *testing.T) {
 		userID := "123e4567-e89b-12d3-a456-426655440001"
 		profilePicture := "http://somepicture"
 		// Original should be IsApproved -> true.
-		err := d.UpdateUser(&datastore.UserInfo{ID: uuid.FromStringOrNil(userID), FirstName: "first", LastName: "last", ProfilePicture: &profilePicture, IsApproved: false})
+		err := d.UpdateUser(&datastore.UserInfo{ID: uuid.FromStringOrNil(userID), FirstName: "first", LastName: "last", ProfilePicture: &profilePicture, IsApproved: false, Role: srvutils.AdminRole})
 		require.NoError(t, err)
 
 		userInfoFetched, err := d.GetUser(uuid.FromStringOrNil(userID))
@@ -494,6 +500,7 @@ func TestDatastore(t *testing.T) {
 		require.NotNil(t, userInfoFetched)
 		assert.Equal(t, "http://somepicture", *userInfoFetched.ProfilePicture)
 		assert.Equal(t, false, userInfoFetched.IsApproved)
+		assert.Equal(t, srvutils.AdminRole, userInfoFetched.Role)
 	})
 
 	t.Run("update user org", func(t *testing.T) {
@@ -712,4 +719,66 @@ func TestDatastore(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, 2, len(ideConfigs))
 	})
+	t.Run("set and get user cluster roles", func(t *testing.T) {
+		mustLoadTestData(db)
+		d := datastore.NewDatastore(db, "test_key")
+		userID := uuid.FromStringOrNil("123e4567-e89b-12d3-a456-426655440001")
+		clusterID := uuid.FromStringOrNil("223e4567-e89b-12d3-a456-426655440000")
+
+		roles, err := d.GetUserClusterRoles(userID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, len(roles))
+
+		require.NoError(t, d.SetUserClusterRole(userID, clusterID, srvutils.MemberRole))
+		require.NoError(t, d.SetUserClusterRole(userID, clusterID, srvutils.AdminRole))
+		roles, err = d.GetUserClusterRoles(userID)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(roles))
+		assert.Equal(t, clusterID, roles[0].ClusterID)
+		assert.Equal(t, srvutils.AdminRole, roles[0].Role)
+
+		require.NoError(t, d.SetUserClusterRole(userID, clusterID, srvutils.NoRole))
+		roles, err = d.GetUserClusterRoles(userID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, len(roles))
+	})
+	t.Run("create, list and expire audit events", func(t *testing.T) {
+		mustLoadTestData(db)
+		d := datastore.NewDatastore(db, "test_key")
+		orgID := uuid.FromStringOrNil("123e4567-e89b-12d3-a456-426655440000")
+		userID := uuid.FromStringOrNil("123e4567-e89b-12d3-a456-426655440001")
+
+		events := []*datastore.AuditEvent{
+			{OrgID: orgID, ActorID: &userID, ActorType: "user", Action: "deploy_key.create", TargetType: "deploy_key", TargetID: "key1"},
+			{OrgID: orgID, ActorID: &userID, ActorType: "user", Action: "deploy_key.delete", TargetType: "deploy_key", TargetID: "key1", Before: "desc: test"},
+			{OrgID: uuid.Must(uuid.NewV4()), ActorType: "user", Action: "deploy_key.delete", TargetType: "deploy_key", TargetID: "key2"},
+		}
+		for _, e := range events {
+			id, err := d.CreateAuditEvent(e)
+			require.NoError(t, err)
+			assert.NotEqual(t, uuid.Nil, id)
+		}
+
+		resp, err := d.ListAuditEvents(orgID, &datastore.AuditEventFilter{})
+		require.NoError(t, err)
+		require.Equal(t, 2, len(resp))
+		assert.Equal(t, "deploy_key.delete", resp[0].Action)
+		assert.Equal(t, "desc: test", resp[0].Before)
+		assert.Equal(t, userID, *resp[0].ActorID)
+
+		resp, err = d.ListAuditEvents(orgID, &datastore.AuditEventFilter{Action: "deploy_key.create"})
+		require.NoError(t, err)
+		require.Equal(t, 1, len(resp))
+
+		resp, err = d.ListAuditEvents(orgID, &datastore.AuditEventFilter{Limit: 1})
+		require.NoError(t, err)
+		require.Equal(t, 1, len(resp))
+
+		_, err = db.Exec(`UPDATE audit_events SET action='tampered'`)
+		require.Error(t, err)
+
+		n, err := d.DeleteAuditEventsBefore(time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+	})
 }
@@ -21,9 +21,11 @@ package main
 import (
 	"net/http"
 	_ "net/http/pprof"
+	"time"
 
 	bindata "github.com/golang-migrate/migrate/source/go_bindata"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
 	"px.dev/pixie/src/cloud/profile/controllers"
@@ -38,6 +40,10 @@ import (
 	"px.dev/pixie/src/shared/services/server"
 )
 
+func init() {
+	pflag.Duration("audit_retention", 90*24*time.Hour, "How long to keep org audit events before deleting them")
+}
+
 func main() {
 	services.SetupService("profile-service", 51500)
 	services.PostFlagSetupAndParse()
@@ -72,6 +78,25 @@ func main() {
 
 	svr := controllers.NewServer(env, datastore, datastore, datastore, datastore)
 
+	as := controllers.NewAuditServer(datastore)
+	quitCh := make(chan bool)
+	go func() {
+		retention := viper.GetDuration("audit_retention")
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			if err := as.DeleteExpiredEvents(retention); err != nil {
+				log.WithError(err).Error("Failed to delete expired audit events")
+			}
+			select {
+			case <-quitCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	defer close(quitCh)
+
 	serverOpts := &server.GRPCServerOptions{
 		DisableAuth: map[string]bool{
 			"/px.services.OrgService/VerifyInviteToken": true,
@@ -80,6 +105,7 @@ func main() {
 	s := server.NewPLServerWithOptions(env, mux, serverOpts)
 	profilepb.RegisterProfileServiceServer(s.GRPCServer(), svr)
 	profilepb.RegisterOrgServiceServer(s.GRPCServer(), svr)
+	profilepb.RegisterAuditServiceServer(s.GRPCServer(), as)
 	s.Start()
 	s.StopOnInterrupt()
 }
@@ -0,0 +1,3 @@
+DROP TABLE user_cluster_roles;
+
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN role varchar(20) NOT NULL DEFAULT 'member';
+
+-- Existing members could previously manage their whole org, so keep them as admins
+-- and make the earliest user of each org its owner.
+UPDATE users SET role = 'admin' WHERE org_id IS NOT NULL;
+UPDATE users SET role = 'owner' WHERE id IN (
+  SELECT DISTINCT ON (org_id) id FROM users WHERE org_id IS NOT NULL ORDER BY org_id, created_at ASC
+);
+
+CREATE TABLE user_cluster_roles (
+  user_id UUID NOT NULL,
+  cluster_id UUID NOT NULL,
+  role varchar(20) NOT NULL,
+
+  PRIMARY KEY(user_id, cluster_id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS prevent_audit_events_update ON audit_events;
+DROP FUNCTION IF EXISTS prevent_audit_event_update;
+DROP TABLE audit_events;
@@ -0,0 +1,27 @@
+CREATE TABLE audit_events (
+  id UUID UNIQUE DEFAULT uuid_generate_v4(),
+  org_id UUID NOT NULL,
+  actor_id UUID,
+  actor_type varchar(20) NOT NULL,
+  action varchar(100) NOT NULL,
+  target_type varchar(100) NOT NULL,
+  target_id varchar(1024) NOT NULL,
+  before_summary TEXT NOT NULL DEFAULT '',
+  after_summary TEXT NOT NULL DEFAULT '',
+  source_ip varchar(64) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(id)
+);
+
+CREATE INDEX idx_audit_events_org_created_at ON audit_events(org_id, created_at DESC);
+
+-- Audit events are append-only. Old events are only ever deleted by the retention policy.
+CREATE FUNCTION prevent_audit_event_update() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'audit events cannot be modified';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER prevent_audit_events_update BEFORE UPDATE ON audit_events
+  FOR EACH ROW EXECUTE PROCEDURE prevent_audit_event_update();
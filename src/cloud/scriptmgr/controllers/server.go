@@ -26,13 +26,13 @@ import (
 
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/jsonpb"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/api/proto/vispb"
 	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/cloud/shared/blobstore"
 	"px.dev/pixie/src/utils"
 )
 
@@ -59,18 +59,18 @@ type scriptStore struct {
 type Server struct {
 	bundleBucket    string
 	bundlePath      string
-	sc              stiface.Client
+	blobs           blobstore.Store
 	store           *scriptStore
 	storeLastUpdate time.Time
 	SeedUUID        uuid.UUID
 }
 
 // NewServer creates a new GRPC scriptmgr server.
-func NewServer(bundleBucket string, bundlePath string, sc stiface.Client) *Server {
+func NewServer(bundleBucket string, bundlePath string, store blobstore.Store) *Server {
 	s := &Server{
 		bundleBucket: bundleBucket,
 		bundlePath:   bundlePath,
-		sc:           sc,
+		blobs:        store,
 		store: &scriptStore{
 			Scripts:   make(map[uuid.UUID]*scriptModel),
 			LiveViews: make(map[uuid.UUID]*liveViewModel),
@@ -83,7 +83,7 @@ func NewServer(bundleBucket string, bundlePath string, sc stiface.Client) *Serve
 		log.WithError(err).
 			WithField("bucket", s.bundleBucket).
 			WithField("path", s.bundlePath).
-			Error("Failed to update store using bundle.json from gcs.")
+			Error("Failed to update store using bundle.json from the blob store.")
 	}
 	return s
 }
@@ -118,7 +118,7 @@ func (s *Server) addScript(name string, bundleScript *pixieScript, hasLiveView b
 }
 
 func (s *Server) updateStore() error {
-	b, err := getBundle(s.sc, s.bundleBucket, s.bundlePath)
+	b, err := getBundle(s.blobs, s.bundleBucket, s.bundlePath)
 	if err != nil {
 		return err
 	}
@@ -146,27 +146,28 @@ func (s *Server) storeUpdater() {
 	ctx := context.Background()
 	for range t.C {
 		log.Trace("Checking if bundle needs updating...")
-		attrs, err := s.sc.Bucket(s.bundleBucket).Object(s.bundlePath).Attrs(ctx)
+		updated, err := s.blobs.LastModified(ctx, s.bundleBucket, s.bundlePath)
 		if err != nil {
 			log.WithError(err).
 				WithField("bucket", s.bundleBucket).
 				WithField("path", s.bundlePath).
-				Error("Failed to get attrs of bundle.json")
+				Error("Failed to get last modified time of bundle.json")
+			continue
 		}
-		if attrs.Updated.After(s.storeLastUpdate) {
+		if updated.After(s.storeLastUpdate) {
 			log.Trace("Update to bundle required. Updating...")
 			err := s.updateStore()
 			if err != nil {
 				log.WithError(err).
 					WithField("bucket", s.bundleBucket).
 					WithField("path", s.bundlePath).
-					Error("Failed to update bundle.json from gcs.")
+					Error("Failed to update bundle.json from the blob store.")
 			}
 			log.
 				WithField("scripts", s.store.Scripts).
 				WithField("live views", s.store.LiveViews).
 				Trace("Finished updating bundle.")
-			s.storeLastUpdate = attrs.Updated
+			s.storeLastUpdate = updated
 		}
 	}
 }
@@ -28,7 +28,6 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/jsonpb"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
@@ -37,6 +36,7 @@ import (
 	"px.dev/pixie/src/api/proto/vispb"
 	"px.dev/pixie/src/cloud/scriptmgr/controllers"
 	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/cloud/shared/blobstore"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/utils/testingutils"
 )
@@ -88,11 +88,11 @@ var testBundle = map[string]scriptsDef{
 	},
 }
 
-func mustSetupFakeBucket(t *testing.T, testBundle map[string]scriptsDef) stiface.Client {
+func mustSetupFakeBucket(t *testing.T, testBundle map[string]scriptsDef) blobstore.Store {
 	bundleJSON, err := json.Marshal(testBundle)
 	require.NoError(t, err)
 
-	return testingutils.NewMockGCSClient(map[string]*testingutils.MockGCSBucket{
+	client := testingutils.NewMockGCSClient(map[string]*testingutils.MockGCSBucket{
 		bundleBucket: testingutils.NewMockGCSBucket(
 			map[string]*testingutils.MockGCSObject{
 				bundlePath: testingutils.NewMockGCSObject(
@@ -105,6 +105,7 @@ func mustSetupFakeBucket(t *testing.T, testBundle map[string]scriptsDef) stiface
 			nil,
 		),
 	})
+	return blobstore.NewGCSStore(client, nil)
 }
 
 func TestScriptMgr_GetLiveViews(t *testing.T) {
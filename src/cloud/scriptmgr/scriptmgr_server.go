@@ -23,8 +23,6 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 
-	"cloud.google.com/go/storage"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -32,6 +30,7 @@ import (
 
 	"px.dev/pixie/src/cloud/scriptmgr/controllers"
 	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/cloud/shared/blobstore"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/shared/services/env"
 	"px.dev/pixie/src/shared/services/healthz"
@@ -39,7 +38,7 @@ import (
 )
 
 func init() {
-	pflag.String("bundle_bucket", "pixie-prod-artifacts", "GCS Bucket containing the bundle of scripts.")
+	pflag.String("bundle_bucket", "pixie-prod-artifacts", "Bucket containing the bundle of scripts.")
 	pflag.String("bundle_path", "script-bundles/bundle.json", "Path to bundle within bucket.")
 }
 
@@ -56,15 +55,15 @@ func main() {
 
 	s := server.NewPLServer(env.New(viper.GetString("domain_name")), mux)
 
-	client, err := storage.NewClient(context.Background(), option.WithoutAuthentication())
+	store, err := blobstore.NewFromFlags(context.Background(), nil, option.WithoutAuthentication())
 	if err != nil {
-		log.WithError(err).Fatal("Failed to initialize GCS client.")
+		log.WithError(err).Fatal("Failed to initialize blob store.")
 	}
 
 	svr := controllers.NewServer(
 		viper.GetString("bundle_bucket"),
 		viper.GetString("bundle_path"),
-		stiface.AdaptClient(client))
+		store)
 	svr.Start()
 
 	scriptmgrpb.RegisterScriptMgrServiceServer(s.GRPCServer(), svr)
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package blobstore provides a small object storage abstraction so that cloud services
+// can be backed by GCS, an S3-compatible store or the local filesystem.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2/jwt"
+	"google.golang.org/api/option"
+)
+
+// ErrNotFound is returned when the requested object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+func init() {
+	pflag.String("blob_store", "gcs", "The blob store backend to use: one of gcs|s3|fs")
+	pflag.String("blob_fs_root", "/data/blobs", "The root directory used by the fs blob store. Each bucket is a subdirectory")
+	pflag.String("blob_fs_download_url", "", "The externally reachable URL that serves the fs blob store's download handler")
+	pflag.String("blob_s3_endpoint", "", "The endpoint of the S3-compatible store, eg: https://minio.plc:9000")
+	pflag.String("blob_s3_region", "us-east-1", "The region of the S3-compatible store")
+	pflag.String("blob_s3_access_key_id", "", "The access key ID for the S3-compatible store")
+	pflag.String("blob_s3_secret_access_key", "", "The secret access key for the S3-compatible store")
+	pflag.Bool("blob_s3_path_style", true, "Use path-style bucket addressing, as required by most S3-compatible stores")
+}
+
+// Store is a read-only view of a set of buckets holding blobs.
+type Store interface {
+	// NewReader returns a reader for the object at path in the given bucket.
+	NewReader(ctx context.Context, bucket, path string) (io.ReadCloser, error)
+	// LastModified returns the time the object was last updated.
+	LastModified(ctx context.Context, bucket, path string) (time.Time, error)
+	// List returns the names of all objects in the bucket that start with prefix.
+	List(ctx context.Context, bucket, prefix string) ([]string, error)
+	// DownloadURL returns a URL that can be used to download the object until expires.
+	DownloadURL(ctx context.Context, bucket, path string, expires time.Time) (string, error)
+}
+
+// NewFromFlags creates the store selected by the blob_store flag. The service account and
+// client options are only used by the GCS backend.
+func NewFromFlags(ctx context.Context, sa *jwt.Config, opts ...option.ClientOption) (Store, error) {
+	switch backend := viper.GetString("blob_store"); backend {
+	case "gcs":
+		client, err := storage.NewClient(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return NewGCSStore(stiface.AdaptClient(client), sa), nil
+	case "s3":
+		return NewS3Store(&S3Config{
+			Endpoint:        viper.GetString("blob_s3_endpoint"),
+			Region:          viper.GetString("blob_s3_region"),
+			AccessKeyID:     viper.GetString("blob_s3_access_key_id"),
+			SecretAccessKey: viper.GetString("blob_s3_secret_access_key"),
+			PathStyle:       viper.GetBool("blob_s3_path_style"),
+		})
+	case "fs":
+		return NewFSStore(viper.GetString("blob_fs_root"), viper.GetString("blob_fs_download_url"),
+			[]byte(viper.GetString("jwt_signing_key"))), nil
+	default:
+		return nil, fmt.Errorf("unknown blob store backend %q", backend)
+	}
+}
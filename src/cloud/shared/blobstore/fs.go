@@ -0,0 +1,213 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package blobstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FSStore is a Store backed by a directory on the local filesystem. Each bucket is a
+// subdirectory of the root. Since there is nothing to sign a URL with, FSStore also
+// implements http.Handler to serve the download URLs it hands out.
+type FSStore struct {
+	root       string
+	baseURL    string
+	signingKey []byte
+}
+
+// NewFSStore creates a new filesystem backed store. Download URLs are generated relative
+// to baseURL, which should route to the store's http.Handler, and are signed with signingKey.
+func NewFSStore(root, baseURL string, signingKey []byte) *FSStore {
+	return &FSStore{
+		root:       root,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		signingKey: signingKey,
+	}
+}
+
+func validateBucket(bucket string) error {
+	if bucket == "" || bucket == "." || bucket == ".." || strings.ContainsAny(bucket, `/\`) {
+		return fmt.Errorf("invalid bucket %q", bucket)
+	}
+	return nil
+}
+
+// objectPath returns the path on disk for the object, making sure it stays within the bucket.
+func (s *FSStore) objectPath(bucket, name string) (string, error) {
+	if err := validateBucket(bucket); err != nil {
+		return "", err
+	}
+	cleaned := path.Clean("/" + name)
+	if cleaned == "/" {
+		return "", fmt.Errorf("invalid object path %q", name)
+	}
+	return filepath.Join(s.root, bucket, filepath.FromSlash(cleaned)), nil
+}
+
+// NewReader returns a reader for the given object.
+func (s *FSStore) NewReader(ctx context.Context, bucket, name string) (io.ReadCloser, error) {
+	p, err := s.objectPath(bucket, name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// LastModified returns the time the object was last updated.
+func (s *FSStore) LastModified(ctx context.Context, bucket, name string) (time.Time, error) {
+	p, err := s.objectPath(bucket, name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// List returns the names of all objects in the bucket with the given prefix.
+func (s *FSStore) List(ctx context.Context, bucket, prefix string) ([]string, error) {
+	if err := validateBucket(bucket); err != nil {
+		return nil, err
+	}
+	bucketDir := filepath.Join(s.root, bucket)
+	var names []string
+	err := filepath.WalkDir(bucketDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(bucketDir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return names, err
+}
+
+func (s *FSStore) sign(bucket, name string, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%s\n%s\n%d", bucket, name, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// DownloadURL returns a signed URL, served by the store's handler, for the object.
+func (s *FSStore) DownloadURL(ctx context.Context, bucket, name string, expires time.Time) (string, error) {
+	if s.baseURL == "" {
+		return "", errors.New("no download URL configured for the fs blob store")
+	}
+	p, err := s.objectPath(bucket, name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(p); errors.Is(err, fs.ErrNotExist) {
+		return "", ErrNotFound
+	} else if err != nil {
+		return "", err
+	}
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	q.Set("signature", s.sign(bucket, name, expires.Unix()))
+	u := url.URL{Path: "/" + bucket + "/" + name, RawQuery: q.Encode()}
+	return s.baseURL + u.String(), nil
+}
+
+// ServeHTTP serves objects for URLs generated by DownloadURL. Requests are expected to have
+// the form /<bucket>/<path>, so the handler should be mounted with http.StripPrefix.
+func (s *FSStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	bucket, name := parts[0], strings.TrimPrefix(path.Clean("/"+parts[1]), "/")
+
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		http.Error(w, "link expired", http.StatusForbidden)
+		return
+	}
+	sig, err := hex.DecodeString(r.URL.Query().Get("signature"))
+	if err != nil {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+	expected, _ := hex.DecodeString(s.sign(bucket, name, expires))
+	if !hmac.Equal(sig, expected) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	p, err := s.objectPath(bucket, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, path.Base(name), info.ModTime(), f)
+}
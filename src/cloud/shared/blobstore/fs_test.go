@@ -0,0 +1,127 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package blobstore_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/blobstore"
+)
+
+func setupFSStore(t *testing.T) *blobstore.FSStore {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "artifacts", "cli", "1.0.0"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "artifacts", "cli", "1.0.0", "cli_linux_amd64"), []byte("binary"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "artifacts", "bundle.json"), []byte("{}"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "secret"), []byte("secret"), 0644))
+	return blobstore.NewFSStore(root, "https://work.example.com/blobs/", []byte("signing-key"))
+}
+
+func TestFSStore_NewReader(t *testing.T) {
+	s := setupFSStore(t)
+	ctx := context.Background()
+
+	r, err := s.NewReader(ctx, "artifacts", "cli/1.0.0/cli_linux_amd64")
+	require.NoError(t, err)
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(b))
+
+	_, err = s.NewReader(ctx, "artifacts", "cli/2.0.0/cli_linux_amd64")
+	assert.Equal(t, blobstore.ErrNotFound, err)
+
+	modified, err := s.LastModified(ctx, "artifacts", "bundle.json")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), modified, time.Minute)
+
+	// Paths can't escape the bucket.
+	_, err = s.NewReader(ctx, "artifacts", "../secret")
+	assert.Equal(t, blobstore.ErrNotFound, err)
+	_, err = s.NewReader(ctx, "..", "secret")
+	assert.Error(t, err)
+}
+
+func TestFSStore_List(t *testing.T) {
+	s := setupFSStore(t)
+
+	names, err := s.List(context.Background(), "artifacts", "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"bundle.json", "cli/1.0.0/cli_linux_amd64"}, names)
+
+	names, err = s.List(context.Background(), "artifacts", "cli/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cli/1.0.0/cli_linux_amd64"}, names)
+
+	names, err = s.List(context.Background(), "missing", "")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestFSStore_DownloadURL(t *testing.T) {
+	s := setupFSStore(t)
+	ctx := context.Background()
+
+	_, err := s.DownloadURL(ctx, "artifacts", "cli/2.0.0/cli_linux_amd64", time.Now().Add(time.Hour))
+	assert.Equal(t, blobstore.ErrNotFound, err)
+
+	link, err := s.DownloadURL(ctx, "artifacts", "cli/1.0.0/cli_linux_amd64", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "work.example.com", u.Host)
+	assert.Equal(t, "/blobs/artifacts/cli/1.0.0/cli_linux_amd64", u.Path)
+
+	h := http.StripPrefix("/blobs", s)
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	w := serve(u.RequestURI())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "binary", w.Body.String())
+
+	// Tampering with the path or expiry invalidates the signature.
+	q := u.Query()
+	w = serve("/blobs/artifacts/bundle.json?" + q.Encode())
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	q.Set("expires", "99999999999")
+	w = serve(u.Path + "?" + q.Encode())
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	expired, err := s.DownloadURL(ctx, "artifacts", "cli/1.0.0/cli_linux_amd64", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	u, err = url.Parse(expired)
+	require.NoError(t, err)
+	w = serve(u.RequestURI())
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+	"golang.org/x/oauth2/jwt"
+	"google.golang.org/api/iterator"
+)
+
+// GCSURLSigner is the function used to sign GCS urls.
+var GCSURLSigner = storage.SignedURL
+
+// GCSStore is a Store backed by Google Cloud Storage.
+type GCSStore struct {
+	sc stiface.Client
+	sa *jwt.Config
+}
+
+// NewGCSStore creates a new GCS backed store. If sa is nil, download URLs point at the
+// object's public media link instead of being signed.
+func NewGCSStore(sc stiface.Client, sa *jwt.Config) *GCSStore {
+	return &GCSStore{sc: sc, sa: sa}
+}
+
+// NewReader returns a reader for the given object.
+func (s *GCSStore) NewReader(ctx context.Context, bucket, path string) (io.ReadCloser, error) {
+	r, err := s.sc.Bucket(bucket).Object(path).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, ErrNotFound
+	}
+	return r, err
+}
+
+// LastModified returns the time the object was last updated.
+func (s *GCSStore) LastModified(ctx context.Context, bucket, path string) (time.Time, error) {
+	attrs, err := s.sc.Bucket(bucket).Object(path).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return attrs.Updated, nil
+}
+
+// List returns the names of all objects in the bucket with the given prefix.
+func (s *GCSStore) List(ctx context.Context, bucket, prefix string) ([]string, error) {
+	it := s.sc.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	var names []string
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, attrs.Name)
+	}
+}
+
+// DownloadURL returns a signed URL for the object, or its media link if no service account is configured.
+func (s *GCSStore) DownloadURL(ctx context.Context, bucket, path string, expires time.Time) (string, error) {
+	if s.sa != nil {
+		return GCSURLSigner(bucket, path, &storage.SignedURLOptions{
+			GoogleAccessID: s.sa.Email,
+			PrivateKey:     s.sa.PrivateKey,
+			Method:         "GET",
+			Expires:        expires,
+		})
+	}
+	attrs, err := s.sc.Bucket(bucket).Object(path).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return attrs.MediaLink, nil
+}
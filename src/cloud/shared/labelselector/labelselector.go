@@ -0,0 +1,190 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package labelselector parses and evaluates equality-based label selectors for clusters,
+// such as "env=prod,region!=eu,canary".
+package labelselector
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const maxLabelLength = 63
+
+var labelRegex = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_./]*[A-Za-z0-9])?$`)
+
+type operator int
+
+const (
+	opEquals operator = iota
+	opNotEquals
+	opExists
+	opNotExists
+)
+
+type requirement struct {
+	key   string
+	op    operator
+	value string
+}
+
+func (r requirement) matches(labels map[string]string) bool {
+	v, ok := labels[r.key]
+	switch r.op {
+	case opEquals:
+		return ok && v == r.value
+	case opNotEquals:
+		return !ok || v != r.value
+	case opExists:
+		return ok
+	case opNotExists:
+		return !ok
+	}
+	return false
+}
+
+func (r requirement) String() string {
+	switch r.op {
+	case opEquals:
+		return r.key + "=" + r.value
+	case opNotEquals:
+		return r.key + "!=" + r.value
+	case opNotExists:
+		return "!" + r.key
+	}
+	return r.key
+}
+
+// Selector is a parsed label selector. All requirements must match for the selector to match.
+// The empty selector matches every set of labels.
+type Selector struct {
+	reqs []requirement
+}
+
+// Parse parses a comma-separated list of requirements. Each requirement is one of
+// "key=value", "key==value", "key!=value", "key" (the label exists) or "!key" (the label does not exist).
+func Parse(s string) (*Selector, error) {
+	sel := &Selector{}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return sel, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("empty requirement in selector %q", s)
+		}
+
+		var r requirement
+		switch {
+		case strings.Contains(part, "!="):
+			kv := strings.SplitN(part, "!=", 2)
+			r = requirement{key: strings.TrimSpace(kv[0]), op: opNotEquals, value: strings.TrimSpace(kv[1])}
+		case strings.Contains(part, "=="):
+			kv := strings.SplitN(part, "==", 2)
+			r = requirement{key: strings.TrimSpace(kv[0]), op: opEquals, value: strings.TrimSpace(kv[1])}
+		case strings.Contains(part, "="):
+			kv := strings.SplitN(part, "=", 2)
+			r = requirement{key: strings.TrimSpace(kv[0]), op: opEquals, value: strings.TrimSpace(kv[1])}
+		case strings.HasPrefix(part, "!"):
+			r = requirement{key: strings.TrimSpace(part[1:]), op: opNotExists}
+		default:
+			r = requirement{key: part, op: opExists}
+		}
+
+		if err := ValidateKey(r.key); err != nil {
+			return nil, err
+		}
+		if r.op == opEquals || r.op == opNotEquals {
+			if err := ValidateValue(r.value); err != nil {
+				return nil, err
+			}
+		}
+		sel.reqs = append(sel.reqs, r)
+	}
+	return sel, nil
+}
+
+// Empty returns whether the selector has no requirements.
+func (s *Selector) Empty() bool {
+	return s == nil || len(s.reqs) == 0
+}
+
+// Matches returns whether the given labels satisfy every requirement of the selector.
+func (s *Selector) Matches(labels map[string]string) bool {
+	if s == nil {
+		return true
+	}
+	for _, r := range s.reqs {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the canonical form of the selector, with requirements sorted by key.
+func (s *Selector) String() string {
+	if s == nil {
+		return ""
+	}
+	parts := make([]string, len(s.reqs))
+	for i, r := range s.reqs {
+		parts[i] = r.String()
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// ValidateKey returns an error if the given string is not a valid label key.
+func ValidateKey(k string) error {
+	if k == "" {
+		return fmt.Errorf("label key cannot be empty")
+	}
+	if len(k) > maxLabelLength || !labelRegex.MatchString(k) {
+		return fmt.Errorf("invalid label key %q", k)
+	}
+	return nil
+}
+
+// ValidateValue returns an error if the given string is not a valid label value. Values may be empty.
+func ValidateValue(v string) error {
+	if v == "" {
+		return nil
+	}
+	if len(v) > maxLabelLength || !labelRegex.MatchString(v) {
+		return fmt.Errorf("invalid label value %q", v)
+	}
+	return nil
+}
+
+// ValidateLabels returns an error if any of the given labels has an invalid key or value.
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if err := ValidateKey(k); err != nil {
+			return err
+		}
+		if err := ValidateValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package labelselector_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/labelselector"
+)
+
+func TestParse_Matches(t *testing.T) {
+	labels := map[string]string{
+		"env":    "prod",
+		"region": "eu-west",
+		"canary": "",
+	}
+
+	tests := []struct {
+		name     string
+		selector string
+		matches  bool
+	}{
+		{"empty", "", true},
+		{"equals", "env=prod", true},
+		{"double equals", "env==prod", true},
+		{"equals mismatch", "env=staging", false},
+		{"not equals", "region!=us-east", true},
+		{"not equals mismatch", "region!=eu-west", false},
+		{"not equals missing", "team!=infra", true},
+		{"exists", "canary", true},
+		{"exists missing", "team", false},
+		{"not exists", "!team", true},
+		{"not exists mismatch", "!canary", false},
+		{"multiple", "env=prod, region=eu-west,canary", true},
+		{"multiple mismatch", "env=prod,region=us-east", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sel, err := labelselector.Parse(tc.selector)
+			require.NoError(t, err)
+			assert.Equal(t, tc.matches, sel.Matches(labels))
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, s := range []string{"env=prod,", "=prod", "env=pr od", "!", "-env=prod"} {
+		_, err := labelselector.Parse(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSelector_String(t *testing.T) {
+	sel, err := labelselector.Parse("region != eu, env=prod, canary, !team")
+	require.NoError(t, err)
+	assert.Equal(t, "!team,canary,env=prod,region!=eu", sel.String())
+	assert.False(t, sel.Empty())
+
+	sel, err = labelselector.Parse(" ")
+	require.NoError(t, err)
+	assert.True(t, sel.Empty())
+}
+
+func TestValidateLabels(t *testing.T) {
+	assert.NoError(t, labelselector.ValidateLabels(map[string]string{"env": "prod", "pixie.dev/tier": ""}))
+	assert.Error(t, labelselector.ValidateLabels(map[string]string{"": "prod"}))
+	assert.Error(t, labelselector.ValidateLabels(map[string]string{"env": "a b"}))
+}
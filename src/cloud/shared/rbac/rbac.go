@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package rbac contains helpers for checking the org roles of the caller in cloud services.
+package rbac
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	srvutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+var roleToProto = map[srvutils.OrgRole]profilepb.OrgRole{
+	srvutils.NoRole:     profilepb.OR_UNKNOWN,
+	srvutils.ViewerRole: profilepb.OR_VIEWER,
+	srvutils.MemberRole: profilepb.OR_MEMBER,
+	srvutils.AdminRole:  profilepb.OR_ADMIN,
+	srvutils.OwnerRole:  profilepb.OR_OWNER,
+}
+
+var roleFromProto = map[profilepb.OrgRole]srvutils.OrgRole{
+	profilepb.OR_UNKNOWN: srvutils.NoRole,
+	profilepb.OR_VIEWER:  srvutils.ViewerRole,
+	profilepb.OR_MEMBER:  srvutils.MemberRole,
+	profilepb.OR_ADMIN:   srvutils.AdminRole,
+	profilepb.OR_OWNER:   srvutils.OwnerRole,
+}
+
+// RoleToProto converts a role to its profile proto representation.
+func RoleToProto(r srvutils.OrgRole) profilepb.OrgRole {
+	return roleToProto[r]
+}
+
+// RoleFromProto converts a profile proto role.
+func RoleFromProto(r profilepb.OrgRole) srvutils.OrgRole {
+	return roleFromProto[r]
+}
+
+// UserRolesFromProto returns the roles held by the given user.
+func UserRolesFromProto(u *profilepb.UserInfo) *srvutils.UserRoles {
+	roles := &srvutils.UserRoles{
+		OrgRole:      RoleFromProto(u.GetRole()),
+		ClusterRoles: make(map[string]srvutils.OrgRole),
+	}
+	for _, cr := range u.GetClusterRoles() {
+		roles.ClusterRoles[utils.ProtoToUUIDStr(cr.ClusterID)] = RoleFromProto(cr.Role)
+	}
+	return roles
+}
+
+// RequireOrgRole returns a PermissionDenied error unless the user in the context holds at least the given
+// role in their org. Requests made with service credentials are always allowed.
+func RequireOrgRole(ctx context.Context, role srvutils.OrgRole) error {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if srvutils.GetClaimsType(sCtx.Claims) == srvutils.ServiceClaimType {
+		return nil
+	}
+	if !srvutils.GetOrgRole(sCtx.Claims).AtLeast(role) {
+		return status.Errorf(codes.PermissionDenied, "This action requires the %s role", role)
+	}
+	return nil
+}
+
+// RequireUserOrOrgRole returns a PermissionDenied error unless the user in the context is the given user, or
+// holds at least the given role in their org. Requests made with service credentials are always allowed.
+func RequireUserOrOrgRole(ctx context.Context, userID uuid.UUID, role srvutils.OrgRole) error {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if srvutils.GetClaimsType(sCtx.Claims) == srvutils.ServiceClaimType {
+		return nil
+	}
+	if userID != uuid.Nil && sCtx.Claims.GetUserClaims().GetUserID() == userID.String() {
+		return nil
+	}
+	if !srvutils.GetOrgRole(sCtx.Claims).AtLeast(role) {
+		return status.Errorf(codes.PermissionDenied, "This action requires the %s role", role)
+	}
+	return nil
+}
+
+// RequireClusterRole returns a PermissionDenied error unless the user in the context holds at least the given
+// role on the cluster, either through their org role or a grant for the cluster.
+func RequireClusterRole(ctx context.Context, clusterID uuid.UUID, role srvutils.OrgRole) error {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if srvutils.GetClaimsType(sCtx.Claims) == srvutils.ServiceClaimType {
+		return nil
+	}
+	if !srvutils.GetClusterRole(sCtx.Claims, clusterID.String()).AtLeast(role) {
+		return status.Errorf(codes.PermissionDenied, "This action requires the %s role on the cluster", role)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package rbac_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/shared/rbac"
+	"px.dev/pixie/src/shared/services/authcontext"
+	srvutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+var testClusterID = uuid.FromStringOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+func contextWithRoles(roles *srvutils.UserRoles) context.Context {
+	sCtx := authcontext.New()
+	sCtx.Claims = srvutils.GenerateJWTForUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "test@test.com", time.Now(), "pixie", roles)
+	return authcontext.NewContext(context.Background(), sCtx)
+}
+
+func TestUserRolesFromProto(t *testing.T) {
+	roles := rbac.UserRolesFromProto(&profilepb.UserInfo{
+		Role: profilepb.OR_VIEWER,
+		ClusterRoles: []*profilepb.ClusterRole{
+			{ClusterID: utils.ProtoFromUUID(testClusterID), Role: profilepb.OR_ADMIN},
+		},
+	})
+	assert.Equal(t, srvutils.ViewerRole, roles.OrgRole)
+	assert.Equal(t, map[string]srvutils.OrgRole{testClusterID.String(): srvutils.AdminRole}, roles.ClusterRoles)
+}
+
+func TestRequireOrgRole(t *testing.T) {
+	ctx := contextWithRoles(&srvutils.UserRoles{OrgRole: srvutils.MemberRole})
+	assert.NoError(t, rbac.RequireOrgRole(ctx, srvutils.ViewerRole))
+	assert.NoError(t, rbac.RequireOrgRole(ctx, srvutils.MemberRole))
+	err := rbac.RequireOrgRole(ctx, srvutils.AdminRole)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	sCtx := authcontext.New()
+	sCtx.Claims = srvutils.GenerateJWTForService("AuthService", "pixie")
+	assert.NoError(t, rbac.RequireOrgRole(authcontext.NewContext(context.Background(), sCtx), srvutils.OwnerRole))
+}
+
+func TestRequireUserOrOrgRole(t *testing.T) {
+	ctx := contextWithRoles(&srvutils.UserRoles{OrgRole: srvutils.ViewerRole})
+	self := uuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9")
+	assert.NoError(t, rbac.RequireUserOrOrgRole(ctx, self, srvutils.AdminRole))
+	err := rbac.RequireUserOrOrgRole(ctx, uuid.Must(uuid.NewV4()), srvutils.AdminRole)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	ctx = contextWithRoles(&srvutils.UserRoles{OrgRole: srvutils.AdminRole})
+	assert.NoError(t, rbac.RequireUserOrOrgRole(ctx, uuid.Must(uuid.NewV4()), srvutils.AdminRole))
+}
+
+func TestRequireClusterRole(t *testing.T) {
+	ctx := contextWithRoles(&srvutils.UserRoles{
+		OrgRole:      srvutils.ViewerRole,
+		ClusterRoles: map[string]srvutils.OrgRole{testClusterID.String(): srvutils.AdminRole},
+	})
+	assert.NoError(t, rbac.RequireClusterRole(ctx, testClusterID, srvutils.AdminRole))
+	err := rbac.RequireClusterRole(ctx, uuid.Must(uuid.NewV4()), srvutils.MemberRole)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -29,20 +29,51 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
 	"github.com/nats-io/nats.go"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/cloud/shared/vzshard"
 	"px.dev/pixie/src/cloud/vzconn/vzconnpb"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/bridgeutils"
+	"px.dev/pixie/src/shared/cvmsgs"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/services/msgbus"
+	"px.dev/pixie/src/utils"
 )
 
+// v2cCredits is the number of messages a vizier which supports flow control may send before it has to wait for
+// more credits. It is well below the capacity of grpcInCh, so that reading from the stream never blocks.
+const v2cCredits = 1024
+
+// outboxAckInterval is how often the outbox messages which were sent to a vizier that doesn't acknowledge
+// messages itself are acknowledged on its behalf.
+const outboxAckInterval = 2 * time.Second
+
+// StreamOptions are the settings for the stream which were negotiated with the vizier when it registered.
+type StreamOptions struct {
+	// Whether the vizier acknowledges the outbox messages it receives. If it doesn't, messages are
+	// acknowledged on its behalf as soon as they are sent.
+	OutboxAcks bool
+	// The compression algorithm for the messages which are sent to the vizier.
+	Compression cvmsgspb.BridgeCompression
+	// The number of messages which may be sent to the vizier before it grants more credits. If zero, messages
+	// are sent without flow control.
+	C2VCredits int64
+	// The number of messages which the vizier may send before it is granted more credits. If zero, the
+	// vizier sends messages without flow control.
+	V2CCredits int64
+}
+
 // NATSBridgeController is responsible for routing messages from Vizier to NATS. It assumes that all authentication/handshakes
 // are completed before being created.
 type NATSBridgeController struct {
@@ -51,28 +82,50 @@ type NATSBridgeController struct {
 	l         *log.Entry
 	srv       vzconnpb.VZConnService_NATSBridgeServer
 
-	nc        *nats.Conn
-	st        msgbus.Streamer
+	nc          *nats.Conn
+	st          msgbus.Streamer
+	vzmgrClient vzmgrpb.VZMgrServiceClient
+	opts        StreamOptions
+	// The sequence numbers of the outbox messages which were replayed when the stream started, and have not
+	// been seen live yet. Sequence numbers can commit out of order, so a live message with a lower sequence
+	// number than the replayed ones may still need to be sent. Only accessed by _run.
+	replayedSeqIDs map[int64]struct{}
+	// The outbox messages which were sent, but not yet acknowledged on behalf of a vizier that doesn't
+	// acknowledge messages itself. Only accessed by _run.
+	pendingAcks []int64
+
 	grpcOutCh chan *vzconnpb.C2VBridgeMessage
 	grpcInCh  chan *vzconnpb.V2CBridgeMessage
+	// Control messages are sent ahead of the messages in grpcOutCh, and aren't subject to flow control.
+	ctrlOutCh chan *vzconnpb.C2VBridgeMessage
+
+	sendWindow *bridgeutils.SendWindow
+	recvWindow *bridgeutils.ReceiveWindow
 
 	quitCh chan bool // Channel is used to signal that things should shutdown.
 	subCh  chan *nats.Msg
 }
 
 // NewNATSBridgeController creates a NATSBridgeController.
-func NewNATSBridgeController(clusterID uuid.UUID, srv vzconnpb.VZConnService_NATSBridgeServer, nc *nats.Conn, st msgbus.Streamer) *NATSBridgeController {
+func NewNATSBridgeController(clusterID uuid.UUID, srv vzconnpb.VZConnService_NATSBridgeServer, nc *nats.Conn, st msgbus.Streamer,
+	vzmgrClient vzmgrpb.VZMgrServiceClient, opts StreamOptions) *NATSBridgeController {
 	streamID := uuid.Must(uuid.NewV4())
 	return &NATSBridgeController{
-		streamID:  streamID,
-		clusterID: clusterID,
-		l:         log.WithField("StreamID", streamID),
-		srv:       srv,
-		nc:        nc,
-		st:        st,
+		streamID:    streamID,
+		clusterID:   clusterID,
+		l:           log.WithField("StreamID", streamID),
+		srv:         srv,
+		nc:          nc,
+		st:          st,
+		vzmgrClient: vzmgrClient,
+		opts:        opts,
 
 		grpcOutCh: make(chan *vzconnpb.C2VBridgeMessage, 4096),
 		grpcInCh:  make(chan *vzconnpb.V2CBridgeMessage, 4096),
+		ctrlOutCh: make(chan *vzconnpb.C2VBridgeMessage, 256),
+
+		sendWindow: bridgeutils.NewSendWindow(opts.C2VCredits),
+		recvWindow: bridgeutils.NewReceiveWindow(opts.V2CCredits),
 
 		quitCh: make(chan bool),
 		subCh:  make(chan *nats.Msg, 4096),
@@ -115,6 +168,9 @@ func (s *NATSBridgeController) Run() error {
 	eg.Go(func() error {
 		return s._run(ctx)
 	})
+	eg.Go(func() error {
+		return s.handleGRPCMessages(ctx)
+	})
 	err = eg.Wait()
 	if status.Code(err) == codes.Canceled {
 		s.l.Info("Closing stream, context cancellation")
@@ -132,11 +188,22 @@ func (s *NATSBridgeController) Run() error {
 }
 
 func (s *NATSBridgeController) _run(ctx context.Context) error {
+	// The NATS subscription is already active, so messages which are added to the outbox while it is
+	// replayed are either in the replayed set, or arrive on subCh afterwards.
+	s.replayOutbox(ctx)
+
+	ackTicker := time.NewTicker(outboxAckInterval)
+	defer ackTicker.Stop()
+	// The stream's context is already canceled when the remaining acks are flushed.
+	defer s.flushOutboxAcks(context.Background())
+
 	for {
 		var err error
 		select {
 		case <-s.quitCh:
 			return nil
+		case <-ackTicker.C:
+			s.flushOutboxAcks(ctx)
 		case msg := <-s.subCh:
 			msgKind := cleanCloudToVizierMessageKind(msg.Subject)
 			cloudToVizierMsgCount.
@@ -146,7 +213,23 @@ func (s *NATSBridgeController) _run(ctx context.Context) error {
 				WithLabelValues(msgKind).
 				Observe(float64(len(msg.Data)))
 
-			err = s.sendNATSMessageToGRPC(msg)
+			err = s.sendNATSMessageToGRPC(ctx, msg)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handleGRPCMessages handles the messages from the vizier. It runs separately from the messages sent to the
+// vizier, so that waiting for the vizier to grant credits doesn't stop us from granting credits to it.
+func (s *NATSBridgeController) handleGRPCMessages(ctx context.Context) error {
+	for {
+		select {
+		case <-s.quitCh:
+			return nil
 		case msg := <-s.grpcInCh:
 			msgKind := cleanVizierToCloudMessageKind(msg.Topic)
 			vizierToCloudMsgCount.
@@ -156,13 +239,25 @@ func (s *NATSBridgeController) _run(ctx context.Context) error {
 				WithLabelValues(msgKind).
 				Observe(float64(len(msg.Msg.Value)))
 
-			err = s.sendMessageToMessageBus(msg)
+			switch msg.Topic {
+			case cvmsgs.BridgeCreditsChannel:
+				s.handleCreditGrant(msg)
+				continue
+			case cvmsgs.C2VOutboxAckChannel:
+				s.handleOutboxAck(ctx, msg)
+				continue
+			}
+			if err := s.sendMessageToMessageBus(msg); err != nil {
+				return err
+			}
+			if credits := s.recvWindow.Processed(); credits > 0 {
+				if err := s.grantCredits(ctx, credits); err != nil {
+					return err
+				}
+			}
 		case <-ctx.Done():
 			return ctx.Err()
 		}
-		if err != nil {
-			return err
-		}
 	}
 }
 
@@ -174,24 +269,149 @@ func (s *NATSBridgeController) getRemoteSubject(topic string) string {
 	return ""
 }
 
-func (s *NATSBridgeController) sendNATSMessageToGRPC(msg *nats.Msg) error {
+func (s *NATSBridgeController) sendNATSMessageToGRPC(ctx context.Context, msg *nats.Msg) error {
 	c2vMsg := cvmsgspb.C2VMessage{}
 	err := c2vMsg.Unmarshal(msg.Data)
 	if err != nil {
 		return err
 	}
 
+	if _, ok := s.replayedSeqIDs[c2vMsg.SeqID]; ok && c2vMsg.SeqID != 0 {
+		// This message was already sent when the outbox was replayed. Each message is only published once.
+		delete(s.replayedSeqIDs, c2vMsg.SeqID)
+		return nil
+	}
+
 	topic := s.getRemoteSubject(msg.Subject)
 
 	outMsg := &vzconnpb.C2VBridgeMessage{
 		Topic: topic,
 		Msg:   c2vMsg.Msg,
+		SeqID: c2vMsg.SeqID,
 	}
 
-	s.grpcOutCh <- outMsg
+	select {
+	case s.grpcOutCh <- outMsg:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if c2vMsg.SeqID != 0 && !s.opts.OutboxAcks {
+		s.pendingAcks = append(s.pendingAcks, c2vMsg.SeqID)
+	}
 	return nil
 }
 
+// flushOutboxAcks acknowledges the live outbox messages which were sent to a vizier that doesn't acknowledge
+// messages itself. They are acknowledged in batches, so that busy viziers don't cost a request per message.
+func (s *NATSBridgeController) flushOutboxAcks(ctx context.Context) {
+	if len(s.pendingAcks) == 0 {
+		return
+	}
+	s.ackOutboxMessages(ctx, s.pendingAcks)
+	s.pendingAcks = nil
+}
+
+func (s *NATSBridgeController) outboxContext(ctx context.Context) (context.Context, error) {
+	serviceAuthToken, err := getServiceCredentials(viper.GetString("jwt_signing_key"))
+	if err != nil {
+		return nil, err
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization",
+		fmt.Sprintf("bearer %s", serviceAuthToken)), nil
+}
+
+// replayOutbox sends the messages in the vizier's outbox which it has not acknowledged. Failures are logged,
+// since the messages are replayed again when the vizier next connects.
+func (s *NATSBridgeController) replayOutbox(ctx context.Context) {
+	outCtx, err := s.outboxContext(ctx)
+	if err != nil {
+		s.l.WithError(err).Error("Failed to get credentials to replay outbox")
+		return
+	}
+	resp, err := s.vzmgrClient.GetC2VOutbox(outCtx, &vzmgrpb.GetC2VOutboxRequest{
+		VizierID: utils.ProtoFromUUID(s.clusterID),
+	})
+	if err != nil {
+		s.l.WithError(err).Error("Failed to fetch outbox")
+		return
+	}
+	if len(resp.Messages) == 0 {
+		return
+	}
+
+	s.l.WithField("count", len(resp.Messages)).Info("Replaying outbox")
+	seqIDs := make([]int64, len(resp.Messages))
+	s.replayedSeqIDs = make(map[int64]struct{}, len(resp.Messages))
+	for i, m := range resp.Messages {
+		select {
+		case s.grpcOutCh <- &vzconnpb.C2VBridgeMessage{
+			Topic: m.Topic,
+			Msg:   m.Msg,
+			SeqID: m.SeqID,
+		}:
+		case <-ctx.Done():
+			return
+		}
+		seqIDs[i] = m.SeqID
+		s.replayedSeqIDs[m.SeqID] = struct{}{}
+	}
+	cloudToVizierOutboxReplayCount.Add(float64(len(resp.Messages)))
+
+	if !s.opts.OutboxAcks {
+		s.ackOutboxMessages(ctx, seqIDs)
+	}
+}
+
+func (s *NATSBridgeController) handleCreditGrant(msg *vzconnpb.V2CBridgeMessage) {
+	grant := &cvmsgspb.BridgeCreditGrant{}
+	if err := types.UnmarshalAny(msg.Msg, grant); err != nil {
+		s.l.WithError(err).Error("Received malformed credit grant")
+		return
+	}
+	s.sendWindow.Grant(grant.Credits)
+}
+
+// grantCredits allows the vizier to send more messages.
+func (s *NATSBridgeController) grantCredits(ctx context.Context, credits int64) error {
+	anyMsg, err := types.MarshalAny(&cvmsgspb.BridgeCreditGrant{Credits: credits})
+	if err != nil {
+		return err
+	}
+	select {
+	case s.ctrlOutCh <- &vzconnpb.C2VBridgeMessage{
+		Topic: cvmsgs.BridgeCreditsChannel,
+		Msg:   anyMsg,
+	}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *NATSBridgeController) handleOutboxAck(ctx context.Context, msg *vzconnpb.V2CBridgeMessage) {
+	ack := &cvmsgspb.C2VOutboxAck{}
+	if err := types.UnmarshalAny(msg.Msg, ack); err != nil {
+		s.l.WithError(err).Error("Received malformed outbox ack")
+		return
+	}
+	s.ackOutboxMessages(ctx, ack.SeqIDs)
+}
+
+// ackOutboxMessages removes the messages from the vizier's outbox. Failures are logged, since a message
+// which is not removed is only sent again.
+func (s *NATSBridgeController) ackOutboxMessages(ctx context.Context, seqIDs []int64) {
+	outCtx, err := s.outboxContext(ctx)
+	if err == nil {
+		_, err = s.vzmgrClient.AckC2VMessages(outCtx, &vzmgrpb.AckC2VMessagesRequest{
+			VizierID: utils.ProtoFromUUID(s.clusterID),
+			SeqIDs:   seqIDs,
+		})
+	}
+	if err != nil {
+		s.l.WithError(err).Error("Failed to ack outbox messages")
+	}
+}
+
 func (s *NATSBridgeController) sendMessageToMessageBus(msg *vzconnpb.V2CBridgeMessage) error {
 	cid := s.clusterID.String()
 	natsMsg := &cvmsgspb.V2CMessage{
@@ -235,6 +455,11 @@ func (s *NATSBridgeController) startStreamGRPCReader(ctx context.Context) error
 			if err != nil {
 				return err
 			}
+			msg.Msg, err = bridgeutils.DecompressMsg(msg.Msg, msg.Compression)
+			if err != nil {
+				return err
+			}
+			msg.Compression = cvmsgspb.BRIDGE_COMPRESSION_NONE
 			s.grpcInCh <- msg
 		}
 	}
@@ -243,6 +468,24 @@ func (s *NATSBridgeController) startStreamGRPCReader(ctx context.Context) error
 func (s *NATSBridgeController) startStreamGRPCWriter(ctx context.Context) error {
 	s.l.Trace("Starting GRPC writer stream")
 	for {
+		// Control messages are sent ahead of everything else.
+		select {
+		case m := <-s.ctrlOutCh:
+			if err := s.sendGRPCMessage(m); err != nil {
+				return err
+			}
+			continue
+		default:
+		}
+
+		// Other messages are only sent while the vizier has granted credits for them.
+		var dataCh chan *vzconnpb.C2VBridgeMessage
+		if s.sendWindow.Available() {
+			dataCh = s.grpcOutCh
+		} else {
+			cloudToVizierCreditWaitCount.Inc()
+		}
+
 		select {
 		case <-s.srv.Context().Done():
 			return nil
@@ -253,13 +496,27 @@ func (s *NATSBridgeController) startStreamGRPCWriter(ctx context.Context) error
 			return nil
 		case <-ctx.Done():
 			return ctx.Err()
-		case m := <-s.grpcOutCh:
-			// Write message to GRPC if it exists.
-			err := s.srv.Send(m)
-			if err != nil {
-				s.l.WithError(err).Error("Failed to send message")
+		case <-s.sendWindow.Granted():
+			// More messages may be sent now.
+		case m := <-s.ctrlOutCh:
+			if err := s.sendGRPCMessage(m); err != nil {
+				return err
+			}
+		case m := <-dataCh:
+			s.sendWindow.Consume()
+			if err := s.sendGRPCMessage(m); err != nil {
 				return err
 			}
 		}
 	}
 }
+
+func (s *NATSBridgeController) sendGRPCMessage(m *vzconnpb.C2VBridgeMessage) error {
+	out := *m
+	out.Msg, out.Compression = bridgeutils.CompressMsg(m.Msg, s.opts.Compression)
+	err := s.srv.Send(&out)
+	if err != nil {
+		s.l.WithError(err).Error("Failed to send message")
+	}
+	return err
+}
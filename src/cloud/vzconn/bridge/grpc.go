@@ -34,6 +34,7 @@ import (
 
 	"px.dev/pixie/src/cloud/vzconn/vzconnpb"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/bridgeutils"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/services/msgbus"
 	"px.dev/pixie/src/shared/services/utils"
@@ -82,9 +83,10 @@ func (s *GRPCServer) RegisterVizierDeployment(ctx context.Context, req *vzconnpb
 	newCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization",
 		fmt.Sprintf("bearer %s", serviceAuthToken))
 	vzmgrResp, err := s.vzDeploymentClient.RegisterVizierDeployment(newCtx, &vzmgrpb.RegisterVizierDeploymentRequest{
-		K8sClusterUID:  req.K8sClusterUID,
-		K8sClusterName: req.K8sClusterName,
-		DeploymentKey:  deployKey,
+		K8sClusterUID:   req.K8sClusterUID,
+		K8sClusterName:  req.K8sClusterName,
+		VizierNamespace: req.VizierNamespace,
+		DeploymentKey:   deployKey,
 	})
 	if err != nil {
 		return nil, err
@@ -114,7 +116,7 @@ func (s *GRPCServer) NATSBridge(srv vzconnpb.VZConnService_NATSBridgeServer) err
 		return convertToGRPCErr(ErrBadRegistrationMessage)
 	}
 	clusterID := registerMsg.VizierID
-	err = s.handleRegisterMessage(registerMsg, srv)
+	opts, err := s.handleRegisterMessage(registerMsg, srv)
 	if err != nil {
 		return convertToGRPCErr(err)
 	}
@@ -123,14 +125,28 @@ func (s *GRPCServer) NATSBridge(srv vzconnpb.VZConnService_NATSBridgeServer) err
 
 	// Each Vizier calls this endpoint. Once it's called we will basically
 	// create NATS bridge and subscribe to the relevant channels.
-	c := NewNATSBridgeController(utils2.UUIDFromProtoOrNil(clusterID), srv, s.nc, s.st)
+	c := NewNATSBridgeController(utils2.UUIDFromProtoOrNil(clusterID), srv, s.nc, s.st, s.vzmgrClient, opts)
 	bridgeMetricsCollector.Register(c)
 	defer bridgeMetricsCollector.Unregister(c)
 
 	return convertToGRPCErr(c.Run())
 }
 
-func (s *GRPCServer) handleRegisterMessage(msg *cvmsgspb.RegisterVizierRequest, srv vzconnpb.VZConnService_NATSBridgeServer) error {
+// negotiateStreamOptions picks the stream settings which both the vizier and the cloud support.
+func negotiateStreamOptions(msg *cvmsgspb.RegisterVizierRequest) StreamOptions {
+	opts := StreamOptions{
+		OutboxAcks:  msg.C2VOutboxAcks,
+		Compression: bridgeutils.NegotiateCompression(msg.SupportedCompression),
+	}
+	// Viziers which don't grant credits don't expect to be granted any either.
+	if msg.C2VCredits > 0 {
+		opts.C2VCredits = msg.C2VCredits
+		opts.V2CCredits = v2cCredits
+	}
+	return opts
+}
+
+func (s *GRPCServer) handleRegisterMessage(msg *cvmsgspb.RegisterVizierRequest, srv vzconnpb.VZConnService_NATSBridgeServer) (StreamOptions, error) {
 	vzID := utils2.UUIDFromProtoOrNil(msg.VizierID)
 
 	log.WithField("VizierID", vzID.String()).
@@ -138,18 +154,22 @@ func (s *GRPCServer) handleRegisterMessage(msg *cvmsgspb.RegisterVizierRequest,
 
 	serviceAuthToken, err := getClusterCredentials(viper.GetString("jwt_signing_key"), vzID)
 	if err != nil {
-		return err
+		return StreamOptions{}, err
 	}
 	ctx := metadata.AppendToOutgoingContext(srv.Context(), "authorization",
 		fmt.Sprintf("bearer %s", serviceAuthToken))
 	vzmgrResp, err := s.vzmgrClient.VizierConnected(ctx, msg)
 	if err != nil {
-		return err
+		return StreamOptions{}, err
 	}
 
+	opts := negotiateStreamOptions(msg)
+	vzmgrResp.Compression = opts.Compression
+	vzmgrResp.V2CCredits = opts.V2CCredits
+
 	var respAsAny *types.Any
 	if respAsAny, err = types.MarshalAny(vzmgrResp); err != nil {
-		return err
+		return StreamOptions{}, err
 	}
 
 	sendErr := srv.Send(&vzconnpb.C2VBridgeMessage{
@@ -158,12 +178,12 @@ func (s *GRPCServer) handleRegisterMessage(msg *cvmsgspb.RegisterVizierRequest,
 	})
 	// If registration failed it's an error and we should destroy the stream processor.
 	if vzmgrResp.Status == cvmsgspb.ST_OK {
-		return sendErr
+		return opts, sendErr
 	}
 	if vzmgrResp.Status == cvmsgspb.ST_FAILED_NOT_FOUND {
-		return ErrRegistrationFailedNotFound
+		return StreamOptions{}, ErrRegistrationFailedNotFound
 	}
-	return ErrRegistrationFailedUnknown
+	return StreamOptions{}, ErrRegistrationFailedUnknown
 }
 
 func convertToGRPCErr(err error) error {
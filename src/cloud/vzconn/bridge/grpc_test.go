@@ -22,7 +22,9 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/proto"
@@ -44,6 +46,7 @@ import (
 	"px.dev/pixie/src/cloud/vzconn/vzconnpb"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	mock_vzmgrpb "px.dev/pixie/src/cloud/vzmgr/vzmgrpb/mock"
+	"px.dev/pixie/src/shared/bridgeutils"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/services/msgbus"
 	"px.dev/pixie/src/utils"
@@ -166,6 +169,10 @@ func TestNATSGRPCBridgeHandshakeTest_CorrectRegistration(t *testing.T) {
 	ts.mockVZMgr.EXPECT().
 		VizierConnected(gomock.Any(), regReq).
 		Return(&cvmsgspb.RegisterVizierAck{Status: cvmsgspb.ST_OK}, nil)
+	ts.mockVZMgr.EXPECT().
+		GetC2VOutbox(gomock.Any(), gomock.Any()).
+		Return(&vzmgrpb.GetC2VOutboxResponse{}, nil).
+		AnyTimes()
 
 	// Make some GRPC Requests.
 	ctx := context.Background()
@@ -282,15 +289,26 @@ func TestNATSGRPCBridgeHandshakeTest_MalformedRegister(t *testing.T) {
 }
 
 func registerVizier(ts *testState, vizierID uuid.UUID, stream vzconnpb.VZConnService_NATSBridgeClient, readCh chan readMsgWrapper) {
+	registerVizierWithOutbox(ts, vizierID, stream, readCh, true, nil)
+}
+
+func registerVizierWithOutbox(ts *testState, vizierID uuid.UUID, stream vzconnpb.VZConnService_NATSBridgeClient, readCh chan readMsgWrapper,
+	outboxAcks bool, outbox []*vzmgrpb.C2VOutboxMessage) {
 	regReq := &cvmsgspb.RegisterVizierRequest{
-		VizierID: utils.ProtoFromUUIDStrOrNil(vizierID.String()),
-		JwtKey:   "123",
-		Address:  "123:123",
+		VizierID:      utils.ProtoFromUUIDStrOrNil(vizierID.String()),
+		JwtKey:        "123",
+		Address:       "123:123",
+		C2VOutboxAcks: outboxAcks,
 	}
 
 	ts.mockVZMgr.EXPECT().
 		VizierConnected(gomock.Any(), regReq).
 		Return(&cvmsgspb.RegisterVizierAck{Status: cvmsgspb.ST_OK}, nil)
+	ts.mockVZMgr.EXPECT().
+		GetC2VOutbox(gomock.Any(), &vzmgrpb.GetC2VOutboxRequest{
+			VizierID: utils.ProtoFromUUIDStrOrNil(vizierID.String()),
+		}).
+		Return(&vzmgrpb.GetC2VOutboxResponse{Messages: outbox}, nil)
 
 	err := stream.Send(&vzconnpb.V2CBridgeMessage{
 		Topic:     "register",
@@ -361,6 +379,215 @@ func TestNATSGRPCBridge_BridgingTest(t *testing.T) {
 	assert.Equal(t, expectedMsg, msg)
 }
 
+func TestNATSGRPCBridge_OutboxReplay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ts, cleanup := createTestState(t, ctrl)
+	defer cleanup(t)
+
+	ctx := context.Background()
+	client := vzconnpb.NewVZConnServiceClient(ts.conn)
+	stream, err := client.NATSBridge(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readCh := grpcReader(stream)
+	vizierID := uuid.Must(uuid.NewV4())
+	update := convertToAny(&cvmsgspb.CronScriptUpdate{RequestID: "1"})
+	registerVizierWithOutbox(ts, vizierID, stream, readCh, true, []*vzmgrpb.C2VOutboxMessage{
+		{SeqID: 4, Topic: "CronScriptsUpdates", Msg: update},
+		{SeqID: 7, Topic: "VizierUpdate", Msg: update},
+	})
+
+	// The outbox is replayed after registration.
+	for _, seqID := range []int64{4, 7} {
+		m := <-readCh
+		require.NoError(t, m.err)
+		assert.Equal(t, seqID, m.msg.SeqID)
+		assert.Equal(t, update, m.msg.Msg)
+	}
+
+	// Live messages which were already replayed are dropped. Messages can commit out of order, so a message
+	// with a lower sequence number than the replayed ones may only be seen live.
+	for _, seqID := range []int64{7, 5, 8} {
+		b, err := (&cvmsgspb.C2VMessage{
+			VizierID: vizierID.String(),
+			Msg:      update,
+			SeqID:    seqID,
+		}).Marshal()
+		require.NoError(t, err)
+		require.NoError(t, ts.nc.Publish(vzshard.C2VTopic("CronScriptsUpdates", vizierID), b))
+	}
+	for _, seqID := range []int64{5, 8} {
+		m := <-readCh
+		require.NoError(t, m.err)
+		assert.Equal(t, seqID, m.msg.SeqID)
+		assert.Equal(t, "CronScriptsUpdates", m.msg.Topic)
+	}
+
+	ackCh := make(chan []int64, 1)
+	ts.mockVZMgr.EXPECT().
+		AckC2VMessages(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req *vzmgrpb.AckC2VMessagesRequest, opts ...grpc.CallOption) (*types.Empty, error) {
+			assert.Equal(t, vizierID, utils.UUIDFromProtoOrNil(req.VizierID))
+			ackCh <- req.SeqIDs
+			return &types.Empty{}, nil
+		})
+	err = stream.Send(&vzconnpb.V2CBridgeMessage{
+		Topic: "c2vOutboxAck",
+		Msg:   convertToAny(&cvmsgspb.C2VOutboxAck{SeqIDs: []int64{4, 5, 7, 8}}),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{4, 5, 7, 8}, <-ackCh)
+}
+
+func TestNATSGRPCBridge_OutboxAcksOnBehalfOfVizier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ts, cleanup := createTestState(t, ctrl)
+	defer cleanup(t)
+
+	ctx := context.Background()
+	client := vzconnpb.NewVZConnServiceClient(ts.conn)
+	stream, err := client.NATSBridge(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readCh := grpcReader(stream)
+	vizierID := uuid.Must(uuid.NewV4())
+	registerVizierWithOutbox(ts, vizierID, stream, readCh, false, nil)
+
+	ackCh := make(chan []int64, 2)
+	ts.mockVZMgr.EXPECT().
+		AckC2VMessages(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req *vzmgrpb.AckC2VMessagesRequest, opts ...grpc.CallOption) (*types.Empty, error) {
+			ackCh <- req.SeqIDs
+			return &types.Empty{}, nil
+		}).
+		MinTimes(1).
+		MaxTimes(2)
+
+	update := convertToAny(&cvmsgspb.CronScriptUpdate{RequestID: "1"})
+	for _, seqID := range []int64{1, 2} {
+		b, err := (&cvmsgspb.C2VMessage{
+			VizierID: vizierID.String(),
+			Msg:      update,
+			SeqID:    seqID,
+		}).Marshal()
+		require.NoError(t, err)
+		require.NoError(t, ts.nc.Publish(vzshard.C2VTopic("CronScriptsUpdates", vizierID), b))
+	}
+	for _, seqID := range []int64{1, 2} {
+		m := <-readCh
+		require.NoError(t, m.err)
+		assert.Equal(t, seqID, m.msg.SeqID)
+	}
+
+	// The messages are acknowledged together, unless the first batch was flushed in between them.
+	var acked []int64
+	for len(acked) < 2 {
+		acked = append(acked, <-ackCh...)
+	}
+	assert.Equal(t, []int64{1, 2}, acked)
+}
+
+func TestNATSGRPCBridge_CompressionAndFlowControl(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ts, cleanup := createTestState(t, ctrl)
+	defer cleanup(t)
+
+	ctx := context.Background()
+	client := vzconnpb.NewVZConnServiceClient(ts.conn)
+	stream, err := client.NATSBridge(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readCh := grpcReader(stream)
+	vizierID := uuid.Must(uuid.NewV4())
+	regReq := &cvmsgspb.RegisterVizierRequest{
+		VizierID:             utils.ProtoFromUUIDStrOrNil(vizierID.String()),
+		JwtKey:               "123",
+		Address:              "123:123",
+		C2VOutboxAcks:        true,
+		SupportedCompression: []cvmsgspb.BridgeCompression{cvmsgspb.BRIDGE_COMPRESSION_SNAPPY},
+		C2VCredits:           2,
+	}
+	ts.mockVZMgr.EXPECT().
+		VizierConnected(gomock.Any(), regReq).
+		Return(&cvmsgspb.RegisterVizierAck{Status: cvmsgspb.ST_OK}, nil)
+	ts.mockVZMgr.EXPECT().
+		GetC2VOutbox(gomock.Any(), gomock.Any()).
+		Return(&vzmgrpb.GetC2VOutboxResponse{}, nil)
+
+	err = stream.Send(&vzconnpb.V2CBridgeMessage{
+		Topic: "register",
+		Msg:   convertToAny(regReq),
+	})
+	require.NoError(t, err)
+
+	m := <-readCh
+	require.NoError(t, m.err)
+	ack := &cvmsgspb.RegisterVizierAck{}
+	require.NoError(t, types.UnmarshalAny(m.msg.Msg, ack))
+	assert.Equal(t, cvmsgspb.BRIDGE_COMPRESSION_SNAPPY, ack.Compression)
+	assert.Greater(t, ack.V2CCredits, int64(0))
+
+	// Compressed messages from the vizier are decompressed before they are published.
+	t1Ch := make(chan *nats.Msg, 10)
+	sub, err := ts.nc.ChanSubscribe(vzshard.V2CTopic("t1", vizierID), t1Ch)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	largeMsg := convertToAny(&cvmsgspb.CronScriptUpdate{RequestID: strings.Repeat("a", 4096)})
+	compressed, compression := bridgeutils.CompressMsg(largeMsg, cvmsgspb.BRIDGE_COMPRESSION_SNAPPY)
+	require.Equal(t, cvmsgspb.BRIDGE_COMPRESSION_SNAPPY, compression)
+	err = stream.Send(&vzconnpb.V2CBridgeMessage{
+		Topic:       "t1",
+		Msg:         compressed,
+		Compression: compression,
+	})
+	require.NoError(t, err)
+
+	natsMsg := <-t1Ch
+	v2cMsg := &cvmsgspb.V2CMessage{}
+	require.NoError(t, v2cMsg.Unmarshal(natsMsg.Data))
+	assert.Equal(t, largeMsg, v2cMsg.Msg)
+
+	// Only as many messages as the vizier granted credits for are sent to it.
+	for i := 0; i < 3; i++ {
+		b, err := (&cvmsgspb.C2VMessage{
+			VizierID: vizierID.String(),
+			Msg:      largeMsg,
+		}).Marshal()
+		require.NoError(t, err)
+		require.NoError(t, ts.nc.Publish(vzshard.C2VTopic("t2", vizierID), b))
+	}
+	for i := 0; i < 2; i++ {
+		m := <-readCh
+		require.NoError(t, m.err)
+		assert.Equal(t, "t2", m.msg.Topic)
+		assert.Equal(t, cvmsgspb.BRIDGE_COMPRESSION_SNAPPY, m.msg.Compression)
+		decompressed, err := bridgeutils.DecompressMsg(m.msg.Msg, m.msg.Compression)
+		require.NoError(t, err)
+		assert.Equal(t, largeMsg, decompressed)
+	}
+	select {
+	case m := <-readCh:
+		t.Fatalf("Unexpected message sent without credits: %v", m.msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	err = stream.Send(&vzconnpb.V2CBridgeMessage{
+		Topic: "bridgeCredits",
+		Msg:   convertToAny(&cvmsgspb.BridgeCreditGrant{Credits: 1}),
+	})
+	require.NoError(t, err)
+	m = <-readCh
+	require.NoError(t, m.err)
+	assert.Equal(t, "t2", m.msg.Topic)
+}
+
 func TestNATSGRPCBridge_RegisterVizierDeployment(t *testing.T) {
 	vizierID := uuid.Must(uuid.NewV4())
 	ctrl := gomock.NewController(t)
@@ -38,6 +38,16 @@ var (
 		Buckets: msgHistBuckets,
 	}, []string{"kind"})
 
+	cloudToVizierOutboxReplayCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "cloud_to_vizier_outbox_replay_count",
+		Help: "Number of outbox messages from cloud to vizier which were replayed when the vizier connected.",
+	})
+
+	cloudToVizierCreditWaitCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "cloud_to_vizier_credit_wait_count",
+		Help: "Number of times messages from cloud to vizier waited for the vizier to grant more credits.",
+	})
+
 	vizierToCloudMsgCount = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "vizier_to_cloud_msg_count",
 		Help: "Number of messages from vizier to cloud.",
@@ -78,6 +88,8 @@ var (
 func init() {
 	prometheus.MustRegister(cloudToVizierMsgCount)
 	prometheus.MustRegister(cloudToVizierMsgSizeDist)
+	prometheus.MustRegister(cloudToVizierOutboxReplayCount)
+	prometheus.MustRegister(cloudToVizierCreditWaitCount)
 
 	prometheus.MustRegister(vizierToCloudMsgCount)
 	prometheus.MustRegister(vizierToCloudMsgSizeDist)
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/jmoiron/sqlx"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/shared/vzshard"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+)
+
+// c2vOutboxMessageTTL is how long a message stays in a vizier's outbox before it is dropped, if the
+// vizier never acknowledges it.
+const c2vOutboxMessageTTL = 7 * 24 * time.Hour
+
+// enqueueC2VMessage adds the message to the vizier's outbox, and publishes it on the vizier's c2v topic
+// so that it is delivered right away if the vizier is connected.
+func enqueueC2VMessage(db *sqlx.DB, nc *nats.Conn, vizierID uuid.UUID, topic string, msg *types.Any) (int64, error) {
+	b, err := msg.Marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	var seqID int64
+	query := `INSERT INTO vizier_c2v_outbox (vizier_cluster_id, topic, msg) VALUES ($1, $2, $3) RETURNING seq_id`
+	err = db.QueryRowx(query, vizierID, topic, b).Scan(&seqID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = db.Exec(`DELETE FROM vizier_c2v_outbox WHERE vizier_cluster_id=$1 AND created_at < $2`,
+		vizierID, time.Now().Add(-c2vOutboxMessageTTL))
+	if err != nil {
+		log.WithError(err).WithField("vizierID", vizierID).Error("Failed to drop expired outbox messages")
+	}
+
+	if nc == nil {
+		return seqID, nil
+	}
+	wrappedMsg := &cvmsgspb.C2VMessage{
+		VizierID: vizierID.String(),
+		Msg:      msg,
+		SeqID:    seqID,
+	}
+	wb, err := wrappedMsg.Marshal()
+	if err != nil {
+		return seqID, nil
+	}
+	// The message is already in the outbox, so it is delivered when the vizier reconnects even if this fails.
+	err = nc.Publish(vzshard.C2VTopic(topic, vizierID), wb)
+	if err != nil {
+		log.WithError(err).WithField("vizierID", vizierID).Error("Could not publish outbox message to nats")
+	}
+	return seqID, nil
+}
+
+// EnqueueC2VMessage adds a message to the vizier's cloud-to-vizier outbox.
+func (s *Server) EnqueueC2VMessage(ctx context.Context, req *vzmgrpb.EnqueueC2VMessageRequest) (*vzmgrpb.EnqueueC2VMessageResponse, error) {
+	vizierID := utils.UUIDFromProtoOrNil(req.VizierID)
+	if vizierID == uuid.Nil {
+		return nil, status.Error(codes.InvalidArgument, "vizier ID must be specified")
+	}
+	if req.Topic == "" || req.Msg == nil {
+		return nil, status.Error(codes.InvalidArgument, "topic and message must be specified")
+	}
+
+	seqID, err := enqueueC2VMessage(s.db, s.nc, vizierID, req.Topic, req.Msg)
+	if err != nil {
+		log.WithError(err).WithField("vizierID", vizierID).Error("Failed to enqueue outbox message")
+		return nil, status.Error(codes.Internal, "failed to enqueue message")
+	}
+	return &vzmgrpb.EnqueueC2VMessageResponse{
+		SeqID: seqID,
+	}, nil
+}
+
+// GetC2VOutbox fetches the messages in the vizier's outbox which have not been acknowledged.
+func (s *Server) GetC2VOutbox(ctx context.Context, req *vzmgrpb.GetC2VOutboxRequest) (*vzmgrpb.GetC2VOutboxResponse, error) {
+	vizierID := utils.UUIDFromProtoOrNil(req.VizierID)
+	query := `SELECT seq_id, topic, msg, created_at FROM vizier_c2v_outbox
+              WHERE vizier_cluster_id=$1 AND seq_id > $2 AND created_at >= $3 ORDER BY seq_id`
+	rows, err := s.db.Queryx(query, vizierID, req.AfterSeqID, time.Now().Add(-c2vOutboxMessageTTL))
+	if err != nil {
+		log.WithError(err).WithField("vizierID", vizierID).Error("Failed to fetch outbox messages")
+		return nil, status.Error(codes.Internal, "failed to fetch outbox")
+	}
+	defer rows.Close()
+
+	resp := &vzmgrpb.GetC2VOutboxResponse{
+		Messages: make([]*vzmgrpb.C2VOutboxMessage, 0),
+	}
+	for rows.Next() {
+		var seqID int64
+		var topic string
+		var b []byte
+		var createdAt time.Time
+		if err := rows.Scan(&seqID, &topic, &b, &createdAt); err != nil {
+			return nil, status.Error(codes.Internal, "failed to read outbox")
+		}
+		msg := &types.Any{}
+		if err := msg.Unmarshal(b); err != nil {
+			log.WithError(err).WithField("seqID", seqID).Error("Dropping malformed outbox message")
+			continue
+		}
+		ts, _ := types.TimestampProto(createdAt)
+		resp.Messages = append(resp.Messages, &vzmgrpb.C2VOutboxMessage{
+			SeqID:     seqID,
+			Topic:     topic,
+			Msg:       msg,
+			CreatedAt: ts,
+		})
+	}
+	return resp, nil
+}
+
+// AckC2VMessages removes the acknowledged messages from the vizier's outbox.
+func (s *Server) AckC2VMessages(ctx context.Context, req *vzmgrpb.AckC2VMessagesRequest) (*types.Empty, error) {
+	if len(req.SeqIDs) == 0 {
+		return &types.Empty{}, nil
+	}
+	vizierID := utils.UUIDFromProtoOrNil(req.VizierID)
+	query, args, err := sqlx.In(`DELETE FROM vizier_c2v_outbox WHERE vizier_cluster_id=? AND seq_id IN (?)`, vizierID, req.SeqIDs)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid sequence IDs")
+	}
+	_, err = s.db.Exec(s.db.Rebind(query), args...)
+	if err != nil {
+		log.WithError(err).WithField("vizierID", vizierID).Error("Failed to ack outbox messages")
+		return nil, status.Error(codes.Internal, "failed to ack messages")
+	}
+	return &types.Empty{}, nil
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/vzmgr/controllers"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+)
+
+func TestServer_C2VOutbox(t *testing.T) {
+	mustLoadTestData(db)
+
+	s := controllers.New(db, "test", nil, nil, nil)
+	vzID := utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001")
+	otherVzID := utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440002")
+
+	msg, err := types.MarshalAny(&cvmsgspb.CronScriptUpdate{RequestID: "1"})
+	require.NoError(t, err)
+
+	var seqIDs []int64
+	for _, id := range []*uuidpb.UUID{vzID, vzID, otherVzID, vzID} {
+		resp, err := s.EnqueueC2VMessage(CreateTestContext(), &vzmgrpb.EnqueueC2VMessageRequest{
+			VizierID: id,
+			Topic:    "CronScriptUpdates",
+			Msg:      msg,
+		})
+		require.NoError(t, err)
+		seqIDs = append(seqIDs, resp.SeqID)
+	}
+	assert.True(t, seqIDs[0] < seqIDs[1])
+	assert.True(t, seqIDs[1] < seqIDs[3])
+
+	outbox, err := s.GetC2VOutbox(CreateTestContext(), &vzmgrpb.GetC2VOutboxRequest{VizierID: vzID})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(outbox.Messages))
+	assert.Equal(t, []int64{seqIDs[0], seqIDs[1], seqIDs[3]},
+		[]int64{outbox.Messages[0].SeqID, outbox.Messages[1].SeqID, outbox.Messages[2].SeqID})
+	assert.Equal(t, "CronScriptUpdates", outbox.Messages[0].Topic)
+	assert.Equal(t, msg, outbox.Messages[0].Msg)
+
+	outbox, err = s.GetC2VOutbox(CreateTestContext(), &vzmgrpb.GetC2VOutboxRequest{VizierID: vzID, AfterSeqID: seqIDs[1]})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(outbox.Messages))
+	assert.Equal(t, seqIDs[3], outbox.Messages[0].SeqID)
+
+	_, err = s.AckC2VMessages(CreateTestContext(), &vzmgrpb.AckC2VMessagesRequest{
+		VizierID: vzID,
+		SeqIDs:   []int64{seqIDs[0], seqIDs[3]},
+	})
+	require.NoError(t, err)
+
+	outbox, err = s.GetC2VOutbox(CreateTestContext(), &vzmgrpb.GetC2VOutboxRequest{VizierID: vzID})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(outbox.Messages))
+	assert.Equal(t, seqIDs[1], outbox.Messages[0].SeqID)
+
+	// Acks only apply to the vizier's own outbox.
+	_, err = s.AckC2VMessages(CreateTestContext(), &vzmgrpb.AckC2VMessagesRequest{
+		VizierID: vzID,
+		SeqIDs:   []int64{seqIDs[2]},
+	})
+	require.NoError(t, err)
+	outbox, err = s.GetC2VOutbox(CreateTestContext(), &vzmgrpb.GetC2VOutboxRequest{VizierID: otherVzID})
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(outbox.Messages))
+
+	t.Run("missing topic", func(t *testing.T) {
+		_, err := s.EnqueueC2VMessage(CreateTestContext(), &vzmgrpb.EnqueueC2VMessageRequest{
+			VizierID: vzID,
+			Msg:      msg,
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
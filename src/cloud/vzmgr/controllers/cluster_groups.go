@@ -0,0 +1,267 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/jackc/pgx"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/shared/labelselector"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/utils"
+)
+
+// Code for `unique_violation`, see https://www.postgresql.org/docs/current/errcodes-appendix.html.
+const uniqueViolation = "23505"
+
+// ClusterGroup is a named group of clusters, defined by a label selector.
+type ClusterGroup struct {
+	ID       uuid.UUID `db:"id"`
+	OrgID    uuid.UUID `db:"org_id"`
+	Name     string    `db:"name"`
+	Selector string    `db:"selector"`
+}
+
+func (g *ClusterGroup) toProto() *vzmgrpb.ClusterGroup {
+	return &vzmgrpb.ClusterGroup{
+		ID:       utils.ProtoFromUUID(g.ID),
+		OrgID:    utils.ProtoFromUUID(g.OrgID),
+		Name:     g.Name,
+		Selector: g.Selector,
+	}
+}
+
+// fetchVizierLabels returns the labels attached to each of the given viziers.
+func fetchVizierLabels(db *sqlx.DB, ids []uuid.UUID) (map[uuid.UUID]map[string]string, error) {
+	labels := make(map[uuid.UUID]map[string]string)
+	if len(ids) == 0 {
+		return labels, nil
+	}
+
+	query, args, err := sqlx.In(`SELECT vizier_cluster_id, key, value FROM vizier_cluster_labels WHERE vizier_cluster_id IN (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Queryx(db.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var key, value string
+		if err := rows.Scan(&id, &key, &value); err != nil {
+			return nil, err
+		}
+		if _, ok := labels[id]; !ok {
+			labels[id] = make(map[string]string)
+		}
+		labels[id][key] = value
+	}
+	return labels, nil
+}
+
+// GetViziersBySelector gets the IDs of all viziers in the org which match the given label selector.
+func (s *Server) GetViziersBySelector(ctx context.Context, req *vzmgrpb.GetViziersBySelectorRequest) (*vzmgrpb.GetViziersByOrgResponse, error) {
+	if err := validateOrgID(ctx, req.OrgID); err != nil {
+		return nil, err
+	}
+	sel, err := labelselector.Parse(req.Selector)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid selector: %s", err.Error())
+	}
+
+	query := `SELECT c.id, l.key, l.value FROM vizier_cluster AS c
+              LEFT JOIN vizier_cluster_labels AS l ON c.id=l.vizier_cluster_id
+              WHERE c.org_id=$1 ORDER BY c.id`
+	rows, err := s.db.Queryx(query, utils.UUIDFromProtoOrNil(req.OrgID))
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch vizier labels")
+		return nil, status.Error(codes.Internal, "failed to fetch viziers")
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	labels := make(map[uuid.UUID]map[string]string)
+	for rows.Next() {
+		var id uuid.UUID
+		var key, value sql.NullString
+		if err := rows.Scan(&id, &key, &value); err != nil {
+			return nil, status.Error(codes.Internal, "failed to read viziers")
+		}
+		if _, ok := labels[id]; !ok {
+			labels[id] = make(map[string]string)
+			ids = append(ids, id)
+		}
+		if key.Valid {
+			labels[id][key.String] = value.String
+		}
+	}
+
+	vizierIDs := []*uuidpb.UUID{}
+	for _, id := range ids {
+		if sel.Matches(labels[id]) {
+			vizierIDs = append(vizierIDs, utils.ProtoFromUUID(id))
+		}
+	}
+	return &vzmgrpb.GetViziersByOrgResponse{VizierIDs: vizierIDs}, nil
+}
+
+// UpdateVizierLabels replaces the labels attached to the given vizier.
+func (s *Server) UpdateVizierLabels(ctx context.Context, req *vzmgrpb.UpdateVizierLabelsRequest) (*types.Empty, error) {
+	if err := s.validateOrgOwnsCluster(ctx, req.VizierID); err != nil {
+		return nil, err
+	}
+	if err := labelselector.ValidateLabels(req.Labels); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	vizierID := utils.UUIDFromProtoOrNil(req.VizierID)
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to update labels")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM vizier_cluster_labels WHERE vizier_cluster_id=$1`, vizierID)
+	if err != nil {
+		log.WithError(err).Error("Failed to delete vizier labels")
+		return nil, status.Error(codes.Internal, "failed to update labels")
+	}
+	for k, v := range req.Labels {
+		_, err = tx.Exec(`INSERT INTO vizier_cluster_labels(vizier_cluster_id, key, value) VALUES ($1, $2, $3)`, vizierID, k, v)
+		if err != nil {
+			log.WithError(err).Error("Failed to insert vizier label")
+			return nil, status.Error(codes.Internal, "failed to update labels")
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Internal, "failed to update labels")
+	}
+	return &types.Empty{}, nil
+}
+
+// GetClusterGroups fetches all cluster groups in the given org.
+func (s *Server) GetClusterGroups(ctx context.Context, orgID *uuidpb.UUID) (*vzmgrpb.GetClusterGroupsResponse, error) {
+	if err := validateOrgID(ctx, orgID); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, org_id, name, selector FROM vizier_cluster_groups WHERE org_id=$1 ORDER BY name`
+	rows, err := s.db.Queryx(query, utils.UUIDFromProtoOrNil(orgID))
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch cluster groups")
+		return nil, status.Error(codes.Internal, "failed to fetch cluster groups")
+	}
+	defer rows.Close()
+
+	resp := &vzmgrpb.GetClusterGroupsResponse{}
+	for rows.Next() {
+		var g ClusterGroup
+		if err := rows.StructScan(&g); err != nil {
+			return nil, status.Error(codes.Internal, "failed to read cluster groups")
+		}
+		resp.Groups = append(resp.Groups, g.toProto())
+	}
+	return resp, nil
+}
+
+func validateClusterGroup(req *vzmgrpb.ClusterGroup) (string, error) {
+	if req.Name == "" {
+		return "", status.Error(codes.InvalidArgument, "cluster group name cannot be empty")
+	}
+	sel, err := labelselector.Parse(req.Selector)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid selector: %s", err.Error())
+	}
+	return sel.String(), nil
+}
+
+// CreateClusterGroup creates a new cluster group in the org.
+func (s *Server) CreateClusterGroup(ctx context.Context, req *vzmgrpb.ClusterGroup) (*vzmgrpb.ClusterGroup, error) {
+	if err := validateOrgID(ctx, req.OrgID); err != nil {
+		return nil, err
+	}
+	selector, err := validateClusterGroup(req)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO vizier_cluster_groups(org_id, name, selector) VALUES ($1, $2, $3) RETURNING id, org_id, name, selector`
+	var g ClusterGroup
+	err = s.db.QueryRowx(query, utils.UUIDFromProtoOrNil(req.OrgID), req.Name, selector).StructScan(&g)
+	if err != nil {
+		if pgErr, ok := err.(pgx.PgError); ok && pgErr.Code == uniqueViolation {
+			return nil, status.Errorf(codes.AlreadyExists, "cluster group %s already exists", req.Name)
+		}
+		log.WithError(err).Error("Failed to create cluster group")
+		return nil, status.Error(codes.Internal, "failed to create cluster group")
+	}
+	return g.toProto(), nil
+}
+
+// UpdateClusterGroup updates the selector of an existing cluster group.
+func (s *Server) UpdateClusterGroup(ctx context.Context, req *vzmgrpb.ClusterGroup) (*vzmgrpb.ClusterGroup, error) {
+	if err := validateOrgID(ctx, req.OrgID); err != nil {
+		return nil, err
+	}
+	selector, err := validateClusterGroup(req)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE vizier_cluster_groups SET selector=$1 WHERE org_id=$2 AND name=$3 RETURNING id, org_id, name, selector`
+	var g ClusterGroup
+	err = s.db.QueryRowx(query, selector, utils.UUIDFromProtoOrNil(req.OrgID), req.Name).StructScan(&g)
+	if err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "cluster group %s not found", req.Name)
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to update cluster group")
+		return nil, status.Error(codes.Internal, "failed to update cluster group")
+	}
+	return g.toProto(), nil
+}
+
+// DeleteClusterGroup deletes the named cluster group from the org.
+func (s *Server) DeleteClusterGroup(ctx context.Context, req *vzmgrpb.DeleteClusterGroupRequest) (*types.Empty, error) {
+	if err := validateOrgID(ctx, req.OrgID); err != nil {
+		return nil, err
+	}
+
+	res, err := s.db.Exec(`DELETE FROM vizier_cluster_groups WHERE org_id=$1 AND name=$2`, utils.UUIDFromProtoOrNil(req.OrgID), req.Name)
+	if err != nil {
+		log.WithError(err).Error("Failed to delete cluster group")
+		return nil, status.Error(codes.Internal, "failed to delete cluster group")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, status.Errorf(codes.NotFound, "cluster group %s not found", req.Name)
+	}
+	return &types.Empty{}, nil
+}
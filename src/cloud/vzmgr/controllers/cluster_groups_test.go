@@ -0,0 +1,225 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/vzmgr/controllers"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/utils"
+)
+
+func TestServer_UpdateVizierLabels(t *testing.T) {
+	mustLoadTestData(db)
+
+	s := controllers.New(db, "test", nil, nil, nil)
+	vzID := utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001")
+
+	_, err := s.UpdateVizierLabels(CreateTestContext(), &vzmgrpb.UpdateVizierLabelsRequest{
+		VizierID: vzID,
+		Labels:   map[string]string{"env": "prod", "region": "eu"},
+	})
+	require.NoError(t, err)
+
+	resp, err := s.GetVizierInfo(CreateTestContext(), vzID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "region": "eu"}, resp.Labels)
+
+	// Updating the labels replaces the existing set.
+	_, err = s.UpdateVizierLabels(CreateTestContext(), &vzmgrpb.UpdateVizierLabelsRequest{
+		VizierID: vzID,
+		Labels:   map[string]string{"env": "staging"},
+	})
+	require.NoError(t, err)
+
+	infos, err := s.GetVizierInfos(CreateTestContext(), &vzmgrpb.GetVizierInfosRequest{
+		VizierIDs: []*uuidpb.UUID{vzID},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(infos.VizierInfos))
+	assert.Equal(t, map[string]string{"env": "staging"}, infos.VizierInfos[0].Labels)
+
+	t.Run("invalid label", func(t *testing.T) {
+		_, err := s.UpdateVizierLabels(CreateTestContext(), &vzmgrpb.UpdateVizierLabelsRequest{
+			VizierID: vzID,
+			Labels:   map[string]string{"env": "not valid"},
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("cluster in other org", func(t *testing.T) {
+		_, err := s.UpdateVizierLabels(CreateTestContext(), &vzmgrpb.UpdateVizierLabelsRequest{
+			VizierID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440003"),
+			Labels:   map[string]string{"env": "prod"},
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestServer_GetViziersBySelector(t *testing.T) {
+	mustLoadTestData(db)
+	insertLabel := `INSERT INTO vizier_cluster_labels(vizier_cluster_id, key, value) VALUES ($1, $2, $3)`
+	db.MustExec(insertLabel, "123e4567-e89b-12d3-a456-426655440000", "env", "prod")
+	db.MustExec(insertLabel, "123e4567-e89b-12d3-a456-426655440000", "region", "eu")
+	db.MustExec(insertLabel, "123e4567-e89b-12d3-a456-426655440001", "env", "prod")
+	db.MustExec(insertLabel, "123e4567-e89b-12d3-a456-426655440002", "env", "staging")
+	db.MustExec(insertLabel, "223e4567-e89b-12d3-a456-426655440003", "env", "prod")
+
+	s := controllers.New(db, "test", nil, nil, nil)
+
+	tests := []struct {
+		selector string
+		expected []string
+	}{
+		{
+			selector: "env=prod",
+			expected: []string{"123e4567-e89b-12d3-a456-426655440000", "123e4567-e89b-12d3-a456-426655440001"},
+		},
+		{
+			selector: "env=prod,region!=eu",
+			expected: []string{"123e4567-e89b-12d3-a456-426655440001"},
+		},
+		{
+			selector: "env,!region",
+			expected: []string{"123e4567-e89b-12d3-a456-426655440001", "123e4567-e89b-12d3-a456-426655440002"},
+		},
+		{
+			selector: "env=dev",
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.selector, func(t *testing.T) {
+			resp, err := s.GetViziersBySelector(CreateTestContext(), &vzmgrpb.GetViziersBySelectorRequest{
+				OrgID:    utils.ProtoFromUUIDStrOrNil(testAuthOrgID),
+				Selector: tc.selector,
+			})
+			require.NoError(t, err)
+			ids := []string{}
+			for _, id := range resp.VizierIDs {
+				ids = append(ids, utils.UUIDFromProtoOrNil(id).String())
+			}
+			assert.ElementsMatch(t, tc.expected, ids)
+		})
+	}
+
+	t.Run("empty selector", func(t *testing.T) {
+		resp, err := s.GetViziersBySelector(CreateTestContext(), &vzmgrpb.GetViziersBySelectorRequest{
+			OrgID: utils.ProtoFromUUIDStrOrNil(testAuthOrgID),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 6, len(resp.VizierIDs))
+	})
+
+	t.Run("invalid selector", func(t *testing.T) {
+		_, err := s.GetViziersBySelector(CreateTestContext(), &vzmgrpb.GetViziersBySelectorRequest{
+			OrgID:    utils.ProtoFromUUIDStrOrNil(testAuthOrgID),
+			Selector: "env=prod,",
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("mismatched org", func(t *testing.T) {
+		_, err := s.GetViziersBySelector(CreateTestContext(), &vzmgrpb.GetViziersBySelectorRequest{
+			OrgID:    utils.ProtoFromUUIDStrOrNil(testNonAuthOrgID),
+			Selector: "env=prod",
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestServer_ClusterGroups(t *testing.T) {
+	mustLoadTestData(db)
+	db.MustExec(`DELETE FROM vizier_cluster_groups`)
+
+	s := controllers.New(db, "test", nil, nil, nil)
+	orgID := utils.ProtoFromUUIDStrOrNil(testAuthOrgID)
+
+	g, err := s.CreateClusterGroup(CreateTestContext(), &vzmgrpb.ClusterGroup{
+		OrgID:    orgID,
+		Name:     "prod-eu",
+		Selector: "region=eu, env=prod",
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, g.ID)
+	assert.Equal(t, "prod-eu", g.Name)
+	assert.Equal(t, "env=prod,region=eu", g.Selector)
+
+	_, err = s.CreateClusterGroup(CreateTestContext(), &vzmgrpb.ClusterGroup{
+		OrgID:    orgID,
+		Name:     "prod-eu",
+		Selector: "env=prod",
+	})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = s.CreateClusterGroup(CreateTestContext(), &vzmgrpb.ClusterGroup{
+		OrgID:    orgID,
+		Name:     "staging",
+		Selector: "env=staging",
+	})
+	require.NoError(t, err)
+
+	_, err = s.CreateClusterGroup(CreateTestContext(), &vzmgrpb.ClusterGroup{
+		OrgID:    orgID,
+		Name:     "invalid",
+		Selector: "env=a b",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	updated, err := s.UpdateClusterGroup(CreateTestContext(), &vzmgrpb.ClusterGroup{
+		OrgID:    orgID,
+		Name:     "prod-eu",
+		Selector: "env=prod,region=eu-west",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, g.ID, updated.ID)
+	assert.Equal(t, "env=prod,region=eu-west", updated.Selector)
+
+	_, err = s.UpdateClusterGroup(CreateTestContext(), &vzmgrpb.ClusterGroup{
+		OrgID: orgID,
+		Name:  "missing",
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	groups, err := s.GetClusterGroups(CreateTestContext(), orgID)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(groups.Groups))
+	assert.Equal(t, updated, groups.Groups[0])
+	assert.Equal(t, "staging", groups.Groups[1].Name)
+
+	_, err = s.DeleteClusterGroup(CreateTestContext(), &vzmgrpb.DeleteClusterGroupRequest{OrgID: orgID, Name: "staging"})
+	require.NoError(t, err)
+	_, err = s.DeleteClusterGroup(CreateTestContext(), &vzmgrpb.DeleteClusterGroupRequest{OrgID: orgID, Name: "staging"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	groups, err = s.GetClusterGroups(CreateTestContext(), orgID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(groups.Groups))
+
+	_, err = s.GetClusterGroups(CreateTestContext(), utils.ProtoFromUUIDStrOrNil(testNonAuthOrgID))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
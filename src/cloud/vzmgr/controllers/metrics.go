@@ -35,11 +35,18 @@ var (
 			Name: "vizier_updated",
 			Help: "Number of viziers that were updated",
 		})
+
+	rolloutHaltedCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "vizier_rollout_halted",
+			Help: "Number of vizier rollouts that were halted because a wave did not become healthy",
+		})
 )
 
 func init() {
 	prometheus.MustRegister(missingUpdateCount)
 	prometheus.MustRegister(vizierUpdatedCounter)
+	prometheus.MustRegister(rolloutHaltedCounter)
 }
 
 const statusQuery = `
@@ -132,3 +139,66 @@ func (c *statusMetricsCollector) Collect(ch chan<- prometheus.Metric) {
 			s.ShardID, s.Status.Stringify())
 	}
 }
+
+const c2vOutboxDepthQuery = `
+SELECT
+  vizier_cluster_id,
+  COUNT(1) AS depth
+FROM
+  vizier_c2v_outbox
+GROUP BY
+  vizier_cluster_id;
+`
+
+type c2vOutboxMetricsCollector struct {
+	db        *sqlx.DB
+	depthDesc *prometheus.Desc
+}
+
+// NewC2VOutboxMetricsCollector creates a prometheus collector for the depth of each vizier's cloud-to-vizier outbox.
+// Only viziers with unacknowledged messages have a series, so the number of series is bounded by the viziers
+// which are behind rather than all of the viziers.
+func NewC2VOutboxMetricsCollector(db *sqlx.DB) prometheus.Collector {
+	return &c2vOutboxMetricsCollector{
+		db: db,
+		depthDesc: prometheus.NewDesc(
+			"vzmgr_c2v_outbox_depth",
+			"Number of cloud-to-vizier messages which the vizier has not acknowledged",
+			[]string{"vizier_id"},
+			nil),
+	}
+}
+
+// Describe implements Collector.
+func (c *c2vOutboxMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.depthDesc
+}
+
+// Collect implements Collector.
+func (c *c2vOutboxMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	rows, err := c.db.Queryx(c2vOutboxDepthQuery)
+	if err != nil {
+		log.WithError(err).Warn("Failed to run outbox depth query")
+		return
+	}
+
+	type depthInfo struct {
+		VizierID string `db:"vizier_cluster_id"`
+		Depth    int64  `db:"depth"`
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		d := &depthInfo{}
+		err := rows.StructScan(d)
+		if err != nil {
+			log.WithError(err).Warn("Failed to scan struct from postgres")
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.depthDesc,
+			prometheus.GaugeValue,
+			float64(d.Depth),
+			d.VizierID)
+	}
+}
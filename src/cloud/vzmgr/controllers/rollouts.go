@@ -0,0 +1,307 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/shared/labelselector"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/utils"
+)
+
+// The maximum number of rollouts returned by GetRollouts.
+const maxRollouts = 20
+
+func rolloutStateToProto(state string) vzmgrpb.RolloutState {
+	if v, ok := vzmgrpb.RolloutState_value["ROLLOUT_"+state]; ok {
+		return vzmgrpb.RolloutState(v)
+	}
+	return vzmgrpb.ROLLOUT_STATE_UNKNOWN
+}
+
+// GetRolloutPolicy fetches the auto-update rollout policy of an org.
+func (s *Server) GetRolloutPolicy(ctx context.Context, req *uuidpb.UUID) (*vzmgrpb.RolloutPolicy, error) {
+	if err := validateOrgID(ctx, req); err != nil {
+		return nil, err
+	}
+
+	policy, err := getRolloutPolicy(s.db, utils.UUIDFromProtoOrNil(req))
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch rollout policy")
+		return nil, status.Error(codes.Internal, "failed to fetch rollout policy")
+	}
+	if policy == nil {
+		return nil, status.Error(codes.NotFound, "org has no rollout policy")
+	}
+	return policy.toProto(), nil
+}
+
+func (s *Server) validateRolloutPolicy(ctx context.Context, req *vzmgrpb.RolloutPolicy) (RolloutWaves, error) {
+	if req.SoakTimeS < 0 || req.HealthDeadlineS < 0 {
+		return nil, status.Error(codes.InvalidArgument, "soak time and health deadline must not be negative")
+	}
+	if req.MaintenanceWindowStartMinute < 0 || req.MaintenanceWindowStartMinute >= minutesPerDay {
+		return nil, status.Errorf(codes.InvalidArgument, "maintenance window start must be between 0 and %d", minutesPerDay-1)
+	}
+	if req.MaintenanceWindowDurationMinutes < 0 || req.MaintenanceWindowDurationMinutes > minutesPerDay {
+		return nil, status.Errorf(codes.InvalidArgument, "maintenance window duration must be between 0 and %d", minutesPerDay)
+	}
+
+	waves := make(RolloutWaves, len(req.Waves))
+	for i, w := range req.Waves {
+		if w.Selector == "" && len(w.ClusterIDs) == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "wave %d must have a selector or cluster IDs", i)
+		}
+		sel, err := labelselector.Parse(w.Selector)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid selector for wave %d: %s", i, err.Error())
+		}
+		for _, id := range w.ClusterIDs {
+			if err := s.validateOrgOwnsCluster(ctx, id); err != nil {
+				return nil, err
+			}
+		}
+		waves[i] = &vzmgrpb.RolloutWave{
+			Selector:   sel.String(),
+			ClusterIDs: w.ClusterIDs,
+		}
+	}
+	return waves, nil
+}
+
+// UpdateRolloutPolicy creates or replaces the auto-update rollout policy of an org. Changes apply to
+// rollouts that start after the update, except for the timing settings which apply immediately.
+func (s *Server) UpdateRolloutPolicy(ctx context.Context, req *vzmgrpb.RolloutPolicy) (*vzmgrpb.RolloutPolicy, error) {
+	if err := validateOrgID(ctx, req.OrgID); err != nil {
+		return nil, err
+	}
+	waves, err := s.validateRolloutPolicy(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &RolloutPolicy{
+		OrgID:                            utils.UUIDFromProtoOrNil(req.OrgID),
+		Waves:                            waves,
+		SoakTimeS:                        req.SoakTimeS,
+		HealthDeadlineS:                  req.HealthDeadlineS,
+		MaintenanceWindowStartMinute:     req.MaintenanceWindowStartMinute,
+		MaintenanceWindowDurationMinutes: req.MaintenanceWindowDurationMinutes,
+	}
+	query := `INSERT INTO vizier_rollout_policies(org_id, waves, soak_time_s, health_deadline_s,
+              maintenance_window_start_minute, maintenance_window_duration_minutes)
+            VALUES (:org_id, :waves, :soak_time_s, :health_deadline_s, :maintenance_window_start_minute,
+              :maintenance_window_duration_minutes)
+            ON CONFLICT (org_id) DO UPDATE SET waves=EXCLUDED.waves, soak_time_s=EXCLUDED.soak_time_s,
+              health_deadline_s=EXCLUDED.health_deadline_s,
+              maintenance_window_start_minute=EXCLUDED.maintenance_window_start_minute,
+              maintenance_window_duration_minutes=EXCLUDED.maintenance_window_duration_minutes, updated_at=NOW()`
+	_, err = s.db.NamedExec(query, policy)
+	if err != nil {
+		log.WithError(err).Error("Failed to update rollout policy")
+		return nil, status.Error(codes.Internal, "failed to update rollout policy")
+	}
+	return policy.toProto(), nil
+}
+
+// DeleteRolloutPolicy deletes the rollout policy of an org. Unfinished rollouts are deleted with it,
+// and the org's viziers go back to being updated as soon as a new version is released.
+func (s *Server) DeleteRolloutPolicy(ctx context.Context, req *uuidpb.UUID) (*types.Empty, error) {
+	if err := validateOrgID(ctx, req); err != nil {
+		return nil, err
+	}
+	orgID := utils.UUIDFromProtoOrNil(req)
+
+	txn, err := s.db.Beginx()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete rollout policy")
+	}
+	defer txn.Rollback()
+
+	res, err := txn.Exec(`DELETE FROM vizier_rollout_policies WHERE org_id=$1`, orgID)
+	if err != nil {
+		log.WithError(err).Error("Failed to delete rollout policy")
+		return nil, status.Error(codes.Internal, "failed to delete rollout policy")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, status.Error(codes.NotFound, "org has no rollout policy")
+	}
+	_, err = txn.Exec(`DELETE FROM vizier_rollouts WHERE org_id=$1 AND state IN ('RUNNING', 'PAUSED', 'HALTED')`, orgID)
+	if err != nil {
+		log.WithError(err).Error("Failed to delete rollouts")
+		return nil, status.Error(codes.Internal, "failed to delete rollout policy")
+	}
+	if err := txn.Commit(); err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete rollout policy")
+	}
+	return &types.Empty{}, nil
+}
+
+// fetchRollouts fetches the most recent rollouts for an org, or a single rollout if rolloutID is set.
+func fetchRollouts(db *sqlx.DB, orgID uuid.UUID, rolloutID uuid.UUID) ([]*vzmgrpb.Rollout, error) {
+	query := `SELECT id, org_id, version, state, current_wave, num_waves, message, created_at, updated_at
+            FROM vizier_rollouts WHERE org_id=$1 AND ($2::uuid IS NULL OR id=$2) ORDER BY created_at DESC LIMIT $3`
+	var filter *uuid.UUID
+	if rolloutID != uuid.Nil {
+		filter = &rolloutID
+	}
+	var rows []rolloutRow
+	err := db.Select(&rows, query, orgID, filter, maxRollouts)
+	if err != nil {
+		return nil, err
+	}
+
+	rollouts := make([]*vzmgrpb.Rollout, len(rows))
+	rolloutMap := make(map[uuid.UUID]*vzmgrpb.Rollout)
+	ids := make([]uuid.UUID, len(rows))
+	for i, r := range rows {
+		createdAt, err := types.TimestampProto(r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		updatedAt, err := types.TimestampProto(r.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		rollouts[i] = &vzmgrpb.Rollout{
+			ID:          utils.ProtoFromUUID(r.ID),
+			OrgID:       utils.ProtoFromUUID(r.OrgID),
+			Version:     r.Version,
+			State:       rolloutStateToProto(r.State),
+			CurrentWave: int32(r.CurrentWave),
+			NumWaves:    int32(r.NumWaves),
+			Message:     r.Message,
+			CreatedAt:   createdAt,
+			UpdatedAt:   updatedAt,
+			Clusters:    make([]*vzmgrpb.RolloutCluster, 0),
+		}
+		rolloutMap[r.ID] = rollouts[i]
+		ids[i] = r.ID
+	}
+	if len(ids) == 0 {
+		return rollouts, nil
+	}
+
+	clusterQuery, args, err := sqlx.In(`SELECT c.rollout_id, c.vizier_cluster_id, c.wave,
+              i.status, i.vizier_version, r.version
+            FROM vizier_rollout_clusters AS c
+            JOIN vizier_rollouts AS r ON c.rollout_id=r.id
+            JOIN vizier_cluster_info AS i ON c.vizier_cluster_id=i.vizier_cluster_id
+            WHERE c.rollout_id IN (?) ORDER BY c.wave, c.vizier_cluster_id`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var clusters []struct {
+		RolloutID uuid.UUID `db:"rollout_id"`
+		VizierID  uuid.UUID `db:"vizier_cluster_id"`
+		Wave      int32     `db:"wave"`
+		Status    string    `db:"status"`
+		VzVersion string    `db:"vizier_version"`
+		Version   string    `db:"version"`
+	}
+	err = db.Select(&clusters, db.Rebind(clusterQuery), args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range clusters {
+		r := rolloutMap[c.RolloutID]
+		r.Clusters = append(r.Clusters, &vzmgrpb.RolloutCluster{
+			VizierID: utils.ProtoFromUUID(c.VizierID),
+			Wave:     c.Wave,
+			Updated:  c.Status == "HEALTHY" && versionsMatch(c.VzVersion, c.Version),
+		})
+	}
+	return rollouts, nil
+}
+
+// GetRollouts fetches the most recent auto-update rollouts of an org.
+func (s *Server) GetRollouts(ctx context.Context, req *uuidpb.UUID) (*vzmgrpb.GetRolloutsResponse, error) {
+	if err := validateOrgID(ctx, req); err != nil {
+		return nil, err
+	}
+
+	rollouts, err := fetchRollouts(s.db, utils.UUIDFromProtoOrNil(req), uuid.Nil)
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch rollouts")
+		return nil, status.Error(codes.Internal, "failed to fetch rollouts")
+	}
+	return &vzmgrpb.GetRolloutsResponse{Rollouts: rollouts}, nil
+}
+
+// setRolloutState moves a rollout from one of the given states into a new state.
+func (s *Server) setRolloutState(ctx context.Context, req *vzmgrpb.RolloutRequest, from []string, to string) (*vzmgrpb.Rollout, error) {
+	if err := validateOrgID(ctx, req.OrgID); err != nil {
+		return nil, err
+	}
+	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
+	rolloutID := utils.UUIDFromProtoOrNil(req.RolloutID)
+	if rolloutID == uuid.Nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid rollout id")
+	}
+
+	rollouts, err := fetchRollouts(s.db, orgID, rolloutID)
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch rollout")
+		return nil, status.Error(codes.Internal, "failed to fetch rollout")
+	}
+	if len(rollouts) == 0 {
+		return nil, status.Error(codes.NotFound, "rollout not found")
+	}
+
+	// Resuming a rollout restarts the health deadline and soak time of its current wave.
+	query, args, err := sqlx.In(`UPDATE vizier_rollouts SET state=?, message='', wave_started_at=NULL, soak_started_at=NULL,
+              updated_at=NOW() WHERE id=? AND org_id=? AND state IN (?)`, to, rolloutID, orgID, from)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to update rollout")
+	}
+	res, err := s.db.Exec(s.db.Rebind(query), args...)
+	if err != nil {
+		log.WithError(err).Error("Failed to update rollout")
+		return nil, status.Error(codes.Internal, "failed to update rollout")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "rollout is %s", rollouts[0].State.String())
+	}
+
+	rollouts, err = fetchRollouts(s.db, orgID, rolloutID)
+	if err != nil || len(rollouts) == 0 {
+		return nil, status.Error(codes.Internal, "failed to fetch rollout")
+	}
+	log.WithField("rolloutID", rolloutID).Infof("Vizier rollout is now %s", to)
+	return rollouts[0], nil
+}
+
+// PauseRollout pauses a running rollout, so that no further viziers are updated until it is resumed.
+func (s *Server) PauseRollout(ctx context.Context, req *vzmgrpb.RolloutRequest) (*vzmgrpb.Rollout, error) {
+	return s.setRolloutState(ctx, req, []string{"RUNNING"}, "PAUSED")
+}
+
+// ResumeRollout resumes a paused or halted rollout from its current wave.
+func (s *Server) ResumeRollout(ctx context.Context, req *vzmgrpb.RolloutRequest) (*vzmgrpb.Rollout, error) {
+	return s.setRolloutState(ctx, req, []string{"PAUSED", "HALTED"}, "RUNNING")
+}
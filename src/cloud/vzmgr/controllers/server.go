@@ -99,6 +99,7 @@ func New(db *sqlx.DB, dbKey string, dnsMgrClient dnsmgrpb.DNSMgrServiceClient, n
 	}
 
 	_ = prometheus.Register(NewStatusMetricsCollector(db))
+	_ = prometheus.Register(NewC2VOutboxMetricsCollector(db))
 
 	for _, shard := range vzshard.GenerateShardRange() {
 		s.startShardedHandler(shard, "heartbeat", s.HandleVizierHeartbeat)
@@ -399,9 +400,15 @@ func (s *Server) GetVizierInfos(ctx context.Context, req *vzmgrpb.GetVizierInfos
 		vzInfoMap[vzInfo.ID] = vzInfoPb
 	}
 
+	labels, err := fetchVizierLabels(s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
 	vzInfos := make([]*cvmsgspb.VizierInfo, len(req.VizierIDs))
 	for i, id := range ids {
 		if val, ok := vzInfoMap[id]; ok {
+			val.Labels = labels[id]
 			vzInfos[i] = val
 		} else {
 			vzInfos[i] = &cvmsgspb.VizierInfo{}
@@ -446,6 +453,12 @@ func (s *Server) GetVizierInfo(ctx context.Context, req *uuidpb.UUID) (*cvmsgspb
 		}
 
 		vzInfoPb := vizierInfoToProto(vzInfo)
+		labels, err := fetchVizierLabels(s.db, []uuid.UUID{clusterID})
+		if err != nil {
+			log.WithError(err).Error("Could not query Vizier labels")
+			return nil, status.Error(codes.Internal, "could not query for viziers")
+		}
+		vzInfoPb.Labels = labels[clusterID]
 		return vzInfoPb, nil
 	}
 	return nil, status.Error(codes.NotFound, "vizier not found")
@@ -911,17 +924,18 @@ func (s *Server) UpdateOrInstallVizier(ctx context.Context, req *cvmsgspb.Update
 	return resp, nil
 }
 
-func findVizierWithUID(ctx context.Context, tx *sqlx.Tx, orgID uuid.UUID, clusterUID string) (uuid.UUID, vizierStatus, error) {
+func findVizierWithUID(ctx context.Context, tx *sqlx.Tx, orgID uuid.UUID, clusterUID string, vizierNamespace string) (uuid.UUID, vizierStatus, error) {
 	query := `
        SELECT vizier_cluster.id, status from vizier_cluster, vizier_cluster_info
        WHERE vizier_cluster.id = vizier_cluster_info.vizier_cluster_id
        AND vizier_cluster.org_id = $1
        AND vizier_cluster.cluster_uid = $2
+       AND vizier_cluster.vizier_namespace = $3
     `
 
 	var vizierID uuid.UUID
 	var status vizierStatus
-	err := tx.QueryRowxContext(ctx, query, orgID, clusterUID).Scan(&vizierID, &status)
+	err := tx.QueryRowxContext(ctx, query, orgID, clusterUID, vizierNamespace).Scan(&vizierID, &status)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return uuid.Nil, vizierStatus(cvmsgspb.VZ_ST_UNKNOWN), nil
@@ -987,7 +1001,9 @@ func setClusterName(ctx context.Context, tx *sqlx.Tx, clusterID uuid.UUID, gener
 }
 
 // ProvisionOrClaimVizier provisions a given cluster or returns the ID if it already exists,
-func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, userID uuid.UUID, clusterUID string, clusterName string) (uuid.UUID, string, error) {
+// Namespace-scoped viziers in the same cluster are provisioned separately, based on their namespace.
+func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, userID uuid.UUID, clusterUID string, vizierNamespace string,
+	clusterName string) (uuid.UUID, string, error) {
 	// TODO(zasgar): This duplicates some functionality in the Create function. Will deprecate that Create function soon.
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -1071,7 +1087,7 @@ func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, us
 		return clusterID, finalName, nil
 	}
 
-	clusterID, status, err := findVizierWithUID(ctx, tx, orgID, clusterUID)
+	clusterID, status, err := findVizierWithUID(ctx, tx, orgID, clusterUID, vizierNamespace)
 	if err != nil {
 		return uuid.Nil, "", err
 	}
@@ -1088,8 +1104,8 @@ func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, us
 	}
 	if clusterID != uuid.Nil {
 		// Set the cluster ID.
-		query := `UPDATE vizier_cluster SET cluster_uid=$1 WHERE id=$2`
-		rows, err := tx.QueryxContext(ctx, query, clusterUID, clusterID)
+		query := `UPDATE vizier_cluster SET cluster_uid=$1, vizier_namespace=$2 WHERE id=$3`
+		rows, err := tx.QueryxContext(ctx, query, clusterUID, vizierNamespace, clusterID)
 		if err != nil {
 			return uuid.Nil, "", err
 		}
@@ -1100,10 +1116,10 @@ func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, us
 	// Insert new vizier case.
 	query := `
     	WITH ins AS (
-               INSERT INTO vizier_cluster (org_id, project_name, cluster_uid) VALUES($1, $2, $3) RETURNING id
+               INSERT INTO vizier_cluster (org_id, project_name, cluster_uid, vizier_namespace) VALUES($1, $2, $3, $4) RETURNING id
 		)
 		INSERT INTO vizier_cluster_info(vizier_cluster_id, status) SELECT id, 'DISCONNECTED' FROM ins RETURNING vizier_cluster_id`
-	err = tx.QueryRowContext(ctx, query, orgID, DefaultProjectName, clusterUID).Scan(&clusterID)
+	err = tx.QueryRowContext(ctx, query, orgID, DefaultProjectName, clusterUID, vizierNamespace).Scan(&clusterID)
 	if err != nil {
 		return uuid.Nil, "", err
 	}
@@ -0,0 +1,438 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/segmentio/analytics-go.v3"
+
+	"px.dev/pixie/src/cloud/shared/labelselector"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/services/events"
+	"px.dev/pixie/src/utils"
+)
+
+const minutesPerDay = 24 * 60
+
+// RolloutWaves is the ordered list of waves in a rollout policy.
+type RolloutWaves []*vzmgrpb.RolloutWave
+
+// Value Returns a golang database/sql driver value for RolloutWaves.
+func (w RolloutWaves) Value() (driver.Value, error) {
+	res, err := json.Marshal(w)
+	if err != nil {
+		return res, err
+	}
+	return driver.Value(res), err
+}
+
+// Scan Scans the sqlx database type ([]bytes) into the RolloutWaves type.
+func (w *RolloutWaves) Scan(src interface{}) error {
+	switch jsonText := src.(type) {
+	case []byte:
+		err := json.Unmarshal(jsonText, w)
+		if err != nil {
+			return status.Error(codes.Internal, "could not unmarshal rollout waves")
+		}
+	default:
+		return status.Error(codes.Internal, "could not unmarshal rollout waves")
+	}
+
+	return nil
+}
+
+// RolloutPolicy controls how auto-updates are rolled out to the viziers in an org.
+type RolloutPolicy struct {
+	OrgID                            uuid.UUID    `db:"org_id"`
+	Waves                            RolloutWaves `db:"waves"`
+	SoakTimeS                        int64        `db:"soak_time_s"`
+	HealthDeadlineS                  int64        `db:"health_deadline_s"`
+	MaintenanceWindowStartMinute     int32        `db:"maintenance_window_start_minute"`
+	MaintenanceWindowDurationMinutes int32        `db:"maintenance_window_duration_minutes"`
+}
+
+// NumWaves returns the number of waves in a rollout under this policy. This includes the final
+// wave, which contains every cluster that isn't in one of the policy's waves.
+func (p *RolloutPolicy) NumWaves() int {
+	return len(p.Waves) + 1
+}
+
+// WaveForCluster returns the wave that the given cluster is updated in. A cluster belongs to the first
+// wave that lists its ID or whose selector matches its labels.
+func (p *RolloutPolicy) WaveForCluster(id uuid.UUID, labels map[string]string) int {
+	for i, w := range p.Waves {
+		for _, c := range w.ClusterIDs {
+			if utils.UUIDFromProtoOrNil(c) == id {
+				return i
+			}
+		}
+		if w.Selector == "" {
+			continue
+		}
+		sel, err := labelselector.Parse(w.Selector)
+		if err != nil {
+			log.WithError(err).WithField("orgID", p.OrgID).Error("Invalid selector in rollout policy")
+			continue
+		}
+		if sel.Matches(labels) {
+			return i
+		}
+	}
+	return len(p.Waves)
+}
+
+// InMaintenanceWindow returns whether updates may be sent at the given time. The window may wrap
+// around midnight UTC. A policy without a window allows updates at any time.
+func (p *RolloutPolicy) InMaintenanceWindow(t time.Time) bool {
+	if p.MaintenanceWindowDurationMinutes <= 0 || p.MaintenanceWindowDurationMinutes >= minutesPerDay {
+		return true
+	}
+	t = t.UTC()
+	minute := t.Hour()*60 + t.Minute()
+	offset := (minute - int(p.MaintenanceWindowStartMinute) + minutesPerDay) % minutesPerDay
+	return offset < int(p.MaintenanceWindowDurationMinutes)
+}
+
+func (p *RolloutPolicy) toProto() *vzmgrpb.RolloutPolicy {
+	waves := p.Waves
+	if waves == nil {
+		waves = make(RolloutWaves, 0)
+	}
+	return &vzmgrpb.RolloutPolicy{
+		OrgID:                            utils.ProtoFromUUID(p.OrgID),
+		Waves:                            waves,
+		SoakTimeS:                        p.SoakTimeS,
+		HealthDeadlineS:                  p.HealthDeadlineS,
+		MaintenanceWindowStartMinute:     p.MaintenanceWindowStartMinute,
+		MaintenanceWindowDurationMinutes: p.MaintenanceWindowDurationMinutes,
+	}
+}
+
+// getRolloutPolicy fetches the rollout policy for an org, returning nil if the org has none.
+func getRolloutPolicy(db *sqlx.DB, orgID uuid.UUID) (*RolloutPolicy, error) {
+	query := `SELECT org_id, waves, soak_time_s, health_deadline_s, maintenance_window_start_minute,
+              maintenance_window_duration_minutes FROM vizier_rollout_policies WHERE org_id=$1`
+	var policy RolloutPolicy
+	err := db.Get(&policy, query, orgID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// rolloutRow is a rollout as stored in the vizier_rollouts table.
+type rolloutRow struct {
+	ID          uuid.UUID `db:"id"`
+	OrgID       uuid.UUID `db:"org_id"`
+	Version     string    `db:"version"`
+	State       string    `db:"state"`
+	CurrentWave int       `db:"current_wave"`
+	NumWaves    int       `db:"num_waves"`
+	Message     string    `db:"message"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// versionsMatch returns whether the version reported by a vizier is the given release. Viziers
+// report their version with build metadata (e.g. 0.10.2+Distribution.0ff4a3f.20211202095424.1),
+// which is ignored in the comparison.
+func versionsMatch(vzVersion string, version string) bool {
+	v, err := semver.Parse(vzVersion)
+	if err != nil {
+		return vzVersion == version
+	}
+	target, err := semver.Parse(version)
+	if err != nil {
+		return vzVersion == version
+	}
+	return v.Compare(target) == 0
+}
+
+// rolloutAllowsUpdate returns whether the vizier may be updated to the latest version right now.
+// Viziers in orgs without a rollout policy may always be updated. Otherwise, the vizier must be in
+// the current (or an earlier) wave of a running rollout, inside the org's maintenance window.
+func (u *Updater) rolloutAllowsUpdate(vizierID uuid.UUID) (bool, error) {
+	query := `SELECT p.org_id, p.waves, p.soak_time_s, p.health_deadline_s, p.maintenance_window_start_minute,
+              p.maintenance_window_duration_minutes FROM vizier_rollout_policies AS p, vizier_cluster AS c
+              WHERE c.id=$1 AND c.org_id=p.org_id`
+	var policy RolloutPolicy
+	err := u.db.Get(&policy, query, vizierID)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !policy.InMaintenanceWindow(time.Now()) {
+		return false, nil
+	}
+
+	rollout, err := u.getOrCreateRollout(&policy, u.latestVersion)
+	if err != nil {
+		return false, err
+	}
+	if rollout.State != "RUNNING" {
+		return false, nil
+	}
+
+	wave, err := u.rolloutWaveForCluster(rollout, &policy, vizierID)
+	if err != nil {
+		return false, err
+	}
+	if wave > rollout.CurrentWave {
+		return false, nil
+	}
+
+	// The health deadline of a wave starts counting once its first update is sent, so that waves
+	// which start outside the maintenance window aren't halted before they had a chance to update.
+	_, err = u.db.Exec(`UPDATE vizier_rollouts SET wave_started_at=NOW(), updated_at=NOW()
+                      WHERE id=$1 AND current_wave=$2 AND wave_started_at IS NULL`, rollout.ID, rollout.CurrentWave)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// getOrCreateRollout returns the org's rollout of the given version, starting a new one if needed.
+// Starting a rollout assigns every connected, auto-updating cluster in the org to its wave, and
+// supersedes any unfinished rollout of an older version.
+func (u *Updater) getOrCreateRollout(policy *RolloutPolicy, version string) (*rolloutRow, error) {
+	var rollout rolloutRow
+	query := `SELECT id, org_id, version, state, current_wave, num_waves, message, created_at, updated_at
+           FROM vizier_rollouts WHERE org_id=$1 AND version=$2`
+	err := u.db.Get(&rollout, query, policy.OrgID, version)
+	if err == nil {
+		return &rollout, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	txn, err := u.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	var rolloutID uuid.UUID
+	query = `INSERT INTO vizier_rollouts(org_id, version, num_waves) VALUES ($1, $2, $3)
+            ON CONFLICT (org_id, version) DO NOTHING RETURNING id`
+	err = txn.QueryRow(query, policy.OrgID, version, policy.NumWaves()).Scan(&rolloutID)
+	switch {
+	case err == sql.ErrNoRows:
+		// The rollout already exists.
+	case err != nil:
+		return nil, err
+	default:
+		err = u.startRollout(txn, policy, rolloutID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = txn.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	query = `SELECT id, org_id, version, state, current_wave, num_waves, message, created_at, updated_at
+           FROM vizier_rollouts WHERE org_id=$1 AND version=$2`
+	err = u.db.Get(&rollout, query, policy.OrgID, version)
+	if err != nil {
+		return nil, err
+	}
+	return &rollout, nil
+}
+
+func (u *Updater) startRollout(txn *sqlx.Tx, policy *RolloutPolicy, rolloutID uuid.UUID) error {
+	_, err := txn.Exec(`UPDATE vizier_rollouts SET state='SUPERSEDED', updated_at=NOW()
+                      WHERE org_id=$1 AND id!=$2 AND state IN ('RUNNING', 'PAUSED', 'HALTED')`, policy.OrgID, rolloutID)
+	if err != nil {
+		return err
+	}
+
+	query := `SELECT c.id FROM vizier_cluster AS c, vizier_cluster_info AS i
+            WHERE c.org_id=$1 AND c.id=i.vizier_cluster_id AND i.auto_update_enabled
+            AND i.status NOT IN ('DISCONNECTED', 'UNKNOWN')`
+	var ids []uuid.UUID
+	err = txn.Select(&ids, query, policy.OrgID)
+	if err != nil {
+		return err
+	}
+	labels, err := fetchVizierLabels(u.db, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		_, err = txn.Exec(`INSERT INTO vizier_rollout_clusters(rollout_id, vizier_cluster_id, wave) VALUES ($1, $2, $3)`,
+			rolloutID, id, policy.WaveForCluster(id, labels[id]))
+		if err != nil {
+			return err
+		}
+	}
+	log.WithField("orgID", policy.OrgID).WithField("numClusters", len(ids)).Info("Started Vizier rollout")
+	return nil
+}
+
+// rolloutWaveForCluster returns the wave of the given cluster in the rollout. Clusters that joined
+// after the rollout started are assigned a wave the first time they are seen.
+func (u *Updater) rolloutWaveForCluster(rollout *rolloutRow, policy *RolloutPolicy, vizierID uuid.UUID) (int, error) {
+	var wave int
+	query := `SELECT wave FROM vizier_rollout_clusters WHERE rollout_id=$1 AND vizier_cluster_id=$2`
+	err := u.db.Get(&wave, query, rollout.ID, vizierID)
+	if err == nil {
+		return wave, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	labels, err := fetchVizierLabels(u.db, []uuid.UUID{vizierID})
+	if err != nil {
+		return 0, err
+	}
+	wave = policy.WaveForCluster(vizierID, labels[vizierID])
+	// The policy may have gained waves since the rollout started.
+	if wave >= rollout.NumWaves {
+		wave = rollout.NumWaves - 1
+	}
+	_, err = u.db.Exec(`INSERT INTO vizier_rollout_clusters(rollout_id, vizier_cluster_id, wave) VALUES ($1, $2, $3)
+                      ON CONFLICT DO NOTHING`, rollout.ID, vizierID, wave)
+	if err != nil {
+		return 0, err
+	}
+	return wave, nil
+}
+
+// AdvanceRollouts checks the current wave of every running rollout. Once all of a wave's clusters are
+// healthy on the new version and have soaked for the policy's soak time, the rollout moves on to the
+// next wave. If the wave misses its health deadline instead, the rollout is halted.
+//
+// A wave's health deadline normally starts with its first update, but it is also started once the
+// org's maintenance window opens, so that a wave whose clusters never check in halts the rollout
+// rather than stalling it.
+func (u *Updater) AdvanceRollouts() {
+	query := `SELECT r.id, r.org_id, r.version, r.current_wave, r.num_waves, p.soak_time_s, p.health_deadline_s,
+              p.maintenance_window_start_minute, p.maintenance_window_duration_minutes,
+              EXTRACT(EPOCH FROM (NOW() - r.wave_started_at))::bigint AS wave_elapsed_s,
+              EXTRACT(EPOCH FROM (NOW() - r.soak_started_at))::bigint AS soak_elapsed_s
+            FROM vizier_rollouts AS r, vizier_rollout_policies AS p
+            WHERE r.state='RUNNING' AND r.org_id=p.org_id`
+	var rollouts []struct {
+		ID              uuid.UUID `db:"id"`
+		OrgID           uuid.UUID `db:"org_id"`
+		Version         string    `db:"version"`
+		CurrentWave     int       `db:"current_wave"`
+		NumWaves        int       `db:"num_waves"`
+		SoakTimeS       int64     `db:"soak_time_s"`
+		HealthDeadlineS int64     `db:"health_deadline_s"`
+		WindowStart     int32     `db:"maintenance_window_start_minute"`
+		WindowDuration  int32     `db:"maintenance_window_duration_minutes"`
+		WaveElapsedS    *int64    `db:"wave_elapsed_s"`
+		SoakElapsedS    *int64    `db:"soak_elapsed_s"`
+	}
+	err := u.db.Select(&rollouts, query)
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch running rollouts")
+		return
+	}
+
+	for _, r := range rollouts {
+		var clusters []struct {
+			Status  string `db:"status"`
+			Version string `db:"vizier_version"`
+		}
+		query := `SELECT i.status, i.vizier_version
+                FROM vizier_rollout_clusters AS c, vizier_cluster_info AS i
+                WHERE c.rollout_id=$1 AND c.wave=$2 AND c.vizier_cluster_id=i.vizier_cluster_id`
+		err := u.db.Select(&clusters, query, r.ID, r.CurrentWave)
+		if err != nil {
+			log.WithError(err).Error("Failed to fetch rollout wave status")
+			continue
+		}
+		total, healthy := len(clusters), 0
+		for _, c := range clusters {
+			if c.Status == "HEALTHY" && versionsMatch(c.Version, r.Version) {
+				healthy++
+			}
+		}
+
+		window := &RolloutPolicy{MaintenanceWindowStartMinute: r.WindowStart, MaintenanceWindowDurationMinutes: r.WindowDuration}
+		switch {
+		case healthy == total && total > 0 && r.SoakElapsedS == nil:
+			_, err = u.db.Exec(`UPDATE vizier_rollouts SET soak_started_at=NOW(), updated_at=NOW() WHERE id=$1`, r.ID)
+		case healthy == total && total > 0 && *r.SoakElapsedS < r.SoakTimeS:
+			// The wave is still soaking.
+		case healthy == total && r.CurrentWave+1 >= r.NumWaves:
+			log.WithField("orgID", r.OrgID).WithField("version", r.Version).Info("Vizier rollout completed")
+			_, err = u.db.Exec(`UPDATE vizier_rollouts SET state='COMPLETED', updated_at=NOW() WHERE id=$1`, r.ID)
+		case healthy == total:
+			_, err = u.db.Exec(`UPDATE vizier_rollouts SET current_wave=$2, wave_started_at=NULL, soak_started_at=NULL,
+                          updated_at=NOW() WHERE id=$1`, r.ID, r.CurrentWave+1)
+		case r.HealthDeadlineS > 0 && r.WaveElapsedS != nil && *r.WaveElapsedS > r.HealthDeadlineS:
+			msg := fmt.Sprintf("%d of %d clusters in wave %d did not become healthy on version %s within %s",
+				total-healthy, total, r.CurrentWave, r.Version, time.Duration(r.HealthDeadlineS)*time.Second)
+			u.haltRollout(r.ID, r.OrgID, msg)
+		case r.WaveElapsedS == nil && window.InMaintenanceWindow(time.Now()):
+			_, err = u.db.Exec(`UPDATE vizier_rollouts SET wave_started_at=NOW(), updated_at=NOW()
+                          WHERE id=$1 AND wave_started_at IS NULL`, r.ID)
+		case r.SoakElapsedS != nil:
+			// A cluster became unhealthy while the wave was soaking, so the soak starts over.
+			_, err = u.db.Exec(`UPDATE vizier_rollouts SET soak_started_at=NULL, updated_at=NOW() WHERE id=$1`, r.ID)
+		}
+		if err != nil {
+			log.WithError(err).Error("Failed to update rollout")
+		}
+	}
+}
+
+func (u *Updater) haltRollout(rolloutID uuid.UUID, orgID uuid.UUID, msg string) {
+	_, err := u.db.Exec(`UPDATE vizier_rollouts SET state='HALTED', message=$2, updated_at=NOW() WHERE id=$1`, rolloutID, msg)
+	if err != nil {
+		log.WithError(err).Error("Failed to halt rollout")
+		return
+	}
+
+	rolloutHaltedCounter.Inc()
+	log.WithField("orgID", orgID).WithField("rolloutID", rolloutID).Error("Vizier rollout halted: " + msg)
+	events.Client().Enqueue(&analytics.Track{
+		UserId: orgID.String(),
+		Event:  events.VizierRolloutHalted,
+		Properties: analytics.NewProperties().
+			Set("org_id", orgID.String()).
+			Set("rollout_id", rolloutID.String()).
+			Set("message", msg),
+	})
+}
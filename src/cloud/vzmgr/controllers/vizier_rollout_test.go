@@ -0,0 +1,342 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/vzmgr/controllers"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/utils"
+)
+
+const testHealthyCluster = "123e4567-e89b-12d3-a456-426655440001"
+
+func mustClearRollouts(db *sqlx.DB) {
+	db.MustExec(`DELETE FROM vizier_rollouts`)
+	db.MustExec(`DELETE FROM vizier_rollout_policies`)
+}
+
+func mustInsertRolloutPolicy(db *sqlx.DB, policy *controllers.RolloutPolicy) {
+	query := `INSERT INTO vizier_rollout_policies(org_id, waves, soak_time_s, health_deadline_s,
+	          maintenance_window_start_minute, maintenance_window_duration_minutes)
+	          VALUES (:org_id, :waves, :soak_time_s, :health_deadline_s, :maintenance_window_start_minute,
+	          :maintenance_window_duration_minutes)`
+	_, err := db.NamedExec(query, policy)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func rolloutState(t *testing.T, db *sqlx.DB) (string, int) {
+	var state string
+	var wave int
+	err := db.QueryRow(`SELECT state, current_wave FROM vizier_rollouts WHERE org_id=$1 AND version='0.4.1'`, testAuthOrgID).
+		Scan(&state, &wave)
+	require.NoError(t, err)
+	return state, wave
+}
+
+func TestRolloutPolicy_WaveForCluster(t *testing.T) {
+	canary := uuid.Must(uuid.NewV4())
+	policy := &controllers.RolloutPolicy{
+		Waves: controllers.RolloutWaves{
+			{ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUID(canary)}},
+			{Selector: "env=staging"},
+			{Selector: "env=prod,region=eu"},
+		},
+	}
+
+	assert.Equal(t, 4, policy.NumWaves())
+	assert.Equal(t, 0, policy.WaveForCluster(canary, map[string]string{"env": "prod", "region": "eu"}))
+	assert.Equal(t, 1, policy.WaveForCluster(uuid.Must(uuid.NewV4()), map[string]string{"env": "staging"}))
+	assert.Equal(t, 2, policy.WaveForCluster(uuid.Must(uuid.NewV4()), map[string]string{"env": "prod", "region": "eu"}))
+	assert.Equal(t, 3, policy.WaveForCluster(uuid.Must(uuid.NewV4()), map[string]string{"env": "prod", "region": "us"}))
+	assert.Equal(t, 3, policy.WaveForCluster(uuid.Must(uuid.NewV4()), nil))
+}
+
+func TestRolloutPolicy_InMaintenanceWindow(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2021, 5, 4, hour, minute, 0, 0, time.UTC)
+	}
+
+	always := &controllers.RolloutPolicy{}
+	assert.True(t, always.InMaintenanceWindow(at(13, 0)))
+
+	// 02:00 - 04:00 UTC.
+	night := &controllers.RolloutPolicy{
+		MaintenanceWindowStartMinute:     120,
+		MaintenanceWindowDurationMinutes: 120,
+	}
+	assert.False(t, night.InMaintenanceWindow(at(1, 59)))
+	assert.True(t, night.InMaintenanceWindow(at(2, 0)))
+	assert.True(t, night.InMaintenanceWindow(at(3, 59)))
+	assert.False(t, night.InMaintenanceWindow(at(4, 0)))
+	assert.True(t, night.InMaintenanceWindow(time.Date(2021, 5, 4, 4, 30, 0, 0, time.FixedZone("UTC+2", 2*60*60))))
+
+	// 23:00 - 01:00 UTC.
+	wrapped := &controllers.RolloutPolicy{
+		MaintenanceWindowStartMinute:     23 * 60,
+		MaintenanceWindowDurationMinutes: 120,
+	}
+	assert.True(t, wrapped.InMaintenanceWindow(at(23, 30)))
+	assert.True(t, wrapped.InMaintenanceWindow(at(0, 30)))
+	assert.False(t, wrapped.InMaintenanceWindow(at(1, 0)))
+	assert.False(t, wrapped.InMaintenanceWindow(at(22, 59)))
+}
+
+func TestUpdater_StagedRollout(t *testing.T) {
+	updater, _, db, _, cleanup := setUpUpdater(t)
+	defer cleanup()
+	mustClearRollouts(db)
+
+	mustInsertRolloutPolicy(db, &controllers.RolloutPolicy{
+		OrgID: uuid.FromStringOrNil(testAuthOrgID),
+		Waves: controllers.RolloutWaves{
+			{ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil(testHealthyCluster)}},
+		},
+		HealthDeadlineS: 3600,
+	})
+
+	// The canary is in the first wave, every other cluster is in the final wave.
+	assert.True(t, updater.AddToUpdateQueue(uuid.FromStringOrNil(testHealthyCluster)))
+	assert.False(t, updater.AddToUpdateQueue(uuid.FromStringOrNil(testExistingClusterActive)))
+
+	// The canary hasn't updated yet.
+	updater.AdvanceRollouts()
+	state, wave := rolloutState(t, db)
+	assert.Equal(t, "RUNNING", state)
+	assert.Equal(t, 0, wave)
+
+	db.MustExec(`UPDATE vizier_cluster_info SET vizier_version='0.4.1', status='HEALTHY' WHERE vizier_cluster_id=$1`, testHealthyCluster)
+	// The first check starts the soak, the second finishes it.
+	updater.AdvanceRollouts()
+	_, wave = rolloutState(t, db)
+	assert.Equal(t, 0, wave)
+	updater.AdvanceRollouts()
+	_, wave = rolloutState(t, db)
+	assert.Equal(t, 1, wave)
+
+	assert.True(t, updater.AddToUpdateQueue(uuid.FromStringOrNil(testExistingClusterActive)))
+
+	db.MustExec(`UPDATE vizier_cluster_info SET vizier_version='0.4.1', status='HEALTHY' WHERE vizier_cluster_id=$1`, testExistingClusterActive)
+	updater.AdvanceRollouts()
+	updater.AdvanceRollouts()
+	state, _ = rolloutState(t, db)
+	assert.Equal(t, "COMPLETED", state)
+}
+
+func TestUpdater_RolloutHaltsAfterDeadline(t *testing.T) {
+	updater, _, db, _, cleanup := setUpUpdater(t)
+	defer cleanup()
+	mustClearRollouts(db)
+
+	mustInsertRolloutPolicy(db, &controllers.RolloutPolicy{
+		OrgID: uuid.FromStringOrNil(testAuthOrgID),
+		Waves: controllers.RolloutWaves{
+			{ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil(testHealthyCluster)}},
+		},
+		HealthDeadlineS: 600,
+	})
+
+	assert.True(t, updater.AddToUpdateQueue(uuid.FromStringOrNil(testHealthyCluster)))
+	db.MustExec(`UPDATE vizier_rollouts SET wave_started_at=NOW() - INTERVAL '1 hour'`)
+
+	updater.AdvanceRollouts()
+	state, wave := rolloutState(t, db)
+	assert.Equal(t, "HALTED", state)
+	assert.Equal(t, 0, wave)
+
+	assert.False(t, updater.AddToUpdateQueue(uuid.FromStringOrNil(testExistingClusterActive)))
+}
+
+func TestUpdater_RolloutIgnoresBuildMetadata(t *testing.T) {
+	updater, _, db, _, cleanup := setUpUpdater(t)
+	defer cleanup()
+	mustClearRollouts(db)
+
+	mustInsertRolloutPolicy(db, &controllers.RolloutPolicy{
+		OrgID: uuid.FromStringOrNil(testAuthOrgID),
+		Waves: controllers.RolloutWaves{
+			{ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil(testHealthyCluster)}},
+		},
+		HealthDeadlineS: 3600,
+	})
+
+	assert.True(t, updater.AddToUpdateQueue(uuid.FromStringOrNil(testHealthyCluster)))
+
+	// Viziers report their version with the build metadata of the release.
+	db.MustExec(`UPDATE vizier_cluster_info SET vizier_version='0.4.1+Distribution.0ff4a3f.20211202095424.1', status='HEALTHY'
+	             WHERE vizier_cluster_id=$1`, testHealthyCluster)
+	updater.AdvanceRollouts()
+	updater.AdvanceRollouts()
+	_, wave := rolloutState(t, db)
+	assert.Equal(t, 1, wave)
+}
+
+func TestUpdater_RolloutHaltsWhenWaveNeverUpdates(t *testing.T) {
+	updater, _, db, _, cleanup := setUpUpdater(t)
+	defer cleanup()
+	mustClearRollouts(db)
+
+	mustInsertRolloutPolicy(db, &controllers.RolloutPolicy{
+		OrgID: uuid.FromStringOrNil(testAuthOrgID),
+		Waves: controllers.RolloutWaves{
+			{ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil(testHealthyCluster)}},
+		},
+		HealthDeadlineS: 600,
+	})
+
+	// Start the rollout from another cluster's heartbeat. The canary never checks in, so no update
+	// is sent to its wave.
+	assert.False(t, updater.AddToUpdateQueue(uuid.FromStringOrNil(testExistingClusterActive)))
+
+	// The deadline of the wave starts anyway.
+	updater.AdvanceRollouts()
+	var started bool
+	require.NoError(t, db.QueryRow(`SELECT wave_started_at IS NOT NULL FROM vizier_rollouts WHERE org_id=$1`, testAuthOrgID).Scan(&started))
+	assert.True(t, started)
+
+	db.MustExec(`UPDATE vizier_rollouts SET wave_started_at=NOW() - INTERVAL '1 hour'`)
+	updater.AdvanceRollouts()
+	state, _ := rolloutState(t, db)
+	assert.Equal(t, "HALTED", state)
+}
+
+func TestUpdater_RolloutMaintenanceWindow(t *testing.T) {
+	updater, _, db, _, cleanup := setUpUpdater(t)
+	defer cleanup()
+	mustClearRollouts(db)
+
+	// A one hour window that starts two hours from now.
+	now := time.Now().UTC()
+	mustInsertRolloutPolicy(db, &controllers.RolloutPolicy{
+		OrgID:                            uuid.FromStringOrNil(testAuthOrgID),
+		MaintenanceWindowStartMinute:     int32((now.Hour()*60 + now.Minute() + 120) % (24 * 60)),
+		MaintenanceWindowDurationMinutes: 60,
+	})
+
+	assert.False(t, updater.AddToUpdateQueue(uuid.FromStringOrNil(testHealthyCluster)))
+
+	// Viziers in orgs without a policy are updated right away.
+	assert.True(t, updater.AddToUpdateQueue(uuid.FromStringOrNil("223e4567-e89b-12d3-a456-426655440003")))
+}
+
+func TestServer_RolloutPolicy(t *testing.T) {
+	mustLoadTestData(db)
+	mustClearRollouts(db)
+
+	s := controllers.New(db, "test", nil, nil, nil)
+	ctx := CreateTestContext()
+	orgID := utils.ProtoFromUUIDStrOrNil(testAuthOrgID)
+
+	_, err := s.GetRolloutPolicy(ctx, orgID)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = s.UpdateRolloutPolicy(ctx, &vzmgrpb.RolloutPolicy{
+		OrgID: orgID,
+		Waves: []*vzmgrpb.RolloutWave{{}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.UpdateRolloutPolicy(ctx, &vzmgrpb.RolloutPolicy{
+		OrgID: orgID,
+		Waves: []*vzmgrpb.RolloutWave{{ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440003")}}},
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = s.UpdateRolloutPolicy(ctx, &vzmgrpb.RolloutPolicy{
+		OrgID:                            utils.ProtoFromUUIDStrOrNil(testNonAuthOrgID),
+		MaintenanceWindowDurationMinutes: 60,
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	policy := &vzmgrpb.RolloutPolicy{
+		OrgID: orgID,
+		Waves: []*vzmgrpb.RolloutWave{
+			{ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil(testHealthyCluster)}},
+			{Selector: "region=eu, env=prod"},
+		},
+		SoakTimeS:                        3600,
+		HealthDeadlineS:                  1800,
+		MaintenanceWindowStartMinute:     120,
+		MaintenanceWindowDurationMinutes: 240,
+	}
+	resp, err := s.UpdateRolloutPolicy(ctx, policy)
+	require.NoError(t, err)
+	assert.Equal(t, "env=prod,region=eu", resp.Waves[1].Selector)
+
+	resp, err = s.GetRolloutPolicy(ctx, orgID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3600), resp.SoakTimeS)
+	assert.Equal(t, int64(1800), resp.HealthDeadlineS)
+	assert.Equal(t, int32(240), resp.MaintenanceWindowDurationMinutes)
+	require.Len(t, resp.Waves, 2)
+	assert.Equal(t, testHealthyCluster, utils.ProtoToUUIDStr(resp.Waves[0].ClusterIDs[0]))
+
+	_, err = s.DeleteRolloutPolicy(ctx, orgID)
+	require.NoError(t, err)
+	_, err = s.GetRolloutPolicy(ctx, orgID)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_PauseAndResumeRollout(t *testing.T) {
+	mustLoadTestData(db)
+	mustClearRollouts(db)
+
+	s := controllers.New(db, "test", nil, nil, nil)
+	ctx := CreateTestContext()
+	orgID := utils.ProtoFromUUIDStrOrNil(testAuthOrgID)
+
+	rolloutID := "523e4567-e89b-12d3-a456-426655440000"
+	db.MustExec(`INSERT INTO vizier_rollouts(id, org_id, version, num_waves) VALUES ($1, $2, '0.4.1', 2)`, rolloutID, testAuthOrgID)
+	db.MustExec(`INSERT INTO vizier_rollout_clusters(rollout_id, vizier_cluster_id, wave) VALUES ($1, $2, 0)`, rolloutID, testHealthyCluster)
+
+	rollouts, err := s.GetRollouts(ctx, orgID)
+	require.NoError(t, err)
+	require.Len(t, rollouts.Rollouts, 1)
+	assert.Equal(t, vzmgrpb.ROLLOUT_RUNNING, rollouts.Rollouts[0].State)
+	assert.Equal(t, int32(2), rollouts.Rollouts[0].NumWaves)
+	require.Len(t, rollouts.Rollouts[0].Clusters, 1)
+	assert.False(t, rollouts.Rollouts[0].Clusters[0].Updated)
+
+	req := &vzmgrpb.RolloutRequest{OrgID: orgID, RolloutID: utils.ProtoFromUUIDStrOrNil(rolloutID)}
+	rollout, err := s.PauseRollout(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, vzmgrpb.ROLLOUT_PAUSED, rollout.State)
+
+	_, err = s.PauseRollout(ctx, req)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	rollout, err = s.ResumeRollout(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, vzmgrpb.ROLLOUT_RUNNING, rollout.State)
+
+	_, err = s.ResumeRollout(ctx, &vzmgrpb.RolloutRequest{OrgID: orgID, RolloutID: utils.ProtoFromUUID(uuid.Must(uuid.NewV4()))})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -99,6 +99,7 @@ func (u *Updater) pollVizierVersion() {
 			if err == nil {
 				u.latestVersion = vzVersion
 			}
+			u.AdvanceRollouts()
 		}
 	}
 }
@@ -197,6 +198,8 @@ func (u *Updater) updateOrInstallVizier(vizierID uuid.UUID, version string, rede
 	defer sub.Unsubscribe()
 
 	log.WithField("Vizier ID", vizierID.String()).WithField("version", req.Version).Info("Sending update request to Vizier")
+	// Updates are not sent through the vizier's outbox: if the vizier doesn't respond in time, the update is
+	// retried on a later heartbeat, and a replayed update could be older than the one that is in progress.
 	u.sendNATSMessage("VizierUpdate", reqAnyMsg, vizierID)
 
 	// Wait to receive a response from the vizier that it has received the update message.
@@ -261,13 +264,29 @@ func (u *Updater) VersionUpToDate(version string) bool {
 	return true
 }
 
-// AddToUpdateQueue queues the given Vizier for an update to the latest version.
+// AddToUpdateQueue queues the given Vizier for an update to the latest version, if its org's rollout
+// policy allows it to be updated now.
 func (u *Updater) AddToUpdateQueue(vizierID uuid.UUID) bool {
+	// This runs on every heartbeat of an outdated vizier, so check the queue before the rollout
+	// policy, which needs several DB queries.
+	if u.isQueued(vizierID) {
+		return false // Vizier is already queued for an update.
+	}
+
+	allowed, err := u.rolloutAllowsUpdate(vizierID)
+	if err != nil {
+		log.WithError(err).WithField("vizierID", vizierID).Error("Failed to check rollout policy")
+		return false
+	}
+	if !allowed {
+		return false
+	}
+
 	u.queueMu.Lock()
 	defer u.queueMu.Unlock()
 
 	if _, ok := u.queuedViziers[vizierID]; ok {
-		return false // Vizier is already queued for an update.
+		return false // Vizier was queued while the rollout policy was checked.
 	}
 
 	// Add to queue if possible, else we will add it next time around.
@@ -282,6 +301,13 @@ func (u *Updater) AddToUpdateQueue(vizierID uuid.UUID) bool {
 	return false
 }
 
+func (u *Updater) isQueued(vizierID uuid.UUID) bool {
+	u.queueMu.Lock()
+	defer u.queueMu.Unlock()
+	_, ok := u.queuedViziers[vizierID]
+	return ok
+}
+
 // ProcessUpdateQueue updates the Viziers in the update queue.
 func (u *Updater) ProcessUpdateQueue() {
 	for {
@@ -56,7 +56,7 @@ func (f *fakeDF) FetchOrgUserIDUsingDeploymentKey(ctx context.Context, key strin
 type fakeProvisioner struct {
 }
 
-func (f *fakeProvisioner) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, userID uuid.UUID, clusterUID string, clusterName string) (uuid.UUID, string, error) {
+func (f *fakeProvisioner) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, userID uuid.UUID, clusterUID string, vizierNamespace string, clusterName string) (uuid.UUID, string, error) {
 	if testOrgID == orgID && testUserID == userID && clusterUID == "cluster1" && clusterName == "test" {
 		return testValidClusterID, clusterName, nil
 	}
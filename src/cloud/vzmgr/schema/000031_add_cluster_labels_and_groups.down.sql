@@ -0,0 +1,4 @@
+DROP TABLE vizier_cluster_groups;
+
+DROP INDEX idx_vizier_cluster_labels_key_value;
+DROP TABLE vizier_cluster_labels;
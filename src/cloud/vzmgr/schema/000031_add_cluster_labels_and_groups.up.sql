@@ -0,0 +1,28 @@
+-- This table contains the user-defined labels attached to a cluster.
+CREATE TABLE vizier_cluster_labels (
+  -- The cluster this label is attached to.
+  vizier_cluster_id UUID NOT NULL REFERENCES vizier_cluster(id) ON DELETE CASCADE,
+  -- The key of the label, eg: env.
+  key varchar(63) NOT NULL,
+  -- The value of the label, eg: prod.
+  value varchar(63) NOT NULL DEFAULT '',
+
+  PRIMARY KEY(vizier_cluster_id, key)
+);
+
+CREATE INDEX idx_vizier_cluster_labels_key_value ON vizier_cluster_labels(key, value);
+
+-- This table contains named groups of clusters, defined by a label selector.
+CREATE TABLE vizier_cluster_groups (
+  id UUID UNIQUE DEFAULT uuid_generate_v4(),
+  -- org_id is the owner of this group.
+  org_id UUID NOT NULL,
+  -- The name of the group, unique within the org.
+  name varchar(255) NOT NULL,
+  -- The label selector clusters must match to be part of this group, eg: env=prod,region=eu.
+  selector varchar(1000) NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT NOW(),
+
+  UNIQUE(org_id, name),
+  PRIMARY KEY(id)
+);
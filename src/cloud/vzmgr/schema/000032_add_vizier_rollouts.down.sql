@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS vizier_rollout_clusters;
+DROP TABLE IF EXISTS vizier_rollouts;
+DROP TYPE IF EXISTS vizier_rollout_state;
+DROP TABLE IF EXISTS vizier_rollout_policies;
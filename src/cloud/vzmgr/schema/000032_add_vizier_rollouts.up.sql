@@ -0,0 +1,49 @@
+-- A rollout policy controls how auto-updates are rolled out to the viziers in an org.
+CREATE TABLE vizier_rollout_policies (
+  org_id UUID NOT NULL,
+  -- The ordered list of waves, as JSON. Each wave selects clusters by label selector and/or ID.
+  waves json NOT NULL DEFAULT '[]',
+  -- How long every cluster in a wave must stay healthy before the next wave starts.
+  soak_time_s bigint NOT NULL DEFAULT 0,
+  -- How long the clusters in a wave have to become healthy before the rollout is halted.
+  health_deadline_s bigint NOT NULL DEFAULT 0,
+  -- The daily maintenance window in UTC. A zero duration allows updates at any time.
+  maintenance_window_start_minute int NOT NULL DEFAULT 0,
+  maintenance_window_duration_minutes int NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(org_id)
+);
+
+CREATE TYPE vizier_rollout_state AS ENUM ('RUNNING', 'PAUSED', 'HALTED', 'COMPLETED', 'SUPERSEDED');
+
+-- A rollout tracks the progress of a single vizier version through an org's waves.
+CREATE TABLE vizier_rollouts (
+  id UUID NOT NULL DEFAULT uuid_generate_v4(),
+  org_id UUID NOT NULL,
+  version varchar(50) NOT NULL,
+  state vizier_rollout_state NOT NULL DEFAULT 'RUNNING',
+  current_wave int NOT NULL DEFAULT 0,
+  num_waves int NOT NULL,
+  -- Set when the first update of the current wave is sent.
+  wave_started_at TIMESTAMP,
+  -- Set when every cluster in the current wave is first seen healthy.
+  soak_started_at TIMESTAMP,
+  message text NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(id),
+  UNIQUE(org_id, version)
+);
+
+-- The wave each cluster was assigned to for a rollout.
+CREATE TABLE vizier_rollout_clusters (
+  rollout_id UUID NOT NULL,
+  vizier_cluster_id UUID NOT NULL,
+  wave int NOT NULL,
+
+  PRIMARY KEY(rollout_id, vizier_cluster_id),
+  FOREIGN KEY(rollout_id) REFERENCES vizier_rollouts(id) ON DELETE CASCADE,
+  FOREIGN KEY(vizier_cluster_id) REFERENCES vizier_cluster(id) ON DELETE CASCADE
+);
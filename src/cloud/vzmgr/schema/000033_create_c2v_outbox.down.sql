@@ -0,0 +1 @@
+DROP TABLE IF EXISTS vizier_c2v_outbox;
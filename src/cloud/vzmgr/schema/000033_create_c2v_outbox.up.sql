@@ -0,0 +1,14 @@
+-- The cloud-to-vizier messages which have not yet been acknowledged by each vizier.
+CREATE TABLE vizier_c2v_outbox (
+  vizier_cluster_id UUID NOT NULL,
+  -- Sequence numbers are shared by all viziers, so they only increase for a given vizier.
+  seq_id bigserial NOT NULL,
+  topic varchar(1024) NOT NULL,
+  msg bytea NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(vizier_cluster_id, seq_id),
+  FOREIGN KEY(vizier_cluster_id) REFERENCES vizier_cluster(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_vizier_c2v_outbox_created_at ON vizier_c2v_outbox(created_at);
@@ -0,0 +1,4 @@
+-- The namespace of a namespace-scoped vizier. Several namespace-scoped viziers may run in the same
+-- cluster, so they share a cluster_uid and are told apart by their namespace. Empty for viziers that
+-- monitor the whole cluster.
+ALTER TABLE vizier_cluster ADD COLUMN vizier_namespace varchar(253) NOT NULL DEFAULT '';
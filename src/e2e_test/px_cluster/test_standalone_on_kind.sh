@@ -0,0 +1,121 @@
+#!/bin/bash -eE
+
+# Copyright 2018- The Pixie Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+# SPDX-License-Identifier: Apache-2.0
+
+# shellcheck disable=SC1090
+
+# This script creates a Kind cluster, deploys a standalone Vizier that runs
+# without Pixie Cloud and executes a simple pxl script on it directly.
+# It expects the Vizier images to be available in the local docker daemon,
+# tagged with IMAGE_TAG (default: latest), e.g. after building them with bazel.
+
+# --- begin runfiles.bash initialization v2 ---
+# Copy-pasted from the Bazel Bash runfiles library v2.
+set -uo pipefail; f=bazel_tools/tools/bash/runfiles/runfiles.bash
+source "${RUNFILES_DIR:-/dev/null}/$f" 2>/dev/null || \
+  source "$(grep -sm1 "^$f " "${RUNFILES_MANIFEST_FILE:-/dev/null}" | cut -f2- -d' ')" 2>/dev/null || \
+  source "$0.runfiles/$f" 2>/dev/null || \
+  source "$(grep -sm1 "^$f " "$0.runfiles_manifest" | cut -f2- -d' ')" 2>/dev/null || \
+  source "$(grep -sm1 "^$f " "$0.exe.runfiles_manifest" | cut -f2- -d' ')" 2>/dev/null || \
+  { echo>&2 "ERROR: cannot find $f"; exit 1; }; f=; set -e
+# --- end runfiles.bash initialization v2 ---
+
+# Get arguments
+if [ "$#" -ne 0 ]; then
+  echo "Usage: $0"
+  exit 1
+fi
+
+image_tag="${IMAGE_TAG:-latest}"
+namespace="pl"
+standalone_dir="$(dirname "$(rlocation px/k8s/vizier/standalone/kustomization.yaml)")"
+example="$(rlocation px/src/api/go/pxapi/examples/standalone_example/standalone_example_/standalone_example)"
+
+# Create a random cluster name.
+cluster_name="test-standalone-${RANDOM}"
+workdir="$(mktemp -d)"
+
+# Create the cluster
+kind create cluster --name "$cluster_name"
+
+port_forward_pid=""
+function cleanup() {
+  if [ -n "$port_forward_pid" ]; then
+    kill "$port_forward_pid" || true
+  fi
+  kind delete cluster --name "$cluster_name"
+  rm -rf "$workdir"
+}
+# Delete cluster on exit (covers error cases too).
+trap cleanup EXIT
+
+kustomize build "$standalone_dir" | \
+  sed "s|\(gcr.io/pixie-oss/pixie-dev/vizier/[a-z_]*\):latest|\1:${image_tag}|" > "${workdir}/vizier.yaml"
+
+# Make locally built images available to the cluster, anything else is pulled.
+for image in $(grep -o "gcr.io/pixie-oss/pixie-dev/vizier/[a-z_]*:${image_tag}" "${workdir}/vizier.yaml" | sort -u); do
+  if docker image inspect "$image" > /dev/null 2>&1; then
+    kind load docker-image "$image" --name "$cluster_name"
+  fi
+done
+
+api_key="$(openssl rand -hex 16)"
+kubectl create namespace "$namespace"
+kubectl -n "$namespace" create configmap pl-cloud-config
+kubectl -n "$namespace" create secret generic pl-cluster-secrets --from-literal=jwt-signing-key="$(openssl rand -hex 32)"
+kubectl -n "$namespace" create secret generic pl-standalone-api-keys --from-literal=ci="$api_key"
+kubectl apply -f "${workdir}/vizier.yaml"
+
+for deployment in vizier-certmgr vizier-cloud-connector vizier-query-broker; do
+  kubectl -n "$namespace" wait --for=condition=available --timeout=10m "deployment/${deployment}"
+done
+
+function get_standalone_cluster_id() {
+  kubectl -n "$namespace" get secret pl-cluster-secrets -o jsonpath='{.data.cluster-id}' | base64 -d
+}
+
+# The cloud connector generates the cluster ID, it must be kept when it restarts.
+cluster_id="$(get_standalone_cluster_id)"
+if [ -z "$cluster_id" ]; then
+  echo "Test FAILED: No cluster ID was generated"
+  exit 1
+fi
+kubectl -n "$namespace" rollout restart deployment/vizier-cloud-connector
+kubectl -n "$namespace" rollout status --timeout=10m deployment/vizier-cloud-connector
+if [ "$(get_standalone_cluster_id)" != "$cluster_id" ]; then
+  echo "Test FAILED: Cluster ID changed after the cloud connector restarted"
+  exit 1
+fi
+
+# Wait some additional time for the agents to register and collect data.
+sleep 60
+
+kubectl -n "$namespace" get secret service-tls-certs -o jsonpath='{.data.ca\.crt}' | base64 -d > "${workdir}/ca.crt"
+kubectl -n "$namespace" port-forward svc/vizier-query-broker-svc 50300:50300 &
+port_forward_pid=$!
+sleep 5
+
+output=$(PX_API_KEY="$api_key" PX_DIRECT_ADDR="localhost:50300" PX_CLUSTER_ID="$cluster_id" \
+  PX_CA_CERT="${workdir}/ca.crt" "$example")
+
+echo "Sample output:" >&2
+echo "$output" | head -10 >&2
+if [ -z "$output" ]; then
+  echo "Test FAILED: Not enough results"
+  exit 1
+fi
+echo "Test PASSED"
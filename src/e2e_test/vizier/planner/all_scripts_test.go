@@ -292,7 +292,7 @@ func setupPlanner(req *plannerpb.QueryRequest, udfInfo *udfspb.UDFInfo, distribu
 	if err != nil {
 		return planner, nil, err
 	}
-	flags, err := controllers.ParseQueryFlags(req.QueryStr)
+	flags, err := controllers.ParseQueryFlags(req.QueryStr, nil, nil)
 	if err != nil {
 		return planner, nil, err
 	}
@@ -49,6 +49,8 @@ var (
 // Adds the list of known types to the given scheme.
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
+		&Tracepoint{},
+		&TracepointList{},
 		&Vizier{},
 		&VizierList{},
 	)
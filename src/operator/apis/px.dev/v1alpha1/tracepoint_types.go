@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TracepointSpec defines the desired state of a Tracepoint.
+type TracepointSpec struct {
+	// PxL is the PxL script which defines the tracepoint, using pxtrace.UpsertTracepoint. The script must
+	// define exactly one tracepoint. The tracepoint is named after the Tracepoint resource, and its TTL is
+	// managed by Vizier for as long as the resource exists.
+	PxL string `json:"pxl"`
+	// TableName is the name of the table which the tracepoint writes its output to. If specified, it
+	// overrides the table name given in the PxL script.
+	TableName string `json:"tableName,omitempty"`
+	// Target selects the agents which the tracepoint should be deployed to. If not specified, the tracepoint
+	// is deployed to all agents.
+	Target *TracepointTarget `json:"target,omitempty"`
+}
+
+// TracepointTarget selects the agents which a tracepoint is deployed to. An agent is selected only if it
+// matches every field which is specified.
+type TracepointTarget struct {
+	// NodeNames are the names of the nodes to deploy the tracepoint to.
+	NodeNames []string `json:"nodeNames,omitempty"`
+	// NodeSelector selects the nodes to deploy the tracepoint to by their labels.
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// Namespaces restricts the tracepoint to nodes running a pod in one of these namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// PodSelector restricts the tracepoint to nodes running a pod with matching labels. If Namespaces is
+	// specified, the pod must also be in one of those namespaces.
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+}
+
+// TracepointStatus defines the observed state of a Tracepoint.
+type TracepointStatus struct {
+	// ID is the ID of the tracepoint deployment in Vizier.
+	ID string `json:"id,omitempty"`
+	// Phase is a high-level summary of the state of the tracepoint across all of its agents.
+	Phase TracepointPhase `json:"phase,omitempty"`
+	// Message is a human-readable message with details about why the tracepoint is in this phase.
+	Message string `json:"message,omitempty"`
+	// ObservedGeneration is the generation of the Tracepoint spec which is currently deployed.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Agents is the state of the tracepoint on each agent which it has been deployed to.
+	Agents []AgentTracepointStatus `json:"agents,omitempty"`
+}
+
+// AgentTracepointStatus is the state of a tracepoint on a single agent.
+type AgentTracepointStatus struct {
+	// AgentID is the ID of the agent.
+	AgentID string `json:"agentID"`
+	// State is the lifecycle state of the tracepoint on the agent.
+	State string `json:"state,omitempty"`
+	// Message describes why the tracepoint is unhealthy on the agent, if it is.
+	Message string `json:"message,omitempty"`
+}
+
+// TracepointPhase is a high-level summary of the state of a tracepoint.
+type TracepointPhase string
+
+const (
+	// TracepointPhaseNone is used when the tracepoint has not yet been reconciled.
+	TracepointPhaseNone TracepointPhase = ""
+	// TracepointPhasePending is used when the tracepoint is still being deployed to its agents.
+	TracepointPhasePending TracepointPhase = "Pending"
+	// TracepointPhaseRunning is used when the tracepoint is running on at least one agent.
+	TracepointPhaseRunning TracepointPhase = "Running"
+	// TracepointPhaseFailed is used when the tracepoint could not be compiled or deployed.
+	TracepointPhaseFailed TracepointPhase = "Failed"
+	// TracepointPhaseTerminated is used when the tracepoint has been removed from its agents.
+	TracepointPhaseTerminated TracepointPhase = "Terminated"
+)
+
+// Tracepoint is the Schema for the tracepoints API. A Tracepoint is deployed by the Vizier in the same
+// namespace, and is kept alive for as long as the resource exists.
+// +genclient
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+type Tracepoint struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TracepointSpec   `json:"spec,omitempty"`
+	Status TracepointStatus `json:"status,omitempty"`
+}
+
+// TracepointList contains a list of Tracepoint
+// +kubebuilder:object:root=true
+type TracepointList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Tracepoint `json:"items"`
+}
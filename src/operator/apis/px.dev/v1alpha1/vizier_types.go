@@ -69,6 +69,33 @@ type VizierSpec struct {
 	DataCollectorParams *DataCollectorParams `json:"dataCollectorParams,omitempty"`
 	// LeadershipElectionParams specifies configurable values for the K8s leaderships elections which Vizier uses manage pod leadership.
 	LeadershipElectionParams *LeadershipElectionParams `json:"leadershipElectionParams,omitempty"`
+	// DefaultQueryFlags specifies cluster-level default values for query flags, for example:
+	// "max_output_rows_per_table". Scripts and individual requests may still override these values.
+	DefaultQueryFlags map[string]string `json:"defaultQueryFlags,omitempty"`
+	// AutoRollback defines whether, and when, the operator should redeploy the last healthy version of the Vizier
+	// if an update fails to become healthy. If not specified, failed updates are not rolled back.
+	AutoRollback *AutoRollbackPolicy `json:"autoRollback,omitempty"`
+	// NamespaceScope restricts data collection to the listed namespaces. A namespace-scoped Vizier gives its
+	// cluster-scoped resources, such as ClusterRoles, namespace-unique names so that several Vizier instances
+	// can run side-by-side in the same cluster. If empty, the Vizier collects data from all namespaces.
+	NamespaceScope []string `json:"namespaceScope,omitempty"`
+	// BundleConfigMap is the name of a ConfigMap, in the Vizier's namespace, containing a deployment bundle created
+	// by "px bundle-vizier". Large bundles continue in ConfigMaps with the same name suffixed by "-1", "-2", etc.
+	// If specified, the Vizier YAMLs are rendered from the bundle rather than fetched from Pixie Cloud, which allows
+	// Vizier to be deployed to air-gapped clusters.
+	BundleConfigMap string `json:"bundleConfigMap,omitempty"`
+	// Registry is a container registry which Vizier images should be pulled from instead of their default
+	// registries, for example a mirror that the images in a deployment bundle have been loaded into.
+	Registry string `json:"registry,omitempty"`
+}
+
+// AutoRollbackPolicy defines the policy for rolling back failed Vizier updates.
+type AutoRollbackPolicy struct {
+	// Enabled specifies whether failed updates should be automatically rolled back to the last healthy version.
+	Enabled bool `json:"enabled,omitempty"`
+	// TimeoutSeconds is how long an updated Vizier may stay unhealthy before the update is considered failed.
+	// Defaults to 10 minutes.
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
 }
 
 // DataAccessLevel defines the levels of data access that can be used when executing a script on a cluster.
@@ -117,6 +144,27 @@ type VizierStatus struct {
 	Message string `json:"message,omitempty"`
 	// SentryDSN is key for Viziers that is used to send errors and stacktraces to Sentry.
 	SentryDSN string `json:"sentryDSN,omitempty"`
+	// LastHealthyVersion is the most recent version of the Vizier instance that reached a healthy state.
+	LastHealthyVersion string `json:"lastHealthyVersion,omitempty"`
+	// LastHealthySpec is the spec that was deployed when the Vizier last reached a healthy state. This is the spec
+	// that is redeployed when a failed update is rolled back.
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	LastHealthySpec *VizierSpec `json:"lastHealthySpec,omitempty"`
+	// RolledBackFromVersion is the version of the failed update that is currently being rolled back, if any.
+	RolledBackFromVersion string `json:"rolledBackFromVersion,omitempty"`
+	// FailedVersion is the version of the most recent update that failed and was rolled back. The Vizier is not
+	// updated to this version again, until another version has been deployed successfully.
+	FailedVersion string `json:"failedVersion,omitempty"`
+	// Conditions are the results of the individual health checks that the VizierPhase is summarized from.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// IncompatibleNodes is the number of nodes in the cluster with a kernel version that Pixie does not support.
+	IncompatibleNodes int32 `json:"incompatibleNodes,omitempty"`
+	// CrashingPEMs is the number of running PEM pods which are crashing.
+	CrashingPEMs int32 `json:"crashingPEMs,omitempty"`
 }
 
 // VizierPhase is a high-level summary of where the Vizier is in its lifecycle.
@@ -138,6 +186,27 @@ const (
 	VizierPhaseDegraded VizierPhase = "Degraded"
 )
 
+// The types of the conditions in the VizierStatus. Each condition is the result of a single health check
+// performed by the operator.
+const (
+	// VizierConditionVersionSupported indicates whether the running Vizier version is still supported.
+	VizierConditionVersionSupported = "VersionSupported"
+	// VizierConditionControlPlaneReady indicates whether the Vizier control plane pods are running.
+	VizierConditionControlPlaneReady = "ControlPlaneReady"
+	// VizierConditionNATSReady indicates whether the NATS message bus is running and reachable.
+	VizierConditionNATSReady = "NATSReady"
+	// VizierConditionPEMResourcesAvailable indicates whether there are sufficient resources to schedule the PEMs.
+	VizierConditionPEMResourcesAvailable = "PEMResourcesAvailable"
+	// VizierConditionPEMsRunning indicates whether the PEMs are running without crashing.
+	VizierConditionPEMsRunning = "PEMsRunning"
+	// VizierConditionCloudConnected indicates whether the cloud connector is running and connected to Pixie Cloud.
+	VizierConditionCloudConnected = "CloudConnected"
+	// VizierConditionMetadataPVCReady indicates whether the PVC used by the metadata service is bound.
+	VizierConditionMetadataPVCReady = "MetadataPVCReady"
+	// VizierConditionNodesCompatible indicates whether enough nodes have a kernel version that Pixie supports.
+	VizierConditionNodesCompatible = "NodesCompatible"
+)
+
 // ReconciliationPhase is the state the Reconciler has reached while managing this
 // vizier. When the Reconciler creates a Vizier, the Reconciler sets this value to `Updating`.
 // When successful, the Reconciler moves to a `Ready` phase. If unsuccessful,
@@ -210,7 +279,6 @@ type LeadershipElectionParams struct {
 
 // Vizier is the Schema for the viziers API
 // +genclient
-// +genclient:noStatus
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 type Vizier struct {
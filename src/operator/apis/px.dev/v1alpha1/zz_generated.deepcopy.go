@@ -6,9 +6,40 @@
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentTracepointStatus) DeepCopyInto(out *AgentTracepointStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentTracepointStatus.
+func (in *AgentTracepointStatus) DeepCopy() *AgentTracepointStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentTracepointStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoRollbackPolicy) DeepCopyInto(out *AutoRollbackPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoRollbackPolicy.
+func (in *AutoRollbackPolicy) DeepCopy() *AutoRollbackPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoRollbackPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataCollectorParams) DeepCopyInto(out *DataCollectorParams) {
 	*out = *in
@@ -103,6 +134,140 @@ func (in *PodSecurityContext) DeepCopy() *PodSecurityContext {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Tracepoint) DeepCopyInto(out *Tracepoint) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Tracepoint.
+func (in *Tracepoint) DeepCopy() *Tracepoint {
+	if in == nil {
+		return nil
+	}
+	out := new(Tracepoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Tracepoint) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracepointList) DeepCopyInto(out *TracepointList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Tracepoint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracepointList.
+func (in *TracepointList) DeepCopy() *TracepointList {
+	if in == nil {
+		return nil
+	}
+	out := new(TracepointList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TracepointList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracepointSpec) DeepCopyInto(out *TracepointSpec) {
+	*out = *in
+	if in.Target != nil {
+		in, out := &in.Target, &out.Target
+		*out = new(TracepointTarget)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracepointSpec.
+func (in *TracepointSpec) DeepCopy() *TracepointSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TracepointSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracepointStatus) DeepCopyInto(out *TracepointStatus) {
+	*out = *in
+	if in.Agents != nil {
+		in, out := &in.Agents, &out.Agents
+		*out = make([]AgentTracepointStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracepointStatus.
+func (in *TracepointStatus) DeepCopy() *TracepointStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TracepointStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracepointTarget) DeepCopyInto(out *TracepointTarget) {
+	*out = *in
+	if in.NodeNames != nil {
+		in, out := &in.NodeNames, &out.NodeNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracepointTarget.
+func (in *TracepointTarget) DeepCopy() *TracepointTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(TracepointTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Vizier) DeepCopyInto(out *Vizier) {
 	*out = *in
@@ -187,6 +352,23 @@ func (in *VizierSpec) DeepCopyInto(out *VizierSpec) {
 		*out = new(LeadershipElectionParams)
 		**out = **in
 	}
+	if in.DefaultQueryFlags != nil {
+		in, out := &in.DefaultQueryFlags, &out.DefaultQueryFlags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.AutoRollback != nil {
+		in, out := &in.AutoRollback, &out.AutoRollback
+		*out = new(AutoRollbackPolicy)
+		**out = **in
+	}
+	if in.NamespaceScope != nil {
+		in, out := &in.NamespaceScope, &out.NamespaceScope
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VizierSpec.
@@ -206,6 +388,18 @@ func (in *VizierStatus) DeepCopyInto(out *VizierStatus) {
 		in, out := &in.LastReconciliationPhaseTime, &out.LastReconciliationPhaseTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastHealthySpec != nil {
+		in, out := &in.LastHealthySpec, &out.LastHealthySpec
+		*out = new(VizierSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VizierStatus.
@@ -86,6 +86,18 @@ func (c *FakeViziers) Update(ctx context.Context, vizier *v1alpha1.Vizier, opts
 	return obj.(*v1alpha1.Vizier), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeViziers) UpdateStatus(ctx context.Context, vizier *v1alpha1.Vizier, opts v1.UpdateOptions) (*v1alpha1.Vizier, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(viziersResource, "status", c.ns, vizier), &v1alpha1.Vizier{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.Vizier), err
+}
+
 // Delete takes name of the vizier and deletes it. Returns an error if one occurs.
 func (c *FakeViziers) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -24,6 +24,7 @@ type ViziersGetter interface {
 type VizierInterface interface {
 	Create(ctx context.Context, vizier *v1alpha1.Vizier, opts v1.CreateOptions) (*v1alpha1.Vizier, error)
 	Update(ctx context.Context, vizier *v1alpha1.Vizier, opts v1.UpdateOptions) (*v1alpha1.Vizier, error)
+	UpdateStatus(ctx context.Context, vizier *v1alpha1.Vizier, opts v1.UpdateOptions) (*v1alpha1.Vizier, error)
 	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
 	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.Vizier, error)
@@ -119,6 +120,22 @@ func (c *viziers) Update(ctx context.Context, vizier *v1alpha1.Vizier, opts v1.U
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *viziers) UpdateStatus(ctx context.Context, vizier *v1alpha1.Vizier, opts v1.UpdateOptions) (result *v1alpha1.Vizier, err error) {
+	result = &v1alpha1.Vizier{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("viziers").
+		Name(vizier.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(vizier).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the vizier and deletes it. Returns an error if one occurs.
 func (c *viziers) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/artifacts"
+	"px.dev/pixie/src/utils/shared/yamls"
+	vizieryamls "px.dev/pixie/src/utils/template_generator/vizier_yamls"
+)
+
+// readVizierBundle reads the Vizier's deployment bundle from its bundle ConfigMaps.
+func readVizierBundle(ctx context.Context, clientset kubernetes.Interface, ns string, vz *v1alpha1.Vizier) (*artifacts.Bundle, error) {
+	return artifacts.ReadVizierBundleConfigMaps(vz.Spec.BundleConfigMap, func(name string) (map[string][]byte, error) {
+		cm, err := clientset.CoreV1().ConfigMaps(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return cm.BinaryData, nil
+	})
+}
+
+// getBundleVersion returns the Vizier version contained in the Vizier's deployment bundle.
+func getBundleVersion(ctx context.Context, clientset kubernetes.Interface, ns string, vz *v1alpha1.Vizier) (string, error) {
+	bundle, err := readVizierBundle(ctx, clientset, ns, vz)
+	if err != nil {
+		return "", err
+	}
+	return bundle.Manifest.VizierVersion, nil
+}
+
+// generateVizierYAMLsFromBundle renders the Vizier YAMLs from the deployment bundle in the Vizier's bundle
+// ConfigMap, rather than requesting them from Pixie Cloud.
+func generateVizierYAMLsFromBundle(ctx context.Context, clientset *kubernetes.Clientset, ns string, vz *v1alpha1.Vizier) (map[string]string, error) {
+	bundle, err := readVizierBundle(ctx, clientset, ns, vz)
+	if err != nil {
+		return nil, err
+	}
+	if bundle.Manifest.VizierVersion != vz.Spec.Version {
+		return nil, fmt.Errorf("bundle %s contains Vizier version %s, but version %s was requested",
+			vz.Spec.BundleConfigMap, bundle.Manifest.VizierVersion, vz.Spec.Version)
+	}
+
+	tmplValues := vizieryamls.VizierTmplValuesFromSpec(ns, vizierSpecToConfig(vz))
+	vzYAMLs, err := yamls.ExecuteTemplatedYAMLs(bundle.VizierTemplates, vizieryamls.VizierTmplValuesToArgs(tmplValues))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vz.Spec.Patches) > 0 {
+		apiGroupResources, err := restmapper.GetAPIGroupResources(clientset.Discovery())
+		if err != nil {
+			return nil, err
+		}
+		rm := restmapper.NewDiscoveryRESTMapper(apiGroupResources)
+		for _, y := range vzYAMLs {
+			y.YAML, err = yamls.AddPatchesToYAML(clientset, y.YAML, vz.Spec.Patches, rm)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	yamlMap := make(map[string]string)
+	for _, y := range vzYAMLs {
+		yamlMap[y.Name] = y.YAML
+	}
+	return yamlMap, nil
+}
+
+// rewriteVizierImages points the images in the given Vizier YAMLs at the Vizier's custom registry, if any.
+func rewriteVizierImages(yamlMap map[string]string, vz *v1alpha1.Vizier) {
+	if vz.Spec.Registry == "" {
+		return
+	}
+	for k, y := range yamlMap {
+		yamlMap[k] = artifacts.RewriteImageRegistry(y, vz.Spec.Registry)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/utils/shared/artifacts"
+	"px.dev/pixie/src/utils/shared/yamls"
+)
+
+func TestGetBundleVersion(t *testing.T) {
+	parts, err := artifacts.VizierBundleConfigMapsData(&artifacts.Bundle{
+		Manifest: &artifacts.BundleManifest{VizierVersion: "0.9.1"},
+		VizierTemplates: []*yamls.YAMLFile{
+			{Name: "secrets", YAML: "kind: Secret"},
+			// Large enough that the bundle is split across several ConfigMaps.
+			{Name: "large", YAML: randomYAML(2 * 1024 * 1024)},
+		},
+	})
+	require.NoError(t, err)
+	require.Greater(t, len(parts), 1)
+
+	clientset := fake.NewSimpleClientset()
+	for i, data := range parts {
+		_, err := clientset.CoreV1().ConfigMaps("pl").Create(context.Background(), &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: artifacts.VizierBundleConfigMapName("pl-vizier-bundle", i), Namespace: "pl"},
+			BinaryData: data,
+		}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	vz := &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{BundleConfigMap: "pl-vizier-bundle"}}
+
+	version, err := getBundleVersion(context.Background(), clientset, "pl", vz)
+	require.NoError(t, err)
+	assert.Equal(t, "0.9.1", version)
+
+	vz.Spec.BundleConfigMap = "missing"
+	_, err = getBundleVersion(context.Background(), clientset, "pl", vz)
+	assert.Error(t, err)
+}
+
+func TestRewriteVizierImages(t *testing.T) {
+	yamlMap := map[string]string{
+		"nats": "containers:\n- name: nats\n  image: nats:2.1\n",
+	}
+
+	rewriteVizierImages(yamlMap, &v1alpha1.Vizier{})
+	assert.Equal(t, "containers:\n- name: nats\n  image: nats:2.1\n", yamlMap["nats"])
+
+	rewriteVizierImages(yamlMap, &v1alpha1.Vizier{Spec: v1alpha1.VizierSpec{Registry: "registry.internal"}})
+	assert.Equal(t, "containers:\n- name: nats\n  image: registry.internal/nats:2.1\n", yamlMap["nats"])
+}
+
+// randomYAML returns an incompressible YAML string of roughly the given size.
+func randomYAML(size int) string {
+	var sb strings.Builder
+	sb.WriteString("data: ")
+	x := uint32(1)
+	for sb.Len() < size {
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		sb.WriteByte(byte('a' + x%26))
+	}
+	return sb.String()
+}
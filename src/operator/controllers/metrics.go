@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	pixiev1alpha1 "px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+var (
+	vizierCheckStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vizier_check_status",
+		Help: "Result of each Vizier health check: 1 if the check passed, 0 if it failed, and -1 if it could not be performed.",
+	}, []string{"namespace", "vizier", "check"})
+
+	vizierPhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vizier_phase",
+		Help: "Whether the Vizier is in the given phase.",
+	}, []string{"namespace", "vizier", "phase"})
+
+	vizierIncompatibleNodes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vizier_incompatible_nodes",
+		Help: "Number of nodes with a kernel version that is not supported by Pixie.",
+	}, []string{"namespace", "vizier"})
+
+	vizierCrashingPEMs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "vizier_crashing_pems",
+		Help: "Number of running PEM pods which are crashing.",
+	}, []string{"namespace", "vizier"})
+)
+
+var allVizierPhases = []pixiev1alpha1.VizierPhase{
+	pixiev1alpha1.VizierPhaseDisconnected,
+	pixiev1alpha1.VizierPhaseHealthy,
+	pixiev1alpha1.VizierPhaseUpdating,
+	pixiev1alpha1.VizierPhaseUnhealthy,
+	pixiev1alpha1.VizierPhaseDegraded,
+}
+
+var allVizierConditionTypes = []string{
+	pixiev1alpha1.VizierConditionVersionSupported,
+	pixiev1alpha1.VizierConditionControlPlaneReady,
+	pixiev1alpha1.VizierConditionNATSReady,
+	pixiev1alpha1.VizierConditionPEMResourcesAvailable,
+	pixiev1alpha1.VizierConditionPEMsRunning,
+	pixiev1alpha1.VizierConditionCloudConnected,
+	pixiev1alpha1.VizierConditionMetadataPVCReady,
+	pixiev1alpha1.VizierConditionNodesCompatible,
+}
+
+func init() {
+	// Register with the controller-runtime registry, so that the metrics are served from the manager's metrics endpoint.
+	metrics.Registry.MustRegister(vizierCheckStatus)
+	metrics.Registry.MustRegister(vizierPhase)
+	metrics.Registry.MustRegister(vizierIncompatibleNodes)
+	metrics.Registry.MustRegister(vizierCrashingPEMs)
+}
+
+// recordVizierMetrics exports the results of the Vizier's health checks, and the status derived from them.
+func recordVizierMetrics(vz *pixiev1alpha1.Vizier, checks []*vizierCheck) {
+	for _, c := range checks {
+		val := 0.0
+		switch {
+		case c.state == nil:
+			val = -1.0
+		case isOk(c.state):
+			val = 1.0
+		}
+		vizierCheckStatus.WithLabelValues(vz.Namespace, vz.Name, c.conditionType).Set(val)
+	}
+
+	for _, phase := range allVizierPhases {
+		val := 0.0
+		if vz.Status.VizierPhase == phase {
+			val = 1.0
+		}
+		vizierPhase.WithLabelValues(vz.Namespace, vz.Name, string(phase)).Set(val)
+	}
+	vizierIncompatibleNodes.WithLabelValues(vz.Namespace, vz.Name).Set(float64(vz.Status.IncompatibleNodes))
+	vizierCrashingPEMs.WithLabelValues(vz.Namespace, vz.Name).Set(float64(vz.Status.CrashingPEMs))
+}
+
+// deleteVizierMetrics removes the series exported for the Vizier, so that a deleted Vizier is not
+// reported with the values it last had.
+func deleteVizierMetrics(namespace, name string) {
+	for _, conditionType := range allVizierConditionTypes {
+		vizierCheckStatus.DeleteLabelValues(namespace, name, conditionType)
+	}
+	for _, phase := range allVizierPhases {
+		vizierPhase.DeleteLabelValues(namespace, name, string(phase))
+	}
+	vizierIncompatibleNodes.DeleteLabelValues(namespace, name)
+	vizierCrashingPEMs.DeleteLabelValues(namespace, name)
+}
@@ -33,6 +33,8 @@ import (
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
@@ -116,9 +118,9 @@ type VizierMonitor struct {
 	podStates *concurrentPodMap
 
 	// States from the various state-updaters, which should be aggregated into a single status.
-	nodeState       *vizierState
-	pvcState        *vizierState
-	aggregatedState *vizierState
+	stateMu   sync.Mutex
+	nodeState *vizierState
+	pvcState  *vizierState
 
 	vzUpdate func(context.Context, client.Object, ...client.UpdateOption) error
 	vzGet    func(context.Context, types.NamespacedName, client.Object) error
@@ -218,6 +220,9 @@ func (m *VizierMonitor) watchK8sPods() {
 type vizierState struct {
 	// Reason is the description of the state. Should only be set with values enumerated in `src/shared/status/vzstatus.go`
 	Reason status.VizierReason
+	// NumFailing is the number of resources, such as nodes or pods, which failed the check that produced this
+	// state. Only set by checks which count resources.
+	NumFailing int32
 }
 
 func okState() *vizierState {
@@ -446,58 +451,107 @@ func getPEMCrashingState(pods *concurrentPodMap) *vizierState {
 	}
 	numPems := float64(len(pems))
 	if pemCrashing == numPems {
-		return &vizierState{Reason: status.PEMsAllFailing}
+		return &vizierState{Reason: status.PEMsAllFailing, NumFailing: int32(pemCrashing)}
 	}
 	if pemCrashing > numPems*pemCrashingThreshold {
-		return &vizierState{Reason: status.PEMsHighFailureRate}
+		return &vizierState{Reason: status.PEMsHighFailureRate, NumFailing: int32(pemCrashing)}
 	}
-	return okState()
+	return &vizierState{Reason: "", NumFailing: int32(pemCrashing)}
 }
 
-// getvizierState determines the state of the Vizier instance based on the snapshot
-// of data available at call time. Reports the first state that fails (does not aggregate),
-// otherwise reports a healthy state.
-func (m *VizierMonitor) getvizierState(vz *pixiev1alpha1.Vizier) *vizierState {
-	// Check the latest vizier version, and current vizier version first. Regardless of
-	// whether the vizier pods are running, we consider the cluster in a degraded state.
-	atClient := cloudpb.NewArtifactTrackerClient(m.cloudClient)
-	vzVersionState := getVizierVersionState(atClient, vz)
-	if vzVersionState != nil && !isOk(vzVersionState) {
-		return vzVersionState
+// getControlPlaneDependentStates checks the control plane, followed by NATS and the cloud connector,
+// whose checks query the statusz endpoints of pods which depend on the control plane, and on NATS for
+// the cloud connector. A check is skipped, and its state is nil, while what it depends on is failing.
+func getControlPlaneDependentStates(httpClient HTTPClient, pods *concurrentPodMap) (controlPlane, nats, cloudConn *vizierState) {
+	controlPlane = getControlPlanePodState(pods)
+	if !isOk(controlPlane) {
+		return controlPlane, nil, nil
 	}
-
-	podState := getControlPlanePodState(m.podStates)
-	if !isOk(podState) {
-		return podState
+	nats = getNATSState(httpClient, pods)
+	if !isOk(nats) {
+		return controlPlane, nats, nil
 	}
+	return controlPlane, nats, getCloudConnState(httpClient, pods)
+}
 
-	natsState := getNATSState(m.httpClient, m.podStates)
-	if !isOk(natsState) {
-		return natsState
-	}
+// vizierCheck is the result of one of the health checks which make up the state of the Vizier.
+type vizierCheck struct {
+	// conditionType is the type of the condition which the check is reported as in the Vizier status.
+	conditionType string
+	// state is the result of the check. A nil state means that the check could not be performed.
+	state *vizierState
+}
 
-	pemResourceState := getPEMResourceLimitsState(m.podStates)
-	if !isOk(pemResourceState) {
-		return pemResourceState
-	}
+// runVizierChecks runs each of the health checks for the Vizier instance, based on the snapshot
+// of data available at call time. The checks are ordered by how severely a failure affects the Vizier.
+func (m *VizierMonitor) runVizierChecks(vz *pixiev1alpha1.Vizier) []*vizierCheck {
+	atClient := cloudpb.NewArtifactTrackerClient(m.cloudClient)
 
-	pemCrashingState := getPEMCrashingState(m.podStates)
-	if !isOk(pemCrashingState) {
-		return pemCrashingState
-	}
+	controlPlaneState, natsState, ccState := getControlPlaneDependentStates(m.httpClient, m.podStates)
 
-	ccState := getCloudConnState(m.httpClient, m.podStates)
-	if !isOk(ccState) {
-		return ccState
-	}
+	// Check the latest vizier version, and current vizier version first. Regardless of
+	// whether the vizier pods are running, we consider the cluster in a degraded state.
+	checks := []*vizierCheck{
+		{pixiev1alpha1.VizierConditionVersionSupported, getVizierVersionState(atClient, vz)},
+		{pixiev1alpha1.VizierConditionControlPlaneReady, controlPlaneState},
+		{pixiev1alpha1.VizierConditionNATSReady, natsState},
+		{pixiev1alpha1.VizierConditionPEMResourcesAvailable, getPEMResourceLimitsState(m.podStates)},
+		{pixiev1alpha1.VizierConditionPEMsRunning, getPEMCrashingState(m.podStates)},
+		{pixiev1alpha1.VizierConditionCloudConnected, ccState},
+	}
+
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	checks = append(checks, &vizierCheck{pixiev1alpha1.VizierConditionNodesCompatible, m.nodeState})
+	// The metadata PVC is only needed if the metadata service is not backed by the etcd operator.
+	if !vz.Spec.UseEtcdOperator {
+		checks = append(checks, &vizierCheck{pixiev1alpha1.VizierConditionMetadataPVCReady, m.pvcState})
+	}
+	return checks
+}
 
-	if !isOk(m.aggregatedState) {
-		return m.aggregatedState
+// summarizeVizierChecks determines the state of the Vizier instance from the results of its checks.
+// Reports the first state that fails (does not aggregate), otherwise reports a healthy state.
+func summarizeVizierChecks(checks []*vizierCheck) *vizierState {
+	for _, c := range checks {
+		if c.state != nil && !isOk(c.state) {
+			return c.state
+		}
 	}
-
 	return okState()
 }
 
+var conditionReasonRe = regexp.MustCompile(`^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$`)
+
+// checkToCondition converts the result of a check into a condition for the Vizier status.
+func checkToCondition(c *vizierCheck, generation int64) metav1.Condition {
+	cond := metav1.Condition{
+		Type:               c.conditionType,
+		ObservedGeneration: generation,
+	}
+	switch {
+	case c.state == nil:
+		cond.Status = metav1.ConditionUnknown
+		cond.Reason = "CheckUnavailable"
+		cond.Message = "The check could not be performed."
+	case isOk(c.state):
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = "Healthy"
+	default:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = string(c.state.Reason)
+		cond.Message = status.GetMessageFromReason(c.state.Reason)
+		// Reasons reported by statusz endpoints may not be valid condition reasons.
+		if !conditionReasonRe.MatchString(cond.Reason) {
+			cond.Reason = "CheckFailed"
+		}
+		if cond.Message == "" {
+			cond.Message = string(c.state.Reason)
+		}
+	}
+	return cond
+}
+
 // translateReasonToPhase maps a specific VizierReason into a more general VizierPhase.
 // Empty reasons are considered healthy and unmatched reasons are by default unhealthy.
 func translateReasonToPhase(reason status.VizierReason) pixiev1alpha1.VizierPhase {
@@ -525,36 +579,14 @@ func (m *VizierMonitor) statusAggregator(nodeStateCh, pvcStateCh <-chan *vizierS
 		case <-m.ctx.Done():
 			return
 		case u := <-nodeStateCh:
+			m.stateMu.Lock()
 			m.nodeState = u
+			m.stateMu.Unlock()
 		case u := <-pvcStateCh:
+			m.stateMu.Lock()
 			m.pvcState = u
+			m.stateMu.Unlock()
 		}
-
-		if !isOk(m.nodeState) {
-			m.aggregatedState = m.nodeState
-			continue
-		}
-
-		// Shortcircuit, don't get vizier crd if the PVC is already in a good state.
-		if isOk(m.pvcState) {
-			m.aggregatedState = m.pvcState
-			continue
-		}
-
-		// PVC is bad or missing, check whether it's needed!
-		vz := &pixiev1alpha1.Vizier{}
-		err := m.vzGet(context.Background(), m.namespacedName, vz)
-		if err != nil {
-			log.WithError(err).Error("Failed to get vizier")
-			continue
-		}
-
-		if !vz.Spec.UseEtcdOperator {
-			m.aggregatedState = m.pvcState
-			continue
-		}
-
-		m.aggregatedState = okState()
 	}
 }
 
@@ -574,15 +606,10 @@ func (m *VizierMonitor) runReconciler() {
 				continue
 			}
 
-			vizierState := m.getvizierState(vz)
+			checks := m.runVizierChecks(vz)
+			updateVizierStatusFromChecks(vz, checks)
+			recordVizierMetrics(vz, checks)
 
-			vz.Status.VizierPhase = translateReasonToPhase(vizierState.Reason)
-			vz.Status.VizierReason = string(vizierState.Reason)
-			vz.Status.Message = status.GetMessageFromReason(vizierState.Reason)
-			// Default to the VizierReason if the message is empty.
-			if vz.Status.Message == "" {
-				vz.Status.Message = vz.Status.VizierReason
-			}
 			err = m.vzUpdate(context.Background(), vz)
 			if err != nil {
 				log.WithError(err).Error("Failed to update vizier status")
@@ -591,6 +618,33 @@ func (m *VizierMonitor) runReconciler() {
 	}
 }
 
+// updateVizierStatusFromChecks summarizes the results of the checks into the status of the Vizier, and
+// publishes each check as a condition.
+func updateVizierStatusFromChecks(vz *pixiev1alpha1.Vizier, checks []*vizierCheck) {
+	vizierState := summarizeVizierChecks(checks)
+
+	vz.Status.VizierPhase = translateReasonToPhase(vizierState.Reason)
+	vz.Status.VizierReason = string(vizierState.Reason)
+	vz.Status.Message = status.GetMessageFromReason(vizierState.Reason)
+	// Default to the VizierReason if the message is empty.
+	if vz.Status.Message == "" {
+		vz.Status.Message = vz.Status.VizierReason
+	}
+
+	for _, c := range checks {
+		meta.SetStatusCondition(&vz.Status.Conditions, checkToCondition(c, vz.Generation))
+		if c.state == nil {
+			continue
+		}
+		switch c.conditionType {
+		case pixiev1alpha1.VizierConditionNodesCompatible:
+			vz.Status.IncompatibleNodes = c.state.NumFailing
+		case pixiev1alpha1.VizierConditionPEMsRunning:
+			vz.Status.CrashingPEMs = c.state.NumFailing
+		}
+	}
+}
+
 // queryPodStatusz returns a pod's self-reported status as served by its statusz endpoint.
 func queryPodStatusz(client HTTPClient, pod *v1.Pod) (bool, string) {
 	podIP := pod.Status.PodIP
@@ -20,14 +20,17 @@ package controllers
 
 import (
 	"bytes"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"testing"
 
 	"github.com/gogo/protobuf/types"
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
@@ -551,6 +554,44 @@ var insufficientMemoryPodCondition = v1.PodCondition{
 	Message: "0/2 nodes are available: 2 Insufficient memory.",
 }
 
+// failingHTTPClient fails the test if a statusz endpoint is queried.
+type failingHTTPClient struct {
+	t *testing.T
+}
+
+func (f *failingHTTPClient) Get(url string) (*http.Response, error) {
+	f.t.Errorf("Unexpected request to %s", url)
+	return nil, errors.New("unexpected request")
+}
+
+func TestMonitor_getControlPlaneDependentStates(t *testing.T) {
+	t.Run("control plane pending", func(t *testing.T) {
+		pods := &concurrentPodMap{unsafeMap: make(map[string]map[string]*podWrapper)}
+		pods.write("vizier-metadata", "vizier-metadata", &podWrapper{pod: &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"plane": "control"}},
+			Status:     v1.PodStatus{Phase: v1.PodPending},
+		}})
+
+		controlPlane, nats, cloudConn := getControlPlaneDependentStates(&failingHTTPClient{t}, pods)
+		assert.Equal(t, status.ControlPlanePodsPending, controlPlane.Reason)
+		assert.Nil(t, nats)
+		assert.Nil(t, cloudConn)
+	})
+
+	t.Run("NATS missing", func(t *testing.T) {
+		pods := &concurrentPodMap{unsafeMap: make(map[string]map[string]*podWrapper)}
+		pods.write("vizier-metadata", "vizier-metadata", &podWrapper{pod: &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"plane": "control"}},
+			Status:     v1.PodStatus{Phase: v1.PodRunning},
+		}})
+
+		controlPlane, nats, cloudConn := getControlPlaneDependentStates(&failingHTTPClient{t}, pods)
+		assert.True(t, isOk(controlPlane))
+		assert.Equal(t, status.NATSPodMissing, nats.Reason)
+		assert.Nil(t, cloudConn)
+	})
+}
+
 func TestMonitor_getPEMsSomeInsufficientMemory(t *testing.T) {
 	type pem struct {
 		name       string
@@ -932,3 +973,79 @@ func TestMonitor_getPEMCrashingState(t *testing.T) {
 		})
 	}
 }
+
+func TestMonitor_updateVizierStatusFromChecks(t *testing.T) {
+	vz := &v1alpha1.Vizier{
+		ObjectMeta: metav1.ObjectMeta{Name: "pixie", Namespace: "pl", Generation: 3},
+	}
+	checks := []*vizierCheck{
+		{v1alpha1.VizierConditionVersionSupported, nil},
+		{v1alpha1.VizierConditionControlPlaneReady, okState()},
+		{v1alpha1.VizierConditionPEMsRunning, &vizierState{Reason: status.PEMsHighFailureRate, NumFailing: 2}},
+		{v1alpha1.VizierConditionCloudConnected, &vizierState{Reason: "some unexpected statusz"}},
+		{v1alpha1.VizierConditionNodesCompatible, &vizierState{Reason: "", NumFailing: 1}},
+	}
+
+	updateVizierStatusFromChecks(vz, checks)
+	recordVizierMetrics(vz, checks)
+
+	// The first failing check determines the phase.
+	assert.Equal(t, v1alpha1.VizierPhaseDegraded, vz.Status.VizierPhase)
+	assert.Equal(t, string(status.PEMsHighFailureRate), vz.Status.VizierReason)
+	assert.Equal(t, int32(2), vz.Status.CrashingPEMs)
+	assert.Equal(t, int32(1), vz.Status.IncompatibleNodes)
+
+	assert.Equal(t, 5, len(vz.Status.Conditions))
+	cond := meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionVersionSupported)
+	assert.Equal(t, metav1.ConditionUnknown, cond.Status)
+	cond = meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionControlPlaneReady)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	assert.Equal(t, int64(3), cond.ObservedGeneration)
+	cond = meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionPEMsRunning)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, string(status.PEMsHighFailureRate), cond.Reason)
+	assert.Equal(t, status.GetMessageFromReason(status.PEMsHighFailureRate), cond.Message)
+	cond = meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionCloudConnected)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, "CheckFailed", cond.Reason)
+	assert.Equal(t, "some unexpected statusz", cond.Message)
+	cond = meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionNodesCompatible)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+
+	assert.Equal(t, -1.0, testutil.ToFloat64(vizierCheckStatus.WithLabelValues("pl", "pixie", v1alpha1.VizierConditionVersionSupported)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(vizierCheckStatus.WithLabelValues("pl", "pixie", v1alpha1.VizierConditionControlPlaneReady)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(vizierCheckStatus.WithLabelValues("pl", "pixie", v1alpha1.VizierConditionPEMsRunning)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(vizierPhase.WithLabelValues("pl", "pixie", string(v1alpha1.VizierPhaseDegraded))))
+	assert.Equal(t, 0.0, testutil.ToFloat64(vizierPhase.WithLabelValues("pl", "pixie", string(v1alpha1.VizierPhaseHealthy))))
+	assert.Equal(t, 2.0, testutil.ToFloat64(vizierCrashingPEMs.WithLabelValues("pl", "pixie")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(vizierIncompatibleNodes.WithLabelValues("pl", "pixie")))
+
+	// Conditions are updated in place once the checks pass.
+	checks[2].state = okState()
+	updateVizierStatusFromChecks(vz, checks)
+	assert.Equal(t, 5, len(vz.Status.Conditions))
+	assert.Equal(t, metav1.ConditionTrue, meta.FindStatusCondition(vz.Status.Conditions, v1alpha1.VizierConditionPEMsRunning).Status)
+	assert.Equal(t, int32(0), vz.Status.CrashingPEMs)
+}
+
+func TestMonitor_deleteVizierMetrics(t *testing.T) {
+	vz := &v1alpha1.Vizier{
+		ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "pl"},
+	}
+	checks := []*vizierCheck{
+		{v1alpha1.VizierConditionControlPlaneReady, okState()},
+		{v1alpha1.VizierConditionPEMsRunning, &vizierState{Reason: status.PEMsHighFailureRate, NumFailing: 2}},
+	}
+	updateVizierStatusFromChecks(vz, checks)
+	recordVizierMetrics(vz, checks)
+
+	countSeries := func() int {
+		return testutil.CollectAndCount(vizierCheckStatus) + testutil.CollectAndCount(vizierPhase) +
+			testutil.CollectAndCount(vizierIncompatibleNodes) + testutil.CollectAndCount(vizierCrashingPEMs)
+	}
+	before := countSeries()
+
+	deleteVizierMetrics("pl", "deleted")
+	// Two checks, one series per phase, and the node and PEM counts.
+	assert.Equal(t, before-(2+len(allVizierPhases)+2), countSeries())
+}
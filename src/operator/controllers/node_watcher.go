@@ -92,9 +92,9 @@ func (n *nodeCompatTracker) removeNode(node *v1.Node) {
 
 func (n *nodeCompatTracker) state() *vizierState {
 	if n.numIncompatible > degradedThreshold*n.numNodes {
-		return &vizierState{Reason: status.KernelVersionsIncompatible}
+		return &vizierState{Reason: status.KernelVersionsIncompatible, NumFailing: int32(n.numIncompatible)}
 	}
-	return okState()
+	return &vizierState{Reason: "", NumFailing: int32(n.numIncompatible)}
 }
 
 // NodeWatcher is responsible for tracking the nodes from the K8s API and using the NodeInfo to determine
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+const (
+	// defaultAutoRollbackTimeout is how long an updated Vizier may stay unhealthy before it is rolled back, if
+	// the auto rollback policy does not specify a timeout.
+	defaultAutoRollbackTimeout = 10 * time.Minute
+)
+
+// Reasons for the events emitted while tracking and rolling back Vizier updates.
+const (
+	eventReasonHealthyVersionRecorded = "HealthyVersionRecorded"
+	eventReasonUpdateFailed           = "UpdateFailed"
+	eventReasonRollbackStarted        = "RollbackStarted"
+	eventReasonRollbackSucceeded      = "RollbackSucceeded"
+	eventReasonRollbackFailed         = "RollbackFailed"
+)
+
+func autoRollbackEnabled(vz *v1alpha1.Vizier) bool {
+	return vz.Spec.AutoRollback != nil && vz.Spec.AutoRollback.Enabled
+}
+
+func autoRollbackTimeout(vz *v1alpha1.Vizier) time.Duration {
+	if vz.Spec.AutoRollback == nil || vz.Spec.AutoRollback.TimeoutSeconds <= 0 {
+		return defaultAutoRollbackTimeout
+	}
+	return time.Duration(vz.Spec.AutoRollback.TimeoutSeconds) * time.Second
+}
+
+// isHealthyDeployment returns whether the desired version of the Vizier has been deployed and is healthy.
+func isHealthyDeployment(vz *v1alpha1.Vizier) bool {
+	return vz.Status.ReconciliationPhase == v1alpha1.ReconciliationPhaseReady &&
+		vz.Status.VizierPhase == v1alpha1.VizierPhaseHealthy &&
+		vz.Status.Version == vz.Spec.Version
+}
+
+// isFailedDeployment returns whether the deployment of the desired version of the Vizier has failed, either
+// because the reconciler could not deploy it, or because it did not become healthy within the rollback timeout.
+func isFailedDeployment(vz *v1alpha1.Vizier, now time.Time) bool {
+	switch vz.Status.ReconciliationPhase {
+	case v1alpha1.ReconciliationPhaseFailed:
+		return true
+	case v1alpha1.ReconciliationPhaseReady:
+		if vz.Status.VizierPhase == v1alpha1.VizierPhaseHealthy || vz.Status.Version != vz.Spec.Version {
+			return false
+		}
+		if vz.Status.LastReconciliationPhaseTime == nil {
+			return false
+		}
+		return now.Sub(vz.Status.LastReconciliationPhaseTime.Time) >= autoRollbackTimeout(vz)
+	default:
+		return false
+	}
+}
+
+// isLastHealthySpec returns whether the Vizier's spec is the last spec that was deployed and healthy.
+func isLastHealthySpec(vz *v1alpha1.Vizier) bool {
+	return vz.Status.LastHealthySpec != nil && equality.Semantic.DeepEqual(*vz.Status.LastHealthySpec, vz.Spec)
+}
+
+// handleVizierRollback records the spec of healthy Viziers and, if the Vizier's auto rollback policy allows,
+// redeploys the last healthy spec of Viziers whose update has failed. Updates which only change the Vizier's
+// configuration are rolled back the same way as version updates.
+func (r *VizierReconciler) handleVizierRollback(ctx context.Context, vz *v1alpha1.Vizier, now time.Time) error {
+	vzClient := r.VzClient.PxV1alpha1().Viziers(vz.Namespace)
+
+	if isHealthyDeployment(vz) {
+		if isLastHealthySpec(vz) && vz.Status.RolledBackFromVersion == "" {
+			return nil
+		}
+		rolledBackFrom := vz.Status.RolledBackFromVersion
+		if vz.Spec.Version != vz.Status.LastHealthyVersion {
+			// A different version is healthy, so the version that failed before may be deployed again.
+			vz.Status.FailedVersion = ""
+		}
+		vz.Status.LastHealthyVersion = vz.Spec.Version
+		vz.Status.LastHealthySpec = vz.Spec.DeepCopy()
+		vz.Status.RolledBackFromVersion = ""
+		if _, err := vzClient.UpdateStatus(ctx, vz, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+
+		if rolledBackFrom != "" {
+			r.Recorder.Eventf(vz, v1.EventTypeNormal, eventReasonRollbackSucceeded,
+				"Rolled back from version %s to healthy version %s", rolledBackFrom, vz.Spec.Version)
+			return nil
+		}
+		r.Recorder.Eventf(vz, v1.EventTypeNormal, eventReasonHealthyVersionRecorded,
+			"Recorded version %s as the last healthy version", vz.Spec.Version)
+		return nil
+	}
+
+	if !autoRollbackEnabled(vz) || !isFailedDeployment(vz, now) {
+		return nil
+	}
+
+	if vz.Status.LastHealthySpec == nil || isLastHealthySpec(vz) {
+		// There is nothing to roll back to. If this was a rollback, report that it failed, but only once.
+		if vz.Status.RolledBackFromVersion == "" {
+			return nil
+		}
+		r.Recorder.Eventf(vz, v1.EventTypeWarning, eventReasonRollbackFailed,
+			"Rollback from version %s to version %s did not become healthy", vz.Status.RolledBackFromVersion, vz.Spec.Version)
+		vz.Status.RolledBackFromVersion = ""
+		_, err := vzClient.UpdateStatus(ctx, vz, metav1.UpdateOptions{})
+		return err
+	}
+
+	failedVersion := vz.Spec.Version
+	configOnly := failedVersion == vz.Status.LastHealthyVersion
+	if configOnly {
+		r.Recorder.Eventf(vz, v1.EventTypeWarning, eventReasonUpdateFailed,
+			"Configuration change of version %s failed to become healthy", failedVersion)
+	} else {
+		r.Recorder.Eventf(vz, v1.EventTypeWarning, eventReasonUpdateFailed,
+			"Update to version %s failed to become healthy", failedVersion)
+	}
+	log.WithField("version", failedVersion).WithField("rollbackVersion", vz.Status.LastHealthyVersion).Info("Rolling back failed Vizier update")
+
+	vz.Spec = *vz.Status.LastHealthySpec.DeepCopy()
+	updated, err := vzClient.Update(ctx, vz, metav1.UpdateOptions{})
+	if err != nil {
+		r.Recorder.Eventf(vz, v1.EventTypeWarning, eventReasonRollbackFailed,
+			"Failed to roll back to version %s: %s", vz.Spec.Version, err.Error())
+		return err
+	}
+
+	// The failed spec may be partially deployed, so make sure that the reconciler redeploys the healthy spec, even
+	// if only its configuration changed.
+	updated.Status.Version = ""
+	updated.Status.RolledBackFromVersion = failedVersion
+	updated.Status.Message = fmt.Sprintf("Rolling back from version %s to version %s", failedVersion, updated.Spec.Version)
+	if configOnly {
+		updated.Status.Message = fmt.Sprintf("Rolling back to the last healthy configuration of version %s", failedVersion)
+	} else {
+		// Keep the cloud from updating the Vizier to the failed version again.
+		updated.Status.FailedVersion = failedVersion
+	}
+	updated, err = vzClient.UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	r.Recorder.Event(updated, v1.EventTypeNormal, eventReasonRollbackStarted, updated.Status.Message)
+	return nil
+}
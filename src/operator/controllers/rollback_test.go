@@ -0,0 +1,240 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/operator/client/versioned/fake"
+)
+
+func newTestVizier(version string, rp v1alpha1.ReconciliationPhase, phase v1alpha1.VizierPhase, phaseTime time.Time) *v1alpha1.Vizier {
+	t := metav1.NewTime(phaseTime)
+	return &v1alpha1.Vizier{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pixie",
+			Namespace: "pl",
+		},
+		Spec: v1alpha1.VizierSpec{
+			Version:      version,
+			AutoRollback: &v1alpha1.AutoRollbackPolicy{Enabled: true, TimeoutSeconds: 300},
+		},
+		Status: v1alpha1.VizierStatus{
+			Version:                     version,
+			ReconciliationPhase:         rp,
+			VizierPhase:                 phase,
+			LastReconciliationPhaseTime: &t,
+		},
+	}
+}
+
+func getTestVizier(t *testing.T, r *VizierReconciler) *v1alpha1.Vizier {
+	vz, err := r.VzClient.PxV1alpha1().Viziers("pl").Get(context.Background(), "pixie", metav1.GetOptions{})
+	require.NoError(t, err)
+	return vz
+}
+
+func assertEvent(t *testing.T, recorder *record.FakeRecorder, expected string) {
+	select {
+	case e := <-recorder.Events:
+		assert.Equal(t, expected, e)
+	default:
+		t.Fatalf("Expected event %q", expected)
+	}
+}
+
+func TestVizierReconciler_RecordsHealthyVersion(t *testing.T) {
+	now := time.Now()
+	vz := newTestVizier("0.1.0", v1alpha1.ReconciliationPhaseReady, v1alpha1.VizierPhaseHealthy, now)
+	recorder := record.NewFakeRecorder(10)
+	r := &VizierReconciler{
+		VzClient: fake.NewSimpleClientset(vz),
+		Recorder: recorder,
+	}
+
+	require.NoError(t, r.handleVizierRollback(context.Background(), getTestVizier(t, r), now))
+	updated := getTestVizier(t, r)
+	assert.Equal(t, "0.1.0", updated.Status.LastHealthyVersion)
+	require.NotNil(t, updated.Status.LastHealthySpec)
+	assert.Equal(t, "0.1.0", updated.Status.LastHealthySpec.Version)
+	assertEvent(t, recorder, "Normal HealthyVersionRecorded Recorded version 0.1.0 as the last healthy version")
+
+	// The healthy version is only recorded once.
+	require.NoError(t, r.handleVizierRollback(context.Background(), updated, now))
+	assert.Equal(t, 0, len(recorder.Events))
+}
+
+func TestVizierReconciler_RollsBackFailedUpdate(t *testing.T) {
+	tests := []struct {
+		name           string
+		rp             v1alpha1.ReconciliationPhase
+		phase          v1alpha1.VizierPhase
+		elapsed        time.Duration
+		disabled       bool
+		expectRollback bool
+	}{
+		{
+			name:           "update failed",
+			rp:             v1alpha1.ReconciliationPhaseFailed,
+			phase:          v1alpha1.VizierPhaseUpdating,
+			elapsed:        time.Minute,
+			expectRollback: true,
+		},
+		{
+			name:           "unhealthy past timeout",
+			rp:             v1alpha1.ReconciliationPhaseReady,
+			phase:          v1alpha1.VizierPhaseUnhealthy,
+			elapsed:        10 * time.Minute,
+			expectRollback: true,
+		},
+		{
+			name:           "unhealthy within timeout",
+			rp:             v1alpha1.ReconciliationPhaseReady,
+			phase:          v1alpha1.VizierPhaseUnhealthy,
+			elapsed:        time.Minute,
+			expectRollback: false,
+		},
+		{
+			name:           "still updating",
+			rp:             v1alpha1.ReconciliationPhaseUpdating,
+			phase:          v1alpha1.VizierPhaseUpdating,
+			elapsed:        10 * time.Minute,
+			expectRollback: false,
+		},
+		{
+			name:           "rollback disabled",
+			rp:             v1alpha1.ReconciliationPhaseFailed,
+			phase:          v1alpha1.VizierPhaseUnhealthy,
+			elapsed:        time.Minute,
+			disabled:       true,
+			expectRollback: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now := time.Now()
+			vz := newTestVizier("0.2.0", test.rp, test.phase, now.Add(-test.elapsed))
+			vz.Status.LastHealthyVersion = "0.1.0"
+			vz.Status.LastHealthySpec = &v1alpha1.VizierSpec{
+				Version:      "0.1.0",
+				AutoRollback: &v1alpha1.AutoRollbackPolicy{Enabled: true},
+			}
+			if test.disabled {
+				vz.Spec.AutoRollback.Enabled = false
+			}
+			recorder := record.NewFakeRecorder(10)
+			r := &VizierReconciler{
+				VzClient: fake.NewSimpleClientset(vz),
+				Recorder: recorder,
+			}
+
+			require.NoError(t, r.handleVizierRollback(context.Background(), getTestVizier(t, r), now))
+			updated := getTestVizier(t, r)
+			if !test.expectRollback {
+				assert.Equal(t, "0.2.0", updated.Spec.Version)
+				assert.Equal(t, "", updated.Status.RolledBackFromVersion)
+				assert.Equal(t, 0, len(recorder.Events))
+				return
+			}
+
+			assert.Equal(t, "0.1.0", updated.Spec.Version)
+			assert.Equal(t, "", updated.Status.Version)
+			assert.Equal(t, "0.2.0", updated.Status.RolledBackFromVersion)
+			assert.Equal(t, "0.2.0", updated.Status.FailedVersion)
+			assertEvent(t, recorder, "Warning UpdateFailed Update to version 0.2.0 failed to become healthy")
+			assertEvent(t, recorder, "Normal RollbackStarted Rolling back from version 0.2.0 to version 0.1.0")
+
+			// Once the previous version is redeployed and healthy, the rollback is complete.
+			updated.Status.Version = "0.1.0"
+			updated.Status.VizierPhase = v1alpha1.VizierPhaseHealthy
+			updated.Status.ReconciliationPhase = v1alpha1.ReconciliationPhaseReady
+			require.NoError(t, r.handleVizierRollback(context.Background(), updated, now))
+			updated = getTestVizier(t, r)
+			assert.Equal(t, "", updated.Status.RolledBackFromVersion)
+			assert.Equal(t, "0.1.0", updated.Status.LastHealthyVersion)
+			// The failed version is remembered, so that the Vizier isn't updated to it again.
+			assert.Equal(t, "0.2.0", updated.Status.FailedVersion)
+			assertEvent(t, recorder, "Normal RollbackSucceeded Rolled back from version 0.2.0 to healthy version 0.1.0")
+
+			// Once another version is healthy, the failed version may be deployed again.
+			updated.Spec.Version = "0.3.0"
+			updated.Status.Version = "0.3.0"
+			require.NoError(t, r.handleVizierRollback(context.Background(), updated, now))
+			updated = getTestVizier(t, r)
+			assert.Equal(t, "", updated.Status.FailedVersion)
+			assert.Equal(t, "0.3.0", updated.Status.LastHealthyVersion)
+		})
+	}
+}
+
+func TestVizierReconciler_RollsBackConfigChange(t *testing.T) {
+	now := time.Now()
+	vz := newTestVizier("0.1.0", v1alpha1.ReconciliationPhaseReady, v1alpha1.VizierPhaseUnhealthy, now.Add(-10*time.Minute))
+	vz.Status.LastHealthyVersion = "0.1.0"
+	vz.Status.LastHealthySpec = vz.Spec.DeepCopy()
+	vz.Spec.PemMemoryLimit = "1Mi"
+	recorder := record.NewFakeRecorder(10)
+	r := &VizierReconciler{
+		VzClient: fake.NewSimpleClientset(vz),
+		Recorder: recorder,
+	}
+
+	require.NoError(t, r.handleVizierRollback(context.Background(), getTestVizier(t, r), now))
+	updated := getTestVizier(t, r)
+	assert.Equal(t, "", updated.Spec.PemMemoryLimit)
+	assert.Equal(t, "0.1.0", updated.Spec.Version)
+	// The status version is cleared, so that the healthy spec is redeployed.
+	assert.Equal(t, "", updated.Status.Version)
+	// The version itself didn't fail.
+	assert.Equal(t, "", updated.Status.FailedVersion)
+	assertEvent(t, recorder, "Warning UpdateFailed Configuration change of version 0.1.0 failed to become healthy")
+	assertEvent(t, recorder, "Normal RollbackStarted Rolling back to the last healthy configuration of version 0.1.0")
+}
+
+func TestVizierReconciler_FailedRollback(t *testing.T) {
+	now := time.Now()
+	vz := newTestVizier("0.1.0", v1alpha1.ReconciliationPhaseFailed, v1alpha1.VizierPhaseUnhealthy, now)
+	vz.Status.LastHealthyVersion = "0.1.0"
+	vz.Status.LastHealthySpec = vz.Spec.DeepCopy()
+	vz.Status.RolledBackFromVersion = "0.2.0"
+	recorder := record.NewFakeRecorder(10)
+	r := &VizierReconciler{
+		VzClient: fake.NewSimpleClientset(vz),
+		Recorder: recorder,
+	}
+
+	// The rollback itself failed, so there is nothing left to roll back to.
+	require.NoError(t, r.handleVizierRollback(context.Background(), getTestVizier(t, r), now))
+	updated := getTestVizier(t, r)
+	assert.Equal(t, "0.1.0", updated.Spec.Version)
+	assert.Equal(t, "", updated.Status.RolledBackFromVersion)
+	assertEvent(t, recorder, "Warning RollbackFailed Rollback from version 0.2.0 to version 0.1.0 did not become healthy")
+
+	require.NoError(t, r.handleVizierRollback(context.Background(), updated, now))
+	assert.Equal(t, 0, len(recorder.Events))
+}
@@ -33,14 +33,17 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/vizierconfigpb"
 	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/operator/client/versioned"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/utils/shared/certs"
 	"px.dev/pixie/src/utils/shared/k8s"
@@ -68,12 +71,15 @@ type VizierReconciler struct {
 
 	Clientset  *kubernetes.Clientset
 	RestConfig *rest.Config
+	VzClient   versioned.Interface
+	Recorder   record.EventRecorder
 
-	monitor *VizierMonitor
+	monitors map[types.NamespacedName]*VizierMonitor
 }
 
 // +kubebuilder:rbac:groups=pixie.px.dev,resources=viziers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=pixie.px.dev,resources=viziers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func getCloudClientConnection(cloudAddr string, devCloudNS string) (*grpc.ClientConn, error) {
 	isInternal := false
@@ -151,10 +157,11 @@ func (r *VizierReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			log.WithError(err).Info("Failed to delete Vizier instance")
 		}
 
-		if r.monitor != nil && r.monitor.namespace == req.Namespace {
-			r.monitor.Quit()
-			r.monitor = nil
+		if m, ok := r.monitors[req.NamespacedName]; ok {
+			m.Quit()
+			delete(r.monitors, req.NamespacedName)
 		}
+		deleteVizierMetrics(req.Namespace, req.Name)
 		// Vizier CRD deleted. The vizier instance should also be deleted.
 		return ctrl.Result{}, err
 	}
@@ -173,14 +180,14 @@ func (r *VizierReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		log.WithError(err).Info("Failed to update Vizier instance")
 	}
 
-	// Check if we are already monitoring this Vizier.
-	if r.monitor == nil || r.monitor.namespace != req.Namespace {
-		if r.monitor != nil {
-			r.monitor.Quit()
-			r.monitor = nil
+	// Check if we are already monitoring this Vizier. Each Vizier instance, which may live in its own namespace,
+	// is monitored separately.
+	if _, ok := r.monitors[req.NamespacedName]; !ok {
+		if r.monitors == nil {
+			r.monitors = make(map[types.NamespacedName]*VizierMonitor)
 		}
 
-		r.monitor = &VizierMonitor{
+		m := &VizierMonitor{
 			namespace:      req.Namespace,
 			namespacedName: req.NamespacedName,
 			vzUpdate:       r.Status().Update,
@@ -191,10 +198,11 @@ func (r *VizierReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		if err != nil {
 			log.WithError(err).Fatal("Failed to initialize vizier monitor")
 		}
-		err = r.monitor.InitAndStartMonitor(cloudClient)
+		err = m.InitAndStartMonitor(cloudClient)
 		if err != nil {
 			log.WithError(err).Fatal("Failed to initialize vizier monitor")
 		}
+		r.monitors[req.NamespacedName] = m
 	}
 
 	// Vizier CRD has been updated, and we should update the running vizier accordingly.
@@ -244,10 +252,15 @@ func (r *VizierReconciler) createVizier(ctx context.Context, req ctrl.Request, v
 	}
 
 	// If no version is set, we should fetch the latest version. This will trigger another reconcile that will do
-	// the actual vizier deployment.
+	// the actual vizier deployment. Viziers deployed from a bundle use the version contained in the bundle.
 	if vz.Spec.Version == "" {
-		atClient := cloudpb.NewArtifactTrackerClient(cloudClient)
-		latest, err := getLatestVizierVersion(ctx, atClient)
+		var latest string
+		if vz.Spec.BundleConfigMap != "" {
+			latest, err = getBundleVersion(ctx, r.Clientset, req.Namespace, vz)
+		} else {
+			atClient := cloudpb.NewArtifactTrackerClient(cloudClient)
+			latest, err = getLatestVizierVersion(ctx, atClient)
+		}
 		if err != nil {
 			log.WithError(err).Error("Failed to get latest Vizier version")
 			return err
@@ -329,16 +342,26 @@ func (r *VizierReconciler) deployVizier(ctx context.Context, req ctrl.Request, v
 		return err
 	}
 
-	configForVizierResp, err := generateVizierYAMLsConfig(ctx, req.Namespace, vz, cloudClient)
-	if err != nil {
-		log.WithError(err).Error("Failed to generate configs for Vizier YAMLs")
-		return err
-	}
-	yamlMap := configForVizierResp.NameToYamlContent
+	var yamlMap map[string]string
+	if vz.Spec.BundleConfigMap != "" {
+		yamlMap, err = generateVizierYAMLsFromBundle(ctx, r.Clientset, req.Namespace, vz)
+		if err != nil {
+			log.WithError(err).Error("Failed to generate Vizier YAMLs from bundle")
+			return err
+		}
+	} else {
+		configForVizierResp, err := generateVizierYAMLsConfig(ctx, req.Namespace, vz, cloudClient)
+		if err != nil {
+			log.WithError(err).Error("Failed to generate configs for Vizier YAMLs")
+			return err
+		}
+		yamlMap = configForVizierResp.NameToYamlContent
 
-	// Update Vizier CRD status sentryDSN so that it can be accessed by other
-	// vizier pods.
-	vz.Status.SentryDSN = configForVizierResp.SentryDSN
+		// Update Vizier CRD status sentryDSN so that it can be accessed by other
+		// vizier pods.
+		vz.Status.SentryDSN = configForVizierResp.SentryDSN
+	}
+	rewriteVizierImages(yamlMap, vz)
 
 	if !update {
 		err = r.deployVizierConfigs(ctx, req.Namespace, vz, yamlMap)
@@ -537,9 +560,75 @@ func updateResourceConfiguration(resource *k8s.Resource, vz *v1alpha1.Vizier) er
 	addKeyValueMapToResource("annotations", vz.Spec.Pod.Annotations, resource.Object.Object)
 	updateResourceRequirements(vz.Spec.Pod.Resources, resource.Object.Object)
 	updatePodSpec(vz.Spec.Pod.NodeSelector, vz.Spec.Pod.SecurityContext, resource.Object.Object)
+	if len(vz.Spec.NamespaceScope) > 0 {
+		scopeClusterResourceNames(vz.Namespace, resource.Object.Object)
+	}
 	return nil
 }
 
+// clusterScopedKinds are the kinds deployed with Vizier which are not namespaced, and whose names must therefore
+// be made unique when multiple Viziers run in the same cluster.
+var clusterScopedKinds = map[string]bool{
+	"ClusterRole":        true,
+	"ClusterRoleBinding": true,
+	"PodSecurityPolicy":  true,
+}
+
+// namespacedResourceName returns the name of a cluster-scoped resource belonging to the Vizier in the given namespace.
+func namespacedResourceName(name string, namespace string) string {
+	return fmt.Sprintf("%s-%s", name, namespace)
+}
+
+// scopeClusterResourceNames suffixes the names of cluster-scoped resources with the Vizier's namespace, and updates any
+// references to those resources, so that they do not collide with resources deployed by other Vizier instances.
+func scopeClusterResourceNames(namespace string, res map[string]interface{}) {
+	kind, _, _ := unstructured.NestedString(res, "kind")
+	if clusterScopedKinds[kind] {
+		if name, ok, err := unstructured.NestedString(res, "metadata", "name"); ok && err == nil {
+			_ = unstructured.SetNestedField(res, namespacedResourceName(name, namespace), "metadata", "name")
+		}
+	}
+
+	switch kind {
+	case "ClusterRoleBinding", "RoleBinding":
+		// Bindings must point at the renamed ClusterRole.
+		if refKind, _, _ := unstructured.NestedString(res, "roleRef", "kind"); refKind == "ClusterRole" {
+			if name, ok, err := unstructured.NestedString(res, "roleRef", "name"); ok && err == nil {
+				_ = unstructured.SetNestedField(res, namespacedResourceName(name, namespace), "roleRef", "name")
+			}
+		}
+	case "ClusterRole", "Role":
+		// Roles which grant use of a PodSecurityPolicy must reference the renamed policy.
+		rules, ok, err := unstructured.NestedSlice(res, "rules")
+		if !ok || err != nil {
+			return
+		}
+		for _, r := range rules {
+			rule, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			resources, _, _ := unstructured.NestedStringSlice(rule, "resources")
+			isPSP := false
+			for _, resource := range resources {
+				if resource == "podsecuritypolicies" {
+					isPSP = true
+				}
+			}
+			names, ok, _ := unstructured.NestedStringSlice(rule, "resourceNames")
+			if !isPSP || !ok {
+				continue
+			}
+			scoped := make([]interface{}, len(names))
+			for i, name := range names {
+				scoped[i] = namespacedResourceName(name, namespace)
+			}
+			rule["resourceNames"] = scoped
+		}
+		_ = unstructured.SetNestedSlice(res, rules, "rules")
+	}
+}
+
 func convertResourceType(originalLst v1.ResourceList) *vizierconfigpb.ResourceList {
 	transformedList := make(map[string]*vizierconfigpb.ResourceQuantity)
 	for rName, rQuantity := range originalLst {
@@ -560,33 +649,46 @@ func generateVizierYAMLsConfig(ctx context.Context, ns string, vz *v1alpha1.Vizi
 
 	req := &cloudpb.ConfigForVizierRequest{
 		Namespace: ns,
-		VzSpec: &vizierconfigpb.VizierSpec{
-			Version:               vz.Spec.Version,
-			DeployKey:             vz.Spec.DeployKey,
-			CustomDeployKeySecret: vz.Spec.CustomDeployKeySecret,
-			DisableAutoUpdate:     vz.Spec.DisableAutoUpdate,
-			UseEtcdOperator:       vz.Spec.UseEtcdOperator,
-			ClusterName:           vz.Spec.ClusterName,
-			CloudAddr:             vz.Spec.CloudAddr,
-			DevCloudNamespace:     vz.Spec.DevCloudNamespace,
-			PemMemoryLimit:        vz.Spec.PemMemoryLimit,
-			ClockConverter:        string(vz.Spec.ClockConverter),
-			DataAccess:            string(vz.Spec.DataAccess),
-			Pod_Policy: &vizierconfigpb.PodPolicyReq{
-				Labels:      vz.Spec.Pod.Labels,
-				Annotations: vz.Spec.Pod.Annotations,
-				Resources: &vizierconfigpb.ResourceReqs{
-					Limits:   convertResourceType(vz.Spec.Pod.Resources.Limits),
-					Requests: convertResourceType(vz.Spec.Pod.Resources.Requests),
-				},
-				NodeSelector: vz.Spec.Pod.NodeSelector,
+		VzSpec:    vizierSpecToConfig(vz),
+	}
+
+	resp, err := client.GetConfigForVizier(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// vizierSpecToConfig converts the Vizier CRD's spec to the spec used for generating Vizier YAMLs.
+func vizierSpecToConfig(vz *v1alpha1.Vizier) *vizierconfigpb.VizierSpec {
+	vzSpec := &vizierconfigpb.VizierSpec{
+		Version:               vz.Spec.Version,
+		DeployKey:             vz.Spec.DeployKey,
+		CustomDeployKeySecret: vz.Spec.CustomDeployKeySecret,
+		DisableAutoUpdate:     vz.Spec.DisableAutoUpdate,
+		UseEtcdOperator:       vz.Spec.UseEtcdOperator,
+		ClusterName:           vz.Spec.ClusterName,
+		CloudAddr:             vz.Spec.CloudAddr,
+		DevCloudNamespace:     vz.Spec.DevCloudNamespace,
+		PemMemoryLimit:        vz.Spec.PemMemoryLimit,
+		ClockConverter:        string(vz.Spec.ClockConverter),
+		DataAccess:            string(vz.Spec.DataAccess),
+		Pod_Policy: &vizierconfigpb.PodPolicyReq{
+			Labels:      vz.Spec.Pod.Labels,
+			Annotations: vz.Spec.Pod.Annotations,
+			Resources: &vizierconfigpb.ResourceReqs{
+				Limits:   convertResourceType(vz.Spec.Pod.Resources.Limits),
+				Requests: convertResourceType(vz.Spec.Pod.Resources.Requests),
 			},
-			Patches: vz.Spec.Patches,
+			NodeSelector: vz.Spec.Pod.NodeSelector,
 		},
+		Patches:           vz.Spec.Patches,
+		DefaultQueryFlags: vz.Spec.DefaultQueryFlags,
+		NamespaceScope:    vz.Spec.NamespaceScope,
 	}
 
 	if vz.Spec.DataCollectorParams != nil {
-		req.VzSpec.DataCollectorParams = &vizierconfigpb.DataCollectorParams{
+		vzSpec.DataCollectorParams = &vizierconfigpb.DataCollectorParams{
 			DatastreamBufferSize:      vz.Spec.DataCollectorParams.DatastreamBufferSize,
 			DatastreamBufferSpikeSize: vz.Spec.DataCollectorParams.DatastreamBufferSpikeSize,
 			CustomPEMFlags:            vz.Spec.DataCollectorParams.CustomPEMFlags,
@@ -594,16 +696,11 @@ func generateVizierYAMLsConfig(ctx context.Context, ns string, vz *v1alpha1.Vizi
 	}
 
 	if vz.Spec.LeadershipElectionParams != nil {
-		req.VzSpec.LeadershipElectionParams = &vizierconfigpb.LeadershipElectionParams{
+		vzSpec.LeadershipElectionParams = &vizierconfigpb.LeadershipElectionParams{
 			ElectionPeriodMs: vz.Spec.LeadershipElectionParams.ElectionPeriodMs,
 		}
 	}
-
-	resp, err := client.GetConfigForVizier(ctx, req)
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+	return vzSpec
 }
 
 // addKeyValueMapToResource adds the given keyValue map to the K8s resource.
@@ -774,7 +871,8 @@ func waitForCluster(clientset *kubernetes.Clientset, namespace string) error {
 }
 
 // watchForFailedVizierUpdates regularly polls for timed-out viziers
-// and marks matching Viziers ReconciliationPhases as failed.
+// and marks matching Viziers ReconciliationPhases as failed. Failed updates
+// are rolled back if allowed by the Vizier's auto rollback policy.
 func (r *VizierReconciler) watchForFailedVizierUpdates() {
 	t := time.NewTicker(updatingVizierCheckPeriod)
 	defer t.Stop()
@@ -786,17 +884,23 @@ func (r *VizierReconciler) watchForFailedVizierUpdates() {
 			log.WithError(err).Error("Unable to list the vizier objects")
 			continue
 		}
-		for _, vz := range viziersList.Items {
+		for i := range viziersList.Items {
+			vz := &viziersList.Items[i]
 			// Set the Vizier Reconciliation phase to Failed if an Update has timed out.
-			if vz.Status.ReconciliationPhase != v1alpha1.ReconciliationPhaseUpdating {
-				continue
+			if vz.Status.ReconciliationPhase == v1alpha1.ReconciliationPhaseUpdating &&
+				time.Since(vz.Status.LastReconciliationPhaseTime.Time) >= updatingFailedTimeout {
+				err := r.Status().Update(ctx, setReconciliationPhase(vz, v1alpha1.ReconciliationPhaseFailed))
+				if err != nil {
+					log.WithError(err).Error("Unable to update vizier status")
+					continue
+				}
+				r.Recorder.Eventf(vz, v1.EventTypeWarning, eventReasonUpdateFailed,
+					"Update to version %s timed out", vz.Spec.Version)
 			}
-			if time.Since(vz.Status.LastReconciliationPhaseTime.Time) < updatingFailedTimeout {
-				continue
-			}
-			err := r.Status().Update(ctx, setReconciliationPhase(&vz, v1alpha1.ReconciliationPhaseFailed))
+
+			err = r.handleVizierRollback(ctx, vz, time.Now())
 			if err != nil {
-				log.WithError(err).Error("Unable to update vizier status")
+				log.WithError(err).Error("Unable to roll back vizier")
 			}
 		}
 	}
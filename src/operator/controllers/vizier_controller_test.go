@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeClusterResourceNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		res      map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name: "cluster role binding",
+			res: map[string]interface{}{
+				"kind":     "ClusterRoleBinding",
+				"metadata": map[string]interface{}{"name": "pl-node-view-binding"},
+				"roleRef":  map[string]interface{}{"kind": "ClusterRole", "name": "pl-node-view"},
+			},
+			expected: map[string]interface{}{
+				"kind":     "ClusterRoleBinding",
+				"metadata": map[string]interface{}{"name": "pl-node-view-binding-team-a"},
+				"roleRef":  map[string]interface{}{"kind": "ClusterRole", "name": "pl-node-view-team-a"},
+			},
+		},
+		{
+			name: "role binding to a role",
+			res: map[string]interface{}{
+				"kind":     "RoleBinding",
+				"metadata": map[string]interface{}{"name": "pl-vizier-crd-binding"},
+				"roleRef":  map[string]interface{}{"kind": "Role", "name": "pl-vizier-crd-role"},
+			},
+			expected: map[string]interface{}{
+				"kind":     "RoleBinding",
+				"metadata": map[string]interface{}{"name": "pl-vizier-crd-binding"},
+				"roleRef":  map[string]interface{}{"kind": "Role", "name": "pl-vizier-crd-role"},
+			},
+		},
+		{
+			name: "cluster role using a pod security policy",
+			res: map[string]interface{}{
+				"kind":     "ClusterRole",
+				"metadata": map[string]interface{}{"name": "pl-psp"},
+				"rules": []interface{}{
+					map[string]interface{}{
+						"resources":     []interface{}{"podsecuritypolicies"},
+						"resourceNames": []interface{}{"pl"},
+						"verbs":         []interface{}{"use"},
+					},
+					map[string]interface{}{
+						"resources": []interface{}{"pods"},
+						"verbs":     []interface{}{"get"},
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"kind":     "ClusterRole",
+				"metadata": map[string]interface{}{"name": "pl-psp-team-a"},
+				"rules": []interface{}{
+					map[string]interface{}{
+						"resources":     []interface{}{"podsecuritypolicies"},
+						"resourceNames": []interface{}{"pl-team-a"},
+						"verbs":         []interface{}{"use"},
+					},
+					map[string]interface{}{
+						"resources": []interface{}{"pods"},
+						"verbs":     []interface{}{"get"},
+					},
+				},
+			},
+		},
+		{
+			name: "namespaced resource",
+			res: map[string]interface{}{
+				"kind":     "Deployment",
+				"metadata": map[string]interface{}{"name": "vizier-metadata"},
+			},
+			expected: map[string]interface{}{
+				"kind":     "Deployment",
+				"metadata": map[string]interface{}{"name": "vizier-metadata"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scopeClusterResourceNames("team-a", test.res)
+			assert.Equal(t, test.expected, test.res)
+		})
+	}
+}
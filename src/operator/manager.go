@@ -29,6 +29,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/operator/client/versioned"
 	"px.dev/pixie/src/operator/controllers"
 	"px.dev/pixie/src/utils/shared/k8s"
 	// +kubebuilder:scaffold:imports
@@ -52,10 +53,14 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var watchNamespace string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&watchNamespace, "watch-namespace", os.Getenv("PL_WATCH_NAMESPACE"),
+		"The namespace the operator watches for Vizier resources. If empty, all namespaces are watched. "+
+			"Setting this allows several operators, each managing its own Vizier, to run in the same cluster.")
 	flag.Parse()
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
@@ -64,6 +69,7 @@ func main() {
 		Port:               9443,
 		LeaderElection:     enableLeaderElection,
 		LeaderElectionID:   leaderElectionID,
+		Namespace:          watchNamespace,
 	})
 	if err != nil {
 		log.WithError(err).Error("Unable to start manager")
@@ -76,12 +82,19 @@ func main() {
 		os.Exit(1)
 	}
 	clientset := k8s.GetClientset(kubeConfig)
+	vzClient, err := versioned.NewForConfig(kubeConfig)
+	if err != nil {
+		log.WithError(err).Error("Unable to create vizier clientset")
+		os.Exit(1)
+	}
 
 	if err = (&controllers.VizierReconciler{
 		Client:     mgr.GetClient(),
 		Scheme:     mgr.GetScheme(),
 		Clientset:  clientset,
 		RestConfig: kubeConfig,
+		VzClient:   vzClient,
+		Recorder:   mgr.GetEventRecorderFor("vizier-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		log.WithError(err).Error("Unable to create controller")
 		os.Exit(1)
@@ -38,6 +38,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/skratchdot/open-golang/open"
 	"golang.org/x/term"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"gopkg.in/segmentio/analytics-go.v3"
 
@@ -56,11 +57,15 @@ var errUserChallengeTimeout = errors.New("timeout waiting for user")
 var errBrowserFailed = errors.New("browser failed to open")
 var errServerListenerFailed = errors.New("failed to start up local server")
 var errUserNotRegistered = errors.New("user is not registered. Please sign up")
+var errDeviceAuthDenied = errors.New("login request was denied")
+var errDeviceAuthExpired = errors.New("login request expired, please retry")
+var deviceAuthSlowDownInterval = 5 * time.Second
 var localServerRedirectURL = "http://localhost:8085/auth_complete"
 var localServerPort = int32(8085)
 var sentSegmentAlias = false
 
-// EnsureDefaultAuthFilePath returns and creates the file path is missing.
+// EnsureDefaultAuthFilePath returns and creates the file path is missing. The credentials file
+// is determined by the current context.
 func EnsureDefaultAuthFilePath() (string, error) {
 	u, err := user.Current()
 	if err != nil {
@@ -75,7 +80,12 @@ func EnsureDefaultAuthFilePath() (string, error) {
 		}
 	}
 
-	pixieAuthFilePath := filepath.Join(pixieDirPath, pixieAuthFile)
+	authFile := pixieAuthFile
+	if ctx, err := pxconfig.CurrentContext(); err == nil && ctx.AuthFile != "" {
+		authFile = ctx.AuthFile
+	}
+
+	pixieAuthFilePath := filepath.Join(pixieDirPath, authFile)
 	return pixieAuthFilePath, nil
 }
 
@@ -191,6 +201,9 @@ type PixieCloudLogin struct {
 	UseAPIKey bool
 	// APIKey to use if specified. Otherwise, prompt for the key if UseAPIKey is true.
 	APIKey string
+	// DeviceMode, if true then use the device authorization flow, where the user approves the login
+	// from a browser on any other device.
+	DeviceMode bool
 }
 
 // Run either launches the browser or prints out the URL for auth.
@@ -202,6 +215,9 @@ func (p *PixieCloudLogin) Run() (*RefreshToken, error) {
 	if len(p.APIKey) > 0 {
 		return p.getRefreshToken("", p.APIKey)
 	}
+	if p.DeviceMode {
+		return p.doDeviceAuth()
+	}
 	// There are two ways to do the auth. The first one is where we automatically open up the browser
 	// and wait for the challenge to complete and call a HTTP server that we started.
 	// The second one is to perform a manual auth.
@@ -419,15 +435,20 @@ func (p *PixieCloudLogin) getRefreshToken(accessToken string, apiKey string) (*R
 		return nil, err
 	}
 
+	return newRefreshToken(ctx, conn, resp.Token, resp.ExpiresAt)
+}
+
+// newRefreshToken creates a RefreshToken for the given token, filled in with the user's org.
+func newRefreshToken(ctx context.Context, conn *grpc.ClientConn, token string, expiresAt int64) (*RefreshToken, error) {
 	// Get the org name from the cloud.
 	var orgID string
-	if token, _ := jwt.Parse([]byte(resp.Token)); token != nil {
-		orgID = srvutils.GetOrgID(token)
+	if parsed, _ := jwt.Parse([]byte(token)); parsed != nil {
+		orgID = srvutils.GetOrgID(parsed)
 	}
 
 	orgClient := cloudpb.NewOrganizationServiceClient(conn)
 	ctx = metadata.AppendToOutgoingContext(ctx, "authorization",
-		fmt.Sprintf("bearer %s", resp.Token))
+		fmt.Sprintf("bearer %s", token))
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 	orgResp, err := orgClient.GetOrg(ctx, apiutils.ProtoFromUUIDStrOrNil(orgID))
@@ -436,13 +457,67 @@ func (p *PixieCloudLogin) getRefreshToken(accessToken string, apiKey string) (*R
 	}
 
 	return &RefreshToken{
-		Token:     resp.Token,
-		ExpiresAt: resp.ExpiresAt,
+		Token:     token,
+		ExpiresAt: expiresAt,
 		OrgID:     orgID,
 		OrgName:   orgResp.OrgName,
 	}, nil
 }
 
+func (p *PixieCloudLogin) doDeviceAuth() (*RefreshToken, error) {
+	conn, err := utils.GetCloudClientConnection(p.CloudAddr)
+	if err != nil {
+		return nil, err
+	}
+	authClient := cloudpb.NewAuthServiceClient(conn)
+
+	clientName := "Pixie CLI"
+	if hostname, err := os.Hostname(); err == nil {
+		clientName = fmt.Sprintf("Pixie CLI on %s", hostname)
+	}
+
+	ctx := context.Background()
+	codeResp, err := authClient.CreateDeviceCode(ctx, &cloudpb.CreateDeviceCodeRequest{
+		ClientName: clientName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// fmt.Printf appears to escape % (as desired) so we use it here instead of the cli logger.
+	fmt.Printf("\nTo log in, visit:\n \t %s\n\nand enter the code: %s\n\n", codeResp.VerificationURI, codeResp.UserCode)
+	fmt.Printf("Or visit:\n \t %s\n\n", codeResp.VerificationURIComplete)
+	utils.Info("Waiting for approval ...")
+
+	interval := time.Duration(codeResp.Interval) * time.Second
+	deadline := time.Unix(codeResp.ExpiresAt, 0)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		pollResp, err := authClient.PollDeviceCode(ctx, &cloudpb.PollDeviceCodeRequest{
+			DeviceCode: codeResp.DeviceCode,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch pollResp.Status {
+		case cloudpb.DCS_PENDING:
+			continue
+		case cloudpb.DCS_SLOW_DOWN:
+			// As specified by RFC 8628, the interval is increased by 5 seconds on every slow down.
+			interval += deviceAuthSlowDownInterval
+		case cloudpb.DCS_APPROVED:
+			return newRefreshToken(ctx, conn, pollResp.Token, pollResp.ExpiresAt)
+		case cloudpb.DCS_DENIED:
+			return nil, errDeviceAuthDenied
+		default:
+			return nil, errDeviceAuthExpired
+		}
+	}
+	return nil, errDeviceAuthExpired
+}
+
 // RefreshToken is the format for the refresh token.
 type RefreshToken struct {
 	Token     string `json:"token"`
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/pixie_cli/pkg/auth"
+	"px.dev/pixie/src/pixie_cli/pkg/auth/testutils"
+)
+
+func setupFakeCloud(t *testing.T) *testutils.FakeCloud {
+	viper.Set("disable_ssl", true)
+	f, err := testutils.NewFakeCloud()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		f.Stop()
+		viper.Set("disable_ssl", false)
+	})
+	return f
+}
+
+// approveNextCode simulates the user entering the next user code in the UI.
+func approveNextCode(t *testing.T, f *testutils.FakeCloud, approved bool) {
+	go func() {
+		userCode := <-f.UserCodes()
+		_, err := f.ApproveDeviceCode(context.Background(), &cloudpb.ApproveDeviceCodeRequest{
+			UserCode: userCode,
+			Approved: approved,
+		})
+		assert.NoError(t, err)
+	}()
+}
+
+func TestPixieCloudLogin_DeviceFlow(t *testing.T) {
+	f := setupFakeCloud(t)
+	approveNextCode(t, f, true)
+
+	l := &auth.PixieCloudLogin{
+		CloudAddr:  f.Addr(),
+		DeviceMode: true,
+	}
+	token, err := l.Run()
+	require.NoError(t, err)
+	assert.NotEmpty(t, token.Token)
+	assert.Equal(t, testutils.FakeOrgID.String(), token.OrgID)
+	assert.Equal(t, testutils.FakeOrgName, token.OrgName)
+}
+
+func TestPixieCloudLogin_DeviceFlowDenied(t *testing.T) {
+	f := setupFakeCloud(t)
+	approveNextCode(t, f, false)
+
+	l := &auth.PixieCloudLogin{
+		CloudAddr:  f.Addr(),
+		DeviceMode: true,
+	}
+	_, err := l.Run()
+	assert.EqualError(t, err, "login request was denied")
+}
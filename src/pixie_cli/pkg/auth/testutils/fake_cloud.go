@@ -0,0 +1,192 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
+	srvutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+const (
+	// FakeSigningKey is the key used by the FakeCloud to sign tokens.
+	FakeSigningKey = "fake-signing-key"
+	// FakeOrgName is the name of the org of the user logged in through the FakeCloud.
+	FakeOrgName = "fake-org"
+)
+
+var (
+	// FakeOrgID is the ID of the org of the user logged in through the FakeCloud.
+	FakeOrgID = uuid.FromStringOrNil("223e4567-e89b-12d3-a456-426655440000")
+	// FakeUserID is the ID of the user logged in through the FakeCloud.
+	FakeUserID = uuid.FromStringOrNil("423e4567-e89b-12d3-a456-426655440000")
+)
+
+type fakeDeviceCode struct {
+	userCode   string
+	clientName string
+	status     cloudpb.DeviceCodeStatus
+}
+
+// FakeCloud is an in-memory implementation of the Pixie Cloud auth and org APIs, used to test CLI
+// logins end to end. It is served over an insecure gRPC connection on localhost.
+type FakeCloud struct {
+	cloudpb.UnimplementedAuthServiceServer
+	cloudpb.UnimplementedOrganizationServiceServer
+
+	// Interval is the poll interval, in seconds, handed out for device codes.
+	Interval int64
+
+	mu          sync.Mutex
+	deviceCodes map[string]*fakeDeviceCode
+	userCodes   chan string
+
+	s   *grpc.Server
+	lis net.Listener
+}
+
+// NewFakeCloud starts a FakeCloud on a random local port.
+func NewFakeCloud() (*FakeCloud, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	f := &FakeCloud{
+		deviceCodes: make(map[string]*fakeDeviceCode),
+		userCodes:   make(chan string, 16),
+		s:           grpc.NewServer(),
+		lis:         lis,
+	}
+	cloudpb.RegisterAuthServiceServer(f.s, f)
+	cloudpb.RegisterOrganizationServiceServer(f.s, f)
+	go func() {
+		_ = f.s.Serve(lis)
+	}()
+	return f, nil
+}
+
+// Addr returns the address the FakeCloud is listening on.
+func (f *FakeCloud) Addr() string {
+	return f.lis.Addr().String()
+}
+
+// Stop stops the FakeCloud.
+func (f *FakeCloud) Stop() {
+	f.s.Stop()
+}
+
+// UserCodes returns a channel on which the user code of every created device code is published.
+// This is used to simulate the user entering the code in the UI.
+func (f *FakeCloud) UserCodes() <-chan string {
+	return f.userCodes
+}
+
+// ApproveDeviceCode approves or denies the device code belonging to the user code.
+func (f *FakeCloud) ApproveDeviceCode(ctx context.Context, req *cloudpb.ApproveDeviceCodeRequest) (*cloudpb.ApproveDeviceCodeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, dc := range f.deviceCodes {
+		if dc.userCode != req.UserCode || dc.status != cloudpb.DCS_PENDING {
+			continue
+		}
+		dc.status = cloudpb.DCS_DENIED
+		if req.Approved {
+			dc.status = cloudpb.DCS_APPROVED
+		}
+		return &cloudpb.ApproveDeviceCodeResponse{ClientName: dc.clientName}, nil
+	}
+	return nil, status.Error(codes.NotFound, "invalid or expired code")
+}
+
+// CreateDeviceCode creates a new device code.
+func (f *FakeCloud) CreateDeviceCode(ctx context.Context, req *cloudpb.CreateDeviceCodeRequest) (*cloudpb.CreateDeviceCodeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := len(f.deviceCodes)
+	deviceCode := fmt.Sprintf("device-code-%d", id)
+	userCode := fmt.Sprintf("BCDF-%04d", id)
+	f.deviceCodes[deviceCode] = &fakeDeviceCode{
+		userCode:   userCode,
+		clientName: req.ClientName,
+		status:     cloudpb.DCS_PENDING,
+	}
+	f.userCodes <- userCode
+
+	return &cloudpb.CreateDeviceCodeResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         "https://work.fake.cloud/device",
+		VerificationURIComplete: "https://work.fake.cloud/device?user_code=" + userCode,
+		ExpiresAt:               time.Now().Add(time.Minute).Unix(),
+		Interval:                f.Interval,
+	}, nil
+}
+
+// PollDeviceCode returns the state of the device code.
+func (f *FakeCloud) PollDeviceCode(ctx context.Context, req *cloudpb.PollDeviceCodeRequest) (*cloudpb.PollDeviceCodeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dc, ok := f.deviceCodes[req.DeviceCode]
+	if !ok {
+		return &cloudpb.PollDeviceCodeResponse{Status: cloudpb.DCS_EXPIRED}, nil
+	}
+	if dc.status != cloudpb.DCS_APPROVED {
+		return &cloudpb.PollDeviceCodeResponse{Status: dc.status}, nil
+	}
+
+	delete(f.deviceCodes, req.DeviceCode)
+	expiresAt := time.Now().Add(time.Hour)
+	claims := srvutils.GenerateJWTForUser(FakeUserID.String(), FakeOrgID.String(), "test@test.com", expiresAt, "fake.cloud", nil)
+	token, err := srvutils.SignJWTClaims(claims, FakeSigningKey)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudpb.PollDeviceCodeResponse{
+		Status:    cloudpb.DCS_APPROVED,
+		Token:     token,
+		ExpiresAt: expiresAt.Unix(),
+	}, nil
+}
+
+// GetOrg returns the org of the logged in user.
+func (f *FakeCloud) GetOrg(ctx context.Context, req *uuidpb.UUID) (*cloudpb.OrgInfo, error) {
+	if utils.UUIDFromProtoOrNil(req) != FakeOrgID {
+		return nil, status.Error(codes.NotFound, "no such org")
+	}
+	return &cloudpb.OrgInfo{
+		ID:      req,
+		OrgName: FakeOrgName,
+	}, nil
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/pixie_cli/pkg/auth"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	utils2 "px.dev/pixie/src/utils"
+)
+
+func init() {
+	AuditCmd.Flags().StringP("output", "o", "", "Output format: one of: json|table|csv")
+	AuditCmd.Flags().String("action", "", "Only show events for this action, eg: api_key.delete")
+	AuditCmd.Flags().String("actor", "", "Only show events performed by this user or API key ID")
+	AuditCmd.Flags().String("target", "", "Only show events on this target ID")
+	AuditCmd.Flags().String("before", "", "Only show events older than this RFC3339 timestamp. Use to page through older events")
+	AuditCmd.Flags().Int64P("limit", "n", 100, "Maximum number of events to show")
+}
+
+// AuditCmd is the audit sub-command of the CLI.
+var AuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "List audit events for the current org",
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+
+		req := &cloudpb.ListAuditEventsRequest{}
+		req.Action, _ = cmd.Flags().GetString("action")
+		req.TargetID, _ = cmd.Flags().GetString("target")
+		req.Limit, _ = cmd.Flags().GetInt64("limit")
+
+		if actor, _ := cmd.Flags().GetString("actor"); actor != "" {
+			actorID, err := uuid.FromString(actor)
+			if err != nil {
+				utils.WithError(err).Fatal("Invalid actor ID")
+			}
+			req.ActorID = utils2.ProtoFromUUID(actorID)
+		}
+		if before, _ := cmd.Flags().GetString("before"); before != "" {
+			t, err := time.Parse(time.RFC3339, before)
+			if err != nil {
+				utils.WithError(err).Fatal("Invalid --before timestamp, expected RFC3339")
+			}
+			req.Before, err = types.TimestampProto(t)
+			if err != nil {
+				utils.WithError(err).Fatal("Invalid --before timestamp")
+			}
+		}
+
+		events, err := listAuditEvents(cloudAddr, req)
+		if err != nil {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatal("Failed to list audit events")
+		}
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		defer w.Finish()
+		w.SetHeader("audit-events", []string{"Time", "Actor", "Action", "Target", "Before", "After", "SourceIP"})
+		for _, e := range events {
+			ts, _ := types.TimestampFromProto(e.Timestamp)
+			actor := e.ActorType
+			if e.ActorID != nil {
+				actor = actor + ":" + utils2.UUIDFromProtoOrNil(e.ActorID).String()
+			}
+			_ = w.Write([]interface{}{ts.Format(time.RFC3339), actor, e.Action,
+				e.TargetType + ":" + e.TargetID, e.Before, e.After, e.SourceIP})
+		}
+	},
+}
+
+func listAuditEvents(cloudAddr string, req *cloudpb.ListAuditEventsRequest) ([]*cloudpb.AuditEvent, error) {
+	cloudConn, err := utils.GetCloudClientConnection(cloudAddr)
+	if err != nil {
+		return nil, err
+	}
+	client := cloudpb.NewAuditServiceClient(cloudConn)
+
+	resp, err := client.ListAuditEvents(auth.CtxWithCreds(context.Background()), req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
@@ -36,6 +36,9 @@ func init() {
 	LoginCmd.PersistentFlags().Bool("manual", false, "Don't automatically open the browser")
 	viper.BindPFlag("manual", LoginCmd.PersistentFlags().Lookup("manual"))
 
+	LoginCmd.Flags().Bool("device", false, "Log in by approving the request from a browser on another device. Useful over SSH or in containers")
+	viper.BindPFlag("device", LoginCmd.Flags().Lookup("device"))
+
 	LoginCmd.Flags().Bool("use_api_key", false, "Use API key for authentication")
 	viper.BindPFlag("use_api_key", LoginCmd.Flags().Lookup("use_api_key"))
 
@@ -65,6 +68,7 @@ var LoginCmd = &cobra.Command{
 			CloudAddr:  viper.GetString("cloud_addr"),
 			UseAPIKey:  viper.GetBool("use_api_key"),
 			APIKey:     viper.GetString("api_key"),
+			DeviceMode: viper.GetBool("device"),
 		}
 		var refreshToken *auth.RefreshToken
 		var err error
@@ -77,6 +81,17 @@ var LoginCmd = &cobra.Command{
 			log.WithError(err).Fatal("Failed to persist auth token")
 		}
 
+		// Remember which cloud these credentials belong to, so that switching to this context
+		// later also switches the cloud.
+		if ctx, err := pxconfig.CurrentContext(); err == nil {
+			err = pxconfig.SetContext(ctx.Name, func(c *pxconfig.Context) {
+				c.CloudAddr = l.CloudAddr
+			})
+			if err != nil {
+				log.WithError(err).Error("Failed to update context")
+			}
+		}
+
 		if token, _ := jwt.Parse([]byte(refreshToken.Token)); token != nil {
 			userID := srvutils.GetUserID(token)
 			if userID != "" {
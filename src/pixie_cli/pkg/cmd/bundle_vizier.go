@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/pixie_cli/pkg/auth"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/utils/shared/artifacts"
+)
+
+func init() {
+	BundleVizierCmd.Flags().String("version", "", "The Vizier version to bundle. Defaults to the latest version")
+	BundleVizierCmd.Flags().String("operator_version", "", "The operator version to bundle. Defaults to the latest version")
+	BundleVizierCmd.Flags().StringP("out", "o", "", "The path to write the bundle to. Defaults to pixie_bundle_<version>.tar.gz")
+	BundleVizierCmd.Flags().Bool("include_images", false, "Whether to include image tarballs in the bundle. Requires docker to pull and save the images")
+	BundleVizierCmd.Flags().Bool("resolve_digests", true, "Whether to look up the digest of each image in its registry")
+}
+
+// BundleVizierCmd is the "bundle-vizier" command. It creates a deployment bundle that can be used with
+// "px deploy --from_bundle" to deploy Pixie to clusters without access to Pixie Cloud's artifacts.
+var BundleVizierCmd = &cobra.Command{
+	Use:   "bundle-vizier",
+	Short: "Create a self-contained bundle for deploying Pixie to air-gapped clusters",
+	Run: func(cmd *cobra.Command, args []string) {
+		version, _ := cmd.Flags().GetString("version")
+		operatorVersion, _ := cmd.Flags().GetString("operator_version")
+		out, _ := cmd.Flags().GetString("out")
+		includeImages, _ := cmd.Flags().GetBool("include_images")
+		resolveDigests, _ := cmd.Flags().GetBool("resolve_digests")
+
+		cloudConn, err := utils.GetCloudClientConnection(viper.GetString("cloud_addr"))
+		if err != nil {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatal("Failed to get grpc connection to cloud")
+		}
+
+		if version == "" {
+			version, err = getLatestVizierVersion(cloudConn)
+			if err != nil {
+				log.WithError(err).Fatal("Failed to fetch Vizier versions")
+			}
+		}
+		if operatorVersion == "" {
+			operatorVersion, err = getLatestOperatorVersion(cloudConn)
+			if err != nil {
+				log.WithError(err).Fatal("Failed to fetch Operator versions")
+			}
+		}
+		if out == "" {
+			out = fmt.Sprintf("pixie_bundle_%s.tar.gz", version)
+		}
+		utils.Infof("Bundling Vizier version %s and operator version %s", version, operatorVersion)
+
+		creds := auth.MustLoadDefaultCredentials()
+		vizierTemplates, err := artifacts.FetchVizierTemplates(cloudConn, creds.Token, version)
+		if err != nil {
+			log.WithError(err).Fatal("Could not fetch Vizier YAMLs")
+		}
+		operatorTemplates, err := artifacts.FetchOperatorTemplates(cloudConn, operatorVersion)
+		if err != nil {
+			log.WithError(err).Fatal("Could not fetch operator YAMLs")
+		}
+
+		var yamlContents []string
+		for _, y := range append(vizierTemplates, operatorTemplates...) {
+			yamlContents = append(yamlContents, y.YAML)
+		}
+		manifest := &artifacts.BundleManifest{
+			VizierVersion:   version,
+			OperatorVersion: operatorVersion,
+			CreatedAt:       time.Now().UTC(),
+		}
+		// The operator's images are deployed by OLM, so they are not referenced by the operator's YAMLs.
+		images := append(artifacts.ListImages(yamlContents...), artifacts.OperatorImages(operatorVersion)...)
+		for _, image := range images {
+			manifest.Images = append(manifest.Images, &artifacts.BundleImage{Name: image})
+		}
+
+		if resolveDigests {
+			client := &http.Client{Timeout: 30 * time.Second}
+			for _, img := range manifest.Images {
+				img.Digest, err = artifacts.ResolveImageDigest(client, img.Name)
+				if err != nil {
+					utils.WithError(err).Errorf("Failed to resolve digest for image %s", img.Name)
+				}
+			}
+		}
+
+		imageTarballs := make(map[string]string)
+		if includeImages {
+			tmpDir, err := ioutil.TempDir("", "pixie_bundle")
+			if err != nil {
+				log.WithError(err).Fatal("Failed to create temporary directory")
+			}
+			defer os.RemoveAll(tmpDir)
+
+			for i, img := range manifest.Images {
+				utils.Infof("Saving image %s", img.Name)
+				tarball := filepath.Join(tmpDir, fmt.Sprintf("%d.tar", i))
+				if err := utils.ExecCommand("docker", "pull", img.Name); err != nil {
+					utils.WithError(err).Fatalf("Failed to pull image %s", img.Name)
+				}
+				if err := utils.ExecCommand("docker", "save", "-o", tarball, img.Name); err != nil {
+					utils.WithError(err).Fatalf("Failed to save image %s", img.Name)
+				}
+				imageTarballs[img.Name] = tarball
+			}
+		}
+
+		f, err := os.Create(out)
+		if err != nil {
+			utils.WithError(err).Fatalf("Failed to create %s", out)
+		}
+		defer f.Close()
+
+		err = artifacts.WriteBundle(f, &artifacts.Bundle{
+			Manifest:          manifest,
+			VizierTemplates:   vizierTemplates,
+			OperatorTemplates: operatorTemplates,
+		}, imageTarballs)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to write bundle")
+		}
+		utils.Infof("Wrote bundle with %d images to %s", len(manifest.Images), out)
+	},
+}
@@ -43,10 +43,10 @@ func init() {
 	ConfigCmd.AddCommand(UpdateConfigCmd)
 }
 
-// ConfigCmd is the "config" command for getting/updating the cluster config.
+// ConfigCmd is the "config" command for getting/updating the cluster config and the CLI contexts.
 var ConfigCmd = &cobra.Command{
 	Use:   "config",
-	Short: "Get/update the current cluster config",
+	Short: "Get/update the current cluster config and CLI contexts",
 }
 
 // GetConfigCmd is the "config get" command.
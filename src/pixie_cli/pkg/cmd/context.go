@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	cliUtils "px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+func init() {
+	GetContextsCmd.Flags().StringP("output", "o", "", "Output format: one of: json|table")
+
+	SetContextCmd.Flags().String("cloud_addr", "", "The address of Pixie Cloud for the context")
+	SetContextCmd.Flags().StringP("cluster", "c", "", "The ID of the cluster to use by default")
+	SetContextCmd.Flags().StringP("namespace", "n", "", "The namespace Vizier is deployed in")
+	SetContextCmd.Flags().StringP("output", "o", "", "The output format to use by default")
+
+	ConfigCmd.AddCommand(GetContextsCmd)
+	ConfigCmd.AddCommand(CurrentContextCmd)
+	ConfigCmd.AddCommand(UseContextCmd)
+	ConfigCmd.AddCommand(SetContextCmd)
+	ConfigCmd.AddCommand(DeleteContextCmd)
+}
+
+// GetContextsCmd is the "config get-contexts" command.
+var GetContextsCmd = &cobra.Command{
+	Use:   "get-contexts",
+	Short: "List the available CLI contexts",
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+
+		current, _ := pxconfig.CurrentContext()
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		defer w.Finish()
+		w.SetHeader("contexts", []string{"Current", "Name", "Cloud Addr", "Cluster", "Namespace", "Output"})
+
+		for _, ctx := range pxconfig.Cfg().Contexts {
+			marker := ""
+			if current != nil && current.Name == ctx.Name {
+				marker = "*"
+			}
+			_ = w.Write([]interface{}{marker, ctx.Name, ctx.CloudAddr, ctx.Cluster, ctx.Namespace, ctx.Output})
+		}
+	},
+}
+
+// CurrentContextCmd is the "config current-context" command.
+var CurrentContextCmd = &cobra.Command{
+	Use:   "current-context",
+	Short: "Print the name of the current CLI context",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, err := pxconfig.CurrentContext()
+		if err != nil {
+			cliUtils.WithError(err).Fatal("Failed to get current context")
+		}
+		cliUtils.Info(ctx.Name)
+	},
+}
+
+// UseContextCmd is the "config use-context" command.
+var UseContextCmd = &cobra.Command{
+	Use:   "use-context NAME",
+	Short: "Set the current CLI context",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pxconfig.UseContext(args[0]); err != nil {
+			cliUtils.WithError(err).Fatal("Failed to switch context")
+		}
+		cliUtils.Infof("Switched to context \"%s\"", args[0])
+	},
+}
+
+// SetContextCmd is the "config set-context" command.
+var SetContextCmd = &cobra.Command{
+	Use:   "set-context NAME",
+	Short: "Create or update a CLI context",
+	Long: "Create or update a CLI context. Only the settings specified through flags are updated. " +
+		"Each context stores its credentials separately, so run `px auth login --context NAME` after creating a new context.",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pxconfig.SetContext(args[0], func(ctx *pxconfig.Context) {
+			if cmd.Flags().Changed("cloud_addr") {
+				ctx.CloudAddr, _ = cmd.Flags().GetString("cloud_addr")
+			}
+			if cmd.Flags().Changed("cluster") {
+				ctx.Cluster, _ = cmd.Flags().GetString("cluster")
+			}
+			if cmd.Flags().Changed("namespace") {
+				ctx.Namespace, _ = cmd.Flags().GetString("namespace")
+			}
+			if cmd.Flags().Changed("output") {
+				ctx.Output, _ = cmd.Flags().GetString("output")
+			}
+		})
+		if err != nil {
+			cliUtils.WithError(err).Fatal("Failed to set context")
+		}
+		cliUtils.Infof("Context \"%s\" updated", args[0])
+	},
+}
+
+// DeleteContextCmd is the "config delete-context" command.
+var DeleteContextCmd = &cobra.Command{
+	Use:   "delete-context NAME",
+	Short: "Delete a CLI context",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pxconfig.DeleteContext(args[0]); err != nil {
+			cliUtils.WithError(err).Fatal("Failed to delete context")
+		}
+		cliUtils.Infof("Deleted context \"%s\"", args[0])
+	},
+}
@@ -37,8 +37,12 @@ import (
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
 	vztypes "px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
@@ -59,6 +63,8 @@ import (
 const (
 	// DefaultCloudAddr is the Community Cloud address.
 	DefaultCloudAddr = "withpixie.ai:443"
+	// vizierBundleConfigMap is the name of the ConfigMap which holds the Vizier templates when deploying from a bundle.
+	vizierBundleConfigMap = "pl-vizier-bundle"
 )
 
 // BlockListedLabels are labels that we won't allow users to specify, since these are labels that we
@@ -181,12 +187,21 @@ func init() {
 	DeployCmd.Flags().String("data_access", "Full", "Data access level defines the level of data that may be accesssed when executing a script on the cluster. Options: 'Full' and 'Restricted'")
 	viper.BindPFlag("data_access", DeployCmd.Flags().Lookup("data_access"))
 
+	DeployCmd.Flags().StringSlice("namespace_scope", []string{}, "Namespaces to restrict data collection to, for example: 'team-a,team-b'. Allows several Viziers to run in the same cluster, each deployed to its own namespace.")
+	viper.BindPFlag("namespace_scope", DeployCmd.Flags().Lookup("namespace_scope"))
+
 	DeployCmd.Flags().Uint32("datastream_buffer_size", 1024*1024, "Internal data collector parameters: the maximum size of a data stream buffer retained between cycles.")
 	viper.BindPFlag("datastream_buffer_size", DeployCmd.Flags().Lookup("datastream_buffer_size"))
 
 	DeployCmd.Flags().Uint32("datastream_buffer_spike_size", 500*1024*1024, "Internal data collector parameters: the maximum temporary size of a data stream buffer before processing.")
 	viper.BindPFlag("datastream_buffer_spike_size", DeployCmd.Flags().Lookup("datastream_buffer_spike_size"))
 
+	DeployCmd.Flags().String("from_bundle", "", "Deploy from a bundle created by 'px bundle-vizier' instead of downloading YAMLs from Pixie Cloud")
+	viper.BindPFlag("from_bundle", DeployCmd.Flags().Lookup("from_bundle"))
+
+	DeployCmd.Flags().String("image_registry", "", "A registry to pull Pixie images from instead of their default registries, for example a mirror of the images in a bundle")
+	viper.BindPFlag("image_registry", DeployCmd.Flags().Lookup("image_registry"))
+
 	// Super secret flags for Pixies.
 	DeployCmd.Flags().MarkHidden("namespace")
 }
@@ -257,6 +272,18 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	dataAccess, _ := cmd.Flags().GetString("data_access")
 	datastreamBufferSize, _ := cmd.Flags().GetUint32("datastream_buffer_size")
 	datastreamBufferSpikeSize, _ := cmd.Flags().GetUint32("datastream_buffer_spike_size")
+	namespaceScope, _ := cmd.Flags().GetStringSlice("namespace_scope")
+	fromBundle, _ := cmd.Flags().GetString("from_bundle")
+	imageRegistry, _ := cmd.Flags().GetString("image_registry")
+
+	var bundle *artifacts.Bundle
+	if fromBundle != "" {
+		var err error
+		bundle, err = artifacts.ReadBundleFile(fromBundle)
+		if err != nil {
+			utils.WithError(err).Fatalf("Failed to read bundle %s", fromBundle)
+		}
+	}
 
 	labelMap := make(map[string]string)
 	if customLabels != "" {
@@ -308,6 +335,10 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	if deployKey == "" && extractPath != "" {
 		utils.Fatal("--deploy_key must be specified when running with --extract_yaml. Please run px deploy-key create.")
 	}
+	// Deploying from a bundle should not require access to Pixie Cloud, so the deploy key can't be generated here.
+	if deployKey == "" && bundle != nil {
+		utils.Fatal("--deploy_key must be specified when running with --from_bundle. Please run px deploy-key create.")
+	}
 
 	if (check || checkOnly) && extractPath == "" {
 		_ = pxanalytics.Client().Enqueue(&analytics.Track{
@@ -353,6 +384,9 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	}
 
 	versionString := viper.GetString("vizier_version")
+	if bundle != nil {
+		versionString = bundle.Manifest.VizierVersion
+	}
 	if len(versionString) == 0 {
 		// Fetch latest version.
 		versionString, err = getLatestVizierVersion(cloudConn)
@@ -364,6 +398,9 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	utils.Infof("Installing Vizier version: %s", versionString)
 
 	operatorVersion := viper.GetString("operator_version")
+	if bundle != nil {
+		operatorVersion = bundle.Manifest.OperatorVersion
+	}
 	if len(operatorVersion) == 0 {
 		operatorVersion, err = getLatestOperatorVersion(cloudConn)
 		if err != nil {
@@ -402,9 +439,16 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 
 	utils.Infof("Generating YAMLs for Pixie")
 
-	templatedYAMLs, err := artifacts.FetchOperatorTemplates(cloudConn, operatorVersion)
-	if err != nil {
-		log.WithError(err).Fatal("Could not fetch Vizier YAMLs")
+	var templatedYAMLs []*yamlsutils.YAMLFile
+	bundleConfigMap := ""
+	if bundle != nil {
+		templatedYAMLs = bundle.OperatorTemplates
+		bundleConfigMap = vizierBundleConfigMap
+	} else {
+		templatedYAMLs, err = artifacts.FetchOperatorTemplates(cloudConn, operatorVersion)
+		if err != nil {
+			log.WithError(err).Fatal("Could not fetch Vizier YAMLs")
+		}
 	}
 
 	clusterName, _ := cmd.Flags().GetString("cluster_name")
@@ -436,8 +480,11 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 				"annotations": annotationMap,
 				"labels":      labelMap,
 			},
-			"patches":    patchesMap,
-			"dataAccess": castedDataAccess,
+			"patches":         patchesMap,
+			"dataAccess":      castedDataAccess,
+			"namespaceScope":  namespaceScope,
+			"bundleConfigMap": bundleConfigMap,
+			"registry":        imageRegistry,
 			"dataCollectorParams": &map[string]interface{}{
 				"datastreamBufferSize":      datastreamBufferSize,
 				"datastreamBufferSpikeSize": datastreamBufferSpikeSize,
@@ -454,6 +501,18 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 		log.WithError(err).Fatal("Failed to fill in templated deployment YAMLs")
 	}
 
+	for _, y := range yamls {
+		y.YAML = artifacts.RewriteImageRegistry(y.YAML, imageRegistry)
+	}
+	// The operator renders the Vizier YAMLs from the bundle, and rewrites their images itself.
+	if bundle != nil {
+		bundleYAML, err := vizierBundleConfigMapsYAML(bundle, namespace)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to create Vizier bundle ConfigMap")
+		}
+		yamls = append(yamls, &yamlsutils.YAMLFile{Name: "vizier_bundle", YAML: bundleYAML})
+	}
+
 	// If extract_path is specified, write out yamls to file.
 	if extractPath != "" {
 		if err := yamlsutils.ExtractYAMLs(yamls, extractPath, "pixie_yamls", yamlsutils.MultiFileExtractYAMLFormat); err != nil {
@@ -502,12 +561,12 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 
 	utils.Infof("Found %v nodes", numNodes)
 
-	clusterID := deploy(cloudConn, clientset, vzClient, kubeConfig, yamlMap, deployOLM, olmNamespace, olmOperatorNamespace, namespace)
+	clusterID := deploy(cloudConn, clientset, vzClient, kubeConfig, yamlMap, deployOLM, olmNamespace, olmOperatorNamespace, namespace, imageRegistry)
 
 	waitForHealthCheck(cloudAddr, clusterID, clientset, namespace, numNodes)
 }
 
-func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClient *versioned.Clientset, kubeConfig *rest.Config, yamlMap map[string]string, deployOLM bool, olmNs, olmOpNs, namespace string) uuid.UUID {
+func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClient *versioned.Clientset, kubeConfig *rest.Config, yamlMap map[string]string, deployOLM bool, olmNs, olmOpNs, namespace, imageRegistry string) uuid.UUID {
 	olmCRDJob := newTaskWrapper("Installing OLM CRDs", func() error {
 		return retryDeploy(clientset, kubeConfig, yamlMap["olm_crd"])
 	})
@@ -523,7 +582,11 @@ func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClien
 		return retryDeploy(clientset, kubeConfig, yamlMap["catalog"])
 	})
 	olmSubscriptionJob := newTaskWrapper("Deploying OLM Subscription", func() error {
-		return retryDeploy(clientset, kubeConfig, yamlMap["subscription"])
+		err := retryDeploy(clientset, kubeConfig, yamlMap["subscription"])
+		if err != nil || imageRegistry == "" {
+			return err
+		}
+		return rewriteOperatorImages(kubeConfig, olmOpNs, imageRegistry)
 	})
 
 	namespaceJob := newTaskWrapper("Creating namespace", func() error {
@@ -546,6 +609,13 @@ func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClien
 		return retryDeploy(clientset, kubeConfig, yamlMap["vizier_crd"])
 	})
 	vzJob := newTaskWrapper("Deploying Vizier", func() error {
+		// When deploying from a bundle, the bundle must be available before the operator reconciles the Vizier.
+		if bundleYAML, ok := yamlMap["vizier_bundle"]; ok {
+			err := k8s.ApplyYAML(clientset, kubeConfig, namespace, strings.NewReader(bundleYAML), true)
+			if err != nil {
+				return err
+			}
+		}
 		return retryDeploy(clientset, kubeConfig, yamlMap["vizier"])
 	})
 
@@ -861,3 +931,118 @@ func waitForPems(clientset *kubernetes.Clientset, namespace string, expectedPods
 	}
 	return nil
 }
+
+// vizierBundleConfigMapsYAML creates the YAML for the ConfigMaps which the operator deploys Vizier from, when
+// deploying from a bundle.
+func vizierBundleConfigMapsYAML(bundle *artifacts.Bundle, namespace string) (string, error) {
+	parts, err := artifacts.VizierBundleConfigMapsData(bundle)
+	if err != nil {
+		return "", err
+	}
+	var cmYAMLs []string
+	for i, data := range parts {
+		cm := &v1.ConfigMap{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      artifacts.VizierBundleConfigMapName(vizierBundleConfigMap, i),
+				Namespace: namespace,
+			},
+			BinaryData: data,
+		}
+		b, err := yaml.Marshal(cm)
+		if err != nil {
+			return "", err
+		}
+		cmYAMLs = append(cmYAMLs, string(b))
+	}
+	return strings.Join(cmYAMLs, "---\n"), nil
+}
+
+var csvGVR = schema.GroupVersionResource{Group: "operators.coreos.com", Version: "v1alpha1", Resource: "clusterserviceversions"}
+
+// rewriteOperatorImages points the operator's images at the given registry. OLM creates the operator's deployment
+// from its ClusterServiceVersion rather than from our YAMLs, so the ClusterServiceVersion is updated once OLM has
+// installed it.
+func rewriteOperatorImages(kubeConfig *rest.Config, olmOpNs string, registry string) error {
+	dynamicClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		return err
+	}
+	csvClient := dynamicClient.Resource(csvGVR).Namespace(olmOpNs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	t := time.NewTicker(2 * time.Second)
+	defer t.Stop()
+	for {
+		csvs, err := csvClient.List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.WithError(err).Debug("Failed to list ClusterServiceVersions")
+		} else {
+			for i := range csvs.Items {
+				csv := &csvs.Items[i]
+				// Skip the copies of the ClusterServiceVersion which OLM makes in other namespaces.
+				if !strings.HasPrefix(csv.GetName(), "pixie-operator") || csv.GetLabels()["olm.copiedFrom"] != "" {
+					continue
+				}
+				updated, err := rewriteCSVImages(csv, registry)
+				if err != nil || !updated {
+					return err
+				}
+				_, err = csvClient.Update(ctx, csv, metav1.UpdateOptions{})
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.New("timed out waiting for OLM to install the operator")
+		case <-t.C:
+		}
+	}
+}
+
+// rewriteCSVImages points the images of the deployments in the ClusterServiceVersion at the given registry. Returns
+// whether any image was changed.
+func rewriteCSVImages(csv *unstructured.Unstructured, registry string) (bool, error) {
+	deployments, ok, err := unstructured.NestedSlice(csv.Object, "spec", "install", "spec", "deployments")
+	if err != nil || !ok {
+		return false, err
+	}
+
+	updated := false
+	for _, d := range deployments {
+		deployment, ok := d.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		containers, ok, err := unstructured.NestedSlice(deployment, "spec", "template", "spec", "containers")
+		if err != nil || !ok {
+			continue
+		}
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			image, ok := container["image"].(string)
+			if !ok {
+				continue
+			}
+			if rewritten := artifacts.RewriteImage(image, registry); rewritten != image {
+				container["image"] = rewritten
+				updated = true
+			}
+		}
+		if err := unstructured.SetNestedSlice(deployment, containers, "spec", "template", "spec", "containers"); err != nil {
+			return false, err
+		}
+	}
+	if !updated {
+		return false, nil
+	}
+	return true, unstructured.SetNestedSlice(csv.Object, deployments, "spec", "install", "spec", "deployments")
+}
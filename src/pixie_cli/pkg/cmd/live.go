@@ -39,10 +39,14 @@ func init() {
 	LiveCmd.Flags().StringP("file", "f", "", "Script file, specify - for STDIN")
 	LiveCmd.Flags().BoolP("new_autocomplete", "n", false, "Whether to use the new autocomplete")
 	LiveCmd.Flags().BoolP("e2e_encryption", "e", true, "Enable E2E encryption")
+	LiveCmd.Flags().String("save", "", "Save the results of the last executed script to a file that can be viewed offline with 'px view', eg: --save results.pxr")
 
 	LiveCmd.Flags().BoolP("all-clusters", "d", false, "Run script across all clusters")
 	LiveCmd.Flags().StringP("cluster", "c", "", "Run only on selected cluster")
+	LiveCmd.Flags().String("selector", "", "Run on the first healthy cluster matching the label selector, eg: env=prod")
+	LiveCmd.Flags().String("group", "", "Run on the first healthy cluster in the cluster group, eg: prod")
 	LiveCmd.Flags().MarkHidden("all-clusters")
+	addDirectVizierFlags(LiveCmd)
 }
 
 // LiveCmd is the "query" command.
@@ -54,9 +58,12 @@ var LiveCmd = &cobra.Command{
 
 		useNewAC, _ := cmd.Flags().GetBool("new_autocomplete")
 
-		br := mustCreateBundleReader()
+		br, err := createBundleReaderForCmd(cmd)
+		if err != nil {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatal("Failed to load bundle scripts")
+		}
 		var execScript *script.ExecutableScript
-		var err error
 		scriptFile, _ := cmd.Flags().GetString("file")
 		var scriptArgs []string
 
@@ -93,15 +100,57 @@ var LiveCmd = &cobra.Command{
 			}
 		}
 
+		useEncryption, _ := cmd.Flags().GetBool("e2e_encryption")
+
+		savePath, _ := cmd.Flags().GetString("save")
+		var recorder *vizier.Recorder
+		if savePath != "" {
+			recorder = vizier.NewRecorder()
+		}
+
+		allClusters, _ := cmd.Flags().GetBool("all-clusters")
+		selectedCluster, _ := cmd.Flags().GetString("cluster")
+		selector, _ := cmd.Flags().GetString("selector")
+		group, _ := cmd.Flags().GetString("group")
+		clusterUUID := uuid.FromStringOrNil(selectedCluster)
+
+		directAddr, _ := cmd.Flags().GetString("direct_addr")
+		if directAddr != "" {
+			if allClusters || selector != "" || group != "" {
+				utils.Fatal("--direct_addr cannot be combined with --selector, --group or --all-clusters.")
+			}
+			if useNewAC {
+				utils.Fatal("--new_autocomplete needs Pixie Cloud and cannot be combined with --direct_addr.")
+			}
+			viziers := mustConnectDirectVizier(cmd, directAddr, clusterUUID)
+			runLiveView(br, viziers, "", nil, execScript, false, useEncryption, clusterUUID, recorder, savePath)
+			return
+		}
+
 		cloudConn, err := utils.GetCloudClientConnection(cloudAddr)
 		if err != nil {
 			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 			log.WithError(err).Fatal("Could not connect to cloud")
 		}
 		aClient := cloudpb.NewAutocompleteServiceClient(cloudConn)
-		allClusters, _ := cmd.Flags().GetBool("all-clusters")
-		selectedCluster, _ := cmd.Flags().GetString("cluster")
-		clusterUUID := uuid.FromStringOrNil(selectedCluster)
+		if selector != "" && (allClusters || selectedCluster != "") {
+			utils.Fatal("--selector cannot be combined with --cluster or --all-clusters.")
+		}
+		if group != "" && (allClusters || selectedCluster != "" || selector != "") {
+			utils.Fatal("--group cannot be combined with --cluster, --selector or --all-clusters.")
+		}
+		if selector != "" {
+			clusterUUID, err = vizier.FirstHealthyVizierBySelector(cloudAddr, selector)
+			if err != nil {
+				utils.WithError(err).Fatal("Could not fetch healthy vizier")
+			}
+		}
+		if group != "" {
+			clusterUUID, err = vizier.FirstHealthyVizierByGroup(cloudAddr, group)
+			if err != nil {
+				utils.WithError(err).Fatal("Could not fetch healthy vizier")
+			}
+		}
 		if !allClusters && clusterUUID == uuid.Nil {
 			clusterUUID, err = vizier.GetCurrentOrFirstHealthyVizier(cloudAddr)
 			if err != nil {
@@ -109,16 +158,27 @@ var LiveCmd = &cobra.Command{
 			}
 		}
 
-		useEncryption, _ := cmd.Flags().GetBool("e2e_encryption")
-
 		viziers := vizier.MustConnectHealthyDefaultVizier(cloudAddr, allClusters, clusterUUID)
-		lv, err := live.New(br, viziers, cloudAddr, aClient, execScript, useNewAC, useEncryption, clusterUUID)
-		if err != nil {
-			utils.WithError(err).Fatal("Failed to initialize live view")
-		}
+		runLiveView(br, viziers, cloudAddr, aClient, execScript, useNewAC, useEncryption, clusterUUID, recorder, savePath)
+	},
+}
 
-		if err := lv.Run(); err != nil {
-			utils.WithError(err).Fatal("Failed to run live view")
+func runLiveView(br *script.BundleManager, viziers []*vizier.Connector, cloudAddr string, aClient cloudpb.AutocompleteServiceClient,
+	execScript *script.ExecutableScript, useNewAC, useEncryption bool, clusterID uuid.UUID, recorder *vizier.Recorder, savePath string) {
+	lv, err := live.New(br, viziers, cloudAddr, aClient, execScript, useNewAC, useEncryption, clusterID, recorder)
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to initialize live view")
+	}
+
+	if err := lv.Run(); err != nil {
+		utils.WithError(err).Fatal("Failed to run live view")
+	}
+
+	if recorder != nil {
+		rec, err := lv.Recording()
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to capture script results")
 		}
-	},
+		writeRecording(rec, savePath)
+	}
 }
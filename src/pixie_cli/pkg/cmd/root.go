@@ -50,6 +50,12 @@ func init() {
 	RootCmd.PersistentFlags().BoolP("quiet", "q", false, "quiet mode")
 	viper.BindPFlag("quiet", RootCmd.PersistentFlags().Lookup("quiet"))
 
+	RootCmd.PersistentFlags().String("context", "", "The name of the CLI context to use. Defaults to the current context")
+	viper.BindPFlag("context", RootCmd.PersistentFlags().Lookup("context"))
+
+	RootCmd.PersistentFlags().String("vizier_namespace", "", "The namespace of the Vizier instance to use, if several are running in the current K8s cluster")
+	viper.BindPFlag("vizier_namespace", RootCmd.PersistentFlags().Lookup("vizier_namespace"))
+
 	RootCmd.PersistentFlags().Bool("do_not_track", false, "do_not_track")
 	viper.BindPFlag("do_not_track", RootCmd.PersistentFlags().Lookup("do_not_track"))
 
@@ -59,16 +65,19 @@ func init() {
 	RootCmd.AddCommand(CreateCloudCertsCmd)
 	RootCmd.AddCommand(DemoCmd)
 	RootCmd.AddCommand(DeployCmd)
+	RootCmd.AddCommand(BundleVizierCmd)
 	RootCmd.AddCommand(DeleteCmd)
 	RootCmd.AddCommand(UpdateCmd)
 	RootCmd.AddCommand(RunCmd)
 	RootCmd.AddCommand(LiveCmd)
+	RootCmd.AddCommand(ViewCmd)
 	RootCmd.AddCommand(GetCmd)
 	RootCmd.AddCommand(ConfigCmd)
 	RootCmd.AddCommand(ScriptCmd)
 	RootCmd.AddCommand(CreateBundle)
 	RootCmd.AddCommand(DeployKeyCmd)
 	RootCmd.AddCommand(APIKeyCmd)
+	RootCmd.AddCommand(AuditCmd)
 	RootCmd.AddCommand(DebugCmd)
 
 	RootCmd.PersistentFlags().MarkHidden("cloud_addr")
@@ -122,6 +131,8 @@ var RootCmd = &cobra.Command{
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		printTestingBanner()
 
+		applyContext(cmd)
+
 		cloudAddr := viper.GetString("cloud_addr")
 		if matched, err := regexp.MatchString(".+:[0-9]+$", cloudAddr); !matched && err == nil {
 			viper.Set("cloud_addr", cloudAddr+":443")
@@ -178,9 +189,48 @@ var RootCmd = &cobra.Command{
 	},
 }
 
+// applyContext uses the settings of the selected context for any settings which were not
+// explicitly specified through flags or environment variables.
+func applyContext(cmd *cobra.Command) {
+	ctx, err := pxconfig.CurrentContext()
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to load context. Run `px config get-contexts` to list the available contexts.")
+	}
+
+	cloudAddrFlag := cmd.Flags().Lookup("cloud_addr")
+	cloudAddrFromEnv := os.Getenv("PX_CLOUD_ADDR") != "" || os.Getenv("PL_CLOUD_ADDR") != ""
+	if ctx.CloudAddr != "" && !cloudAddrFromEnv && (cloudAddrFlag == nil || !cloudAddrFlag.Changed) {
+		viper.Set("cloud_addr", ctx.CloudAddr)
+	}
+
+	// The flags of set-context describe the context being modified, so they shouldn't be
+	// filled in from the current context.
+	if cmd == SetContextCmd {
+		return
+	}
+
+	setFlagDefault := func(name, value string) {
+		f := cmd.Flags().Lookup(name)
+		if value == "" || f == nil || f.Changed {
+			return
+		}
+		_ = cmd.Flags().Set(name, value)
+	}
+	setFlagDefault("cluster", ctx.Cluster)
+	setFlagDefault("output", ctx.Output)
+	// The namespace flag of create-cloud-certs refers to the cloud namespace, not the Vizier namespace.
+	if cmd != CreateCloudCertsCmd {
+		setFlagDefault("namespace", ctx.Namespace)
+	}
+}
+
 func checkAuthForCmd(c *cobra.Command) {
+	// Standalone Viziers are connected to directly and authenticate the API key themselves.
+	if f := c.Flags().Lookup("direct_addr"); f != nil && f.Value.String() != "" {
+		return
+	}
 	switch c {
-	case DeployCmd, UpdateCmd, RunCmd, LiveCmd, GetCmd, ConfigCmd, ScriptCmd, DeployKeyCmd, APIKeyCmd:
+	case DeployCmd, UpdateCmd, RunCmd, LiveCmd, GetCmd, GetConfigCmd, UpdateConfigCmd, ScriptCmd, DeployKeyCmd, APIKeyCmd, AuditCmd:
 		authenticated := auth.IsAuthenticated(viper.GetString("cloud_addr"))
 		if !authenticated {
 			utils.Errorf("Failed to authenticate. Please retry `px auth login`.")
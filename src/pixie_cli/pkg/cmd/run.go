@@ -23,6 +23,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"strings"
 
@@ -46,7 +47,12 @@ func init() {
 	RunCmd.Flags().BoolP("all-clusters", "d", false, "Run script across all clusters")
 	RunCmd.Flags().StringP("cluster", "c", "", "ID of the cluster to run on. "+
 		"Use 'px get viziers', or visit Admin console: work.withpixie.ai/admin, to find the ID")
+	RunCmd.Flags().String("selector", "", "Label selector for the clusters to run on, eg: env=prod,team!=infra")
+	RunCmd.Flags().String("group", "", "Name of the cluster group to run on, eg: prod")
 	RunCmd.Flags().MarkHidden("all-clusters")
+	addDirectVizierFlags(RunCmd)
+	RunCmd.Flags().StringToString("set", map[string]string{}, "Query flags to set for the script, eg: --set max_output_rows_per_table=20000")
+	RunCmd.Flags().String("save", "", "Save the script results to a file that can be viewed offline with 'px view', eg: --save results.pxr")
 
 	RunCmd.Flags().StringP("bundle", "b", "", "Path/URL to bundle file")
 	viper.BindPFlag("bundle", RunCmd.Flags().Lookup("bundle"))
@@ -115,13 +121,14 @@ func createNewCobraCommand() *cobra.Command {
 			format, _ := cmd.Flags().GetString("output")
 
 			format = strings.ToLower(format)
+			savePath, _ := cmd.Flags().GetString("save")
 			if format == "live" {
 				LiveCmd.Run(cmd, args)
 				return
 			}
 
 			listScripts, _ := cmd.Flags().GetBool("list")
-			br, err := createBundleReader()
+			br, err := createBundleReaderForCmd(cmd)
 			if err != nil {
 				// Keep this as a log.Fatal() as opposed to using the utils, because it
 				// is an unexpected error that Sentry should catch.
@@ -171,24 +178,55 @@ func createNewCobraCommand() *cobra.Command {
 				}
 			}
 
+			queryFlags, _ := cmd.Flags().GetStringToString("set")
+			if len(queryFlags) > 0 {
+				execScript.QueryFlags = queryFlags
+			}
+
 			allClusters, _ := cmd.Flags().GetBool("all-clusters")
 			selectedCluster, _ := cmd.Flags().GetString("cluster")
+			selector, _ := cmd.Flags().GetString("selector")
+			group, _ := cmd.Flags().GetString("group")
 			clusterID := uuid.FromStringOrNil(selectedCluster)
+			if selector != "" && (allClusters || selectedCluster != "") {
+				utils.Fatal("--selector cannot be combined with --cluster or --all-clusters.")
+			}
+			if group != "" && (allClusters || selectedCluster != "" || selector != "") {
+				utils.Fatal("--group cannot be combined with --cluster, --selector or --all-clusters.")
+			}
+			directAddr, _ := cmd.Flags().GetString("direct_addr")
+			if directAddr != "" && (allClusters || selector != "" || group != "") {
+				utils.Fatal("--direct_addr cannot be combined with --selector, --group or --all-clusters.")
+			}
 
-			if !allClusters && clusterID == uuid.Nil {
+			if !allClusters && clusterID == uuid.Nil && selector == "" && group == "" && directAddr == "" {
 				clusterID, err = vizier.GetCurrentOrFirstHealthyVizier(cloudAddr)
 				if err != nil {
 					utils.WithError(err).Fatal("Could not fetch healthy vizier")
 				}
 			}
 
-			conns := vizier.MustConnectHealthyDefaultVizier(cloudAddr, allClusters, clusterID)
+			var conns []*vizier.Connector
+			switch {
+			case directAddr != "":
+				conns = mustConnectDirectVizier(cmd, directAddr, clusterID)
+			case selector != "":
+				conns = vizier.MustConnectViziersBySelector(cloudAddr, selector)
+			case group != "":
+				conns = vizier.MustConnectViziersByGroup(cloudAddr, group)
+			default:
+				conns = vizier.MustConnectHealthyDefaultVizier(cloudAddr, allClusters, clusterID)
+			}
 			useEncryption, _ := cmd.Flags().GetBool("e2e_encryption")
 
 			// Support Ctrl+C to cancel a query.
 			ctx, cleanup := utils.WithSignalCancellable(context.Background())
 			defer cleanup()
-			err = vizier.RunScriptAndOutputResults(ctx, conns, execScript, format, useEncryption)
+			var recorder *vizier.Recorder
+			if savePath != "" {
+				recorder = vizier.NewRecorder()
+			}
+			err = vizier.RunScriptAndOutputResults(ctx, conns, execScript, format, useEncryption, recorder)
 
 			if err != nil {
 				vzErr, ok := err.(*vizier.ScriptExecutionError)
@@ -202,6 +240,14 @@ func createNewCobraCommand() *cobra.Command {
 				}
 			}
 
+			// A standalone Vizier has no Pixie Cloud to look up its name or to show it in the live UI.
+			if directAddr != "" {
+				if recorder != nil {
+					saveRecording(recorder, execScript, nil, savePath)
+				}
+				return
+			}
+
 			// Get the name for this cluster for the live view
 			var clusterName *string
 			lister, err := vizier.NewLister(cloudAddr)
@@ -218,6 +264,10 @@ func createNewCobraCommand() *cobra.Command {
 				clusterName = &(vzInfo[0].ClusterName)
 			}
 
+			if recorder != nil {
+				saveRecording(recorder, execScript, clusterName, savePath)
+			}
+
 			if lvl := execScript.LiveViewLink(clusterName); lvl != "" {
 				p := func(s string, a ...interface{}) {
 					fmt.Fprintf(os.Stderr, s, a...)
@@ -231,6 +281,60 @@ func createNewCobraCommand() *cobra.Command {
 	}
 }
 
+func addDirectVizierFlags(cmd *cobra.Command) {
+	cmd.Flags().String("direct_addr", "", "Address of a standalone Vizier to connect to directly instead of through Pixie Cloud, "+
+		"eg: vizier-query-broker-svc.pl.svc:50300")
+	cmd.Flags().String("api_key", "", "API key for the standalone Vizier given by --direct_addr, defaults to $PX_API_KEY")
+	cmd.Flags().String("ca_cert", "", "Path to the PEM encoded CA cert that signed the standalone Vizier's cert")
+}
+
+// mustConnectDirectVizier connects to the standalone Vizier at directAddr. The cluster ID is only
+// used to label the results, a standalone Vizier does not check it.
+func mustConnectDirectVizier(cmd *cobra.Command, directAddr string, clusterID uuid.UUID) []*vizier.Connector {
+	apiKey, _ := cmd.Flags().GetString("api_key")
+	if apiKey == "" {
+		apiKey = viper.GetString("api_key")
+	}
+	if apiKey == "" {
+		utils.Fatal("--direct_addr requires an API key, set --api_key or PX_API_KEY.")
+	}
+
+	var caCert []byte
+	caCertPath, _ := cmd.Flags().GetString("ca_cert")
+	if caCertPath != "" {
+		var err error
+		caCert, err = ioutil.ReadFile(caCertPath)
+		if err != nil {
+			utils.WithError(err).Fatalf("Failed to read CA cert %s", caCertPath)
+		}
+	}
+
+	c, err := vizier.NewDirectConnector(directAddr, clusterID, apiKey, caCert)
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to connect to vizier")
+	}
+	return []*vizier.Connector{c}
+}
+
+func saveRecording(recorder *vizier.Recorder, execScript *script.ExecutableScript, clusterName *string, path string) {
+	name := ""
+	if clusterName != nil {
+		name = *clusterName
+	}
+	rec, err := recorder.Recording(execScript, name)
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to capture script results")
+	}
+	writeRecording(rec, path)
+}
+
+func writeRecording(rec *vizier.Recording, path string) {
+	if err := rec.WriteFile(path); err != nil {
+		utils.WithError(err).Fatalf("Failed to save script results to %s", path)
+	}
+	utils.Infof("Saved script results to %s", path)
+}
+
 // RunCmd is the "query" command.
 var RunCmd = createNewCobraCommand()
 
@@ -28,6 +28,7 @@ import (
 
 	"github.com/bmatcuk/doublestar"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"px.dev/pixie/src/pixie_cli/pkg/components"
@@ -47,18 +48,32 @@ func mustCreateBundleReader() *script.BundleManager {
 	return br
 }
 
-func createBundleReader() (*script.BundleManager, error) {
+func bundleFiles() []string {
 	bundleFile := viper.GetString("bundle")
 	if bundleFile == "" {
 		bundleFile = defaultBundleFile
 	}
-	br, err := script.NewBundleManager([]string{bundleFile, ossBundleFile})
+	return []string{bundleFile, ossBundleFile}
+}
+
+func createBundleReader() (*script.BundleManager, error) {
+	br, err := script.NewBundleManager(bundleFiles())
 	if err != nil {
 		return nil, err
 	}
 	return br, nil
 }
 
+// createBundleReaderForCmd creates the bundle reader for a command that can run scripts on a
+// standalone Vizier. Org scripts are skipped with --direct_addr, since selecting them needs the
+// Pixie Cloud credentials.
+func createBundleReaderForCmd(cmd *cobra.Command) (*script.BundleManager, error) {
+	if directAddr, _ := cmd.Flags().GetString("direct_addr"); directAddr != "" {
+		return script.NewBundleManagerWithOrg(bundleFiles(), "", "")
+	}
+	return createBundleReader()
+}
+
 func listBundleScripts(br *script.BundleManager, format string) {
 	w := components.CreateStreamWriter(format, os.Stdout)
 	defer w.Finish()
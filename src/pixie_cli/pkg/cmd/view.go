@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/live"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+func init() {
+	ViewCmd.Flags().StringP("output", "o", "", "Output format: one of: json|table|csv|live")
+}
+
+// ViewCmd is the "view" command, which renders script results saved with "px run --save".
+var ViewCmd = &cobra.Command{
+	Use:   "view FILE",
+	Short: "View saved script results",
+	Long:  "View script results saved with 'px run --save'. Does not require access to Pixie Cloud or a cluster.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+
+		rec, err := vizier.ReadRecordingFile(args[0])
+		if err != nil {
+			utils.WithError(err).Fatalf("Failed to read script results from %s", args[0])
+		}
+
+		if format == "live" {
+			lv, err := live.NewFromRecording(rec)
+			if err != nil {
+				utils.WithError(err).Fatal("Failed to initialize live view")
+			}
+			if err := lv.Run(); err != nil {
+				utils.WithError(err).Fatal("Failed to run live view")
+			}
+			return
+		}
+
+		fmt.Fprintf(os.Stderr, "Script %s recorded at %s", rec.Header.ScriptName,
+			rec.Header.CapturedAt.Local().Format(time.RFC1123))
+		if rec.Header.ClusterName != "" {
+			fmt.Fprintf(os.Stderr, " on %s", rec.Header.ClusterName)
+		}
+		fmt.Fprintf(os.Stderr, "\n")
+
+		ctx, cleanup := utils.WithSignalCancellable(context.Background())
+		defer cleanup()
+		tw := vizier.NewStreamOutputAdapter(ctx, rec.Stream(ctx), format, nil)
+		if err := tw.Finish(); err != nil {
+			utils.WithError(err).Fatal("Failed to render script results")
+		}
+	},
+}
@@ -0,0 +1,574 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package live
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/tview"
+
+	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+const (
+	timeColName    = "time_"
+	maxBarLabelLen = 30
+)
+
+// seriesColors is the palette used for the series of a chart, in order.
+var seriesColors = []tcell.Color{
+	tcell.NewHexColor(0x3fe7e7),
+	tcell.NewHexColor(0xf4b942),
+	tcell.NewHexColor(0xe55b7f),
+	tcell.NewHexColor(0x7bd35b),
+	tcell.NewHexColor(0xa77cf2),
+	tcell.NewHexColor(0xf2884b),
+	tcell.NewHexColor(0x4b8df2),
+	tcell.NewHexColor(0xd9d9d9),
+}
+
+// barEighths are the block characters used to draw the fractional end of a bar.
+var barEighths = []rune{' ', '▏', '▎', '▍', '▌', '▋', '▊', '▉'}
+
+func seriesColor(idx int) tcell.Color {
+	return seriesColors[idx%len(seriesColors)]
+}
+
+func colIndex(t components.TableView, name string) int {
+	for idx, h := range t.Header() {
+		if h == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func toTime(val interface{}) (time.Time, bool) {
+	switch v := val.(type) {
+	case time.Time:
+		return v, true
+	case int64:
+		return time.Unix(0, v), true
+	}
+	return time.Time{}, false
+}
+
+// formatChartValue formats a value of the column for display inside of a chart.
+func formatChartValue(formatter vizier.DataFormatter, colIdx int, val interface{}) string {
+	return tview.TranslateANSI(fmt.Sprintf("%v", formatter.FormatValue(colIdx, val)))
+}
+
+// printChartError prints a message explaining why the chart could not be drawn.
+func printChartError(screen tcell.Screen, box *tview.Box, err error) {
+	x, y, width, height := box.GetInnerRect()
+	tview.Print(screen, err.Error(), x, y+height/2, width, tview.AlignCenter, tcell.ColorRed)
+}
+
+// brailleCanvas is a canvas of dots which are drawn using braille characters, which
+// gives a resolution of 2x4 dots per terminal cell.
+type brailleCanvas struct {
+	width, height int
+	cells         [][]rune
+	colors        [][]tcell.Color
+}
+
+// brailleDots maps the position of a dot within a cell to its bit in the braille character.
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+func newBrailleCanvas(width, height int) *brailleCanvas {
+	c := &brailleCanvas{
+		width:  width,
+		height: height,
+		cells:  make([][]rune, height),
+		colors: make([][]tcell.Color, height),
+	}
+	for i := range c.cells {
+		c.cells[i] = make([]rune, width)
+		c.colors[i] = make([]tcell.Color, width)
+	}
+	return c
+}
+
+// dotWidth and dotHeight are the dimensions of the canvas in dots.
+func (c *brailleCanvas) dotWidth() int  { return c.width * 2 }
+func (c *brailleCanvas) dotHeight() int { return c.height * 4 }
+
+func (c *brailleCanvas) set(x, y int, color tcell.Color) {
+	if x < 0 || y < 0 || x >= c.dotWidth() || y >= c.dotHeight() {
+		return
+	}
+	c.cells[y/4][x/2] |= brailleDots[y%4][x%2]
+	c.colors[y/4][x/2] = color
+}
+
+// line draws a line between the two dots using Bresenham's algorithm.
+func (c *brailleCanvas) line(x0, y0, x1, y1 int, color tcell.Color) {
+	dx := int(math.Abs(float64(x1 - x0)))
+	dy := -int(math.Abs(float64(y1 - y0)))
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		c.set(x0, y0, color)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func (c *brailleCanvas) draw(screen tcell.Screen, x, y int) {
+	for row := range c.cells {
+		for col, dots := range c.cells[row] {
+			if dots == 0 {
+				continue
+			}
+			style := tcell.StyleDefault.Foreground(c.colors[row][col])
+			screen.SetContent(x+col, y+row, 0x2800+dots, nil, style)
+		}
+	}
+}
+
+type timeseriesPoint struct {
+	t time.Time
+	v float64
+}
+
+type timeseries struct {
+	name   string
+	mode   vispb.TimeseriesChart_Timeseries_Mode
+	points []timeseriesPoint
+}
+
+// timeseriesChart renders a vispb.TimeseriesChart as a line chart.
+type timeseriesChart struct {
+	*tview.Box
+	spec      *vispb.TimeseriesChart
+	table     components.TableView
+	formatter vizier.DataFormatter
+}
+
+func newTimeseriesChart(spec *vispb.TimeseriesChart, table components.TableView, formatter vizier.DataFormatter) *timeseriesChart {
+	return &timeseriesChart{
+		Box:       tview.NewBox(),
+		spec:      spec,
+		table:     table,
+		formatter: formatter,
+	}
+}
+
+// series splits the table into one timeseries per value and series column value.
+func (c *timeseriesChart) series() ([]*timeseries, int, error) {
+	timeIdx := colIndex(c.table, timeColName)
+	if timeIdx < 0 {
+		return nil, 0, fmt.Errorf("column %s not found", timeColName)
+	}
+	if len(c.spec.Timeseries) == 0 {
+		return nil, 0, fmt.Errorf("no timeseries specified")
+	}
+
+	var all []*timeseries
+	valueIdx := -1
+	for _, ts := range c.spec.Timeseries {
+		vIdx := colIndex(c.table, ts.Value)
+		if vIdx < 0 {
+			return nil, 0, fmt.Errorf("column %s not found", ts.Value)
+		}
+		if valueIdx < 0 {
+			valueIdx = vIdx
+		}
+		sIdx := -1
+		if ts.Series != "" {
+			sIdx = colIndex(c.table, ts.Series)
+			if sIdx < 0 {
+				return nil, 0, fmt.Errorf("column %s not found", ts.Series)
+			}
+		}
+
+		bySeries := make(map[string]*timeseries)
+		for _, row := range c.table.Data() {
+			t, ok := toTime(row[timeIdx])
+			if !ok {
+				continue
+			}
+			v, ok := toFloat(row[vIdx])
+			if !ok {
+				continue
+			}
+			name := ts.Value
+			if sIdx >= 0 {
+				name = fmt.Sprintf("%v", row[sIdx])
+				if len(c.spec.Timeseries) > 1 {
+					name = fmt.Sprintf("%s (%s)", name, ts.Value)
+				}
+			}
+			s, ok := bySeries[name]
+			if !ok {
+				s = &timeseries{name: name, mode: ts.Mode}
+				bySeries[name] = s
+				all = append(all, s)
+			}
+			s.points = append(s.points, timeseriesPoint{t, v})
+		}
+	}
+
+	for _, s := range all {
+		sort.Slice(s.points, func(i, j int) bool {
+			return s.points[i].t.Before(s.points[j].t)
+		})
+	}
+	return all, valueIdx, nil
+}
+
+// Draw draws the chart onto the screen.
+func (c *timeseriesChart) Draw(screen tcell.Screen) {
+	c.Box.Draw(screen)
+	x, y, width, height := c.GetInnerRect()
+
+	series, valueIdx, err := c.series()
+	if err != nil {
+		printChartError(screen, c.Box, err)
+		return
+	}
+	if len(series) == 0 {
+		tview.Print(screen, "No data", x, y+height/2, width, tview.AlignCenter, tcell.ColorGray)
+		return
+	}
+
+	var minT, maxT time.Time
+	minV, maxV := 0.0, 0.0
+	for i, s := range series {
+		for j, p := range s.points {
+			if (i == 0 && j == 0) || p.t.Before(minT) {
+				minT = p.t
+			}
+			if (i == 0 && j == 0) || p.t.After(maxT) {
+				maxT = p.t
+			}
+			minV = math.Min(minV, p.v)
+			maxV = math.Max(maxV, p.v)
+		}
+	}
+	if maxV == minV {
+		maxV = minV + 1
+	}
+
+	// The legend takes up the first line.
+	legendX := x
+	for idx, s := range series {
+		entry := fmt.Sprintf("■ %s  ", s.name)
+		_, printed := tview.Print(screen, entry, legendX, y, x+width-legendX, tview.AlignLeft, seriesColor(idx))
+		legendX += printed
+		if legendX >= x+width {
+			break
+		}
+	}
+
+	// The y-axis labels take up the left of the chart, and the time labels take up the last line.
+	maxLabel := formatChartValue(c.formatter, valueIdx, maxV)
+	minLabel := formatChartValue(c.formatter, valueIdx, minV)
+	labelWidth := tview.TaggedStringWidth(maxLabel)
+	if w := tview.TaggedStringWidth(minLabel); w > labelWidth {
+		labelWidth = w
+	}
+	labelWidth++
+
+	plotX, plotY := x+labelWidth, y+1
+	plotWidth, plotHeight := width-labelWidth, height-2
+	if plotWidth < 2 || plotHeight < 1 {
+		return
+	}
+	tview.Print(screen, maxLabel, x, plotY, labelWidth-1, tview.AlignRight, tcell.ColorWhite)
+	tview.Print(screen, minLabel, x, plotY+plotHeight-1, labelWidth-1, tview.AlignRight, tcell.ColorWhite)
+	tview.Print(screen, minT.Format("15:04:05"), plotX, plotY+plotHeight, plotWidth, tview.AlignLeft, tcell.ColorWhite)
+	tview.Print(screen, maxT.Format("15:04:05"), plotX, plotY+plotHeight, plotWidth, tview.AlignRight, tcell.ColorWhite)
+
+	canvas := newBrailleCanvas(plotWidth, plotHeight)
+	span := maxT.Sub(minT)
+	toDot := func(p timeseriesPoint) (int, int) {
+		dx := 0
+		if span > 0 {
+			dx = int(math.Round(float64(p.t.Sub(minT)) / float64(span) * float64(canvas.dotWidth()-1)))
+		}
+		dy := int(math.Round((maxV - p.v) / (maxV - minV) * float64(canvas.dotHeight()-1)))
+		return dx, dy
+	}
+	for idx, s := range series {
+		color := seriesColor(idx)
+		for i, p := range s.points {
+			px, py := toDot(p)
+			switch s.mode {
+			case vispb.MODE_POINT:
+				canvas.set(px, py, color)
+			case vispb.MODE_AREA:
+				canvas.line(px, py, px, canvas.dotHeight()-1, color)
+			default:
+				if i == 0 {
+					canvas.set(px, py, color)
+					continue
+				}
+				prevX, prevY := toDot(s.points[i-1])
+				canvas.line(prevX, prevY, px, py, color)
+			}
+		}
+	}
+	canvas.draw(screen, plotX, plotY)
+}
+
+type barSegment struct {
+	name  string
+	value float64
+}
+
+type bar struct {
+	label    string
+	segments []*barSegment
+}
+
+func (b *bar) total() float64 {
+	t := 0.0
+	for _, s := range b.segments {
+		t += s.value
+	}
+	return t
+}
+
+// barChart renders a vispb.BarChart as horizontal bars.
+type barChart struct {
+	*tview.Box
+	spec      *vispb.BarChart
+	table     components.TableView
+	formatter vizier.DataFormatter
+}
+
+func newBarChart(spec *vispb.BarChart, table components.TableView, formatter vizier.DataFormatter) *barChart {
+	return &barChart{
+		Box:       tview.NewBox(),
+		spec:      spec,
+		table:     table,
+		formatter: formatter,
+	}
+}
+
+// bars aggregates the table into bars, in the order in which their labels first appear.
+// Grouped bars get their own bar, stacked values become segments of a bar.
+func (c *barChart) bars() ([]*bar, []string, int, error) {
+	if c.spec.Bar == nil {
+		return nil, nil, 0, fmt.Errorf("no bar specified")
+	}
+	valueIdx := colIndex(c.table, c.spec.Bar.Value)
+	if valueIdx < 0 {
+		return nil, nil, 0, fmt.Errorf("column %s not found", c.spec.Bar.Value)
+	}
+	labelIdx := colIndex(c.table, c.spec.Bar.Label)
+	if labelIdx < 0 {
+		return nil, nil, 0, fmt.Errorf("column %s not found", c.spec.Bar.Label)
+	}
+	groupIdx, stackIdx := -1, -1
+	if c.spec.Bar.GroupBy != "" {
+		if groupIdx = colIndex(c.table, c.spec.Bar.GroupBy); groupIdx < 0 {
+			return nil, nil, 0, fmt.Errorf("column %s not found", c.spec.Bar.GroupBy)
+		}
+	}
+	if c.spec.Bar.StackBy != "" {
+		if stackIdx = colIndex(c.table, c.spec.Bar.StackBy); stackIdx < 0 {
+			return nil, nil, 0, fmt.Errorf("column %s not found", c.spec.Bar.StackBy)
+		}
+	}
+
+	var bars []*bar
+	var stacks []string
+	byLabel := make(map[string]*bar)
+	seenStacks := make(map[string]bool)
+	for _, row := range c.table.Data() {
+		v, ok := toFloat(row[valueIdx])
+		if !ok {
+			continue
+		}
+		label := fmt.Sprintf("%v", row[labelIdx])
+		if groupIdx >= 0 {
+			label = fmt.Sprintf("%v / %s", row[groupIdx], label)
+		}
+		stack := ""
+		if stackIdx >= 0 {
+			stack = fmt.Sprintf("%v", row[stackIdx])
+			if !seenStacks[stack] {
+				seenStacks[stack] = true
+				stacks = append(stacks, stack)
+			}
+		}
+
+		b, ok := byLabel[label]
+		if !ok {
+			b = &bar{label: label}
+			byLabel[label] = b
+			bars = append(bars, b)
+		}
+		var seg *barSegment
+		for _, s := range b.segments {
+			if s.name == stack {
+				seg = s
+			}
+		}
+		if seg == nil {
+			seg = &barSegment{name: stack}
+			b.segments = append(b.segments, seg)
+		}
+		seg.value += v
+	}
+	return bars, stacks, valueIdx, nil
+}
+
+// Draw draws the chart onto the screen.
+func (c *barChart) Draw(screen tcell.Screen) {
+	c.Box.Draw(screen)
+	x, y, width, height := c.GetInnerRect()
+
+	bars, stacks, valueIdx, err := c.bars()
+	if err != nil {
+		printChartError(screen, c.Box, err)
+		return
+	}
+	if len(bars) == 0 {
+		tview.Print(screen, "No data", x, y+height/2, width, tview.AlignCenter, tcell.ColorGray)
+		return
+	}
+
+	stackColor := make(map[string]tcell.Color)
+	for idx, s := range stacks {
+		stackColor[s] = seriesColor(idx)
+	}
+	if len(stacks) > 0 {
+		legendX := x
+		for _, s := range stacks {
+			_, printed := tview.Print(screen, fmt.Sprintf("■ %s  ", s), legendX, y, x+width-legendX, tview.AlignLeft, stackColor[s])
+			legendX += printed
+			if legendX >= x+width {
+				break
+			}
+		}
+		y++
+		height--
+	}
+
+	labelWidth := 0
+	maxTotal := 0.0
+	valueWidth := 0
+	values := make([]string, len(bars))
+	for idx, b := range bars {
+		if l := runewidth.StringWidth(b.label); l > labelWidth {
+			labelWidth = l
+		}
+		maxTotal = math.Max(maxTotal, b.total())
+		values[idx] = formatChartValue(c.formatter, valueIdx, b.total())
+		if w := tview.TaggedStringWidth(values[idx]); w > valueWidth {
+			valueWidth = w
+		}
+	}
+	if labelWidth > maxBarLabelLen {
+		labelWidth = maxBarLabelLen
+	}
+	barWidth := width - labelWidth - valueWidth - 2
+
+	for idx, b := range bars {
+		if idx >= height {
+			break
+		}
+		row := y + idx
+		// Truncate by display width so that multi-byte characters aren't split.
+		label := runewidth.Truncate(b.label, labelWidth, "…")
+		tview.Print(screen, tview.Escape(label), x, row, labelWidth, tview.AlignRight, tcell.ColorWhite)
+
+		col := x + labelWidth + 1
+		end := drawBar(screen, b, col, row, barWidth, maxTotal, stackColor)
+		tview.Print(screen, values[idx], col+end+1, row, x+width-(col+end+1), tview.AlignLeft, tcell.ColorWhite)
+	}
+}
+
+// drawBar draws the segments of the bar starting at col, scaled so that maxTotal fills barWidth cells.
+// It returns the number of cells drawn.
+func drawBar(screen tcell.Screen, b *bar, col, row, barWidth int, maxTotal float64, stackColor map[string]tcell.Color) int {
+	// Nothing to draw for an all zero series, or when there is no room for the bars.
+	if maxTotal <= 0 || barWidth <= 0 {
+		return 0
+	}
+
+	// Segments are drawn in whole cells, only the end of the bar is drawn in eighths of a cell.
+	drawn := 0.0
+	end := 0
+	for segIdx, s := range b.segments {
+		color, ok := stackColor[s.name]
+		if !ok {
+			color = seriesColor(segIdx)
+		}
+		style := tcell.StyleDefault.Foreground(color)
+		start := int(drawn / maxTotal * float64(barWidth))
+		drawn += s.value
+		eighths := int(math.Round(drawn / maxTotal * float64(barWidth*8)))
+		end = eighths / 8
+		for cell := start; cell < end; cell++ {
+			screen.SetContent(col+cell, row, '█', nil, style)
+		}
+		if segIdx == len(b.segments)-1 && eighths%8 != 0 {
+			screen.SetContent(col+end, row, barEighths[eighths%8], nil, style)
+			end++
+		}
+	}
+	return end
+}
@@ -46,6 +46,7 @@ func (h *helpModal) Show(app *tview.Application) tview.Primitive {
 		{[]string{"ctrl", "c"}, "Quit the application"},
 		{[]string{"ctrl", "v"}, "View the underlying script"},
 		{[]string{"ctrl", "r"}, "Run current script (again)"},
+		{[]string{"t"}, "Toggle the selected widget between chart and table"},
 		{[]string{"escape"}, "Close dialogs/modals"},
 	}
 
@@ -85,8 +85,12 @@ type appState struct {
 	// Sort state is tracked on a per table basis for each column. It is cleared when a new
 	// script is executed.
 	sortState [][]sortType
+	// The widgets of the script's vis spec. When set, the tables are shown on a dashboard
+	// following the vis layout instead of one at a time.
+	widgets []*widget
 	// ----- View Specific State ------
-	// The currently selected table. Will reset to zero when new tables are inserted.
+	// The currently selected table, or widget when showing the dashboard. Will reset to zero
+	// when new tables are inserted.
 	selectedTable int
 
 	scriptViewOpen bool
@@ -113,6 +117,15 @@ type View struct {
 	cloudAddr         string
 	selectedClusterID uuid.UUID
 	vizierLister      *vizier.Lister
+	// recording is set when the view is replaying saved script results rather than running scripts.
+	recording *vizier.Recording
+	// recorder captures the results of the executed scripts when they are being saved.
+	recorder *vizier.Recorder
+	// recordedScript is the script whose results are held by the recorder.
+	recordedScript *script.ExecutableScript
+	// The views and their boxes for each of the widgets on the dashboard.
+	widgetViews []tview.Primitive
+	widgetBoxes []*tview.Box
 }
 
 // Modal is the interface for a pop-up view.
@@ -121,9 +134,61 @@ type Modal interface {
 	Close(a *tview.Application)
 }
 
-// New creates a new live view.
+// New creates a new live view. If recorder is not nil, the results of the last executed
+// script are captured in it. cloudAddr is empty when the viziers are standalone and connected
+// to directly, in which case the cluster name is not looked up.
 func New(br *script.BundleManager, viziers []*vizier.Connector, cloudAddr string, aClient cloudpb.AutocompleteServiceClient,
-	execScript *script.ExecutableScript, useNewAC, useEncryption bool, clusterID uuid.UUID) (*View, error) {
+	execScript *script.ExecutableScript, useNewAC, useEncryption bool, clusterID uuid.UUID, recorder *vizier.Recorder) (*View, error) {
+	var ac autocompleter
+	if useNewAC {
+		ac = newCloudAutocompleter(aClient)
+	} else {
+		ac = newFuzzyAutoCompleter(br)
+	}
+
+	var lister *vizier.Lister
+	if cloudAddr != "" {
+		var err error
+		lister, err = vizier.NewLister(cloudAddr)
+		if err != nil {
+			utils.WithError(err).Error("Failed to create Vizier lister")
+			return nil, err
+		}
+	}
+
+	v := newView(&appState{
+		br:         br,
+		viziers:    viziers,
+		ac:         ac,
+		execScript: execScript,
+	})
+	v.useNewAC = useNewAC
+	v.cloudAddr = cloudAddr
+	v.selectedClusterID = clusterID
+	v.vizierLister = lister
+	v.recorder = recorder
+
+	// If a default script was passed in execute it.
+	v.runScript(execScript, useEncryption)
+	return v, nil
+}
+
+// NewFromRecording creates a live view that renders previously recorded script results.
+// It does not need a connection to Pixie Cloud or Vizier.
+func NewFromRecording(rec *vizier.Recording) (*View, error) {
+	execScript, err := rec.ExecutableScript()
+	if err != nil {
+		return nil, err
+	}
+	v := newView(&appState{
+		execScript: execScript,
+	})
+	v.recording = rec
+	v.runScript(execScript, false)
+	return v, nil
+}
+
+func newView(s *appState) *View {
 	// App is the top level view. The layout is approximately as follows:
 	//  ------------------------------------------
 	//  | View Information ...                   |
@@ -182,19 +247,6 @@ func New(br *script.BundleManager, viziers []*vizier.Connector, cloudAddr string
 	app.SetRoot(layout, true).
 		EnableMouse(true)
 
-	var ac autocompleter
-	if useNewAC {
-		ac = newCloudAutocompleter(aClient)
-	} else {
-		ac = newFuzzyAutoCompleter(br)
-	}
-
-	lister, err := vizier.NewLister(cloudAddr)
-	if err != nil {
-		utils.WithError(err).Error("Failed to create Vizier lister")
-		return nil, err
-	}
-
 	v := &View{
 		app:           app,
 		pages:         pages,
@@ -203,16 +255,7 @@ func New(br *script.BundleManager, viziers []*vizier.Connector, cloudAddr string
 		logoBox:       logoBox,
 		searchBox:     searchBox,
 		bottomBar:     bottomBar,
-		s: &appState{
-			br:         br,
-			viziers:    viziers,
-			ac:         ac,
-			execScript: execScript,
-		},
-		useNewAC:          useNewAC,
-		cloudAddr:         cloudAddr,
-		selectedClusterID: clusterID,
-		vizierLister:      lister,
+		s:             s,
 	}
 
 	// Wire up components.
@@ -233,12 +276,10 @@ func New(br *script.BundleManager, viziers []*vizier.Connector, cloudAddr string
 
 	searchBox.SetChangedFunc(v.search)
 	searchBox.SetInputCapture(v.searchInputCapture)
-	// If a default script was passed in execute it.
-	v.runScript(execScript, useEncryption)
 
 	// Wire up the main keyboard handler.
 	app.SetInputCapture(v.keyHandler)
-	return v, nil
+	return v
 }
 
 // Run runs the view.
@@ -272,10 +313,19 @@ func (v *View) runScript(execScript *script.ExecutableScript, useEncryption bool
 		}
 	}
 
-	resp, err := vizier.RunScript(ctx, v.s.viziers, execScript, encOpts)
-	if err != nil {
-		v.execCompleteWithError(err)
-		return
+	var resp chan *vizier.ExecData
+	if v.recording != nil {
+		resp = v.recording.Stream(ctx)
+	} else {
+		resp, err = vizier.RunScript(ctx, v.s.viziers, execScript, encOpts)
+		if err != nil {
+			v.execCompleteWithError(err)
+			return
+		}
+		if v.recorder != nil {
+			resp = v.recorder.Tee(ctx, resp, decOpts)
+			v.recordedScript = execScript
+		}
 	}
 	tw := vizier.NewStreamOutputAdapter(ctx, resp, vizier.FormatInMemory, decOpts)
 	err = tw.Finish()
@@ -296,6 +346,8 @@ func (v *View) runScript(execScript *script.ExecutableScript, useEncryption bool
 		return
 	}
 
+	v.s.widgets = buildWidgets(execScript.Vis, v.s.tables)
+
 	// Reset sort state.
 	v.s.sortState = make([][]sortType, len(v.s.tables))
 	for i, t := range v.s.tables {
@@ -345,11 +397,11 @@ func (v *View) execCompleteViewUpdate() {
 	v.renderCurrentTable()
 }
 
-func (v *View) updateScriptInfoView() {
-	v.infoView.Clear()
-
-	// Get the name for this cluster for the live view
-	var clusterName *string
+// clusterName returns the name of the selected cluster, or nil if it can't be fetched.
+func (v *View) clusterName() *string {
+	if v.vizierLister == nil {
+		return nil
+	}
 	vzInfo, err := v.vizierLister.GetVizierInfo(v.selectedClusterID)
 	switch {
 	case err != nil:
@@ -357,7 +409,31 @@ func (v *View) updateScriptInfoView() {
 	case len(vzInfo) == 0:
 		utils.Errorf("Error getting cluster name for cluster %s, no results returned", v.selectedClusterID.String())
 	default:
-		clusterName = &(vzInfo[0].ClusterName)
+		return &(vzInfo[0].ClusterName)
+	}
+	return nil
+}
+
+// Recording returns the results of the last script executed in the view.
+// It fails if the view was not created with a recorder.
+func (v *View) Recording() (*vizier.Recording, error) {
+	if v.recorder == nil || v.recordedScript == nil {
+		return nil, vizier.ErrNothingRecorded
+	}
+	name := ""
+	if clusterName := v.clusterName(); clusterName != nil {
+		name = *clusterName
+	}
+	return v.recorder.Recording(v.recordedScript, name)
+}
+
+func (v *View) updateScriptInfoView() {
+	v.infoView.Clear()
+
+	// Get the name for this cluster for the live view
+	var clusterName *string
+	if v.recording == nil {
+		clusterName = v.clusterName()
 	}
 
 	fmt.Fprintf(v.infoView, "%s : %s", withAccent("Script"),
@@ -370,6 +446,17 @@ func (v *View) updateScriptInfoView() {
 	}
 
 	fmt.Fprintf(v.infoView, "\n")
+	if v.recording != nil {
+		fmt.Fprintf(v.infoView, "%s %s", withAccent("Recorded:"), v.recording.Header.CapturedAt.Local().Format(time.RFC1123))
+		if v.recording.Header.ClusterName != "" {
+			fmt.Fprintf(v.infoView, " on %s", v.recording.Header.ClusterName)
+		}
+		return
+	}
+	// Standalone Viziers are not listed in Pixie Cloud, so they have no live UI.
+	if v.vizierLister == nil {
+		return
+	}
 	if lvl := v.s.execScript.LiveViewLink(clusterName); lvl != "" {
 		fmt.Fprintf(v.infoView, "%s %s", withAccent("Live View:"), lvl)
 	}
@@ -381,20 +468,29 @@ func (v *View) renderCurrentTable() {
 		v.pages.RemovePage("table")
 	}
 
+	if len(v.s.widgets) > 0 {
+		v.renderDashboard()
+		return
+	}
+
 	if len(v.s.tables) < v.s.selectedTable {
 		return
 	}
-	table := v.s.tables[v.s.selectedTable]
-	formatter := v.s.tableFormatters[v.s.selectedTable]
-	v.tvTable = v.createTviewTable(table, formatter, v.s.sortState[v.s.selectedTable])
+	v.tvTable = v.createTviewTable(v.s.selectedTable)
 	v.pages.AddAndSwitchToPage("table", v.tvTable, true)
 	v.app.SetFocus(v.pages)
 }
 
 func (v *View) updateTableNav() {
 	v.tableSelector.Clear()
-	for idx, t := range v.s.tables {
-		fmt.Fprintf(v.tableSelector, `%d ["%d"]%s[""]  `, idx+1, idx, withAccent(t.Name()))
+	if len(v.s.widgets) > 0 {
+		for idx, w := range v.s.widgets {
+			fmt.Fprintf(v.tableSelector, `%d ["%d"]%s[""]  `, idx+1, idx, withAccent(w.name))
+		}
+	} else {
+		for idx, t := range v.s.tables {
+			fmt.Fprintf(v.tableSelector, `%d ["%d"]%s[""]  `, idx+1, idx, withAccent(t.Name()))
+		}
 	}
 	v.showTableNav()
 }
@@ -407,7 +503,11 @@ func (v *View) selectPrevTable() {
 	v.selectTableAndHighlight(v.s.selectedTable - 1)
 }
 
-func (v *View) createTviewTable(t components.TableView, formatter vizier.DataFormatter, sortState []sortType) *tview.Table {
+func (v *View) createTviewTable(tableIdx int) *tview.Table {
+	t := v.s.tables[tableIdx]
+	formatter := v.s.tableFormatters[tableIdx]
+	sortState := v.s.sortState[tableIdx]
+
 	table := tview.NewTable().
 		SetBorders(true).
 		SetSelectable(true, true).
@@ -493,8 +593,8 @@ func (v *View) createTviewTable(t components.TableView, formatter vizier.DataFor
 		//fmt.Printf("%+v  %+v\n", row, column)
 		// Switch the sort state.
 		if row == 0 {
-			cs := v.s.sortState[v.s.selectedTable][column]
-			v.s.sortState[v.s.selectedTable][column] = nextSort(cs)
+			cs := v.s.sortState[tableIdx][column]
+			v.s.sortState[tableIdx][column] = nextSort(cs)
 			v.renderCurrentTable()
 		}
 		// Store the selection so we can pop open the blob view on double click.
@@ -547,6 +647,10 @@ func (v *View) showDataModal(s string) {
 }
 
 func (v *View) showAutcompleteModal() {
+	// Other scripts can't be run against a recording.
+	if v.recording != nil {
+		return
+	}
 	v.closeModal()
 	var ac AutocompleteModal
 	if v.useNewAC {
@@ -620,10 +724,21 @@ func (v *View) selectTable(tableNum int) int {
 	if v.s.scriptViewOpen {
 		v.closeScriptView()
 	}
-	if len(v.s.tables) == 0 {
+	numViews := len(v.s.tables)
+	if len(v.s.widgets) > 0 {
+		numViews = len(v.s.widgets)
+	}
+	if numViews == 0 {
 		return 0
 	}
-	tableNum %= len(v.s.tables)
+	tableNum %= numViews
+
+	if len(v.s.widgets) > 0 {
+		// All widgets are already rendered on the dashboard, so only the highlight needs to move.
+		v.s.selectedTable = tableNum
+		v.highlightWidget()
+		return tableNum
+	}
 
 	// We only need to render if it's a different table.
 	if v.s.selectedTable != tableNum {
@@ -837,6 +952,10 @@ func (v *View) keyHandler(event *tcell.EventKey) *tcell.EventKey {
 			v.showSearchBox()
 			return nil
 		}
+		if string(r) == "t" {
+			v.toggleWidgetView()
+			return nil
+		}
 	case tcell.KeyCtrlS:
 		v.showSearchBox()
 		return nil
@@ -39,10 +39,17 @@ type AutocompleteModal interface {
 }
 
 var protoToKindLabelMap = map[cloudpb.AutocompleteEntityKind]string{
-	cloudpb.AEK_SVC:       "svc",
-	cloudpb.AEK_POD:       "pod",
-	cloudpb.AEK_SCRIPT:    "script",
-	cloudpb.AEK_NAMESPACE: "ns",
+	cloudpb.AEK_SVC:         "svc",
+	cloudpb.AEK_POD:         "pod",
+	cloudpb.AEK_SCRIPT:      "script",
+	cloudpb.AEK_NAMESPACE:   "ns",
+	cloudpb.AEK_CONTAINER:   "container",
+	cloudpb.AEK_DEPLOYMENT:  "deploy",
+	cloudpb.AEK_REPLICASET:  "rs",
+	cloudpb.AEK_STATEFULSET: "sts",
+	cloudpb.AEK_DAEMONSET:   "ds",
+	cloudpb.AEK_TABLE:       "table",
+	cloudpb.AEK_CRON_SCRIPT: "cron_script",
 }
 
 type suggestion struct {
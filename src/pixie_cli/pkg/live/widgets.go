@@ -0,0 +1,266 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package live
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+	"github.com/rivo/tview"
+
+	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+)
+
+const (
+	// visGridColumns is the number of columns in the vis spec's layout grid.
+	visGridColumns = 12
+	// visGridRowHeight is the number of lines used for each row of the vis spec's layout grid.
+	visGridRowHeight = 4
+	// defaultWidgetHeight is the height, in grid rows, of widgets without a position.
+	defaultWidgetHeight = 3
+)
+
+// widget is a single widget of the vis spec along with the table it displays.
+type widget struct {
+	name     string
+	tableIdx int
+	position vispb.Widget_Position
+	// chart is the display spec of the widget. It is nil if the widget is a table.
+	chart proto.Message
+	// showTable is set when a chart widget has been toggled to show the raw table.
+	showTable bool
+}
+
+func (w *widget) isChart() bool {
+	return w.chart != nil
+}
+
+// parseDisplaySpec returns the chart display spec of the widget, or nil if
+// the widget can't be rendered as a chart in the terminal.
+func parseDisplaySpec(spec *types.Any) proto.Message {
+	if spec == nil {
+		return nil
+	}
+	for _, chart := range []proto.Message{&vispb.TimeseriesChart{}, &vispb.BarChart{}} {
+		if !types.Is(spec, chart) {
+			continue
+		}
+		if err := types.UnmarshalAny(spec, chart); err != nil {
+			return nil
+		}
+		return chart
+	}
+	return nil
+}
+
+// findWidgetTable returns the index of the table with the output of the named widget.
+func findWidgetTable(tables []components.TableView, name string) int {
+	for idx, t := range tables {
+		if t.Name() == name {
+			return idx
+		}
+	}
+	// Funcs with multiple outputs have their output tables prefixed with the widget name.
+	for idx, t := range tables {
+		if strings.HasPrefix(t.Name(), name+":") {
+			return idx
+		}
+	}
+	return -1
+}
+
+// buildWidgets matches the widgets in the vis spec to the output tables. Tables which are not
+// displayed by any widget get a table widget of their own below the widgets in the spec.
+// Returns nil if the vis spec has no widgets for the tables.
+func buildWidgets(vis *vispb.Vis, tables []components.TableView) []*widget {
+	if vis == nil || len(vis.Widgets) == 0 {
+		return nil
+	}
+
+	var widgets []*widget
+	used := make(map[int]bool)
+	for _, w := range vis.Widgets {
+		name := w.Name
+		if ref, ok := w.FuncOrRef.(*vispb.Widget_GlobalFuncOutputName); ok {
+			name = ref.GlobalFuncOutputName
+		}
+		if name == "" {
+			continue
+		}
+		tableIdx := findWidgetTable(tables, name)
+		if tableIdx < 0 {
+			continue
+		}
+		used[tableIdx] = true
+
+		wgt := &widget{
+			name:     w.Name,
+			tableIdx: tableIdx,
+			chart:    parseDisplaySpec(w.DisplaySpec),
+		}
+		if wgt.name == "" {
+			wgt.name = name
+		}
+		if w.Position != nil {
+			wgt.position = *w.Position
+		}
+		widgets = append(widgets, wgt)
+	}
+	if len(widgets) == 0 {
+		return nil
+	}
+
+	// Place the remaining widgets at the bottom of the grid, one per row.
+	nextRow := int32(0)
+	for _, w := range widgets {
+		if w.position.W == 0 || w.position.H == 0 {
+			continue
+		}
+		if end := w.position.Y + w.position.H; end > nextRow {
+			nextRow = end
+		}
+	}
+	place := func(w *widget) {
+		w.position = vispb.Widget_Position{X: 0, Y: nextRow, W: visGridColumns, H: defaultWidgetHeight}
+		nextRow += defaultWidgetHeight
+	}
+	for _, w := range widgets {
+		if w.position.W == 0 || w.position.H == 0 {
+			place(w)
+		}
+	}
+	for idx, t := range tables {
+		if used[idx] {
+			continue
+		}
+		w := &widget{name: t.Name(), tableIdx: idx}
+		place(w)
+		widgets = append(widgets, w)
+	}
+	return widgets
+}
+
+// createWidgetView creates the primitive for the widget, which is either its chart or its table.
+func (v *View) createWidgetView(w *widget) (tview.Primitive, *tview.Box) {
+	table := v.s.tables[w.tableIdx]
+	formatter := v.s.tableFormatters[w.tableIdx]
+
+	if !w.isChart() || w.showTable {
+		t := v.createTviewTable(w.tableIdx)
+		return t, t.Box
+	}
+
+	switch spec := w.chart.(type) {
+	case *vispb.TimeseriesChart:
+		c := newTimeseriesChart(spec, table, formatter)
+		return c, c.Box
+	case *vispb.BarChart:
+		c := newBarChart(spec, table, formatter)
+		return c, c.Box
+	}
+	t := v.createTviewTable(w.tableIdx)
+	return t, t.Box
+}
+
+// renderDashboard lays out all the widgets on a grid following their positions in the vis spec.
+func (v *View) renderDashboard() {
+	grid := tview.NewGrid()
+
+	numRows := int32(0)
+	for _, w := range v.s.widgets {
+		if end := w.position.Y + w.position.H; end > numRows {
+			numRows = end
+		}
+	}
+	rows := make([]int, numRows)
+	for i := range rows {
+		rows[i] = visGridRowHeight
+	}
+	grid.SetRows(rows...)
+	columns := make([]int, visGridColumns)
+	grid.SetColumns(columns...)
+
+	v.widgetViews = make([]tview.Primitive, len(v.s.widgets))
+	v.widgetBoxes = make([]*tview.Box, len(v.s.widgets))
+	for idx, w := range v.s.widgets {
+		idx := idx
+		p, box := v.createWidgetView(w)
+		title := w.name
+		if w.isChart() && w.showTable {
+			title += " (table)"
+		}
+		box.SetBorder(true).SetTitle(" " + title + " ")
+		box.SetMouseCapture(v.widgetMouseCapture(idx, box.GetMouseCapture()))
+		v.widgetViews[idx] = p
+		v.widgetBoxes[idx] = box
+
+		pos := w.position
+		grid.AddItem(p, int(pos.Y), int(pos.X), int(pos.H), int(pos.W), 0, 0, false)
+	}
+
+	v.pages.AddAndSwitchToPage("table", grid, true)
+	v.highlightWidget()
+}
+
+// widgetMouseCapture selects the widget when it is clicked.
+func (v *View) widgetMouseCapture(idx int, next func(tview.MouseAction, *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse)) func(tview.MouseAction, *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+	return func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+		if action == tview.MouseLeftClick && v.s.selectedTable != idx {
+			v.selectTableAndHighlight(idx)
+		}
+		if next != nil {
+			return next(action, event)
+		}
+		return action, event
+	}
+}
+
+// highlightWidget marks the selected widget and focuses it, which scrolls it into view.
+func (v *View) highlightWidget() {
+	v.tvTable = nil
+	for idx, box := range v.widgetBoxes {
+		if idx != v.s.selectedTable {
+			box.SetBorderColor(tcell.ColorWhite).SetTitleColor(tcell.ColorWhite)
+			continue
+		}
+		box.SetBorderColor(tcell.GetColor(accentColor)).SetTitleColor(tcell.GetColor(accentColor))
+		if t, ok := v.widgetViews[idx].(*tview.Table); ok {
+			v.tvTable = t
+		}
+	}
+	if v.s.selectedTable < len(v.widgetViews) {
+		v.app.SetFocus(v.widgetViews[v.s.selectedTable])
+	}
+}
+
+// toggleWidgetView switches the selected widget between its chart and its raw table.
+func (v *View) toggleWidgetView() {
+	if v.s.selectedTable >= len(v.s.widgets) {
+		return
+	}
+	w := v.s.widgets[v.s.selectedTable]
+	if !w.isChart() {
+		return
+	}
+	w.showTable = !w.showTable
+	v.renderCurrentTable()
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package live
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+)
+
+type fakeTable struct {
+	name string
+}
+
+func (f *fakeTable) Name() string          { return f.name }
+func (f *fakeTable) Header() []string      { return nil }
+func (f *fakeTable) Data() [][]interface{} { return nil }
+
+func mustAny(t *testing.T, pb *vispb.TimeseriesChart) *types.Any {
+	a, err := types.MarshalAny(pb)
+	require.NoError(t, err)
+	return a
+}
+
+func TestBuildWidgets(t *testing.T) {
+	tables := []components.TableView{
+		&fakeTable{"latency"},
+		&fakeTable{"summary"},
+		&fakeTable{"extra"},
+	}
+	vis := &vispb.Vis{
+		Widgets: []*vispb.Widget{
+			{
+				Name:        "latency",
+				Position:    &vispb.Widget_Position{X: 0, Y: 0, W: 6, H: 3},
+				DisplaySpec: mustAny(t, &vispb.TimeseriesChart{Timeseries: []*vispb.TimeseriesChart_Timeseries{{Value: "p50"}}}),
+			},
+			{
+				Name:        "summary",
+				Position:    &vispb.Widget_Position{X: 6, Y: 0, W: 6, H: 4},
+				DisplaySpec: &types.Any{TypeUrl: "types.px.dev/px.vispb.Table"},
+			},
+			{
+				Name: "missing",
+			},
+		},
+	}
+
+	widgets := buildWidgets(vis, tables)
+	require.Len(t, widgets, 3)
+
+	assert.Equal(t, "latency", widgets[0].name)
+	assert.Equal(t, 0, widgets[0].tableIdx)
+	assert.True(t, widgets[0].isChart())
+
+	assert.Equal(t, "summary", widgets[1].name)
+	assert.False(t, widgets[1].isChart())
+
+	// Tables without a widget are placed below the spec's widgets.
+	assert.Equal(t, "extra", widgets[2].name)
+	assert.Equal(t, 2, widgets[2].tableIdx)
+	assert.Equal(t, vispb.Widget_Position{X: 0, Y: 4, W: visGridColumns, H: defaultWidgetHeight}, widgets[2].position)
+}
+
+func TestBuildWidgets_NoVis(t *testing.T) {
+	tables := []components.TableView{&fakeTable{"output"}}
+	assert.Nil(t, buildWidgets(nil, tables))
+	assert.Nil(t, buildWidgets(&vispb.Vis{}, tables))
+}
+
+func TestBrailleCanvas_Line(t *testing.T) {
+	c := newBrailleCanvas(2, 1)
+	c.line(0, 0, 3, 3, 0)
+	// A diagonal line sets one dot per row, two in each cell.
+	assert.Equal(t, brailleDots[0][0]|brailleDots[1][1], c.cells[0][0])
+	assert.Equal(t, brailleDots[2][0]|brailleDots[3][1], c.cells[0][1])
+}
+
+func TestDrawBar(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	require.NoError(t, screen.Init())
+	defer screen.Fini()
+	screen.SetSize(20, 1)
+
+	b := &bar{label: "a", segments: []*barSegment{{value: 3}, {value: 1}}}
+	// The bar is scaled so that the max total fills the whole width.
+	assert.Equal(t, 10, drawBar(screen, b, 0, 0, 10, 4, nil))
+	assert.Equal(t, 5, drawBar(screen, b, 0, 0, 10, 8, nil))
+	r, _, _, _ := screen.GetContent(9, 0)
+	assert.Equal(t, '█', r)
+}
+
+func TestDrawBar_NothingToDraw(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	require.NoError(t, screen.Init())
+	defer screen.Fini()
+
+	zero := &bar{label: "a", segments: []*barSegment{{value: 0}}}
+	// An all zero series has no scale to draw the bars with.
+	assert.Equal(t, 0, drawBar(screen, zero, 0, 0, 10, 0, nil))
+	assert.Equal(t, 0, drawBar(screen, zero, 0, 0, 0, 1, nil))
+	assert.Equal(t, 0, drawBar(screen, zero, 0, 0, -3, 1, nil))
+}
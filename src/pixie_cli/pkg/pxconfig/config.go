@@ -20,6 +20,7 @@ package pxconfig
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -34,6 +35,10 @@ import (
 type ConfigInfo struct {
 	// UniqueClientID is the ID assigned to this user on first startup when auth information is not know. This can be later associated with the UserID.
 	UniqueClientID string `json:"uniqueClientID"`
+	// CurrentContext is the name of the context that is used when no context is specified through the --context flag.
+	CurrentContext string `json:"currentContext,omitempty"`
+	// Contexts are the named contexts that the CLI can switch between.
+	Contexts []*Context `json:"contexts,omitempty"`
 }
 
 // TODO(zasgar): Reconcile with auth.
@@ -43,8 +48,9 @@ const (
 )
 
 var (
-	config *ConfigInfo
-	once   sync.Once
+	config     *ConfigInfo
+	configPath string
+	once       sync.Once
 )
 
 // ensureDefaultConfigFilePath returns and creates the file path is missing.
@@ -79,6 +85,7 @@ func writeDefaultConfig(path string) (*ConfigInfo, error) {
 	}
 
 	cfg := &ConfigInfo{UniqueClientID: clientID.String()}
+	addDefaultContext(cfg)
 	if err := json.NewEncoder(f).Encode(cfg); err != nil {
 		return nil, err
 	}
@@ -99,25 +106,59 @@ func readDefaultConfig(path string) (*ConfigInfo, error) {
 	return cfg, nil
 }
 
+func writeConfig(path string, cfg *ConfigInfo) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(cfg)
+}
+
+// loadConfig reads the config at path, creating it if it does not exist.
+func loadConfig(path string) (*ConfigInfo, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		// Write the default config.
+		cfg, err := writeDefaultConfig(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create default config: %w", err)
+		}
+		return cfg, nil
+	}
+
+	cfg, err := readDefaultConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Configs written by older versions of the CLI have no contexts. Migrate these by
+	// moving the existing credentials into a default context.
+	if len(cfg.Contexts) == 0 {
+		addDefaultContext(cfg)
+		if err := writeConfig(path, cfg); err != nil {
+			return nil, fmt.Errorf("failed to migrate config file: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
 // Cfg returns the default config.
 func Cfg() *ConfigInfo {
 	once.Do(func() {
-		configPath, err := ensureDefaultConfigFilePath()
+		var err error
+		configPath, err = ensureDefaultConfigFilePath()
 		if err != nil {
 			utils.WithError(err).Fatal("Failed to load/create config file path")
 		}
-		_, err = os.Stat(configPath)
-		if os.IsNotExist(err) {
-			// Write the default config.
-			if config, err = writeDefaultConfig(configPath); err != nil {
-				utils.WithError(err).Fatal("Failed to create default config")
-			}
-			return
-		}
-
-		if config, err = readDefaultConfig(configPath); err != nil {
-			utils.WithError(err).Fatal("Failed to read config file")
+		if config, err = loadConfig(configPath); err != nil {
+			utils.WithError(err).Fatal("Failed to load config")
 		}
 	})
 	return config
 }
+
+// Save persists the current state of the config to disk.
+func Save() error {
+	return writeConfig(configPath, Cfg())
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultContextName is the name of the context that is created for configs which predate contexts.
+	DefaultContextName = "default"
+	// defaultAuthFile is the credentials file used by CLI versions without contexts.
+	defaultAuthFile = "auth.json"
+)
+
+var contextNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ErrContextNotFound is returned when a context with the given name does not exist.
+var ErrContextNotFound = errors.New("context not found")
+
+// Context is a named set of settings used by the CLI, such as which Pixie Cloud to talk to and
+// which credentials to use.
+type Context struct {
+	// Name is the unique name of the context.
+	Name string `json:"name"`
+	// CloudAddr is the address of Pixie Cloud for this context. If empty, the default cloud address is used.
+	CloudAddr string `json:"cloudAddr,omitempty"`
+	// AuthFile is the name of the file in the Pixie config directory which holds the credentials for this context.
+	AuthFile string `json:"authFile"`
+	// Cluster is the ID of the cluster to use when a command is not given one explicitly.
+	Cluster string `json:"cluster,omitempty"`
+	// Namespace is the namespace to use when a command is not given one explicitly.
+	Namespace string `json:"namespace,omitempty"`
+	// Output is the output format to use when a command is not given one explicitly.
+	Output string `json:"output,omitempty"`
+}
+
+func addDefaultContext(cfg *ConfigInfo) {
+	cfg.Contexts = append(cfg.Contexts, &Context{
+		Name:     DefaultContextName,
+		AuthFile: defaultAuthFile,
+	})
+	if cfg.CurrentContext == "" {
+		cfg.CurrentContext = DefaultContextName
+	}
+}
+
+// GetContext returns the context with the given name.
+func (c *ConfigInfo) GetContext(name string) (*Context, error) {
+	for _, ctx := range c.Contexts {
+		if ctx.Name == name {
+			return ctx, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrContextNotFound, name)
+}
+
+// CurrentContext returns the context that should be used by the CLI. This is the context specified
+// through the --context flag, if any, and otherwise the current context in the config.
+func CurrentContext() (*Context, error) {
+	name := viper.GetString("context")
+	if name == "" {
+		name = Cfg().CurrentContext
+	}
+	return Cfg().GetContext(name)
+}
+
+// SetContext creates the context with the given name if it does not exist, and updates it with the given
+// function. The result is persisted to disk.
+func SetContext(name string, update func(*Context)) error {
+	if !contextNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid context name '%s': names may only contain alphanumeric characters, '_', '.' and '-'", name)
+	}
+
+	cfg := Cfg()
+	ctx, err := cfg.GetContext(name)
+	if err != nil {
+		ctx = &Context{
+			Name:     name,
+			AuthFile: fmt.Sprintf("auth_%s.json", name),
+		}
+		cfg.Contexts = append(cfg.Contexts, ctx)
+	}
+	update(ctx)
+	return Save()
+}
+
+// UseContext sets the current context to the context with the given name.
+func UseContext(name string) error {
+	cfg := Cfg()
+	if _, err := cfg.GetContext(name); err != nil {
+		return err
+	}
+	cfg.CurrentContext = name
+	return Save()
+}
+
+// DeleteContext removes the context with the given name, along with its credentials. The current
+// context cannot be deleted.
+func DeleteContext(name string) error {
+	cfg := Cfg()
+	if cfg.CurrentContext == name {
+		return errors.New("cannot delete the current context")
+	}
+	idx := -1
+	for i, ctx := range cfg.Contexts {
+		if ctx.Name == name {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return fmt.Errorf("%w: %s", ErrContextNotFound, name)
+	}
+
+	authFile := cfg.Contexts[idx].AuthFile
+	cfg.Contexts = append(cfg.Contexts[:idx], cfg.Contexts[idx+1:]...)
+	if err := Save(); err != nil {
+		return err
+	}
+	// Keep the credentials if another context still uses them.
+	if authFile == "" {
+		return nil
+	}
+	for _, ctx := range cfg.Contexts {
+		if ctx.AuthFile == authFile {
+			return nil
+		}
+	}
+	// The credentials are stored next to the config file.
+	err := os.Remove(filepath.Join(filepath.Dir(configPath), authFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete credentials for context '%s': %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestConfig loads the config from a temporary directory in place of ~/.pixie.
+func setupTestConfig(t *testing.T, dir string) {
+	// Make sure Cfg() doesn't load the real config.
+	once.Do(func() {})
+	path := filepath.Join(dir, pixieConfigFile)
+	cfg, err := loadConfig(path)
+	require.NoError(t, err)
+	config = cfg
+	configPath = path
+}
+
+func readTestConfig(t *testing.T, dir string) *ConfigInfo {
+	cfg, err := readDefaultConfig(filepath.Join(dir, pixieConfigFile))
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestCfg_NewConfig(t *testing.T) {
+	dir := t.TempDir()
+	setupTestConfig(t, dir)
+
+	assert.NotEmpty(t, Cfg().UniqueClientID)
+	assert.Equal(t, DefaultContextName, Cfg().CurrentContext)
+	require.Len(t, Cfg().Contexts, 1)
+	assert.Equal(t, &Context{Name: DefaultContextName, AuthFile: defaultAuthFile}, Cfg().Contexts[0])
+	assert.Equal(t, Cfg(), readTestConfig(t, dir))
+}
+
+func TestCfg_MigratesConfigWithoutContexts(t *testing.T) {
+	dir := t.TempDir()
+	// Configs written by CLI versions without contexts only hold the client ID.
+	oldCfg, err := json.Marshal(map[string]string{"uniqueClientID": "abcd"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, pixieConfigFile), oldCfg, 0600))
+
+	setupTestConfig(t, dir)
+
+	expected := &ConfigInfo{
+		UniqueClientID: "abcd",
+		CurrentContext: DefaultContextName,
+		Contexts:       []*Context{{Name: DefaultContextName, AuthFile: defaultAuthFile}},
+	}
+	assert.Equal(t, expected, Cfg())
+	// The migrated config is persisted.
+	assert.Equal(t, expected, readTestConfig(t, dir))
+}
+
+func TestSetContext(t *testing.T) {
+	dir := t.TempDir()
+	setupTestConfig(t, dir)
+
+	err := SetContext("prod", func(ctx *Context) {
+		ctx.CloudAddr = "withpixie.ai:443"
+	})
+	require.NoError(t, err)
+	err = SetContext("prod", func(ctx *Context) {
+		ctx.Cluster = "cluster-id"
+	})
+	require.NoError(t, err)
+
+	expected := &Context{
+		Name:      "prod",
+		CloudAddr: "withpixie.ai:443",
+		AuthFile:  "auth_prod.json",
+		Cluster:   "cluster-id",
+	}
+	ctx, err := Cfg().GetContext("prod")
+	require.NoError(t, err)
+	assert.Equal(t, expected, ctx)
+
+	ctx, err = readTestConfig(t, dir).GetContext("prod")
+	require.NoError(t, err)
+	assert.Equal(t, expected, ctx)
+	// Creating a context doesn't switch to it.
+	assert.Equal(t, DefaultContextName, Cfg().CurrentContext)
+}
+
+func TestSetContext_InvalidName(t *testing.T) {
+	setupTestConfig(t, t.TempDir())
+
+	err := SetContext("../prod", func(ctx *Context) {})
+	assert.Error(t, err)
+	assert.Len(t, Cfg().Contexts, 1)
+}
+
+func TestUseContext(t *testing.T) {
+	dir := t.TempDir()
+	setupTestConfig(t, dir)
+	require.NoError(t, SetContext("prod", func(ctx *Context) {}))
+
+	require.NoError(t, UseContext("prod"))
+	assert.Equal(t, "prod", readTestConfig(t, dir).CurrentContext)
+	ctx, err := CurrentContext()
+	require.NoError(t, err)
+	assert.Equal(t, "prod", ctx.Name)
+
+	// The --context flag takes precedence over the current context.
+	viper.Set("context", DefaultContextName)
+	defer viper.Set("context", "")
+	ctx, err = CurrentContext()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultContextName, ctx.Name)
+}
+
+func TestUseContext_NotFound(t *testing.T) {
+	setupTestConfig(t, t.TempDir())
+
+	err := UseContext("prod")
+	assert.ErrorIs(t, err, ErrContextNotFound)
+	assert.Equal(t, DefaultContextName, Cfg().CurrentContext)
+}
+
+func TestDeleteContext(t *testing.T) {
+	dir := t.TempDir()
+	setupTestConfig(t, dir)
+	require.NoError(t, SetContext("prod", func(ctx *Context) {}))
+	authPath := filepath.Join(dir, "auth_prod.json")
+	require.NoError(t, os.WriteFile(authPath, []byte("{}"), 0600))
+	defaultAuthPath := filepath.Join(dir, defaultAuthFile)
+	require.NoError(t, os.WriteFile(defaultAuthPath, []byte("{}"), 0600))
+
+	require.NoError(t, DeleteContext("prod"))
+
+	_, err := Cfg().GetContext("prod")
+	assert.ErrorIs(t, err, ErrContextNotFound)
+	_, err = readTestConfig(t, dir).GetContext("prod")
+	assert.ErrorIs(t, err, ErrContextNotFound)
+	_, err = os.Stat(authPath)
+	assert.True(t, os.IsNotExist(err))
+	// The credentials of other contexts are kept.
+	_, err = os.Stat(defaultAuthPath)
+	assert.NoError(t, err)
+}
+
+func TestDeleteContext_NotLoggedIn(t *testing.T) {
+	setupTestConfig(t, t.TempDir())
+	require.NoError(t, SetContext("prod", func(ctx *Context) {}))
+
+	// The context has no credentials file if the user never logged in with it.
+	assert.NoError(t, DeleteContext("prod"))
+}
+
+func TestDeleteContext_Errors(t *testing.T) {
+	setupTestConfig(t, t.TempDir())
+
+	assert.ErrorIs(t, DeleteContext("prod"), ErrContextNotFound)
+	assert.Error(t, DeleteContext(DefaultContextName))
+	assert.Len(t, Cfg().Contexts, 1)
+}
@@ -53,6 +53,8 @@ type ExecutableScript struct {
 	IsLocal bool
 	// Args contains a map from name to argument info.
 	Args map[string]Arg
+	// QueryFlags contains the query flags (eg. max_output_rows_per_table) to set when executing the script.
+	QueryFlags map[string]string
 }
 
 // LiveViewLink returns the fully qualified URL for the live view.
@@ -34,6 +34,10 @@ func ctxWithTokenCreds(ctx context.Context, token string) context.Context {
 	return metadata.AppendToOutgoingContext(ctx, "authorization", fmt.Sprintf("bearer %s", token))
 }
 
+func ctxWithAPIKeyCreds(ctx context.Context, apiKey string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "pixie-api-key", apiKey)
+}
+
 func newVizierClusterInfoClient(cloudAddr string) (cloudpb.VizierClusterInfoClient, error) {
 	isInternal := strings.ContainsAny(cloudAddr, "cluster.local")
 
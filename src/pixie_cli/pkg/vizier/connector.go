@@ -20,6 +20,8 @@ package vizier
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
@@ -32,6 +34,7 @@ import (
 	"github.com/gofrs/uuid"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
@@ -61,6 +64,12 @@ type Connector struct {
 	vzToken            string
 	passthroughEnabled bool
 	cloudAddr          string
+	// The address of a standalone Vizier that is connected to directly, instead of through Pixie Cloud.
+	directAddr string
+	// The API key used to authenticate with a standalone Vizier.
+	apiKey string
+	// The PEM encoded CA cert used to verify a standalone Vizier, if it is not signed by a trusted CA.
+	caCert []byte
 }
 
 // NewConnector returns a new connector.
@@ -83,6 +92,26 @@ func NewConnector(cloudAddr string, vzInfo *cloudpb.ClusterInfo, conn *Connectio
 	return c, nil
 }
 
+// NewDirectConnector returns a new connector to the standalone Vizier at directAddr. Requests
+// are authenticated with the API key and do not go through Pixie Cloud.
+func NewDirectConnector(directAddr string, clusterID uuid.UUID, apiKey string, caCert []byte) (*Connector, error) {
+	c := &Connector{
+		id:         clusterID,
+		directAddr: directAddr,
+		apiKey:     apiKey,
+		caCert:     caCert,
+	}
+
+	err := c.connect(directAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	c.vz = vizierpb.NewVizierServiceClient(c.conn)
+	c.vzDebug = vizierpb.NewVizierDebugServiceClient(c.conn)
+	return c, nil
+}
+
 // Connect connects to Vizier (blocking)
 func (c *Connector) connect(addr string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
@@ -101,6 +130,14 @@ func (c *Connector) connect(addr string) error {
 	if err != nil {
 		return err
 	}
+	if len(c.caCert) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(c.caCert) {
+			return errors.New("failed to parse CA cert")
+		}
+		// Replaces the transport credentials from the default dial options.
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: pool})))
+	}
 
 	dialOpts = append(dialOpts, grpc.WithBlock())
 	// Try to dial with a time out (ctrl-c can be used to cancel)
@@ -118,6 +155,30 @@ func (c *Connector) PassthroughMode() bool {
 	return c.passthroughEnabled
 }
 
+// DirectMode returns true if the connector talks to a standalone Vizier directly.
+func (c *Connector) DirectMode() bool {
+	return c.directAddr != ""
+}
+
+func (c *Connector) addr() string {
+	if c.DirectMode() {
+		return c.directAddr
+	}
+	return c.cloudAddr
+}
+
+// ctxWithCreds adds the credentials for Vizier requests to the context.
+func (c *Connector) ctxWithCreds(ctx context.Context) context.Context {
+	switch {
+	case c.DirectMode():
+		return ctxWithAPIKeyCreds(ctx, c.apiKey)
+	case c.passthroughEnabled:
+		return auth.CtxWithCreds(ctx)
+	default:
+		return ctxWithTokenCreds(ctx, c.vzToken)
+	}
+}
+
 func lookupVariable(variable string, computedArgs []script.Arg) (string, error) {
 	for _, arg := range computedArgs {
 		if arg.Name == variable {
@@ -209,7 +270,7 @@ func containsMutation(script *script.ExecutableScript) bool {
 }
 
 func (c *Connector) restartConnAndResumeExecute(ctx context.Context, queryID string) (vizierpb.VizierService_ExecuteScriptClient, error) {
-	err := c.connect(c.cloudAddr)
+	err := c.connect(c.addr())
 	if err != nil {
 		return nil, err
 	}
@@ -236,7 +297,7 @@ func checkForJWTExpired(s *status.Status) bool {
 }
 
 func (c *Connector) getNewVzConnInfo() error {
-	if c.passthroughEnabled {
+	if c.passthroughEnabled || c.DirectMode() {
 		return nil
 	}
 
@@ -329,16 +390,10 @@ func (c *Connector) ExecuteScriptStream(ctx context.Context, script *script.Exec
 		ExecFuncs:         execFuncs,
 		Mutation:          containsMutation(script),
 		EncryptionOptions: encOpts,
+		QueryFlags:        script.QueryFlags,
 	}
 
-	getAuthCtx := func(ctx context.Context) context.Context {
-		if c.passthroughEnabled {
-			return auth.CtxWithCreds(ctx)
-		}
-		return ctxWithTokenCreds(ctx, c.vzToken)
-	}
-
-	resp, err := c.vz.ExecuteScript(getAuthCtx(ctx), reqPB)
+	resp, err := c.vz.ExecuteScript(c.ctxWithCreds(ctx), reqPB)
 	if err != nil {
 		return nil, err
 	}
@@ -364,7 +419,7 @@ func (c *Connector) ExecuteScriptStream(ctx context.Context, script *script.Exec
 				}
 				return
 			}
-			s.resp, err = c.restartConnAndResumeExecute(getAuthCtx(ctx), s.queryID)
+			s.resp, err = c.restartConnAndResumeExecute(c.ctxWithCreds(ctx), s.queryID)
 			if err != nil {
 				continue
 			}
@@ -388,11 +443,7 @@ func (c *Connector) DebugLogRequest(ctx context.Context, podName string, prev bo
 		Previous:  prev,
 		Container: container,
 	}
-	if c.passthroughEnabled {
-		ctx = auth.CtxWithCreds(ctx)
-	} else {
-		ctx = ctxWithTokenCreds(ctx, c.vzToken)
-	}
+	ctx = c.ctxWithCreds(ctx)
 
 	resp, err := c.vzDebug.DebugLog(ctx, reqPB)
 	if err != nil {
@@ -445,11 +496,7 @@ func (c *Connector) DebugPodsRequest(ctx context.Context) (chan *DebugPodsRespon
 	reqPB := &vizierpb.DebugPodsRequest{
 		ClusterID: c.id.String(),
 	}
-	if c.passthroughEnabled {
-		ctx = auth.CtxWithCreds(ctx)
-	} else {
-		ctx = ctxWithTokenCreds(ctx, c.vzToken)
-	}
+	ctx = c.ctxWithCreds(ctx)
 
 	resp, err := c.vzDebug.DebugPods(ctx, reqPB)
 	if err != nil {
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/pixie_cli/pkg/script"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+type fakeVizierServer struct {
+	vizierpb.UnimplementedVizierServiceServer
+	md  metadata.MD
+	req *vizierpb.ExecuteScriptRequest
+}
+
+func (s *fakeVizierServer) ExecuteScript(req *vizierpb.ExecuteScriptRequest, srv vizierpb.VizierService_ExecuteScriptServer) error {
+	s.md, _ = metadata.FromIncomingContext(srv.Context())
+	s.req = req
+	return srv.Send(&vizierpb.ExecuteScriptResponse{QueryID: "abcd"})
+}
+
+func TestDirectConnector_ExecuteScriptStream(t *testing.T) {
+	viper.Set("disable_ssl", true)
+	defer viper.Set("disable_ssl", false)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	fakeVz := &fakeVizierServer{}
+	s := grpc.NewServer()
+	vizierpb.RegisterVizierServiceServer(s, fakeVz)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	clusterID := uuid.Must(uuid.NewV4())
+	c, err := vizier.NewDirectConnector(lis.Addr().String(), clusterID, "test-api-key", nil)
+	require.NoError(t, err)
+	assert.True(t, c.DirectMode())
+
+	results, err := c.ExecuteScriptStream(context.Background(), &script.ExecutableScript{ScriptString: "import px"}, nil)
+	require.NoError(t, err)
+	var queryIDs []string
+	for res := range results {
+		if res.Resp != nil {
+			queryIDs = append(queryIDs, res.Resp.QueryID)
+		}
+	}
+	assert.Equal(t, []string{"abcd"}, queryIDs)
+
+	assert.Equal(t, []string{"test-api-key"}, fakeVz.md.Get("pixie-api-key"))
+	assert.Empty(t, fakeVz.md.Get("authorization"))
+	assert.Equal(t, clusterID.String(), fakeVz.req.ClusterID)
+}
+
+func TestDirectConnector_BadCACert(t *testing.T) {
+	_, err := vizier.NewDirectConnector("127.0.0.1:0", uuid.Nil, "test-api-key", []byte("not a cert"))
+	assert.EqualError(t, err, "failed to parse CA cert")
+}
@@ -60,6 +60,28 @@ func (l *Lister) GetViziersInfo() ([]*cloudpb.ClusterInfo, error) {
 	return c.Clusters, nil
 }
 
+// GetViziersInfoBySelector returns information about the viziers whose labels match the given selector.
+func (l *Lister) GetViziersInfoBySelector(selector string) ([]*cloudpb.ClusterInfo, error) {
+	ctx := auth.CtxWithCreds(context.Background())
+
+	c, err := l.vc.GetClusterInfo(ctx, &cloudpb.GetClusterInfoRequest{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	return c.Clusters, nil
+}
+
+// GetViziersInfoByGroup returns information about the viziers in the given cluster group.
+func (l *Lister) GetViziersInfoByGroup(group string) ([]*cloudpb.ClusterInfo, error) {
+	ctx := auth.CtxWithCreds(context.Background())
+
+	c, err := l.vc.GetClusterInfo(ctx, &cloudpb.GetClusterInfoRequest{Group: group})
+	if err != nil {
+		return nil, err
+	}
+	return c.Clusters, nil
+}
+
 // GetVizierInfo returns information about a connected vizier.
 func (l *Lister) GetVizierInfo(id uuid.UUID) ([]*cloudpb.ClusterInfo, error) {
 	ctx := auth.CtxWithCreds(context.Background())
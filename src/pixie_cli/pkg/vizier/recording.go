@@ -0,0 +1,351 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+
+	apiutils "px.dev/pixie/src/api/go/pxapi/utils"
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/pixie_cli/pkg/script"
+)
+
+// RecordingVersion is the version of the recording format written by this CLI.
+const RecordingVersion = 1
+
+// maxRecordingFrameSize bounds the size of a single frame so that a corrupt file
+// can't cause us to allocate unbounded amounts of memory.
+const maxRecordingFrameSize = 256 * 1024 * 1024
+
+var recordingMagic = []byte("PXR\x00")
+
+var (
+	// ErrNotARecording is returned when the file being read is not a script recording.
+	ErrNotARecording = errors.New("file is not a px script recording")
+	// ErrUnsupportedRecordingVersion is returned when the recording was written by a newer CLI.
+	ErrUnsupportedRecordingVersion = errors.New("unsupported recording version, please upgrade px")
+	// ErrNothingRecorded is returned when no script execution was captured by a Recorder.
+	ErrNothingRecorded = errors.New("no script execution was recorded")
+)
+
+// RecordingHeader describes the script execution captured in a recording.
+type RecordingHeader struct {
+	Version     int               `json:"version"`
+	ScriptName  string            `json:"scriptName"`
+	Script      string            `json:"script"`
+	Vis         string            `json:"vis,omitempty"`
+	Args        map[string]string `json:"args,omitempty"`
+	QueryFlags  map[string]string `json:"queryFlags,omitempty"`
+	ClusterName string            `json:"clusterName,omitempty"`
+	CapturedAt  time.Time         `json:"capturedAt"`
+}
+
+// Recording is a captured script execution that can be replayed offline.
+// Row batches are always stored decrypted.
+type Recording struct {
+	Header    *RecordingHeader
+	Responses []*vizierpb.ExecuteScriptResponse
+}
+
+// ExecutableScript returns the script that produced the recording.
+func (r *Recording) ExecutableScript() (*script.ExecutableScript, error) {
+	es := &script.ExecutableScript{
+		ScriptName:   r.Header.ScriptName,
+		ScriptString: r.Header.Script,
+		Args:         make(map[string]script.Arg),
+		QueryFlags:   r.Header.QueryFlags,
+		// The script can't be opened in the Live UI since the data is from a point in time.
+		IsLocal: true,
+	}
+	for name, value := range r.Header.Args {
+		es.Args[name] = script.Arg{Name: name, Value: value}
+	}
+	if r.Header.Vis != "" {
+		vis, err := script.ParseVisSpec(r.Header.Vis)
+		if err != nil {
+			return nil, err
+		}
+		es.Vis = vis
+	}
+	return es, nil
+}
+
+// Stream replays the recorded responses in the same form as RunScript.
+func (r *Recording) Stream(ctx context.Context) chan *ExecData {
+	ch := make(chan *ExecData)
+	go func() {
+		defer close(ch)
+		for _, resp := range r.Responses {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- &ExecData{Resp: resp}:
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case ch <- &ExecData{Err: io.EOF}:
+		}
+	}()
+	return ch
+}
+
+// Write serializes the recording. The format is a magic string followed by a gzip
+// stream of length-prefixed frames: the JSON header, then each response proto.
+func (r *Recording) Write(w io.Writer) error {
+	if _, err := w.Write(recordingMagic); err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(w)
+	hdr, err := json.Marshal(r.Header)
+	if err != nil {
+		return err
+	}
+	if err := writeFrame(zw, hdr); err != nil {
+		return err
+	}
+	for _, resp := range r.Responses {
+		b, err := resp.Marshal()
+		if err != nil {
+			return err
+		}
+		if err := writeFrame(zw, b); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// WriteFile writes the recording to the file at path.
+func (r *Recording) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadRecording deserializes a recording written with Recording.Write.
+func ReadRecording(r io.Reader) (*Recording, error) {
+	magic := make([]byte, len(recordingMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, recordingMagic) {
+		return nil, ErrNotARecording
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	br := bufio.NewReader(zr)
+
+	hdrBytes, err := readFrame(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording header: %w", err)
+	}
+	hdr := &RecordingHeader{}
+	if err := json.Unmarshal(hdrBytes, hdr); err != nil {
+		return nil, fmt.Errorf("failed to parse recording header: %w", err)
+	}
+	if hdr.Version > RecordingVersion {
+		return nil, ErrUnsupportedRecordingVersion
+	}
+
+	rec := &Recording{Header: hdr}
+	for {
+		b, err := readFrame(br)
+		if err == io.EOF {
+			return rec, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recorded response: %w", err)
+		}
+		resp := &vizierpb.ExecuteScriptResponse{}
+		if err := resp.Unmarshal(b); err != nil {
+			return nil, fmt.Errorf("failed to parse recorded response: %w", err)
+		}
+		rec.Responses = append(rec.Responses, resp)
+	}
+}
+
+// ReadRecordingFile reads the recording stored at path.
+func ReadRecordingFile(path string) (*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadRecording(f)
+}
+
+func writeFrame(w io.Writer, b []byte) error {
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(b)))
+	if _, err := w.Write(lenBuf[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > maxRecordingFrameSize {
+		return nil, fmt.Errorf("frame of %d bytes exceeds max size", l)
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// Recorder captures the responses of a script execution so they can be saved as a Recording.
+type Recorder struct {
+	mu         sync.Mutex
+	responses  []*vizierpb.ExecuteScriptResponse
+	capturedAt time.Time
+	err        error
+}
+
+// NewRecorder creates a new Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Tee records every response on the stream while passing it through unchanged.
+// Any previously recorded responses are dropped, so only the last execution is kept
+// when a script is retried or a new script is run.
+func (r *Recorder) Tee(ctx context.Context, stream chan *ExecData, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions) chan *ExecData {
+	r.mu.Lock()
+	r.responses = nil
+	r.capturedAt = time.Now().UTC()
+	r.err = nil
+	r.mu.Unlock()
+
+	out := make(chan *ExecData)
+	go func() {
+		defer close(out)
+		for msg := range stream {
+			if msg != nil && msg.Resp != nil {
+				r.record(msg.Resp, decOpts)
+			}
+			select {
+			case <-ctx.Done():
+				// Keep draining the stream so that the producers sending on it can exit.
+				for range stream {
+				}
+				return
+			case out <- msg:
+			}
+		}
+	}()
+	return out
+}
+
+func (r *Recorder) record(resp *vizierpb.ExecuteScriptResponse, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+
+	// Copy the response, the output adapter decrypts batches in place.
+	b, err := resp.Marshal()
+	if err != nil {
+		r.err = err
+		return
+	}
+	c := &vizierpb.ExecuteScriptResponse{}
+	if err := c.Unmarshal(b); err != nil {
+		r.err = err
+		return
+	}
+
+	if d, ok := c.Result.(*vizierpb.ExecuteScriptResponse_Data); ok && d.Data != nil &&
+		d.Data.EncryptedBatch != nil && decOpts != nil {
+		batch, err := apiutils.DecodeRowBatch(decOpts, d.Data.EncryptedBatch)
+		if err != nil {
+			r.err = err
+			return
+		}
+		d.Data.Batch = batch
+		d.Data.EncryptedBatch = nil
+	}
+	r.responses = append(r.responses, c)
+}
+
+// Recording returns the captured execution of execScript as a Recording.
+func (r *Recorder) Recording(execScript *script.ExecutableScript, clusterName string) (*Recording, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.capturedAt.IsZero() {
+		return nil, ErrNothingRecorded
+	}
+
+	hdr := &RecordingHeader{
+		Version:     RecordingVersion,
+		ScriptName:  execScript.ScriptName,
+		Script:      execScript.ScriptString,
+		Args:        make(map[string]string),
+		QueryFlags:  execScript.QueryFlags,
+		ClusterName: clusterName,
+		CapturedAt:  r.capturedAt,
+	}
+	for name, arg := range execScript.Args {
+		hdr.Args[name] = arg.Value
+	}
+	if execScript.Vis != nil {
+		m := jsonpb.Marshaler{}
+		vis, err := m.MarshalToString(execScript.Vis)
+		if err != nil {
+			return nil, err
+		}
+		hdr.Vis = vis
+	}
+
+	responses := make([]*vizierpb.ExecuteScriptResponse, len(r.responses))
+	copy(responses, r.responses)
+	return &Recording{Header: hdr, Responses: responses}, nil
+}
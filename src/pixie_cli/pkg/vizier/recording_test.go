@@ -0,0 +1,199 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vizier_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/pixie_cli/pkg/script"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+func recordedResponses() []*vizierpb.ExecuteScriptResponse {
+	return []*vizierpb.ExecuteScriptResponse{
+		{
+			QueryID: "abcd",
+			Result: &vizierpb.ExecuteScriptResponse_MetaData{
+				MetaData: &vizierpb.QueryMetadata{
+					Name: "output",
+					ID:   "1",
+					Relation: &vizierpb.Relation{
+						Columns: []*vizierpb.Relation_ColumnInfo{
+							{ColumnName: "svc", ColumnType: vizierpb.STRING},
+							{ColumnName: "count", ColumnType: vizierpb.INT64},
+						},
+					},
+				},
+			},
+		},
+		{
+			QueryID: "abcd",
+			Result: &vizierpb.ExecuteScriptResponse_Data{
+				Data: &vizierpb.QueryData{
+					Batch: &vizierpb.RowBatchData{
+						TableID: "1",
+						Cols: []*vizierpb.Column{
+							{ColData: &vizierpb.Column_StringData{StringData: &vizierpb.StringColumn{Data: []string{"a", "b"}}}},
+							{ColData: &vizierpb.Column_Int64Data{Int64Data: &vizierpb.Int64Column{Data: []int64{1, 2}}}},
+						},
+						NumRows: 2,
+						Eow:     true,
+						Eos:     true,
+					},
+				},
+			},
+		},
+		{
+			QueryID: "abcd",
+			Result: &vizierpb.ExecuteScriptResponse_Data{
+				Data: &vizierpb.QueryData{
+					ExecutionStats: &vizierpb.QueryExecutionStats{RecordsProcessed: 2},
+				},
+			},
+		},
+	}
+}
+
+func TestRecording_RoundTrip(t *testing.T) {
+	capturedAt := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	rec := &vizier.Recording{
+		Header: &vizier.RecordingHeader{
+			Version:     vizier.RecordingVersion,
+			ScriptName:  "px/service_stats",
+			Script:      "import px",
+			Args:        map[string]string{"start_time": "-5m"},
+			QueryFlags:  map[string]string{"max_output_rows_per_table": "100"},
+			ClusterName: "dev-cluster",
+			CapturedAt:  capturedAt,
+		},
+		Responses: recordedResponses(),
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, rec.Write(&buf))
+
+	got, err := vizier.ReadRecording(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, rec.Header, got.Header)
+	require.Len(t, got.Responses, len(rec.Responses))
+	for i := range rec.Responses {
+		assert.Equal(t, rec.Responses[i], got.Responses[i])
+	}
+
+	es, err := got.ExecutableScript()
+	require.NoError(t, err)
+	assert.Equal(t, "px/service_stats", es.ScriptName)
+	assert.Equal(t, "import px", es.ScriptString)
+	assert.Equal(t, "-5m", es.Args["start_time"].Value)
+	assert.Equal(t, "100", es.QueryFlags["max_output_rows_per_table"])
+}
+
+func TestRecording_Replay(t *testing.T) {
+	rec := &vizier.Recording{
+		Header:    &vizier.RecordingHeader{Version: vizier.RecordingVersion},
+		Responses: recordedResponses(),
+	}
+
+	ctx := context.Background()
+	tw := vizier.NewStreamOutputAdapter(ctx, rec.Stream(ctx), vizier.FormatInMemory, nil)
+	require.NoError(t, tw.Finish())
+
+	views, err := tw.Views()
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	assert.Equal(t, "output", views[0].Name())
+	assert.Equal(t, []string{"svc", "count"}, views[0].Header())
+	assert.Len(t, views[0].Data(), 2)
+
+	stats, err := tw.ExecStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.RecordsProcessed)
+}
+
+func TestReadRecording_NotARecording(t *testing.T) {
+	_, err := vizier.ReadRecording(bytes.NewBufferString("table,output\n"))
+	assert.Equal(t, vizier.ErrNotARecording, err)
+}
+
+func TestRecorder_Tee(t *testing.T) {
+	recorder := vizier.NewRecorder()
+	_, err := recorder.Recording(&script.ExecutableScript{ScriptName: "px/test"}, "")
+	assert.Equal(t, vizier.ErrNothingRecorded, err)
+
+	stream := make(chan *vizier.ExecData)
+	go func() {
+		defer close(stream)
+		for _, resp := range recordedResponses() {
+			stream <- &vizier.ExecData{Resp: resp}
+		}
+	}()
+
+	before := time.Now()
+	out := recorder.Tee(context.Background(), stream, nil)
+	after := time.Now()
+	// Results are only read later, the capture time must be the time of the execution.
+	time.Sleep(10 * time.Millisecond)
+	numMsgs := 0
+	for range out {
+		numMsgs++
+	}
+	assert.Equal(t, 3, numMsgs)
+
+	rec, err := recorder.Recording(&script.ExecutableScript{ScriptName: "px/test"}, "cluster")
+	require.NoError(t, err)
+	assert.Equal(t, "px/test", rec.Header.ScriptName)
+	assert.Len(t, rec.Responses, 3)
+	assert.False(t, rec.Header.CapturedAt.Before(before))
+	assert.False(t, rec.Header.CapturedAt.After(after))
+}
+
+func TestRecorder_TeeCancelled(t *testing.T) {
+	recorder := vizier.NewRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stream := make(chan *vizier.ExecData)
+	producerDone := make(chan struct{})
+	go func() {
+		defer close(producerDone)
+		defer close(stream)
+		for _, resp := range recordedResponses() {
+			stream <- &vizier.ExecData{Resp: resp}
+		}
+	}()
+
+	out := recorder.Tee(ctx, stream, nil)
+	<-out
+	cancel()
+
+	// The producer must not be blocked after the reader goes away.
+	select {
+	case <-producerDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("producer blocked sending on the recorded stream")
+	}
+	for range out {
+	}
+}
@@ -59,14 +59,16 @@ func (t *taskWrapper) Run() error {
 }
 
 // RunScriptAndOutputResults runs the specified script on vizier and outputs based on format string.
-func RunScriptAndOutputResults(ctx context.Context, conns []*Connector, execScript *script.ExecutableScript, format string, useEncryption bool) error {
+// If recorder is not nil, the responses of the execution are captured in it.
+func RunScriptAndOutputResults(ctx context.Context, conns []*Connector, execScript *script.ExecutableScript, format string,
+	useEncryption bool, recorder *Recorder) error {
 	// Check for the presence of df.stream() in the query.
 	if strings.Contains(execScript.ScriptString, "stream()") && format != "json" {
 		return fmt.Errorf("Cannot execute a query containing df.stream() using px run with table output. " +
 			"Please try using `px live` instead or setting output format to json (`-o json`).")
 	}
 
-	tw, err := runScript(ctx, conns, execScript, format, useEncryption)
+	tw, err := runScript(ctx, conns, execScript, format, useEncryption, recorder)
 	if err == nil { // Script ran successfully.
 		err = tw.Finish()
 		if err != nil {
@@ -134,7 +136,7 @@ func RunScriptAndOutputResults(ctx context.Context, conns []*Connector, execScri
 
 		tries := 5
 		for tries > 0 {
-			tw, err = runScript(ctx, conns, execScript, format, useEncryption)
+			tw, err = runScript(ctx, conns, execScript, format, useEncryption, recorder)
 			if err == nil {
 				schemaCh <- true
 				break
@@ -175,7 +177,8 @@ func RunScriptAndOutputResults(ctx context.Context, conns []*Connector, execScri
 	return err
 }
 
-func runScript(ctx context.Context, conns []*Connector, execScript *script.ExecutableScript, format string, useEncryption bool) (*StreamOutputAdapter, error) {
+func runScript(ctx context.Context, conns []*Connector, execScript *script.ExecutableScript, format string,
+	useEncryption bool, recorder *Recorder) (*StreamOutputAdapter, error) {
 	var encOpts, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions
 	var err error
 	if useEncryption {
@@ -189,6 +192,9 @@ func runScript(ctx context.Context, conns []*Connector, execScript *script.Execu
 	if err != nil {
 		return nil, err
 	}
+	if recorder != nil {
+		resp = recorder.Tee(ctx, resp, decOpts)
+	}
 
 	tw := NewStreamOutputAdapter(ctx, resp, format, decOpts)
 	err = tw.WaitForCompletion()
@@ -23,8 +23,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/gofrs/uuid"
+	"github.com/spf13/viper"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -35,20 +38,54 @@ import (
 	"px.dev/pixie/src/utils/shared/k8s"
 )
 
-// FindVizierNamespace looks for the namespace that the vizier is running in for the current context.
-func FindVizierNamespace(clientset *kubernetes.Clientset) (string, error) {
+// FindVizierNamespaces returns the namespaces of all Vizier instances running in the current context.
+func FindVizierNamespaces(clientset *kubernetes.Clientset) ([]string, error) {
 	vzPods, err := clientset.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{
 		LabelSelector: "component=vizier",
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	nsSet := make(map[string]bool)
+	namespaces := make([]string, 0)
+	for _, p := range vzPods.Items {
+		if !nsSet[p.Namespace] {
+			nsSet[p.Namespace] = true
+			namespaces = append(namespaces, p.Namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// FindVizierNamespace looks for the namespace that the vizier is running in for the current context.
+// If --vizier_namespace is specified, that instance is used, and an error is returned if it does not exist.
+// Otherwise, if several Vizier instances are running in the cluster, the first one is selected.
+func FindVizierNamespace(clientset *kubernetes.Clientset) (string, error) {
+	namespaces, err := FindVizierNamespaces(clientset)
 	if err != nil {
 		return "", err
 	}
 
-	if len(vzPods.Items) == 0 {
+	if selected := viper.GetString("vizier_namespace"); selected != "" {
+		for _, ns := range namespaces {
+			if ns == selected {
+				return ns, nil
+			}
+		}
+		return "", fmt.Errorf("no Vizier instance found in namespace '%s'", selected)
+	}
+
+	if len(namespaces) == 0 {
 		return "", nil
 	}
+	if len(namespaces) > 1 {
+		cliUtils.Infof("Found Vizier instances in namespaces: %s. Using '%s', specify --vizier_namespace to select another.",
+			strings.Join(namespaces, ", "), namespaces[0])
+	}
 
-	return vzPods.Items[0].Namespace, nil
+	return namespaces[0], nil
 }
 
 // FindOperatorNamespace finds the namespace running the vizier-operator.
@@ -185,7 +222,28 @@ func FirstHealthyVizier(cloudAddr string) (uuid.UUID, error) {
 	if len(vzInfo) == 0 {
 		return uuid.Nil, errors.New("no Viziers available")
 	}
+	return firstHealthyVizier(vzInfo)
+}
 
+// FirstHealthyVizierBySelector returns the cluster ID of the first healthy vizier whose labels match the selector.
+func FirstHealthyVizierBySelector(cloudAddr string, selector string) (uuid.UUID, error) {
+	vzInfo, err := getVizierListBySelector(cloudAddr, selector)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return firstHealthyVizier(vzInfo)
+}
+
+// FirstHealthyVizierByGroup returns the cluster ID of the first healthy vizier in the cluster group.
+func FirstHealthyVizierByGroup(cloudAddr string, group string) (uuid.UUID, error) {
+	vzInfo, err := getVizierListByGroup(cloudAddr, group)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return firstHealthyVizier(vzInfo)
+}
+
+func firstHealthyVizier(vzInfo []*cloudpb.ClusterInfo) (uuid.UUID, error) {
 	// Find the first healthy vizier by default.
 	for _, vz := range vzInfo {
 		if vz.Status == cloudpb.CS_HEALTHY {
@@ -331,6 +389,108 @@ func ConnectToAllViziers(cloudAddr string) ([]*Connector, error) {
 	return conns, nil
 }
 
+func getVizierListBySelector(cloudAddr string, selector string) ([]*cloudpb.ClusterInfo, error) {
+	l, err := NewLister(cloudAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	vzInfo, err := l.GetViziersInfoBySelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vzInfo) == 0 {
+		return nil, fmt.Errorf("no Viziers match selector %q", selector)
+	}
+	return vzInfo, nil
+}
+
+func getVizierListByGroup(cloudAddr string, group string) ([]*cloudpb.ClusterInfo, error) {
+	l, err := NewLister(cloudAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	vzInfo, err := l.GetViziersInfoByGroup(group)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vzInfo) == 0 {
+		return nil, fmt.Errorf("no Viziers in group %q", group)
+	}
+	return vzInfo, nil
+}
+
+// connectToAvailableViziers connects to the healthy and degraded viziers in vzInfos.
+func connectToAvailableViziers(cloudAddr string, vzInfos []*cloudpb.ClusterInfo) ([]*Connector, error) {
+	var conns []*Connector
+	for _, vzInfo := range vzInfos {
+		if vzInfo.Status != cloudpb.CS_HEALTHY && vzInfo.Status != cloudpb.CS_DEGRADED {
+			continue
+		}
+		c, err := createVizierConnection(cloudAddr, vzInfo)
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, c)
+	}
+	return conns, nil
+}
+
+// ConnectToViziersBySelector connects to all available viziers whose labels match the selector.
+func ConnectToViziersBySelector(cloudAddr string, selector string) ([]*Connector, error) {
+	vzInfos, err := getVizierListBySelector(cloudAddr, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	conns, err := connectToAvailableViziers(cloudAddr, vzInfos)
+	if err != nil {
+		return nil, err
+	}
+	if len(conns) == 0 {
+		return nil, fmt.Errorf("no healthy Viziers match selector %q", selector)
+	}
+	return conns, nil
+}
+
+// MustConnectViziersBySelector connects to all available viziers whose labels match the selector.
+func MustConnectViziersBySelector(cloudAddr string, selector string) []*Connector {
+	c, err := ConnectToViziersBySelector(cloudAddr, selector)
+	if err != nil {
+		cliUtils.WithError(err).Fatal("Failed to connect to vizier")
+	}
+	return c
+}
+
+// ConnectToViziersByGroup connects to all available viziers in the cluster group.
+func ConnectToViziersByGroup(cloudAddr string, group string) ([]*Connector, error) {
+	vzInfos, err := getVizierListByGroup(cloudAddr, group)
+	if err != nil {
+		return nil, err
+	}
+
+	conns, err := connectToAvailableViziers(cloudAddr, vzInfos)
+	if err != nil {
+		return nil, err
+	}
+	if len(conns) == 0 {
+		return nil, fmt.Errorf("no healthy Viziers in group %q", group)
+	}
+	return conns, nil
+}
+
+// MustConnectViziersByGroup connects to all available viziers in the cluster group.
+func MustConnectViziersByGroup(cloudAddr string, group string) []*Connector {
+	c, err := ConnectToViziersByGroup(cloudAddr, group)
+	if err != nil {
+		cliUtils.WithError(err).Fatal("Failed to connect to vizier")
+	}
+	return c
+}
+
 // GetClusterIDFromKubeConfig returns the clusterID given the kubeconfig. If anything fails, then will return a nil UUID.
 func GetClusterIDFromKubeConfig(config *rest.Config) uuid.UUID {
 	if config == nil {
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package bridgeutils has the message handling which is shared by both ends of the bridge between
+// Vizier and the cloud: compression, flow control and prioritization.
+package bridgeutils
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+
+	"px.dev/pixie/src/shared/cvmsgspb"
+)
+
+const (
+	// Messages smaller than this are sent uncompressed, since compressing them saves very little.
+	minCompressedSize = 1024
+	// The largest message which may be decompressed. This guards against corrupt or malicious messages.
+	maxDecompressedSize = 64 << 20
+)
+
+// SupportedCompression is the list of compression algorithms which can be used on the bridge, in order of preference.
+var SupportedCompression = []cvmsgspb.BridgeCompression{
+	cvmsgspb.BRIDGE_COMPRESSION_ZSTD,
+	cvmsgspb.BRIDGE_COMPRESSION_SNAPPY,
+}
+
+var (
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+func init() {
+	var err error
+	zstdEncoder, err = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	if err != nil {
+		panic(err)
+	}
+	zstdDecoder, err = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecompressedSize))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// NegotiateCompression returns the most preferred compression algorithm which the peer supports. If there is none,
+// messages are sent uncompressed.
+func NegotiateCompression(peerSupported []cvmsgspb.BridgeCompression) cvmsgspb.BridgeCompression {
+	for _, c := range SupportedCompression {
+		for _, p := range peerSupported {
+			if c == p {
+				return c
+			}
+		}
+	}
+	return cvmsgspb.BRIDGE_COMPRESSION_NONE
+}
+
+// CompressMsg compresses the value of msg with the given algorithm. Small messages, and messages which don't get
+// any smaller, are returned unchanged. It returns the message to send and the algorithm its value is compressed with.
+func CompressMsg(msg *types.Any, compression cvmsgspb.BridgeCompression) (*types.Any, cvmsgspb.BridgeCompression) {
+	if msg == nil || len(msg.Value) < minCompressedSize {
+		return msg, cvmsgspb.BRIDGE_COMPRESSION_NONE
+	}
+
+	var compressed []byte
+	switch compression {
+	case cvmsgspb.BRIDGE_COMPRESSION_ZSTD:
+		compressed = zstdEncoder.EncodeAll(msg.Value, nil)
+	case cvmsgspb.BRIDGE_COMPRESSION_SNAPPY:
+		compressed = snappy.Encode(nil, msg.Value)
+	default:
+		return msg, cvmsgspb.BRIDGE_COMPRESSION_NONE
+	}
+	if len(compressed) >= len(msg.Value) {
+		return msg, cvmsgspb.BRIDGE_COMPRESSION_NONE
+	}
+	return &types.Any{
+		TypeUrl: msg.TypeUrl,
+		Value:   compressed,
+	}, compression
+}
+
+// DecompressMsg returns msg with its value decompressed, given the algorithm it was compressed with.
+func DecompressMsg(msg *types.Any, compression cvmsgspb.BridgeCompression) (*types.Any, error) {
+	if msg == nil || compression == cvmsgspb.BRIDGE_COMPRESSION_NONE {
+		return msg, nil
+	}
+
+	var value []byte
+	var err error
+	switch compression {
+	case cvmsgspb.BRIDGE_COMPRESSION_ZSTD:
+		value, err = zstdDecoder.DecodeAll(msg.Value, nil)
+	case cvmsgspb.BRIDGE_COMPRESSION_SNAPPY:
+		var n int
+		n, err = snappy.DecodedLen(msg.Value)
+		if err == nil && n > maxDecompressedSize {
+			err = fmt.Errorf("decompressed size %d exceeds the limit of %d bytes", n, maxDecompressedSize)
+		}
+		if err == nil {
+			value, err = snappy.Decode(nil, msg.Value)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", compression.String())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s message: %w", compression.String(), err)
+	}
+	return &types.Any{
+		TypeUrl: msg.TypeUrl,
+		Value:   value,
+	}, nil
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bridgeutils_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/shared/bridgeutils"
+	"px.dev/pixie/src/shared/cvmsgspb"
+)
+
+func TestNegotiateCompression(t *testing.T) {
+	tests := []struct {
+		name     string
+		peer     []cvmsgspb.BridgeCompression
+		expected cvmsgspb.BridgeCompression
+	}{
+		{
+			name:     "legacy peer",
+			peer:     nil,
+			expected: cvmsgspb.BRIDGE_COMPRESSION_NONE,
+		},
+		{
+			name:     "snappy only",
+			peer:     []cvmsgspb.BridgeCompression{cvmsgspb.BRIDGE_COMPRESSION_SNAPPY},
+			expected: cvmsgspb.BRIDGE_COMPRESSION_SNAPPY,
+		},
+		{
+			name: "prefers zstd",
+			peer: []cvmsgspb.BridgeCompression{
+				cvmsgspb.BRIDGE_COMPRESSION_SNAPPY,
+				cvmsgspb.BRIDGE_COMPRESSION_ZSTD,
+			},
+			expected: cvmsgspb.BRIDGE_COMPRESSION_ZSTD,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, bridgeutils.NegotiateCompression(test.peer))
+		})
+	}
+}
+
+func TestCompressMsg_RoundTrip(t *testing.T) {
+	large := &types.Any{
+		TypeUrl: "type.googleapis.com/px.cvmsgspb.V2CMessage",
+		Value:   []byte(strings.Repeat("metadata update ", 1000)),
+	}
+
+	for _, compression := range bridgeutils.SupportedCompression {
+		t.Run(compression.String(), func(t *testing.T) {
+			compressed, used := bridgeutils.CompressMsg(large, compression)
+			assert.Equal(t, compression, used)
+			assert.Equal(t, large.TypeUrl, compressed.TypeUrl)
+			assert.Less(t, len(compressed.Value), len(large.Value))
+
+			decompressed, err := bridgeutils.DecompressMsg(compressed, used)
+			require.NoError(t, err)
+			assert.Equal(t, large, decompressed)
+		})
+	}
+}
+
+func TestCompressMsg_SmallMessage(t *testing.T) {
+	small := &types.Any{
+		TypeUrl: "type.googleapis.com/px.cvmsgspb.VizierHeartbeat",
+		Value:   []byte("heartbeat"),
+	}
+
+	compressed, used := bridgeutils.CompressMsg(small, cvmsgspb.BRIDGE_COMPRESSION_ZSTD)
+	assert.Equal(t, cvmsgspb.BRIDGE_COMPRESSION_NONE, used)
+	assert.Equal(t, small, compressed)
+
+	decompressed, err := bridgeutils.DecompressMsg(compressed, used)
+	require.NoError(t, err)
+	assert.Equal(t, small, decompressed)
+}
+
+func TestDecompressMsg_Corrupt(t *testing.T) {
+	corrupt := &types.Any{
+		TypeUrl: "type.googleapis.com/px.cvmsgspb.V2CMessage",
+		Value:   []byte("not compressed at all"),
+	}
+
+	for _, compression := range bridgeutils.SupportedCompression {
+		t.Run(compression.String(), func(t *testing.T) {
+			_, err := bridgeutils.DecompressMsg(corrupt, compression)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bridgeutils
+
+import (
+	"sync"
+
+	"px.dev/pixie/src/shared/cvmsgs"
+)
+
+// controlTopics are the topics of the messages which keep the bridge itself running. They are sent ahead of
+// other messages, and don't count against flow control so that a full window can't hold them up.
+var controlTopics = map[string]bool{
+	"register":                  true,
+	"registerAck":               true,
+	"heartbeat":                 true,
+	cvmsgs.C2VOutboxAckChannel:  true,
+	cvmsgs.BridgeCreditsChannel: true,
+}
+
+// IsControlTopic returns whether messages on the topic are bridge control messages.
+func IsControlTopic(topic string) bool {
+	return controlTopics[topic]
+}
+
+// SendWindow tracks the number of messages which the peer has allowed us to send. It is safe for concurrent use.
+type SendWindow struct {
+	mu sync.Mutex
+	// Whether flow control is enabled. If it isn't, the window never runs out.
+	enabled bool
+	credits int64
+	grantCh chan struct{}
+}
+
+// NewSendWindow creates a send window with the given number of credits. If credits is zero, flow control is
+// disabled.
+func NewSendWindow(credits int64) *SendWindow {
+	w := &SendWindow{
+		grantCh: make(chan struct{}, 1),
+	}
+	w.Reset(credits)
+	return w
+}
+
+// Reset sets the number of credits in the window, for example when a new stream is started. If credits is zero,
+// flow control is disabled.
+func (w *SendWindow) Reset(credits int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.enabled = credits > 0
+	w.credits = credits
+	w.notify()
+}
+
+// Available returns whether a message may be sent.
+func (w *SendWindow) Available() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.enabled || w.credits > 0
+}
+
+// Consume uses up the credit for a message which is being sent.
+func (w *SendWindow) Consume() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.enabled {
+		w.credits--
+	}
+}
+
+// Grant adds credits which were granted by the peer.
+func (w *SendWindow) Grant(credits int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.credits += credits
+	w.notify()
+}
+
+// Granted returns a channel which is signalled when credits are added to the window.
+func (w *SendWindow) Granted() <-chan struct{} {
+	return w.grantCh
+}
+
+func (w *SendWindow) notify() {
+	select {
+	case w.grantCh <- struct{}{}:
+	default:
+	}
+}
+
+// ReceiveWindow tracks the messages which have been processed since credits were last granted to the peer.
+// It is not safe for concurrent use.
+type ReceiveWindow struct {
+	size      int64
+	processed int64
+}
+
+// NewReceiveWindow creates a receive window which allows the peer to send size messages before it has to wait
+// for more credits. If size is zero, flow control is disabled.
+func NewReceiveWindow(size int64) *ReceiveWindow {
+	return &ReceiveWindow{size: size}
+}
+
+// Processed records that a message has been processed. It returns the number of credits which should be granted
+// to the peer, or zero if it's not yet time to grant them. Credits are granted in batches of half the window so
+// that the peer can keep sending while the grant is in flight.
+func (w *ReceiveWindow) Processed() int64 {
+	if w.size <= 0 {
+		return 0
+	}
+	w.processed++
+	if w.processed < (w.size+1)/2 {
+		return 0
+	}
+	credits := w.processed
+	w.processed = 0
+	return credits
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bridgeutils_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"px.dev/pixie/src/shared/bridgeutils"
+	"px.dev/pixie/src/shared/cvmsgs"
+)
+
+func TestIsControlTopic(t *testing.T) {
+	assert.True(t, bridgeutils.IsControlTopic("heartbeat"))
+	assert.True(t, bridgeutils.IsControlTopic(cvmsgs.BridgeCreditsChannel))
+	assert.True(t, bridgeutils.IsControlTopic(cvmsgs.C2VOutboxAckChannel))
+	assert.False(t, bridgeutils.IsControlTopic("DurableMetadataUpdates"))
+	assert.False(t, bridgeutils.IsControlTopic("reply-1234"))
+}
+
+func TestSendWindow(t *testing.T) {
+	w := bridgeutils.NewSendWindow(2)
+	<-w.Granted()
+
+	assert.True(t, w.Available())
+	w.Consume()
+	assert.True(t, w.Available())
+	w.Consume()
+	assert.False(t, w.Available())
+
+	select {
+	case <-w.Granted():
+		t.Fatal("Window should not have been granted credits")
+	default:
+	}
+
+	w.Grant(1)
+	<-w.Granted()
+	assert.True(t, w.Available())
+	w.Consume()
+	assert.False(t, w.Available())
+}
+
+func TestSendWindow_Disabled(t *testing.T) {
+	w := bridgeutils.NewSendWindow(0)
+	for i := 0; i < 10; i++ {
+		assert.True(t, w.Available())
+		w.Consume()
+	}
+
+	w.Reset(1)
+	w.Consume()
+	assert.False(t, w.Available())
+}
+
+func TestReceiveWindow(t *testing.T) {
+	w := bridgeutils.NewReceiveWindow(4)
+	assert.Equal(t, int64(0), w.Processed())
+	assert.Equal(t, int64(2), w.Processed())
+	assert.Equal(t, int64(0), w.Processed())
+	assert.Equal(t, int64(2), w.Processed())
+
+	disabled := bridgeutils.NewReceiveWindow(0)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, int64(0), disabled.Processed())
+	}
+}
@@ -31,4 +31,8 @@ var (
 	CronScriptUpdatesChannel = "CronScriptsUpdates"
 	// CronScriptUpdatesResponseChannel is the NATS channel that script updates are published to.
 	CronScriptUpdatesResponseChannel = "CronScriptsUpdatesResponse"
+	// C2VOutboxAckChannel is the bridge topic that the vizier acknowledges the messages it receives from its outbox on.
+	C2VOutboxAckChannel = "c2vOutboxAck"
+	// BridgeCreditsChannel is the bridge topic that either side grants its peer credits to send more messages on.
+	BridgeCreditsChannel = "bridgeCredits"
 )
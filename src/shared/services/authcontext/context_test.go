@@ -68,7 +68,7 @@ func TestSessionCtx_ValidClaims(t *testing.T) {
 		{
 			name:          "api user claims",
 			isValid:       true,
-			claims:        utils.GenerateJWTForAPIUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", time.Now().Add(time.Minute*60), "withpixie.ai"),
+			claims:        utils.GenerateJWTForAPIUser("6ba7b810-9dad-11d1-80b4-00c04fd430c9", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", time.Now().Add(time.Minute*60), "withpixie.ai", nil),
 			expiryFromNow: time.Minute * 60,
 		},
 		{
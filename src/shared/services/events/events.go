@@ -34,6 +34,8 @@ const (
 	VizierCreated = "Vizier Created"
 	// VizierStatusChange is an event for when a Vizier cluster's status changes.
 	VizierStatusChange = "Vizier Status Change"
+	// VizierRolloutHalted is an event for when an auto-update rollout is halted because a wave did not become healthy.
+	VizierRolloutHalted = "Vizier Rollout Halted"
 	// APIRequest is an event for when a request is made to the Pixie API using an API token.
 	APIRequest = "API Request"
 )
@@ -48,7 +48,7 @@ func init() {
 // GRPCServerOptions are configuration options that are passed to the GRPC server.
 type GRPCServerOptions struct {
 	DisableAuth    map[string]bool
-	AuthMiddleware func(context.Context, env.Env) (string, error) // Currently only used by cloud api-server.
+	AuthMiddleware func(context.Context, env.Env) (string, error) // Used by cloud api-server and standalone query brokers.
 	GRPCServerOpts []grpc.ServerOption
 }
 
@@ -87,6 +87,14 @@ func createGRPCAuthFunc(env env.Env, opts *GRPCServerOptions) func(context.Conte
 		if opts.AuthMiddleware != nil {
 			token, err = opts.AuthMiddleware(ctx, env)
 			if err != nil {
+				// Pass through errors where the middleware already picked a status, such as the
+				// Unauthenticated error for a bad API key from a standalone query broker. These were
+				// previously all returned as Internal. Plain errors, which is all the cloud api-server's
+				// GetAugmentedTokenGRPC returns today, are still returned as Internal, but any status error
+				// it returns in the future now reaches clients with its own code.
+				if _, ok := status.FromError(err); ok {
+					return nil, err
+				}
 				return nil, status.Errorf(codes.Internal, "Auth middleware failed: %v", err)
 			}
 		} else {
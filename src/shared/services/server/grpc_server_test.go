@@ -20,6 +20,7 @@ package server_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"testing"
@@ -238,6 +239,17 @@ func TestGrpcServerUnary(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:         "authmiddleware unauthenticated",
+			token:        "",
+			expectError:  true,
+			clientStream: false,
+			serverOpts: &server.GRPCServerOptions{
+				AuthMiddleware: func(context.Context, env.Env) (string, error) {
+					return "", status.Error(codes.Unauthenticated, "invalid API key")
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -279,3 +291,17 @@ func TestGrpcServerUnary(t *testing.T) {
 		})
 	}
 }
+
+func TestGrpcServer_AuthMiddlewarePlainError(t *testing.T) {
+	lis, cleanup := startTestGRPCServer(&server.GRPCServerOptions{
+		AuthMiddleware: func(context.Context, env.Env) (string, error) {
+			return "", errors.New("failed to fetch token - unauthenticated")
+		},
+	})
+	defer cleanup(t)
+
+	resp, err := makeTestRequest(context.Background(), t, lis)
+	require.Error(t, err)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
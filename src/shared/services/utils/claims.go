@@ -52,8 +52,8 @@ func GetClaimsType(c *jwtpb.JWTClaims) ClaimType {
 	}
 }
 
-// GenerateJWTForUser creates a protobuf claims for the given user.
-func GenerateJWTForUser(userID string, orgID string, email string, expiresAt time.Time, audience string) *jwtpb.JWTClaims {
+// GenerateJWTForUser creates a protobuf claims for the given user. The user's roles, if any, are added to the scopes.
+func GenerateJWTForUser(userID string, orgID string, email string, expiresAt time.Time, audience string, roles *UserRoles) *jwtpb.JWTClaims {
 	claims := jwtpb.JWTClaims{
 		Subject: userID,
 		// Standard claims.
@@ -61,7 +61,7 @@ func GenerateJWTForUser(userID string, orgID string, email string, expiresAt tim
 		ExpiresAt: expiresAt.Unix(),
 		IssuedAt:  time.Now().Unix(),
 		Issuer:    "PL",
-		Scopes:    []string{"user"},
+		Scopes:    append([]string{"user"}, RoleScopes(roles)...),
 	}
 	claims.CustomClaims = &jwtpb.JWTClaims_UserClaims{
 		UserClaims: &jwtpb.UserJWTClaims{
@@ -73,8 +73,8 @@ func GenerateJWTForUser(userID string, orgID string, email string, expiresAt tim
 	return &claims
 }
 
-// GenerateJWTForAPIUser creates a protobuf claims for the api user.
-func GenerateJWTForAPIUser(userID string, orgID string, expiresAt time.Time, audience string) *jwtpb.JWTClaims {
+// GenerateJWTForAPIUser creates a protobuf claims for the api user. The roles, if any, are added to the scopes.
+func GenerateJWTForAPIUser(userID string, orgID string, expiresAt time.Time, audience string, roles *UserRoles) *jwtpb.JWTClaims {
 	claims := jwtpb.JWTClaims{
 		Subject: orgID,
 		// Standard claims.
@@ -82,7 +82,7 @@ func GenerateJWTForAPIUser(userID string, orgID string, expiresAt time.Time, aud
 		ExpiresAt: expiresAt.Unix(),
 		IssuedAt:  time.Now().Unix(),
 		Issuer:    "PL",
-		Scopes:    []string{"user"},
+		Scopes:    append([]string{"user"}, RoleScopes(roles)...),
 	}
 	claims.CustomClaims = &jwtpb.JWTClaims_UserClaims{
 		UserClaims: &jwtpb.UserJWTClaims{
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"px.dev/pixie/src/shared/services/jwtpb"
+)
+
+// OrgRole is the role a user holds within their org. Roles are ordered, each
+// role is granted all of the permissions of the roles below it.
+type OrgRole string
+
+const (
+	// NoRole is used when a user has no role, for example when a cluster grant does not exist.
+	NoRole OrgRole = ""
+	// ViewerRole can only view the org's clusters and data.
+	ViewerRole OrgRole = "viewer"
+	// MemberRole can use and deploy clusters, but cannot manage the org.
+	MemberRole OrgRole = "member"
+	// AdminRole can manage the org's users, clusters and settings.
+	AdminRole OrgRole = "admin"
+	// OwnerRole has full control over the org, including managing admins.
+	OwnerRole OrgRole = "owner"
+)
+
+const (
+	orgRoleScopePrefix     = "role:"
+	clusterRoleScopePrefix = "cluster_role:"
+)
+
+var orgRoleRank = map[OrgRole]int{
+	NoRole:     0,
+	ViewerRole: 1,
+	MemberRole: 2,
+	AdminRole:  3,
+	OwnerRole:  4,
+}
+
+// ParseOrgRole parses the string representation of a role.
+func ParseOrgRole(s string) (OrgRole, error) {
+	r := OrgRole(strings.ToLower(s))
+	if _, ok := orgRoleRank[r]; !ok || r == NoRole {
+		return NoRole, fmt.Errorf("invalid org role '%s'", s)
+	}
+	return r, nil
+}
+
+// AtLeast returns true if the role grants at least the permissions of the given role.
+func (r OrgRole) AtLeast(other OrgRole) bool {
+	return orgRoleRank[r] >= orgRoleRank[other]
+}
+
+// UserRoles are the roles a user holds in their org.
+type UserRoles struct {
+	// OrgRole is the role that applies to all of the org's resources.
+	OrgRole OrgRole
+	// ClusterRoles are per-cluster grants, keyed by cluster ID. A grant can only
+	// raise the user's role on that cluster above their org role.
+	ClusterRoles map[string]OrgRole
+}
+
+// RoleScopes returns the JWT scopes that carry the given roles.
+func RoleScopes(roles *UserRoles) []string {
+	if roles == nil {
+		return nil
+	}
+	var scopes []string
+	if roles.OrgRole != NoRole {
+		scopes = append(scopes, orgRoleScopePrefix+string(roles.OrgRole))
+	}
+	for clusterID, role := range roles.ClusterRoles {
+		if role == NoRole {
+			continue
+		}
+		scopes = append(scopes, fmt.Sprintf("%s%s:%s", clusterRoleScopePrefix, clusterID, role))
+	}
+	return scopes
+}
+
+// SetRoleScopes replaces any role scopes in the claims with the given roles.
+func SetRoleScopes(claims *jwtpb.JWTClaims, roles *UserRoles) {
+	scopes := make([]string, 0, len(claims.Scopes))
+	for _, s := range claims.Scopes {
+		if strings.HasPrefix(s, orgRoleScopePrefix) || strings.HasPrefix(s, clusterRoleScopePrefix) {
+			continue
+		}
+		scopes = append(scopes, s)
+	}
+	claims.Scopes = append(scopes, RoleScopes(roles)...)
+}
+
+// GetOrgRole returns the org role carried in the user claims. Tokens that were
+// issued without a role are treated as belonging to a member.
+func GetOrgRole(claims *jwtpb.JWTClaims) OrgRole {
+	if GetClaimsType(claims) != UserClaimType {
+		return NoRole
+	}
+	for _, s := range claims.Scopes {
+		if !strings.HasPrefix(s, orgRoleScopePrefix) {
+			continue
+		}
+		if r, err := ParseOrgRole(strings.TrimPrefix(s, orgRoleScopePrefix)); err == nil {
+			return r
+		}
+	}
+	return MemberRole
+}
+
+// GetClusterRole returns the effective role of the user on the given cluster,
+// which is the higher of their org role and any grant for the cluster.
+func GetClusterRole(claims *jwtpb.JWTClaims, clusterID string) OrgRole {
+	role := GetOrgRole(claims)
+	if role == NoRole {
+		return NoRole
+	}
+	prefix := clusterRoleScopePrefix + clusterID + ":"
+	for _, s := range claims.Scopes {
+		if !strings.HasPrefix(s, prefix) {
+			continue
+		}
+		if r, err := ParseOrgRole(strings.TrimPrefix(s, prefix)); err == nil && r.AtLeast(role) {
+			role = r
+		}
+	}
+	return role
+}
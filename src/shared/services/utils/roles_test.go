@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/shared/services/utils"
+)
+
+func TestParseOrgRole(t *testing.T) {
+	r, err := utils.ParseOrgRole("Admin")
+	require.NoError(t, err)
+	assert.Equal(t, utils.AdminRole, r)
+
+	_, err = utils.ParseOrgRole("superuser")
+	assert.Error(t, err)
+	_, err = utils.ParseOrgRole("")
+	assert.Error(t, err)
+}
+
+func TestOrgRole_AtLeast(t *testing.T) {
+	assert.True(t, utils.OwnerRole.AtLeast(utils.AdminRole))
+	assert.True(t, utils.AdminRole.AtLeast(utils.AdminRole))
+	assert.False(t, utils.MemberRole.AtLeast(utils.AdminRole))
+	assert.False(t, utils.ViewerRole.AtLeast(utils.MemberRole))
+	assert.False(t, utils.NoRole.AtLeast(utils.ViewerRole))
+}
+
+func TestGetOrgRole(t *testing.T) {
+	claims := utils.GenerateJWTForUser("user", "org", "test@test.com", time.Now(), "pixie", &utils.UserRoles{
+		OrgRole: utils.ViewerRole,
+	})
+	assert.Equal(t, utils.ViewerRole, utils.GetOrgRole(claims))
+
+	// Tokens without a role are treated as members.
+	claims = utils.GenerateJWTForUser("user", "org", "test@test.com", time.Now(), "pixie", nil)
+	assert.Equal(t, utils.MemberRole, utils.GetOrgRole(claims))
+
+	// Service claims have no role.
+	assert.Equal(t, utils.NoRole, utils.GetOrgRole(utils.GenerateJWTForService("svc", "pixie")))
+}
+
+func TestGetClusterRole(t *testing.T) {
+	claims := utils.GenerateJWTForUser("user", "org", "test@test.com", time.Now(), "pixie", &utils.UserRoles{
+		OrgRole: utils.MemberRole,
+		ClusterRoles: map[string]utils.OrgRole{
+			"cluster1": utils.AdminRole,
+			"cluster2": utils.ViewerRole,
+		},
+	})
+	assert.Equal(t, utils.AdminRole, utils.GetClusterRole(claims, "cluster1"))
+	// Grants never lower the org role.
+	assert.Equal(t, utils.MemberRole, utils.GetClusterRole(claims, "cluster2"))
+	assert.Equal(t, utils.MemberRole, utils.GetClusterRole(claims, "cluster3"))
+}
+
+func TestSetRoleScopes(t *testing.T) {
+	claims := utils.GenerateJWTForUser("user", "org", "test@test.com", time.Now(), "pixie", &utils.UserRoles{
+		OrgRole:      utils.AdminRole,
+		ClusterRoles: map[string]utils.OrgRole{"cluster1": utils.OwnerRole},
+	})
+	utils.SetRoleScopes(claims, &utils.UserRoles{OrgRole: utils.ViewerRole})
+	assert.ElementsMatch(t, []string{"user", "role:viewer"}, claims.Scopes)
+	assert.Equal(t, utils.ViewerRole, utils.GetClusterRole(claims, "cluster1"))
+}
@@ -54,7 +54,8 @@ DEFINE_bool(
 DEFINE_int64(
     stirling_check_proc_for_conn_close, true,
     "If enabled, Stirling will check Linux /proc on idle connections to see if they are closed.");
-DEFINE_int64(stirling_untracked_upid_threshold_seconds, 0,
+DEFINE_int64(stirling_untracked_upid_threshold_seconds,
+             gflags::Int64FromEnv("PL_STIRLING_UNTRACKED_UPID_THRESHOLD_S", 0),
              "If non-zero, Stirling will disable data tracking of processes that are outside the "
              "list of PIDs tracked by the context after the specified time period.");
 
@@ -33,6 +33,7 @@ import { useSnackbar } from 'app/components';
 import { SCRATCH_SCRIPT, ScriptsContextProvider } from 'app/containers/App/scripts-context';
 import AdminView from 'app/pages/admin/admin';
 import { ConfigureDataExportView } from 'app/pages/configure-data-export/configure-data-export';
+import { DeviceView } from 'app/pages/device/device';
 import LiveView from 'app/pages/live/live';
 import { SetupRedirect, SetupView } from 'app/pages/setup/setup';
 import {
@@ -334,6 +335,7 @@ export default function PixieWithContext(): React.ReactElement {
               <Route path='/embed/live' component={LiveWithProvider} />
               <Route path={scriptPaths} component={ScriptShortcut} />
               <Route path='/setup' component={SetupRedirect} />
+              <Route path='/device' component={DeviceView} />
               {enablePluginRoutes && <Route path='/configure-data-export' component={ConfigureDataExportView} />}
               <Redirect exact from='/' to='/live' />
               <Route path='/*' component={RouteNotFound} />
@@ -19,7 +19,8 @@
 import { StatusGroup } from 'app/components';
 import { GQLAutocompleteEntityKind } from 'app/types/schema';
 
-export type EntityType = 'AEK_UNKNOWN' | 'AEK_POD' | 'AEK_SVC' | 'AEK_SCRIPT' | 'AEK_NAMESPACE' | 'AEK_NODE';
+export type EntityType = 'AEK_UNKNOWN' | 'AEK_POD' | 'AEK_SVC' | 'AEK_SCRIPT' | 'AEK_NAMESPACE' | 'AEK_NODE' |
+'AEK_CONTAINER' | 'AEK_DEPLOYMENT' | 'AEK_REPLICASET' | 'AEK_STATEFULSET' | 'AEK_DAEMONSET' | 'AEK_TABLE' | 'AEK_CRON_SCRIPT';
 
 // Converts a vixpb.PXType to an entityType that is accepted by autocomplete.
 export function pxTypeToEntityType(pxType: string): GQLAutocompleteEntityKind {
@@ -32,6 +33,8 @@ export function pxTypeToEntityType(pxType: string): GQLAutocompleteEntityKind {
       return GQLAutocompleteEntityKind.AEK_NAMESPACE;
     case 'PX_NODE':
       return GQLAutocompleteEntityKind.AEK_NODE;
+    case 'PX_CONTAINER':
+      return GQLAutocompleteEntityKind.AEK_CONTAINER;
     default:
       return GQLAutocompleteEntityKind.AEK_UNKNOWN;
   }
@@ -49,6 +52,20 @@ export function entityTypeToString(entityType: GQLAutocompleteEntityKind): strin
       return 'ns';
     case GQLAutocompleteEntityKind.AEK_NODE:
       return 'node';
+    case GQLAutocompleteEntityKind.AEK_CONTAINER:
+      return 'container';
+    case GQLAutocompleteEntityKind.AEK_DEPLOYMENT:
+      return 'deploy';
+    case GQLAutocompleteEntityKind.AEK_REPLICASET:
+      return 'rs';
+    case GQLAutocompleteEntityKind.AEK_STATEFULSET:
+      return 'sts';
+    case GQLAutocompleteEntityKind.AEK_DAEMONSET:
+      return 'ds';
+    case GQLAutocompleteEntityKind.AEK_TABLE:
+      return 'table';
+    case GQLAutocompleteEntityKind.AEK_CRON_SCRIPT:
+      return 'cron_script';
     default:
       return '';
   }
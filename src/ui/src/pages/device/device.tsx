@@ -0,0 +1,222 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+import * as React from 'react';
+
+import { gql, useMutation } from '@apollo/client';
+import { Button, TextField, Paper } from '@mui/material';
+import { Theme } from '@mui/material/styles';
+import { createStyles, makeStyles } from '@mui/styles';
+import * as QueryString from 'query-string';
+import { useLocation } from 'react-router';
+
+import { Footer, scrollbarStyles } from 'app/components';
+import NavBars from 'app/containers/App/nav-bars';
+import { SidebarContext } from 'app/context/sidebar-context';
+import { MutationToApproveDeviceCodeArgs } from 'app/types/schema';
+import { Copyright } from 'configurable/copyright';
+
+const useStyles = makeStyles((theme: Theme) => createStyles({
+  root: {
+    width: '100%',
+    height: '100%',
+    display: 'flex',
+    flexDirection: 'column',
+    ...scrollbarStyles(theme),
+  },
+  title: {
+    flexGrow: 1,
+    marginLeft: theme.spacing(2),
+    height: '100%',
+  },
+  titleText: {
+    ...theme.typography.h6,
+    color: theme.palette.foreground.grey5,
+    fontWeight: theme.typography.fontWeightBold,
+    display: 'flex',
+    alignItems: 'center',
+    height: '100%',
+  },
+  main: {
+    marginLeft: theme.spacing(8),
+    flex: 1,
+    minHeight: 0,
+    padding: theme.spacing(1),
+    display: 'flex',
+    flexFlow: 'column nowrap',
+    overflow: 'auto',
+  },
+  mainBlock: {
+    flex: '1 0 auto',
+    display: 'flex',
+    justifyContent: 'center',
+    alignItems: 'center',
+  },
+  mainFooter: {
+    flex: '0 0 auto',
+  },
+  paper: {
+    padding: theme.spacing(6),
+    paddingTop: theme.spacing(4),
+    borderRadius: theme.shape.borderRadius,
+    maxWidth: theme.breakpoints.values.sm,
+
+    '& h1': {
+      ...theme.typography.h1,
+      fontSize: theme.typography.h2.fontSize,
+    },
+    '& p:not(.MuiFormHelperText-root)': {
+      ...theme.typography.body1,
+      color: theme.palette.foreground.one,
+      fontSize: theme.typography.h3.fontSize,
+      marginTop: theme.spacing(3),
+      marginBottom: theme.spacing(3),
+      lineHeight: theme.spacing(4),
+    },
+  },
+  inputContainer: {
+    paddingTop: theme.spacing(3),
+    display: 'flex',
+    justifyContent: 'center',
+  },
+  buttons: {
+    display: 'flex',
+    justifyContent: 'center',
+    gap: theme.spacing(2),
+    paddingTop: theme.spacing(2),
+  },
+}), { name: 'DeviceView' });
+
+const DevicePage = React.memo(({ children }) => {
+  const classes = useStyles();
+  return (
+    <div className={classes.root}>
+      <SidebarContext.Provider value={{ showLiveOptions: false, showAdmin: false }}>
+        <NavBars>
+          <div className={classes.title}>
+            <div className={classes.titleText}>Device Login</div>
+          </div>
+        </NavBars>
+      </SidebarContext.Provider>
+      <div className={classes.main}>
+        <div className={classes.mainBlock}>
+          {children}
+        </div>
+        <div className={classes.mainFooter}>
+          <Footer copyright={Copyright} />
+        </div>
+      </div>
+    </div>
+  );
+});
+DevicePage.displayName = 'DevicePage';
+
+function useUserCodeParam(): string {
+  const { search } = useLocation();
+  const { user_code: userCodeParam } = QueryString.parse(search);
+  const code = Array.isArray(userCodeParam) ? userCodeParam[0] : userCodeParam;
+  return code || '';
+}
+
+/**
+ * Lets the logged in user approve or deny the login request of a device, such as `px auth login --device`.
+ * The device shows the user code, and a link to this page with the code filled in.
+ */
+export const DeviceView = React.memo(() => {
+  const classes = useStyles();
+
+  const [userCode, setUserCode] = React.useState(useUserCodeParam());
+  const [error, setError] = React.useState('');
+  const [result, setResult] = React.useState('');
+  const onInputChange = React.useCallback((event) => {
+    setError('');
+    setUserCode(event.target.value);
+  }, []);
+
+  const [approveDeviceCode, { loading }] = useMutation<{ ApproveDeviceCode: string }, MutationToApproveDeviceCodeArgs>(
+    gql`
+      mutation ApproveDeviceCode($userCode: String!, $approved: Boolean!) {
+        ApproveDeviceCode(userCode: $userCode, approved: $approved)
+      }
+    `,
+  );
+
+  const respond = React.useCallback((approved: boolean) => {
+    if (!userCode.trim().length) return;
+    approveDeviceCode({
+      variables: { userCode: userCode.trim(), approved },
+    }).then(({ data }) => {
+      const clientName = data?.ApproveDeviceCode || 'The device';
+      setResult(approved
+        ? `${clientName} is now logged in. You can close this page.`
+        : `The login request from ${clientName} was denied.`);
+    }).catch((e) => {
+      setError(e.message);
+    });
+  }, [approveDeviceCode, userCode]);
+  const approve = React.useCallback(() => respond(true), [respond]);
+  const deny = React.useCallback(() => respond(false), [respond]);
+
+  const onSubmit = React.useCallback((event: React.FormEvent) => {
+    approve();
+    // So that using Enter to submit the form doesn't force reload the page.
+    event.preventDefault();
+    return false;
+  }, [approve]);
+
+  return (
+    <DevicePage>
+      <Paper elevation={1} className={classes.paper}>
+        <h1>Log In A Device</h1>
+        {result ? (
+          <p>{result}</p>
+        ) : (
+          <form onSubmit={onSubmit}>
+            <p>
+              Enter the code shown by the device. Only approve requests which you started yourself.
+            </p>
+            <div className={classes.inputContainer}>
+              <TextField
+                variant='outlined'
+                error={!!error}
+                label='Code'
+                helperText={error}
+                value={userCode}
+                onChange={onInputChange}
+              />
+            </div>
+            <div className={classes.buttons}>
+              <Button variant='outlined' onClick={deny} disabled={loading || !userCode.trim().length}>
+                Deny
+              </Button>
+              <Button
+                variant='contained'
+                color='primary'
+                onClick={approve}
+                disabled={loading || !userCode.trim().length}
+              >
+                Approve
+              </Button>
+            </div>
+          </form>
+        )}
+      </Paper>
+    </DevicePage>
+  );
+});
+DeviceView.displayName = 'DeviceView';
@@ -66,6 +66,7 @@ export interface GQLMutation {
   CreateInviteToken: string;
   RevokeAllInviteTokens: boolean;
   RemoveUserFromOrg: boolean;
+  ApproveDeviceCode: string;
   UpdateRetentionPluginConfig: boolean;
   UpdateRetentionScript: boolean;
   CreateRetentionScript: string;
@@ -169,7 +170,14 @@ export enum GQLAutocompleteEntityKind {
   AEK_SVC = 'AEK_SVC',
   AEK_SCRIPT = 'AEK_SCRIPT',
   AEK_NAMESPACE = 'AEK_NAMESPACE',
-  AEK_NODE = 'AEK_NODE'
+  AEK_NODE = 'AEK_NODE',
+  AEK_CONTAINER = 'AEK_CONTAINER',
+  AEK_DEPLOYMENT = 'AEK_DEPLOYMENT',
+  AEK_REPLICASET = 'AEK_REPLICASET',
+  AEK_STATEFULSET = 'AEK_STATEFULSET',
+  AEK_DAEMONSET = 'AEK_DAEMONSET',
+  AEK_TABLE = 'AEK_TABLE',
+  AEK_CRON_SCRIPT = 'AEK_CRON_SCRIPT'
 }
 
 export interface GQLAutocompleteSuggestion {
@@ -530,8 +538,12 @@ export interface QueryToClusterByNameResolver<TParent = any, TResult = any> {
   (parent: TParent, args: QueryToClusterByNameArgs, context: any, info: GraphQLResolveInfo): TResult;
 }
 
+export interface QueryToClustersArgs {
+  labelSelector?: string;
+  group?: string;
+}
 export interface QueryToClustersResolver<TParent = any, TResult = any> {
-  (parent: TParent, args: {}, context: any, info: GraphQLResolveInfo): TResult;
+  (parent: TParent, args: QueryToClustersArgs, context: any, info: GraphQLResolveInfo): TResult;
 }
 
 export interface QueryToClusterConnectionArgs {
@@ -669,6 +681,7 @@ export interface GQLMutationTypeResolver<TParent = any> {
   CreateInviteToken?: MutationToCreateInviteTokenResolver<TParent>;
   RevokeAllInviteTokens?: MutationToRevokeAllInviteTokensResolver<TParent>;
   RemoveUserFromOrg?: MutationToRemoveUserFromOrgResolver<TParent>;
+  ApproveDeviceCode?: MutationToApproveDeviceCodeResolver<TParent>;
   UpdateRetentionPluginConfig?: MutationToUpdateRetentionPluginConfigResolver<TParent>;
   UpdateRetentionScript?: MutationToUpdateRetentionScriptResolver<TParent>;
   CreateRetentionScript?: MutationToCreateRetentionScriptResolver<TParent>;
@@ -780,6 +793,14 @@ export interface MutationToRemoveUserFromOrgResolver<TParent = any, TResult = an
   (parent: TParent, args: MutationToRemoveUserFromOrgArgs, context: any, info: GraphQLResolveInfo): TResult;
 }
 
+export interface MutationToApproveDeviceCodeArgs {
+  userCode: string;
+  approved: boolean;
+}
+export interface MutationToApproveDeviceCodeResolver<TParent = any, TResult = any> {
+  (parent: TParent, args: MutationToApproveDeviceCodeArgs, context: any, info: GraphQLResolveInfo): TResult;
+}
+
 export interface MutationToUpdateRetentionPluginConfigArgs {
   id: string;
   enabled?: boolean;
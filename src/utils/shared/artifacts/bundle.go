@@ -0,0 +1,343 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package artifacts
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"px.dev/pixie/src/utils/shared/yamls"
+)
+
+const (
+	// BundleManifestFile is the name of the file describing the contents of a deployment bundle.
+	BundleManifestFile = "manifest.json"
+	// bundleVizierDir is the directory in the bundle containing the templated Vizier YAMLs.
+	bundleVizierDir = "vizier_templates"
+	// bundleOperatorDir is the directory in the bundle containing the templated operator YAMLs.
+	bundleOperatorDir = "operator_templates"
+	// bundleImagesDir is the directory in the bundle containing image tarballs, if any.
+	bundleImagesDir = "images"
+	// bundleConfigMapPartKey is the key in each bundle ConfigMap which holds its part of the compressed bundle.
+	bundleConfigMapPartKey = "bundle.tar.gz.part"
+	// bundleConfigMapPartsKey is the key in the first bundle ConfigMap which holds the number of parts.
+	bundleConfigMapPartsKey = "parts"
+	// maxBundleConfigMapPartSize keeps each bundle ConfigMap well within the 1MiB ConfigMap size limit.
+	maxBundleConfigMapPartSize = 768 * 1024
+)
+
+// BundleImage is a container image referenced by a deployment bundle.
+type BundleImage struct {
+	// Name is the image reference, as it appears in the YAMLs.
+	Name string `json:"name"`
+	// Digest is the content digest of the image manifest, if it could be resolved.
+	Digest string `json:"digest,omitempty"`
+	// Tarball is the path of the image tarball within the bundle, if the image was included.
+	Tarball string `json:"tarball,omitempty"`
+}
+
+// BundleManifest describes the contents of a deployment bundle.
+type BundleManifest struct {
+	VizierVersion   string         `json:"vizierVersion"`
+	OperatorVersion string         `json:"operatorVersion"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	Images          []*BundleImage `json:"images"`
+}
+
+// Bundle is a self-contained set of artifacts that can be used to deploy Vizier without access to Pixie Cloud's
+// artifact storage, for example in air-gapped clusters.
+type Bundle struct {
+	Manifest          *BundleManifest
+	VizierTemplates   []*yamls.YAMLFile
+	OperatorTemplates []*yamls.YAMLFile
+}
+
+// YAMLFilesToMap converts the given YAML files to a map from filename to contents. The filenames are prefixed with
+// the position of the file so that the original order can be restored by YAMLFilesFromMap.
+func YAMLFilesToMap(yamlFiles []*yamls.YAMLFile) map[string]string {
+	m := make(map[string]string)
+	for i, y := range yamlFiles {
+		m[fmt.Sprintf("%04d_%s.yaml", i, y.Name)] = y.YAML
+	}
+	return m
+}
+
+var yamlFileNameRegex = regexp.MustCompile(`(?:[0-9]+_)(.*)(?:\.yaml)`)
+
+// YAMLFilesFromMap converts a map from filename to contents into YAML files, ordered by filename. Filenames look
+// like "./pixie_yamls/00_namespace.yaml", which results in a YAML file named "namespace".
+func YAMLFilesFromMap(yamlMap map[string]string) []*yamls.YAMLFile {
+	yamlNames := make([]string, 0, len(yamlMap))
+	for k := range yamlMap {
+		yamlNames = append(yamlNames, k)
+	}
+	sort.Strings(yamlNames)
+
+	var yamlFiles []*yamls.YAMLFile
+	for _, fName := range yamlNames {
+		ms := yamlFileNameRegex.FindStringSubmatch(fName)
+		if ms == nil || len(ms) != 2 {
+			continue
+		}
+		yamlFiles = append(yamlFiles, &yamls.YAMLFile{
+			Name: ms[1],
+			YAML: yamlMap[fName],
+		})
+	}
+	return yamlFiles
+}
+
+func writeTarFile(tw *tar.Writer, name string, contents []byte) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(contents)),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(contents)
+	return err
+}
+
+func writeTarFileFromDisk(tw *tar.Writer, name string, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     info.Size(),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// BundleImageTarballPath returns the path within the bundle where the tarball for the given image is stored.
+func BundleImageTarballPath(image string) string {
+	return path.Join(bundleImagesDir, strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(image)+".tar")
+}
+
+// WriteBundle writes the bundle to w as a gzipped tarball. imageTarballs maps image names to image tarballs on disk,
+// which are copied into the bundle.
+func WriteBundle(w io.Writer, b *Bundle, imageTarballs map[string]string) error {
+	if b.Manifest == nil {
+		return errors.New("bundle is missing a manifest")
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	for _, img := range b.Manifest.Images {
+		if _, ok := imageTarballs[img.Name]; ok {
+			img.Tarball = BundleImageTarballPath(img.Name)
+		}
+	}
+
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, BundleManifestFile, manifest); err != nil {
+		return err
+	}
+
+	for dir, files := range map[string][]*yamls.YAMLFile{
+		bundleVizierDir:   b.VizierTemplates,
+		bundleOperatorDir: b.OperatorTemplates,
+	} {
+		for name, contents := range YAMLFilesToMap(files) {
+			if err := writeTarFile(tw, path.Join(dir, name), []byte(contents)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, img := range b.Manifest.Images {
+		if img.Tarball == "" {
+			continue
+		}
+		if err := writeTarFileFromDisk(tw, img.Tarball, imageTarballs[img.Name]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// ReadBundle reads a bundle written by WriteBundle. Image tarballs are skipped, since they are loaded into a
+// registry separately.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	b := &Bundle{}
+	vizierMap := make(map[string]string)
+	operatorMap := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		dir, name := path.Split(path.Clean(header.Name))
+		dir = strings.TrimSuffix(dir, "/")
+		if dir == bundleImagesDir {
+			continue
+		}
+
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case dir == "" && name == BundleManifestFile:
+			b.Manifest = &BundleManifest{}
+			if err := json.Unmarshal(contents, b.Manifest); err != nil {
+				return nil, err
+			}
+		case dir == bundleVizierDir:
+			vizierMap[name] = string(contents)
+		case dir == bundleOperatorDir:
+			operatorMap[name] = string(contents)
+		}
+	}
+
+	if b.Manifest == nil {
+		return nil, errors.New("bundle is missing a manifest")
+	}
+	b.VizierTemplates = YAMLFilesFromMap(vizierMap)
+	b.OperatorTemplates = YAMLFilesFromMap(operatorMap)
+	return b, nil
+}
+
+// ReadBundleFile reads the bundle at the given path.
+func ReadBundleFile(bundlePath string) (*Bundle, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBundle(f)
+}
+
+// VizierBundleConfigMapName returns the name of the ConfigMap which holds the given part of a Vizier bundle. The
+// first part is stored in the ConfigMap with the given name.
+func VizierBundleConfigMapName(name string, part int) string {
+	if part == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s-%d", name, part)
+}
+
+// VizierBundleConfigMapsData returns the binary contents of the ConfigMaps which the operator can deploy Vizier from.
+// The bundle's manifest and templated Vizier YAMLs are stored as a gzipped tarball, which is split into as many parts
+// as needed for each to fit within the ConfigMap size limit. Part i should be stored in the ConfigMap named by
+// VizierBundleConfigMapName.
+func VizierBundleConfigMapsData(b *Bundle) ([]map[string][]byte, error) {
+	if b.Manifest == nil {
+		return nil, errors.New("bundle is missing a manifest")
+	}
+	// Image tarballs are never stored in the cluster, since they are loaded into a registry separately.
+	manifest := *b.Manifest
+	manifest.Images = make([]*BundleImage, len(b.Manifest.Images))
+	for i, img := range b.Manifest.Images {
+		manifest.Images[i] = &BundleImage{Name: img.Name, Digest: img.Digest}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, &Bundle{Manifest: &manifest, VizierTemplates: b.VizierTemplates}, nil); err != nil {
+		return nil, err
+	}
+
+	archive := buf.Bytes()
+	var parts []map[string][]byte
+	for len(parts) == 0 || len(archive) > 0 {
+		n := len(archive)
+		if n > maxBundleConfigMapPartSize {
+			n = maxBundleConfigMapPartSize
+		}
+		parts = append(parts, map[string][]byte{bundleConfigMapPartKey: archive[:n]})
+		archive = archive[n:]
+	}
+	parts[0][bundleConfigMapPartsKey] = []byte(strconv.Itoa(len(parts)))
+	return parts, nil
+}
+
+// ReadVizierBundleConfigMaps reads the manifest and templated Vizier YAMLs from the ConfigMaps created with
+// VizierBundleConfigMapsData. getConfigMapData returns the binary contents of the ConfigMap with the given name.
+func ReadVizierBundleConfigMaps(name string, getConfigMapData func(string) (map[string][]byte, error)) (*Bundle, error) {
+	first, err := getConfigMapData(VizierBundleConfigMapName(name, 0))
+	if err != nil {
+		return nil, err
+	}
+	numParts, err := strconv.Atoi(string(first[bundleConfigMapPartsKey]))
+	if err != nil || numParts < 1 {
+		return nil, fmt.Errorf("bundle ConfigMap %s is missing its number of parts", name)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(first[bundleConfigMapPartKey])
+	for i := 1; i < numParts; i++ {
+		data, err := getConfigMapData(VizierBundleConfigMapName(name, i))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data[bundleConfigMapPartKey])
+	}
+	return ReadBundle(&buf)
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package artifacts_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/utils/shared/artifacts"
+	"px.dev/pixie/src/utils/shared/yamls"
+)
+
+const testDeploymentYAML = `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: nats-wait
+        image: "gcr.io/pixie-oss/pixie-dev-public/curl:1.0"
+      containers:
+      - name: app
+        image: gcr.io/pixie-oss/pixie-prod/vizier/metadata_server_image:0.9.1
+      - name: sidecar
+        image: nats:2.1
+      - name: templated
+        image: {{ .Values.image }}
+      - name: partially-templated
+        image: gcr.io/pixie-oss/pixie-prod/vizier/pem_image:{{ .Values.version }}
+`
+
+func TestListImages(t *testing.T) {
+	images := artifacts.ListImages(testDeploymentYAML, "image: nats:2.1")
+	assert.Equal(t, []string{
+		"gcr.io/pixie-oss/pixie-dev-public/curl:1.0",
+		"gcr.io/pixie-oss/pixie-prod/vizier/metadata_server_image:0.9.1",
+		"nats:2.1",
+	}, images)
+}
+
+func TestRewriteImageRegistry(t *testing.T) {
+	rewritten := artifacts.RewriteImageRegistry(testDeploymentYAML, "registry.internal:5000/mirror/")
+	assert.Equal(t, []string{
+		"registry.internal:5000/mirror/nats:2.1",
+		"registry.internal:5000/mirror/pixie-oss/pixie-dev-public/curl:1.0",
+		"registry.internal:5000/mirror/pixie-oss/pixie-prod/vizier/metadata_server_image:0.9.1",
+	}, artifacts.ListImages(rewritten))
+	assert.Contains(t, rewritten, `image: "registry.internal:5000/mirror/pixie-oss/pixie-dev-public/curl:1.0"`)
+	assert.Contains(t, rewritten, "image: {{ .Values.image }}")
+
+	assert.Equal(t, testDeploymentYAML, artifacts.RewriteImageRegistry(testDeploymentYAML, ""))
+}
+
+const testOLMYAML = `containers:
+  - name: catalog-operator
+    args:
+    - -configmapServerImage=quay.io/operator-framework/configmap-operator-registry:latest
+    - -util-image
+    -  quay.io/operator-framework/olm@sha256:b706ee
+    image: quay.io/operator-framework/olm@sha256:b706ee
+`
+
+func TestRewriteImageRegistry_ImageArgs(t *testing.T) {
+	assert.Equal(t, []string{
+		"quay.io/operator-framework/configmap-operator-registry:latest",
+		"quay.io/operator-framework/olm@sha256:b706ee",
+	}, artifacts.ListImages(testOLMYAML))
+
+	rewritten := artifacts.RewriteImageRegistry(testOLMYAML, "registry.internal")
+	assert.Equal(t, []string{
+		"registry.internal/operator-framework/configmap-operator-registry:latest",
+		"registry.internal/operator-framework/olm@sha256:b706ee",
+	}, artifacts.ListImages(rewritten))
+	assert.Contains(t, rewritten, "-  registry.internal/operator-framework/olm@sha256:b706ee\n")
+}
+
+func TestOperatorImages(t *testing.T) {
+	assert.Equal(t, []string{
+		"gcr.io/pixie-oss/pixie-prod/operator/operator_image:0.0.20",
+		"gcr.io/pixie-oss/pixie-prod/operator/bundle:0.0.20",
+	}, artifacts.OperatorImages("0.0.20"))
+	assert.Equal(t, "registry.internal/pixie-oss/pixie-prod/operator/operator_image:0.0.20",
+		artifacts.RewriteImage("gcr.io/pixie-oss/pixie-prod/operator/operator_image:0.0.20", "registry.internal/"))
+	// Images which already point at the registry are left alone.
+	assert.Equal(t, "registry.internal:5000/mirror/nats:2.1", artifacts.RewriteImage("registry.internal:5000/mirror/nats:2.1", "registry.internal:5000/mirror"))
+}
+
+func TestBundle_WriteRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	imageTarball := filepath.Join(dir, "nats.tar")
+	require.NoError(t, ioutil.WriteFile(imageTarball, []byte("image contents"), 0644))
+
+	b := &artifacts.Bundle{
+		Manifest: &artifacts.BundleManifest{
+			VizierVersion:   "0.9.1",
+			OperatorVersion: "0.0.20",
+			CreatedAt:       time.Unix(1600000000, 0).UTC(),
+			Images: []*artifacts.BundleImage{
+				{Name: "nats:2.1", Digest: "sha256:abcd"},
+				{Name: "gcr.io/pixie-oss/pixie-dev-public/curl:1.0"},
+			},
+		},
+		VizierTemplates: []*yamls.YAMLFile{
+			{Name: "secrets", YAML: "kind: Secret"},
+			{Name: "vizier_persistent", YAML: testDeploymentYAML},
+		},
+		OperatorTemplates: []*yamls.YAMLFile{
+			{Name: "vizier_crd", YAML: "kind: CustomResourceDefinition"},
+			{Name: "olm", YAML: "kind: Namespace"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, artifacts.WriteBundle(&buf, b, map[string]string{"nats:2.1": imageTarball}))
+
+	read, err := artifacts.ReadBundle(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, b.Manifest, read.Manifest)
+	assert.Equal(t, artifacts.BundleImageTarballPath("nats:2.1"), read.Manifest.Images[0].Tarball)
+	assert.Equal(t, "", read.Manifest.Images[1].Tarball)
+	assert.Equal(t, b.VizierTemplates, read.VizierTemplates)
+	assert.Equal(t, b.OperatorTemplates, read.OperatorTemplates)
+}
+
+func TestReadBundle_Invalid(t *testing.T) {
+	_, err := artifacts.ReadBundle(bytes.NewReader([]byte("not a bundle")))
+	assert.Error(t, err)
+}
+
+func TestVizierBundleConfigMapsData(t *testing.T) {
+	b := &artifacts.Bundle{
+		Manifest: &artifacts.BundleManifest{
+			VizierVersion: "0.9.1",
+			CreatedAt:     time.Unix(1600000000, 0).UTC(),
+			Images:        []*artifacts.BundleImage{{Name: "nats:2.1", Tarball: "images/nats_2.1.tar"}},
+		},
+		VizierTemplates: []*yamls.YAMLFile{
+			{Name: "secrets", YAML: "kind: Secret"},
+			{Name: "vizier_persistent", YAML: testDeploymentYAML},
+		},
+	}
+
+	parts, err := artifacts.VizierBundleConfigMapsData(b)
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+
+	configMaps := map[string]map[string][]byte{
+		artifacts.VizierBundleConfigMapName("pl-vizier-bundle", 0): parts[0],
+	}
+	getConfigMapData := func(name string) (map[string][]byte, error) {
+		data, ok := configMaps[name]
+		if !ok {
+			return nil, fmt.Errorf("ConfigMap %s not found", name)
+		}
+		return data, nil
+	}
+	read, err := artifacts.ReadVizierBundleConfigMaps("pl-vizier-bundle", getConfigMapData)
+	require.NoError(t, err)
+	assert.Equal(t, "0.9.1", read.Manifest.VizierVersion)
+	// Image tarballs are not stored in the ConfigMaps.
+	assert.Equal(t, []*artifacts.BundleImage{{Name: "nats:2.1"}}, read.Manifest.Images)
+	assert.Equal(t, b.VizierTemplates, read.VizierTemplates)
+
+	_, err = artifacts.ReadVizierBundleConfigMaps("missing", getConfigMapData)
+	assert.Error(t, err)
+}
+
+func TestYAMLFilesToMap_PreservesOrder(t *testing.T) {
+	var yamlFiles []*yamls.YAMLFile
+	for i := 0; i < 120; i++ {
+		yamlFiles = append(yamlFiles, &yamls.YAMLFile{Name: fmt.Sprintf("file%d", i), YAML: "kind: Secret"})
+	}
+	assert.Equal(t, yamlFiles, artifacts.YAMLFilesFromMap(artifacts.YAMLFilesToMap(yamlFiles)))
+}
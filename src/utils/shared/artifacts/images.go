@@ -0,0 +1,228 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package artifacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	imageRegex = regexp.MustCompile(`(?m)^([ \t]*-?[ \t]*image:[ \t]*)(["']?)([^\s"'{}]+)(["']?[ \t]*)$`)
+	// imageArgRegex matches images which are passed as container args, such as those used by OLM's catalog operator.
+	imageArgRegex = regexp.MustCompile(`(?m)^([ \t]*-[ \t]*(?:-configmapServerImage=|-util-image[ \t]*\n[ \t]*-[ \t]*))(["']?)([^\s"'{}]+)(["']?[ \t]*)$`)
+)
+
+// operatorImageRepos are the repositories of the images deployed by OLM for the operator, which are not referenced
+// by the operator's YAMLs.
+var operatorImageRepos = []string{
+	"gcr.io/pixie-oss/pixie-prod/operator/operator_image",
+	"gcr.io/pixie-oss/pixie-prod/operator/bundle",
+}
+
+// ListImages returns the distinct container images referenced by the given YAMLs, in sorted order. Image references
+// which are filled in by a template are skipped.
+func ListImages(yamlContents ...string) []string {
+	seen := make(map[string]bool)
+	var images []string
+	for _, y := range yamlContents {
+		for _, re := range []*regexp.Regexp{imageRegex, imageArgRegex} {
+			for _, m := range re.FindAllStringSubmatch(y, -1) {
+				if !seen[m[3]] {
+					seen[m[3]] = true
+					images = append(images, m[3])
+				}
+			}
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// OperatorImages returns the images which OLM deploys for the given operator version.
+func OperatorImages(operatorVersion string) []string {
+	images := make([]string, len(operatorImageRepos))
+	for i, repo := range operatorImageRepos {
+		images[i] = fmt.Sprintf("%s:%s", repo, operatorVersion)
+	}
+	return images
+}
+
+// splitImageDomain splits an image reference into its registry domain and remainder, following the rules the
+// Docker CLI uses: the first path component is a domain if it contains a "." or ":", or is "localhost".
+func splitImageDomain(image string) (string, string) {
+	i := strings.Index(image, "/")
+	if i == -1 {
+		return "docker.io", "library/" + image
+	}
+	domain := image[:i]
+	if !strings.ContainsAny(domain, ".:") && domain != "localhost" {
+		return "docker.io", image
+	}
+	return domain, image[i+1:]
+}
+
+// RewriteImage replaces the registry of the image with the given registry, for example
+// "gcr.io/pixie-oss/pixie-prod/vizier/pem_image:0.9.1" becomes
+// "registry.internal/pixie-oss/pixie-prod/vizier/pem_image:0.9.1". The image is returned unchanged if the registry
+// is empty, or if the image already points at the registry.
+func RewriteImage(image string, registry string) string {
+	registry = strings.TrimSuffix(registry, "/")
+	if registry == "" || strings.HasPrefix(image, registry+"/") {
+		return image
+	}
+	_, repo := splitImageDomain(image)
+	return registry + "/" + strings.TrimPrefix(repo, "library/")
+}
+
+// RewriteImageRegistry replaces the registry of every image referenced in the YAML with the given registry, as
+// described by RewriteImage. This includes images which are passed as container args.
+func RewriteImageRegistry(yaml string, registry string) string {
+	if strings.TrimSuffix(registry, "/") == "" {
+		return yaml
+	}
+	for _, re := range []*regexp.Regexp{imageRegex, imageArgRegex} {
+		re := re
+		yaml = re.ReplaceAllStringFunc(yaml, func(match string) string {
+			m := re.FindStringSubmatch(match)
+			return m[1] + m[2] + RewriteImage(m[3], registry) + m[4]
+		})
+	}
+	return yaml
+}
+
+// splitImageReference splits the repository path of an image into its name and its tag or digest.
+func splitImageReference(repo string) (string, string) {
+	if i := strings.Index(repo, "@"); i != -1 {
+		return repo[:i], repo[i+1:]
+	}
+	if i := strings.LastIndex(repo, ":"); i != -1 && !strings.Contains(repo[i:], "/") {
+		return repo[:i], repo[i+1:]
+	}
+	return repo, "latest"
+}
+
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+var bearerParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// fetchRegistryToken fetches an anonymous pull token, as described by the challenge in a registry's
+// WWW-Authenticate header.
+func fetchRegistryToken(client *http.Client, challenge string) (string, error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("unsupported registry auth challenge: %s", challenge)
+	}
+	params := make(map[string]string)
+	for _, m := range bearerParamRegex.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	req, err := http.NewRequest(http.MethodGet, params["realm"], nil)
+	if err != nil {
+		return "", err
+	}
+	q := req.URL.Query()
+	for _, k := range []string{"service", "scope"} {
+		if v, ok := params[k]; ok {
+			q.Set(k, v)
+		}
+	}
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch registry token: %s", resp.Status)
+	}
+
+	var tokenResp struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", err
+	}
+	if tokenResp.Token != "" {
+		return tokenResp.Token, nil
+	}
+	return tokenResp.AccessToken, nil
+}
+
+// ResolveImageDigest looks up the manifest digest of the given image using the registry's HTTP API. Only public
+// images, which may be pulled anonymously, can be resolved.
+func ResolveImageDigest(client *http.Client, image string) (string, error) {
+	domain, repo := splitImageDomain(image)
+	name, ref := splitImageReference(repo)
+	if strings.HasPrefix(ref, "sha256:") {
+		return ref, nil
+	}
+	if domain == "docker.io" {
+		domain = "registry-1.docker.io"
+	}
+
+	url := fmt.Sprintf("https://%s/v2/%s/manifests/%s", domain, name, ref)
+	doRequest := func(token string) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodHead, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return client.Do(req)
+	}
+
+	resp, err := doRequest("")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, err := fetchRegistryToken(client, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", err
+		}
+		resp, err = doRequest(token)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve digest for %s: %s", image, resp.Status)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return a digest for %s", image)
+	}
+	return digest, nil
+}
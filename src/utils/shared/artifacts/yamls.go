@@ -96,32 +96,7 @@ func FetchVizierTemplates(conn *grpc.ClientConn, authToken, versionStr string) (
 		return nil, err
 	}
 
-	// Convert to YAML files, using the provided file names.
-	// Get the YAML names, in order.
-	yamlNames := make([]string, len(yamlMap))
-	i := 0
-	for k := range yamlMap {
-		yamlNames[i] = k
-		i++
-	}
-	sort.Strings(yamlNames)
-
-	// Write to YAMLFile slice.
-	var yamlFiles []*yamls.YAMLFile
-	re := regexp.MustCompile(`(?:[0-9]+_)(.*)(?:\.yaml)`)
-	for _, fName := range yamlNames {
-		// The filename looks like "./pixie_yamls/00_namespace.yaml", we want to extract the "namespace".
-		ms := re.FindStringSubmatch(fName)
-		if ms == nil || len(ms) != 2 {
-			continue
-		}
-		yamlFiles = append(yamlFiles, &yamls.YAMLFile{
-			Name: ms[1],
-			YAML: yamlMap[fName],
-		})
-	}
-
-	return yamlFiles, nil
+	return YAMLFilesFromMap(yamlMap), nil
 }
 
 // FetchOperatorTemplates fetches the operator templates for the given version.
@@ -24,6 +24,7 @@ import (
 
 	"k8s.io/client-go/kubernetes"
 
+	"px.dev/pixie/src/api/proto/vizierconfigpb"
 	"px.dev/pixie/src/utils/shared/tar"
 	"px.dev/pixie/src/utils/shared/yamls"
 )
@@ -38,6 +39,8 @@ const (
 	defaultDatastreamBufferSize      = 1024 * 1024
 	defaultDatastreamBufferSpikeSize = 1024 * 1024 * 500
 	defaultElectionPeriodMs          = 7500
+	// How long a namespace-scoped PEM traces a process before dropping it for not being in a scoped namespace.
+	namespaceScopedUntrackedUPIDThresholdS = 60
 )
 
 // VizierTmplValues are the template values that can be used to fill out templated Vizier YAMLs.
@@ -60,6 +63,54 @@ type VizierTmplValues struct {
 	DatastreamBufferSpikeSize uint32
 	ElectionPeriodMs          int64
 	CustomPEMFlags            map[string]string
+	DefaultQueryFlags         map[string]string
+	NamespaceScope            []string
+}
+
+// VizierTmplValuesFromSpec creates the template values for deploying a Vizier with the given spec to the given namespace.
+func VizierTmplValuesFromSpec(namespace string, vzSpec *vizierconfigpb.VizierSpec) *VizierTmplValues {
+	cloudAddr := vzSpec.CloudAddr
+	updateCloudAddr := vzSpec.CloudAddr
+	if vzSpec.DevCloudNamespace != "" {
+		cloudAddr = fmt.Sprintf("vzconn-service.%s.svc.cluster.local:51600", vzSpec.DevCloudNamespace)
+		updateCloudAddr = fmt.Sprintf("api-service.%s.svc.cluster.local:51200", vzSpec.DevCloudNamespace)
+	}
+
+	// We should eventually clean up the templating code, since our Helm charts and extracted YAMLs will now just
+	// be simple CRDs.
+	tmplValues := &VizierTmplValues{
+		DeployKey:             vzSpec.DeployKey,
+		CustomDeployKeySecret: vzSpec.CustomDeployKeySecret,
+		UseEtcdOperator:       vzSpec.UseEtcdOperator,
+		PEMMemoryLimit:        vzSpec.PemMemoryLimit,
+		Namespace:             namespace,
+		CloudAddr:             cloudAddr,
+		CloudUpdateAddr:       updateCloudAddr,
+		ClusterName:           vzSpec.ClusterName,
+		DisableAutoUpdate:     vzSpec.DisableAutoUpdate,
+		ClockConverter:        vzSpec.ClockConverter,
+		DataAccess:            vzSpec.DataAccess,
+	}
+
+	if vzSpec.DataCollectorParams != nil && vzSpec.DataCollectorParams.DatastreamBufferSize != 0 {
+		tmplValues.DatastreamBufferSize = vzSpec.DataCollectorParams.DatastreamBufferSize
+	}
+	if vzSpec.DataCollectorParams != nil && vzSpec.DataCollectorParams.DatastreamBufferSpikeSize != 0 {
+		tmplValues.DatastreamBufferSpikeSize = vzSpec.DataCollectorParams.DatastreamBufferSpikeSize
+	}
+	if vzSpec.DataCollectorParams != nil && vzSpec.DataCollectorParams.CustomPEMFlags != nil {
+		tmplValues.CustomPEMFlags = vzSpec.DataCollectorParams.CustomPEMFlags
+	}
+	if vzSpec.LeadershipElectionParams != nil {
+		tmplValues.ElectionPeriodMs = vzSpec.LeadershipElectionParams.ElectionPeriodMs
+	}
+	if vzSpec.DefaultQueryFlags != nil {
+		tmplValues.DefaultQueryFlags = vzSpec.DefaultQueryFlags
+	}
+	if len(vzSpec.NamespaceScope) > 0 {
+		tmplValues.NamespaceScope = vzSpec.NamespaceScope
+	}
+	return tmplValues
 }
 
 // VizierTmplValuesToArgs converts the vizier template values to args which can be used to fill out a template.
@@ -83,6 +134,8 @@ func VizierTmplValuesToArgs(tmplValues *VizierTmplValues) *yamls.YAMLTmplArgumen
 			"datastreamBufferSpikeSize": tmplValues.DatastreamBufferSpikeSize,
 			"electionPeriodMs":          tmplValues.ElectionPeriodMs,
 			"customPEMFlags":            tmplValues.CustomPEMFlags,
+			"defaultQueryFlags":         tmplValues.DefaultQueryFlags,
+			"namespaceScope":            tmplValues.NamespaceScope,
 		},
 		Release: &map[string]interface{}{
 			"Namespace": tmplValues.Namespace,
@@ -398,6 +451,12 @@ func generateVzYAMLs(clientset *kubernetes.Clientset, yamlMap map[string]string)
 			Placeholder:     "__PX_DATA_ACCESS__",
 			TemplateValue:   fmt.Sprintf(`{{ if .Values.dataAccess }}"{{ .Values.dataAccess }}"{{else}}"%s"{{end}}`, defaultDataAccess),
 		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("vizier-query-broker"),
+			Patch:           `{"spec": {"template": {"spec": {"containers": [{"name": "app", "env": [{"name": "PL_DEFAULT_QUERY_FLAGS","value": "__PX_DEFAULT_QUERY_FLAGS__"}]}] } } } }`,
+			Placeholder:     "__PX_DEFAULT_QUERY_FLAGS__",
+			TemplateValue:   `"{{ range $key, $value := .Values.defaultQueryFlags }}{{$key}}={{$value}},{{ end }}"`,
+		},
 		{
 			TemplateMatcher: yamls.GenerateContainerNameMatcherFn("pem"),
 			Patch:           `{"spec": {"template": { "spec": { "containers": [{"name": "pem", "env": [{"name": "PL_DATASTREAM_BUFFER_SIZE", "value": "__PX_DATASTREAM_BUFFER_SIZE__"}]}] } } } }`,
@@ -422,6 +481,26 @@ func generateVzYAMLs(clientset *kubernetes.Clientset, yamlMap map[string]string)
 			Placeholder:     "__PX_RENEW_PERIOD__",
 			TemplateValue:   fmt.Sprintf(`{{ if .Values.electionPeriodMs }}"{{ .Values.electionPeriodMs }}"{{else}}"%d"{{end}}`, defaultElectionPeriodMs),
 		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("vizier-metadata"),
+			Patch:           `{"spec": {"template": {"spec": {"containers": [{"name": "app", "env": [{"name": "PL_NAMESPACE_SCOPE","value": "__PX_NAMESPACE_SCOPE__"}]}] } } } }`,
+			Placeholder:     "__PX_NAMESPACE_SCOPE__",
+			TemplateValue:   `"{{ if .Values.namespaceScope }}{{ join "," .Values.namespaceScope }}{{ end }}"`,
+		},
+		{
+			TemplateMatcher: yamls.GenerateResourceNameMatcherFn("vizier-cloud-connector"),
+			Patch:           `{"spec": {"template": {"spec": {"containers": [{"name": "app", "env": [{"name": "PL_NAMESPACE_SCOPE","value": "__PX_NAMESPACE_SCOPE__"}]}] } } } }`,
+			Placeholder:     "__PX_NAMESPACE_SCOPE__",
+			TemplateValue:   `"{{ if .Values.namespaceScope }}{{ join "," .Values.namespaceScope }}{{ end }}"`,
+		},
+		{
+			// A namespace-scoped PEM only receives metadata for pods in the scoped namespaces, so it stops
+			// tracing any process that is not part of that metadata.
+			TemplateMatcher: yamls.GenerateContainerNameMatcherFn("pem"),
+			Patch:           `{"spec": {"template": { "spec": { "containers": [{"name": "pem", "env": [{"name": "PL_STIRLING_UNTRACKED_UPID_THRESHOLD_S", "value": "__PX_UNTRACKED_UPID_THRESHOLD__"}]}] } } } }`,
+			Placeholder:     "__PX_UNTRACKED_UPID_THRESHOLD__",
+			TemplateValue:   fmt.Sprintf(`{{ if .Values.namespaceScope }}"%d"{{else}}"0"{{end}}`, namespaceScopedUntrackedUPIDThresholdS),
+		},
 		{
 			TemplateMatcher: yamls.GenerateContainerNameMatcherFn("pem"),
 			Patch:           `{"spec": {"template": { "spec": { "containers": [{"name": "pem", "env": [{"name": "PL_PEM_ENV_VAR_PLACEHOLDER", "value": "__PL_PEM_ENV_VAR_VALUE__"}]}] } } } }`,
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package testingutils
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// SetupPebble starts up a Pebble ACME test server. Pebble is configured to consider all challenges
+// valid, so callers don't need working DNS. It returns the directory URL and an HTTP client that
+// trusts Pebble's self-signed certificate.
+func SetupPebble() (string, *http.Client, func(), error) {
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("could not connect to docker: %w", err)
+	}
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "letsencrypt/pebble",
+		Tag:        "v2.3.1",
+		Env: []string{
+			"PEBBLE_VA_ALWAYS_VALID=1",
+			"PEBBLE_VA_NOSLEEP=1",
+			"PEBBLE_WFE_NONCEREJECT=0",
+		},
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		return "", nil, nil, err
+	}
+	// Set a 5 minute expiration on resources.
+	err = resource.Expire(300)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	// Pebble serves its API with a cert from its own test CA.
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	directoryURL := fmt.Sprintf("https://%s:%s/dir", resource.Container.NetworkSettings.Gateway, resource.GetPort("14000/tcp"))
+	if err = pool.Retry(func() error {
+		resp, err := client.Get(directoryURL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}); err != nil {
+		pool.Purge(resource)
+		return "", nil, nil, fmt.Errorf("cannot start pebble: %w", err)
+	}
+
+	cleanup := func() {
+		pool.Purge(resource)
+	}
+	return directoryURL, client, cleanup, nil
+}
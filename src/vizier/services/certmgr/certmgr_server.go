@@ -19,7 +19,9 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -40,6 +42,35 @@ func init() {
 	pflag.String("namespace", "pl", "The namespace of Vizier")
 	pflag.String("cluster_id", "", "The Cluster ID to use for Pixie Cloud")
 	pflag.String("nats_url", "pl-nats", "The URL of NATS")
+	pflag.Bool("standalone", false, "Whether Vizier runs without Pixie Cloud")
+	pflag.String("proxy_tls_cert", "", "The locally provisioned TLS cert for the proxy in standalone mode. Defaults to the server cert")
+	pflag.String("proxy_tls_key", "", "The locally provisioned TLS key for the proxy in standalone mode. Defaults to the server key")
+}
+
+// installLocalCerts loads the locally provisioned proxy certs, which are used instead of the certs
+// that Pixie Cloud issues when Vizier runs standalone.
+func installLocalCerts(svr *controllers.Server) error {
+	certPath := viper.GetString("proxy_tls_cert")
+	keyPath := viper.GetString("proxy_tls_key")
+	if certPath == "" || keyPath == "" {
+		certPath = viper.GetString("server_tls_cert")
+		keyPath = viper.GetString("server_tls_key")
+	}
+
+	cert, err := os.ReadFile(certPath)
+	if err != nil {
+		return err
+	}
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = svr.UpdateCerts(context.Background(), &certmgrpb.UpdateCertsRequest{
+		Key:  string(key),
+		Cert: string(cert),
+	})
+	return err
 }
 
 func main() {
@@ -75,8 +106,11 @@ func main() {
 		log.WithError(err).Fatal("Timed out: failed to connect to NATS.")
 	}
 
+	standalone := viper.GetBool("standalone")
+	// Standalone Viziers generate their cluster ID locally, so it may not be assigned yet. It is only
+	// needed to request certs from Pixie Cloud.
 	clusterID, err := uuid.FromString(viper.GetString("cluster_id"))
-	if err != nil {
+	if err != nil && !standalone {
 		log.WithError(err).Fatal("Failed to parse passed in cluster ID")
 	}
 
@@ -103,8 +137,15 @@ func main() {
 
 	env := certmgrenv.New("vizier")
 	svr := controllers.NewServer(env, clusterID, nc, k8sAPI)
-	go svr.CertRequester()
-	defer svr.StopCertRequester()
+	if standalone {
+		err = installLocalCerts(svr)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to install local certs")
+		}
+	} else {
+		go svr.CertRequester()
+		defer svr.StopCertRequester()
+	}
 
 	s := server.NewPLServer(env, mux)
 	certmgrpb.RegisterCertMgrServiceServer(s.GRPCServer(), svr)
@@ -0,0 +1,316 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bridge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/cloud/vzconn/vzconnpb"
+	"px.dev/pixie/src/vizier/utils/datastore"
+)
+
+const offlineQueuePrefix = "/v2cOfflineQueue/"
+
+// OverflowPolicy decides what happens to a message which is queued while the offline queue is full.
+type OverflowPolicy int
+
+const (
+	// DropOldest evicts the oldest queued message on the same topic to make room for the new message.
+	DropOldest OverflowPolicy = iota
+	// DropNewest drops the new message, which keeps the queued messages on the topic contiguous.
+	DropNewest
+	// DoNotQueue never queues messages on the topic, for messages which are worthless once the cloud reconnects.
+	DoNotQueue
+)
+
+var overflowPolicyNames = map[string]OverflowPolicy{
+	"drop_oldest":  DropOldest,
+	"drop_newest":  DropNewest,
+	"do_not_queue": DoNotQueue,
+}
+
+// DefaultOverflowPolicies are the overflow policies for topics which don't use DropOldest.
+// Metadata updates keep the oldest updates, so that the cloud only has to request the updates after them.
+var DefaultOverflowPolicies = map[string]OverflowPolicy{
+	"DurableMetadataUpdates": DropNewest,
+}
+
+// ParseOverflowPolicies parses a comma-separated list of topic=policy pairs, such as
+// "DurableMetadataUpdates=drop_newest,ssl=do_not_queue", on top of the default policies.
+func ParseOverflowPolicies(s string) (map[string]OverflowPolicy, error) {
+	policies := make(map[string]OverflowPolicy)
+	for k, v := range DefaultOverflowPolicies {
+		policies[k] = v
+	}
+	if s == "" {
+		return policies, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid overflow policy '%s', expected topic=policy", pair)
+		}
+		policy, ok := overflowPolicyNames[parts[1]]
+		if !ok {
+			return nil, fmt.Errorf("unknown overflow policy '%s' for topic '%s'", parts[1], parts[0])
+		}
+		policies[parts[0]] = policy
+	}
+	return policies, nil
+}
+
+// OfflineQueueStore is the datastore the offline queue persists messages to.
+type OfflineQueueStore interface {
+	datastore.MultiGetter
+	datastore.Setter
+	datastore.Deleter
+}
+
+type queuedMsg struct {
+	seqID   int64
+	topic   string
+	size    int64
+	removed bool
+}
+
+// OfflineQueue is a bounded, on-disk FIFO of Vizier to cloud messages. Messages are queued while the bridge to the
+// cloud is down and drained in order once the bridge has registered again.
+type OfflineQueue struct {
+	ds       OfflineQueueStore
+	maxMsgs  int
+	maxBytes int64
+	policies map[string]OverflowPolicy
+
+	mu sync.Mutex
+	// order holds the queued messages from oldest to newest, including messages that were evicted from the
+	// middle of the queue, which are skipped once they reach the front.
+	order     []*queuedMsg
+	byTopic   map[string][]*queuedMsg
+	numMsgs   int
+	numBytes  int64
+	nextSeqID int64
+	dropped   int64
+}
+
+// NewOfflineQueue creates an offline queue which holds at most maxMsgs messages and maxBytes bytes. Messages which
+// were queued before a restart are loaded from the datastore.
+func NewOfflineQueue(ds OfflineQueueStore, maxMsgs int, maxBytes int64, policies map[string]OverflowPolicy) (*OfflineQueue, error) {
+	q := &OfflineQueue{
+		ds:        ds,
+		maxMsgs:   maxMsgs,
+		maxBytes:  maxBytes,
+		policies:  policies,
+		byTopic:   make(map[string][]*queuedMsg),
+		nextSeqID: 1,
+	}
+
+	keys, values, err := ds.GetWithPrefix(offlineQueuePrefix)
+	if err != nil {
+		return nil, err
+	}
+	for i, k := range keys {
+		seqID, err := strconv.ParseInt(strings.TrimPrefix(k, offlineQueuePrefix), 10, 64)
+		if err != nil {
+			log.WithError(err).WithField("key", k).Error("Invalid key in offline queue, skipping...")
+			continue
+		}
+		msg := &vzconnpb.V2CBridgeMessage{}
+		err = msg.Unmarshal(values[i])
+		if err != nil {
+			log.WithError(err).WithField("key", k).Error("Invalid message in offline queue, skipping...")
+			continue
+		}
+		q.append(&queuedMsg{seqID: seqID, topic: msg.Topic, size: int64(len(values[i]))})
+		if seqID >= q.nextSeqID {
+			q.nextSeqID = seqID + 1
+		}
+	}
+	if q.numMsgs > 0 {
+		log.WithField("count", q.numMsgs).Info("Loaded queued messages from offline queue")
+	}
+	return q, nil
+}
+
+func queueKey(seqID int64) string {
+	// Zero-pad the sequence ID so that the keys sort in queue order.
+	return fmt.Sprintf("%s%020d", offlineQueuePrefix, seqID)
+}
+
+func (q *OfflineQueue) policy(topic string) OverflowPolicy {
+	if p, ok := q.policies[topic]; ok {
+		return p
+	}
+	return DropOldest
+}
+
+func (q *OfflineQueue) append(m *queuedMsg) {
+	q.order = append(q.order, m)
+	q.byTopic[m.topic] = append(q.byTopic[m.topic], m)
+	q.numMsgs++
+	q.numBytes += m.size
+}
+
+// remove removes the message from the queue. The caller must hold the lock.
+func (q *OfflineQueue) remove(m *queuedMsg) error {
+	if m.removed {
+		return nil
+	}
+	if err := q.ds.Delete(queueKey(m.seqID)); err != nil {
+		return err
+	}
+	m.removed = true
+	q.numMsgs--
+	q.numBytes -= m.size
+
+	// Messages are almost always removed from the front, either when draining or when evicting the oldest
+	// message on a topic.
+	msgs := q.byTopic[m.topic]
+	for i, tm := range msgs {
+		if tm != m {
+			continue
+		}
+		if i == 0 {
+			msgs = msgs[1:]
+		} else {
+			msgs = append(msgs[:i], msgs[i+1:]...)
+		}
+		break
+	}
+	if len(msgs) == 0 {
+		delete(q.byTopic, m.topic)
+	} else {
+		q.byTopic[m.topic] = msgs
+	}
+
+	// Drop removed messages from the front of the queue.
+	for len(q.order) > 0 && q.order[0].removed {
+		q.order = q.order[1:]
+	}
+	return nil
+}
+
+func (q *OfflineQueue) full(size int64) bool {
+	return q.numMsgs+1 > q.maxMsgs || q.numBytes+size > q.maxBytes
+}
+
+func (q *OfflineQueue) drop(topic string) {
+	if q.dropped%100 == 0 {
+		log.WithField("topic", topic).
+			WithField("droppedCount", q.dropped).
+			Warn("Dropping message because the offline queue is full")
+	}
+	q.dropped++
+}
+
+// Push adds the message to the back of the queue, applying the topic's overflow policy if the queue is full.
+// It returns whether the message was queued.
+func (q *OfflineQueue) Push(msg *vzconnpb.V2CBridgeMessage) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	policy := q.policy(msg.Topic)
+	if policy == DoNotQueue {
+		return false, nil
+	}
+
+	b, err := msg.Marshal()
+	if err != nil {
+		return false, err
+	}
+	size := int64(len(b))
+	if size > q.maxBytes {
+		q.drop(msg.Topic)
+		return false, nil
+	}
+
+	for q.full(size) {
+		sameTopic := q.byTopic[msg.Topic]
+		if policy == DropNewest || len(sameTopic) == 0 {
+			q.drop(msg.Topic)
+			return false, nil
+		}
+		if err := q.remove(sameTopic[0]); err != nil {
+			return false, err
+		}
+		q.drop(msg.Topic)
+	}
+
+	m := &queuedMsg{seqID: q.nextSeqID, topic: msg.Topic, size: size}
+	if err := q.ds.Set(queueKey(m.seqID), string(b)); err != nil {
+		return false, err
+	}
+	q.nextSeqID++
+	q.append(m)
+	return true, nil
+}
+
+// Peek returns the oldest message in the queue and its sequence ID, or nil if the queue is empty.
+func (q *OfflineQueue) Peek() (int64, *vzconnpb.V2CBridgeMessage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.order) > 0 {
+		m := q.order[0]
+		b, err := q.ds.Get(queueKey(m.seqID))
+		if err != nil {
+			return 0, nil, err
+		}
+		msg := &vzconnpb.V2CBridgeMessage{}
+		if err := msg.Unmarshal(b); err != nil || b == nil {
+			// Skip the message, rather than blocking the rest of the queue on it.
+			log.WithError(err).WithField("seqID", m.seqID).Error("Invalid message in offline queue, skipping...")
+			if err := q.remove(m); err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+		return m.seqID, msg, nil
+	}
+	return 0, nil, nil
+}
+
+// Remove removes the message with the given sequence ID, once it has been handed to the bridge. Removing a
+// message which was already evicted is a no-op.
+func (q *OfflineQueue) Remove(seqID int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, m := range q.order {
+		if m.seqID == seqID {
+			return q.remove(m)
+		}
+		if m.seqID > seqID {
+			break
+		}
+	}
+	return nil
+}
+
+// Len returns the number of queued messages.
+func (q *OfflineQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.numMsgs
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bridge_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/vzconn/vzconnpb"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/vizier/services/cloud_connector/bridge"
+	"px.dev/pixie/src/vizier/utils/datastore/pebbledb"
+)
+
+func openTestQueueStore(t *testing.T, fs vfs.FS) *pebbledb.DataStore {
+	db, err := pebble.Open("queue", &pebble.Options{FS: fs})
+	require.NoError(t, err)
+	return pebbledb.New(db, 3*time.Second)
+}
+
+func makeQueueMsg(t *testing.T, topic string, data string) *vzconnpb.V2CBridgeMessage {
+	msg, err := types.MarshalAny(&cvmsgspb.VLogMessage{Data: []byte(data)})
+	require.NoError(t, err)
+	return &vzconnpb.V2CBridgeMessage{Topic: topic, Msg: msg}
+}
+
+// drainQueue pops all messages off the queue and returns their data, in order.
+func drainQueue(t *testing.T, q *bridge.OfflineQueue) []string {
+	var data []string
+	for {
+		seqID, msg, err := q.Peek()
+		require.NoError(t, err)
+		if msg == nil {
+			return data
+		}
+		logMsg := &cvmsgspb.VLogMessage{}
+		require.NoError(t, types.UnmarshalAny(msg.Msg, logMsg))
+		data = append(data, msg.Topic+":"+string(logMsg.Data))
+		require.NoError(t, q.Remove(seqID))
+	}
+}
+
+func TestOfflineQueue_OrderAndPersistence(t *testing.T) {
+	fs := vfs.NewMem()
+	ds := openTestQueueStore(t, fs)
+
+	q, err := bridge.NewOfflineQueue(ds, 100, 1024*1024, nil)
+	require.NoError(t, err)
+	for _, m := range []*vzconnpb.V2CBridgeMessage{
+		makeQueueMsg(t, "a", "1"),
+		makeQueueMsg(t, "b", "2"),
+		makeQueueMsg(t, "a", "3"),
+	} {
+		queued, err := q.Push(m)
+		require.NoError(t, err)
+		assert.True(t, queued)
+	}
+	assert.Equal(t, 3, q.Len())
+	require.NoError(t, ds.Close())
+
+	// The messages survive a restart.
+	ds = openTestQueueStore(t, fs)
+	defer ds.Close()
+	q, err = bridge.NewOfflineQueue(ds, 100, 1024*1024, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, q.Len())
+
+	queued, err := q.Push(makeQueueMsg(t, "b", "4"))
+	require.NoError(t, err)
+	assert.True(t, queued)
+
+	assert.Equal(t, []string{"a:1", "b:2", "a:3", "b:4"}, drainQueue(t, q))
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestOfflineQueue_OverflowPolicies(t *testing.T) {
+	ds := openTestQueueStore(t, vfs.NewMem())
+	defer ds.Close()
+
+	policies, err := bridge.ParseOverflowPolicies("newest=drop_newest,reply=do_not_queue")
+	require.NoError(t, err)
+	q, err := bridge.NewOfflineQueue(ds, 3, 1024*1024, policies)
+	require.NoError(t, err)
+
+	push := func(topic string, data string) bool {
+		queued, err := q.Push(makeQueueMsg(t, topic, data))
+		require.NoError(t, err)
+		return queued
+	}
+
+	assert.False(t, push("reply", "0"))
+	assert.True(t, push("oldest", "1"))
+	assert.True(t, push("newest", "2"))
+	assert.True(t, push("oldest", "3"))
+
+	// The queue is full. The oldest message on the topic is evicted to make room.
+	assert.True(t, push("oldest", "4"))
+	// The new message is dropped.
+	assert.False(t, push("newest", "5"))
+
+	assert.Equal(t, []string{"newest:2", "oldest:3", "oldest:4"}, drainQueue(t, q))
+}
+
+func TestOfflineQueue_MaxBytes(t *testing.T) {
+	ds := openTestQueueStore(t, vfs.NewMem())
+	defer ds.Close()
+
+	msg := makeQueueMsg(t, "a", "1")
+	size := int64(msg.Size())
+	q, err := bridge.NewOfflineQueue(ds, 100, 2*size, nil)
+	require.NoError(t, err)
+
+	for _, data := range []string{"1", "2", "3"} {
+		queued, err := q.Push(makeQueueMsg(t, "a", data))
+		require.NoError(t, err)
+		assert.True(t, queued)
+	}
+	assert.Equal(t, []string{"a:2", "a:3"}, drainQueue(t, q))
+}
+
+func TestParseOverflowPolicies(t *testing.T) {
+	policies, err := bridge.ParseOverflowPolicies("")
+	require.NoError(t, err)
+	assert.Equal(t, bridge.DefaultOverflowPolicies, policies)
+
+	policies, err = bridge.ParseOverflowPolicies("DurableMetadataUpdates=drop_oldest, ssl=do_not_queue")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bridge.OverflowPolicy{
+		"DurableMetadataUpdates": bridge.DropOldest,
+		"ssl":                    bridge.DoNotQueue,
+	}, policies)
+
+	_, err = bridge.ParseOverflowPolicies("ssl=keep_everything")
+	assert.Error(t, err)
+	_, err = bridge.ParseOverflowPolicies("ssl")
+	assert.Error(t, err)
+}
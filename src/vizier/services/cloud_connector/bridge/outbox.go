@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bridge
+
+// maxTrackedOutboxMsgs is the number of delivered outbox messages that are remembered to drop duplicates.
+const maxTrackedOutboxMsgs = 4096
+
+// seqIDSet holds the most recently added sequence numbers, up to a fixed capacity.
+type seqIDSet struct {
+	ids   map[int64]bool
+	order []int64
+	next  int
+}
+
+func newSeqIDSet(capacity int) *seqIDSet {
+	return &seqIDSet{
+		ids:   make(map[int64]bool, capacity),
+		order: make([]int64, 0, capacity),
+	}
+}
+
+func (s *seqIDSet) contains(id int64) bool {
+	return s.ids[id]
+}
+
+// add adds the sequence number to the set, evicting the oldest one if the set is full.
+func (s *seqIDSet) add(id int64) {
+	if s.ids[id] {
+		return
+	}
+	if len(s.order) < cap(s.order) {
+		s.order = append(s.order, id)
+	} else {
+		delete(s.ids, s.order[s.next])
+		s.order[s.next] = id
+		s.next = (s.next + 1) % len(s.order)
+	}
+	s.ids[id] = true
+}
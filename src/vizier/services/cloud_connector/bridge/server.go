@@ -45,6 +45,8 @@ import (
 	"px.dev/pixie/src/api/proto/vizierpb"
 	"px.dev/pixie/src/cloud/vzconn/vzconnpb"
 	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/shared/bridgeutils"
+	"px.dev/pixie/src/shared/cvmsgs"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	vzstatus "px.dev/pixie/src/shared/status"
 	"px.dev/pixie/src/utils"
@@ -128,11 +130,17 @@ const (
 	registrationTimeout           = 30 * time.Second
 	passthroughReplySubjectPrefix = "v2c.reply-"
 	vizStatusCheckFailInterval    = 10 * time.Second
+	// The number of messages the cloud may send before it has to wait for more credits. This is well below
+	// the capacity of grpcInCh, so that reading from the stream never blocks.
+	c2vCredits = 1024
 )
 
 // ErrRegistrationTimeout is the registration timeout error.
 var ErrRegistrationTimeout = errors.New("Registration timeout")
 
+// ErrVersionRolledBack is the error returned when an update to a version that the operator rolled back is requested.
+var ErrVersionRolledBack = errors.New("Version was rolled back after a failed update")
+
 const upgradeJobName = "vizier-upgrade-job"
 
 // VizierInfo fetches information about Vizier.
@@ -147,6 +155,7 @@ type VizierInfo interface {
 	DeleteJob(string) error
 	GetJob(string) (*batchv1.Job, error)
 	GetClusterUID() (string, error)
+	GetVizierNamespace() string
 	UpdateClusterID(string) error
 	UpdateClusterName(string) error
 	UpdateClusterIDAnnotation(string) error
@@ -196,6 +205,9 @@ type Bridge struct {
 	grpcInCh  chan *vzconnpb.C2VBridgeMessage
 	// Explicitly prioritize passthrough traffic to prevent script failure under load.
 	ptOutCh chan *vzconnpb.V2CBridgeMessage
+	// Control messages, such as heartbeats and acks, are sent ahead of all other traffic and aren't subject
+	// to flow control.
+	ctrlOutCh chan *vzconnpb.V2CBridgeMessage
 	// This tracks the message we are trying to send, but has not been sent yet.
 	pendingGRPCOutMsg *vzconnpb.V2CBridgeMessage
 
@@ -207,10 +219,35 @@ type Bridge struct {
 	updateFailed  bool         // True if an update has failed (sticky).
 
 	droppedMessagesBeforeResume int64 // Number of messages dropped before successful resume.
+
+	// The outbox messages which were recently delivered. The cloud resends messages until they are
+	// acknowledged, so a message which is received again is only acknowledged again.
+	deliveredOutboxMsgs *seqIDSet
+
+	// The settings negotiated with the cloud for the current stream.
+	compression atomic.Value // The compression algorithm for the messages we send.
+	sendWindow  *bridgeutils.SendWindow
+	recvWindow  *bridgeutils.ReceiveWindow
+
+	// Persists the messages published while the stream is down, so that they are sent once it is back up.
+	// Messages are dropped while disconnected if this is nil.
+	offlineQueue *OfflineQueue
+	// Hands the oldest queued message to the GRPC writer, which acks its sequence ID on offlineSentCh
+	// once it has been sent. Queued messages are only removed from the queue after they are sent.
+	offlineOutCh  chan *offlineQueueMsg
+	offlineSentCh chan int64
+	// Stops moving messages from NATS to the offline queue. It is set while the stream is not bridging.
+	stopQueueing func()
+}
+
+// offlineQueueMsg is a message from the offline queue, along with its sequence ID in the queue.
+type offlineQueueMsg struct {
+	seqID int64
+	msg   *vzconnpb.V2CBridgeMessage
 }
 
 // New creates a cloud connector to cloud bridge.
-func New(vizierID uuid.UUID, assignedClusterName string, jwtSigningKey string, deployKey string, sessionID int64, vzClient vzconnpb.VZConnServiceClient, vzInfo VizierInfo, vzOperator VizierOperatorInfo, nc *nats.Conn, checker VizierHealthChecker) *Bridge {
+func New(vizierID uuid.UUID, assignedClusterName string, jwtSigningKey string, deployKey string, sessionID int64, vzClient vzconnpb.VZConnServiceClient, vzInfo VizierInfo, vzOperator VizierOperatorInfo, nc *nats.Conn, checker VizierHealthChecker, offlineQueue *OfflineQueue) *Bridge {
 	return &Bridge{
 		vizierID:            vizierID,
 		assignedClusterName: assignedClusterName,
@@ -228,10 +265,18 @@ func New(vizierID uuid.UUID, assignedClusterName string, jwtSigningKey string, d
 		ptOutCh:           make(chan *vzconnpb.V2CBridgeMessage, 5000),
 		grpcOutCh:         make(chan *vzconnpb.V2CBridgeMessage, 5000),
 		grpcInCh:          make(chan *vzconnpb.C2VBridgeMessage, 5000),
+		ctrlOutCh:         make(chan *vzconnpb.V2CBridgeMessage, 256),
 		pendingGRPCOutMsg: nil,
 		quitCh:            make(chan bool),
 		wg:                sync.WaitGroup{},
 		wdWg:              sync.WaitGroup{},
+
+		deliveredOutboxMsgs: newSeqIDSet(maxTrackedOutboxMsgs),
+		sendWindow:          bridgeutils.NewSendWindow(0),
+		recvWindow:          bridgeutils.NewReceiveWindow(0),
+		offlineQueue:        offlineQueue,
+		offlineOutCh:        make(chan *offlineQueueMsg),
+		offlineSentCh:       make(chan int64),
 	}
 }
 
@@ -281,8 +326,9 @@ func (s *Bridge) RegisterDeployment() error {
 		return err
 	}
 	resp, err := s.vzConnClient.RegisterVizierDeployment(ctx, &vzconnpb.RegisterVizierDeploymentRequest{
-		K8sClusterUID:  clusterInfo.ClusterUID,
-		K8sClusterName: clusterInfo.ClusterName,
+		K8sClusterUID:   clusterInfo.ClusterUID,
+		K8sClusterName:  clusterInfo.ClusterName,
+		VizierNamespace: s.vzInfo.GetVizierNamespace(),
 	})
 	if err != nil {
 		return err
@@ -371,6 +417,10 @@ func (s *Bridge) RunStream() {
 		}
 	}()
 
+	// Messages published before the first stream is up, and between streams, are queued.
+	s.resumeOfflineQueueing()
+	defer s.pauseOfflineQueueing()
+
 	// Check if there is an existing update job. If so, then set the status to "UPDATING".
 	_, err = s.vzInfo.GetJob(upgradeJobName)
 	if err != nil && !k8sErrors.IsNotFound(err) {
@@ -425,6 +475,11 @@ func (s *Bridge) handleUpdateMessage(msg *types.Any) error {
 	// trigger the update through the CRD. Otherwise, we fallback to the
 	// update job.
 	updating, err := s.vzOperator.UpdateCRDVizierVersion(pb.Version)
+	if err == ErrVersionRolledBack {
+		// Redeploying the version would only fail and be rolled back again.
+		log.WithField("version", pb.Version).Warn("Not updating to a version that was rolled back")
+		return s.sendUpdateResponse(false)
+	}
 	if err == nil {
 		if updating {
 			s.updateRunning.Store(true)
@@ -463,8 +518,12 @@ func (s *Bridge) handleUpdateMessage(msg *types.Any) error {
 	}
 
 	// Send response message to indicate update job has started.
+	return s.sendUpdateResponse(true)
+}
+
+func (s *Bridge) sendUpdateResponse(updateStarted bool) error {
 	m := cvmsgspb.UpdateOrInstallVizierResponse{
-		UpdateStarted: true,
+		UpdateStarted: updateStarted,
 	}
 	reqAnyMsg, err := types.MarshalAny(&m)
 	if err != nil {
@@ -617,10 +676,13 @@ func (s *Bridge) doRegistrationHandshake(stream vzconnpb.VZConnService_NATSBridg
 	}
 	// Send over a registration request and wait for ACK.
 	regReq := &cvmsgspb.RegisterVizierRequest{
-		VizierID:    utils.ProtoFromUUID(s.vizierID),
-		JwtKey:      s.jwtSigningKey,
-		Address:     addr,
-		ClusterInfo: clusterInfo,
+		VizierID:             utils.ProtoFromUUID(s.vizierID),
+		JwtKey:               s.jwtSigningKey,
+		Address:              addr,
+		ClusterInfo:          clusterInfo,
+		C2VOutboxAcks:        true,
+		SupportedCompression: bridgeutils.SupportedCompression,
+		C2VCredits:           c2vCredits,
 	}
 
 	err = s.publishBridgeSync(stream, "register", regReq)
@@ -630,6 +692,9 @@ func (s *Bridge) doRegistrationHandshake(stream vzconnpb.VZConnService_NATSBridg
 
 	for {
 		select {
+		case <-s.quitCh:
+			// The bridge is being stopped, which StartStream checks for once registration returns.
+			return nil
 		case <-stream.Context().Done():
 			return errors.New("registration unsuccessful: stream closed before complete")
 		case <-time.After(registrationTimeout):
@@ -663,6 +728,16 @@ func (s *Bridge) doRegistrationHandshake(stream vzconnpb.VZConnService_NATSBridg
 				}
 				s.assignedClusterName = registerAck.VizierName
 			}
+
+			s.compression.Store(registerAck.Compression)
+			// Clouds which don't grant credits for our messages don't expect credits for theirs either.
+			if registerAck.V2CCredits > 0 {
+				s.sendWindow.Reset(registerAck.V2CCredits)
+				s.recvWindow = bridgeutils.NewReceiveWindow(c2vCredits)
+			} else {
+				s.sendWindow.Reset(0)
+				s.recvWindow = bridgeutils.NewReceiveWindow(0)
+			}
 			return nil
 		}
 	}
@@ -679,6 +754,10 @@ func (s *Bridge) StartStream() error {
 	done := make(chan bool)
 	defer close(done)
 
+	// Messages published until the registration completes are persisted, instead of piling up in NATS.
+	// RunStream has usually started queueing already, when the previous stream ended.
+	s.resumeOfflineQueueing()
+
 	// We backoff-retry the registration logic but immediately fail the core-logic.
 	backOffOpts := backoff.NewExponentialBackOff()
 	backOffOpts.InitialInterval = 30 * time.Second
@@ -692,6 +771,10 @@ func (s *Bridge) StartStream() error {
 		}
 
 		log.Trace("Start Vizier registration")
+		// Until the cloud acks the registration, messages are sent the way every cloud supports.
+		s.compression.Store(cvmsgspb.BRIDGE_COMPRESSION_NONE)
+		s.sendWindow.Reset(0)
+
 		var err error
 		var ctx context.Context
 		ctx, cancel = context.WithCancel(context.Background())
@@ -732,8 +815,12 @@ func (s *Bridge) StartStream() error {
 	default:
 	}
 
+	// HandleNATSBridging reads NATS from here on, sending queued messages ahead of new ones. Once it
+	// returns, queueing resumes right away so that nothing published until the next stream is lost.
+	s.pauseOfflineQueueing()
 	s.wg.Add(1)
 	err = s.HandleNATSBridging(stream, done)
+	s.resumeOfflineQueueing()
 	if err != nil {
 		log.WithError(err).Error("Error inside NATS bridge")
 		return err
@@ -770,6 +857,12 @@ func (s *Bridge) startStreamGRPCReader(stream vzconnpb.VZConnService_NATSBridgeC
 				log.WithError(err).Trace("Got a stream read error")
 				return
 			}
+			msg.Msg, err = bridgeutils.DecompressMsg(msg.Msg, msg.Compression)
+			if err != nil {
+				log.WithError(err).Error("Failed to decompress message")
+				return
+			}
+			msg.Compression = cvmsgspb.BRIDGE_COMPRESSION_NONE
 			s.grpcInCh <- msg
 		}
 	}
@@ -792,6 +885,7 @@ func (s *Bridge) startStreamGRPCWriter(stream vzconnpb.VZConnService_NATSBridgeC
 		}
 
 		if m != nil {
+			m = s.compressMsg(m)
 			// Write message to GRPC if it exists.
 			err := stream.Send(m)
 			if err != nil {
@@ -803,11 +897,56 @@ func (s *Bridge) startStreamGRPCWriter(stream vzconnpb.VZConnService_NATSBridgeC
 		}
 	}
 
+	// Messages other than control messages use up the credits which the cloud has granted.
+	sendDataMsg := func(m *vzconnpb.V2CBridgeMessage) {
+		s.sendWindow.Consume()
+		sendMsg(m)
+	}
+
+	// Queued messages are removed from the offline queue only once they have been sent. A message which
+	// fails to send stays queued, rather than pending, and is sent again on the next stream.
+	sendQueuedMsg := func(m *offlineQueueMsg) {
+		s.sendWindow.Consume()
+		err := stream.Send(s.compressMsg(m.msg))
+		if err != nil {
+			log.WithError(err).Error("Error sending queued GRPC message")
+			return
+		}
+		err = s.offlineQueue.Remove(m.seqID)
+		if err != nil {
+			log.WithError(err).Error("Failed to remove message from offline queue")
+		}
+		select {
+		case s.offlineSentCh <- m.seqID:
+		case <-done:
+		case <-s.quitCh:
+		}
+	}
+
 	for {
 		// If there's a pending message, send it.
 		sendMsg(nil)
 
-		// Try to send PT traffic first.
+		// Control messages are sent ahead of everything else.
+		select {
+		case m := <-s.ctrlOutCh:
+			sendMsg(m)
+			continue
+		default:
+		}
+
+		var ptOutCh, grpcOutCh chan *vzconnpb.V2CBridgeMessage
+		var offlineOutCh chan *offlineQueueMsg
+		if s.sendWindow.Available() {
+			ptOutCh = s.ptOutCh
+			grpcOutCh = s.grpcOutCh
+			// Messages bridged before the stream went down are older than the queued messages.
+			if len(s.grpcOutCh) == 0 {
+				offlineOutCh = s.offlineOutCh
+			}
+		}
+
+		// Try to send PT traffic next.
 		select {
 		case <-s.quitCh:
 			return
@@ -822,8 +961,8 @@ func (s *Bridge) startStreamGRPCWriter(stream vzconnpb.VZConnService_NATSBridgeC
 			}
 			// Quit called.
 			return
-		case m := <-s.ptOutCh:
-			sendMsg(m)
+		case m := <-ptOutCh:
+			sendDataMsg(m)
 			continue
 		default:
 		}
@@ -842,11 +981,92 @@ func (s *Bridge) startStreamGRPCWriter(stream vzconnpb.VZConnService_NATSBridgeC
 			}
 			// Quit called.
 			return
-		case m := <-s.ptOutCh:
-			sendMsg(m)
-		case m := <-s.grpcOutCh:
+		case <-s.sendWindow.Granted():
+			// More messages may be sent now.
+		case m := <-s.ctrlOutCh:
 			sendMsg(m)
+		case m := <-ptOutCh:
+			sendDataMsg(m)
+		case m := <-grpcOutCh:
+			sendDataMsg(m)
+		case m := <-offlineOutCh:
+			sendQueuedMsg(m)
+		}
+	}
+}
+
+func (s *Bridge) compressMsg(m *vzconnpb.V2CBridgeMessage) *vzconnpb.V2CBridgeMessage {
+	out := *m
+	out.Msg, out.Compression = bridgeutils.CompressMsg(m.Msg, s.compression.Load().(cvmsgspb.BridgeCompression))
+	return &out
+}
+
+// resumeOfflineQueueing starts moving messages from NATS to the offline queue, if it isn't already.
+func (s *Bridge) resumeOfflineQueueing() {
+	if s.stopQueueing == nil {
+		s.stopQueueing = s.startOfflineQueueing()
+	}
+}
+
+// pauseOfflineQueueing stops moving messages from NATS to the offline queue, so that they can be bridged.
+func (s *Bridge) pauseOfflineQueueing() {
+	if s.stopQueueing != nil {
+		s.stopQueueing()
+		s.stopQueueing = nil
+	}
+}
+
+// startOfflineQueueing moves messages from NATS to the offline queue until the returned func is called.
+func (s *Bridge) startOfflineQueueing() func() {
+	if s.offlineQueue == nil {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-s.quitCh:
+				return
+			case data := <-s.natsCh:
+				s.queueOfflineMessage(data)
+			}
 		}
+	}()
+
+	return func() {
+		close(stop)
+		wg.Wait()
+	}
+}
+
+func (s *Bridge) queueOfflineMessage(data *nats.Msg) {
+	// The passthrough request that this replies to does not survive the stream restarting.
+	if strings.HasPrefix(data.Subject, passthroughReplySubjectPrefix) {
+		return
+	}
+	if !strings.HasPrefix(data.Subject, messagebus.V2CTopic("")) {
+		log.WithField("subject", data.Subject).Error("Invalid subject sent to nats channel")
+		return
+	}
+
+	v2cMsg, topic, err := s.parseV2CNatsMsg(data)
+	if err != nil {
+		log.WithError(err).Error("Failed to parse message")
+		return
+	}
+	_, err = s.offlineQueue.Push(&vzconnpb.V2CBridgeMessage{
+		Topic:     topic,
+		SessionId: s.sessionID,
+		Msg:       v2cMsg.Msg,
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to queue message")
 	}
 }
 
@@ -880,7 +1100,33 @@ func (s *Bridge) HandleNATSBridging(stream vzconnpb.VZConnService_NATSBridgeClie
 	log.Info("Starting NATS bridge.")
 	hbChan := s.generateHeartbeats(done)
 
+	// The oldest message in the offline queue, which is sent ahead of new messages. It stays in the queue
+	// until the GRPC writer has sent it, so that it is sent again on the next stream if this one fails.
+	var queuedSeqID int64
+	var queuedMsg *vzconnpb.V2CBridgeMessage
+	// Whether queuedMsg has been handed to the GRPC writer.
+	queuedMsgSending := false
+
 	for {
+		var drainCh chan *offlineQueueMsg
+		if s.offlineQueue != nil {
+			if queuedMsg == nil && s.offlineQueue.Len() > 0 {
+				var err error
+				queuedSeqID, queuedMsg, err = s.offlineQueue.Peek()
+				if err != nil {
+					log.WithError(err).Error("Failed to read from offline queue")
+					return err
+				}
+				if queuedMsg != nil {
+					// The message may have been queued by a previous session of this connector.
+					queuedMsg.SessionId = s.sessionID
+				}
+			}
+			if queuedMsg != nil && !queuedMsgSending {
+				drainCh = s.offlineOutCh
+			}
+		}
+
 		select {
 		case <-s.quitCh:
 			return nil
@@ -906,76 +1152,132 @@ func (s *Bridge) HandleNATSBridging(stream vzconnpb.VZConnService_NATSBridgeClie
 				if err != nil {
 					return err
 				}
+			} else if queuedMsg != nil {
+				// Queue behind the messages from the outage, to keep the messages in order.
+				s.queueOfflineMessage(data)
 			} else {
 				err = s.publishBridgeCh(topic, v2cMsg.Msg)
 				if err != nil {
 					return err
 				}
 			}
+		case drainCh <- &offlineQueueMsg{seqID: queuedSeqID, msg: queuedMsg}:
+			queuedMsgSending = true
+		case seqID := <-s.offlineSentCh:
+			if queuedMsg != nil && seqID == queuedSeqID {
+				queuedMsg = nil
+				queuedMsgSending = false
+			}
 		case bridgeMsg := <-s.grpcInCh:
 			if bridgeMsg == nil {
 				return nil
 			}
 
-			if bridgeMsg.Topic == "VizierUpdate" {
-				err := s.handleUpdateMessage(bridgeMsg.Msg)
-				if err != nil && !k8sErrors.IsAlreadyExists(err) {
-					log.WithError(err).Error("Failed to launch vizier update job")
-				}
+			if bridgeMsg.Topic == cvmsgs.BridgeCreditsChannel {
+				s.handleCreditGrant(bridgeMsg)
 				continue
 			}
-
-			if bridgeMsg.Topic == "VizierPassthroughRequest" {
-				pb := &cvmsgspb.C2VAPIStreamRequest{}
-				err := types.UnmarshalAny(bridgeMsg.Msg, pb)
+			if err := s.deliverC2VBridgeMessage(bridgeMsg); err != nil {
+				return err
+			}
+			if bridgeutils.IsControlTopic(bridgeMsg.Topic) {
+				continue
+			}
+			if credits := s.recvWindow.Processed(); credits > 0 {
+				err := s.publishProtoToBridgeCh(cvmsgs.BridgeCreditsChannel, &cvmsgspb.BridgeCreditGrant{Credits: credits})
 				if err != nil {
-					log.WithError(err).Error("Could not unmarshal c2v stream req message")
 					return err
 				}
-				switch pb.Msg.(type) {
-				case *cvmsgspb.C2VAPIStreamRequest_DebugLogReq:
-					err := s.handleDebugLogRequest(pb.RequestID, pb.GetDebugLogReq())
-					if err != nil {
-						log.WithError(err).Error("Could not handle debug log request")
-					}
-					continue
-				case *cvmsgspb.C2VAPIStreamRequest_DebugPodsReq:
-					err := s.handleDebugPodsRequest(pb.RequestID, pb.GetDebugPodsReq())
-					if err != nil {
-						log.WithError(err).Error("Could not handle debug pods request")
-					}
-					continue
-				default:
-				}
-			}
-
-			topic := messagebus.C2VTopic(bridgeMsg.Topic)
-
-			natsMsg := &cvmsgspb.C2VMessage{
-				VizierID: s.vizierID.String(),
-				Msg:      bridgeMsg.Msg,
 			}
-			b, err := natsMsg.Marshal()
+		case hbMsg := <-hbChan:
+			err := s.publishProtoToBridgeCh(HeartbeatTopic, hbMsg)
 			if err != nil {
-				log.WithError(err).Error("Failed to marshal")
 				return err
 			}
+		case <-stream.Context().Done():
+			log.Info("Stream has been closed, shutting down grpc readers")
+			return nil
+		}
+	}
+}
+
+// deliverC2VBridgeMessage handles a message from the cloud, and acknowledges it if it was sent from the outbox.
+func (s *Bridge) deliverC2VBridgeMessage(bridgeMsg *vzconnpb.C2VBridgeMessage) error {
+	if bridgeMsg.SeqID != 0 && s.deliveredOutboxMsgs.contains(bridgeMsg.SeqID) {
+		// The message was already delivered, but the cloud did not get the ack.
+		return s.ackOutboxMessage(bridgeMsg.SeqID)
+	}
+	if err := s.handleC2VBridgeMessage(bridgeMsg); err != nil {
+		return err
+	}
+	if bridgeMsg.SeqID != 0 {
+		s.deliveredOutboxMsgs.add(bridgeMsg.SeqID)
+		return s.ackOutboxMessage(bridgeMsg.SeqID)
+	}
+	return nil
+}
+
+func (s *Bridge) handleCreditGrant(bridgeMsg *vzconnpb.C2VBridgeMessage) {
+	grant := &cvmsgspb.BridgeCreditGrant{}
+	if err := types.UnmarshalAny(bridgeMsg.Msg, grant); err != nil {
+		log.WithError(err).Error("Received malformed credit grant")
+		return
+	}
+	s.sendWindow.Grant(grant.Credits)
+}
 
-			err = s.nc.Publish(topic, b)
+// handleC2VBridgeMessage handles a message from the cloud, either directly or by publishing it on NATS.
+func (s *Bridge) handleC2VBridgeMessage(bridgeMsg *vzconnpb.C2VBridgeMessage) error {
+	if bridgeMsg.Topic == "VizierUpdate" {
+		err := s.handleUpdateMessage(bridgeMsg.Msg)
+		if err != nil && !k8sErrors.IsAlreadyExists(err) {
+			log.WithError(err).Error("Failed to launch vizier update job")
+		}
+		return nil
+	}
+
+	if bridgeMsg.Topic == "VizierPassthroughRequest" {
+		pb := &cvmsgspb.C2VAPIStreamRequest{}
+		err := types.UnmarshalAny(bridgeMsg.Msg, pb)
+		if err != nil {
+			log.WithError(err).Error("Could not unmarshal c2v stream req message")
+			return err
+		}
+		switch pb.Msg.(type) {
+		case *cvmsgspb.C2VAPIStreamRequest_DebugLogReq:
+			err := s.handleDebugLogRequest(pb.RequestID, pb.GetDebugLogReq())
 			if err != nil {
-				log.WithError(err).Error("Failed to publish")
-				return err
+				log.WithError(err).Error("Could not handle debug log request")
 			}
-		case hbMsg := <-hbChan:
-			err := s.publishProtoToBridgeCh(HeartbeatTopic, hbMsg)
+			return nil
+		case *cvmsgspb.C2VAPIStreamRequest_DebugPodsReq:
+			err := s.handleDebugPodsRequest(pb.RequestID, pb.GetDebugPodsReq())
 			if err != nil {
-				return err
+				log.WithError(err).Error("Could not handle debug pods request")
 			}
-		case <-stream.Context().Done():
-			log.Info("Stream has been closed, shutting down grpc readers")
 			return nil
+		default:
 		}
 	}
+
+	topic := messagebus.C2VTopic(bridgeMsg.Topic)
+
+	natsMsg := &cvmsgspb.C2VMessage{
+		VizierID: s.vizierID.String(),
+		Msg:      bridgeMsg.Msg,
+	}
+	b, err := natsMsg.Marshal()
+	if err != nil {
+		log.WithError(err).Error("Failed to marshal")
+		return err
+	}
+
+	err = s.nc.Publish(topic, b)
+	if err != nil {
+		log.WithError(err).Error("Failed to publish")
+		return err
+	}
+	return nil
 }
 
 // Stop terminates the server. Don't reuse this server object after stop has been called.
@@ -993,6 +1295,11 @@ func (s *Bridge) publishBridgeCh(topic string, msg *types.Any) error {
 		Msg:       msg,
 	}
 
+	if bridgeutils.IsControlTopic(topic) {
+		s.ctrlOutCh <- wrappedReq
+		return nil
+	}
+
 	// Don't stall the queue for regular message.
 	select {
 	case s.grpcOutCh <- wrappedReq:
@@ -1023,6 +1330,12 @@ func (s *Bridge) publishPTBridgeCh(topic string, msg *types.Any) error {
 	return nil
 }
 
+func (s *Bridge) ackOutboxMessage(seqID int64) error {
+	return s.publishProtoToBridgeCh(cvmsgs.C2VOutboxAckChannel, &cvmsgspb.C2VOutboxAck{
+		SeqIDs: []int64{seqID},
+	})
+}
+
 func (s *Bridge) publishProtoToBridgeCh(topic string, msg proto.Message) error {
 	anyMsg, err := types.MarshalAny(msg)
 	if err != nil {
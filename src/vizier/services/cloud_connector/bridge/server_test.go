@@ -23,10 +23,13 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/proto"
 	"github.com/gogo/protobuf/types"
@@ -41,11 +44,13 @@ import (
 	"px.dev/pixie/src/api/proto/vizierpb"
 	"px.dev/pixie/src/cloud/vzconn/vzconnpb"
 	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/shared/bridgeutils"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/k8s/metadatapb"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/utils/testingutils"
 	"px.dev/pixie/src/vizier/services/cloud_connector/bridge"
+	"px.dev/pixie/src/vizier/utils/datastore/pebbledb"
 )
 
 const bufSize = 1024 * 1024
@@ -72,9 +77,38 @@ func marshalAndSend(srv vzconnpb.VZConnService_NATSBridgeServer, topic string, m
 
 func handleMsg(srv vzconnpb.VZConnService_NATSBridgeServer, msg *vzconnpb.V2CBridgeMessage) error {
 	if msg.Topic == "register" {
-		return marshalAndSend(srv, "registerAck", &cvmsgspb.RegisterVizierAck{Status: cvmsgspb.ST_OK})
+		regReq := &cvmsgspb.RegisterVizierRequest{}
+		if err := types.UnmarshalAny(msg.Msg, regReq); err != nil {
+			return err
+		}
+		return marshalAndSend(srv, "registerAck", &cvmsgspb.RegisterVizierAck{
+			Status:      cvmsgspb.ST_OK,
+			Compression: bridgeutils.NegotiateCompression(regReq.SupportedCompression),
+		})
+	}
+	if msg.Topic == "randomtopicCompressed" {
+		compressed, compression := bridgeutils.CompressMsg(msg.Msg, cvmsgspb.BRIDGE_COMPRESSION_ZSTD)
+		return srv.Send(&vzconnpb.C2VBridgeMessage{
+			Topic:       "randomtopicCompressedMsg",
+			Msg:         compressed,
+			Compression: compression,
+		})
+	}
+	if msg.Topic == "randomtopic" || msg.Topic == "c2vOutboxAck" {
+		return nil
 	}
-	if msg.Topic == "randomtopic" {
+	if msg.Topic == "randomtopicOutbox" {
+		// Send the message twice, as the cloud does when an ack is lost.
+		for i := 0; i < 2; i++ {
+			err := srv.Send(&vzconnpb.C2VBridgeMessage{
+				Topic: "randomtopicOutboxMsg",
+				Msg:   msg.Msg,
+				SeqID: 5,
+			})
+			if err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 	if msg.Topic == "randomtopicNeedsResponse" {
@@ -119,7 +153,12 @@ func (fs *FakeVZConnServer) NATSBridge(srv vzconnpb.VZConnService_NATSBridgeServ
 			// Ignore heartbeats
 			if msg.Topic != bridge.HeartbeatTopic {
 				fs.msgQ = append(fs.msgQ, msg)
-				err = handleMsg(srv, msg)
+				decompressed := *msg
+				decompressed.Msg, err = bridgeutils.DecompressMsg(msg.Msg, msg.Compression)
+				if err != nil {
+					return err
+				}
+				err = handleMsg(srv, &decompressed)
 				if err != nil {
 					fs.t.Errorf("Error marshalling: %+v", err)
 					return err
@@ -216,6 +255,10 @@ func (f *FakeVZInfo) GetClusterUID() (string, error) {
 	return "fake-uid", nil
 }
 
+func (f *FakeVZInfo) GetVizierNamespace() string {
+	return ""
+}
+
 func (f *FakeVZInfo) UpdateClusterID(string) error {
 	return nil
 }
@@ -320,7 +363,7 @@ func TestNATSGRPCBridgeTest_CorrectRegistrationFlow(t *testing.T) {
 	ts.wg.Add(1)
 
 	sessionID := time.Now().UnixNano()
-	b := bridge.New(ts.vzID, "", ts.jwt, "", sessionID, ts.vzClient, makeFakeVZInfo("foobar", 123), &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{})
+	b := bridge.New(ts.vzID, "", ts.jwt, "", sessionID, ts.vzClient, makeFakeVZInfo("foobar", 123), &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{}, nil)
 	defer b.Stop()
 	go b.RunStream()
 
@@ -355,7 +398,7 @@ func TestNATSGRPCBridgeTest_TestOutboundNATSMessage(t *testing.T) {
 	ts.wg.Add(1)
 
 	sessionID := time.Now().UnixNano()
-	b := bridge.New(ts.vzID, "", ts.jwt, "", sessionID, ts.vzClient, makeFakeVZInfo("foobar", 123), &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{})
+	b := bridge.New(ts.vzID, "", ts.jwt, "", sessionID, ts.vzClient, makeFakeVZInfo("foobar", 123), &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{}, nil)
 	defer func() {
 		b.Stop()
 	}()
@@ -413,7 +456,7 @@ func TestNATSGRPCBridgeTest_TestInboundNATSMessage(t *testing.T) {
 	ts.wg.Add(1)
 
 	sessionID := time.Now().UnixNano()
-	b := bridge.New(ts.vzID, "", ts.jwt, "", sessionID, ts.vzClient, makeFakeVZInfo("foobar", 123), &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{})
+	b := bridge.New(ts.vzID, "", ts.jwt, "", sessionID, ts.vzClient, makeFakeVZInfo("foobar", 123), &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{}, nil)
 	defer b.Stop()
 
 	go b.RunStream()
@@ -487,7 +530,7 @@ func TestNATSGRPCBridgeTest_TestRegisterDeployment(t *testing.T) {
 
 	vzInfo := makeFakeVZInfo("foo", 123)
 	sessionID := time.Now().UnixNano()
-	b := bridge.New(vzID, "", ts.jwt, "", sessionID, ts.vzClient, vzInfo, &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{})
+	b := bridge.New(vzID, "", ts.jwt, "", sessionID, ts.vzClient, vzInfo, &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{}, nil)
 	defer b.Stop()
 
 	go b.RunStream()
@@ -507,3 +550,229 @@ func TestNATSGRPCBridgeTest_TestRegisterDeployment(t *testing.T) {
 		assert.Equal(t, "fakeName", vzInfo.lastClusterName)
 	}()
 }
+
+// Test a message that is sent by VZConn from the vizier's outbox, and should be acknowledged.
+func TestNATSGRPCBridgeTest_TestInboundOutboxMessage(t *testing.T) {
+	ts, cleanup := makeTestState(t)
+	defer cleanup(t)
+
+	// wait for registration
+	ts.wg.Add(1)
+
+	sessionID := time.Now().UnixNano()
+	b := bridge.New(ts.vzID, "", ts.jwt, "", sessionID, ts.vzClient, makeFakeVZInfo("foobar", 123), &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{}, nil)
+	defer b.Stop()
+
+	go b.RunStream()
+	ts.wg.Wait()
+
+	registerMsg := &cvmsgspb.RegisterVizierRequest{}
+	err := types.UnmarshalAny(ts.vzServer.msgQ[0].Msg, registerMsg)
+	require.NoError(t, err)
+	assert.True(t, registerMsg.C2VOutboxAcks)
+
+	natsCh := make(chan *nats.Msg, 10)
+	natsSub, err := ts.nats.ChanSubscribe("c2v.randomtopicOutboxMsg", natsCh)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, natsSub.Unsubscribe())
+	}()
+
+	// The request, and an ack for each of the two copies of the outbox message.
+	ts.wg.Add(3)
+	subany, err := types.MarshalAny(&cvmsgspb.VLogMessage{Data: []byte("Foobar")})
+	require.NoError(t, err)
+	serializedBytes, err := (&cvmsgspb.V2CMessage{
+		VizierID:  ts.vzID.String(),
+		SessionId: sessionID,
+		Msg:       subany,
+	}).Marshal()
+	require.NoError(t, err)
+	err = ts.nats.PublishMsg(&nats.Msg{Subject: "v2c.randomtopicOutbox", Data: serializedBytes})
+	require.NoError(t, err)
+
+	ts.wg.Wait()
+	require.Equal(t, 4, len(ts.vzServer.msgQ))
+	for _, msg := range ts.vzServer.msgQ[2:] {
+		assert.Equal(t, "c2vOutboxAck", msg.Topic)
+		ack := &cvmsgspb.C2VOutboxAck{}
+		require.NoError(t, types.UnmarshalAny(msg.Msg, ack))
+		assert.Equal(t, []int64{5}, ack.SeqIDs)
+	}
+
+	// The message is only published once.
+	<-natsCh
+	select {
+	case <-natsCh:
+		t.Fatal("Outbox message was delivered twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNATSGRPCBridgeTest_TestCompressedMessages(t *testing.T) {
+	ts, cleanup := makeTestState(t)
+	defer cleanup(t)
+
+	// wait for registration
+	ts.wg.Add(1)
+
+	sessionID := time.Now().UnixNano()
+	b := bridge.New(ts.vzID, "", ts.jwt, "", sessionID, ts.vzClient, makeFakeVZInfo("foobar", 123), &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{}, nil)
+	defer b.Stop()
+
+	go b.RunStream()
+	ts.wg.Wait()
+
+	registerMsg := &cvmsgspb.RegisterVizierRequest{}
+	err := types.UnmarshalAny(ts.vzServer.msgQ[0].Msg, registerMsg)
+	require.NoError(t, err)
+	assert.Equal(t, bridgeutils.SupportedCompression, registerMsg.SupportedCompression)
+	assert.Greater(t, registerMsg.C2VCredits, int64(0))
+
+	natsCh := make(chan *nats.Msg, 10)
+	natsSub, err := ts.nats.ChanSubscribe("c2v.randomtopicCompressedMsg", natsCh)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, natsSub.Unsubscribe())
+	}()
+
+	ts.wg.Add(1)
+	subany, err := types.MarshalAny(&cvmsgspb.VLogMessage{Data: []byte(strings.Repeat("Foobar", 1000))})
+	require.NoError(t, err)
+	serializedBytes, err := (&cvmsgspb.V2CMessage{
+		VizierID:  ts.vzID.String(),
+		SessionId: sessionID,
+		Msg:       subany,
+	}).Marshal()
+	require.NoError(t, err)
+	err = ts.nats.PublishMsg(&nats.Msg{Subject: "v2c.randomtopicCompressed", Data: serializedBytes})
+	require.NoError(t, err)
+	ts.wg.Wait()
+
+	// Large messages are compressed with the negotiated algorithm.
+	sent := ts.vzServer.msgQ[1]
+	assert.Equal(t, "randomtopicCompressed", sent.Topic)
+	assert.Equal(t, cvmsgspb.BRIDGE_COMPRESSION_ZSTD, sent.Compression)
+	assert.Less(t, len(sent.Msg.Value), len(subany.Value))
+
+	// Compressed messages from the cloud are published decompressed.
+	natsMsg := <-natsCh
+	c2vMsg := &cvmsgspb.C2VMessage{}
+	require.NoError(t, c2vMsg.Unmarshal(natsMsg.Data))
+	assert.Equal(t, subany, c2vMsg.Msg)
+}
+
+func TestNATSGRPCBridgeTest_TestOfflineQueueDrain(t *testing.T) {
+	ts, cleanup := makeTestState(t)
+	defer cleanup(t)
+
+	db, err := pebble.Open("queue", &pebble.Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	ds := pebbledb.New(db, 3*time.Second)
+	defer ds.Close()
+	q, err := bridge.NewOfflineQueue(ds, 100, 1024*1024, nil)
+	require.NoError(t, err)
+
+	// Messages queued while the cloud was unreachable.
+	var queued []*types.Any
+	for _, data := range []string{"first", "second"} {
+		subany, err := types.MarshalAny(&cvmsgspb.VLogMessage{Data: []byte(data)})
+		require.NoError(t, err)
+		ok, err := q.Push(&vzconnpb.V2CBridgeMessage{Topic: "randomtopic", Msg: subany})
+		require.NoError(t, err)
+		require.True(t, ok)
+		queued = append(queued, subany)
+	}
+
+	// Wait for registration and the queued messages.
+	ts.wg.Add(3)
+
+	sessionID := time.Now().UnixNano()
+	b := bridge.New(ts.vzID, "", ts.jwt, "", sessionID, ts.vzClient, makeFakeVZInfo("foobar", 123), &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{}, q)
+	defer b.Stop()
+
+	go b.RunStream()
+	ts.wg.Wait()
+
+	require.Equal(t, 3, len(ts.vzServer.msgQ))
+	assert.Equal(t, "register", ts.vzServer.msgQ[0].Topic)
+	for i, subany := range queued {
+		msg := ts.vzServer.msgQ[i+1]
+		assert.Equal(t, "randomtopic", msg.Topic)
+		assert.Equal(t, sessionID, msg.SessionId)
+		assert.Equal(t, subany, msg.Msg)
+	}
+	// Messages are removed from the queue once they have been sent.
+	assert.Eventually(t, func() bool { return q.Len() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+// failOnceVZConnClient fails the first send of a message on the given topic, and closes that stream.
+type failOnceVZConnClient struct {
+	vzconnpb.VZConnServiceClient
+	topic  string
+	mu     sync.Mutex
+	failed bool
+}
+
+func (c *failOnceVZConnClient) NATSBridge(ctx context.Context, opts ...grpc.CallOption) (vzconnpb.VZConnService_NATSBridgeClient, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.VZConnServiceClient.NATSBridge(ctx, opts...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &failOnceStream{VZConnService_NATSBridgeClient: stream, c: c, cancel: cancel}, nil
+}
+
+type failOnceStream struct {
+	vzconnpb.VZConnService_NATSBridgeClient
+	c      *failOnceVZConnClient
+	cancel context.CancelFunc
+}
+
+func (s *failOnceStream) Send(m *vzconnpb.V2CBridgeMessage) error {
+	s.c.mu.Lock()
+	fail := m.Topic == s.c.topic && !s.c.failed
+	s.c.failed = s.c.failed || fail
+	s.c.mu.Unlock()
+	if fail {
+		s.cancel()
+		return io.EOF
+	}
+	return s.VZConnService_NATSBridgeClient.Send(m)
+}
+
+func TestNATSGRPCBridgeTest_TestOfflineQueueSendFailure(t *testing.T) {
+	ts, cleanup := makeTestState(t)
+	defer cleanup(t)
+
+	db, err := pebble.Open("queue", &pebble.Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	ds := pebbledb.New(db, 3*time.Second)
+	defer ds.Close()
+	q, err := bridge.NewOfflineQueue(ds, 100, 1024*1024, nil)
+	require.NoError(t, err)
+
+	subany, err := types.MarshalAny(&cvmsgspb.VLogMessage{Data: []byte("queued")})
+	require.NoError(t, err)
+	ok, err := q.Push(&vzconnpb.V2CBridgeMessage{Topic: "randomtopic", Msg: subany})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Wait for both registrations and the queued message, which is only sent on the second stream.
+	ts.wg.Add(3)
+
+	vzClient := &failOnceVZConnClient{VZConnServiceClient: ts.vzClient, topic: "randomtopic"}
+	b := bridge.New(ts.vzID, "", ts.jwt, "", time.Now().UnixNano(), vzClient, makeFakeVZInfo("foobar", 123), &FakeVZOperatorInfo{}, ts.nats, &FakeVZChecker{}, q)
+	defer b.Stop()
+
+	go b.RunStream()
+	ts.wg.Wait()
+
+	require.Equal(t, 3, len(ts.vzServer.msgQ))
+	assert.Equal(t, "register", ts.vzServer.msgQ[0].Topic)
+	assert.Equal(t, "register", ts.vzServer.msgQ[1].Topic)
+	assert.Equal(t, "randomtopic", ts.vzServer.msgQ[2].Topic)
+	assert.Equal(t, subany, ts.vzServer.msgQ[2].Msg)
+	assert.Eventually(t, func() bool { return q.Len() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
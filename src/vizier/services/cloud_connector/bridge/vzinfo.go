@@ -83,6 +83,7 @@ type K8sVizierInfo struct {
 	vzClient                      *versioned.Clientset
 	clusterVersion                string
 	clusterName                   string
+	namespaceScoped               bool
 	controlPlanePodStatuses       map[string]*cvmsgspb.PodStatus
 	unhealthyDataPlanePodStatuses map[string]*cvmsgspb.PodStatus
 	k8sStateLastUpdated           time.Time
@@ -109,8 +110,9 @@ func getK8sVersion() (string, error) {
 	return version.GitVersion, nil
 }
 
-// NewK8sVizierInfo creates a new K8sVizierInfo.
-func NewK8sVizierInfo(clusterName, ns string) (*K8sVizierInfo, error) {
+// NewK8sVizierInfo creates a new K8sVizierInfo. A namespace-scoped Vizier shares its cluster with other Vizier
+// instances, so it reports the namespace it runs in when registering.
+func NewK8sVizierInfo(clusterName, ns string, namespaceScoped bool) (*K8sVizierInfo, error) {
 	// There is a specific config for services running in the cluster.
 	kubeConfig, err := rest.InClusterConfig()
 	if err != nil {
@@ -130,10 +132,11 @@ func NewK8sVizierInfo(clusterName, ns string) (*K8sVizierInfo, error) {
 	}
 
 	vzInfo := &K8sVizierInfo{
-		ns:          ns,
-		clientset:   clientset,
-		vzClient:    vzCrdClient,
-		clusterName: clusterName,
+		ns:              ns,
+		clientset:       clientset,
+		vzClient:        vzCrdClient,
+		clusterName:     clusterName,
+		namespaceScoped: namespaceScoped,
 	}
 
 	go func() {
@@ -234,6 +237,15 @@ func (v *K8sVizierInfo) GetClusterUID() (string, error) {
 	return string(ksNS.UID), nil
 }
 
+// GetVizierNamespace gets the namespace that a namespace-scoped Vizier runs in. Cluster-scoped Viziers
+// return an empty string, as they are identified by the cluster UID alone.
+func (v *K8sVizierInfo) GetVizierNamespace() string {
+	if v.namespaceScoped {
+		return v.ns
+	}
+	return ""
+}
+
 const nanosPerSecond = int64(1000 * 1000 * 1000)
 
 func nanosToTimestampProto(nanos int64) *types.Timestamp {
@@ -709,6 +721,15 @@ func (v *K8sVizierInfo) UpdateClusterID(id string) error {
 	return err
 }
 
+// GetClusterID gets the cluster ID stored in the cluster secrets.
+func (v *K8sVizierInfo) GetClusterID() (string, error) {
+	s, err := v.clientset.CoreV1().Secrets(v.ns).Get(context.Background(), "pl-cluster-secrets", metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return string(s.Data["cluster-id"]), nil
+}
+
 // UpdateClusterName updates the cluster ID in the cluster secrets.
 func (v *K8sVizierInfo) UpdateClusterName(id string) error {
 	s, err := v.clientset.CoreV1().Secrets(v.ns).Get(context.Background(), "pl-cluster-secrets", metav1.GetOptions{})
@@ -762,7 +783,8 @@ func (v *K8sVizierInfo) GetVizierCRD() (*v1alpha1.Vizier, error) {
 // updating the CRD. Returns whether or not an update was actually initiated.
 // This is used to determine whether the vizier should actually be in "UPDATING"
 // status, in the case of falsely initated update requests. This will be fixed
-// as we move to having the operator fully manage vizier statuses.
+// as we move to having the operator fully manage vizier statuses. Returns
+// ErrVersionRolledBack if the operator rolled back a failed update to the version.
 func (v *K8sVizierInfo) UpdateCRDVizierVersion(version string) (bool, error) {
 	vz, err := v.GetVizierCRD()
 	if err != nil {
@@ -772,6 +794,9 @@ func (v *K8sVizierInfo) UpdateCRDVizierVersion(version string) (bool, error) {
 	if vz.Spec.Version == version {
 		return false, nil
 	}
+	if vz.Status.FailedVersion == version {
+		return false, ErrVersionRolledBack
+	}
 
 	vz.Spec.Version = version
 	_, err = v.vzClient.PxV1alpha1().Viziers(v.ns).Update(context.Background(), vz, metav1.UpdateOptions{})
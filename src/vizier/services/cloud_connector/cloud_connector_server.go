@@ -25,6 +25,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/cockroachdb/pebble"
 	"github.com/gofrs/uuid"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
@@ -43,8 +44,21 @@ import (
 	"px.dev/pixie/src/shared/status"
 	controllers "px.dev/pixie/src/vizier/services/cloud_connector/bridge"
 	"px.dev/pixie/src/vizier/services/cloud_connector/vizhealth"
+	"px.dev/pixie/src/vizier/utils/datastore/pebbledb"
 )
 
+// offlineQueueTTLDuration represents how often we evict expired keys from the offline queue's datastore.
+const offlineQueueTTLDuration = 1 * time.Minute
+
+func mustInitOfflineQueueDatastore(path string) *pebbledb.DataStore {
+	log.Infof("Using pebbledb: %s for the offline queue", path)
+	db, err := pebble.Open(path, &pebble.Options{})
+	if err != nil {
+		log.WithError(err).Fatal("Failed to open pebble database for the offline queue")
+	}
+	return pebbledb.New(db, offlineQueueTTLDuration)
+}
+
 func init() {
 	pflag.String("cluster_id", "", "The Cluster ID to use for Pixie Cloud")
 	pflag.String("nats_url", "pl-nats", "The URL of NATS")
@@ -57,6 +71,12 @@ func init() {
 	pflag.String("vizier_name", "", "The name of the user's K8s cluster, assigned by Pixie cloud")
 	pflag.String("deploy_key", "", "The deploy key for the cluster")
 	pflag.Bool("disable_auto_update", false, "Whether auto-update should be disabled")
+	pflag.String("namespace_scope", "", "Comma-separated list of namespaces this Vizier collects data from, if it is namespace-scoped")
+	pflag.Bool("standalone", false, "Whether Vizier runs without Pixie Cloud")
+	pflag.String("offline_queue_path", "", "The directory used to queue messages for Pixie Cloud while it is unreachable. Queueing is disabled if empty")
+	pflag.Int("offline_queue_max_messages", 50000, "The maximum number of messages to queue while Pixie Cloud is unreachable")
+	pflag.Int64("offline_queue_max_bytes", 64*1024*1024, "The maximum total size of the messages queued while Pixie Cloud is unreachable")
+	pflag.String("offline_queue_policies", "", "Comma-separated list of topic=policy overrides for the offline queue, where policy is one of drop_oldest, drop_newest or do_not_queue")
 }
 func newVzServiceClient() (vizierpb.VizierServiceClient, error) {
 	dialOpts, err := services.GetGRPCClientDialOpts()
@@ -76,7 +96,8 @@ func newVzServiceClient() (vizierpb.VizierServiceClient, error) {
 
 // Checks to see if the cloud connector has successfully assigned a cluster ID.
 type readinessCheck struct {
-	bridge *controllers.Bridge
+	bridge     *controllers.Bridge
+	standalone bool
 }
 
 func (r *readinessCheck) Name() string {
@@ -84,6 +105,10 @@ func (r *readinessCheck) Name() string {
 }
 
 func (r *readinessCheck) Check() error {
+	// Standalone Viziers assign their own cluster ID before the server starts.
+	if r.standalone {
+		return nil
+	}
 	s := r.bridge.GetStatus()
 	if s == "" {
 		return nil
@@ -110,11 +135,13 @@ func main() {
 
 	deployKey := viper.GetString("deploy_key")
 
-	vzInfo, err := controllers.NewK8sVizierInfo(viper.GetString("cluster_name"), viper.GetString("pod_namespace"))
+	vzInfo, err := controllers.NewK8sVizierInfo(viper.GetString("cluster_name"), viper.GetString("pod_namespace"), viper.GetString("namespace_scope") != "")
 	if err != nil {
 		log.WithError(err).Fatal("Could not get k8s info")
 	}
 
+	standalone := viper.GetBool("standalone")
+
 	// Clean up cert-provisioner-job, if exists.
 	certJob, err := vzInfo.GetJob("cert-provisioner-job")
 	if err == nil && certJob != nil {
@@ -148,6 +175,32 @@ func main() {
 	// Resign leadership after the server stops.
 	defer resign()
 
+	if standalone {
+		// Standalone Viziers don't register with Pixie Cloud, so they generate their own cluster ID. It is
+		// persisted in the cluster secrets, so it is picked up again when the cloud connector restarts.
+		// This only happens once we are the leader, so that replicas don't race to generate different IDs.
+		if vizierID == uuid.Nil {
+			// The previous leader may have generated the ID after this pod started.
+			id, err := vzInfo.GetClusterID()
+			if err != nil {
+				log.WithError(err).Fatal("Failed to read cluster ID")
+			}
+			vizierID = uuid.FromStringOrNil(id)
+		}
+		if vizierID == uuid.Nil {
+			vizierID = uuid.Must(uuid.NewV4())
+			err = vzInfo.UpdateClusterID(vizierID.String())
+			if err != nil {
+				log.WithError(err).Fatal("Failed to persist generated cluster ID")
+			}
+			log.WithField("clusterID", vizierID).Info("Generated cluster ID for standalone Vizier")
+		}
+		err = vzInfo.UpdateClusterIDAnnotation(vizierID.String())
+		if err != nil {
+			log.WithError(err).Error("Failed to update cluster ID annotation")
+		}
+	}
+
 	qbVzClient, err := newVzServiceClient()
 	if err != nil {
 		log.WithError(err).Fatal("Failed to init qb stub")
@@ -163,20 +216,37 @@ func main() {
 	// We just use the current time in nanoseconds to mark the session ID. This will let the cloud side know that
 	// the cloud connector restarted. Clock skew might make this incorrect, but we mostly want this for debugging.
 	sessionID := time.Now().UnixNano()
-	svr := controllers.New(vizierID, assignedClusterName, viper.GetString("jwt_signing_key"), deployKey, sessionID, nil, vzInfo, vzInfo, nil, checker)
-	go svr.RunStream()
-	defer svr.Stop()
+	var offlineQueue *controllers.OfflineQueue
+	if queuePath := viper.GetString("offline_queue_path"); queuePath != "" && !standalone {
+		ds := mustInitOfflineQueueDatastore(queuePath)
+		defer ds.Close()
+
+		policies, err := controllers.ParseOverflowPolicies(viper.GetString("offline_queue_policies"))
+		if err != nil {
+			log.WithError(err).Fatal("Failed to parse offline queue policies")
+		}
+		offlineQueue, err = controllers.NewOfflineQueue(ds, viper.GetInt("offline_queue_max_messages"), viper.GetInt64("offline_queue_max_bytes"), policies)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to load offline queue")
+		}
+	}
+
+	svr := controllers.New(vizierID, assignedClusterName, viper.GetString("jwt_signing_key"), deployKey, sessionID, nil, vzInfo, vzInfo, nil, checker, offlineQueue)
+	if !standalone {
+		go svr.RunStream()
+		defer svr.Stop()
+	}
 
 	mux := http.NewServeMux()
 	// Set up healthz endpoint.
 	healthz.RegisterDefaultChecks(mux)
 	// Set up readyz endpoint.
-	healthz.InstallPathHandler(mux, "/readyz", &readinessCheck{svr})
+	healthz.InstallPathHandler(mux, "/readyz", &readinessCheck{svr, standalone})
 
 	statusz.InstallPathHandler(mux, "/statusz", func() string {
-		// Check state of the bridge.
+		// Check state of the bridge, which only runs when connected to Pixie Cloud.
 		bridgeStatus := svr.GetStatus()
-		if bridgeStatus != "" {
+		if !standalone && bridgeStatus != "" {
 			return string(bridgeStatus)
 		}
 
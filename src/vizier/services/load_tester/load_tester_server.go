@@ -100,6 +100,10 @@ func (f *fakeVZInfo) GetClusterUID() (string, error) {
 	return f.clusterUID.String(), nil
 }
 
+func (f *fakeVZInfo) GetVizierNamespace() string {
+	return ""
+}
+
 func (f *fakeVZInfo) GetClusterID() (string, error) {
 	return f.vzID, nil
 }
@@ -237,7 +241,8 @@ func main() {
 				&fakeVZInfo{clusterUID: clusterUID, idx: i},
 				&fakeVZOperator{},
 				nc,
-				&fakeVZHealthChecker{})
+				&fakeVZHealthChecker{},
+				nil)
 			cloudConnSvrs[i] = svr
 			go svr.RunStream()
 			i++
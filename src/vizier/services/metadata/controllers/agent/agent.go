@@ -52,6 +52,9 @@ type Store interface {
 	GetASID() (uint32, error)
 	GetAgentIDFromPodName(podName string) (string, error)
 
+	GetAgentConfigValue(podName string, key string) (string, error)
+	SetAgentConfigValue(podName string, key string, value string) error
+
 	GetAgentsDataInfo() (map[uuid.UUID]*messagespb.AgentDataInfo, error)
 	UpdateAgentDataInfo(agentID uuid.UUID, dataInfo *messagespb.AgentDataInfo) error
 
@@ -110,8 +113,9 @@ type Manager interface {
 	// a given cursorID, the full initial state will be read first.
 	GetAgentUpdates(cursorID uuid.UUID) ([]*metadata_servicepb.AgentUpdate, *storepb.ComputedSchema, error)
 
-	// UpdateConfig updates the config for the specified agent.
-	UpdateConfig(string, string, string, string) error
+	// UpdateConfig updates the config for the specified agent, and returns the value which was previously set
+	// for the key, if any.
+	UpdateConfig(string, string, string, string) (string, error)
 
 	// GetComputedSchema gets the computed schemas
 	GetComputedSchema() (*storepb.ComputedSchema, error)
@@ -544,12 +548,17 @@ func (m *ManagerImpl) MessageActiveAgents(msg []byte) error {
 	return m.MessageAgents(agentIDs, msg)
 }
 
-// UpdateConfig updates the config key and value for the specified agent.
-func (m *ManagerImpl) UpdateConfig(ns string, podName string, key string, value string) error {
+// UpdateConfig updates the config key and value for the specified agent. It returns the value previously
+// set for the key through UpdateConfig, or an empty string if there was none.
+func (m *ManagerImpl) UpdateConfig(ns string, podName string, key string, value string) (string, error) {
 	// Find the agent ID for the agent with the given name.
 	agentID, err := m.agtStore.GetAgentIDFromPodName(podName)
 	if err != nil || agentID == "" {
-		return errors.New("Could not find agent with the given name")
+		return "", errors.New("Could not find agent with the given name")
+	}
+	prevValue, err := m.agtStore.GetAgentConfigValue(podName, key)
+	if err != nil {
+		return "", err
 	}
 
 	// Send the config update to the agent over NATS.
@@ -567,14 +576,18 @@ func (m *ManagerImpl) UpdateConfig(ns string, podName string, key string, value
 	}
 	msg, err := updateReq.Marshal()
 	if err != nil {
-		return err
+		return "", err
 	}
 	topic := messagebus.AgentTopic(agentID)
 	err = m.conn.Publish(topic, msg)
 	if err != nil {
-		return err
+		return "", err
 	}
-	return nil
+	err = m.agtStore.SetAgentConfigValue(podName, key, value)
+	if err != nil {
+		return "", err
+	}
+	return prevValue, nil
 }
 
 // GetAgentUpdates returns the latest agent status since the last call to GetAgentUpdates().
@@ -91,6 +91,10 @@ func getPodNameToAgentIDKey(podName string) string {
 	return path.Join("/podToAgentID", podName)
 }
 
+func getAgentConfigKey(podName string, key string) string {
+	return path.Join("/agentConfig", podName, key)
+}
+
 func getProcessKey(upid string) string {
 	return path.Join("/processes", upid)
 }
@@ -203,6 +207,12 @@ func (a *Datastore) DeleteAgent(agentID uuid.UUID) error {
 	if err != nil {
 		return err
 	}
+	if podName := aPb.Info.HostInfo.PodName; podName != "" {
+		err = a.ds.DeleteWithPrefix(getAgentConfigKey(podName, "") + "/")
+		if err != nil {
+			return err
+		}
+	}
 
 	// Deletes from the computedSchema
 	err = a.UpdateSchemas(agentID, []*storepb.TableInfo{})
@@ -286,6 +296,21 @@ func (a *Datastore) GetAgentIDFromPodName(podName string) (string, error) {
 	return string(id), nil
 }
 
+// GetAgentConfigValue returns the value which was last set for the config key of the agent with the given pod
+// name, or an empty string if it was never set.
+func (a *Datastore) GetAgentConfigValue(podName string, key string) (string, error) {
+	val, err := a.ds.Get(getAgentConfigKey(podName, key))
+	if err != nil {
+		return "", err
+	}
+	return string(val), nil
+}
+
+// SetAgentConfigValue records the value which was set for the config key of the agent with the given pod name.
+func (a *Datastore) SetAgentConfigValue(podName string, key string, value string) error {
+	return a.ds.Set(getAgentConfigKey(podName, key), value)
+}
+
 // GetAgentsDataInfo returns all of the information about data tables that each agent has.
 func (a *Datastore) GetAgentsDataInfo() (map[uuid.UUID]*messagespb.AgentDataInfo, error) {
 	dataInfos := make(map[uuid.UUID]*messagespb.AgentDataInfo)
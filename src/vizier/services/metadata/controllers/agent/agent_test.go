@@ -759,8 +759,15 @@ func TestAgent_UpdateConfig(t *testing.T) {
 		require.NoError(t, err)
 	}()
 
-	err = agtMgr.UpdateConfig("pl", "pem-existing", "gprof", "true")
+	prevValue, err := agtMgr.UpdateConfig("pl", "pem-existing", "gprof", "true")
 	require.NoError(t, err)
+	assert.Equal(t, "", prevValue)
+	wg.Wait()
 
-	defer wg.Wait()
+	// The value which was set is returned by the next update.
+	wg.Add(1)
+	prevValue, err = agtMgr.UpdateConfig("pl", "pem-existing", "gprof", "true")
+	require.NoError(t, err)
+	assert.Equal(t, "true", prevValue)
+	wg.Wait()
 }
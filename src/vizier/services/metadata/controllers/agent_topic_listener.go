@@ -33,6 +33,7 @@ import (
 	"px.dev/pixie/src/vizier/messages/messagespb"
 	"px.dev/pixie/src/vizier/services/metadata/controllers/agent"
 	"px.dev/pixie/src/vizier/services/metadata/controllers/tracepoint"
+	"px.dev/pixie/src/vizier/services/metadata/storepb"
 	"px.dev/pixie/src/vizier/services/shared/agentpb"
 	"px.dev/pixie/src/vizier/utils/messagebus"
 )
@@ -316,6 +317,11 @@ func (ah *AgentHandler) processMessages() {
 		if err != nil {
 			log.WithError(err).Error("Failed to delete agent from tracepoint manager")
 		}
+		// The agent may have been the last one selected by the target of a tracepoint.
+		err = ah.tpMgr.ResolveTargets()
+		if err != nil {
+			log.WithError(err).Error("Failed to resolve tracepoint targets")
+		}
 		ah.wg.Done()
 	}()
 
@@ -415,23 +421,41 @@ func (ah *AgentHandler) onAgentRegisterRequest(m *messagespb.RegisterAgentReques
 	}
 
 	go func() {
-		// Register all tracepoints on new agent.
+		// Register all tracepoints which run on every agent on the new agent.
 		tracepoints, err := ah.tpMgr.GetAllTracepoints()
 		if err != nil {
 			log.WithError(err).Error("Could not get all tracepoints")
 			return
 		}
 
-		agentIDs := []uuid.UUID{agentID}
-
+		hasTargets := false
 		for _, tp := range tracepoints {
+			if tp.Rollout != nil && tp.Rollout.State == storepb.ROLLOUT_CANARY {
+				// The tracepoint is only deployed to its canaries until its rollout completes. New agents
+				// run the previous version in the meantime.
+				continue
+			}
+			if tp.Target != nil {
+				// Tracepoints with a target are deployed when their targets are re-resolved below.
+				hasTargets = true
+				continue
+			}
 			if tp.ExpectedState != statuspb.TERMINATED_STATE {
-				err = ah.tpMgr.RegisterTracepoint(agentIDs, utils.UUIDFromProtoOrNil(tp.ID), tp.Tracepoint)
+				err = ah.tpMgr.RegisterTracepoint([]uuid.UUID{agentID}, utils.UUIDFromProtoOrNil(tp.ID), tp.Tracepoint)
 				if err != nil {
 					log.WithError(err).Error("Failed to send RegisterTracepoint request")
 				}
 			}
 		}
+
+		if !hasTargets {
+			return
+		}
+		// Deploy the tracepoints whose targets select the new agent.
+		err = ah.tpMgr.ResolveTargets()
+		if err != nil {
+			log.WithError(err).Error("Failed to resolve tracepoint targets")
+		}
 	}()
 }
 
@@ -81,7 +81,7 @@ func setup(t *testing.T, sendMsgFn controllers.SendMessageFn) (*controllers.Agen
 		GetActiveAgents().
 		Return([]*agentpb.Agent{agentInfo}, nil)
 
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 	atl, _ := controllers.NewAgentTopicListener(mockAgtMgr, tracepointMgr, sendMsgFn)
 
 	cleanup := func() {
@@ -536,6 +536,11 @@ func TestAgentTracepointInfoUpdate(t *testing.T) {
 	agentID := uuid.Must(uuid.NewV4())
 	tpID := uuid.Must(uuid.NewV4())
 
+	mockTracepointStore.
+		EXPECT().
+		GetTracepoint(tpID).
+		Return(nil, nil)
+
 	mockTracepointStore.
 		EXPECT().
 		UpdateTracepointState(&storepb.AgentTracepointStatus{
@@ -45,8 +45,9 @@ type watcher interface {
 	StartWatcher(chan struct{})
 }
 
-// NewController creates a new Controller.
-func NewController(updateCh chan *K8sResourceMessage) (*Controller, error) {
+// NewController creates a new Controller. If namespaces is non-empty, only resources within those namespaces are
+// forwarded on the update channel. Cluster-scoped resources, such as nodes, are always forwarded.
+func NewController(namespaces []string, updateCh chan *K8sResourceMessage) (*Controller, error) {
 	// There is a specific config for services running in the cluster.
 	kubeConfig, err := rest.InClusterConfig()
 	if err != nil {
@@ -60,6 +61,7 @@ func NewController(updateCh chan *K8sResourceMessage) (*Controller, error) {
 	}
 
 	quitCh := make(chan struct{})
+	scope := newNamespaceScope(namespaces)
 
 	// Create a watcher for each resource.
 	// The resource types we watch the K8s API for. These types are in a specific order:
@@ -67,10 +69,10 @@ func NewController(updateCh chan *K8sResourceMessage) (*Controller, error) {
 	// contain pods.
 	watchers := []watcher{
 		nodeWatcher("nodes", updateCh, clientset),
-		namespaceWatcher("namespaces", updateCh, clientset),
-		podWatcher("pods", updateCh, clientset),
-		endpointsWatcher("endpoints", updateCh, clientset),
-		serviceWatcher("services", updateCh, clientset),
+		namespaceWatcher("namespaces", updateCh, clientset, scope),
+		podWatcher("pods", updateCh, clientset, scope),
+		endpointsWatcher("endpoints", updateCh, clientset, scope),
+		serviceWatcher("services", updateCh, clientset, scope),
 	}
 
 	mc := &Controller{quitCh: quitCh, updateCh: updateCh, watchers: watchers}
@@ -151,6 +151,11 @@ type Handler struct {
 	// State that should be shared across all update processors.
 	state ProcessorState
 	once  sync.Once
+
+	// The nodes and pods which currently exist in the cluster, keyed by name.
+	resourceMu sync.RWMutex
+	nodes      map[string]*metadatapb.Node
+	pods       map[string]*metadatapb.Pod
 }
 
 // NewHandler creates a new Handler.
@@ -159,7 +164,8 @@ func NewHandler(updateCh <-chan *K8sResourceMessage, mds Store, conn *nats.Conn)
 	leaderMsgs := make(map[string]*metadatapb.Endpoints)
 	handlerMap := make(map[string]UpdateProcessor)
 	state := ProcessorState{LeaderMsgs: leaderMsgs, PodCIDRs: make([]string, 0), NodeToIP: make(map[string]string), PodToIP: make(map[string]string)}
-	mh := &Handler{updateCh: updateCh, mds: mds, conn: conn, done: done, processHandlerMap: handlerMap, state: state}
+	mh := &Handler{updateCh: updateCh, mds: mds, conn: conn, done: done, processHandlerMap: handlerMap, state: state,
+		nodes: make(map[string]*metadatapb.Node), pods: make(map[string]*metadatapb.Pod)}
 
 	// Register update processors.
 	mh.processHandlerMap["endpoints"] = &EndpointsUpdateProcessor{}
@@ -219,6 +225,8 @@ func (m *Handler) processUpdates() {
 			if !valid {
 				continue
 			}
+			m.cacheResource(update)
+
 			// Persist the update in the data store.
 			updates := processor.GetStoredProtos(update)
 			if updates == nil {
@@ -384,6 +392,52 @@ func (m *Handler) GetPodCIDRs() []string {
 	return m.state.PodCIDRs
 }
 
+// GetNodes returns the nodes which currently exist in the cluster.
+func (m *Handler) GetNodes() []*metadatapb.Node {
+	m.resourceMu.RLock()
+	defer m.resourceMu.RUnlock()
+
+	nodes := make([]*metadatapb.Node, 0, len(m.nodes))
+	for _, n := range m.nodes {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// GetPods returns the pods which currently exist in the cluster.
+func (m *Handler) GetPods() []*metadatapb.Pod {
+	m.resourceMu.RLock()
+	defer m.resourceMu.RUnlock()
+
+	pods := make([]*metadatapb.Pod, 0, len(m.pods))
+	for _, p := range m.pods {
+		pods = append(pods, p)
+	}
+	return pods
+}
+
+// cacheResource tracks the current state of the node or pod in the update, if any.
+func (m *Handler) cacheResource(obj *storepb.K8SResource) {
+	m.resourceMu.Lock()
+	defer m.resourceMu.Unlock()
+
+	if n := obj.GetNode(); n != nil {
+		if n.Metadata.DeletionTimestampNS != 0 {
+			delete(m.nodes, n.Metadata.Name)
+		} else {
+			m.nodes[n.Metadata.Name] = n
+		}
+	}
+	if p := obj.GetPod(); p != nil {
+		podName := fmt.Sprintf("%s/%s", p.Metadata.Namespace, p.Metadata.Name)
+		if p.Metadata.DeletionTimestampNS != 0 {
+			delete(m.pods, podName)
+		} else {
+			m.pods[podName] = p
+		}
+	}
+}
+
 func setDeleted(objMeta *metadatapb.ObjectMetadata) {
 	if objMeta.DeletionTimestampNS != 0 {
 		// Deletion timestamp already set.
@@ -958,6 +1012,7 @@ func getResourceUpdateFromPod(pod *metadatapb.Pod, uv int64) *metadatapb.Resourc
 				HostIP:           pod.Status.HostIP,
 				Message:          pod.Status.Message,
 				Reason:           pod.Status.Reason,
+				OwnerReferences:  pod.Metadata.OwnerReferences,
 			},
 		},
 	}
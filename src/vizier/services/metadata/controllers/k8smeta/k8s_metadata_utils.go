@@ -22,6 +22,7 @@ import (
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
@@ -31,6 +32,48 @@ import (
 	"px.dev/pixie/src/vizier/services/metadata/storepb"
 )
 
+// namespaceScope is the set of namespaces that resources are watched in. An empty scope includes all namespaces.
+type namespaceScope map[string]struct{}
+
+func newNamespaceScope(namespaces []string) namespaceScope {
+	scope := make(namespaceScope)
+	for _, ns := range namespaces {
+		scope[ns] = struct{}{}
+	}
+	return scope
+}
+
+// includes returns whether the given K8s object falls within the scope. Namespace objects are matched on their name,
+// and cluster-scoped objects are always included.
+func (s namespaceScope) includes(obj interface{}) bool {
+	if len(s) == 0 {
+		return true
+	}
+	o, ok := obj.(metav1.Object)
+	if !ok {
+		return true
+	}
+	ns := o.GetNamespace()
+	if _, isNamespace := obj.(*v1.Namespace); isNamespace {
+		ns = o.GetName()
+	}
+	if ns == "" {
+		return true
+	}
+	_, ok = s[ns]
+	return ok
+}
+
+// filter wraps the given converter so that objects outside of the scope are dropped.
+func (s namespaceScope) filter(convert func(obj interface{}) *K8sResourceMessage) func(obj interface{}) *K8sResourceMessage {
+	return func(obj interface{}) *K8sResourceMessage {
+		if !s.includes(obj) {
+			return nil
+		}
+		return convert(obj)
+	}
+}
+
 type informerWatcher struct {
 	convert func(obj interface{}) *K8sResourceMessage
 	objType string
@@ -70,40 +113,40 @@ func (i *informerWatcher) StartWatcher(quitCh chan struct{}) {
 	i.inf.Run(quitCh)
 }
 
-func podWatcher(resource string, ch chan *K8sResourceMessage, clientset *kubernetes.Clientset) *informerWatcher {
+func podWatcher(resource string, ch chan *K8sResourceMessage, clientset *kubernetes.Clientset, scope namespaceScope) *informerWatcher {
 	factory := informers.NewSharedInformerFactory(clientset, 12*time.Hour)
 	return &informerWatcher{
-		convert: podConverter,
+		convert: scope.filter(podConverter),
 		objType: resource,
 		ch:      ch,
 		inf:     factory.Core().V1().Pods().Informer(),
 	}
 }
 
-func serviceWatcher(resource string, ch chan *K8sResourceMessage, clientset *kubernetes.Clientset) *informerWatcher {
+func serviceWatcher(resource string, ch chan *K8sResourceMessage, clientset *kubernetes.Clientset, scope namespaceScope) *informerWatcher {
 	factory := informers.NewSharedInformerFactory(clientset, 12*time.Hour)
 	return &informerWatcher{
-		convert: serviceConverter,
+		convert: scope.filter(serviceConverter),
 		objType: resource,
 		ch:      ch,
 		inf:     factory.Core().V1().Services().Informer(),
 	}
 }
 
-func namespaceWatcher(resource string, ch chan *K8sResourceMessage, clientset *kubernetes.Clientset) *informerWatcher {
+func namespaceWatcher(resource string, ch chan *K8sResourceMessage, clientset *kubernetes.Clientset, scope namespaceScope) *informerWatcher {
 	factory := informers.NewSharedInformerFactory(clientset, 12*time.Hour)
 	return &informerWatcher{
-		convert: namespaceConverter,
+		convert: scope.filter(namespaceConverter),
 		objType: resource,
 		ch:      ch,
 		inf:     factory.Core().V1().Namespaces().Informer(),
 	}
 }
 
-func endpointsWatcher(resource string, ch chan *K8sResourceMessage, clientset *kubernetes.Clientset) *informerWatcher {
+func endpointsWatcher(resource string, ch chan *K8sResourceMessage, clientset *kubernetes.Clientset, scope namespaceScope) *informerWatcher {
 	factory := informers.NewSharedInformerFactory(clientset, 12*time.Hour)
 	return &informerWatcher{
-		convert: endpointsConverter,
+		convert: scope.filter(endpointsConverter),
 		objType: resource,
 		ch:      ch,
 		inf:     factory.Core().V1().Endpoints().Informer(),
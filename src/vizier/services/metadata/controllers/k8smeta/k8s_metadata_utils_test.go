@@ -0,0 +1,81 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package k8smeta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNamespaceScope_Filter(t *testing.T) {
+	tests := []struct {
+		name       string
+		namespaces []string
+		obj        interface{}
+		expected   bool
+	}{
+		{
+			name:     "empty scope includes everything",
+			obj:      &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "other"}},
+			expected: true,
+		},
+		{
+			name:       "pod in scope",
+			namespaces: []string{"team-a", "team-b"},
+			obj:        &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "team-b"}},
+			expected:   true,
+		},
+		{
+			name:       "pod out of scope",
+			namespaces: []string{"team-a"},
+			obj:        &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "team-b"}},
+			expected:   false,
+		},
+		{
+			name:       "namespace matched by name",
+			namespaces: []string{"team-a"},
+			obj:        &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+			expected:   true,
+		},
+		{
+			name:       "namespace out of scope",
+			namespaces: []string{"team-a"},
+			obj:        &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+			expected:   false,
+		},
+		{
+			name:       "cluster-scoped resource",
+			namespaces: []string{"team-a"},
+			obj:        &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+			expected:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			convert := newNamespaceScope(test.namespaces).filter(func(obj interface{}) *K8sResourceMessage {
+				return &K8sResourceMessage{}
+			})
+			assert.Equal(t, test.expected, convert(test.obj) != nil)
+		})
+	}
+}
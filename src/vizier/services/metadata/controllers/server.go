@@ -32,11 +32,15 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/gogo/protobuf/types"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/carnot/planner/distributedpb"
+	"px.dev/pixie/src/carnot/planner/dynamic_tracing/ir/logicalpb"
 	"px.dev/pixie/src/common/base/statuspb"
+	"px.dev/pixie/src/shared/services/authcontext"
 	"px.dev/pixie/src/table_store/schemapb"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/vizier/services/metadata/controllers/agent"
@@ -346,17 +350,30 @@ func (s *Server) GetWithPrefixKey(ctx context.Context, req *metadatapb.WithPrefi
 	return resp, nil
 }
 
-// RegisterTracepoint is a request to register the tracepoints specified in the TracepointDeployment on all agents.
+// RegisterTracepoint is a request to register the tracepoints specified in the TracepointDeployment on the
+// agents selected by each tracepoint's target.
 func (s *Server) RegisterTracepoint(ctx context.Context, req *metadatapb.RegisterTracepointRequest) (*metadatapb.RegisterTracepointResponse, error) {
 	responses := make([]*metadatapb.RegisterTracepointResponse_TracepointStatus, len(req.Requests))
 
+	for _, tp := range req.Requests {
+		if err := tracepoint.ValidateTarget(tp.Target); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "tracepoint %s: %v", tp.Name, err)
+		}
+	}
+
+	creator := creatorFromContext(ctx)
+
 	// Create tracepoint.
 	for i, tp := range req.Requests {
 		ttl, err := types.DurationFromProto(tp.TTL)
 		if err != nil {
 			return nil, err
 		}
-		tracepointID, err := s.tpMgr.CreateTracepoint(tp.Name, tp.TracepointDeployment, ttl)
+		opts := &tracepoint.DeploymentOptions{
+			Creator:         creator,
+			RolloutFraction: tp.RolloutFraction,
+		}
+		tracepointID, err := s.tpMgr.CreateTracepoint(tp.Name, tp.TracepointDeployment, tp.Target, ttl, opts)
 		if err != nil && err != tracepoint.ErrTracepointAlreadyExists {
 			return nil, err
 		}
@@ -379,30 +396,118 @@ func (s *Server) RegisterTracepoint(ctx context.Context, req *metadatapb.Registe
 			Name: tp.Name,
 		}
 
-		// Get all agents currently running.
-		agents, err := s.agtMgr.GetActiveAgents()
+		err = s.deployTracepoint(*tracepointID, tp.TracepointDeployment, tp.Target, opts)
 		if err != nil {
 			return nil, err
 		}
-		agentIDs := make([]uuid.UUID, len(agents))
-		for i, agt := range agents {
-			agentIDs[i] = utils.UUIDFromProtoOrNil(agt.Info.AgentID)
-		}
+	}
 
-		// Register tracepoint on all agents.
-		err = s.tpMgr.RegisterTracepoint(agentIDs, *tracepointID, tp.TracepointDeployment)
+	resp := &metadatapb.RegisterTracepointResponse{
+		Tracepoints: responses,
+		Status: &statuspb.Status{
+			ErrCode: statuspb.OK,
+		},
+	}
+
+	return resp, nil
+}
+
+// deployTracepoint registers the tracepoint on the agents selected by its target. If the tracepoint is rolled out
+// in stages, it is only registered on the canaries.
+func (s *Server) deployTracepoint(tracepointID uuid.UUID, deployment *logicalpb.TracepointDeployment, target *storepb.TracepointTarget, opts *tracepoint.DeploymentOptions) error {
+	// Get all agents currently running.
+	agents, err := s.agtMgr.GetActiveAgents()
+	if err != nil {
+		return err
+	}
+	agentIDs, err := s.tpMgr.SelectAgents(target, agents)
+	if err != nil {
+		return err
+	}
+	err = s.tpMgr.SetTargetAgents(tracepointID, target, agentIDs)
+	if err != nil {
+		return err
+	}
+	if opts.IsStaged() {
+		agentIDs, err = s.tpMgr.SelectRolloutAgents(tracepointID, agentIDs)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	resp := &metadatapb.RegisterTracepointResponse{
-		Tracepoints: responses,
+	// Register tracepoint on the targeted agents.
+	return s.tpMgr.RegisterTracepoint(agentIDs, tracepointID, deployment)
+}
+
+// creatorFromContext returns the user or service which made the request, to record in the tracepoint's
+// version history.
+func creatorFromContext(ctx context.Context) string {
+	aCtx, err := authcontext.FromContext(ctx)
+	if err != nil || aCtx.Claims == nil {
+		return ""
+	}
+	if userClaims := aCtx.Claims.GetUserClaims(); userClaims != nil && userClaims.Email != "" {
+		return userClaims.Email
+	}
+	if serviceClaims := aCtx.Claims.GetServiceClaims(); serviceClaims != nil && serviceClaims.ServiceID != "" {
+		return serviceClaims.ServiceID
+	}
+	return aCtx.Claims.Subject
+}
+
+// GetTracepointVersions gets the version history of the tracepoint with the given name.
+func (s *Server) GetTracepointVersions(ctx context.Context, req *metadatapb.GetTracepointVersionsRequest) (*metadatapb.GetTracepointVersionsResponse, error) {
+	versions, err := s.tpMgr.GetTracepointVersions(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &metadatapb.GetTracepointVersionsResponse{
+		Versions: versions,
+	}, nil
+}
+
+// RollbackTracepoint redeploys a previous version of the tracepoint with the given name.
+func (s *Server) RollbackTracepoint(ctx context.Context, req *metadatapb.RollbackTracepointRequest) (*metadatapb.RollbackTracepointResponse, error) {
+	ttl, err := types.DurationFromProto(req.TTL)
+	if err != nil {
+		return nil, err
+	}
+	opts := &tracepoint.DeploymentOptions{
+		Creator:         creatorFromContext(ctx),
+		RolloutFraction: req.RolloutFraction,
+	}
+	tracepointID, err := s.tpMgr.RollbackTracepoint(req.Name, req.Version, ttl, opts)
+	if err == tracepoint.ErrTracepointVersionNotFound {
+		return nil, status.Errorf(codes.NotFound, "tracepoint %s has no version %d", req.Name, req.Version)
+	}
+	if err != nil && err != tracepoint.ErrTracepointAlreadyExists {
+		return nil, err
+	}
+
+	tp, infoErr := s.tpMgr.GetTracepointInfo(*tracepointID)
+	if infoErr != nil {
+		return nil, infoErr
+	}
+	if tp == nil {
+		return nil, status.Errorf(codes.NotFound, "tracepoint %s not found", req.Name)
+	}
+	resp := &metadatapb.RollbackTracepointResponse{
+		ID:      tp.ID,
+		Version: tp.Version,
 		Status: &statuspb.Status{
 			ErrCode: statuspb.OK,
 		},
 	}
+	if err == tracepoint.ErrTracepointAlreadyExists {
+		// The requested version is the one which is already deployed.
+		resp.Status.ErrCode = statuspb.ALREADY_EXISTS
+		return resp, nil
+	}
 
+	err = s.deployTracepoint(*tracepointID, tp.Tracepoint, tp.Target, opts)
+	if err != nil {
+		return nil, err
+	}
 	return resp, nil
 }
 
@@ -427,6 +532,7 @@ func (s *Server) GetTracepointInfo(ctx context.Context, req *metadatapb.GetTrace
 
 	tracepointState := make([]*metadatapb.GetTracepointInfoResponse_TracepointState, len(tracepointInfos))
 
+	var agents []*agentpb.Agent
 	for i, tp := range tracepointInfos {
 		if tp == nil { // TracepointDeployment does not exist.
 			tracepointState[i] = &metadatapb.GetTracepointInfoResponse_TracepointState{
@@ -452,13 +558,38 @@ func (s *Server) GetTracepointInfo(ctx context.Context, req *metadatapb.GetTrace
 			schemas[i] = t.TableName
 		}
 
+		var targetAgentIDs []*uuidpb.UUID
+		noMatchingAgents := false
+		if tp.Target != nil {
+			if agents == nil {
+				agents, err = s.agtMgr.GetActiveAgents()
+				if err != nil {
+					return nil, err
+				}
+			}
+			agentIDs, err := s.tpMgr.SelectAgents(tp.Target, agents)
+			if err != nil {
+				return nil, err
+			}
+			targetAgentIDs = make([]*uuidpb.UUID, len(agentIDs))
+			for j, id := range agentIDs {
+				targetAgentIDs[j] = utils.ProtoFromUUID(id)
+			}
+			noMatchingAgents = len(agentIDs) == 0
+		}
+
 		tracepointState[i] = &metadatapb.GetTracepointInfoResponse_TracepointState{
-			ID:            tp.ID,
-			State:         state,
-			Statuses:      statuses,
-			Name:          tp.Name,
-			ExpectedState: tp.ExpectedState,
-			SchemaNames:   schemas,
+			ID:               tp.ID,
+			State:            state,
+			Statuses:         statuses,
+			Name:             tp.Name,
+			ExpectedState:    tp.ExpectedState,
+			SchemaNames:      schemas,
+			TargetAgentIDs:   targetAgentIDs,
+			AgentStatuses:    tracepointStates,
+			Version:          tp.Version,
+			Rollout:          tp.Rollout,
+			NoMatchingAgents: noMatchingAgents,
 		}
 	}
 
@@ -537,7 +668,35 @@ func (s *Server) UpdateConfig(ctx context.Context, req *metadatapb.UpdateConfigR
 		return nil, errors.New("Incorrectly formatted pod name. Must be of the form '<ns>/<podName>'")
 	}
 
-	err := s.agtMgr.UpdateConfig(splitName[0], splitName[1], req.Key, req.Value)
+	notFound := &metadatapb.UpdateConfigResponse{
+		Status: &statuspb.Status{
+			ErrCode: statuspb.NOT_FOUND,
+			Msg:     fmt.Sprintf("no active agent with pod name %s", req.AgentPodName),
+		},
+	}
+	// Agents only run in the namespace of the metadata service, and are looked up by pod name alone.
+	if splitName[0] != viper.GetString("pod_namespace") {
+		return notFound, nil
+	}
+
+	if req.ValidateOnly {
+		agents, err := s.agtMgr.GetActiveAgents()
+		if err != nil {
+			return nil, err
+		}
+		for _, agt := range agents {
+			if agt.Info != nil && agt.Info.HostInfo != nil && agt.Info.HostInfo.PodName == splitName[1] {
+				return &metadatapb.UpdateConfigResponse{
+					Status: &statuspb.Status{
+						ErrCode: statuspb.OK,
+					},
+				}, nil
+			}
+		}
+		return notFound, nil
+	}
+
+	prevValue, err := s.agtMgr.UpdateConfig(splitName[0], splitName[1], req.Key, req.Value)
 	if err != nil {
 		return nil, err
 	}
@@ -546,5 +705,6 @@ func (s *Server) UpdateConfig(ctx context.Context, req *metadatapb.UpdateConfigR
 		Status: &statuspb.Status{
 			ErrCode: statuspb.OK,
 		},
+		PreviousValue: prevValue,
 	}, nil
 }
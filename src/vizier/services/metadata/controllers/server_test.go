@@ -36,7 +36,9 @@ import (
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	grpc_metadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 
 	"px.dev/pixie/src/api/proto/uuidpb"
@@ -272,7 +274,7 @@ func Test_Server_RegisterTracepoint(t *testing.T) {
 	mockAgtMgr := mock_agent.NewMockManager(ctrl)
 	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
 
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 
 	program := &logicalpb.TracepointDeployment{
 		Programs: []*logicalpb.TracepointDeployment_TracepointProgram{
@@ -326,6 +328,14 @@ func Test_Server_RegisterTracepoint(t *testing.T) {
 			assert.Equal(t, "test_tracepoint", tracepointInfo.Name)
 			return nil
 		})
+	mockTracepointStore.
+		EXPECT().
+		GetTracepointVersions("test_tracepoint").
+		Return(nil, nil)
+	mockTracepointStore.
+		EXPECT().
+		UpsertTracepointVersion(gomock.Any()).
+		Return(nil)
 	mockTracepointStore.
 		EXPECT().
 		SetTracepointWithName("test_tracepoint", gomock.Any()).
@@ -379,7 +389,7 @@ func Test_Server_RegisterTracepoint_Exists(t *testing.T) {
 	mockAgtMgr := mock_agent.NewMockManager(ctrl)
 	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
 
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 
 	program := &logicalpb.TracepointDeployment{
 		Programs: []*logicalpb.TracepointDeployment_TracepointProgram{
@@ -450,6 +460,14 @@ func Test_Server_RegisterTracepoint_Exists(t *testing.T) {
 			assert.Equal(t, "test_tracepoint", tracepointInfo.Name)
 			return nil
 		})
+	mockTracepointStore.
+		EXPECT().
+		GetTracepointVersions("test_tracepoint").
+		Return(nil, nil)
+	mockTracepointStore.
+		EXPECT().
+		UpsertTracepointVersion(gomock.Any()).
+		Return(nil)
 	mockTracepointStore.
 		EXPECT().
 		SetTracepointWithName("test_tracepoint", gomock.Any()).
@@ -496,6 +514,99 @@ func Test_Server_RegisterTracepoint_Exists(t *testing.T) {
 	assert.Equal(t, statuspb.OK, resp.Tracepoints[0].Status.ErrCode)
 }
 
+func Test_Server_RegisterTracepoint_InvalidTarget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockAgtMgr := mock_agent.NewMockManager(ctrl)
+	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
+
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
+	defer tracepointMgr.Close()
+
+	env, err := metadataenv.New("vizier")
+	require.NoError(t, err)
+	s := controllers.NewServer(env, nil, mockAgtMgr, tracepointMgr)
+
+	req := metadatapb.RegisterTracepointRequest{
+		Requests: []*metadatapb.RegisterTracepointRequest_TracepointRequest{
+			{
+				TracepointDeployment: &logicalpb.TracepointDeployment{},
+				Name:                 "test_tracepoint",
+				TTL: &types.Duration{
+					Seconds: 5,
+				},
+				Target: &storepb.TracepointTarget{
+					PodSelector: "app in (web",
+				},
+			},
+		},
+	}
+
+	// No tracepoint should be created if its target is invalid.
+	resp, err := s.RegisterTracepoint(context.Background(), &req)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func Test_Server_GetTracepointVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockAgtMgr := mock_agent.NewMockManager(ctrl)
+	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
+
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
+	defer tracepointMgr.Close()
+
+	mockTracepointStore.
+		EXPECT().
+		GetTracepointVersions("test_tracepoint").
+		Return([]*storepb.TracepointVersion{
+			{Name: "test_tracepoint", Version: 2},
+			{Name: "test_tracepoint", Version: 1},
+		}, nil)
+
+	env, err := metadataenv.New("vizier")
+	require.NoError(t, err)
+	s := controllers.NewServer(env, nil, mockAgtMgr, tracepointMgr)
+
+	resp, err := s.GetTracepointVersions(context.Background(), &metadatapb.GetTracepointVersionsRequest{
+		Name: "test_tracepoint",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(resp.Versions))
+	assert.Equal(t, int64(1), resp.Versions[0].Version)
+	assert.Equal(t, int64(2), resp.Versions[1].Version)
+}
+
+func Test_Server_RollbackTracepoint_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockAgtMgr := mock_agent.NewMockManager(ctrl)
+	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
+
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
+	defer tracepointMgr.Close()
+
+	mockTracepointStore.
+		EXPECT().
+		GetTracepointVersion("test_tracepoint", int64(3)).
+		Return(nil, nil)
+
+	env, err := metadataenv.New("vizier")
+	require.NoError(t, err)
+	s := controllers.NewServer(env, nil, mockAgtMgr, tracepointMgr)
+
+	resp, err := s.RollbackTracepoint(context.Background(), &metadatapb.RollbackTracepointRequest{
+		Name:    "test_tracepoint",
+		Version: 3,
+		TTL: &types.Duration{
+			Seconds: 5,
+		},
+	})
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
 func Test_Server_GetTracepointInfo(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -612,7 +723,7 @@ func Test_Server_GetTracepointInfo(t *testing.T) {
 			mockAgtMgr := mock_agent.NewMockManager(ctrl)
 			mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
 
-			tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+			tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 
 			program := &logicalpb.TracepointDeployment{
 				Programs: []*logicalpb.TracepointDeployment_TracepointProgram{
@@ -684,6 +795,61 @@ func Test_Server_GetTracepointInfo(t *testing.T) {
 	}
 }
 
+func Test_Server_GetTracepointInfo_NoMatchingAgents(t *testing.T) {
+	// Set up mock.
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockAgtMgr := mock_agent.NewMockManager(ctrl)
+	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
+
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
+
+	tID := uuid.Must(uuid.NewV4())
+	mockTracepointStore.
+		EXPECT().
+		GetTracepointsForIDs([]uuid.UUID{tID}).
+		Return([]*storepb.TracepointInfo{
+			{
+				ID:            utils.ProtoFromUUID(tID),
+				Tracepoint:    &logicalpb.TracepointDeployment{},
+				ExpectedState: statuspb.RUNNING_STATE,
+				Target:        &storepb.TracepointTarget{NodeNames: []string{"node-2"}},
+			},
+		}, nil)
+	mockTracepointStore.
+		EXPECT().
+		GetTracepointStates(tID).
+		Return(nil, nil)
+	mockAgtMgr.
+		EXPECT().
+		GetActiveAgents().
+		Return([]*agentpb.Agent{
+			{
+				Info: &agentpb.AgentInfo{
+					AgentID:      utils.ProtoFromUUID(uuid.Must(uuid.NewV4())),
+					HostInfo:     &agentpb.HostInfo{Hostname: "node-1"},
+					Capabilities: &agentpb.AgentCapabilities{CollectsData: true},
+				},
+			},
+		}, nil)
+
+	// Set up server.
+	env, err := metadataenv.New("vizier")
+	if err != nil {
+		t.Fatal("Failed to create api environment.")
+	}
+
+	s := controllers.NewServer(env, nil, mockAgtMgr, tracepointMgr)
+	resp, err := s.GetTracepointInfo(context.Background(), &metadatapb.GetTracepointInfoRequest{
+		IDs: []*uuidpb.UUID{utils.ProtoFromUUID(tID)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(resp.Tracepoints))
+	assert.Equal(t, statuspb.PENDING_STATE, resp.Tracepoints[0].State)
+	assert.True(t, resp.Tracepoints[0].NoMatchingAgents)
+	assert.Empty(t, resp.Tracepoints[0].TargetAgentIDs)
+}
+
 func Test_Server_RemoveTracepoint(t *testing.T) {
 	// Set up mock.
 	ctrl := gomock.NewController(t)
@@ -691,7 +857,7 @@ func Test_Server_RemoveTracepoint(t *testing.T) {
 	mockAgtMgr := mock_agent.NewMockManager(ctrl)
 	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
 
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 
 	tpID1 := uuid.Must(uuid.NewV4())
 	tpID2 := uuid.Must(uuid.NewV4())
@@ -703,6 +869,13 @@ func Test_Server_RemoveTracepoint(t *testing.T) {
 			&tpID1, &tpID2,
 		}, nil)
 
+	mockTracepointStore.
+		EXPECT().
+		GetTracepointsForIDs([]uuid.UUID{tpID1, tpID2}).
+		Return([]*storepb.TracepointInfo{
+			{ID: utils.ProtoFromUUID(tpID1)}, {ID: utils.ProtoFromUUID(tpID2)},
+		}, nil)
+
 	mockTracepointStore.
 		EXPECT().
 		DeleteTracepointTTLs([]uuid.UUID{tpID1, tpID2}).
@@ -1048,12 +1221,15 @@ func Test_Server_UpdateConfig(t *testing.T) {
 	defer ctrl.Finish()
 	mockAgtMgr := mock_agent.NewMockManager(ctrl)
 	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
+
+	viper.Set("pod_namespace", "pl")
+	defer viper.Set("pod_namespace", nil)
 
 	mockAgtMgr.
 		EXPECT().
 		UpdateConfig("pl", "pem-1234", "gprof", "true").
-		Return(nil)
+		Return("false", nil)
 
 	// Set up server.
 	env, err := metadataenv.New("vizier")
@@ -1074,6 +1250,7 @@ func Test_Server_UpdateConfig(t *testing.T) {
 	assert.NotNil(t, resp)
 	require.NoError(t, err)
 	assert.Equal(t, statuspb.OK, resp.Status.ErrCode)
+	assert.Equal(t, "false", resp.PreviousValue)
 
 	// This is an invalid request because AgentPodName must contain the namespace.
 	invalidReq := metadatapb.UpdateConfigRequest{
@@ -1085,3 +1262,65 @@ func Test_Server_UpdateConfig(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Nil(t, resp)
 }
+
+func Test_Server_UpdateConfig_ValidateOnly(t *testing.T) {
+	// Set up mock.
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockAgtMgr := mock_agent.NewMockManager(ctrl)
+	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
+
+	viper.Set("pod_namespace", "pl")
+	defer viper.Set("pod_namespace", nil)
+
+	mockAgtMgr.
+		EXPECT().
+		GetActiveAgents().
+		Return([]*agentpb.Agent{
+			{
+				Info: &agentpb.AgentInfo{
+					HostInfo: &agentpb.HostInfo{
+						PodName: "pem-1234",
+					},
+				},
+			},
+		}, nil).
+		Times(2)
+
+	// Set up server.
+	env, err := metadataenv.New("vizier")
+	if err != nil {
+		t.Fatal("Failed to create api environment.")
+	}
+
+	s := controllers.NewServer(env, nil, mockAgtMgr, tracepointMgr)
+
+	resp, err := s.UpdateConfig(context.Background(), &metadatapb.UpdateConfigRequest{
+		AgentPodName: "pl/pem-1234",
+		Key:          "gprof",
+		Value:        "true",
+		ValidateOnly: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, statuspb.OK, resp.Status.ErrCode)
+
+	resp, err = s.UpdateConfig(context.Background(), &metadatapb.UpdateConfigRequest{
+		AgentPodName: "pl/pem-5678",
+		Key:          "gprof",
+		Value:        "true",
+		ValidateOnly: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, statuspb.NOT_FOUND, resp.Status.ErrCode)
+
+	// The agent's pod name alone isn't enough, it also has to be in the right namespace.
+	resp, err = s.UpdateConfig(context.Background(), &metadatapb.UpdateConfigRequest{
+		AgentPodName: "other/pem-1234",
+		Key:          "gprof",
+		Value:        "true",
+		ValidateOnly: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, statuspb.NOT_FOUND, resp.Status.ErrCode)
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package tracepoint
+
+import (
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"px.dev/pixie/src/shared/k8s/metadatapb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/vizier/services/metadata/storepb"
+	"px.dev/pixie/src/vizier/services/shared/agentpb"
+)
+
+// K8sMetadataGetter provides the K8s state which is used to resolve tracepoint targets.
+type K8sMetadataGetter interface {
+	GetNodes() []*metadatapb.Node
+	GetPods() []*metadatapb.Pod
+}
+
+// targetSelector is a parsed TracepointTarget, which can be matched against agents.
+type targetSelector struct {
+	target       *storepb.TracepointTarget
+	nodeSelector labels.Selector
+	podSelector  labels.Selector
+}
+
+func isEmptyTarget(target *storepb.TracepointTarget) bool {
+	return target == nil || (len(target.NodeNames) == 0 && target.NodeSelector == "" &&
+		len(target.Namespaces) == 0 && target.PodSelector == "" && len(target.UPIDs) == 0)
+}
+
+func parseTarget(target *storepb.TracepointTarget) (*targetSelector, error) {
+	ts := &targetSelector{target: target}
+	if target.NodeSelector != "" {
+		sel, err := labels.Parse(target.NodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node selector %q: %v", target.NodeSelector, err)
+		}
+		ts.nodeSelector = sel
+	}
+	if target.PodSelector != "" {
+		sel, err := labels.Parse(target.PodSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pod selector %q: %v", target.PodSelector, err)
+		}
+		ts.podSelector = sel
+	}
+	return ts, nil
+}
+
+// ValidateTarget checks that the given tracepoint target can be resolved.
+func ValidateTarget(target *storepb.TracepointTarget) error {
+	if isEmptyTarget(target) {
+		return nil
+	}
+	_, err := parseTarget(target)
+	return err
+}
+
+func contains(vals []string, val string) bool {
+	for _, v := range vals {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeForAgent finds the node which the agent is running on, if it is known.
+func nodeForAgent(agt *agentpb.Agent, nodes []*metadatapb.Node) *metadatapb.Node {
+	hostInfo := agt.GetInfo().GetHostInfo()
+	for _, n := range nodes {
+		for _, addr := range n.GetStatus().GetAddresses() {
+			if addr.Type == metadatapb.NODE_ADDR_TYPE_INTERNAL_IP && addr.Address == hostInfo.GetHostIP() {
+				return n
+			}
+		}
+	}
+	for _, n := range nodes {
+		if n.Metadata.Name == hostInfo.GetHostname() {
+			return n
+		}
+	}
+	return nil
+}
+
+func (ts *targetSelector) matchesPod(pod *metadatapb.Pod) bool {
+	if len(ts.target.Namespaces) > 0 && !contains(ts.target.Namespaces, pod.Metadata.Namespace) {
+		return false
+	}
+	if ts.podSelector != nil && !ts.podSelector.Matches(labels.Set(pod.Metadata.Labels)) {
+		return false
+	}
+	return true
+}
+
+func (ts *targetSelector) matchesAgent(agt *agentpb.Agent, nodes []*metadatapb.Node, pods []*metadatapb.Pod) bool {
+	// Only agents which collect data are able to run tracepoints.
+	if !agt.GetInfo().GetCapabilities().GetCollectsData() {
+		return false
+	}
+
+	if len(ts.target.UPIDs) > 0 {
+		ownsUPID := false
+		for _, upid := range ts.target.UPIDs {
+			if upid.Asid == agt.ASID {
+				ownsUPID = true
+				break
+			}
+		}
+		if !ownsUPID {
+			return false
+		}
+	}
+
+	node := nodeForAgent(agt, nodes)
+	nodeName := agt.GetInfo().GetHostInfo().GetHostname()
+	if node != nil {
+		nodeName = node.Metadata.Name
+	}
+
+	if len(ts.target.NodeNames) > 0 && !contains(ts.target.NodeNames, nodeName) {
+		return false
+	}
+	if ts.nodeSelector != nil && (node == nil || !ts.nodeSelector.Matches(labels.Set(node.Metadata.Labels))) {
+		return false
+	}
+
+	if len(ts.target.Namespaces) == 0 && ts.podSelector == nil {
+		return true
+	}
+	hostIP := agt.GetInfo().GetHostInfo().GetHostIP()
+	for _, pod := range pods {
+		onNode := (hostIP != "" && pod.GetStatus().GetHostIP() == hostIP) ||
+			(node != nil && pod.GetSpec().GetNodeName() == node.Metadata.Name)
+		if onNode && ts.matchesPod(pod) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectAgents returns the IDs of the agents which are selected by the target.
+func selectAgents(target *storepb.TracepointTarget, agents []*agentpb.Agent, nodes []*metadatapb.Node, pods []*metadatapb.Pod) ([]uuid.UUID, error) {
+	agentIDs := make([]uuid.UUID, 0)
+	if isEmptyTarget(target) {
+		for _, agt := range agents {
+			agentIDs = append(agentIDs, utils.UUIDFromProtoOrNil(agt.Info.AgentID))
+		}
+		return agentIDs, nil
+	}
+
+	ts, err := parseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	for _, agt := range agents {
+		if ts.matchesAgent(agt, nodes, pods) {
+			agentIDs = append(agentIDs, utils.UUIDFromProtoOrNil(agt.Info.AgentID))
+		}
+	}
+	return agentIDs, nil
+}
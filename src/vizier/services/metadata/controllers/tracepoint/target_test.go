@@ -0,0 +1,312 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package tracepoint_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/carnot/planner/dynamic_tracing/ir/logicalpb"
+	"px.dev/pixie/src/common/base/statuspb"
+	"px.dev/pixie/src/shared/k8s/metadatapb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/vizier/messages/messagespb"
+	mock_agent "px.dev/pixie/src/vizier/services/metadata/controllers/agent/mock"
+	"px.dev/pixie/src/vizier/services/metadata/controllers/tracepoint"
+	mock_tracepoint "px.dev/pixie/src/vizier/services/metadata/controllers/tracepoint/mock"
+	"px.dev/pixie/src/vizier/services/metadata/storepb"
+	"px.dev/pixie/src/vizier/services/shared/agentpb"
+)
+
+type fakeK8sMetadata struct {
+	nodes []*metadatapb.Node
+	pods  []*metadatapb.Pod
+}
+
+func (f *fakeK8sMetadata) GetNodes() []*metadatapb.Node {
+	return f.nodes
+}
+
+func (f *fakeK8sMetadata) GetPods() []*metadatapb.Pod {
+	return f.pods
+}
+
+func makeNode(name string, ip string, labels map[string]string) *metadatapb.Node {
+	return &metadatapb.Node{
+		Metadata: &metadatapb.ObjectMetadata{Name: name, Labels: labels},
+		Status: &metadatapb.NodeStatus{
+			Addresses: []*metadatapb.NodeAddress{
+				{Type: metadatapb.NODE_ADDR_TYPE_INTERNAL_IP, Address: ip},
+			},
+		},
+	}
+}
+
+func makePod(ns string, name string, hostIP string, labels map[string]string) *metadatapb.Pod {
+	return &metadatapb.Pod{
+		Metadata: &metadatapb.ObjectMetadata{Name: name, Namespace: ns, Labels: labels},
+		Spec:     &metadatapb.PodSpec{},
+		Status:   &metadatapb.PodStatus{HostIP: hostIP},
+	}
+}
+
+func makeAgent(id uuid.UUID, asid uint32, hostname string, hostIP string, collectsData bool) *agentpb.Agent {
+	return &agentpb.Agent{
+		Info: &agentpb.AgentInfo{
+			AgentID: utils.ProtoFromUUID(id),
+			HostInfo: &agentpb.HostInfo{
+				Hostname: hostname,
+				HostIP:   hostIP,
+			},
+			Capabilities: &agentpb.AgentCapabilities{CollectsData: collectsData},
+		},
+		ASID: asid,
+	}
+}
+
+func TestManager_SelectAgents(t *testing.T) {
+	agent1 := uuid.Must(uuid.NewV4())
+	agent2 := uuid.Must(uuid.NewV4())
+	agent3 := uuid.Must(uuid.NewV4())
+	kelvin := uuid.Must(uuid.NewV4())
+
+	agents := []*agentpb.Agent{
+		makeAgent(agent1, 1, "node-1", "10.0.0.1", true),
+		makeAgent(agent2, 2, "node-2", "10.0.0.2", true),
+		makeAgent(agent3, 3, "node-3", "10.0.0.3", true),
+		makeAgent(kelvin, 4, "kelvin", "10.0.0.1", false),
+	}
+	k8sMeta := &fakeK8sMetadata{
+		nodes: []*metadatapb.Node{
+			makeNode("node-1", "10.0.0.1", map[string]string{"pool": "gpu"}),
+			makeNode("node-2", "10.0.0.2", map[string]string{"pool": "gpu"}),
+			makeNode("node-3", "10.0.0.3", map[string]string{"pool": "default"}),
+		},
+		pods: []*metadatapb.Pod{
+			makePod("frontend", "web-1", "10.0.0.1", map[string]string{"app": "web"}),
+			makePod("backend", "db-1", "10.0.0.2", map[string]string{"app": "db"}),
+			makePod("frontend", "web-2", "10.0.0.3", map[string]string{"app": "web"}),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		target   *storepb.TracepointTarget
+		expected []uuid.UUID
+	}{
+		{
+			name:     "no target",
+			target:   nil,
+			expected: []uuid.UUID{agent1, agent2, agent3, kelvin},
+		},
+		{
+			name:     "node names",
+			target:   &storepb.TracepointTarget{NodeNames: []string{"node-1", "node-3"}},
+			expected: []uuid.UUID{agent1, agent3},
+		},
+		{
+			name:     "node selector",
+			target:   &storepb.TracepointTarget{NodeSelector: "pool=gpu"},
+			expected: []uuid.UUID{agent1, agent2},
+		},
+		{
+			name:     "namespaces",
+			target:   &storepb.TracepointTarget{Namespaces: []string{"backend"}},
+			expected: []uuid.UUID{agent2},
+		},
+		{
+			name:     "pod selector",
+			target:   &storepb.TracepointTarget{PodSelector: "app in (web)"},
+			expected: []uuid.UUID{agent1, agent3},
+		},
+		{
+			name: "node selector and pod selector",
+			target: &storepb.TracepointTarget{
+				NodeSelector: "pool=gpu",
+				Namespaces:   []string{"frontend"},
+				PodSelector:  "app=web",
+			},
+			expected: []uuid.UUID{agent1},
+		},
+		{
+			name: "upids",
+			target: &storepb.TracepointTarget{
+				UPIDs: []*logicalpb.UPID{{Asid: 3, Pid: 123, TsNs: 456}},
+			},
+			expected: []uuid.UUID{agent3},
+		},
+		{
+			name:     "no matches",
+			target:   &storepb.TracepointTarget{Namespaces: []string{"unknown"}},
+			expected: []uuid.UUID{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracepointMgr := tracepoint.NewManager(nil, nil, k8sMeta, 5*time.Second)
+			defer tracepointMgr.Close()
+
+			agentIDs, err := tracepointMgr.SelectAgents(test.target, agents)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, test.expected, agentIDs)
+		})
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	assert.NoError(t, tracepoint.ValidateTarget(nil))
+	assert.NoError(t, tracepoint.ValidateTarget(&storepb.TracepointTarget{
+		NodeSelector: "pool=gpu,zone!=us-west1-a",
+		PodSelector:  "app",
+	}))
+	assert.Error(t, tracepoint.ValidateTarget(&storepb.TracepointTarget{NodeSelector: "pool in (gpu"}))
+	assert.Error(t, tracepoint.ValidateTarget(&storepb.TracepointTarget{PodSelector: "app in web"}))
+}
+
+func TestManager_ResolveTargets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockAgtMgr := mock_agent.NewMockManager(ctrl)
+	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
+
+	agent1 := uuid.Must(uuid.NewV4())
+	agent2 := uuid.Must(uuid.NewV4())
+	agent3 := uuid.Must(uuid.NewV4())
+	leftAgent := uuid.Must(uuid.NewV4())
+
+	k8sMeta := &fakeK8sMetadata{
+		nodes: []*metadatapb.Node{
+			makeNode("node-1", "10.0.0.1", nil),
+			makeNode("node-2", "10.0.0.2", nil),
+			makeNode("node-3", "10.0.0.3", nil),
+		},
+		pods: []*metadatapb.Pod{
+			makePod("frontend", "web-1", "10.0.0.1", map[string]string{"app": "web"}),
+			makePod("frontend", "web-2", "10.0.0.3", map[string]string{"app": "web"}),
+		},
+	}
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, k8sMeta, 5*time.Second)
+	defer tracepointMgr.Close()
+
+	webTP := uuid.Must(uuid.NewV4())
+	unmatchedTP := uuid.Must(uuid.NewV4())
+	unchangedTP := uuid.Must(uuid.NewV4())
+	allAgentsTP := uuid.Must(uuid.NewV4())
+
+	mockTracepointStore.
+		EXPECT().
+		GetTracepoints().
+		Return([]*storepb.TracepointInfo{
+			{
+				// The web pods moved from node-2 to node-3, and an agent selected by the target left.
+				ID:             utils.ProtoFromUUID(webTP),
+				Name:           "web",
+				ExpectedState:  statuspb.RUNNING_STATE,
+				Target:         &storepb.TracepointTarget{PodSelector: "app=web"},
+				TargetAgentIDs: []*uuidpb.UUID{utils.ProtoFromUUID(agent1), utils.ProtoFromUUID(agent2), utils.ProtoFromUUID(leftAgent)},
+			},
+			{
+				ID:             utils.ProtoFromUUID(unmatchedTP),
+				Name:           "unmatched",
+				ExpectedState:  statuspb.RUNNING_STATE,
+				Target:         &storepb.TracepointTarget{Namespaces: []string{"backend"}},
+				TargetAgentIDs: []*uuidpb.UUID{utils.ProtoFromUUID(agent2)},
+			},
+			{
+				ID:             utils.ProtoFromUUID(unchangedTP),
+				Name:           "unchanged",
+				ExpectedState:  statuspb.RUNNING_STATE,
+				Target:         &storepb.TracepointTarget{NodeNames: []string{"node-1"}},
+				TargetAgentIDs: []*uuidpb.UUID{utils.ProtoFromUUID(agent1)},
+			},
+			{
+				ID:            utils.ProtoFromUUID(allAgentsTP),
+				Name:          "all",
+				ExpectedState: statuspb.RUNNING_STATE,
+			},
+		}, nil)
+	mockAgtMgr.
+		EXPECT().
+		GetActiveAgents().
+		Return([]*agentpb.Agent{
+			makeAgent(agent1, 1, "node-1", "10.0.0.1", true),
+			makeAgent(agent2, 2, "node-2", "10.0.0.2", true),
+			makeAgent(agent3, 3, "node-3", "10.0.0.3", true),
+		}, nil)
+
+	mockAgtMgr.EXPECT().MessageAgents([]uuid.UUID{agent3}, &tracepointMsgMatcher{id: webTP, register: true}).Return(nil)
+	mockAgtMgr.EXPECT().MessageAgents([]uuid.UUID{agent2}, &tracepointMsgMatcher{id: webTP}).Return(nil)
+	mockAgtMgr.EXPECT().MessageAgents([]uuid.UUID{agent2}, &tracepointMsgMatcher{id: unmatchedTP}).Return(nil)
+
+	mockTracepointStore.
+		EXPECT().
+		UpsertTracepoint(webTP, gomock.Any()).
+		DoAndReturn(func(id uuid.UUID, tp *storepb.TracepointInfo) error {
+			assert.ElementsMatch(t, []*uuidpb.UUID{utils.ProtoFromUUID(agent1), utils.ProtoFromUUID(agent3)}, tp.TargetAgentIDs)
+			return nil
+		})
+	mockTracepointStore.
+		EXPECT().
+		UpsertTracepoint(unmatchedTP, gomock.Any()).
+		DoAndReturn(func(id uuid.UUID, tp *storepb.TracepointInfo) error {
+			assert.Empty(t, tp.TargetAgentIDs)
+			return nil
+		})
+
+	require.NoError(t, tracepointMgr.ResolveTargets())
+}
+
+// tracepointMsgMatcher matches a marshaled request to register or remove the tracepoint with the given ID.
+type tracepointMsgMatcher struct {
+	id       uuid.UUID
+	register bool
+}
+
+func (m *tracepointMsgMatcher) Matches(x interface{}) bool {
+	msg, ok := x.([]byte)
+	if !ok {
+		return false
+	}
+	vzMsg := &messagespb.VizierMessage{}
+	if err := proto.Unmarshal(msg, vzMsg); err != nil {
+		return false
+	}
+	tpMsg := vzMsg.GetTracepointMessage()
+	if m.register {
+		req := tpMsg.GetRegisterTracepointRequest()
+		return req != nil && utils.UUIDFromProtoOrNil(req.ID) == m.id
+	}
+	req := tpMsg.GetRemoveTracepointRequest()
+	return req != nil && utils.UUIDFromProtoOrNil(req.ID) == m.id
+}
+
+func (m *tracepointMsgMatcher) String() string {
+	if m.register {
+		return "registers tracepoint " + m.id.String()
+	}
+	return "removes tracepoint " + m.id.String()
+}
@@ -21,6 +21,8 @@ package tracepoint
 import (
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"sync"
 	"time"
 
@@ -31,21 +33,46 @@ import (
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/carnot/planner/dynamic_tracing/ir/logicalpb"
 	"px.dev/pixie/src/common/base/statuspb"
+	"px.dev/pixie/src/shared/k8s/metadatapb"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/vizier/messages/messagespb"
 	"px.dev/pixie/src/vizier/services/metadata/storepb"
+	"px.dev/pixie/src/vizier/services/shared/agentpb"
 )
 
 var (
 	// ErrTracepointAlreadyExists is produced if a tracepoint already exists with the given name
 	// and does not have a matching schema.
 	ErrTracepointAlreadyExists = errors.New("TracepointDeployment already exists")
+	// ErrTracepointVersionNotFound is produced if the requested version of a tracepoint does not exist.
+	ErrTracepointVersionNotFound = errors.New("Tracepoint version not found")
 )
 
+// maxTracepointVersions is the number of versions kept in the version history of each tracepoint name.
+const maxTracepointVersions = 20
+
+// targetResolveInterval is how often the targets of the running tracepoints are re-resolved.
+const targetResolveInterval = 30 * time.Second
+
 // agentMessenger is a controller that lets us message all agents and all active agents.
 type agentMessenger interface {
 	MessageAgents(agentIDs []uuid.UUID, msg []byte) error
 	MessageActiveAgents(msg []byte) error
+	GetActiveAgents() ([]*agentpb.Agent, error)
+}
+
+// DeploymentOptions are the optional settings for deploying a new version of a tracepoint.
+type DeploymentOptions struct {
+	// Creator is the user or service which created the tracepoint, recorded in its version history.
+	Creator string
+	// RolloutFraction, if between 0 and 1, is the fraction of agents which the tracepoint is first deployed to.
+	// The tracepoint is only deployed to the rest of its agents once it is running on all of the first ones.
+	RolloutFraction float32
+}
+
+// IsStaged returns whether the tracepoint is rolled out in stages.
+func (o *DeploymentOptions) IsStaged() bool {
+	return o != nil && o.RolloutFraction > 0 && o.RolloutFraction < 1
 }
 
 // Store is a datastore which can store, update, and retrieve information about tracepoints.
@@ -55,6 +82,7 @@ type Store interface {
 	GetTracepoints() ([]*storepb.TracepointInfo, error)
 	UpdateTracepointState(*storepb.AgentTracepointStatus) error
 	GetTracepointStates(uuid.UUID) ([]*storepb.AgentTracepointStatus, error)
+	DeleteTracepointState(uuid.UUID, uuid.UUID) error
 	SetTracepointWithName(string, uuid.UUID) error
 	GetTracepointsWithNames([]string) ([]*uuid.UUID, error)
 	GetTracepointsForIDs([]uuid.UUID) ([]*storepb.TracepointInfo, error)
@@ -63,26 +91,36 @@ type Store interface {
 	DeleteTracepoint(uuid.UUID) error
 	DeleteTracepointsForAgent(uuid.UUID) error
 	GetTracepointTTLs() ([]uuid.UUID, []time.Time, error)
+	UpsertTracepointVersion(*storepb.TracepointVersion) error
+	GetTracepointVersion(string, int64) (*storepb.TracepointVersion, error)
+	GetTracepointVersions(string) ([]*storepb.TracepointVersion, error)
+	DeleteTracepointVersions(string, []int64) error
 }
 
 // Manager manages the tracepoints deployed in the cluster.
 type Manager struct {
-	ts     Store
-	agtMgr agentMessenger
+	ts      Store
+	agtMgr  agentMessenger
+	k8sMeta K8sMetadataGetter
+
+	// rolloutMu guards the read-modify-write updates of version histories and staged rollouts.
+	rolloutMu sync.Mutex
 
 	done chan struct{}
 	once sync.Once
 }
 
 // NewManager creates a new tracepoint manager.
-func NewManager(ts Store, agtMgr agentMessenger, ttlReaperDuration time.Duration) *Manager {
+func NewManager(ts Store, agtMgr agentMessenger, k8sMeta K8sMetadataGetter, ttlReaperDuration time.Duration) *Manager {
 	tm := &Manager{
-		ts:     ts,
-		agtMgr: agtMgr,
-		done:   make(chan struct{}),
+		ts:      ts,
+		agtMgr:  agtMgr,
+		k8sMeta: k8sMeta,
+		done:    make(chan struct{}),
 	}
 
 	go tm.watchForTracepointExpiry(ttlReaperDuration)
+	go tm.watchForTargetChanges()
 	return tm
 }
 
@@ -99,6 +137,24 @@ func (m *Manager) watchForTracepointExpiry(ttlReaperDuration time.Duration) {
 	}
 }
 
+// watchForTargetChanges periodically re-resolves the targets of the running tracepoints, so that they follow
+// the nodes and pods which they select.
+func (m *Manager) watchForTargetChanges() {
+	ticker := time.NewTicker(targetResolveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			err := m.ResolveTargets()
+			if err != nil {
+				log.WithError(err).Warn("error encountered when trying to resolve tracepoint targets")
+			}
+		}
+	}
+}
+
 func (m *Manager) terminateExpiredTracepoints() {
 	tps, err := m.ts.GetTracepoints()
 	if err != nil {
@@ -178,8 +234,47 @@ func (m *Manager) deleteTracepoint(id uuid.UUID) error {
 	return m.ts.DeleteTracepoint(id)
 }
 
-// CreateTracepoint creates and stores info about the given tracepoint.
-func (m *Manager) CreateTracepoint(tracepointName string, tracepointDeployment *logicalpb.TracepointDeployment, ttl time.Duration) (*uuid.UUID, error) {
+// CreateTracepoint creates and stores info about the given tracepoint. If the tracepoint's definition has changed,
+// it is stored as a new version in the tracepoint's version history.
+func (m *Manager) CreateTracepoint(tracepointName string, tracepointDeployment *logicalpb.TracepointDeployment, target *storepb.TracepointTarget, ttl time.Duration, opts *DeploymentOptions) (*uuid.UUID, error) {
+	return m.createTracepoint(tracepointName, tracepointDeployment, target, ttl, opts, 0)
+}
+
+// RollbackTracepoint redeploys the given version of the tracepoint with the given name. The rollback is
+// recorded as a new version in the tracepoint's version history.
+func (m *Manager) RollbackTracepoint(tracepointName string, version int64, ttl time.Duration, opts *DeploymentOptions) (*uuid.UUID, error) {
+	prevVersion, err := m.ts.GetTracepointVersion(tracepointName, version)
+	if err != nil {
+		return nil, err
+	}
+	if prevVersion == nil {
+		return nil, ErrTracepointVersionNotFound
+	}
+	return m.createTracepoint(tracepointName, prevVersion.Tracepoint, prevVersion.Target, ttl, opts, version)
+}
+
+// GetTracepointVersions gets the version history of the tracepoint with the given name, ordered from oldest
+// to newest.
+func (m *Manager) GetTracepointVersions(tracepointName string) ([]*storepb.TracepointVersion, error) {
+	versions, err := m.ts.GetTracepointVersions(tracepointName)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+	return versions, nil
+}
+
+func (m *Manager) createTracepoint(tracepointName string, tracepointDeployment *logicalpb.TracepointDeployment, target *storepb.TracepointTarget, ttl time.Duration, opts *DeploymentOptions, rollbackOfVersion int64) (*uuid.UUID, error) {
+	if isEmptyTarget(target) {
+		target = nil
+	}
+
+	// The previous version of the tracepoint, which keeps running on the agents outside of a staged rollout
+	// until the rollout completes.
+	var previousID *uuid.UUID
+
 	// Check to see if a tracepoint with the matching name already exists.
 	resp, err := m.ts.GetTracepointsWithNames([]string{tracepointName})
 	if err != nil {
@@ -217,23 +312,69 @@ func (m *Manager) CreateTracepoint(tracepointName string, tracepointDeployment *
 			} else {
 				allTpsSame = false
 			}
+			// A tracepoint with different targets must be redeployed, so that it is removed from the agents
+			// which are no longer targeted.
+			if !proto.Equal(prevTracepoint.Target, target) {
+				allTpsSame = false
+			}
+
+			// If the previous tracepoint is still being rolled out, the version before it is still running on
+			// the agents outside of the rollout.
+			var rolloutPrevID *uuid.UUID
+			if isCanary(prevTracepoint) && prevTracepoint.Rollout.PreviousID != nil {
+				id := utils.UUIDFromProtoOrNil(prevTracepoint.Rollout.PreviousID)
+				rolloutPrevID = &id
+			}
 
 			if allTpsSame {
 				err = m.ts.SetTracepointTTL(*prevTracepointID, ttl)
 				if err != nil {
 					return nil, err
 				}
+				if rolloutPrevID != nil {
+					// Keep the version outside of the rollout alive until the rollout completes.
+					err = m.ts.SetTracepointTTL(*rolloutPrevID, ttl)
+					if err != nil {
+						return nil, err
+					}
+				}
 				return prevTracepointID, ErrTracepointAlreadyExists
 			}
 
-			// Something has changed, so trigger termination of the old tracepoint.
-			err = m.ts.DeleteTracepointTTLs([]uuid.UUID{*prevTracepointID})
-			if err != nil {
-				return nil, err
+			// Something has changed, so trigger termination of the old tracepoint. In a staged rollout, the old
+			// tracepoint is only terminated once the rollout completes.
+			toTerminate := []uuid.UUID{*prevTracepointID}
+			if opts.IsStaged() {
+				toTerminate = nil
+				previousID = prevTracepointID
+				if rolloutPrevID != nil {
+					toTerminate = []uuid.UUID{*prevTracepointID}
+					previousID = rolloutPrevID
+				}
+			} else if rolloutPrevID != nil {
+				toTerminate = append(toTerminate, *rolloutPrevID)
+			}
+			if len(toTerminate) > 0 {
+				err = m.ts.DeleteTracepointTTLs(toTerminate)
+				if err != nil {
+					return nil, err
+				}
 			}
 		}
 	}
 
+	m.rolloutMu.Lock()
+	defer m.rolloutMu.Unlock()
+
+	versions, err := m.GetTracepointVersions(tracepointName)
+	if err != nil {
+		return nil, err
+	}
+	version := int64(1)
+	if len(versions) > 0 {
+		version = versions[len(versions)-1].Version + 1
+	}
+
 	tpID, err := uuid.NewV4()
 	if err != nil {
 		return nil, err
@@ -243,6 +384,17 @@ func (m *Manager) CreateTracepoint(tracepointName string, tracepointDeployment *
 		Tracepoint:    tracepointDeployment,
 		Name:          tracepointName,
 		ExpectedState: statuspb.RUNNING_STATE,
+		Target:        target,
+		Version:       version,
+	}
+	if opts.IsStaged() {
+		newTracepoint.Rollout = &storepb.TracepointRollout{
+			State:    storepb.ROLLOUT_CANARY,
+			Fraction: opts.RolloutFraction,
+		}
+		if previousID != nil {
+			newTracepoint.Rollout.PreviousID = utils.ProtoFromUUID(*previousID)
+		}
 	}
 	err = m.ts.UpsertTracepoint(tpID, newTracepoint)
 	if err != nil {
@@ -256,9 +408,308 @@ func (m *Manager) CreateTracepoint(tracepointName string, tracepointDeployment *
 	if err != nil {
 		return nil, err
 	}
+
+	tpVersion := &storepb.TracepointVersion{
+		Name:              tracepointName,
+		Version:           version,
+		ID:                utils.ProtoFromUUID(tpID),
+		Tracepoint:        tracepointDeployment,
+		Target:            target,
+		CreateTimeNS:      time.Now().UnixNano(),
+		RollbackOfVersion: rollbackOfVersion,
+	}
+	if opts != nil {
+		tpVersion.Creator = opts.Creator
+	}
+	if newTracepoint.Rollout != nil {
+		tpVersion.RolloutState = newTracepoint.Rollout.State
+	}
+	err = m.ts.UpsertTracepointVersion(tpVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop the oldest versions, so that the history doesn't grow without bound.
+	if numVersions := len(versions) + 1; numVersions > maxTracepointVersions {
+		var expired []int64
+		for _, v := range versions[:numVersions-maxTracepointVersions] {
+			expired = append(expired, v.Version)
+		}
+		err = m.ts.DeleteTracepointVersions(tracepointName, expired)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &tpID, nil
 }
 
+func isCanary(tp *storepb.TracepointInfo) bool {
+	return tp != nil && tp.Rollout != nil && tp.Rollout.State == storepb.ROLLOUT_CANARY
+}
+
+// SelectRolloutAgents returns the agents which the given tracepoint should be deployed to now, out of the agents
+// selected by its target. If the tracepoint is rolled out in stages, this picks the canaries, which the tracepoint
+// is deployed to first, and removes the previous version of the tracepoint from them.
+func (m *Manager) SelectRolloutAgents(tracepointID uuid.UUID, agentIDs []uuid.UUID) ([]uuid.UUID, error) {
+	m.rolloutMu.Lock()
+	defer m.rolloutMu.Unlock()
+
+	tp, err := m.ts.GetTracepoint(tracepointID)
+	if err != nil {
+		return nil, err
+	}
+	if !isCanary(tp) {
+		return agentIDs, nil
+	}
+	if len(tp.Rollout.CanaryAgentIDs) > 0 {
+		// The canaries have already been picked.
+		canaries := make([]uuid.UUID, len(tp.Rollout.CanaryAgentIDs))
+		for i, id := range tp.Rollout.CanaryAgentIDs {
+			canaries[i] = utils.UUIDFromProtoOrNil(id)
+		}
+		return canaries, nil
+	}
+
+	numCanaries := int(math.Ceil(float64(tp.Rollout.Fraction) * float64(len(agentIDs))))
+	if numCanaries >= len(agentIDs) {
+		// There are too few agents to roll out in stages.
+		err = m.completeRollout(tp, nil)
+		if err != nil {
+			return nil, err
+		}
+		return agentIDs, nil
+	}
+
+	// Pick the canaries in a stable order, so that the same agents are picked if the tracepoint is redeployed.
+	sorted := make([]uuid.UUID, len(agentIDs))
+	copy(sorted, agentIDs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].String() < sorted[j].String()
+	})
+	canaries := sorted[:numCanaries]
+
+	for _, id := range canaries {
+		tp.Rollout.CanaryAgentIDs = append(tp.Rollout.CanaryAgentIDs, utils.ProtoFromUUID(id))
+	}
+	err = m.ts.UpsertTracepoint(tracepointID, tp)
+	if err != nil {
+		return nil, err
+	}
+
+	if tp.Rollout.PreviousID != nil {
+		// Replace the previous version on the canaries.
+		err = m.removeTracepointFromAgents(utils.UUIDFromProtoOrNil(tp.Rollout.PreviousID), canaries)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return canaries, nil
+}
+
+func (m *Manager) removeTracepointFromAgents(tracepointID uuid.UUID, agentIDs []uuid.UUID) error {
+	tracepointReq := messagespb.VizierMessage{
+		Msg: &messagespb.VizierMessage_TracepointMessage{
+			TracepointMessage: &messagespb.TracepointMessage{
+				Msg: &messagespb.TracepointMessage_RemoveTracepointRequest{
+					RemoveTracepointRequest: &messagespb.RemoveTracepointRequest{
+						ID: utils.ProtoFromUUID(tracepointID),
+					},
+				},
+			},
+		},
+	}
+	msg, err := tracepointReq.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return m.agtMgr.MessageAgents(agentIDs, msg)
+}
+
+// advanceRollout moves the staged rollout of the given tracepoint forward after a canary reports a new state.
+// The rollout is aborted if the canary failed, and completed once all of the canaries are running.
+func (m *Manager) advanceRollout(tracepointID uuid.UUID, agentID *uuidpb.UUID, state statuspb.LifeCycleState) error {
+	tp, err := m.ts.GetTracepoint(tracepointID)
+	if err != nil {
+		return err
+	}
+	if !isCanary(tp) {
+		return nil
+	}
+
+	isCanaryAgent := false
+	for _, id := range tp.Rollout.CanaryAgentIDs {
+		if id.Equal(agentID) {
+			isCanaryAgent = true
+			break
+		}
+	}
+	if !isCanaryAgent {
+		return nil
+	}
+
+	switch state {
+	case statuspb.FAILED_STATE:
+		return m.abortRollout(tp)
+	case statuspb.RUNNING_STATE:
+		states, err := m.ts.GetTracepointStates(tracepointID)
+		if err != nil {
+			return err
+		}
+		running := make(map[uuid.UUID]bool)
+		for _, s := range states {
+			if s != nil && s.State == statuspb.RUNNING_STATE {
+				running[utils.UUIDFromProtoOrNil(s.AgentID)] = true
+			}
+		}
+		var canaries []uuid.UUID
+		for _, id := range tp.Rollout.CanaryAgentIDs {
+			canaryID := utils.UUIDFromProtoOrNil(id)
+			if !running[canaryID] {
+				return nil
+			}
+			canaries = append(canaries, canaryID)
+		}
+		return m.deployToRemainingAgents(tp, canaries)
+	}
+	return nil
+}
+
+// deployToRemainingAgents completes the staged rollout of the tracepoint by deploying it to the agents outside
+// of the canaries.
+func (m *Manager) deployToRemainingAgents(tp *storepb.TracepointInfo, canaries []uuid.UUID) error {
+	err := m.completeRollout(tp, canaries)
+	if err != nil {
+		return err
+	}
+
+	agents, err := m.agtMgr.GetActiveAgents()
+	if err != nil {
+		return err
+	}
+	agentIDs, err := m.SelectAgents(tp.Target, agents)
+	if err != nil {
+		return err
+	}
+	isCanary := make(map[uuid.UUID]bool)
+	for _, id := range canaries {
+		isCanary[id] = true
+	}
+	err = m.setTargetAgents(tp, agentIDs)
+	if err != nil {
+		return err
+	}
+	var remaining []uuid.UUID
+	for _, id := range agentIDs {
+		if !isCanary[id] {
+			remaining = append(remaining, id)
+		}
+	}
+	if len(remaining) == 0 {
+		return nil
+	}
+	return m.RegisterTracepoint(remaining, utils.UUIDFromProtoOrNil(tp.ID), tp.Tracepoint)
+}
+
+// completeRollout marks the staged rollout of the tracepoint as complete, and terminates the previous version.
+func (m *Manager) completeRollout(tp *storepb.TracepointInfo, canaries []uuid.UUID) error {
+	tpID := utils.UUIDFromProtoOrNil(tp.ID)
+	tp.Rollout.State = storepb.ROLLOUT_COMPLETE
+	err := m.ts.UpsertTracepoint(tpID, tp)
+	if err != nil {
+		return err
+	}
+	err = m.setVersionRolloutState(tp, storepb.ROLLOUT_COMPLETE)
+	if err != nil {
+		return err
+	}
+	if tp.Rollout.PreviousID == nil {
+		return nil
+	}
+	return m.ts.DeleteTracepointTTLs([]uuid.UUID{utils.UUIDFromProtoOrNil(tp.Rollout.PreviousID)})
+}
+
+// abortRollout terminates the tracepoint after it failed on a canary, and restores the previous version on the
+// canaries.
+func (m *Manager) abortRollout(tp *storepb.TracepointInfo) error {
+	tpID := utils.UUIDFromProtoOrNil(tp.ID)
+	log.WithField("tracepoint", tp.Name).WithField("version", tp.Version).Info("Aborting tracepoint rollout")
+
+	tp.Rollout.State = storepb.ROLLOUT_ABORTED
+	err := m.ts.UpsertTracepoint(tpID, tp)
+	if err != nil {
+		return err
+	}
+	err = m.setVersionRolloutState(tp, storepb.ROLLOUT_ABORTED)
+	if err != nil {
+		return err
+	}
+	err = m.ts.DeleteTracepointTTLs([]uuid.UUID{tpID})
+	if err != nil {
+		return err
+	}
+	err = m.terminateTracepoint(tpID)
+	if err != nil {
+		return err
+	}
+
+	if tp.Rollout.PreviousID == nil {
+		return nil
+	}
+	prevID := utils.UUIDFromProtoOrNil(tp.Rollout.PreviousID)
+	prevTracepoint, err := m.ts.GetTracepoint(prevID)
+	if err != nil {
+		return err
+	}
+	if prevTracepoint == nil || prevTracepoint.ExpectedState == statuspb.TERMINATED_STATE {
+		return nil
+	}
+	err = m.ts.SetTracepointWithName(tp.Name, prevID)
+	if err != nil {
+		return err
+	}
+	canaries := make([]uuid.UUID, len(tp.Rollout.CanaryAgentIDs))
+	for i, id := range tp.Rollout.CanaryAgentIDs {
+		canaries[i] = utils.UUIDFromProtoOrNil(id)
+	}
+	return m.RegisterTracepoint(canaries, prevID, prevTracepoint.Tracepoint)
+}
+
+func (m *Manager) setVersionRolloutState(tp *storepb.TracepointInfo, state storepb.TracepointRollout_State) error {
+	if tp.Version == 0 {
+		return nil
+	}
+	tpVersion, err := m.ts.GetTracepointVersion(tp.Name, tp.Version)
+	if err != nil || tpVersion == nil {
+		return err
+	}
+	tpVersion.RolloutState = state
+	return m.ts.UpsertTracepointVersion(tpVersion)
+}
+
+// recordVersionStatus records the state of the tracepoint on an agent in the tracepoint's version history.
+func (m *Manager) recordVersionStatus(tp *storepb.TracepointInfo, agentStatus *storepb.AgentTracepointStatus) error {
+	if tp.Version == 0 {
+		return nil
+	}
+	tpVersion, err := m.ts.GetTracepointVersion(tp.Name, tp.Version)
+	if err != nil || tpVersion == nil {
+		return err
+	}
+	found := false
+	for i, s := range tpVersion.AgentStatuses {
+		if s.AgentID.Equal(agentStatus.AgentID) {
+			tpVersion.AgentStatuses[i] = agentStatus
+			found = true
+			break
+		}
+	}
+	if !found {
+		tpVersion.AgentStatuses = append(tpVersion.AgentStatuses, agentStatus)
+	}
+	return m.ts.UpsertTracepointVersion(tpVersion)
+}
+
 // GetAllTracepoints gets all the tracepoints currently tracked by the metadata service.
 func (m *Manager) GetAllTracepoints() ([]*storepb.TracepointInfo, error) {
 	return m.ts.GetTracepoints()
@@ -266,8 +717,36 @@ func (m *Manager) GetAllTracepoints() ([]*storepb.TracepointInfo, error) {
 
 // UpdateAgentTracepointStatus updates the tracepoint info with the new agent tracepoint status.
 func (m *Manager) UpdateAgentTracepointStatus(tracepointID *uuidpb.UUID, agentID *uuidpb.UUID, state statuspb.LifeCycleState, status *statuspb.Status) error {
+	tID := utils.UUIDFromProtoOrNil(tracepointID)
+	tracepointState := &storepb.AgentTracepointStatus{
+		State:   state,
+		Status:  status,
+		ID:      tracepointID,
+		AgentID: agentID,
+	}
+
+	m.rolloutMu.Lock()
+	defer m.rolloutMu.Unlock()
+
+	tp, err := m.ts.GetTracepoint(tID)
+	if err != nil {
+		return err
+	}
+	if tp != nil {
+		err = m.recordVersionStatus(tp, tracepointState)
+		if err != nil {
+			return err
+		}
+	}
+
+	if state == statuspb.TERMINATED_STATE && tp != nil && tp.ExpectedState != statuspb.TERMINATED_STATE &&
+		!isEmptyTarget(tp.Target) && !containsAgent(tp.TargetAgentIDs, agentID) {
+		// The tracepoint was removed from an agent which its target no longer selects. It keeps running on the
+		// other agents, so the agent's state is dropped rather than terminating the whole tracepoint.
+		return m.ts.DeleteTracepointState(tID, utils.UUIDFromProtoOrNil(agentID))
+	}
+
 	if state == statuspb.TERMINATED_STATE { // If all agent tracepoint statuses are now terminated, we can finally delete the tracepoint from the datastore.
-		tID := utils.UUIDFromProtoOrNil(tracepointID)
 		states, err := m.GetTracepointStates(tID)
 		if err != nil {
 			return err
@@ -285,14 +764,15 @@ func (m *Manager) UpdateAgentTracepointStatus(tracepointID *uuidpb.UUID, agentID
 		}
 	}
 
-	tracepointState := &storepb.AgentTracepointStatus{
-		State:   state,
-		Status:  status,
-		ID:      tracepointID,
-		AgentID: agentID,
+	err = m.ts.UpdateTracepointState(tracepointState)
+	if err != nil {
+		return err
 	}
 
-	return m.ts.UpdateTracepointState(tracepointState)
+	if !isCanary(tp) {
+		return nil
+	}
+	return m.advanceRollout(tID, agentID, state)
 }
 
 // RegisterTracepoint sends requests to the given agents to register the specified tracepoint.
@@ -317,6 +797,141 @@ func (m *Manager) RegisterTracepoint(agentIDs []uuid.UUID, tracepointID uuid.UUI
 	return m.agtMgr.MessageAgents(agentIDs, msg)
 }
 
+// SelectAgents returns the IDs of the given agents which are selected by the tracepoint target.
+// If the target is empty, all of the agents are selected.
+func (m *Manager) SelectAgents(target *storepb.TracepointTarget, agents []*agentpb.Agent) ([]uuid.UUID, error) {
+	var nodes []*metadatapb.Node
+	var pods []*metadatapb.Pod
+	if m.k8sMeta != nil && !isEmptyTarget(target) {
+		nodes = m.k8sMeta.GetNodes()
+		pods = m.k8sMeta.GetPods()
+	}
+	return selectAgents(target, agents, nodes, pods)
+}
+
+// SetTargetAgents records the agents which are selected by the target of the tracepoint, which the tracepoint
+// has been deployed to.
+func (m *Manager) SetTargetAgents(tracepointID uuid.UUID, target *storepb.TracepointTarget, agentIDs []uuid.UUID) error {
+	if isEmptyTarget(target) {
+		return nil
+	}
+
+	m.rolloutMu.Lock()
+	defer m.rolloutMu.Unlock()
+
+	tp, err := m.ts.GetTracepoint(tracepointID)
+	if err != nil || tp == nil {
+		return err
+	}
+	return m.setTargetAgents(tp, agentIDs)
+}
+
+func (m *Manager) setTargetAgents(tp *storepb.TracepointInfo, agentIDs []uuid.UUID) error {
+	if isEmptyTarget(tp.Target) {
+		return nil
+	}
+	tp.TargetAgentIDs = make([]*uuidpb.UUID, len(agentIDs))
+	for i, id := range agentIDs {
+		tp.TargetAgentIDs[i] = utils.ProtoFromUUID(id)
+	}
+	return m.ts.UpsertTracepoint(utils.UUIDFromProtoOrNil(tp.ID), tp)
+}
+
+func containsAgent(agentIDs []*uuidpb.UUID, agentID *uuidpb.UUID) bool {
+	for _, id := range agentIDs {
+		if id.Equal(agentID) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveTargets re-resolves the targets of the running tracepoints against the active agents and the current
+// nodes and pods. Each tracepoint is registered on the agents which its target newly selects, and removed from
+// the agents which its target no longer selects. Tracepoints without a target run on all agents, and tracepoints
+// which are still being rolled out only run on their canaries, so neither is re-resolved.
+func (m *Manager) ResolveTargets() error {
+	m.rolloutMu.Lock()
+	defer m.rolloutMu.Unlock()
+
+	tps, err := m.ts.GetTracepoints()
+	if err != nil {
+		return err
+	}
+	var targeted []*storepb.TracepointInfo
+	for _, tp := range tps {
+		if tp != nil && tp.ExpectedState != statuspb.TERMINATED_STATE && !isEmptyTarget(tp.Target) && !isCanary(tp) {
+			targeted = append(targeted, tp)
+		}
+	}
+	if len(targeted) == 0 {
+		return nil
+	}
+
+	agents, err := m.agtMgr.GetActiveAgents()
+	if err != nil {
+		return err
+	}
+	active := make(map[uuid.UUID]bool)
+	for _, agt := range agents {
+		active[utils.UUIDFromProtoOrNil(agt.Info.AgentID)] = true
+	}
+
+	for _, tp := range targeted {
+		tpID := utils.UUIDFromProtoOrNil(tp.ID)
+		selected, err := m.SelectAgents(tp.Target, agents)
+		if err != nil {
+			log.WithError(err).WithField("tracepoint", tp.Name).Warn("Failed to resolve tracepoint target")
+			continue
+		}
+
+		isSelected := make(map[uuid.UUID]bool)
+		for _, id := range selected {
+			isSelected[id] = true
+		}
+		wasSelected := make(map[uuid.UUID]bool)
+		var removed []uuid.UUID
+		for _, pb := range tp.TargetAgentIDs {
+			id := utils.UUIDFromProtoOrNil(pb)
+			wasSelected[id] = true
+			// Agents which have left the cluster no longer run the tracepoint.
+			if !isSelected[id] && active[id] {
+				removed = append(removed, id)
+			}
+		}
+		var added []uuid.UUID
+		for _, id := range selected {
+			if !wasSelected[id] {
+				added = append(added, id)
+			}
+		}
+		if len(added) == 0 && len(selected) == len(tp.TargetAgentIDs) {
+			continue
+		}
+
+		if len(added) > 0 {
+			err = m.RegisterTracepoint(added, tpID, tp.Tracepoint)
+			if err != nil {
+				return err
+			}
+		}
+		if len(removed) > 0 {
+			err = m.removeTracepointFromAgents(tpID, removed)
+			if err != nil {
+				return err
+			}
+		}
+		if len(selected) == 0 {
+			log.WithField("tracepoint", tp.Name).Info("No agents match the tracepoint target")
+		}
+		err = m.setTargetAgents(tp, selected)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetTracepointInfo gets the status for the tracepoint with the given ID.
 func (m *Manager) GetTracepointInfo(tracepointID uuid.UUID) (*storepb.TracepointInfo, error) {
 	return m.ts.GetTracepoint(tracepointID)
@@ -348,6 +963,18 @@ func (m *Manager) RemoveTracepoints(names []string) error {
 		ids[i] = *id
 	}
 
+	// Tracepoints which are being rolled out in stages also have a previous version running, which
+	// must be removed as well.
+	tps, err := m.ts.GetTracepointsForIDs(ids)
+	if err != nil {
+		return err
+	}
+	for _, tp := range tps {
+		if isCanary(tp) && tp.Rollout.PreviousID != nil {
+			ids = append(ids, utils.UUIDFromProtoOrNil(tp.Rollout.PreviousID))
+		}
+	}
+
 	return m.ts.DeleteTracepointTTLs(ids)
 }
 
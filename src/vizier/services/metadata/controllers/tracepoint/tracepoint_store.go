@@ -19,6 +19,7 @@
 package tracepoint
 
 import (
+	"fmt"
 	"path"
 	"strings"
 	"time"
@@ -38,6 +39,8 @@ const (
 	tracepointStatesPrefix = "/tracepointStates/"
 	tracepointTTLsPrefix   = "/tracepointTTL/"
 	tracepointNamesPrefix  = "/tracepointName/"
+	// tracepointVersionsPrefix is the prefix for the version history of each tracepoint name.
+	tracepointVersionsPrefix = "/tracepointVersion/"
 )
 
 // Datastore implements the TracepointStore interface on a given Datastore.
@@ -70,6 +73,17 @@ func getTracepointTTLKey(tracepointID uuid.UUID) string {
 	return path.Join(tracepointTTLsPrefix, tracepointID.String())
 }
 
+func getTracepointVersionsKey(tracepointName string) string {
+	// The trailing slash ensures that the versions of a tracepoint aren't mixed up with those of a tracepoint
+	// whose name has this name as a prefix.
+	return path.Join(tracepointVersionsPrefix, tracepointName) + "/"
+}
+
+func getTracepointVersionKey(tracepointName string, version int64) string {
+	// Versions are zero-padded so that they are listed in order.
+	return path.Join(tracepointVersionsPrefix, tracepointName, fmt.Sprintf("%020d", version))
+}
+
 // GetTracepointsWithNames gets which tracepoint is associated with the given name.
 func (t *Datastore) GetTracepointsWithNames(tracepointNames []string) ([]*uuid.UUID, error) {
 	eg := errgroup.Group{}
@@ -214,6 +228,11 @@ func (t *Datastore) UpdateTracepointState(state *storepb.AgentTracepointStatus)
 	return t.ds.Set(getTracepointStateKey(tpID, utils.UUIDFromProtoOrNil(state.AgentID)), string(val))
 }
 
+// DeleteTracepointState deletes the state of the tracepoint on the given agent.
+func (t *Datastore) DeleteTracepointState(tracepointID uuid.UUID, agentID uuid.UUID) error {
+	return t.ds.Delete(getTracepointStateKey(tracepointID, agentID))
+}
+
 // GetTracepointStates gets all the agentTracepoint states for the given tracepoint.
 func (t *Datastore) GetTracepointStates(tracepointID uuid.UUID) ([]*storepb.AgentTracepointStatus, error) {
 	_, vals, err := t.ds.GetWithPrefix(getTracepointStatesKey(tracepointID))
@@ -307,3 +326,61 @@ func (t *Datastore) GetTracepointTTLs() ([]uuid.UUID, []time.Time, error) {
 
 	return ids, expirations, nil
 }
+
+// UpsertTracepointVersion updates or creates an entry in the version history of a tracepoint.
+func (t *Datastore) UpsertTracepointVersion(version *storepb.TracepointVersion) error {
+	val, err := version.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return t.ds.Set(getTracepointVersionKey(version.Name, version.Version), string(val))
+}
+
+// GetTracepointVersion gets the given version of the tracepoint with the given name, if it exists.
+func (t *Datastore) GetTracepointVersion(tracepointName string, version int64) (*storepb.TracepointVersion, error) {
+	resp, err := t.ds.Get(getTracepointVersionKey(tracepointName, version))
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, nil
+	}
+
+	versionPb := &storepb.TracepointVersion{}
+	err = proto.Unmarshal(resp, versionPb)
+	if err != nil {
+		return nil, err
+	}
+	return versionPb, nil
+}
+
+// GetTracepointVersions gets the version history of the tracepoint with the given name, ordered from oldest
+// to newest.
+func (t *Datastore) GetTracepointVersions(tracepointName string) ([]*storepb.TracepointVersion, error) {
+	_, vals, err := t.ds.GetWithPrefix(getTracepointVersionsKey(tracepointName))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*storepb.TracepointVersion, 0, len(vals))
+	for _, val := range vals {
+		pb := &storepb.TracepointVersion{}
+		err := proto.Unmarshal(val, pb)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, pb)
+	}
+	return versions, nil
+}
+
+// DeleteTracepointVersions deletes the given versions from the version history of a tracepoint.
+func (t *Datastore) DeleteTracepointVersions(tracepointName string, versions []int64) error {
+	keys := make([]string, len(versions))
+	for i, v := range versions {
+		keys[i] = getTracepointVersionKey(tracepointName, v)
+	}
+
+	return t.ds.DeleteAll(keys)
+}
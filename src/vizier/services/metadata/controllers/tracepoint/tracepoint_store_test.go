@@ -362,3 +362,46 @@ func TestTracepointStore_GetTracepointTTLs(t *testing.T) {
 	assert.Contains(t, tracepoints, s1ID)
 	assert.Contains(t, tracepoints, s2ID)
 }
+
+func TestTracepointStore_TracepointVersions(t *testing.T) {
+	_, ts, cleanup := setupTest(t)
+	defer cleanup()
+
+	for _, v := range []int64{2, 10, 1} {
+		err := ts.UpsertTracepointVersion(&storepb.TracepointVersion{
+			Name:    "test",
+			Version: v,
+			ID:      utils.ProtoFromUUID(uuid.Must(uuid.NewV4())),
+		})
+		require.NoError(t, err)
+	}
+	// A tracepoint whose name has the other name as a prefix.
+	err := ts.UpsertTracepointVersion(&storepb.TracepointVersion{
+		Name:    "test2",
+		Version: 1,
+	})
+	require.NoError(t, err)
+
+	versions, err := ts.GetTracepointVersions("test")
+	require.NoError(t, err)
+	require.Equal(t, 3, len(versions))
+	assert.Equal(t, int64(1), versions[0].Version)
+	assert.Equal(t, int64(2), versions[1].Version)
+	assert.Equal(t, int64(10), versions[2].Version)
+
+	version, err := ts.GetTracepointVersion("test", 2)
+	require.NoError(t, err)
+	assert.Equal(t, versions[1], version)
+
+	version, err = ts.GetTracepointVersion("test", 3)
+	require.NoError(t, err)
+	assert.Nil(t, version)
+
+	err = ts.DeleteTracepointVersions("test", []int64{1, 2})
+	require.NoError(t, err)
+
+	versions, err = ts.GetTracepointVersions("test")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(versions))
+	assert.Equal(t, int64(10), versions[0].Version)
+}
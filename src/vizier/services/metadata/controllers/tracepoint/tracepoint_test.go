@@ -23,12 +23,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/carnot/planner/dynamic_tracing/ir/logicalpb"
 	"px.dev/pixie/src/common/base/statuspb"
 	"px.dev/pixie/src/utils"
@@ -37,6 +40,8 @@ import (
 	"px.dev/pixie/src/vizier/services/metadata/controllers/tracepoint"
 	mock_tracepoint "px.dev/pixie/src/vizier/services/metadata/controllers/tracepoint/mock"
 	"px.dev/pixie/src/vizier/services/metadata/storepb"
+	"px.dev/pixie/src/vizier/services/shared/agentpb"
+	"px.dev/pixie/src/vizier/utils/datastore/pebbledb"
 )
 
 func TestCreateTracepoint(t *testing.T) {
@@ -261,6 +266,11 @@ func TestCreateTracepoint(t *testing.T) {
 			var newID uuid.UUID
 
 			if !test.expectError && !test.expectTTLUpdateOnly {
+				mockTracepointStore.
+					EXPECT().
+					GetTracepointVersions("test_tracepoint").
+					Return(nil, nil)
+
 				mockTracepointStore.
 					EXPECT().
 					UpsertTracepoint(gomock.Any(), gomock.Any()).
@@ -271,6 +281,7 @@ func TestCreateTracepoint(t *testing.T) {
 							Name:          "test_tracepoint",
 							ID:            utils.ProtoFromUUID(id),
 							ExpectedState: statuspb.RUNNING_STATE,
+							Version:       1,
 						}, tpInfo)
 						return nil
 					})
@@ -290,13 +301,24 @@ func TestCreateTracepoint(t *testing.T) {
 						assert.Equal(t, newID, id)
 						return nil
 					})
+
+				mockTracepointStore.
+					EXPECT().
+					UpsertTracepointVersion(gomock.Any()).
+					DoAndReturn(func(v *storepb.TracepointVersion) error {
+						assert.Equal(t, "test_tracepoint", v.Name)
+						assert.Equal(t, int64(1), v.Version)
+						assert.Equal(t, utils.ProtoFromUUID(newID), v.ID)
+						assert.Equal(t, test.newTracepoint, v.Tracepoint)
+						return nil
+					})
 			}
 
 			mockAgtMgr := mock_agent.NewMockManager(ctrl)
-			tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+			tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 			defer tracepointMgr.Close()
 
-			actualTpID, err := tracepointMgr.CreateTracepoint("test_tracepoint", test.newTracepoint, time.Second*5)
+			actualTpID, err := tracepointMgr.CreateTracepoint("test_tracepoint", test.newTracepoint, nil, time.Second*5, nil)
 			if test.expectError || test.expectTTLUpdateOnly {
 				assert.Equal(t, tracepoint.ErrTracepointAlreadyExists, err)
 			} else {
@@ -314,7 +336,7 @@ func TestGetTracepoints(t *testing.T) {
 	mockAgtMgr := mock_agent.NewMockManager(ctrl)
 	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
 
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 	defer tracepointMgr.Close()
 
 	tID1 := uuid.Must(uuid.NewV4())
@@ -345,7 +367,7 @@ func TestGetTracepointInfo(t *testing.T) {
 	mockAgtMgr := mock_agent.NewMockManager(ctrl)
 	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
 
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 	defer tracepointMgr.Close()
 
 	tID1 := uuid.Must(uuid.NewV4())
@@ -370,7 +392,7 @@ func TestGetTracepointStates(t *testing.T) {
 	mockAgtMgr := mock_agent.NewMockManager(ctrl)
 	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
 
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 	defer tracepointMgr.Close()
 
 	agentUUID1 := uuid.Must(uuid.NewV4())
@@ -407,7 +429,7 @@ func TestRegisterTracepoint(t *testing.T) {
 	mockAgtMgr := mock_agent.NewMockManager(ctrl)
 	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
 
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 	defer tracepointMgr.Close()
 
 	agentUUID := uuid.Must(uuid.NewV4())
@@ -456,7 +478,7 @@ func TestUpdateAgentTracepointStatus(t *testing.T) {
 	mockAgtMgr := mock_agent.NewMockManager(ctrl)
 	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
 
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 	defer tracepointMgr.Close()
 
 	agentUUID1 := uuid.Must(uuid.NewV4())
@@ -467,6 +489,11 @@ func TestUpdateAgentTracepointStatus(t *testing.T) {
 		State:   statuspb.RUNNING_STATE,
 	}
 
+	mockTracepointStore.
+		EXPECT().
+		GetTracepoint(tpID).
+		Return(nil, nil)
+
 	mockTracepointStore.
 		EXPECT().
 		UpdateTracepointState(expectedTracepointState).
@@ -483,13 +510,18 @@ func TestUpdateAgentTracepointStatus_Terminated(t *testing.T) {
 	mockAgtMgr := mock_agent.NewMockManager(ctrl)
 	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
 
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 	defer tracepointMgr.Close()
 
 	agentUUID1 := uuid.Must(uuid.NewV4())
 	tpID := uuid.Must(uuid.NewV4())
 	agentUUID2 := uuid.Must(uuid.NewV4())
 
+	mockTracepointStore.
+		EXPECT().
+		GetTracepoint(tpID).
+		Return(nil, nil)
+
 	mockTracepointStore.
 		EXPECT().
 		GetTracepointStates(tpID).
@@ -597,7 +629,7 @@ func TestTTLExpiration(t *testing.T) {
 		Times(2).
 		DoAndReturn(msgHandler)
 
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 25*time.Millisecond)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 25*time.Millisecond)
 	defer tracepointMgr.Close()
 
 	wg.Wait()
@@ -612,7 +644,7 @@ func TestUpdateAgentTracepointStatus_RemoveTracepoints(t *testing.T) {
 	mockAgtMgr := mock_agent.NewMockManager(ctrl)
 	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
 
-	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, 5*time.Second)
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
 	defer tracepointMgr.Close()
 
 	tpID1 := uuid.Must(uuid.NewV4())
@@ -625,6 +657,13 @@ func TestUpdateAgentTracepointStatus_RemoveTracepoints(t *testing.T) {
 			&tpID1, &tpID2,
 		}, nil)
 
+	mockTracepointStore.
+		EXPECT().
+		GetTracepointsForIDs([]uuid.UUID{tpID1, tpID2}).
+		Return([]*storepb.TracepointInfo{
+			{ID: utils.ProtoFromUUID(tpID1)}, {ID: utils.ProtoFromUUID(tpID2)},
+		}, nil)
+
 	mockTracepointStore.
 		EXPECT().
 		DeleteTracepointTTLs([]uuid.UUID{tpID1, tpID2}).
@@ -633,3 +672,242 @@ func TestUpdateAgentTracepointStatus_RemoveTracepoints(t *testing.T) {
 	err := tracepointMgr.RemoveTracepoints([]string{"test1", "test2"})
 	require.NoError(t, err)
 }
+
+func setupVersionTest(t *testing.T) (*tracepoint.Manager, *tracepoint.Datastore, *mock_agent.MockManager, func()) {
+	ctrl := gomock.NewController(t)
+	c, err := pebble.Open("test", &pebble.Options{
+		FS: vfs.NewMem(),
+	})
+	require.NoError(t, err)
+	db := pebbledb.New(c, 3*time.Second)
+	ts := tracepoint.NewDatastore(db)
+	mockAgtMgr := mock_agent.NewMockManager(ctrl)
+	tracepointMgr := tracepoint.NewManager(ts, mockAgtMgr, nil, time.Hour)
+
+	cleanup := func() {
+		tracepointMgr.Close()
+		ctrl.Finish()
+		require.NoError(t, db.Close())
+	}
+	return tracepointMgr, ts, mockAgtMgr, cleanup
+}
+
+func makeTracepointDeployment(tableName string) *logicalpb.TracepointDeployment {
+	return &logicalpb.TracepointDeployment{
+		Programs: []*logicalpb.TracepointDeployment_TracepointProgram{
+			{TableName: tableName},
+		},
+	}
+}
+
+func tracepointMessage(t *testing.T, msg []byte) *messagespb.TracepointMessage {
+	vzMsg := &messagespb.VizierMessage{}
+	require.NoError(t, proto.Unmarshal(msg, vzMsg))
+	return vzMsg.GetTracepointMessage()
+}
+
+func TestRollbackTracepoint(t *testing.T) {
+	tracepointMgr, ts, _, cleanup := setupVersionTest(t)
+	defer cleanup()
+
+	tpID1, err := tracepointMgr.CreateTracepoint("test", makeTracepointDeployment("table1"), nil, time.Minute,
+		&tracepoint.DeploymentOptions{Creator: "user@test.com"})
+	require.NoError(t, err)
+	tpID2, err := tracepointMgr.CreateTracepoint("test", makeTracepointDeployment("table2"), nil, time.Minute, nil)
+	require.NoError(t, err)
+
+	tpID3, err := tracepointMgr.RollbackTracepoint("test", 1, time.Minute, &tracepoint.DeploymentOptions{Creator: "admin"})
+	require.NoError(t, err)
+	assert.NotEqual(t, *tpID1, *tpID3)
+
+	versions, err := tracepointMgr.GetTracepointVersions("test")
+	require.NoError(t, err)
+	require.Equal(t, 3, len(versions))
+	for i, id := range []*uuid.UUID{tpID1, tpID2, tpID3} {
+		assert.Equal(t, int64(i+1), versions[i].Version)
+		assert.Equal(t, utils.ProtoFromUUID(*id), versions[i].ID)
+	}
+	assert.Equal(t, "user@test.com", versions[0].Creator)
+	assert.Equal(t, "admin", versions[2].Creator)
+	assert.Equal(t, int64(1), versions[2].RollbackOfVersion)
+	assert.Equal(t, makeTracepointDeployment("table1"), versions[2].Tracepoint)
+
+	tp, err := ts.GetTracepoint(*tpID3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), tp.Version)
+	assert.Equal(t, makeTracepointDeployment("table1"), tp.Tracepoint)
+
+	// Rolling back to the version which is already deployed is a no-op.
+	_, err = tracepointMgr.RollbackTracepoint("test", 3, time.Minute, nil)
+	assert.Equal(t, tracepoint.ErrTracepointAlreadyExists, err)
+
+	_, err = tracepointMgr.RollbackTracepoint("test", 4, time.Minute, nil)
+	assert.Equal(t, tracepoint.ErrTracepointVersionNotFound, err)
+}
+
+func TestStagedRollout(t *testing.T) {
+	tests := []struct {
+		name          string
+		canaryState   statuspb.LifeCycleState
+		expectAborted bool
+	}{
+		{
+			name:        "complete",
+			canaryState: statuspb.RUNNING_STATE,
+		},
+		{
+			name:          "aborted",
+			canaryState:   statuspb.FAILED_STATE,
+			expectAborted: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracepointMgr, ts, mockAgtMgr, cleanup := setupVersionTest(t)
+			defer cleanup()
+
+			agentIDs := make([]uuid.UUID, 4)
+			agents := make([]*agentpb.Agent, 4)
+			for i := range agentIDs {
+				agentIDs[i] = uuid.Must(uuid.NewV4())
+				agents[i] = makeAgent(agentIDs[i], uint32(i), "node", "10.0.0.1", true)
+			}
+
+			tpID1, err := tracepointMgr.CreateTracepoint("test", makeTracepointDeployment("table1"), nil, time.Minute, nil)
+			require.NoError(t, err)
+			tpID2, err := tracepointMgr.CreateTracepoint("test", makeTracepointDeployment("table2"), nil, time.Minute,
+				&tracepoint.DeploymentOptions{RolloutFraction: 0.5})
+			require.NoError(t, err)
+
+			// The previous version keeps running until the rollout completes.
+			ttlIDs, _, err := ts.GetTracepointTTLs()
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []uuid.UUID{*tpID1, *tpID2}, ttlIDs)
+
+			// The previous version is replaced on the canaries.
+			var removedFrom []uuid.UUID
+			mockAgtMgr.
+				EXPECT().
+				MessageAgents(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ids []uuid.UUID, msg []byte) error {
+					req := tracepointMessage(t, msg).GetRemoveTracepointRequest()
+					require.NotNil(t, req)
+					assert.Equal(t, utils.ProtoFromUUID(*tpID1), req.ID)
+					removedFrom = ids
+					return nil
+				})
+
+			canaries, err := tracepointMgr.SelectRolloutAgents(*tpID2, agentIDs)
+			require.NoError(t, err)
+			assert.Equal(t, 2, len(canaries))
+			assert.Equal(t, canaries, removedFrom)
+
+			// Selecting the agents again returns the same canaries.
+			selected, err := tracepointMgr.SelectRolloutAgents(*tpID2, agentIDs)
+			require.NoError(t, err)
+			assert.Equal(t, canaries, selected)
+
+			// The first canary is running, so the rollout waits for the second one.
+			err = tracepointMgr.UpdateAgentTracepointStatus(utils.ProtoFromUUID(*tpID2), utils.ProtoFromUUID(canaries[0]), statuspb.RUNNING_STATE, nil)
+			require.NoError(t, err)
+
+			if test.expectAborted {
+				mockAgtMgr.
+					EXPECT().
+					MessageActiveAgents(gomock.Any()).
+					DoAndReturn(func(msg []byte) error {
+						req := tracepointMessage(t, msg).GetRemoveTracepointRequest()
+						require.NotNil(t, req)
+						assert.Equal(t, utils.ProtoFromUUID(*tpID2), req.ID)
+						return nil
+					})
+				mockAgtMgr.
+					EXPECT().
+					MessageAgents(canaries, gomock.Any()).
+					DoAndReturn(func(ids []uuid.UUID, msg []byte) error {
+						req := tracepointMessage(t, msg).GetRegisterTracepointRequest()
+						require.NotNil(t, req)
+						assert.Equal(t, utils.ProtoFromUUID(*tpID1), req.ID)
+						return nil
+					})
+			} else {
+				mockAgtMgr.
+					EXPECT().
+					GetActiveAgents().
+					Return(agents, nil)
+				mockAgtMgr.
+					EXPECT().
+					MessageAgents(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ids []uuid.UUID, msg []byte) error {
+						req := tracepointMessage(t, msg).GetRegisterTracepointRequest()
+						require.NotNil(t, req)
+						assert.Equal(t, utils.ProtoFromUUID(*tpID2), req.ID)
+						assert.ElementsMatch(t, append(ids, canaries...), agentIDs)
+						return nil
+					})
+			}
+
+			err = tracepointMgr.UpdateAgentTracepointStatus(utils.ProtoFromUUID(*tpID2), utils.ProtoFromUUID(canaries[1]), test.canaryState, nil)
+			require.NoError(t, err)
+
+			tp, err := ts.GetTracepoint(*tpID2)
+			require.NoError(t, err)
+			versions, err := tracepointMgr.GetTracepointVersions("test")
+			require.NoError(t, err)
+			require.Equal(t, 2, len(versions))
+			assert.Equal(t, 2, len(versions[1].AgentStatuses))
+			names, err := ts.GetTracepointsWithNames([]string{"test"})
+			require.NoError(t, err)
+			ttlIDs, _, err = ts.GetTracepointTTLs()
+			require.NoError(t, err)
+
+			if test.expectAborted {
+				assert.Equal(t, storepb.ROLLOUT_ABORTED, tp.Rollout.State)
+				assert.Equal(t, statuspb.TERMINATED_STATE, tp.ExpectedState)
+				assert.Equal(t, storepb.ROLLOUT_ABORTED, versions[1].RolloutState)
+				assert.Equal(t, *tpID1, *names[0])
+				assert.Equal(t, []uuid.UUID{*tpID1}, ttlIDs)
+			} else {
+				assert.Equal(t, storepb.ROLLOUT_COMPLETE, tp.Rollout.State)
+				assert.Equal(t, storepb.ROLLOUT_COMPLETE, versions[1].RolloutState)
+				assert.Equal(t, *tpID2, *names[0])
+				assert.Equal(t, []uuid.UUID{*tpID2}, ttlIDs)
+			}
+		})
+	}
+}
+
+func TestUpdateAgentTracepointStatus_TerminatedOnDeselectedAgent(t *testing.T) {
+	// Set up mock.
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockAgtMgr := mock_agent.NewMockManager(ctrl)
+	mockTracepointStore := mock_tracepoint.NewMockStore(ctrl)
+
+	tracepointMgr := tracepoint.NewManager(mockTracepointStore, mockAgtMgr, nil, 5*time.Second)
+	defer tracepointMgr.Close()
+
+	agentUUID1 := uuid.Must(uuid.NewV4())
+	agentUUID2 := uuid.Must(uuid.NewV4())
+	tpID := uuid.Must(uuid.NewV4())
+
+	mockTracepointStore.
+		EXPECT().
+		GetTracepoint(tpID).
+		Return(&storepb.TracepointInfo{
+			ID:             utils.ProtoFromUUID(tpID),
+			ExpectedState:  statuspb.RUNNING_STATE,
+			Target:         &storepb.TracepointTarget{NodeNames: []string{"node-1"}},
+			TargetAgentIDs: []*uuidpb.UUID{utils.ProtoFromUUID(agentUUID1)},
+		}, nil)
+
+	// The tracepoint keeps running on the agents which are still selected.
+	mockTracepointStore.
+		EXPECT().
+		DeleteTracepointState(tpID, agentUUID2).
+		Return(nil)
+
+	err := tracepointMgr.UpdateAgentTracepointStatus(utils.ProtoFromUUID(tpID), utils.ProtoFromUUID(agentUUID2), statuspb.TERMINATED_STATE, nil)
+	require.NoError(t, err)
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/vizier/services/metadata/storepb"
+	"px.dev/pixie/src/vizier/utils/messagebus"
+)
+
+// TracepointTablesTopic is the topic on which the output tables of the tracepoints are sent to the cloud.
+var TracepointTablesTopic = messagebus.V2CTopic("DurableTracepointTables")
+
+type tracepointLister interface {
+	GetAllTracepoints() ([]*storepb.TracepointInfo, error)
+	GetTracepointStates(uuid.UUID) ([]*storepb.AgentTracepointStatus, error)
+}
+
+// TracepointTablesPublisher periodically sends the output tables of the tracepoints to the cloud, so that they
+// can be searched. A snapshot is only sent when the tables have changed, or when resendInterval has passed since
+// the last one.
+type TracepointTablesPublisher struct {
+	tpMgr          tracepointLister
+	sendMessage    SendMessageFn
+	isLeader       *bool
+	resendInterval time.Duration
+
+	lastTables []*cvmsgspb.TracepointTable
+	lastSent   time.Time
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewTracepointTablesPublisher creates a publisher which checks for changes to the tracepoint tables every interval.
+func NewTracepointTablesPublisher(tpMgr tracepointLister, sendMsgFn SendMessageFn, isLeader *bool, interval time.Duration, resendInterval time.Duration) *TracepointTablesPublisher {
+	p := &TracepointTablesPublisher{
+		tpMgr:          tpMgr,
+		sendMessage:    sendMsgFn,
+		isLeader:       isLeader,
+		resendInterval: resendInterval,
+		done:           make(chan struct{}),
+	}
+	go p.run(interval)
+	return p
+}
+
+func (p *TracepointTablesPublisher) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			if !*p.isLeader {
+				continue
+			}
+			err := p.Publish()
+			if err != nil {
+				log.WithError(err).Error("Failed to publish tracepoint tables")
+			}
+		}
+	}
+}
+
+// Stop stops publishing the tracepoint tables.
+func (p *TracepointTablesPublisher) Stop() {
+	p.once.Do(func() {
+		close(p.done)
+	})
+}
+
+func (p *TracepointTablesPublisher) getTables() ([]*cvmsgspb.TracepointTable, error) {
+	tps, err := p.tpMgr.GetAllTracepoints()
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make([]*cvmsgspb.TracepointTable, 0)
+	for _, tp := range tps {
+		if tp.Tracepoint == nil {
+			continue
+		}
+		agentStates, err := p.tpMgr.GetTracepointStates(utils.UUIDFromProtoOrNil(tp.ID))
+		if err != nil {
+			return nil, err
+		}
+		state, _ := getTracepointStateFromAgentTracepointStates(agentStates)
+
+		for _, prog := range tp.Tracepoint.Programs {
+			tables = append(tables, &cvmsgspb.TracepointTable{
+				Name:           prog.TableName,
+				TracepointID:   tp.ID,
+				TracepointName: tp.Name,
+				State:          vizierpb.LifeCycleState(state),
+			})
+		}
+	}
+	return tables, nil
+}
+
+func tablesEqual(a []*cvmsgspb.TracepointTable, b []*cvmsgspb.TracepointTable) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Publish sends the current tracepoint tables to the cloud, if they have changed since they were last sent.
+func (p *TracepointTablesPublisher) Publish() error {
+	tables, err := p.getTables()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if p.lastTables != nil && tablesEqual(tables, p.lastTables) && now.Sub(p.lastSent) < p.resendInterval {
+		return nil
+	}
+
+	anyMsg, err := types.MarshalAny(&cvmsgspb.TracepointTablesUpdate{
+		Tables:      tables,
+		TimestampNS: now.UnixNano(),
+	})
+	if err != nil {
+		return err
+	}
+	b, err := (&cvmsgspb.V2CMessage{Msg: anyMsg}).Marshal()
+	if err != nil {
+		return err
+	}
+	err = p.sendMessage(TracepointTablesTopic, b)
+	if err != nil {
+		return err
+	}
+
+	p.lastTables = tables
+	p.lastSent = now
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/carnot/planner/dynamic_tracing/ir/logicalpb"
+	"px.dev/pixie/src/common/base/statuspb"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/vizier/services/metadata/controllers"
+	"px.dev/pixie/src/vizier/services/metadata/storepb"
+)
+
+type fakeTracepointLister struct {
+	tps    []*storepb.TracepointInfo
+	states map[uuid.UUID][]*storepb.AgentTracepointStatus
+}
+
+func (f *fakeTracepointLister) GetAllTracepoints() ([]*storepb.TracepointInfo, error) {
+	return f.tps, nil
+}
+
+func (f *fakeTracepointLister) GetTracepointStates(id uuid.UUID) ([]*storepb.AgentTracepointStatus, error) {
+	return f.states[id], nil
+}
+
+func TestTracepointTablesPublisher_Publish(t *testing.T) {
+	tpID := uuid.Must(uuid.NewV4())
+	lister := &fakeTracepointLister{
+		tps: []*storepb.TracepointInfo{
+			{
+				ID:   utils.ProtoFromUUID(tpID),
+				Name: "http_probe",
+				Tracepoint: &logicalpb.TracepointDeployment{
+					Programs: []*logicalpb.TracepointDeployment_TracepointProgram{
+						{TableName: "http_table"},
+						{TableName: "http_errors_table"},
+					},
+				},
+			},
+		},
+		states: map[uuid.UUID][]*storepb.AgentTracepointStatus{
+			tpID: {{State: statuspb.RUNNING_STATE}},
+		},
+	}
+
+	var sent []*cvmsgspb.TracepointTablesUpdate
+	sendMsg := func(topic string, b []byte) error {
+		assert.Equal(t, controllers.TracepointTablesTopic, topic)
+		v2cMsg := &cvmsgspb.V2CMessage{}
+		require.NoError(t, v2cMsg.Unmarshal(b))
+		update := &cvmsgspb.TracepointTablesUpdate{}
+		require.NoError(t, types.UnmarshalAny(v2cMsg.Msg, update))
+		sent = append(sent, update)
+		return nil
+	}
+
+	isLeader := true
+	p := controllers.NewTracepointTablesPublisher(lister, sendMsg, &isLeader, time.Hour, time.Hour)
+	defer p.Stop()
+
+	require.NoError(t, p.Publish())
+	require.Equal(t, 1, len(sent))
+	assert.Equal(t, []*cvmsgspb.TracepointTable{
+		{
+			Name:           "http_table",
+			TracepointID:   utils.ProtoFromUUID(tpID),
+			TracepointName: "http_probe",
+			State:          vizierpb.RUNNING_STATE,
+		},
+		{
+			Name:           "http_errors_table",
+			TracepointID:   utils.ProtoFromUUID(tpID),
+			TracepointName: "http_probe",
+			State:          vizierpb.RUNNING_STATE,
+		},
+	}, sent[0].Tables)
+
+	// Nothing has changed, so no new snapshot is sent.
+	require.NoError(t, p.Publish())
+	assert.Equal(t, 1, len(sent))
+
+	lister.states[tpID] = []*storepb.AgentTracepointStatus{{State: statuspb.TERMINATED_STATE}}
+	require.NoError(t, p.Publish())
+	require.Equal(t, 2, len(sent))
+	assert.Equal(t, vizierpb.TERMINATED_STATE, sent[1].Tables[0].State)
+	assert.Equal(t, vizierpb.TERMINATED_STATE, sent[1].Tables[1].State)
+}
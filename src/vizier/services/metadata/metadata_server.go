@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/cockroachdb/pebble"
@@ -70,6 +71,7 @@ func init() {
 	pflag.String("pod_namespace", "pl", "The namespace this pod runs in. Used for leader elections")
 	pflag.String("nats_url", "pl-nats", "The URL of NATS")
 	pflag.Bool("use_etcd_operator", false, "Whether the etcd operator should be used instead of the persistent version.")
+	pflag.String("namespace_scope", "", "Comma-separated list of namespaces to collect metadata from. If empty, all namespaces are watched.")
 
 	// Metadata flags are set using the env vars in pl-cluster-config.
 	// We historically set PL_ETCD_OPERATOR_ENABLED but not PL_USE_ETCD_OPERATOR in the configmap.
@@ -79,6 +81,18 @@ func init() {
 	viper.BindEnv("use_etcd_operator", "PL_ETCD_OPERATOR_ENABLED")
 }
 
+// parseNamespaceScope splits the comma-separated namespace_scope flag into a list of namespaces.
+func parseNamespaceScope(scope string) []string {
+	var namespaces []string
+	for _, ns := range strings.Split(scope, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
 func mustInitEtcdDatastore() (*etcd.DataStore, func()) {
 	log.Infof("Using etcd: %s for metadata", viper.GetString("md_etcd_server"))
 	var tlsConfig *tls.Config
@@ -231,7 +245,7 @@ func main() {
 	updateCh := make(chan *k8smeta.K8sResourceMessage)
 	mdh := k8smeta.NewHandler(updateCh, k8sMds, nc)
 
-	k8sMc, err := k8smeta.NewController(updateCh)
+	k8sMc, err := k8smeta.NewController(parseNamespaceScope(viper.GetString("namespace_scope")), updateCh)
 	defer k8sMc.Stop()
 
 	ads := agent.NewDatastore(dataStore, 24*time.Hour)
@@ -257,7 +271,7 @@ func main() {
 
 	tds := tracepoint.NewDatastore(dataStore)
 	// Initialize tracepoint handler.
-	tracepointMgr := tracepoint.NewManager(tds, agtMgr, 30*time.Second)
+	tracepointMgr := tracepoint.NewManager(tds, agtMgr, mdh, 30*time.Second)
 	defer tracepointMgr.Close()
 
 	mc, err := controllers.NewMessageBusController(nc, agtMgr, tracepointMgr,
@@ -268,6 +282,9 @@ func main() {
 	}
 	defer mc.Close()
 
+	tablesPublisher := controllers.NewTracepointTablesPublisher(tracepointMgr, nc.Publish, &isLeader, 30*time.Second, 10*time.Minute)
+	defer tablesPublisher.Stop()
+
 	// Set up server.
 	env, err := metadataenv.New("vizier")
 	if err != nil {
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package apikeys
+
+import (
+	"context"
+	"crypto/subtle"
+	"sync"
+	"time"
+
+	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/shared/services/env"
+	svcutils "px.dev/pixie/src/shared/services/utils"
+)
+
+const (
+	// apiKeyHeader is the header that clients send their API key in, matching Pixie Cloud.
+	apiKeyHeader = "pixie-api-key"
+	// refreshInterval is how long the API keys read from the secret are cached. Revoked keys stop working
+	// after at most this long.
+	refreshInterval = 30 * time.Second
+)
+
+// Validator checks API keys against the keys stored in a local Secret. Every value in the Secret's data is a
+// valid API key, the names of the entries are only used to tell keys apart. This lets a standalone Vizier
+// authenticate px and pxapi clients without Pixie Cloud.
+type Validator struct {
+	clientset  kubernetes.Interface
+	namespace  string
+	secretName string
+
+	mu          sync.Mutex
+	keys        [][]byte
+	lastRefresh time.Time
+}
+
+// NewValidator creates a new validator for the API keys in the given secret.
+func NewValidator(clientset kubernetes.Interface, namespace string, secretName string) *Validator {
+	return &Validator{
+		clientset:  clientset,
+		namespace:  namespace,
+		secretName: secretName,
+	}
+}
+
+func (v *Validator) getKeys(ctx context.Context) ([][]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.keys != nil && time.Since(v.lastRefresh) < refreshInterval {
+		return v.keys, nil
+	}
+
+	s, err := v.clientset.CoreV1().Secrets(v.namespace).Get(ctx, v.secretName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		// No secret means that no API keys have been provisioned.
+		v.keys = [][]byte{}
+		v.lastRefresh = time.Now()
+		return v.keys, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([][]byte, 0, len(s.Data))
+	for _, k := range s.Data {
+		if len(k) > 0 {
+			keys = append(keys, k)
+		}
+	}
+	v.keys = keys
+	v.lastRefresh = time.Now()
+	return v.keys, nil
+}
+
+// Validate returns whether the given API key is one of the keys in the secret.
+func (v *Validator) Validate(ctx context.Context, apiKey string) (bool, error) {
+	if apiKey == "" {
+		return false, nil
+	}
+	keys, err := v.getKeys(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	valid := false
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare(k, []byte(apiKey)) == 1 {
+			valid = true
+		}
+	}
+	return valid, nil
+}
+
+// AuthMiddleware is a GRPC auth middleware which exchanges a valid API key for a service token. Requests which
+// already carry a bearer token, such as those from other Vizier services, are passed through unchanged.
+func (v *Validator) AuthMiddleware(ctx context.Context, e env.Env) (string, error) {
+	if token, err := grpc_auth.AuthFromMD(ctx, "bearer"); err == nil {
+		return token, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	apiKeys := md.Get(apiKeyHeader)
+	if len(apiKeys) != 1 {
+		return "", status.Error(codes.Unauthenticated, "missing bearer token or API key")
+	}
+
+	valid, err := v.Validate(ctx, apiKeys[0])
+	if err != nil {
+		log.WithError(err).Error("Failed to read API keys")
+		return "", status.Error(codes.Internal, "failed to validate API key")
+	}
+	if !valid {
+		return "", status.Error(codes.Unauthenticated, "invalid API key")
+	}
+
+	claims := svcutils.GenerateJWTForService("api_key", e.Audience())
+	return svcutils.SignJWTClaims(claims, e.JWTSigningKey())
+}
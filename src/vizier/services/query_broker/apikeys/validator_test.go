@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package apikeys_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/shared/services/env"
+	svcutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/vizier/services/query_broker/apikeys"
+)
+
+func TestValidator_AuthMiddleware(t *testing.T) {
+	viper.Set("jwt_signing_key", "jwt-key")
+	e := env.New("vizier")
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pl-standalone-api-keys",
+			Namespace: "pl",
+		},
+		Data: map[string][]byte{
+			"ci":    []byte("px-api-abc"),
+			"alice": []byte("px-api-def"),
+		},
+	}
+
+	tests := []struct {
+		name         string
+		secret       *corev1.Secret
+		md           metadata.MD
+		expectedCode codes.Code
+		expectedSvc  string
+	}{
+		{
+			name:         "valid API key",
+			secret:       secret,
+			md:           metadata.Pairs("pixie-api-key", "px-api-def"),
+			expectedCode: codes.OK,
+			expectedSvc:  "api_key",
+		},
+		{
+			name:         "invalid API key",
+			secret:       secret,
+			md:           metadata.Pairs("pixie-api-key", "px-api-xyz"),
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "missing secret",
+			md:           metadata.Pairs("pixie-api-key", "px-api-abc"),
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "no credentials",
+			secret:       secret,
+			md:           metadata.MD{},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "bearer token passthrough",
+			md:           metadata.Pairs("authorization", "bearer service-token"),
+			expectedCode: codes.OK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			if test.secret != nil {
+				clientset = fake.NewSimpleClientset(test.secret)
+			}
+			v := apikeys.NewValidator(clientset, "pl", "pl-standalone-api-keys")
+
+			ctx := metadata.NewIncomingContext(context.Background(), test.md)
+			token, err := v.AuthMiddleware(ctx, e)
+			assert.Equal(t, test.expectedCode, status.Code(err))
+			if test.expectedCode != codes.OK {
+				return
+			}
+			require.NoError(t, err)
+
+			if test.expectedSvc == "" {
+				assert.Equal(t, "service-token", token)
+				return
+			}
+			parsed, err := svcutils.ParseToken(token, "jwt-key", "vizier")
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSvc, svcutils.GetServiceID(parsed))
+		})
+	}
+}
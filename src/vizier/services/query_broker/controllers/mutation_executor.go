@@ -21,8 +21,11 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -30,14 +33,17 @@ import (
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/api/proto/vizierpb"
 	"px.dev/pixie/src/carnot/planner/distributedpb"
-	"px.dev/pixie/src/carnot/planner/plannerpb"
 	"px.dev/pixie/src/carnot/planpb"
 	"px.dev/pixie/src/common/base/statuspb"
 	"px.dev/pixie/src/shared/services/authcontext"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
+	"px.dev/pixie/src/vizier/services/metadata/storepb"
 )
 
+// defaultRestoreTTL is the TTL used when restoring a tracepoint version which has no TTL of its own.
+var defaultRestoreTTL = types.DurationProto(10 * time.Minute)
+
 // TracepointMap stores a map from the name to tracepoint info.
 type TracepointMap map[string]*TracepointInfo
 
@@ -110,111 +116,222 @@ func (m *MutationExecutorImpl) Execute(ctx context.Context, req *vizierpb.Execut
 		return nil, nil
 	}
 
-	registerTracepointsReq := &metadatapb.RegisterTracepointRequest{
-		Requests: make([]*metadatapb.RegisterTracepointRequest_TracepointRequest, 0),
-	}
-	deleteTracepointsReq := &metadatapb.RemoveTracepointRequest{
-		Names: make([]string, 0),
-	}
-	configmapReqs := make([]*metadatapb.UpdateConfigRequest, 0)
-
-	outputTablesMap := make(map[string]bool)
-	// TODO(zasgar): We should make sure that we don't simultaneously add and delete the tracepoint.
-	// While this will probably work, we should restrict this because it's likely not the intended behavior.
-	for _, mut := range mutations.Mutations {
-		switch mut := mut.Mutation.(type) {
-		case *plannerpb.CompileMutation_Trace:
-			{
-				name := mut.Trace.Name
-				registerTracepointsReq.Requests = append(registerTracepointsReq.Requests,
-					&metadatapb.RegisterTracepointRequest_TracepointRequest{
-						TracepointDeployment: mut.Trace,
-						Name:                 mut.Trace.Name,
-						TTL:                  mut.Trace.TTL,
-					})
-
-				if _, ok := m.activeTracepoints[name]; ok {
-					return nil, fmt.Errorf("tracepoint with name '%s', already used", name)
-				}
-				for _, tracepoint := range mut.Trace.Programs {
-					outputTablesMap[tracepoint.TableName] = true
-				}
-
-				m.activeTracepoints[name] = &TracepointInfo{
-					Name:   name,
-					ID:     uuid.Nil,
-					Status: nil,
-				}
-			}
-		case *plannerpb.CompileMutation_DeleteTracepoint:
-			{
-				deleteTracepointsReq.Names = append(deleteTracepointsReq.Names, mut.DeleteTracepoint.Name)
-			}
-		case *plannerpb.CompileMutation_ConfigUpdate:
-			{
-				configmapReqs = append(configmapReqs, &metadatapb.UpdateConfigRequest{
-					Key:          mut.ConfigUpdate.Key,
-					Value:        mut.ConfigUpdate.Value,
-					AgentPodName: mut.ConfigUpdate.AgentPodName,
-				})
-			}
-		}
+	plan, err := m.validateMutations(ctx, mutations.Mutations)
+	if validationErr, ok := err.(*MutationValidationError); ok {
+		return validationErr.Status(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m.applyMutations(ctx, plan)
+}
+
+// applyMutations applies the registrations, deletions and config updates of the plan, in that order. If a
+// step fails, the tracepoints which were registered or deleted and the configs which were updated so far,
+// including by a registration which only partly succeeded, are restored to the state they had before the script ran.
+func (m *MutationExecutorImpl) applyMutations(ctx context.Context, plan *mutationPlan) (*statuspb.Status, error) {
+	registerNames := make([]string, len(plan.register.Requests))
+	for i, tpReq := range plan.register.Requests {
+		registerNames[i] = tpReq.Name
+	}
+	names := append(append([]string{}, registerNames...), plan.remove.Names...)
+	prevVersions, err := m.getDeployedVersions(ctx, names)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(registerTracepointsReq.Requests) > 0 {
-		resp, err := m.mdtp.RegisterTracepoint(ctx, registerTracepointsReq)
+	var registered []*metadatapb.RegisterTracepointResponse_TracepointStatus
+	// The registered tracepoints which were created or replaced, and need to be restored if a later step fails.
+	var changed []string
+	if len(plan.register.Requests) > 0 {
+		resp, err := m.mdtp.RegisterTracepoint(ctx, plan.register)
 		if err != nil {
 			log.WithError(err).
 				Errorf("Failed to register tracepoints")
+			// The metadata service registers the tracepoints one at a time, so the ones before the failure are deployed.
+			m.restoreTracepoints(ctx, m.changedTracepoints(ctx, registerNames, prevVersions), prevVersions)
 			return nil, ErrTracepointRegistrationFailed
 		}
 		if resp.Status != nil && resp.Status.ErrCode != statuspb.OK {
 			log.WithField("status", resp.Status.String()).
 				Errorf("Failed to register tracepoints with bad status")
-
+			m.restoreTracepoints(ctx, m.changedTracepoints(ctx, registerNames, prevVersions), prevVersions)
 			return resp.Status, ErrTracepointRegistrationFailed
 		}
 
-		// Update the internal stat of the tracepoints.
+		registered = resp.Tracepoints
 		for _, tp := range resp.Tracepoints {
-			id := utils.UUIDFromProtoOrNil(tp.ID)
-			m.activeTracepoints[tp.Name].ID = id
-			m.activeTracepoints[tp.Name].Status = tp.Status
+			// Tracepoints which already existed with the same definition are left untouched.
+			if tp.Status == nil || tp.Status.ErrCode != statuspb.ALREADY_EXISTS {
+				changed = append(changed, tp.Name)
+			}
 		}
 	}
-	if len(deleteTracepointsReq.Names) > 0 {
-		delResp, err := m.mdtp.RemoveTracepoint(ctx, deleteTracepointsReq)
+
+	if len(plan.remove.Names) > 0 {
+		delResp, err := m.mdtp.RemoveTracepoint(ctx, plan.remove)
+		if err == nil && delResp.Status != nil && delResp.Status.ErrCode != statuspb.OK {
+			err = fmt.Errorf("bad status: %s", delResp.Status.String())
+		}
 		if err != nil {
 			log.WithError(err).
 				Errorf("Failed to delete tracepoints")
+			m.restoreTracepoints(ctx, changed, prevVersions)
 			return nil, ErrTracepointDeletionFailed
 		}
-		if delResp.Status != nil && delResp.Status.ErrCode != statuspb.OK {
-			log.WithField("status", delResp.Status.String()).
-				Errorf("Failed to delete tracepoints with bad status")
-			return delResp.Status, ErrTracepointDeletionFailed
+		// Only the deleted tracepoints which were deployed need to be restored.
+		for _, name := range plan.remove.Names {
+			if _, ok := prevVersions[name]; ok {
+				changed = append(changed, name)
+			}
 		}
-		// Remove the tracepoints we considered deleted.
-		for _, tpName := range deleteTracepointsReq.Names {
-			delete(m.activeTracepoints, tpName)
+	}
+
+	// The updates which undo the config updates applied so far.
+	var configUndos []*metadatapb.UpdateConfigRequest
+	for _, configReq := range plan.configs {
+		resp, err := m.mdconf.UpdateConfig(ctx, configReq)
+		if err == nil && resp.Status != nil && resp.Status.ErrCode != statuspb.OK {
+			err = fmt.Errorf("bad status: %s", resp.Status.String())
 		}
+		if err != nil {
+			log.WithError(err).
+				WithField("agent", configReq.AgentPodName).
+				Errorf("Failed to update config")
+			m.restoreConfigs(ctx, configUndos)
+			m.restoreTracepoints(ctx, changed, prevVersions)
+			return nil, ErrConfigUpdateFailed
+		}
+		configUndos = append(configUndos, &metadatapb.UpdateConfigRequest{
+			Key:          configReq.Key,
+			Value:        resp.PreviousValue,
+			AgentPodName: configReq.AgentPodName,
+		})
 	}
 
-	if len(configmapReqs) > 0 {
-		for _, configmapReq := range configmapReqs {
-			resp, err := m.mdconf.UpdateConfig(ctx, configmapReq)
-			if err != nil || (resp.Status != nil && resp.Status.ErrCode != statuspb.OK) {
-				return nil, ErrConfigUpdateFailed
+	// Update the internal state of the tracepoints, now that all of the mutations have been applied.
+	for _, tp := range registered {
+		m.activeTracepoints[tp.Name] = &TracepointInfo{
+			Name:   tp.Name,
+			ID:     utils.UUIDFromProtoOrNil(tp.ID),
+			Status: tp.Status,
+		}
+	}
+	for _, tpName := range plan.remove.Names {
+		delete(m.activeTracepoints, tpName)
+	}
+	m.outputTables = plan.outputTables
+
+	return nil, nil
+}
+
+// getDeployedVersions returns the latest version of each of the named tracepoints, if that version is still
+// deployed.
+func (m *MutationExecutorImpl) getDeployedVersions(ctx context.Context, names []string) (map[string]*storepb.TracepointVersion, error) {
+	deployed := make(map[string]*storepb.TracepointVersion)
+	for _, name := range names {
+		if _, ok := deployed[name]; ok {
+			continue
+		}
+		resp, err := m.mdtp.GetTracepointVersions(ctx, &metadatapb.GetTracepointVersionsRequest{Name: name})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Versions) == 0 {
+			continue
+		}
+		latest := resp.Versions[len(resp.Versions)-1]
+
+		infoResp, err := m.mdtp.GetTracepointInfo(ctx, &metadatapb.GetTracepointInfoRequest{
+			IDs: []*uuidpb.UUID{latest.ID},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, tp := range infoResp.Tracepoints {
+			if tp.ExpectedState != statuspb.TERMINATED_STATE {
+				deployed[name] = latest
 			}
 		}
 	}
+	return deployed, nil
+}
 
-	m.outputTables = make([]string, 0)
-	for k := range outputTablesMap {
-		m.outputTables = append(m.outputTables, k)
+// changedTracepoints returns the named tracepoints whose deployed version is no longer the one in prevVersions.
+// If the deployed versions can't be fetched, all of the names are returned so that they are restored.
+func (m *MutationExecutorImpl) changedTracepoints(ctx context.Context, names []string, prevVersions map[string]*storepb.TracepointVersion) []string {
+	deployed, err := m.getDeployedVersions(ctx, names)
+	if err != nil {
+		log.WithError(err).Error("Failed to get deployed tracepoint versions")
+		return names
+	}
+	var changed []string
+	for _, name := range names {
+		cur, ok := deployed[name]
+		if !ok {
+			continue
+		}
+		if prev, ok := prevVersions[name]; !ok || prev.Version != cur.Version {
+			changed = append(changed, name)
+		}
 	}
+	return changed
+}
 
-	return nil, nil
+// restoreTracepoints returns the named tracepoints to the versions which were deployed before the script
+// ran, and removes the ones which were not deployed. Failures are logged, as there is nothing more to undo.
+func (m *MutationExecutorImpl) restoreTracepoints(ctx context.Context, names []string, prevVersions map[string]*storepb.TracepointVersion) {
+	var toRemove []string
+	for _, name := range names {
+		prev, ok := prevVersions[name]
+		if !ok {
+			toRemove = append(toRemove, name)
+			continue
+		}
+		ttl := defaultRestoreTTL
+		if prev.Tracepoint != nil && prev.Tracepoint.TTL != nil {
+			ttl = prev.Tracepoint.TTL
+		}
+		_, err := m.mdtp.RollbackTracepoint(ctx, &metadatapb.RollbackTracepointRequest{
+			Name:    name,
+			Version: prev.Version,
+			TTL:     ttl,
+		})
+		if err != nil {
+			log.WithError(err).WithField("tracepoint", name).Error("Failed to restore tracepoint")
+		}
+	}
+	if len(toRemove) == 0 {
+		return
+	}
+	resp, err := m.mdtp.RemoveTracepoint(ctx, &metadatapb.RemoveTracepointRequest{Names: toRemove})
+	if err == nil && resp.Status != nil && resp.Status.ErrCode != statuspb.OK {
+		err = fmt.Errorf("bad status: %s", resp.Status.String())
+	}
+	if err != nil {
+		log.WithError(err).WithField("tracepoints", toRemove).Error("Failed to remove registered tracepoints")
+	}
+}
+
+// restoreConfigs applies the given undo updates in reverse order. Configs which had no previous value can't
+// be restored, since the agent's default isn't known. Failures are logged, as there is nothing more to undo.
+func (m *MutationExecutorImpl) restoreConfigs(ctx context.Context, undos []*metadatapb.UpdateConfigRequest) {
+	for i := len(undos) - 1; i >= 0; i-- {
+		undo := undos[i]
+		logger := log.WithField("agent", undo.AgentPodName).WithField("key", undo.Key)
+		if undo.Value == "" {
+			logger.Warn("Cannot restore config which had no previous value")
+			continue
+		}
+		resp, err := m.mdconf.UpdateConfig(ctx, undo)
+		if err == nil && resp.Status != nil && resp.Status.ErrCode != statuspb.OK {
+			err = fmt.Errorf("bad status: %s", resp.Status.String())
+		}
+		if err != nil {
+			logger.WithError(err).Error("Failed to restore config")
+		}
+	}
 }
 
 // MutationInfo returns the summarized mutation information.
@@ -241,6 +358,7 @@ func (m *MutationExecutorImpl) MutationInfo(ctx context.Context) (*vizierpb.Muta
 	}
 
 	ready := true
+	var unmatched []string
 	for idx, tp := range resp.Tracepoints {
 		mutationInfo.States[idx] = &vizierpb.MutationInfo_MutationState{
 			ID:    utils.UUIDFromProtoOrNil(tp.ID).String(),
@@ -250,6 +368,18 @@ func (m *MutationExecutorImpl) MutationInfo(ctx context.Context) (*vizierpb.Muta
 		if tp.State != statuspb.RUNNING_STATE {
 			ready = false
 		}
+		if tp.NoMatchingAgents {
+			unmatched = append(unmatched, tp.Name)
+		}
+	}
+
+	if len(unmatched) > 0 {
+		// The tracepoints are deployed once a matching agent registers, so the client keeps waiting.
+		mutationInfo.Status = &vizierpb.Status{
+			Code:    int32(codes.Unavailable),
+			Message: fmt.Sprintf("no agents match the selector of tracepoint '%s'", strings.Join(unmatched, "', '")),
+		}
+		return mutationInfo, nil
 	}
 
 	if !ready {
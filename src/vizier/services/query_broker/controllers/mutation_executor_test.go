@@ -19,23 +19,463 @@
 package controllers_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/carnot/planner/compilerpb"
 	"px.dev/pixie/src/carnot/planner/distributedpb"
+	"px.dev/pixie/src/carnot/planner/dynamic_tracing/ir/logicalpb"
+	"px.dev/pixie/src/carnot/planner/plannerpb"
+	"px.dev/pixie/src/carnot/planpb"
+	"px.dev/pixie/src/common/base/statuspb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
+	mock_metadatapb "px.dev/pixie/src/vizier/services/metadata/metadatapb/mock"
+	"px.dev/pixie/src/vizier/services/metadata/storepb"
+	"px.dev/pixie/src/vizier/services/query_broker/controllers"
+	mock_controllers "px.dev/pixie/src/vizier/services/query_broker/controllers/mock"
 )
 
-func TestMutationExecutor_Execute(t *testing.T) {
-	// Set up mocks.
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+func traceMutation(name string, tables ...string) *plannerpb.CompileMutation {
+	programs := make([]*logicalpb.TracepointDeployment_TracepointProgram, len(tables))
+	for i, table := range tables {
+		programs[i] = &logicalpb.TracepointDeployment_TracepointProgram{TableName: table}
+	}
+	return &plannerpb.CompileMutation{
+		Mutation: &plannerpb.CompileMutation_Trace{
+			Trace: &logicalpb.TracepointDeployment{
+				Name:     name,
+				TTL:      types.DurationProto(300000000000),
+				Programs: programs,
+			},
+		},
+	}
+}
 
+func deleteMutation(name string) *plannerpb.CompileMutation {
+	return &plannerpb.CompileMutation{
+		Mutation: &plannerpb.CompileMutation_DeleteTracepoint{
+			DeleteTracepoint: &plannerpb.DeleteTracepoint{Name: name},
+		},
+	}
+}
+
+func configMutation(pod, key, value string) *plannerpb.CompileMutation {
+	return &plannerpb.CompileMutation{
+		Mutation: &plannerpb.CompileMutation_ConfigUpdate{
+			ConfigUpdate: &plannerpb.ConfigUpdate{
+				AgentPodName: pod,
+				Key:          key,
+				Value:        value,
+			},
+		},
+	}
+}
+
+// atPosition sets the positions in the script which the compiler reports for a mutation.
+func atPosition(mut *plannerpb.CompileMutation, line, column uint64, tablePositions ...*plannerpb.SourcePosition) *plannerpb.CompileMutation {
+	mut.Position = &plannerpb.SourcePosition{Line: line, Column: column}
+	mut.TablePositions = tablePositions
+	return mut
+}
+
+func setupMutationExecutor(t *testing.T, ctrl *gomock.Controller, mutations ...*plannerpb.CompileMutation) (controllers.MutationExecutor,
+	*mock_metadatapb.MockMetadataTracepointServiceClient, *mock_metadatapb.MockMetadataConfigServiceClient) {
 	plannerStatePB := new(distributedpb.LogicalPlannerState)
 	if err := proto.UnmarshalText(singleAgentDistributedState, plannerStatePB); err != nil {
 		t.Fatal("Cannot Unmarshal protobuf.")
 	}
 
-	t.Skip("These tests are incomplete and need to be done")
+	planner := mock_controllers.NewMockPlanner(ctrl)
+	planner.
+		EXPECT().
+		CompileMutations(gomock.Any(), gomock.Any()).
+		Return(&plannerpb.CompileMutationsResponse{Mutations: mutations}, nil)
+	mdtp := mock_metadatapb.NewMockMetadataTracepointServiceClient(ctrl)
+	mdconf := mock_metadatapb.NewMockMetadataConfigServiceClient(ctrl)
+
+	return controllers.NewMutationExecutor(planner, mdtp, mdconf, plannerStatePB.DistributedState), mdtp, mdconf
+}
+
+func executeMutations(t *testing.T, me controllers.MutationExecutor) (*statuspb.Status, error) {
+	ctx := authcontext.NewContext(context.Background(), authcontext.New())
+	return me.Execute(ctx, &vizierpb.ExecuteScriptRequest{QueryStr: "import pxtrace"}, &planpb.PlanOptions{})
+}
+
+func compilerErrors(t *testing.T, s *statuspb.Status) []*compilerpb.LineColError {
+	require.NotNil(t, s)
+	assert.Equal(t, statuspb.INVALID_ARGUMENT, s.ErrCode)
+	errGroup := &compilerpb.CompilerErrorGroup{}
+	require.NoError(t, types.UnmarshalAny(s.Context, errGroup))
+	errs := make([]*compilerpb.LineColError, len(errGroup.Errors))
+	for i, err := range errGroup.Errors {
+		errs[i] = err.GetLineColError()
+	}
+	return errs
+}
+
+func TestMutationExecutor_Execute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	me, mdtp, mdconf := setupMutationExecutor(t, ctrl,
+		traceMutation("http_probe", "http_table"),
+		configMutation("pl/pem-1234", "gprof", "true"))
+
+	mdconf.
+		EXPECT().
+		UpdateConfig(gomock.Any(), &metadatapb.UpdateConfigRequest{
+			AgentPodName: "pl/pem-1234",
+			Key:          "gprof",
+			Value:        "true",
+			ValidateOnly: true,
+		}).
+		Return(&metadatapb.UpdateConfigResponse{Status: &statuspb.Status{ErrCode: statuspb.OK}}, nil)
+	mdtp.
+		EXPECT().
+		GetTracepointVersions(gomock.Any(), &metadatapb.GetTracepointVersionsRequest{Name: "http_probe"}).
+		Return(&metadatapb.GetTracepointVersionsResponse{}, nil)
+	mdtp.
+		EXPECT().
+		RegisterTracepoint(gomock.Any(), gomock.Any()).
+		Return(&metadatapb.RegisterTracepointResponse{
+			Tracepoints: []*metadatapb.RegisterTracepointResponse_TracepointStatus{
+				{
+					Name:   "http_probe",
+					ID:     utils.ProtoFromUUIDStrOrNil("11285cdd-1de9-4ab1-ae6a-0ba08c8c676c"),
+					Status: &statuspb.Status{ErrCode: statuspb.OK},
+				},
+			},
+		}, nil)
+	mdconf.
+		EXPECT().
+		UpdateConfig(gomock.Any(), &metadatapb.UpdateConfigRequest{
+			AgentPodName: "pl/pem-1234",
+			Key:          "gprof",
+			Value:        "true",
+		}).
+		Return(&metadatapb.UpdateConfigResponse{Status: &statuspb.Status{ErrCode: statuspb.OK}}, nil)
+
+	s, err := executeMutations(t, me)
+	require.NoError(t, err)
+	assert.Nil(t, s)
+}
+
+func TestMutationExecutor_Execute_AgentSelector(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mut := traceMutation("http_probe", "http_table")
+	mut.AgentSelector = &plannerpb.AgentSelector{
+		NodeNames:   []string{"node-1"},
+		PodSelector: "app=web",
+	}
+	me, mdtp, _ := setupMutationExecutor(t, ctrl, mut)
+
+	tpID := utils.ProtoFromUUIDStrOrNil("11285cdd-1de9-4ab1-ae6a-0ba08c8c676c")
+	mdtp.
+		EXPECT().
+		GetTracepointVersions(gomock.Any(), &metadatapb.GetTracepointVersionsRequest{Name: "http_probe"}).
+		Return(&metadatapb.GetTracepointVersionsResponse{}, nil)
+	mdtp.
+		EXPECT().
+		RegisterTracepoint(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req *metadatapb.RegisterTracepointRequest, opts ...grpc.CallOption) (*metadatapb.RegisterTracepointResponse, error) {
+			require.Len(t, req.Requests, 1)
+			assert.Equal(t, &storepb.TracepointTarget{
+				NodeNames:   []string{"node-1"},
+				PodSelector: "app=web",
+			}, req.Requests[0].Target)
+			return &metadatapb.RegisterTracepointResponse{
+				Tracepoints: []*metadatapb.RegisterTracepointResponse_TracepointStatus{
+					{
+						Name:   "http_probe",
+						ID:     tpID,
+						Status: &statuspb.Status{ErrCode: statuspb.OK},
+					},
+				},
+			}, nil
+		})
+
+	s, err := executeMutations(t, me)
+	require.NoError(t, err)
+	assert.Nil(t, s)
+
+	mdtp.
+		EXPECT().
+		GetTracepointInfo(gomock.Any(), gomock.Any()).
+		Return(&metadatapb.GetTracepointInfoResponse{
+			Tracepoints: []*metadatapb.GetTracepointInfoResponse_TracepointState{
+				{
+					ID:               tpID,
+					Name:             "http_probe",
+					State:            statuspb.PENDING_STATE,
+					NoMatchingAgents: true,
+				},
+			},
+		}, nil)
+
+	ctx := authcontext.NewContext(context.Background(), authcontext.New())
+	info, err := me.MutationInfo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.Unavailable), info.Status.Code)
+	assert.Equal(t, "no agents match the selector of tracepoint 'http_probe'", info.Status.Message)
+}
+
+func TestMutationExecutor_Execute_Conflicts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	me, _, mdconf := setupMutationExecutor(t, ctrl,
+		atPosition(traceMutation("http_probe", "http_table"), 3, 26, &plannerpb.SourcePosition{Line: 3, Column: 40}),
+		atPosition(traceMutation("dns_probe", "http_table"), 4, 26, &plannerpb.SourcePosition{Line: 4, Column: 39}),
+		atPosition(deleteMutation("http_probe"), 5, 26),
+		atPosition(configMutation("pl/pem-1234", "gprof", "true"), 6, 27))
+
+	mdconf.
+		EXPECT().
+		UpdateConfig(gomock.Any(), gomock.Any()).
+		Return(&metadatapb.UpdateConfigResponse{
+			Status: &statuspb.Status{
+				ErrCode: statuspb.NOT_FOUND,
+				Msg:     "no active agent with pod name pl/pem-1234",
+			},
+		}, nil)
+
+	s, err := executeMutations(t, me)
+	require.NoError(t, err)
+	errs := compilerErrors(t, s)
+	require.Len(t, errs, 3)
+
+	assert.Equal(t, "output table 'http_table' is written by both tracepoint 'http_probe' and tracepoint 'dns_probe'", errs[0].Message)
+	assert.Equal(t, uint64(4), errs[0].Line)
+	assert.Equal(t, uint64(39), errs[0].Column)
+
+	assert.Equal(t, "tracepoint 'http_probe' is both upserted and deleted", errs[1].Message)
+	assert.Equal(t, uint64(5), errs[1].Line)
+	assert.Equal(t, uint64(26), errs[1].Column)
+
+	assert.Equal(t, "cannot update config of agent 'pl/pem-1234': no active agent with pod name pl/pem-1234", errs[2].Message)
+	assert.Equal(t, uint64(6), errs[2].Line)
+	assert.Equal(t, uint64(27), errs[2].Column)
+}
+
+func TestMutationExecutor_Execute_RollbackOnFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	me, mdtp, mdconf := setupMutationExecutor(t, ctrl,
+		traceMutation("http_probe", "http_table"),
+		deleteMutation("dns_probe"),
+		configMutation("pl/pem-1234", "gprof", "true"))
+
+	prevID := utils.ProtoFromUUIDStrOrNil("8ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	prevVersion := &metadatapb.GetTracepointVersionsResponse{
+		Versions: []*storepb.TracepointVersion{
+			{
+				Name:    "dns_probe",
+				Version: 3,
+				ID:      prevID,
+				Tracepoint: &logicalpb.TracepointDeployment{
+					Name: "dns_probe",
+					TTL:  types.DurationProto(60000000000),
+				},
+			},
+		},
+	}
+
+	mdconf.
+		EXPECT().
+		UpdateConfig(gomock.Any(), gomock.Any()).
+		Return(&metadatapb.UpdateConfigResponse{Status: &statuspb.Status{ErrCode: statuspb.OK}}, nil)
+	mdtp.
+		EXPECT().
+		GetTracepointVersions(gomock.Any(), &metadatapb.GetTracepointVersionsRequest{Name: "http_probe"}).
+		Return(&metadatapb.GetTracepointVersionsResponse{}, nil)
+	mdtp.
+		EXPECT().
+		GetTracepointVersions(gomock.Any(), &metadatapb.GetTracepointVersionsRequest{Name: "dns_probe"}).
+		Return(prevVersion, nil)
+	mdtp.
+		EXPECT().
+		GetTracepointInfo(gomock.Any(), gomock.Any()).
+		Return(&metadatapb.GetTracepointInfoResponse{
+			Tracepoints: []*metadatapb.GetTracepointInfoResponse_TracepointState{
+				{
+					ID:            prevID,
+					Name:          "dns_probe",
+					State:         statuspb.RUNNING_STATE,
+					ExpectedState: statuspb.RUNNING_STATE,
+				},
+			},
+		}, nil)
+	mdtp.
+		EXPECT().
+		RegisterTracepoint(gomock.Any(), gomock.Any()).
+		Return(&metadatapb.RegisterTracepointResponse{
+			Tracepoints: []*metadatapb.RegisterTracepointResponse_TracepointStatus{
+				{
+					Name:   "http_probe",
+					ID:     utils.ProtoFromUUIDStrOrNil("11285cdd-1de9-4ab1-ae6a-0ba08c8c676c"),
+					Status: &statuspb.Status{ErrCode: statuspb.OK},
+				},
+			},
+		}, nil)
+	mdtp.
+		EXPECT().
+		RemoveTracepoint(gomock.Any(), &metadatapb.RemoveTracepointRequest{Names: []string{"dns_probe"}}).
+		Return(&metadatapb.RemoveTracepointResponse{Status: &statuspb.Status{ErrCode: statuspb.OK}}, nil)
+
+	// The config update fails, so the registration and the deletion are undone.
+	mdconf.
+		EXPECT().
+		UpdateConfig(gomock.Any(), gomock.Any()).
+		Return(&metadatapb.UpdateConfigResponse{Status: &statuspb.Status{ErrCode: statuspb.INTERNAL}}, nil)
+	mdtp.
+		EXPECT().
+		RollbackTracepoint(gomock.Any(), &metadatapb.RollbackTracepointRequest{
+			Name:    "dns_probe",
+			Version: 3,
+			TTL:     types.DurationProto(60000000000),
+		}).
+		Return(&metadatapb.RollbackTracepointResponse{Status: &statuspb.Status{ErrCode: statuspb.ALREADY_EXISTS}}, nil)
+	mdtp.
+		EXPECT().
+		RemoveTracepoint(gomock.Any(), &metadatapb.RemoveTracepointRequest{Names: []string{"http_probe"}}).
+		Return(&metadatapb.RemoveTracepointResponse{Status: &statuspb.Status{ErrCode: statuspb.OK}}, nil)
+
+	s, err := executeMutations(t, me)
+	assert.Equal(t, controllers.ErrConfigUpdateFailed, err)
+	assert.Nil(t, s)
+}
+
+func TestMutationExecutor_Execute_RollbackPartialRegistration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	me, mdtp, _ := setupMutationExecutor(t, ctrl,
+		traceMutation("http_probe", "http_table"),
+		traceMutation("dns_probe", "dns_table"))
+
+	mdtp.
+		EXPECT().
+		GetTracepointVersions(gomock.Any(), &metadatapb.GetTracepointVersionsRequest{Name: "http_probe"}).
+		Return(&metadatapb.GetTracepointVersionsResponse{}, nil)
+	mdtp.
+		EXPECT().
+		GetTracepointVersions(gomock.Any(), &metadatapb.GetTracepointVersionsRequest{Name: "dns_probe"}).
+		Return(&metadatapb.GetTracepointVersionsResponse{}, nil)
+	// The second tracepoint of the batch fails to register, after the first one was deployed.
+	mdtp.
+		EXPECT().
+		RegisterTracepoint(gomock.Any(), gomock.Any()).
+		Return(nil, status.Error(codes.Internal, "failed to deploy dns_probe"))
+
+	httpID := utils.ProtoFromUUIDStrOrNil("11285cdd-1de9-4ab1-ae6a-0ba08c8c676c")
+	mdtp.
+		EXPECT().
+		GetTracepointVersions(gomock.Any(), &metadatapb.GetTracepointVersionsRequest{Name: "http_probe"}).
+		Return(&metadatapb.GetTracepointVersionsResponse{
+			Versions: []*storepb.TracepointVersion{{Name: "http_probe", Version: 1, ID: httpID}},
+		}, nil)
+	mdtp.
+		EXPECT().
+		GetTracepointVersions(gomock.Any(), &metadatapb.GetTracepointVersionsRequest{Name: "dns_probe"}).
+		Return(&metadatapb.GetTracepointVersionsResponse{}, nil)
+	mdtp.
+		EXPECT().
+		GetTracepointInfo(gomock.Any(), &metadatapb.GetTracepointInfoRequest{IDs: []*uuidpb.UUID{httpID}}).
+		Return(&metadatapb.GetTracepointInfoResponse{
+			Tracepoints: []*metadatapb.GetTracepointInfoResponse_TracepointState{
+				{
+					ID:            httpID,
+					Name:          "http_probe",
+					State:         statuspb.PENDING_STATE,
+					ExpectedState: statuspb.RUNNING_STATE,
+				},
+			},
+		}, nil)
+	// Only the tracepoint which was deployed is removed.
+	mdtp.
+		EXPECT().
+		RemoveTracepoint(gomock.Any(), &metadatapb.RemoveTracepointRequest{Names: []string{"http_probe"}}).
+		Return(&metadatapb.RemoveTracepointResponse{Status: &statuspb.Status{ErrCode: statuspb.OK}}, nil)
+
+	s, err := executeMutations(t, me)
+	assert.Equal(t, controllers.ErrTracepointRegistrationFailed, err)
+	assert.Nil(t, s)
+}
+
+func TestMutationExecutor_Execute_RollbackConfigs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	me, _, mdconf := setupMutationExecutor(t, ctrl,
+		configMutation("pl/pem-1234", "gprof", "true"),
+		configMutation("pl/pem-1234", "heap", "true"),
+		configMutation("pl/pem-5678", "gprof", "true"))
+
+	// The updates are all validated before any of them is applied.
+	mdconf.
+		EXPECT().
+		UpdateConfig(gomock.Any(), gomock.Any()).
+		Return(&metadatapb.UpdateConfigResponse{Status: &statuspb.Status{ErrCode: statuspb.OK}}, nil).
+		Times(3)
+	gomock.InOrder(
+		mdconf.
+			EXPECT().
+			UpdateConfig(gomock.Any(), &metadatapb.UpdateConfigRequest{
+				AgentPodName: "pl/pem-1234",
+				Key:          "gprof",
+				Value:        "true",
+			}).
+			Return(&metadatapb.UpdateConfigResponse{
+				Status:        &statuspb.Status{ErrCode: statuspb.OK},
+				PreviousValue: "false",
+			}, nil),
+		mdconf.
+			EXPECT().
+			UpdateConfig(gomock.Any(), &metadatapb.UpdateConfigRequest{
+				AgentPodName: "pl/pem-1234",
+				Key:          "heap",
+				Value:        "true",
+			}).
+			Return(&metadatapb.UpdateConfigResponse{Status: &statuspb.Status{ErrCode: statuspb.OK}}, nil),
+		// The last update fails, so the first one is undone. The second one had no previous value to restore.
+		mdconf.
+			EXPECT().
+			UpdateConfig(gomock.Any(), &metadatapb.UpdateConfigRequest{
+				AgentPodName: "pl/pem-5678",
+				Key:          "gprof",
+				Value:        "true",
+			}).
+			Return(&metadatapb.UpdateConfigResponse{Status: &statuspb.Status{ErrCode: statuspb.INTERNAL}}, nil),
+		mdconf.
+			EXPECT().
+			UpdateConfig(gomock.Any(), &metadatapb.UpdateConfigRequest{
+				AgentPodName: "pl/pem-1234",
+				Key:          "gprof",
+				Value:        "false",
+			}).
+			Return(&metadatapb.UpdateConfigResponse{
+				Status:        &statuspb.Status{ErrCode: statuspb.OK},
+				PreviousValue: "true",
+			}, nil),
+	)
+
+	s, err := executeMutations(t, me)
+	assert.Equal(t, controllers.ErrConfigUpdateFailed, err)
+	assert.Nil(t, s)
 }
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"px.dev/pixie/src/carnot/planner/compilerpb"
+	"px.dev/pixie/src/carnot/planner/plannerpb"
+	"px.dev/pixie/src/common/base/statuspb"
+	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
+	"px.dev/pixie/src/vizier/services/metadata/storepb"
+)
+
+// MutationValidationError is returned when the mutations in a script conflict with each other, or cannot be
+// applied. Each error holds the line and column in the script of the value which caused it, or zero if the
+// compiler did not report the value's position.
+type MutationValidationError struct {
+	errs []*compilerpb.LineColError
+}
+
+func (e *MutationValidationError) add(pos scriptPosition, format string, args ...interface{}) {
+	e.errs = append(e.errs, &compilerpb.LineColError{
+		Line:    pos.line,
+		Column:  pos.col,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (e *MutationValidationError) Error() string {
+	msgs := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		msgs[i] = err.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Status converts the error into a status containing compiler-style errors, which
+// can be returned to the client.
+func (e *MutationValidationError) Status() *statuspb.Status {
+	return lineColErrorsToStatus(e.errs, e.Error())
+}
+
+// scriptPosition is a line and column in a script, as reported by the compiler. A zero position
+// means unknown.
+type scriptPosition struct {
+	line uint64
+	col  uint64
+}
+
+func positionFromProto(pb *plannerpb.SourcePosition) scriptPosition {
+	if pb == nil {
+		return scriptPosition{}
+	}
+	return scriptPosition{line: pb.Line, col: pb.Column}
+}
+
+// targetFromProto converts the agents selected in the script into the target of a tracepoint. A nil selector
+// targets all agents.
+func targetFromProto(pb *plannerpb.AgentSelector) *storepb.TracepointTarget {
+	if pb == nil {
+		return nil
+	}
+	return &storepb.TracepointTarget{
+		NodeNames:    pb.NodeNames,
+		NodeSelector: pb.NodeSelector,
+		Namespaces:   pb.Namespaces,
+		PodSelector:  pb.PodSelector,
+	}
+}
+
+// mutationPlan holds the requests needed to apply the mutations of a script.
+type mutationPlan struct {
+	register     *metadatapb.RegisterTracepointRequest
+	remove       *metadatapb.RemoveTracepointRequest
+	configs      []*metadatapb.UpdateConfigRequest
+	outputTables []string
+}
+
+// validateMutations checks that the mutations don't conflict with each other or with the tracepoints
+// which this executor has already deployed, and converts them into a plan. Conflicts are returned as
+// a MutationValidationError, other failures as a plain error.
+func (m *MutationExecutorImpl) validateMutations(ctx context.Context,
+	mutations []*plannerpb.CompileMutation) (*mutationPlan, error) {
+	plan := &mutationPlan{
+		register: &metadatapb.RegisterTracepointRequest{
+			Requests: make([]*metadatapb.RegisterTracepointRequest_TracepointRequest, 0),
+		},
+		remove: &metadatapb.RemoveTracepointRequest{
+			Names: make([]string, 0),
+		},
+		configs:      make([]*metadatapb.UpdateConfigRequest, 0),
+		outputTables: make([]string, 0),
+	}
+	validationErr := &MutationValidationError{}
+
+	upserted := make(map[string]bool)
+	deleted := make(map[string]bool)
+	// The tracepoint which writes to each output table.
+	tableWriters := make(map[string]string)
+	configValues := make(map[string]string)
+	configPositions := make(map[*metadatapb.UpdateConfigRequest]scriptPosition)
+
+	for _, compiled := range mutations {
+		pos := positionFromProto(compiled.Position)
+		switch mut := compiled.Mutation.(type) {
+		case *plannerpb.CompileMutation_Trace:
+			{
+				name := mut.Trace.Name
+				_, active := m.activeTracepoints[name]
+				switch {
+				case upserted[name] || active:
+					validationErr.add(pos, "tracepoint '%s' is upserted more than once", name)
+				case deleted[name]:
+					validationErr.add(pos, "tracepoint '%s' is both upserted and deleted", name)
+				}
+				upserted[name] = true
+
+				for i, program := range mut.Trace.Programs {
+					table := program.TableName
+					var tablePos scriptPosition
+					if i < len(compiled.TablePositions) {
+						tablePos = positionFromProto(compiled.TablePositions[i])
+					}
+					writer, ok := tableWriters[table]
+					if !ok {
+						tableWriters[table] = name
+						plan.outputTables = append(plan.outputTables, table)
+						continue
+					}
+					if writer == name {
+						validationErr.add(tablePos, "output table '%s' is written more than once by tracepoint '%s'", table, name)
+					} else {
+						validationErr.add(tablePos, "output table '%s' is written by both tracepoint '%s' and tracepoint '%s'",
+							table, writer, name)
+					}
+				}
+
+				plan.register.Requests = append(plan.register.Requests,
+					&metadatapb.RegisterTracepointRequest_TracepointRequest{
+						TracepointDeployment: mut.Trace,
+						Name:                 name,
+						TTL:                  mut.Trace.TTL,
+						Target:               targetFromProto(compiled.AgentSelector),
+					})
+			}
+		case *plannerpb.CompileMutation_DeleteTracepoint:
+			{
+				name := mut.DeleteTracepoint.Name
+				if upserted[name] {
+					validationErr.add(pos, "tracepoint '%s' is both upserted and deleted", name)
+				}
+				if !deleted[name] {
+					plan.remove.Names = append(plan.remove.Names, name)
+				}
+				deleted[name] = true
+			}
+		case *plannerpb.CompileMutation_ConfigUpdate:
+			{
+				update := mut.ConfigUpdate
+				key := fmt.Sprintf("%s/%s", update.AgentPodName, update.Key)
+				if value, ok := configValues[key]; ok {
+					if value != update.Value {
+						validationErr.add(pos, "config '%s' of agent '%s' is set to both '%s' and '%s'",
+							update.Key, update.AgentPodName, value, update.Value)
+					}
+					continue
+				}
+				configValues[key] = update.Value
+
+				req := &metadatapb.UpdateConfigRequest{
+					Key:          update.Key,
+					Value:        update.Value,
+					AgentPodName: update.AgentPodName,
+				}
+				plan.configs = append(plan.configs, req)
+				configPositions[req] = pos
+			}
+		}
+	}
+
+	for _, req := range plan.configs {
+		resp, err := m.mdconf.UpdateConfig(ctx, &metadatapb.UpdateConfigRequest{
+			Key:          req.Key,
+			Value:        req.Value,
+			AgentPodName: req.AgentPodName,
+			ValidateOnly: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp.Status != nil && resp.Status.ErrCode != statuspb.OK {
+			validationErr.add(configPositions[req], "cannot update config of agent '%s': %s", req.AgentPodName, resp.Status.Msg)
+		}
+	}
+
+	if len(validationErr.errs) > 0 {
+		return nil, validationErr
+	}
+	return plan, nil
+}
@@ -75,6 +75,7 @@ type QueryExecutorImpl struct {
 	mdconf              metadatapb.MetadataConfigServiceClient
 	resultForwarder     QueryResultForwarder
 	planner             Planner
+	defaultQueryFlags   map[string]string
 
 	eg *errgroup.Group
 
@@ -97,6 +98,7 @@ func NewQueryExecutorFromServer(s *Server, mutExecFactory MutationExecFactory) Q
 		s.mdconf,
 		s.resultForwarder,
 		s.planner,
+		s.defaultQueryFlags,
 		mutExecFactory,
 	)
 }
@@ -112,6 +114,7 @@ func NewQueryExecutor(
 	mdconf metadatapb.MetadataConfigServiceClient,
 	resultForwarder QueryResultForwarder,
 	planner Planner,
+	defaultQueryFlags map[string]string,
 	mutExecFactory MutationExecFactory,
 ) QueryExecutor {
 	return &QueryExecutorImpl{
@@ -124,6 +127,7 @@ func NewQueryExecutor(
 		mdconf:              mdconf,
 		resultForwarder:     resultForwarder,
 		planner:             planner,
+		defaultQueryFlags:   defaultQueryFlags,
 		mutationExecFactory: mutExecFactory,
 	}
 }
@@ -221,10 +225,19 @@ func (q *QueryExecutorImpl) sendResponse(ctx context.Context, resultCh chan<- *v
 	}
 }
 
-func (q *QueryExecutorImpl) getPlanOpts(queryStr string) (*planpb.PlanOptions, error) {
-	flags, err := ParseQueryFlags(queryStr)
+func (q *QueryExecutorImpl) getPlanOpts(ctx context.Context, resultCh chan<- *vizierpb.ExecuteScriptResponse, req *vizierpb.ExecuteScriptRequest) (*planpb.PlanOptions, error) {
+	flags, err := ParseQueryFlags(req.QueryStr, q.defaultQueryFlags, req.QueryFlags)
 	if err != nil {
-		return nil, err
+		var flagsErr *QueryFlagsError
+		if !errors.As(err, &flagsErr) {
+			return nil, err
+		}
+		// Invalid flags are reported to the user in the same way as compilation errors.
+		s := flagsErr.Status()
+		if err := q.sendResponse(ctx, resultCh, StatusToVizierResponse(q.queryID, s)); err != nil {
+			return nil, err
+		}
+		return nil, StatusToError(s)
 	}
 
 	planOpts := flags.GetPlanOptions()
@@ -381,7 +394,7 @@ func (q *QueryExecutorImpl) buildQueryPlanOpts(ctx context.Context, resultCh cha
 }
 
 func (q *QueryExecutorImpl) prepareScript(ctx context.Context, resultCh chan<- *vizierpb.ExecuteScriptResponse, req *vizierpb.ExecuteScriptRequest) error {
-	planOpts, err := q.getPlanOpts(req.QueryStr)
+	planOpts, err := q.getPlanOpts(ctx, resultCh, req)
 	if err != nil {
 		return err
 	}
@@ -19,14 +19,17 @@
 package controllers
 
 import (
-	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/gogo/protobuf/types"
 	"github.com/spf13/cast"
 
+	"px.dev/pixie/src/carnot/planner/compilerpb"
 	"px.dev/pixie/src/carnot/planpb"
+	"px.dev/pixie/src/common/base/statuspb"
 )
 
 // The prefix which a PL Config line should begin with.
@@ -40,6 +43,57 @@ var defaultQueryFlags = map[string]interface{}{
 	"max_output_rows_per_table": 10000,
 }
 
+// QueryFlagsError is returned when one or more query flags are invalid. Each error holds the line and column
+// of the offending "#px:set" line in the script, or zero if the flag was not set in the script.
+type QueryFlagsError struct {
+	errs []*compilerpb.LineColError
+}
+
+func (e *QueryFlagsError) add(line, col uint64, msg string) {
+	e.errs = append(e.errs, &compilerpb.LineColError{
+		Line:    line,
+		Column:  col,
+		Message: msg,
+	})
+}
+
+func (e *QueryFlagsError) Error() string {
+	msgs := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		msgs[i] = err.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Status converts the error into a status containing compiler-style errors, which
+// can be returned to the client.
+func (e *QueryFlagsError) Status() *statuspb.Status {
+	return lineColErrorsToStatus(e.errs, e.Error())
+}
+
+// lineColErrorsToStatus wraps the given errors in an INVALID_ARGUMENT status, in the same format that the
+// compiler uses to report errors.
+func lineColErrorsToStatus(errs []*compilerpb.LineColError, msg string) *statuspb.Status {
+	errGroup := &compilerpb.CompilerErrorGroup{
+		Errors: make([]*compilerpb.CompilerError, len(errs)),
+	}
+	for i, err := range errs {
+		errGroup.Errors[i] = &compilerpb.CompilerError{
+			Error: &compilerpb.CompilerError_LineColError{
+				LineColError: err,
+			},
+		}
+	}
+	s := &statuspb.Status{
+		ErrCode: statuspb.INVALID_ARGUMENT,
+		Msg:     msg,
+	}
+	if ctx, err := types.MarshalAny(errGroup); err == nil {
+		s.Context = ctx
+	}
+	return s
+}
+
 // QueryFlags represents a set of Pixie configuration flags.
 type QueryFlags struct {
 	flags map[string]interface{}
@@ -107,7 +161,7 @@ func (f *QueryFlags) set(key string, value string) error {
 		}
 
 		if err != nil {
-			return err
+			return fmt.Errorf("invalid value '%s' for flag %s: expected %T", value, key, defVal)
 		}
 		f.flags[key] = typedVal
 		return nil
@@ -116,6 +170,20 @@ func (f *QueryFlags) set(key string, value string) error {
 	return fmt.Errorf("%s is not a valid flag", key)
 }
 
+// setAll sets each of the given flags, in sorted key order, adding any failures to flagErr.
+func (f *QueryFlags) setAll(flags map[string]string, flagErr *QueryFlagsError) {
+	keys := make([]string, 0, len(flags))
+	for k := range flags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := f.set(k, flags[k]); err != nil {
+			flagErr.add(0, 0, err.Error())
+		}
+	}
+}
+
 // GetPlanOptions creates the plan option proto from the specified query flags.
 func (f *QueryFlags) GetPlanOptions() *planpb.PlanOptions {
 	return &planpb.PlanOptions{
@@ -125,29 +193,68 @@ func (f *QueryFlags) GetPlanOptions() *planpb.PlanOptions {
 	}
 }
 
+// ParseDefaultQueryFlags parses cluster-level default query flags, specified as a comma separated
+// list of key=value pairs.
+func ParseDefaultQueryFlags(flagsStr string) (map[string]string, error) {
+	flags := make(map[string]string)
+	for _, kv := range strings.Split(flagsStr, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		keyVal := strings.Split(kv, "=")
+		if len(keyVal) != 2 {
+			return nil, fmt.Errorf("default query flag '%s' is malformed", kv)
+		}
+		flags[keyVal[0]] = keyVal[1]
+	}
+
+	// Make sure that all of the defaults are valid flags.
+	flagErr := &QueryFlagsError{}
+	newQueryFlags().setAll(flags, flagErr)
+	if len(flagErr.errs) > 0 {
+		return nil, flagErr
+	}
+	return flags, nil
+}
+
 // ParseQueryFlags takes a query string containing some config options and generates
-// a QueryFlags object that can be used to retrieve those options.
-func ParseQueryFlags(queryStr string) (*QueryFlags, error) {
+// a QueryFlags object that can be used to retrieve those options. Flags are applied in
+// order of increasing precedence: the cluster defaults, flags set in the query string, and
+// finally flags set on the request. If any flag is invalid, a *QueryFlagsError is returned.
+func ParseQueryFlags(queryStr string, clusterFlags map[string]string, reqFlags map[string]string) (*QueryFlags, error) {
 	qf := newQueryFlags()
+	flagErr := &QueryFlagsError{}
+
+	qf.setAll(clusterFlags, flagErr)
 
-	for _, line := range strings.Split(strings.TrimSuffix(queryStr, "\n"), "\n") {
+	for i, line := range strings.Split(strings.TrimSuffix(queryStr, "\n"), "\n") {
 		// If the line begins with the PL config prefix, attempt to parse the line.
-		if strings.HasPrefix(line, plConfigPrefix) {
-			queryComponents := strings.Split(line, " ")
-
-			if len(queryComponents) != 2 {
-				return nil, errors.New("Config setting is malformed")
-			}
-			keyVal := strings.Split(queryComponents[1], "=")
-			if len(keyVal) != 2 {
-				return nil, errors.New("Config setting is malformed")
-			}
-			err := qf.set(keyVal[0], keyVal[1])
-			if err != nil {
-				return nil, err
-			}
+		if !strings.HasPrefix(line, plConfigPrefix) {
+			continue
+		}
+		lineNum := uint64(i + 1)
+		col := uint64(len(plConfigPrefix) + 1)
+
+		queryComponents := strings.Split(line, " ")
+		if len(queryComponents) != 2 {
+			flagErr.add(lineNum, col, "Config setting is malformed")
+			continue
+		}
+		keyVal := strings.Split(queryComponents[1], "=")
+		if len(keyVal) != 2 {
+			flagErr.add(lineNum, col, "Config setting is malformed")
+			continue
+		}
+		if err := qf.set(keyVal[0], keyVal[1]); err != nil {
+			flagErr.add(lineNum, col, err.Error())
 		}
 	}
 
+	qf.setAll(reqFlags, flagErr)
+
+	if len(flagErr.errs) > 0 {
+		return nil, flagErr
+	}
 	return qf, nil
 }
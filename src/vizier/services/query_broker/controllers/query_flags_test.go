@@ -21,9 +21,12 @@ package controllers_test
 import (
 	"testing"
 
+	"github.com/gogo/protobuf/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"px.dev/pixie/src/carnot/planner/compilerpb"
+	"px.dev/pixie/src/common/base/statuspb"
 	"px.dev/pixie/src/vizier/services/query_broker/controllers"
 )
 
@@ -55,7 +58,7 @@ const nonexistentFlag = `
 `
 
 func TestParseQueryFlags_WithFlag(t *testing.T) {
-	qf, err := controllers.ParseQueryFlags(validQueryWithFlag)
+	qf, err := controllers.ParseQueryFlags(validQueryWithFlag, nil, nil)
 
 	require.NoError(t, err)
 	assert.NotNil(t, qf)
@@ -71,7 +74,7 @@ func TestParseQueryFlags_WithFlag(t *testing.T) {
 }
 
 func TestParseQueryFlags_NoFlag(t *testing.T) {
-	qf, err := controllers.ParseQueryFlags(validQueryWithoutFlag)
+	qf, err := controllers.ParseQueryFlags(validQueryWithoutFlag, nil, nil)
 
 	require.NoError(t, err)
 	assert.NotNil(t, qf)
@@ -87,21 +90,21 @@ func TestParseQueryFlags_NoFlag(t *testing.T) {
 }
 
 func TestParseQueryFlags_InvalidFlag(t *testing.T) {
-	qf, err := controllers.ParseQueryFlags(invalidFlag1)
+	qf, err := controllers.ParseQueryFlags(invalidFlag1, nil, nil)
 	assert.Nil(t, qf)
 	assert.NotNil(t, err)
 
-	qf, err = controllers.ParseQueryFlags(invalidFlag2)
+	qf, err = controllers.ParseQueryFlags(invalidFlag2, nil, nil)
 	assert.Nil(t, qf)
 	assert.NotNil(t, err)
 
-	qf, err = controllers.ParseQueryFlags(nonexistentFlag)
+	qf, err = controllers.ParseQueryFlags(nonexistentFlag, nil, nil)
 	assert.Nil(t, qf)
 	assert.NotNil(t, err)
 }
 
 func TestParseQueryFlags_PlanOptions(t *testing.T) {
-	qf, err := controllers.ParseQueryFlags(validQueryWithFlag)
+	qf, err := controllers.ParseQueryFlags(validQueryWithFlag, nil, nil)
 
 	require.NoError(t, err)
 	assert.NotNil(t, qf)
@@ -110,3 +113,70 @@ func TestParseQueryFlags_PlanOptions(t *testing.T) {
 	assert.Equal(t, options.Explain, false)
 	assert.Equal(t, options.Analyze, true)
 }
+
+func TestParseQueryFlags_Precedence(t *testing.T) {
+	clusterFlags := map[string]string{
+		"analyze":                   "false",
+		"explain":                   "true",
+		"max_output_rows_per_table": "20000",
+	}
+	reqFlags := map[string]string{
+		"max_output_rows_per_table": "100",
+	}
+	qf, err := controllers.ParseQueryFlags(validQueryWithFlag, clusterFlags, reqFlags)
+	require.NoError(t, err)
+
+	// Set by the cluster default.
+	assert.Equal(t, true, qf.GetBool("explain"))
+	// Cluster default overridden by the script.
+	assert.Equal(t, true, qf.GetBool("analyze"))
+	// Script overridden by the request.
+	assert.Equal(t, int64(100), qf.GetInt64("max_output_rows_per_table"))
+}
+
+func TestParseQueryFlags_Errors(t *testing.T) {
+	reqFlags := map[string]string{
+		"analyze": "notabool",
+	}
+	qf, err := controllers.ParseQueryFlags(nonexistentFlag, nil, reqFlags)
+	assert.Nil(t, qf)
+	require.Error(t, err)
+
+	flagsErr, ok := err.(*controllers.QueryFlagsError)
+	require.True(t, ok)
+
+	s := flagsErr.Status()
+	assert.Equal(t, statuspb.INVALID_ARGUMENT, s.ErrCode)
+
+	errGroup := &compilerpb.CompilerErrorGroup{}
+	require.NoError(t, types.UnmarshalAny(s.Context, errGroup))
+	require.Equal(t, 2, len(errGroup.Errors))
+
+	scriptErr := errGroup.Errors[0].GetLineColError()
+	assert.Equal(t, uint64(2), scriptErr.Line)
+	assert.Equal(t, uint64(9), scriptErr.Column)
+	assert.Equal(t, "ABCD is not a valid flag", scriptErr.Message)
+
+	reqErr := errGroup.Errors[1].GetLineColError()
+	assert.Equal(t, uint64(0), reqErr.Line)
+	assert.Contains(t, reqErr.Message, "analyze")
+}
+
+func TestParseDefaultQueryFlags(t *testing.T) {
+	flags, err := controllers.ParseDefaultQueryFlags("max_output_rows_per_table=20000,explain=true,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"max_output_rows_per_table": "20000",
+		"explain":                   "true",
+	}, flags)
+
+	flags, err = controllers.ParseDefaultQueryFlags("")
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(flags))
+
+	_, err = controllers.ParseDefaultQueryFlags("max_output_rows_per_table=abc")
+	assert.Error(t, err)
+
+	_, err = controllers.ParseDefaultQueryFlags("ABCD=efgh")
+	assert.Error(t, err)
+}
@@ -86,6 +86,9 @@ type Server struct {
 
 	planner Planner
 
+	// defaultQueryFlags are the cluster-level defaults for query flags.
+	defaultQueryFlags map[string]string
+
 	queryExecFactory QueryExecutorFactory
 }
 
@@ -94,6 +97,7 @@ type QueryExecutorFactory func(*Server, MutationExecFactory) QueryExecutor
 
 // NewServer creates GRPC handlers.
 func NewServer(env querybrokerenv.QueryBrokerEnv, agentsTracker AgentsTracker, dataPrivacy DataPrivacy,
+	defaultQueryFlags map[string]string,
 	mds metadatapb.MetadataTracepointServiceClient, mdconf metadatapb.MetadataConfigServiceClient,
 	natsConn *nats.Conn, queryExecFactory QueryExecutorFactory) (*Server, error) {
 	var udfInfo udfspb.UDFInfo
@@ -105,7 +109,7 @@ func NewServer(env querybrokerenv.QueryBrokerEnv, agentsTracker AgentsTracker, d
 		return nil, err
 	}
 
-	return NewServerWithForwarderAndPlanner(env, agentsTracker, dataPrivacy, NewQueryResultForwarder(), mds, mdconf,
+	return NewServerWithForwarderAndPlanner(env, agentsTracker, dataPrivacy, defaultQueryFlags, NewQueryResultForwarder(), mds, mdconf,
 		natsConn, c, queryExecFactory)
 }
 
@@ -113,6 +117,7 @@ func NewServer(env querybrokerenv.QueryBrokerEnv, agentsTracker AgentsTracker, d
 func NewServerWithForwarderAndPlanner(env querybrokerenv.QueryBrokerEnv,
 	agentsTracker AgentsTracker,
 	dataPrivacy DataPrivacy,
+	defaultQueryFlags map[string]string,
 	resultForwarder QueryResultForwarder,
 	mds metadatapb.MetadataTracepointServiceClient,
 	mdconf metadatapb.MetadataConfigServiceClient,
@@ -128,6 +133,7 @@ func NewServerWithForwarderAndPlanner(env querybrokerenv.QueryBrokerEnv,
 		mdtp:              mds,
 		mdconf:            mdconf,
 		planner:           planner,
+		defaultQueryFlags: defaultQueryFlags,
 		queryExecFactory:  queryExecFactory,
 		healthcheckQuitCh: make(chan struct{}),
 	}
@@ -327,6 +333,28 @@ func (s *Server) ExecuteScript(req *vizierpb.ExecuteScriptRequest, srv vizierpb.
 	return queryExec.Wait()
 }
 
+// CompileMutations compiles the mutations, such as tracepoint definitions, in the given PxL script
+// against the current state of the cluster.
+func (s *Server) CompileMutations(queryStr string) (*plannerpb.CompileMutationsResponse, error) {
+	flags, err := ParseQueryFlags(queryStr, s.defaultQueryFlags, nil)
+	if err != nil {
+		return nil, err
+	}
+	req, err := VizierQueryRequestToPlannerMutationRequest(&vizierpb.ExecuteScriptRequest{
+		QueryStr: queryStr,
+		Mutation: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	distributedState := s.agentsTracker.GetAgentInfo().DistributedState()
+	plannerState := &distributedpb.LogicalPlannerState{
+		DistributedState: &distributedState,
+		PlanOptions:      flags.GetPlanOptions(),
+	}
+	return s.planner.CompileMutations(plannerState, req)
+}
+
 // TransferResultChunk implements the API that allows the query broker receive streamed results
 // from Carnot instances.
 func (s *Server) TransferResultChunk(srv carnotpb.ResultSinkService_TransferResultChunkServer) error {
@@ -31,18 +31,23 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	"px.dev/pixie/src/api/proto/vizierpb"
 	"px.dev/pixie/src/carnot/carnotpb"
+	"px.dev/pixie/src/operator/client/versioned"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/shared/services/healthz"
 	"px.dev/pixie/src/shared/services/httpmiddleware"
 	"px.dev/pixie/src/shared/services/server"
 	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
+	"px.dev/pixie/src/vizier/services/query_broker/apikeys"
 	"px.dev/pixie/src/vizier/services/query_broker/controllers"
 	"px.dev/pixie/src/vizier/services/query_broker/ptproxy"
 	"px.dev/pixie/src/vizier/services/query_broker/querybrokerenv"
 	scriptrunner "px.dev/pixie/src/vizier/services/query_broker/script_runner"
+	tracepointcontroller "px.dev/pixie/src/vizier/services/query_broker/tracepoint_controller"
 	"px.dev/pixie/src/vizier/services/query_broker/tracker"
 )
 
@@ -57,6 +62,10 @@ func init() {
 	pflag.String("mds_service", "vizier-metadata-svc", "The metadata service name")
 	pflag.String("mds_port", "50400", "The querybroker service port")
 	pflag.String("pod_namespace", "pl", "The namespace this pod runs in.")
+	pflag.Bool("standalone", false, "Whether Vizier runs without Pixie Cloud")
+	pflag.String("api_key_secret", "pl-standalone-api-keys", "The secret holding the API keys accepted in standalone mode")
+	pflag.String("cron_script_configmap", "pl-cron-scripts", "The ConfigMap holding the cron scripts run in standalone mode")
+	pflag.String("default_query_flags", "", "Cluster-level default values for query flags, specified as a comma separated list of key=value pairs")
 }
 
 // NewVizierServiceClient creates a new vz RPC client stub.
@@ -164,10 +173,25 @@ func main() {
 		log.WithError(err).Fatal("Failed to create data privacy manager.")
 	}
 
+	defaultQueryFlags, err := controllers.ParseDefaultQueryFlags(viper.GetString("default_query_flags"))
+	if err != nil {
+		log.WithError(err).Fatal("Failed to parse default query flags.")
+	}
+
+	kubeConfig, err := rest.InClusterConfig()
+	if err != nil {
+		log.WithError(err).Fatal("Unable to get incluster kubeconfig")
+	}
+	clientset, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to create k8s clientset")
+	}
+	standalone := viper.GetBool("standalone")
+
 	agentTracker := tracker.NewAgents(mdsClient, viper.GetString("jwt_signing_key"))
 	agentTracker.Start()
 	defer agentTracker.Stop()
-	svr, err := controllers.NewServer(env, agentTracker, dataPrivacy, mdtpClient, mdconfClient, natsConn, controllers.NewQueryExecutorFromServer)
+	svr, err := controllers.NewServer(env, agentTracker, dataPrivacy, defaultQueryFlags, mdtpClient, mdconfClient, natsConn, controllers.NewQueryExecutorFromServer)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to initialize GRPC server funcs.")
 	}
@@ -176,8 +200,15 @@ func main() {
 	// For query broker we bump up the max message size since resuls might be larger than 4mb.
 	maxMsgSize := grpc.MaxRecvMsgSize(8 * 1024 * 1024)
 
-	s := server.NewPLServer(env,
-		httpmiddleware.WithBearerAuthMiddleware(env, mux), maxMsgSize)
+	serverOpts := &server.GRPCServerOptions{GRPCServerOpts: []grpc.ServerOption{maxMsgSize}}
+	if standalone {
+		// Without Pixie Cloud to proxy requests, px and pxapi clients connect directly and authenticate
+		// with API keys that are provisioned locally.
+		validator := apikeys.NewValidator(clientset, viper.GetString("pod_namespace"), viper.GetString("api_key_secret"))
+		serverOpts.AuthMiddleware = validator.AuthMiddleware
+	}
+	s := server.NewPLServerWithOptions(env,
+		httpmiddleware.WithBearerAuthMiddleware(env, mux), serverOpts)
 
 	carnotpb.RegisterResultSinkServiceServer(s.GRPCServer(), svr)
 	vizierpb.RegisterVizierServiceServer(s.GRPCServer(), svr)
@@ -189,18 +220,20 @@ func main() {
 		log.WithError(err).Fatal("Failed to init vzservice client.")
 	}
 
-	// Start passthrough proxy.
-	ptProxy, err := ptproxy.NewPassThroughProxy(natsConn, vzServiceClient)
-	if err != nil {
-		log.WithError(err).Fatal("Failed to start passthrough proxy.")
-	}
-	go func() {
-		err := ptProxy.Run()
+	// Start passthrough proxy. Standalone Viziers have no cloud to proxy requests from.
+	if !standalone {
+		ptProxy, err := ptproxy.NewPassThroughProxy(natsConn, vzServiceClient)
 		if err != nil {
-			log.WithError(err).Error("Passthrough proxy failed to run")
+			log.WithError(err).Fatal("Failed to start passthrough proxy.")
 		}
-	}()
-	defer ptProxy.Close()
+		go func() {
+			err := ptProxy.Run()
+			if err != nil {
+				log.WithError(err).Error("Passthrough proxy failed to run")
+			}
+		}()
+		defer ptProxy.Close()
+	}
 
 	// Start cron script runner.
 	sr, err := scriptrunner.New(natsConn, csClient, vzServiceClient, viper.GetString("jwt_signing_key"))
@@ -208,10 +241,24 @@ func main() {
 		log.WithError(err).Fatal("Failed to start script runner")
 	}
 
-	err = sr.SyncScripts()
+	if standalone {
+		err = sr.SyncConfigMapScripts(clientset, viper.GetString("pod_namespace"), viper.GetString("cron_script_configmap"))
+	} else {
+		err = sr.SyncScripts()
+	}
 	if err != nil {
 		log.WithError(err).Error("Failed to sync cron scripts")
 	}
+	defer sr.Stop()
+
+	// Start the controller which deploys the tracepoints defined by Tracepoint resources.
+	pxClient, err := versioned.NewForConfig(kubeConfig)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to create Pixie clientset")
+	}
+	tpController := tracepointcontroller.New(viper.GetString("pod_namespace"), pxClient, svr, mdtpClient, viper.GetString("jwt_signing_key"))
+	tpController.Start()
+	defer tpController.Stop()
 
 	s.Start()
 	s.StopOnInterrupt()
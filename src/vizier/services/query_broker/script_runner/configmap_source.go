@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package scriptrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"px.dev/pixie/src/shared/cvmsgspb"
+	svcutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
+)
+
+// configMapScriptNamespace is used to derive stable cron script IDs from the entries of a ConfigMap.
+var configMapScriptNamespace = uuid.Must(uuid.FromString("5c9a4b1e-3f2d-4e8a-9b61-0d7c2a8e4f13"))
+
+// configMapScript is the format of a single cron script entry in the cron script ConfigMap.
+type configMapScript struct {
+	Script     string `yaml:"script"`
+	Configs    string `yaml:"configs"`
+	FrequencyS int64  `yaml:"frequencyS"`
+}
+
+// ScriptsFromConfigMap parses the cron scripts defined in the given ConfigMap, keyed by script ID. Each data entry
+// holds a single script. The ID is derived from the ConfigMap and entry names, so it is stable across restarts.
+// Entries which cannot be parsed are skipped.
+func ScriptsFromConfigMap(cm *corev1.ConfigMap) map[string]*cvmsgspb.CronScript {
+	scripts := make(map[string]*cvmsgspb.CronScript)
+	for key, data := range cm.Data {
+		var s configMapScript
+		err := yaml.UnmarshalStrict([]byte(data), &s)
+		if err != nil {
+			log.WithError(err).WithField("script", key).Error("Failed to parse cron script from ConfigMap, skipping...")
+			continue
+		}
+		if s.Script == "" {
+			log.WithField("script", key).Error("Cron script in ConfigMap has no script body, skipping...")
+			continue
+		}
+
+		id := uuid.NewV5(configMapScriptNamespace, fmt.Sprintf("%s/%s/%s", cm.Namespace, cm.Name, key))
+		scripts[id.String()] = &cvmsgspb.CronScript{
+			ID:         utils.ProtoFromUUID(id),
+			Script:     s.Script,
+			Configs:    s.Configs,
+			FrequencyS: s.FrequencyS,
+		}
+	}
+	return scripts
+}
+
+// SyncConfigMapScripts runs the cron scripts defined in the given ConfigMap instead of the scripts registered in
+// Pixie Cloud. This is used by standalone Viziers. The runners are kept in sync with the ConfigMap until the
+// script runner is stopped.
+func (s *ScriptRunner) SyncConfigMapScripts(clientset kubernetes.Interface, namespace string, name string) error {
+	claims := svcutils.GenerateJWTForService("cron_script_store", "vizier")
+	token, _ := svcutils.SignJWTClaims(claims, s.signingKey)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization",
+		fmt.Sprintf("bearer %s", token))
+
+	// The ConfigMap is the source of truth, so clear out any scripts persisted by a previous run.
+	_, err := s.csClient.SetScripts(ctx, &metadatapb.SetScriptsRequest{Scripts: make(map[string]*cvmsgspb.CronScript)})
+	if err != nil {
+		log.WithError(err).Error("Failed to delete scripts from store")
+		return err
+	}
+
+	isScriptConfigMap := func(obj interface{}) bool {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		cm, ok := obj.(*corev1.ConfigMap)
+		return ok && cm.Namespace == namespace && cm.Name == name
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if isScriptConfigMap(obj) {
+				s.reconcileScripts(ScriptsFromConfigMap(obj.(*corev1.ConfigMap)))
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if isScriptConfigMap(newObj) {
+				s.reconcileScripts(ScriptsFromConfigMap(newObj.(*corev1.ConfigMap)))
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if isScriptConfigMap(obj) {
+				s.reconcileScripts(make(map[string]*cvmsgspb.CronScript))
+			}
+		},
+	})
+	go informer.Run(s.done)
+
+	if !cache.WaitForCacheSync(s.done, informer.HasSynced) {
+		return errors.New("Failed to sync cron script ConfigMap")
+	}
+	return nil
+}
+
+// reconcileScripts starts, restarts and stops runners so that exactly the given scripts are running.
+func (s *ScriptRunner) reconcileScripts(scripts map[string]*cvmsgspb.CronScript) {
+	var deleted []uuid.UUID
+	upserted := make(map[uuid.UUID]*cvmsgspb.CronScript)
+
+	s.runnerMapMu.Lock()
+	for id := range s.runnerMap {
+		if _, ok := scripts[id.String()]; !ok {
+			deleted = append(deleted, id)
+		}
+	}
+	for k, v := range scripts {
+		id := uuid.FromStringOrNil(k)
+		if r, ok := s.runnerMap[id]; ok && r.cronScript.Equal(v) {
+			continue
+		}
+		upserted[id] = v
+	}
+	s.runnerMapMu.Unlock()
+
+	for _, id := range deleted {
+		err := s.deleteScript(id)
+		if err != nil {
+			log.WithError(err).Error("Failed to delete script")
+		}
+	}
+	for id, script := range upserted {
+		err := s.upsertScript(id, script)
+		if err != nil {
+			log.WithError(err).Error("Failed to upsert script")
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/proto"
@@ -31,6 +32,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/scripts"
@@ -750,3 +754,100 @@ func TestScriptRunner_SyncScripts(t *testing.T) {
 		})
 	}
 }
+
+func TestScriptRunner_SyncConfigMapScripts(t *testing.T) {
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+
+	staleID := uuid.Must(uuid.FromString("223e4567-e89b-12d3-a456-426655440000"))
+	fcs := &fakeCronStore{scripts: map[uuid.UUID]*cvmsgspb.CronScript{
+		staleID: &cvmsgspb.CronScript{
+			ID:         utils.ProtoFromUUID(staleID),
+			Script:     "stale script",
+			FrequencyS: 5,
+		},
+	}}
+	sr, err := New(nc, fcs, nil, "test")
+	require.NoError(t, err)
+	defer sr.Stop()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pl-cron-scripts",
+			Namespace: "pl",
+		},
+		Data: map[string]string{
+			"http-stats": "script: px.display()\nfrequencyS: 60\n",
+			"conn-stats": "script: test script\nconfigs: config1\nfrequencyS: 300\n",
+			"invalid":    "scriptz: [",
+		},
+	}
+	clientset := fake.NewSimpleClientset(cm)
+
+	httpID := uuid.NewV5(configMapScriptNamespace, "pl/pl-cron-scripts/http-stats")
+	connID := uuid.NewV5(configMapScriptNamespace, "pl/pl-cron-scripts/conn-stats")
+
+	runningScripts := func() map[uuid.UUID]*cvmsgspb.CronScript {
+		sr.runnerMapMu.Lock()
+		defer sr.runnerMapMu.Unlock()
+		m := make(map[uuid.UUID]*cvmsgspb.CronScript)
+		for k, v := range sr.runnerMap {
+			m[k] = v.cronScript
+		}
+		return m
+	}
+
+	err = sr.SyncConfigMapScripts(clientset, "pl", "pl-cron-scripts")
+	require.NoError(t, err)
+
+	expectedScripts := map[uuid.UUID]*cvmsgspb.CronScript{
+		httpID: &cvmsgspb.CronScript{
+			ID:         utils.ProtoFromUUID(httpID),
+			Script:     "px.display()",
+			FrequencyS: 60,
+		},
+		connID: &cvmsgspb.CronScript{
+			ID:         utils.ProtoFromUUID(connID),
+			Script:     "test script",
+			Configs:    "config1",
+			FrequencyS: 300,
+		},
+	}
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual(expectedScripts, runningScripts())
+	}, 5*time.Second, 10*time.Millisecond)
+	sr.runnerMapMu.Lock()
+	assert.Equal(t, expectedScripts, fcs.scripts)
+	sr.runnerMapMu.Unlock()
+
+	// Update one script and remove the other.
+	cm.Data = map[string]string{
+		"http-stats": "script: px.display()\nfrequencyS: 30\n",
+	}
+	_, err = clientset.CoreV1().ConfigMaps("pl").Update(context.Background(), cm, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	expectedScripts = map[uuid.UUID]*cvmsgspb.CronScript{
+		httpID: &cvmsgspb.CronScript{
+			ID:         utils.ProtoFromUUID(httpID),
+			Script:     "px.display()",
+			FrequencyS: 30,
+		},
+	}
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual(expectedScripts, runningScripts())
+	}, 5*time.Second, 10*time.Millisecond)
+	sr.runnerMapMu.Lock()
+	assert.Equal(t, expectedScripts, fcs.scripts)
+	sr.runnerMapMu.Unlock()
+
+	// Deleting the ConfigMap stops all scripts.
+	err = clientset.CoreV1().ConfigMaps("pl").Delete(context.Background(), "pl-cron-scripts", metav1.DeleteOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(runningScripts()) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	sr.runnerMapMu.Lock()
+	assert.Equal(t, 0, len(fcs.scripts))
+	sr.runnerMapMu.Unlock()
+}
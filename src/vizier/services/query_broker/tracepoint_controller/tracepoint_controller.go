@@ -0,0 +1,355 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package tracepointcontroller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/carnot/planner/plannerpb"
+	"px.dev/pixie/src/common/base/statuspb"
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/operator/client/versioned"
+	svcutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
+	"px.dev/pixie/src/vizier/services/metadata/storepb"
+)
+
+const (
+	// tracepointTTL is the TTL that tracepoints are registered with. The TTL is refreshed on every sync, so
+	// a tracepoint only expires if its Tracepoint resource is deleted while the controller is not running.
+	tracepointTTL = 10 * time.Minute
+	// syncPeriod is how often the Tracepoint resources are reconciled.
+	syncPeriod = 30 * time.Second
+)
+
+// MutationCompiler compiles the mutations in a PxL script.
+type MutationCompiler interface {
+	CompileMutations(queryStr string) (*plannerpb.CompileMutationsResponse, error)
+}
+
+// compiledTracepoint is the tracepoint request compiled from a specific generation of a Tracepoint resource.
+type compiledTracepoint struct {
+	generation int64
+	req        *metadatapb.RegisterTracepointRequest_TracepointRequest
+	err        error
+}
+
+// Controller reconciles the Tracepoint resources in the Vizier's namespace into tracepoints in the
+// metadata service. Tracepoints are kept alive while their resource exists, and the per-agent state of
+// each tracepoint is written back to the resource's status.
+type Controller struct {
+	namespace  string
+	pxClient   versioned.Interface
+	compiler   MutationCompiler
+	mdtp       metadatapb.MetadataTracepointServiceClient
+	signingKey string
+
+	// compiled is a cache of the compiled tracepoints, keyed by resource name.
+	compiled map[string]*compiledTracepoint
+	// managed is the set of tracepoints which have been registered by this controller.
+	managed map[string]bool
+
+	done chan struct{}
+	once sync.Once
+}
+
+// New creates a new tracepoint controller.
+func New(namespace string, pxClient versioned.Interface, compiler MutationCompiler,
+	mdtp metadatapb.MetadataTracepointServiceClient, signingKey string) *Controller {
+	return &Controller{
+		namespace:  namespace,
+		pxClient:   pxClient,
+		compiler:   compiler,
+		mdtp:       mdtp,
+		signingKey: signingKey,
+		compiled:   make(map[string]*compiledTracepoint),
+		managed:    make(map[string]bool),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start periodically reconciles the Tracepoint resources until the controller is stopped.
+func (c *Controller) Start() {
+	go func() {
+		ticker := time.NewTicker(syncPeriod)
+		defer ticker.Stop()
+		for {
+			if err := c.SyncTracepoints(context.Background()); err != nil {
+				log.WithError(err).Error("Failed to sync Tracepoint resources")
+			}
+			select {
+			case <-c.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the controller.
+func (c *Controller) Stop() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *Controller) authContext(ctx context.Context) context.Context {
+	claims := svcutils.GenerateJWTForService("tracepoint_controller", "vizier")
+	token, _ := svcutils.SignJWTClaims(claims, c.signingKey)
+	return metadata.AppendToOutgoingContext(ctx, "authorization", fmt.Sprintf("bearer %s", token))
+}
+
+// SyncTracepoints deploys the tracepoint for each Tracepoint resource, and removes the tracepoints whose
+// resources have been deleted.
+func (c *Controller) SyncTracepoints(ctx context.Context) error {
+	tpList, err := c.pxClient.PxV1alpha1().Tracepoints(c.namespace).List(ctx, metav1.ListOptions{})
+	if k8serrors.IsNotFound(err) {
+		// The Tracepoint CRD has not been installed in this cluster.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx = c.authContext(ctx)
+
+	existing := make(map[string]bool)
+	for i := range tpList.Items {
+		tp := &tpList.Items[i]
+		existing[tp.Name] = true
+		c.syncTracepoint(ctx, tp)
+	}
+
+	var removed []string
+	for name := range c.managed {
+		if !existing[name] {
+			removed = append(removed, name)
+		}
+	}
+	for name := range c.compiled {
+		if !existing[name] {
+			delete(c.compiled, name)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	resp, err := c.mdtp.RemoveTracepoint(ctx, &metadatapb.RemoveTracepointRequest{Names: removed})
+	if err != nil {
+		return err
+	}
+	if resp.Status != nil && resp.Status.ErrCode != statuspb.OK {
+		return fmt.Errorf("failed to remove tracepoints: %s", resp.Status.Msg)
+	}
+	for _, name := range removed {
+		delete(c.managed, name)
+	}
+	return nil
+}
+
+func (c *Controller) syncTracepoint(ctx context.Context, tp *v1alpha1.Tracepoint) {
+	status := c.deployTracepoint(ctx, tp)
+	status.ObservedGeneration = tp.Generation
+	if equality.Semantic.DeepEqual(tp.Status, *status) {
+		return
+	}
+
+	tp.Status = *status
+	_, err := c.pxClient.PxV1alpha1().Tracepoints(c.namespace).UpdateStatus(ctx, tp, metav1.UpdateOptions{})
+	if err != nil {
+		log.WithError(err).WithField("tracepoint", tp.Name).Error("Failed to update Tracepoint status")
+	}
+}
+
+func failedStatus(err error) *v1alpha1.TracepointStatus {
+	return &v1alpha1.TracepointStatus{
+		Phase:   v1alpha1.TracepointPhaseFailed,
+		Message: err.Error(),
+	}
+}
+
+// deployTracepoint registers the tracepoint for the given resource, which also refreshes its TTL, and returns
+// the resource's new status.
+func (c *Controller) deployTracepoint(ctx context.Context, tp *v1alpha1.Tracepoint) *v1alpha1.TracepointStatus {
+	compiled, ok := c.compiled[tp.Name]
+	if !ok || compiled.generation != tp.Generation {
+		req, err := c.compileTracepoint(tp)
+		compiled = &compiledTracepoint{generation: tp.Generation, req: req, err: err}
+		c.compiled[tp.Name] = compiled
+	}
+	if compiled.err != nil {
+		return failedStatus(compiled.err)
+	}
+
+	resp, err := c.mdtp.RegisterTracepoint(ctx, &metadatapb.RegisterTracepointRequest{
+		Requests: []*metadatapb.RegisterTracepointRequest_TracepointRequest{compiled.req},
+	})
+	if err != nil {
+		log.WithError(err).WithField("tracepoint", tp.Name).Error("Failed to register tracepoint")
+		return failedStatus(err)
+	}
+	if len(resp.Tracepoints) != 1 {
+		return failedStatus(errors.New("unexpected response when registering tracepoint"))
+	}
+	tpStatus := resp.Tracepoints[0]
+	if tpStatus.Status != nil && tpStatus.Status.ErrCode != statuspb.OK && tpStatus.Status.ErrCode != statuspb.ALREADY_EXISTS {
+		return failedStatus(fmt.Errorf("failed to register tracepoint: %s", tpStatus.Status.Msg))
+	}
+	c.managed[tp.Name] = true
+
+	infoResp, err := c.mdtp.GetTracepointInfo(ctx, &metadatapb.GetTracepointInfoRequest{
+		IDs: []*uuidpb.UUID{tpStatus.ID},
+	})
+	if err != nil || len(infoResp.Tracepoints) != 1 {
+		log.WithError(err).WithField("tracepoint", tp.Name).Error("Failed to get tracepoint info")
+		return &v1alpha1.TracepointStatus{
+			ID:     utils.UUIDFromProtoOrNil(tpStatus.ID).String(),
+			Phase:  v1alpha1.TracepointPhasePending,
+			Agents: tp.Status.Agents,
+		}
+	}
+	return tracepointStatusFromInfo(infoResp.Tracepoints[0])
+}
+
+// compileTracepoint compiles the PxL script of the given resource into a tracepoint request.
+func (c *Controller) compileTracepoint(tp *v1alpha1.Tracepoint) (*metadatapb.RegisterTracepointRequest_TracepointRequest, error) {
+	resp, err := c.compiler.CompileMutations(tp.Spec.PxL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != nil && resp.Status.ErrCode != statuspb.OK {
+		return nil, fmt.Errorf("failed to compile PxL: %s", resp.Status.Msg)
+	}
+
+	var traces []*plannerpb.CompileMutation_Trace
+	for _, mut := range resp.Mutations {
+		trace, ok := mut.Mutation.(*plannerpb.CompileMutation_Trace)
+		if !ok {
+			return nil, errors.New("PxL script may only define tracepoints")
+		}
+		traces = append(traces, trace)
+	}
+	if len(traces) != 1 {
+		return nil, fmt.Errorf("PxL script must define exactly one tracepoint, found %d", len(traces))
+	}
+
+	deployment := traces[0].Trace
+	deployment.Name = tp.Name
+	deployment.TTL = types.DurationProto(tracepointTTL)
+	if tp.Spec.TableName != "" {
+		if len(deployment.Programs) != 1 {
+			return nil, errors.New("tableName may only be specified for tracepoints with a single program")
+		}
+		program := deployment.Programs[0]
+		program.TableName = tp.Spec.TableName
+		if program.Spec != nil && len(program.Spec.Outputs) == 1 {
+			program.Spec.Outputs[0].Name = tp.Spec.TableName
+		}
+	}
+
+	target, err := targetToProto(tp.Spec.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &metadatapb.RegisterTracepointRequest_TracepointRequest{
+		TracepointDeployment: deployment,
+		Name:                 tp.Name,
+		TTL:                  deployment.TTL,
+		Target:               target,
+	}, nil
+}
+
+// targetToProto converts the target of a Tracepoint resource to the target used by the metadata service.
+func targetToProto(target *v1alpha1.TracepointTarget) (*storepb.TracepointTarget, error) {
+	if target == nil {
+		return nil, nil
+	}
+	pb := &storepb.TracepointTarget{
+		NodeNames:  target.NodeNames,
+		Namespaces: target.Namespaces,
+	}
+	if target.NodeSelector != nil {
+		sel, err := metav1.LabelSelectorAsSelector(target.NodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nodeSelector: %v", err)
+		}
+		pb.NodeSelector = sel.String()
+	}
+	if target.PodSelector != nil {
+		sel, err := metav1.LabelSelectorAsSelector(target.PodSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid podSelector: %v", err)
+		}
+		pb.PodSelector = sel.String()
+	}
+	return pb, nil
+}
+
+func phaseFromState(state statuspb.LifeCycleState) v1alpha1.TracepointPhase {
+	switch state {
+	case statuspb.RUNNING_STATE:
+		return v1alpha1.TracepointPhaseRunning
+	case statuspb.FAILED_STATE:
+		return v1alpha1.TracepointPhaseFailed
+	case statuspb.TERMINATED_STATE:
+		return v1alpha1.TracepointPhaseTerminated
+	default:
+		return v1alpha1.TracepointPhasePending
+	}
+}
+
+// tracepointStatusFromInfo converts the tracepoint info from the metadata service to a Tracepoint status.
+func tracepointStatusFromInfo(info *metadatapb.GetTracepointInfoResponse_TracepointState) *v1alpha1.TracepointStatus {
+	status := &v1alpha1.TracepointStatus{
+		ID:    utils.UUIDFromProtoOrNil(info.ID).String(),
+		Phase: phaseFromState(info.State),
+	}
+	for _, s := range info.Statuses {
+		if s != nil && s.Msg != "" {
+			status.Message = s.Msg
+			break
+		}
+	}
+	for _, agentStatus := range info.AgentStatuses {
+		a := v1alpha1.AgentTracepointStatus{
+			AgentID: utils.UUIDFromProtoOrNil(agentStatus.AgentID).String(),
+			State:   agentStatus.State.String(),
+		}
+		if agentStatus.Status != nil {
+			a.Message = agentStatus.Status.Msg
+		}
+		status.Agents = append(status.Agents, a)
+	}
+	return status
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package tracepointcontroller_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"px.dev/pixie/src/carnot/planner/dynamic_tracing/ir/logicalpb"
+	"px.dev/pixie/src/carnot/planner/plannerpb"
+	"px.dev/pixie/src/common/base/statuspb"
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/operator/client/versioned/fake"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/vizier/services/metadata/metadatapb"
+	mock_metadatapb "px.dev/pixie/src/vizier/services/metadata/metadatapb/mock"
+	"px.dev/pixie/src/vizier/services/metadata/storepb"
+	tracepointcontroller "px.dev/pixie/src/vizier/services/query_broker/tracepoint_controller"
+)
+
+type fakeCompiler struct {
+	resp *plannerpb.CompileMutationsResponse
+}
+
+func (f *fakeCompiler) CompileMutations(queryStr string) (*plannerpb.CompileMutationsResponse, error) {
+	return f.resp, nil
+}
+
+func traceResponse() *plannerpb.CompileMutationsResponse {
+	return &plannerpb.CompileMutationsResponse{
+		Status: &statuspb.Status{ErrCode: statuspb.OK},
+		Mutations: []*plannerpb.CompileMutation{
+			{
+				Mutation: &plannerpb.CompileMutation_Trace{
+					Trace: &logicalpb.TracepointDeployment{
+						Name: "script_name",
+						Programs: []*logicalpb.TracepointDeployment_TracepointProgram{
+							{
+								TableName: "script_table",
+								Spec: &logicalpb.TracepointSpec{
+									Outputs: []*logicalpb.Output{{Name: "script_table"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestController_SyncTracepoints(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tp := &v1alpha1.Tracepoint{
+		ObjectMeta: metav1.ObjectMeta{Name: "http_tp", Namespace: "pl", Generation: 1},
+		Spec: v1alpha1.TracepointSpec{
+			PxL:       "import pxtrace",
+			TableName: "http_events_custom",
+			Target: &v1alpha1.TracepointTarget{
+				Namespaces: []string{"default"},
+				PodSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "web"},
+				},
+			},
+		},
+	}
+	pxClient := fake.NewSimpleClientset(tp)
+	mdtp := mock_metadatapb.NewMockMetadataTracepointServiceClient(ctrl)
+
+	tpID := uuid.Must(uuid.NewV4())
+	agentID := uuid.Must(uuid.NewV4())
+
+	mdtp.EXPECT().
+		RegisterTracepoint(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req *metadatapb.RegisterTracepointRequest) (*metadatapb.RegisterTracepointResponse, error) {
+			require.Len(t, req.Requests, 1)
+			r := req.Requests[0]
+			assert.Equal(t, "http_tp", r.Name)
+			assert.Equal(t, "http_tp", r.TracepointDeployment.Name)
+			assert.Equal(t, "http_events_custom", r.TracepointDeployment.Programs[0].TableName)
+			assert.Equal(t, "http_events_custom", r.TracepointDeployment.Programs[0].Spec.Outputs[0].Name)
+			assert.NotNil(t, r.TTL)
+			assert.Equal(t, &storepb.TracepointTarget{
+				Namespaces:  []string{"default"},
+				PodSelector: "app=web",
+			}, r.Target)
+			return &metadatapb.RegisterTracepointResponse{
+				Tracepoints: []*metadatapb.RegisterTracepointResponse_TracepointStatus{
+					{
+						ID:     utils.ProtoFromUUID(tpID),
+						Name:   "http_tp",
+						Status: &statuspb.Status{ErrCode: statuspb.OK},
+					},
+				},
+				Status: &statuspb.Status{ErrCode: statuspb.OK},
+			}, nil
+		})
+	mdtp.EXPECT().
+		GetTracepointInfo(gomock.Any(), gomock.Any()).
+		Return(&metadatapb.GetTracepointInfoResponse{
+			Tracepoints: []*metadatapb.GetTracepointInfoResponse_TracepointState{
+				{
+					ID:    utils.ProtoFromUUID(tpID),
+					State: statuspb.RUNNING_STATE,
+					Name:  "http_tp",
+					AgentStatuses: []*storepb.AgentTracepointStatus{
+						{
+							ID:      utils.ProtoFromUUID(tpID),
+							AgentID: utils.ProtoFromUUID(agentID),
+							State:   statuspb.RUNNING_STATE,
+						},
+					},
+				},
+			},
+		}, nil)
+
+	c := tracepointcontroller.New("pl", pxClient, &fakeCompiler{resp: traceResponse()}, mdtp, "signing_key")
+	require.NoError(t, c.SyncTracepoints(context.Background()))
+
+	updated, err := pxClient.PxV1alpha1().Tracepoints("pl").Get(context.Background(), "http_tp", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.TracepointStatus{
+		ID:                 tpID.String(),
+		Phase:              v1alpha1.TracepointPhaseRunning,
+		ObservedGeneration: 1,
+		Agents: []v1alpha1.AgentTracepointStatus{
+			{AgentID: agentID.String(), State: "RUNNING_STATE"},
+		},
+	}, updated.Status)
+
+	// Deleting the resource should remove the tracepoint.
+	err = pxClient.PxV1alpha1().Tracepoints("pl").Delete(context.Background(), "http_tp", metav1.DeleteOptions{})
+	require.NoError(t, err)
+	mdtp.EXPECT().
+		RemoveTracepoint(gomock.Any(), &metadatapb.RemoveTracepointRequest{Names: []string{"http_tp"}}).
+		Return(&metadatapb.RemoveTracepointResponse{Status: &statuspb.Status{ErrCode: statuspb.OK}}, nil)
+	require.NoError(t, c.SyncTracepoints(context.Background()))
+}
+
+func TestController_SyncTracepoints_CompileError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tp := &v1alpha1.Tracepoint{
+		ObjectMeta: metav1.ObjectMeta{Name: "bad_tp", Namespace: "pl", Generation: 2},
+		Spec:       v1alpha1.TracepointSpec{PxL: "import px"},
+	}
+	pxClient := fake.NewSimpleClientset(tp)
+	mdtp := mock_metadatapb.NewMockMetadataTracepointServiceClient(ctrl)
+
+	compiler := &fakeCompiler{resp: &plannerpb.CompileMutationsResponse{
+		Status: &statuspb.Status{ErrCode: statuspb.OK},
+	}}
+	c := tracepointcontroller.New("pl", pxClient, compiler, mdtp, "signing_key")
+	require.NoError(t, c.SyncTracepoints(context.Background()))
+
+	updated, err := pxClient.PxV1alpha1().Tracepoints("pl").Get(context.Background(), "bad_tp", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, v1alpha1.TracepointPhaseFailed, updated.Status.Phase)
+	assert.Equal(t, int64(2), updated.Status.ObservedGeneration)
+	assert.Contains(t, updated.Status.Message, "exactly one tracepoint")
+}